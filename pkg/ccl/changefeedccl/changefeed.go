@@ -0,0 +1,319 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// rowChange is a change to a row of a watched table, as seen by a rangefeed.
+type rowChange struct {
+	table *sqlbase.TableDescriptor
+	// row holds the values of the columns of the table after the change, or is
+	// nil if the row was deleted.
+	row tree.Datums
+	// pk holds the values of the primary key columns of the row.
+	pk      tree.Datums
+	updated hlc.Timestamp
+}
+
+// rowDecoder decodes the key/values of the primary index of a table into
+// rows. The tables of a changefeed are restricted to a single column family,
+// so each key/value holds an entire row.
+type rowDecoder struct {
+	desc  *sqlbase.TableDescriptor
+	rf    sqlbase.MultiRowFetcher
+	alloc sqlbase.DatumAlloc
+
+	// The types, directions and column indexes of the primary key columns,
+	// and the EncDatums to decode them into when a row is deleted.
+	pkTypes  []sqlbase.ColumnType
+	pkDirs   []encoding.Direction
+	pkColIdx []int
+	pkVals   []sqlbase.EncDatum
+}
+
+func makeRowDecoder(desc *sqlbase.TableDescriptor) (*rowDecoder, error) {
+	d := &rowDecoder{desc: desc}
+
+	colIdxMap := make(map[sqlbase.ColumnID]int, len(desc.Columns))
+	for i, c := range desc.Columns {
+		colIdxMap[c.ID] = i
+	}
+	var valNeededForCol util.FastIntSet
+	valNeededForCol.AddRange(0, len(desc.Columns)-1)
+	if err := d.rf.Init(
+		false /* reverse */, false /* returnRangeInfo */, &d.alloc,
+		sqlbase.MultiRowFetcherTableArgs{
+			Desc:            desc,
+			Index:           &desc.PrimaryIndex,
+			ColIdxMap:       colIdxMap,
+			Cols:            desc.Columns,
+			ValNeededForCol: valNeededForCol,
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	pkTypes, err := sqlbase.GetColumnTypes(desc, desc.PrimaryIndex.ColumnIDs)
+	if err != nil {
+		return nil, err
+	}
+	d.pkTypes = pkTypes
+	d.pkDirs = make([]encoding.Direction, len(desc.PrimaryIndex.ColumnDirections))
+	for i, dir := range desc.PrimaryIndex.ColumnDirections {
+		if d.pkDirs[i], err = dir.ToEncodingDirection(); err != nil {
+			return nil, err
+		}
+	}
+	d.pkColIdx = make([]int, len(desc.PrimaryIndex.ColumnIDs))
+	for i, id := range desc.PrimaryIndex.ColumnIDs {
+		d.pkColIdx[i] = colIdxMap[id]
+	}
+	d.pkVals = make([]sqlbase.EncDatum, len(desc.PrimaryIndex.ColumnIDs))
+	return d, nil
+}
+
+// decode decodes a key/value of the primary index of the table into the
+// change of a row.
+func (d *rowDecoder) decode(ctx context.Context, kv roachpb.KeyValue) (rowChange, error) {
+	change := rowChange{table: d.desc, updated: kv.Value.Timestamp}
+	if !kv.Value.IsPresent() {
+		// The row was deleted: only its primary key is known.
+		_, matches, err := sqlbase.DecodeIndexKey(
+			d.desc, &d.desc.PrimaryIndex, d.pkTypes, d.pkVals, d.pkDirs, kv.Key,
+		)
+		if err != nil {
+			return rowChange{}, err
+		}
+		if !matches {
+			return rowChange{}, errors.Errorf("key %s does not match table %s", kv.Key, d.desc.Name)
+		}
+		change.pk = make(tree.Datums, len(d.pkVals))
+		for i := range d.pkVals {
+			if err := d.pkVals[i].EnsureDecoded(&d.pkTypes[i], &d.alloc); err != nil {
+				return rowChange{}, err
+			}
+			change.pk[i] = d.pkVals[i].Datum
+		}
+		return change, nil
+	}
+
+	if err := d.rf.StartScanFrom(ctx, &sqlbase.SpanKVFetcher{KVs: []roachpb.KeyValue{kv}}); err != nil {
+		return rowChange{}, err
+	}
+	row, _, _, err := d.rf.NextRowDecoded(ctx)
+	if err != nil {
+		return rowChange{}, err
+	}
+	if row == nil {
+		return rowChange{}, errors.Errorf("no row decoded from key %s", kv.Key)
+	}
+	// The row is only valid until the next call to the fetcher.
+	change.row = append(tree.Datums(nil), row...)
+	change.pk = make(tree.Datums, len(d.pkColIdx))
+	for i, idx := range d.pkColIdx {
+		change.pk[i] = change.row[idx]
+	}
+	return change, nil
+}
+
+// fetchTableDescs returns the descriptors of the tables as of the timestamp.
+func fetchTableDescs(
+	ctx context.Context, db *client.DB, ts hlc.Timestamp, ids []sqlbase.ID,
+) ([]*sqlbase.TableDescriptor, error) {
+	var descs []*sqlbase.TableDescriptor
+	err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		descs = descs[:0]
+		txn.SetFixedTimestamp(ctx, ts)
+		for _, id := range ids {
+			desc, err := sqlbase.GetTableDescFromID(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			descs = append(descs, desc)
+		}
+		return nil
+	})
+	return descs, err
+}
+
+// validateTableDescChange returns an error if the rows of a table whose
+// descriptor changed from oldDesc to newDesc can't be decoded and encoded the
+// same way with either descriptor, in which case the rows emitted since the
+// change, decoded with oldDesc, may be wrong. Changes which don't touch the
+// name, the columns or the primary key of the table, like adding an index,
+// are allowed.
+func validateTableDescChange(oldDesc, newDesc *sqlbase.TableDescriptor) error {
+	if newDesc.Dropped() {
+		return errors.Errorf("CHANGEFEED target table %s was dropped", oldDesc.Name)
+	}
+	if err := validateChangefeedTable(newDesc); err != nil {
+		return err
+	}
+	unsupported := func(what string) error {
+		return errors.Errorf(
+			"CHANGEFEED cannot follow the schema change of table %s from version %d to %d: %s",
+			oldDesc.Name, oldDesc.Version, newDesc.Version, what)
+	}
+	if newDesc.Name != oldDesc.Name {
+		return unsupported("the table was renamed")
+	}
+	if len(newDesc.Columns) != len(oldDesc.Columns) {
+		return unsupported("columns were added or dropped")
+	}
+	for i := range newDesc.Columns {
+		oldCol, newCol := &oldDesc.Columns[i], &newDesc.Columns[i]
+		if newCol.ID != oldCol.ID || newCol.Name != oldCol.Name || !newCol.Type.Equal(oldCol.Type) {
+			return unsupported(fmt.Sprintf("column %s was altered", oldCol.Name))
+		}
+	}
+	for _, m := range newDesc.Mutations {
+		if m.GetColumn() != nil {
+			return unsupported("columns are being added or dropped")
+		}
+	}
+	oldPK, newPK := &oldDesc.PrimaryIndex, &newDesc.PrimaryIndex
+	if newPK.ID != oldPK.ID || len(newPK.ColumnIDs) != len(oldPK.ColumnIDs) {
+		return unsupported("the primary key was altered")
+	}
+	for i := range newPK.ColumnIDs {
+		if newPK.ColumnIDs[i] != oldPK.ColumnIDs[i] {
+			return unsupported("the primary key was altered")
+		}
+	}
+	return nil
+}
+
+// runChangefeed emits the changes to the rows of the tables of a changefeed
+// committed above its high-water timestamp into the sink, until the context
+// is canceled or an error is encountered, which is returned.
+//
+// Each time the resolved timestamp of all the watched spans advances, the
+// descriptors of the tables are fetched as of the resolved timestamp. A table
+// whose schema changed is reloaded if its rows are decoded the same way,
+// otherwise the changefeed fails. Then the sink is flushed, the checkpoint
+// function, if any, is called with the new resolved timestamp, and the
+// resolved timestamp is emitted into the sink.
+func runChangefeed(
+	ctx context.Context,
+	db *client.DB,
+	ds *kv.DistSender,
+	details jobs.ChangefeedDetails,
+	sink Sink,
+	checkpoint func(context.Context, hlc.Timestamp) error,
+) error {
+	decoders := make(map[sqlbase.ID]*rowDecoder, len(details.TableDescs))
+	tableIDs := make([]sqlbase.ID, 0, len(details.TableDescs))
+	spans := make([]roachpb.Span, 0, len(details.TableDescs))
+	for i := range details.TableDescs {
+		desc := &details.TableDescs[i]
+		d, err := makeRowDecoder(desc)
+		if err != nil {
+			return err
+		}
+		decoders[desc.ID] = d
+		tableIDs = append(tableIDs, desc.ID)
+		spans = append(spans, desc.PrimaryIndexSpan())
+	}
+	frontier := makeSpanFrontier(details.HighWater, spans...)
+
+	eventC := make(chan *roachpb.RangeFeedEvent, 16)
+	g, gCtx := errgroup.WithContext(ctx)
+	for _, span := range spans {
+		req := &roachpb.RangeFeedRequest{
+			Header: roachpb.Header{Timestamp: details.HighWater},
+			Span:   span,
+		}
+		g.Go(func() error {
+			return ds.RangeFeed(gCtx, req, eventC).GoError()
+		})
+	}
+	g.Go(func() error {
+		for {
+			var event *roachpb.RangeFeedEvent
+			select {
+			case <-gCtx.Done():
+				return gCtx.Err()
+			case event = <-eventC:
+			}
+
+			switch t := event.GetValue().(type) {
+			case *roachpb.RangeFeedValue:
+				_, tableID, err := keys.DecodeTablePrefix(t.Key)
+				if err != nil {
+					return err
+				}
+				d, ok := decoders[sqlbase.ID(tableID)]
+				if !ok {
+					return errors.Errorf("unexpected key %s", t.Key)
+				}
+				change, err := d.decode(gCtx, roachpb.KeyValue{Key: t.Key, Value: t.Value})
+				if err != nil {
+					return err
+				}
+				key, value, err := encodeRowChange(change)
+				if err != nil {
+					return err
+				}
+				if err := sink.EmitRow(gCtx, change.table, key, value); err != nil {
+					return err
+				}
+			case *roachpb.RangeFeedCheckpoint:
+				if !frontier.Forward(t.Span, t.ResolvedTS) {
+					continue
+				}
+				resolved := frontier.Frontier()
+				descs, err := fetchTableDescs(gCtx, db, resolved, tableIDs)
+				if err != nil {
+					return errors.Wrapf(err, "fetching the descriptors of the tables as of %s", resolved)
+				}
+				for _, desc := range descs {
+					d := decoders[desc.ID]
+					if desc.Version == d.desc.Version {
+						continue
+					}
+					if err := validateTableDescChange(d.desc, desc); err != nil {
+						return err
+					}
+					if decoders[desc.ID], err = makeRowDecoder(desc); err != nil {
+						return err
+					}
+				}
+				if err := sink.Flush(gCtx); err != nil {
+					return err
+				}
+				if checkpoint != nil {
+					if err := checkpoint(gCtx, resolved); err != nil {
+						return err
+					}
+				}
+				if err := sink.EmitResolvedTimestamp(gCtx, encodeResolvedTimestamp(resolved)); err != nil {
+					return err
+				}
+			}
+		}
+	})
+	return g.Wait()
+}
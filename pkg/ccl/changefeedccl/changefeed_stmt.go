@@ -0,0 +1,286 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/sqlccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+const (
+	// changefeedOptCursor is the option holding the timestamp above which the
+	// changes are emitted, in the format of AS OF SYSTEM TIME. It defaults to
+	// the time of the statement.
+	changefeedOptCursor = "cursor"
+)
+
+var changefeedOptionExpectValues = map[string]bool{
+	changefeedOptCursor: true,
+}
+
+// changefeedPlanHook implements sql.PlanHookFn.
+func changefeedPlanHook(
+	stmt tree.Statement, p sql.PlanHookState,
+) (func(context.Context, chan<- tree.Datums) error, sqlbase.ResultColumns, error) {
+	changefeedStmt, ok := stmt.(*tree.CreateChangefeed)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	if err := p.RequireSuperUser("CHANGEFEED"); err != nil {
+		return nil, nil, err
+	}
+	if !storage.RangefeedEnabled.Get(&p.ExecCfg().Settings.SV) {
+		return nil, nil, errors.Errorf("CHANGEFEED requires the %s setting", "kv.rangefeed.enabled")
+	}
+
+	var sinkURIFn func() (string, error)
+	var header sqlbase.ResultColumns
+	if changefeedStmt.SinkURI == nil {
+		header = sqlbase.ResultColumns{
+			{Name: "table", Typ: types.String},
+			{Name: "key", Typ: types.Bytes},
+			{Name: "value", Typ: types.Bytes},
+		}
+	} else {
+		if err := utilccl.CheckEnterpriseEnabled(
+			p.ExecCfg().Settings, p.ExecCfg().ClusterID(), p.ExecCfg().Organization(),
+			"CHANGEFEED",
+		); err != nil {
+			return nil, nil, err
+		}
+		var err error
+		sinkURIFn, err = p.TypeAsString(changefeedStmt.SinkURI, "CREATE CHANGEFEED")
+		if err != nil {
+			return nil, nil, err
+		}
+		header = sqlbase.ResultColumns{
+			{Name: "job_id", Typ: types.Int},
+		}
+	}
+
+	optsFn, err := p.TypeAsStringOpts(changefeedStmt.Options, changefeedOptionExpectValues)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fn := func(ctx context.Context, resultsCh chan<- tree.Datums) error {
+		ctx, span := tracing.ChildSpan(ctx, stmt.StatementTag())
+		defer tracing.FinishSpan(span)
+
+		opts, err := optsFn()
+		if err != nil {
+			return err
+		}
+
+		highWater := p.ExecCfg().Clock.Now()
+		if cursor, ok := opts[changefeedOptCursor]; ok {
+			asOf := tree.AsOfClause{Expr: tree.NewStrVal(cursor)}
+			var err error
+			if highWater, err = sql.EvalAsOfTimestamp(nil, asOf, highWater); err != nil {
+				return err
+			}
+		}
+
+		if len(changefeedStmt.Targets.Databases) > 0 {
+			return errors.Errorf("CHANGEFEED cannot target databases, only tables")
+		}
+		if err := changefeedStmt.Targets.NormalizeTablesWithDatabase(p.EvalContext().Database); err != nil {
+			return err
+		}
+		targetDescs, _, err := sqlccl.ResolveTargetsToDescriptors(
+			ctx, p, highWater, changefeedStmt.Targets,
+		)
+		if err != nil {
+			return err
+		}
+		details := jobs.ChangefeedDetails{
+			Opts:      opts,
+			HighWater: highWater,
+		}
+		var descriptorIDs []sqlbase.ID
+		for _, desc := range targetDescs {
+			tableDesc := desc.GetTable()
+			if tableDesc == nil {
+				continue
+			}
+			if err := validateChangefeedTable(tableDesc); err != nil {
+				return err
+			}
+			if err := p.CheckPrivilege(tableDesc, privilege.SELECT); err != nil {
+				return err
+			}
+			details.TableDescs = append(details.TableDescs, *tableDesc)
+			descriptorIDs = append(descriptorIDs, tableDesc.ID)
+		}
+
+		if sinkURIFn == nil {
+			// Without a sink, the changes are returned as the results of the
+			// statement until it is canceled.
+			return runChangefeed(
+				ctx, p.ExecCfg().DB, p.ExecCfg().DistSender, details, &channelSink{resultsCh: resultsCh},
+				nil, /* checkpoint */
+			)
+		}
+
+		sinkURI, err := sinkURIFn()
+		if err != nil {
+			return err
+		}
+		details.SinkURI = sinkURI
+		// Check that the sink can be opened before starting the job.
+		sink, err := getSink(ctx, sinkURI, p.ExecCfg().Settings)
+		if err != nil {
+			return err
+		}
+		if err := sink.Close(); err != nil {
+			return err
+		}
+
+		description, err := changefeedJobDescription(changefeedStmt, sinkURI)
+		if err != nil {
+			return err
+		}
+		job := p.ExecCfg().JobRegistry.NewJob(jobs.Record{
+			Description:   description,
+			Username:      p.User(),
+			DescriptorIDs: descriptorIDs,
+			Details:       details,
+		})
+
+		// The job runs until it is canceled, so it outlives the statement.
+		stopper := p.ExecCfg().RPCContext.Stopper
+		jobCtx := stopper.WithCancel(p.ExecCfg().AmbientCtx.AnnotateCtx(context.Background()))
+		jobCtx, cancel := context.WithCancel(jobCtx)
+		if err := job.Created(jobCtx, cancel); err != nil {
+			cancel()
+			return err
+		}
+		if err := job.Started(jobCtx); err != nil {
+			cancel()
+			return err
+		}
+		if err := stopper.RunAsyncTask(jobCtx, "changefeed", func(ctx context.Context) {
+			defer cancel()
+			err := runChangefeedJob(ctx, job, p.ExecCfg().Settings)
+			if err := job.FinishedWith(ctx, err); err != nil {
+				log.Errorf(ctx, "job %d: ignoring FinishedWith error: %+v", *job.ID(), err)
+			}
+		}); err != nil {
+			cancel()
+			return err
+		}
+
+		resultsCh <- tree.Datums{tree.NewDInt(tree.DInt(*job.ID()))}
+		return nil
+	}
+	return fn, header, nil
+}
+
+// validateChangefeedTable returns an error if the changes to the table can't
+// be emitted by a changefeed.
+func validateChangefeedTable(tableDesc *sqlbase.TableDescriptor) error {
+	if tableDesc.IsView() {
+		return errors.Errorf("CHANGEFEED cannot target views: %s", tableDesc.Name)
+	}
+	if tableDesc.IsSequence() {
+		return errors.Errorf("CHANGEFEED cannot target sequences: %s", tableDesc.Name)
+	}
+	if tableDesc.IsInterleaved() {
+		return errors.Errorf("CHANGEFEED cannot target interleaved tables: %s", tableDesc.Name)
+	}
+	if len(tableDesc.Families) != 1 {
+		return errors.Errorf(
+			"CHANGEFEED cannot target tables with %d column families: %s",
+			len(tableDesc.Families), tableDesc.Name,
+		)
+	}
+	return nil
+}
+
+func changefeedJobDescription(changefeed *tree.CreateChangefeed, sinkURI string) (string, error) {
+	sinkURI, err := storageccl.SanitizeExportStorageURI(sinkURI)
+	if err != nil {
+		return "", err
+	}
+	c := &tree.CreateChangefeed{
+		Targets: changefeed.Targets,
+		SinkURI: tree.NewDString(sinkURI),
+		Options: changefeed.Options,
+	}
+	return tree.AsStringWithFlags(c, tree.FmtSimpleQualified), nil
+}
+
+// runChangefeedJob runs the changefeed of the job into its sink, recording
+// each resolved timestamp as the high-water timestamp of the job, so that it
+// resumes from there.
+func runChangefeedJob(ctx context.Context, job *jobs.Job, settings *cluster.Settings) error {
+	details := job.Record.Details.(jobs.ChangefeedDetails)
+	sink, err := getSink(ctx, details.SinkURI, settings)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sink.Close(); err != nil {
+			log.Warningf(ctx, "failed to close changefeed sink: %+v", err)
+		}
+	}()
+
+	checkpoint := func(ctx context.Context, resolved hlc.Timestamp) error {
+		return job.Progressed(ctx, 0, func(ctx context.Context, details interface{}) {
+			switch d := details.(type) {
+			case *jobs.Payload_Changefeed:
+				d.Changefeed.HighWater = resolved
+			default:
+				log.Errorf(ctx, "job payload had unexpected type %T", d)
+			}
+		})
+	}
+	return runChangefeed(ctx, job.DB(), job.DistSender(), details, sink, checkpoint)
+}
+
+func changefeedResumeHook(
+	typ jobs.Type, settings *cluster.Settings,
+) func(context.Context, *jobs.Job) error {
+	if typ != jobs.TypeChangefeed {
+		return nil
+	}
+
+	return func(ctx context.Context, job *jobs.Job) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if err := job.Created(ctx, cancel); err != nil {
+			return err
+		}
+		if err := job.Started(ctx); err != nil {
+			return err
+		}
+		return runChangefeedJob(ctx, job, settings)
+	}
+}
+
+func init() {
+	sql.AddPlanHook(changefeedPlanHook)
+	jobs.AddResumeHook(changefeedResumeHook)
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestChangefeed(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, dirCleanupFn := testutils.TempDir(t)
+	defer dirCleanupFn()
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer s.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.foo (a INT PRIMARY KEY, b STRING)`)
+	sqlDB.Exec(t, `CREATE VIEW d.v AS SELECT a FROM d.foo`)
+
+	expectErr := func(expected, query string) {
+		t.Helper()
+		if _, err := db.Exec(query); !testutils.IsError(err, expected) {
+			t.Fatalf("expected error %q, got %v", expected, err)
+		}
+	}
+
+	// Changefeeds require rangefeeds.
+	expectErr(`requires the kv.rangefeed.enabled setting`,
+		`CREATE CHANGEFEED FOR d.foo INTO 'nodelocal:///feed'`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING kv.rangefeed.enabled = true`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING kv.closed_timestamp.target_duration = '100ms'`)
+
+	expectErr(`cannot target databases`, `CREATE CHANGEFEED FOR DATABASE d INTO 'nodelocal:///feed'`)
+	expectErr(`cannot target views`, `CREATE CHANGEFEED FOR d.v INTO 'nodelocal:///feed'`)
+
+	sqlDB.Exec(t, `INSERT INTO d.foo VALUES (1, 'a'), (2, 'b')`)
+	var cursor string
+	sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&cursor)
+	sqlDB.Exec(t, `UPSERT INTO d.foo VALUES (2, 'c'), (3, 'd')`)
+	sqlDB.Exec(t, `DELETE FROM d.foo WHERE a = 1`)
+
+	var jobID int64
+	sqlDB.QueryRow(t,
+		`CREATE CHANGEFEED FOR d.foo INTO 'nodelocal:///feed' WITH cursor=$1`, cursor,
+	).Scan(&jobID)
+
+	// The changes above the cursor are emitted, followed by a resolved
+	// timestamp.
+	expected := []string{
+		`{"after": null, "key": [1]}`,
+		`{"after": {"a": 2, "b": "c"}, "key": [2]}`,
+		`{"after": {"a": 3, "b": "d"}, "key": [3]}`,
+	}
+	testutils.SucceedsSoon(t, func() error {
+		rows, resolved, err := readFeed(filepath.Join(dir, "feed"))
+		if err != nil {
+			return err
+		}
+		if !resolved {
+			return errors.New("no resolved timestamp emitted")
+		}
+		if strings.Join(rows, "\n") != strings.Join(expected, "\n") {
+			return errors.Errorf("expected rows %v, got %v", expected, rows)
+		}
+		return nil
+	})
+
+	sqlDB.Exec(t, `CANCEL JOB $1`, jobID)
+}
+
+// TestChangefeedSchemaChange verifies that a changefeed follows the schema
+// changes of its tables which don't alter how their rows are emitted, and
+// fails on the others.
+func TestChangefeedSchemaChange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, dirCleanupFn := testutils.TempDir(t)
+	defer dirCleanupFn()
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer s.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING kv.rangefeed.enabled = true`)
+	sqlDB.Exec(t, `SET CLUSTER SETTING kv.closed_timestamp.target_duration = '100ms'`)
+	sqlDB.Exec(t, `CREATE DATABASE d`)
+	sqlDB.Exec(t, `CREATE TABLE d.foo (a INT PRIMARY KEY, b STRING)`)
+	sqlDB.Exec(t, `INSERT INTO d.foo VALUES (1, 'a')`)
+
+	var jobID int64
+	sqlDB.QueryRow(t, `CREATE CHANGEFEED FOR d.foo INTO 'nodelocal:///feed'`).Scan(&jobID)
+
+	expectRows := func(expected ...string) {
+		t.Helper()
+		testutils.SucceedsSoon(t, func() error {
+			rows, _, err := readFeed(filepath.Join(dir, "feed"))
+			if err != nil {
+				return err
+			}
+			if strings.Join(rows, "\n") != strings.Join(expected, "\n") {
+				return errors.Errorf("expected rows %v, got %v", expected, rows)
+			}
+			return nil
+		})
+	}
+	jobStatus := func() (status, errStr string) {
+		sqlDB.QueryRow(t,
+			`SELECT status, error FROM crdb_internal.jobs WHERE id = $1`, jobID,
+		).Scan(&status, &errStr)
+		return status, errStr
+	}
+
+	// Adding an index doesn't change the rows of the table.
+	sqlDB.Exec(t, `CREATE INDEX ON d.foo (b)`)
+	sqlDB.Exec(t, `INSERT INTO d.foo VALUES (2, 'b')`)
+	expectRows(
+		`{"after": {"a": 1, "b": "a"}, "key": [1]}`,
+		`{"after": {"a": 2, "b": "b"}, "key": [2]}`,
+	)
+	if status, errStr := jobStatus(); status != "running" {
+		t.Fatalf("expected the changefeed to be running, got %s: %s", status, errStr)
+	}
+
+	// Adding a column does.
+	sqlDB.Exec(t, `ALTER TABLE d.foo ADD COLUMN c INT`)
+	testutils.SucceedsSoon(t, func() error {
+		status, errStr := jobStatus()
+		if status != "failed" {
+			return errors.Errorf("expected the changefeed to fail, got %s", status)
+		}
+		if expected := "cannot follow the schema change of table foo"; !strings.Contains(errStr, expected) {
+			t.Fatalf("expected error %q, got %q", expected, errStr)
+		}
+		return nil
+	})
+}
+
+// readFeed returns the rows emitted into the directory, without their updated
+// timestamps and sorted, and whether a resolved timestamp was emitted.
+func readFeed(dir string) (rows []string, resolved bool, _ error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".RESOLVED") {
+			resolved = true
+			continue
+		}
+		contents, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, false, err
+		}
+		for _, row := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+			if idx := strings.Index(row, `, "updated"`); idx >= 0 {
+				row = row[:idx] + "}"
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.Strings(rows)
+	return rows, resolved, nil
+}
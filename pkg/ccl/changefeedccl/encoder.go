@@ -0,0 +1,78 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+)
+
+const (
+	jsonAfterField    = "after"
+	jsonKeyField      = "key"
+	jsonUpdatedField  = "updated"
+	jsonResolvedField = "resolved"
+)
+
+// encodeRowChange encodes a row change into JSON. The key is the array of the
+// primary key values of the row. The value is an object holding the key, the
+// values of all the columns of the row after the change, or null if the row
+// was deleted, and the MVCC timestamp of the change:
+//
+//	{"after": {"a": 1, "b": "foo"}, "key": [1], "updated": "1530000000000000000.0000000000"}
+func encodeRowChange(change rowChange) (key, value []byte, _ error) {
+	keyBuilder := json.NewArrayBuilder(len(change.pk))
+	for _, d := range change.pk {
+		j, err := tree.AsJSON(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyBuilder.Add(j)
+	}
+	keyJSON := keyBuilder.Build()
+
+	var after json.JSON = json.NullJSONValue
+	if change.row != nil {
+		afterBuilder := json.NewObjectBuilder(len(change.row))
+		for i, col := range change.table.Columns {
+			j, err := tree.AsJSON(change.row[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			afterBuilder.Add(col.Name, j)
+		}
+		after = afterBuilder.Build()
+	}
+
+	valueBuilder := json.NewObjectBuilder(3)
+	valueBuilder.Add(jsonAfterField, after)
+	valueBuilder.Add(jsonKeyField, keyJSON)
+	valueBuilder.Add(jsonUpdatedField, json.FromString(timestampString(change.updated)))
+	return []byte(keyJSON.String()), []byte(valueBuilder.Build().String()), nil
+}
+
+// encodeResolvedTimestamp encodes a resolved timestamp into JSON, as an object
+// holding the timestamp:
+//
+//	{"resolved": "1530000000000000000.0000000000"}
+//
+// All the changes committed at or below a resolved timestamp have been emitted
+// before it.
+func encodeResolvedTimestamp(resolved hlc.Timestamp) []byte {
+	b := json.NewObjectBuilder(1)
+	b.Add(jsonResolvedField, json.FromString(timestampString(resolved)))
+	return []byte(b.Build().String())
+}
+
+// timestampString formats a timestamp as a decimal, as accepted by the cursor
+// option of changefeeds and AS OF SYSTEM TIME.
+func timestampString(ts hlc.Timestamp) string {
+	return tree.TimestampToDecimal(ts).Decimal.String()
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// Sink is an abstraction for anything that a changefeed may emit into.
+type Sink interface {
+	// EmitRow enqueues a row message for delivery on the sink. An error may be
+	// returned if a previously enqueued message has failed.
+	EmitRow(ctx context.Context, table *sqlbase.TableDescriptor, key, value []byte) error
+	// EmitResolvedTimestamp delivers a resolved timestamp message on the sink.
+	// It is only called after a Flush, so all the rows changed at or below the
+	// timestamp have been delivered before it.
+	EmitResolvedTimestamp(ctx context.Context, payload []byte) error
+	// Flush blocks until every message enqueued by EmitRow has been delivered.
+	Flush(ctx context.Context) error
+	// Close releases the resources of the sink. It does not guarantee the
+	// delivery of the outstanding messages.
+	Close() error
+}
+
+// getSink returns the Sink for the given URI, which is any location supported
+// by ExportStorage.
+func getSink(ctx context.Context, sinkURI string, settings *cluster.Settings) (Sink, error) {
+	conf, err := storageccl.ExportStorageConfFromURI(sinkURI)
+	if err != nil {
+		return nil, err
+	}
+	es, err := storageccl.MakeExportStorage(ctx, conf, settings)
+	if err != nil {
+		return nil, err
+	}
+	return newExportStorageSink(es), nil
+}
+
+// exportStorageSink emits the changes of each table as newline-delimited JSON
+// files, and resolved timestamps as RESOLVED files, into an ExportStorage.
+//
+// The files are named <prefix>-<seq>-<table>.ndjson and
+// <prefix>-<seq>.RESOLVED, where the prefix is unique to each run of the
+// changefeed and the sequence number increases with each flush, so that
+// sorting the file names orders the files of each run. A file of rows may hold
+// changes above the following resolved timestamp, and the changes of a run
+// may be emitted again by the next run of the changefeed.
+type exportStorageSink struct {
+	es     storageccl.ExportStorage
+	prefix string
+	seq    int
+
+	// The values of the rows emitted since the last flush, by table.
+	rows map[string]*bytes.Buffer
+}
+
+func newExportStorageSink(es storageccl.ExportStorage) *exportStorageSink {
+	return &exportStorageSink{
+		es:     es,
+		prefix: fmt.Sprintf("%020d", timeutil.Now().UnixNano()),
+		rows:   make(map[string]*bytes.Buffer),
+	}
+}
+
+// EmitRow implements the Sink interface.
+func (s *exportStorageSink) EmitRow(
+	_ context.Context, table *sqlbase.TableDescriptor, _, value []byte,
+) error {
+	buf, ok := s.rows[table.Name]
+	if !ok {
+		buf = &bytes.Buffer{}
+		s.rows[table.Name] = buf
+	}
+	buf.Write(value)
+	buf.WriteByte('\n')
+	return nil
+}
+
+// EmitResolvedTimestamp implements the Sink interface.
+func (s *exportStorageSink) EmitResolvedTimestamp(ctx context.Context, payload []byte) error {
+	name := fmt.Sprintf("%s-%08d.RESOLVED", s.prefix, s.seq)
+	s.seq++
+	return s.es.WriteFile(ctx, name, bytes.NewReader(payload))
+}
+
+// Flush implements the Sink interface.
+func (s *exportStorageSink) Flush(ctx context.Context) error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+	tables := make([]string, 0, len(s.rows))
+	for table := range s.rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		name := fmt.Sprintf("%s-%08d-%s.ndjson", s.prefix, s.seq, table)
+		if err := s.es.WriteFile(ctx, name, bytes.NewReader(s.rows[table].Bytes())); err != nil {
+			return err
+		}
+	}
+	s.seq++
+	s.rows = make(map[string]*bytes.Buffer)
+	return nil
+}
+
+// Close implements the Sink interface.
+func (s *exportStorageSink) Close() error {
+	return s.es.Close()
+}
+
+// channelSink emits the changes as rows of the results of an EXPERIMENTAL
+// CHANGEFEED statement, with the columns (table, key, value). The rows of
+// resolved timestamps have NULL table and key columns.
+type channelSink struct {
+	resultsCh chan<- tree.Datums
+}
+
+// EmitRow implements the Sink interface.
+func (s *channelSink) EmitRow(
+	ctx context.Context, table *sqlbase.TableDescriptor, key, value []byte,
+) error {
+	return s.emit(ctx, tree.Datums{
+		tree.NewDString(table.Name),
+		tree.NewDBytes(tree.DBytes(key)),
+		tree.NewDBytes(tree.DBytes(value)),
+	})
+}
+
+// EmitResolvedTimestamp implements the Sink interface.
+func (s *channelSink) EmitResolvedTimestamp(ctx context.Context, payload []byte) error {
+	return s.emit(ctx, tree.Datums{tree.DNull, tree.DNull, tree.NewDBytes(tree.DBytes(payload))})
+}
+
+func (s *channelSink) emit(ctx context.Context, row tree.Datums) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s.resultsCh <- row:
+		return nil
+	}
+}
+
+// Flush implements the Sink interface.
+func (s *channelSink) Flush(_ context.Context) error {
+	return nil
+}
+
+// Close implements the Sink interface.
+func (s *channelSink) Close() error {
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+type spanFrontierEntry struct {
+	span roachpb.Span
+	ts   hlc.Timestamp
+}
+
+// spanFrontier tracks the timestamps of a set of spans, each part of which
+// can be forwarded independently, and the minimum of these timestamps: the
+// frontier.
+type spanFrontier struct {
+	// The tracked spans, sorted and non-overlapping, with their timestamps.
+	// Adjacent spans with the same timestamp are merged.
+	entries []spanFrontierEntry
+}
+
+// makeSpanFrontier returns a spanFrontier tracking the given non-overlapping
+// spans at the given timestamp.
+func makeSpanFrontier(ts hlc.Timestamp, spans ...roachpb.Span) *spanFrontier {
+	f := &spanFrontier{}
+	for _, span := range spans {
+		f.entries = append(f.entries, spanFrontierEntry{span: span, ts: ts})
+	}
+	sort.Slice(f.entries, func(i, j int) bool {
+		return f.entries[i].span.Key.Compare(f.entries[j].span.Key) < 0
+	})
+	f.entries = mergeSpanFrontierEntries(f.entries)
+	return f
+}
+
+// Frontier returns the minimum timestamp of the tracked spans.
+func (f *spanFrontier) Frontier() hlc.Timestamp {
+	var frontier hlc.Timestamp
+	for i, e := range f.entries {
+		if i == 0 || e.ts.Less(frontier) {
+			frontier = e.ts
+		}
+	}
+	return frontier
+}
+
+// Forward advances the timestamp of the tracked parts of the span to at least
+// the given timestamp, and returns whether the frontier advanced.
+func (f *spanFrontier) Forward(span roachpb.Span, ts hlc.Timestamp) bool {
+	prev := f.Frontier()
+	entries := make([]spanFrontierEntry, 0, len(f.entries)+2)
+	for _, e := range f.entries {
+		if !e.span.Overlaps(span) || !e.ts.Less(ts) {
+			entries = append(entries, e)
+			continue
+		}
+		// Split the entry into the parts before, within and after the span.
+		overlap := e.span
+		if overlap.Key.Compare(span.Key) < 0 {
+			entries = append(entries, spanFrontierEntry{
+				span: roachpb.Span{Key: e.span.Key, EndKey: span.Key}, ts: e.ts,
+			})
+			overlap.Key = span.Key
+		}
+		var after *spanFrontierEntry
+		if span.EndKey.Compare(overlap.EndKey) < 0 {
+			after = &spanFrontierEntry{
+				span: roachpb.Span{Key: span.EndKey, EndKey: e.span.EndKey}, ts: e.ts,
+			}
+			overlap.EndKey = span.EndKey
+		}
+		entries = append(entries, spanFrontierEntry{span: overlap, ts: ts})
+		if after != nil {
+			entries = append(entries, *after)
+		}
+	}
+	f.entries = mergeSpanFrontierEntries(entries)
+	return prev.Less(f.Frontier())
+}
+
+// mergeSpanFrontierEntries merges the adjacent entries with the same
+// timestamp of the sorted entries.
+func mergeSpanFrontierEntries(entries []spanFrontierEntry) []spanFrontierEntry {
+	merged := entries[:0]
+	for _, e := range entries {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.ts == e.ts && last.span.EndKey.Equal(e.span.Key) {
+				last.span.EndKey = e.span.EndKey
+				continue
+			}
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestSpanFrontier(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ts := func(wallTime int64) hlc.Timestamp { return hlc.Timestamp{WallTime: wallTime} }
+	span := func(key, endKey string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(key), EndKey: roachpb.Key(endKey)}
+	}
+	f := makeSpanFrontier(ts(1), span("a", "c"), span("d", "f"))
+
+	expect := func(advanced, expAdvanced bool, expTS hlc.Timestamp) {
+		t.Helper()
+		if advanced != expAdvanced {
+			t.Fatalf("expected advanced=%t, got %t", expAdvanced, advanced)
+		}
+		if f.Frontier() != expTS {
+			t.Fatalf("expected frontier %s, got %s", expTS, f.Frontier())
+		}
+	}
+
+	// Forwarding part of the spans doesn't advance the frontier.
+	expect(f.Forward(span("a", "b"), ts(3)), false, ts(1))
+	expect(f.Forward(span("b", "c"), ts(2)), false, ts(1))
+	// Forwarding the rest of the spans does, up to the minimum timestamp.
+	expect(f.Forward(span("c", "f"), ts(4)), true, ts(2))
+	// Backward updates are ignored.
+	expect(f.Forward(span("a", "f"), ts(1)), false, ts(2))
+	// Spans overlapping several entries forward all of them.
+	expect(f.Forward(span("a", "z"), ts(5)), true, ts(5))
+	if len(f.entries) != 2 {
+		t.Fatalf("expected the entries to be merged, got %+v", f.entries)
+	}
+}
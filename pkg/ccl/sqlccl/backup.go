@@ -363,7 +363,10 @@ func writeBackupDescriptor(
 	return exportStore.WriteFile(ctx, filename, bytes.NewReader(descBuf))
 }
 
-func resolveTargetsToDescriptors(
+// ResolveTargetsToDescriptors returns the descriptors matching the targets as
+// of the given timestamp, along with the IDs of the databases whose tables are
+// all included.
+func ResolveTargetsToDescriptors(
 	ctx context.Context, p sql.PlanHookState, endTime hlc.Timestamp, targets tree.TargetList,
 ) ([]sqlbase.Descriptor, []sqlbase.ID, error) {
 	var err error
@@ -707,7 +710,7 @@ func backupPlanHook(
 			return err
 		}
 
-		targetDescs, completeDBs, err := resolveTargetsToDescriptors(ctx, p, endTime, backupStmt.Targets)
+		targetDescs, completeDBs, err := ResolveTargetsToDescriptors(ctx, p, endTime, backupStmt.Targets)
 		if err != nil {
 			return err
 		}
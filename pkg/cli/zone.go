@@ -182,6 +182,7 @@ The zone config format has the following YAML schema:
 
   num_replicas: <num>
   constraints: [comma-separated attribute list]
+  lease_preferences: [[comma-separated attribute list], ...]
   range_min_bytes: <size-in-bytes>
   range_max_bytes: <size-in-bytes>
   gc:
@@ -193,6 +194,12 @@ num_replicas: 3
 constraints: [ssd, -mem]
 EOF
 
+Constraints can also specify how many replicas should satisfy them, for example
+to place two replicas in one region and one in another:
+
+num_replicas: 3
+constraints: {+region=us-east1: 2, +region=us-west1: 1}
+
 Note that the specified zone config is merged with the existing zone config for
 the database or table.
 `,
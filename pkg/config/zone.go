@@ -224,8 +224,23 @@ func (c *Constraint) FromString(short string) error {
 var _ yaml.Marshaler = Constraints{}
 var _ yaml.Unmarshaler = &Constraints{}
 
-// MarshalYAML implements yaml.Marshaler.
+// MarshalYAML implements yaml.Marshaler. Constraints applying to every replica
+// are marshaled as a list, e.g. [+ssd, -region=us-west1], and per-replica
+// constraints as a map from comma-separated constraints to the number of
+// replicas which should satisfy them, e.g. {+region=us-east1: 2,
+// '+region=us-west1,+ssd': 1}.
 func (c Constraints) MarshalYAML() (interface{}, error) {
+	if len(c.PerReplica) > 0 {
+		perReplica := make(yaml.MapSlice, len(c.PerReplica))
+		for i, rc := range c.PerReplica {
+			short := make([]string, len(rc.Constraints))
+			for j, c := range rc.Constraints {
+				short[j] = c.String()
+			}
+			perReplica[i] = yaml.MapItem{Key: strings.Join(short, ","), Value: rc.NumReplicas}
+		}
+		return perReplica, nil
+	}
 	short := make([]string, len(c.Constraints))
 	for i, c := range c.Constraints {
 		short[i] = c.String()
@@ -237,7 +252,12 @@ func (c Constraints) MarshalYAML() (interface{}, error) {
 func (c *Constraints) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var shortConstraints []string
 	if err := unmarshal(&shortConstraints); err != nil {
-		return err
+		var perReplica yaml.MapSlice
+		if err := unmarshal(&perReplica); err != nil {
+			return errors.New(
+				"constraints must be a list of constraints or a map from constraints to replica counts")
+		}
+		return c.unmarshalPerReplica(perReplica)
 	}
 	constraints := make([]Constraint, len(shortConstraints))
 	for i, short := range shortConstraints {
@@ -246,6 +266,66 @@ func (c *Constraints) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		}
 	}
 	c.Constraints = constraints
+	c.PerReplica = nil
+	return nil
+}
+
+func (c *Constraints) unmarshalPerReplica(perReplica yaml.MapSlice) error {
+	replicaConstraints := make([]ReplicaConstraints, len(perReplica))
+	for i, item := range perReplica {
+		key, ok := item.Key.(string)
+		if !ok {
+			return errors.Errorf("per-replica constraints must be strings, not %v", item.Key)
+		}
+		numReplicas, ok := item.Value.(int)
+		if !ok {
+			return errors.Errorf("the number of replicas for constraints %q must be an integer, not %v",
+				key, item.Value)
+		}
+		rc := &replicaConstraints[i]
+		rc.NumReplicas = int32(numReplicas)
+		for _, short := range strings.Split(key, ",") {
+			short = strings.TrimSpace(short)
+			if short == "" {
+				continue
+			}
+			var constraint Constraint
+			if err := constraint.FromString(short); err != nil {
+				return err
+			}
+			rc.Constraints = append(rc.Constraints, constraint)
+		}
+	}
+	c.Constraints = nil
+	c.PerReplica = replicaConstraints
+	return nil
+}
+
+var _ yaml.Marshaler = LeasePreference{}
+var _ yaml.Unmarshaler = &LeasePreference{}
+
+// MarshalYAML implements yaml.Marshaler.
+func (l LeasePreference) MarshalYAML() (interface{}, error) {
+	short := make([]string, len(l.Constraints))
+	for i, c := range l.Constraints {
+		short[i] = c.String()
+	}
+	return short, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *LeasePreference) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var shortConstraints []string
+	if err := unmarshal(&shortConstraints); err != nil {
+		return err
+	}
+	constraints := make([]Constraint, len(shortConstraints))
+	for i, short := range shortConstraints {
+		if err := constraints[i].FromString(short); err != nil {
+			return err
+		}
+	}
+	l.Constraints = constraints
 	return nil
 }
 
@@ -321,9 +401,85 @@ func (z *ZoneConfig) Validate() error {
 		return fmt.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
 			z.RangeMinBytes, z.RangeMaxBytes)
 	}
+	if err := z.Constraints.validate(z.NumReplicas); err != nil {
+		return err
+	}
+	for _, leasePref := range z.LeasePreferences {
+		if len(leasePref.Constraints) == 0 {
+			return fmt.Errorf("every lease preference must include at least one constraint")
+		}
+		for _, constraint := range leasePref.Constraints {
+			if constraint.Type == Constraint_POSITIVE {
+				return fmt.Errorf("lease preference constraints must either be required " +
+					"(e.g. '+region=us-east1') or prohibited (e.g. '-region=us-west1')")
+			}
+		}
+	}
+	return nil
+}
+
+// validate returns an error if the constraints cannot be satisfied by the
+// given number of replicas, or cannot be satisfied by any store.
+func (c *Constraints) validate(numReplicas int32) error {
+	if err := validateConstraintSet(c.Constraints); err != nil {
+		return err
+	}
+	if len(c.PerReplica) == 0 {
+		return nil
+	}
+	if len(c.Constraints) > 0 {
+		return fmt.Errorf("constraints must either apply to all replicas or specify replica counts")
+	}
+	var total int32
+	for _, rc := range c.PerReplica {
+		if rc.NumReplicas <= 0 {
+			return fmt.Errorf("constraints %s must apply to at least one replica",
+				constraintsString(rc.Constraints))
+		}
+		if len(rc.Constraints) == 0 {
+			return fmt.Errorf("every replica count must have at least one constraint")
+		}
+		for _, constraint := range rc.Constraints {
+			if constraint.Type == Constraint_POSITIVE {
+				return fmt.Errorf("constraints with replica counts must either be required " +
+					"(e.g. '+region=us-east1') or prohibited (e.g. '-region=us-west1')")
+			}
+		}
+		if err := validateConstraintSet(rc.Constraints); err != nil {
+			return err
+		}
+		total += rc.NumReplicas
+	}
+	if total > numReplicas {
+		return fmt.Errorf("constraints apply to %d replicas, but num_replicas is only %d",
+			total, numReplicas)
+	}
 	return nil
 }
 
+// validateConstraintSet returns an error if no store can satisfy all of the
+// constraints, because one of them is both required and prohibited.
+func validateConstraintSet(constraints []Constraint) error {
+	for i, c := range constraints {
+		for _, o := range constraints[i+1:] {
+			if c.Key == o.Key && c.Value == o.Value && c.Type != o.Type &&
+				(c.Type == Constraint_PROHIBITED || o.Type == Constraint_PROHIBITED) {
+				return fmt.Errorf("constraints %s contradict each other",
+					constraintsString([]Constraint{c, o}))
+			}
+		}
+	}
+	return nil
+}
+
+func constraintsString(constraints []Constraint) string {
+	short := make([]string, len(constraints))
+	for i, c := range constraints {
+		short[i] = c.String()
+	}
+	return "[" + strings.Join(short, ", ") + "]"
+}
+
 // DeleteTableConfig removes any configuration that applies to the table
 // targeted by this ZoneConfig, leaving only its subzone configs, if any. After
 // calling DeleteTableConfig, IsZubzonePlaceholder will return true.
@@ -63,6 +63,122 @@ func TestZoneConfigValidate(t *testing.T) {
 			},
 			"is greater than or equal to RangeMaxBytes",
 		},
+		{
+			config.ZoneConfig{
+				NumReplicas:      1,
+				RangeMaxBytes:    config.DefaultZoneConfig().RangeMaxBytes,
+				LeasePreferences: []config.LeasePreference{{}},
+			},
+			"every lease preference must include at least one constraint",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				LeasePreferences: []config.LeasePreference{
+					{Constraints: []config.Constraint{{Value: "a", Type: config.Constraint_POSITIVE}}},
+				},
+			},
+			"lease preference constraints must either be required",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				LeasePreferences: []config.LeasePreference{
+					{Constraints: []config.Constraint{{Value: "a", Type: config.Constraint_REQUIRED}}},
+					{Constraints: []config.Constraint{{Value: "b", Type: config.Constraint_PROHIBITED}}},
+				},
+			},
+			"",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{Constraints: []config.Constraint{
+					{Value: "a", Type: config.Constraint_REQUIRED},
+					{Value: "a", Type: config.Constraint_PROHIBITED},
+				}},
+			},
+			"constraints \\[\\+a, -a\\] contradict each other",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{
+					Constraints: []config.Constraint{{Value: "a", Type: config.Constraint_REQUIRED}},
+					PerReplica: []config.ReplicaConstraints{{NumReplicas: 1, Constraints: []config.Constraint{
+						{Key: "region", Value: "us-east1", Type: config.Constraint_REQUIRED},
+					}}},
+				},
+			},
+			"constraints must either apply to all replicas or specify replica counts",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{PerReplica: []config.ReplicaConstraints{
+					{NumReplicas: 0, Constraints: []config.Constraint{
+						{Key: "region", Value: "us-east1", Type: config.Constraint_REQUIRED},
+					}},
+				}},
+			},
+			"must apply to at least one replica",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{PerReplica: []config.ReplicaConstraints{
+					{NumReplicas: 1},
+				}},
+			},
+			"every replica count must have at least one constraint",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{PerReplica: []config.ReplicaConstraints{
+					{NumReplicas: 1, Constraints: []config.Constraint{{Value: "ssd", Type: config.Constraint_POSITIVE}}},
+				}},
+			},
+			"constraints with replica counts must either be required",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{PerReplica: []config.ReplicaConstraints{
+					{NumReplicas: 2, Constraints: []config.Constraint{
+						{Key: "region", Value: "us-east1", Type: config.Constraint_REQUIRED},
+					}},
+					{NumReplicas: 2, Constraints: []config.Constraint{
+						{Key: "region", Value: "us-west1", Type: config.Constraint_REQUIRED},
+					}},
+				}},
+			},
+			"constraints apply to 4 replicas, but num_replicas is only 3",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   3,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				Constraints: config.Constraints{PerReplica: []config.ReplicaConstraints{
+					{NumReplicas: 2, Constraints: []config.Constraint{
+						{Key: "region", Value: "us-east1", Type: config.Constraint_REQUIRED},
+					}},
+					{NumReplicas: 1, Constraints: []config.Constraint{
+						{Key: "region", Value: "us-west1", Type: config.Constraint_REQUIRED},
+						{Value: "ssd", Type: config.Constraint_PROHIBITED},
+					}},
+				}},
+			},
+			"",
+		},
 	}
 	for i, c := range testCases {
 		err := c.cfg.Validate()
@@ -191,6 +307,146 @@ constraints: [foo, +duck=foo, -duck=foo]
 	}
 }
 
+func TestZoneConfigMarshalYAMLLeasePreferences(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	original := config.ZoneConfig{
+		RangeMinBytes: 1,
+		RangeMaxBytes: 1,
+		GC: config.GCPolicy{
+			TTLSeconds: 1,
+		},
+		NumReplicas: 1,
+		Constraints: config.Constraints{
+			Constraints: []config.Constraint{},
+		},
+		LeasePreferences: []config.LeasePreference{
+			{
+				Constraints: []config.Constraint{
+					{
+						Type:  config.Constraint_REQUIRED,
+						Key:   "region",
+						Value: "us-east1",
+					},
+					{
+						Type:  config.Constraint_PROHIBITED,
+						Value: "mem",
+					},
+				},
+			},
+			{
+				Constraints: []config.Constraint{
+					{
+						Type:  config.Constraint_REQUIRED,
+						Key:   "region",
+						Value: "us-west1",
+					},
+				},
+			},
+		},
+	}
+
+	expected := `range_min_bytes: 1
+range_max_bytes: 1
+gc:
+  ttlseconds: 1
+num_replicas: 1
+constraints: []
+lease_preferences: [[+region=us-east1, -mem], [+region=us-west1]]
+`
+
+	body, err := yaml.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != expected {
+		t.Fatalf("yaml.Marshal(%+v) = %s; not %s", original, body, expected)
+	}
+
+	var unmarshaled config.ZoneConfig
+	if err := yaml.UnmarshalStrict(body, &unmarshaled); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&unmarshaled, &original) {
+		t.Errorf("yaml.UnmarshalStrict(%q) = %+v; not %+v", body, unmarshaled, original)
+	}
+}
+
+func TestZoneConfigMarshalYAMLPerReplicaConstraints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	original := config.ZoneConfig{
+		RangeMinBytes: 1,
+		RangeMaxBytes: 1,
+		GC: config.GCPolicy{
+			TTLSeconds: 1,
+		},
+		NumReplicas: 3,
+		Constraints: config.Constraints{
+			PerReplica: []config.ReplicaConstraints{
+				{
+					NumReplicas: 2,
+					Constraints: []config.Constraint{
+						{
+							Type:  config.Constraint_REQUIRED,
+							Key:   "region",
+							Value: "us-east1",
+						},
+					},
+				},
+				{
+					NumReplicas: 1,
+					Constraints: []config.Constraint{
+						{
+							Type:  config.Constraint_REQUIRED,
+							Key:   "region",
+							Value: "us-west1",
+						},
+						{
+							Type:  config.Constraint_PROHIBITED,
+							Value: "mem",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := `range_min_bytes: 1
+range_max_bytes: 1
+gc:
+  ttlseconds: 1
+num_replicas: 3
+constraints: {+region=us-east1: 2, '+region=us-west1,-mem': 1}
+`
+
+	body, err := yaml.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != expected {
+		t.Fatalf("yaml.Marshal(%+v) = %s; not %s", original, body, expected)
+	}
+
+	var unmarshaled config.ZoneConfig
+	if err := yaml.UnmarshalStrict(body, &unmarshaled); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&unmarshaled, &original) {
+		t.Errorf("yaml.UnmarshalStrict(%q) = %+v; not %+v", body, unmarshaled, original)
+	}
+
+	for _, invalid := range []string{
+		"constraints: {+region=us-east1: two}",
+		"constraints: +region=us-east1",
+	} {
+		var zone config.ZoneConfig
+		if err := yaml.UnmarshalStrict([]byte(invalid), &zone); err == nil {
+			t.Errorf("expected yaml.UnmarshalStrict(%q) to fail", invalid)
+		}
+	}
+}
+
 func TestZoneSpecifiers(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/rpc"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/util/grpcutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -435,8 +436,10 @@ func (ds *DistSender) sendSingleRange(
 	replicas.OptimizeReplicaOrder(ds.getNodeDescriptor())
 
 	// If this request needs to go to a lease holder and we know who that is, move
-	// it to the front.
-	if !(ba.IsReadOnly() && ba.ReadConsistency == roachpb.INCONSISTENT) {
+	// it to the front. Reads which can be served by any replica are instead
+	// sent to the closest one.
+	if !(ba.IsReadOnly() && ba.ReadConsistency == roachpb.INCONSISTENT) &&
+		!ds.canSendToFollower(ba) {
 		if storeID, ok := ds.leaseHolderCache.Lookup(ctx, desc.RangeID); ok {
 			if i := replicas.FindReplica(storeID); i >= 0 {
 				replicas.MoveToFront(i)
@@ -463,6 +466,24 @@ func (ds *DistSender) sendSingleRange(
 	return br, pErr
 }
 
+// canSendToFollower returns whether the batch can be sent to the closest
+// replica rather than to the lease holder, because it only reads data old
+// enough for its timestamp to have likely been closed on all the replicas.
+// Replicas which can't serve it redirect it to the lease holder.
+func (ds *DistSender) canSendToFollower(ba roachpb.BatchRequest) bool {
+	if !storagebase.FollowerReadsEnabled.Get(&ds.st.SV) || !storagebase.IsFollowerReadBatch(ba) {
+		return false
+	}
+	ts := storagebase.FollowerReadTimestamp(ba)
+	if ts == (hlc.Timestamp{}) {
+		return false
+	}
+	// Leave the lease holders twice the target duration to close the
+	// timestamp, as they only close timestamps periodically.
+	targetDuration := storagebase.ClosedTimestampTargetDuration.Get(&ds.st.SV)
+	return !ds.clock.Now().Add(-2*targetDuration.Nanoseconds(), 0).Less(ts)
+}
+
 // initAndVerifyBatch initializes timestamp-related information and
 // verifies batch constraints before splitting.
 func (ds *DistSender) initAndVerifyBatch(
@@ -787,10 +808,15 @@ func (ds *DistSender) divideAndSendBatchToRanges(
 			}
 			// If the request is more than but ends with EndTransaction, we
 			// want the caller to come again with the EndTransaction in an
-			// extra call.
+			// extra call. The exception is an EndTransaction which carries
+			// in-flight writes: it only stages the commit, which takes effect
+			// once all of the writes it is sent alongside have succeeded.
 			if l := len(ba.Requests) - 1; l > 0 && ba.Requests[l].GetInner().Method() == roachpb.EndTransaction {
-				responseCh <- response{pErr: errNo1PCTxn}
-				return
+				et := ba.Requests[l].GetInner().(*roachpb.EndTransactionRequest)
+				if len(et.InFlightWrites) == 0 {
+					responseCh <- response{pErr: errNo1PCTxn}
+					return
+				}
 			}
 		}
 
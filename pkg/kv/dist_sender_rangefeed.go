@@ -0,0 +1,260 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kv
+
+import (
+	"io"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+)
+
+// rangeFeedGroup tracks the goroutines running the single-range rangefeeds of
+// a DistSender.RangeFeed call, and the first error any of them returned.
+type rangeFeedGroup struct {
+	wg   sync.WaitGroup
+	errC chan *roachpb.Error
+}
+
+func (g *rangeFeedGroup) setErr(pErr *roachpb.Error) {
+	select {
+	case g.errC <- pErr:
+	default:
+	}
+}
+
+// RangeFeed divides a RangeFeed request on range boundaries and establishes a
+// RangeFeed to each of the individual ranges. It streams back results on the
+// provided channel until the context is canceled or an unrecoverable error
+// is encountered, which is returned.
+//
+// The RangeFeedCheckpoint events streamed back apply to the spans of the
+// individual ranges. Consumers must track the resolved timestamps of the
+// spans to compute the resolved timestamp of the requested span. When a
+// range is split or merged, the rangefeeds of the resulting ranges restart
+// from the last resolved timestamp of the original range, so values may be
+// streamed back more than once.
+func (ds *DistSender) RangeFeed(
+	ctx context.Context, args *roachpb.RangeFeedRequest, eventCh chan<- *roachpb.RangeFeedEvent,
+) *roachpb.Error {
+	ctx = ds.AnnotateCtx(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rs, err := keys.SpanAddr(args.Span)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+
+	g := &rangeFeedGroup{errC: make(chan *roachpb.Error, 1)}
+	if pErr := ds.divideAndSendRangeFeedToRanges(ctx, g, rs, args.Timestamp, eventCh); pErr != nil {
+		g.setErr(pErr)
+	}
+
+	var pErr *roachpb.Error
+	select {
+	case pErr = <-g.errC:
+	case <-ctx.Done():
+		pErr = roachpb.NewError(ctx.Err())
+	}
+	// Stop the rangefeeds of all the ranges, and wait for them to stop
+	// sending on the channel.
+	cancel()
+	g.wg.Wait()
+	return pErr
+}
+
+// divideAndSendRangeFeedToRanges establishes a RangeFeed to each of the
+// ranges of the span, each run by a new goroutine of the group.
+func (ds *DistSender) divideAndSendRangeFeedToRanges(
+	ctx context.Context,
+	g *rangeFeedGroup,
+	rs roachpb.RSpan,
+	ts hlc.Timestamp,
+	eventCh chan<- *roachpb.RangeFeedEvent,
+) *roachpb.Error {
+	ri := NewRangeIterator(ds)
+	for ri.Seek(ctx, rs.Key, Ascending); ri.Valid(); ri.Next(ctx) {
+		desc := ri.Desc()
+		token := ri.Token()
+		partialRS, err := rs.Intersect(desc)
+		if err != nil {
+			return roachpb.NewError(err)
+		}
+		g.wg.Add(1)
+		if err := ds.rpcContext.Stopper.RunAsyncTask(
+			ctx, "kv.DistSender: rangefeed", func(ctx context.Context) {
+				defer g.wg.Done()
+				if pErr := ds.partialRangeFeed(ctx, g, partialRS, ts, desc, token, eventCh); pErr != nil {
+					g.setErr(pErr)
+				}
+			},
+		); err != nil {
+			g.wg.Done()
+			return roachpb.NewError(err)
+		}
+		if !ri.NeedAnother(rs) {
+			return nil
+		}
+	}
+	return ri.Error()
+}
+
+// partialRangeFeed establishes a RangeFeed to the range specified by desc.
+// It manages lifecycle events of the range in order to maintain the RangeFeed
+// connection; this may involve instructing higher-level functions to retry
+// this rangefeed, or subdividing the range further in the event of a split.
+func (ds *DistSender) partialRangeFeed(
+	ctx context.Context,
+	g *rangeFeedGroup,
+	rs roachpb.RSpan,
+	ts hlc.Timestamp,
+	desc *roachpb.RangeDescriptor,
+	token *EvictionToken,
+	eventCh chan<- *roachpb.RangeFeedEvent,
+) *roachpb.Error {
+	span := rs.AsRawSpanWithNoLocals()
+	for r := retry.StartWithCtx(ctx, ds.rpcRetryOptions); r.Next(); {
+		if desc == nil {
+			var err error
+			desc, token, err = ds.getDescriptor(ctx, rs.Key, nil, false /* useReverseScan */)
+			if err != nil {
+				log.VEventf(ctx, 1, "range descriptor re-lookup failed: %s", err)
+				continue
+			}
+			if !desc.RSpan().ContainsKeyRange(rs.Key, rs.EndKey) {
+				// The range was split: establish a RangeFeed to each of the
+				// resulting ranges instead.
+				return ds.divideAndSendRangeFeedToRanges(ctx, g, rs, ts, eventCh)
+			}
+		}
+
+		maxTS, pErr := ds.singleRangeFeed(ctx, span, ts, desc, eventCh)
+		// Restart from the last resolved timestamp.
+		ts.Forward(maxTS)
+		if pErr == nil {
+			continue
+		}
+		switch t := pErr.GetDetail().(type) {
+		case *roachpb.SendError, *roachpb.RangeNotFoundError:
+			// Evict the descriptor from the cache and reload on next attempt.
+			if err := token.Evict(ctx); err != nil {
+				return roachpb.NewError(err)
+			}
+			desc = nil
+		case *roachpb.RangeKeyMismatchError:
+			// Evict the descriptor from the cache and divide the span.
+			if err := token.Evict(ctx); err != nil {
+				return roachpb.NewError(err)
+			}
+			return ds.divideAndSendRangeFeedToRanges(ctx, g, rs, ts, eventCh)
+		case *roachpb.RangeFeedRetryError:
+			switch t.Reason {
+			case roachpb.RangeFeedRetryError_REASON_RAFT_SNAPSHOT,
+				roachpb.RangeFeedRetryError_REASON_LOGICAL_OPS_MISSING,
+				roachpb.RangeFeedRetryError_REASON_SLOW_CONSUMER:
+				// Try again with the same descriptor.
+			case roachpb.RangeFeedRetryError_REASON_REPLICA_REMOVED:
+				if err := token.Evict(ctx); err != nil {
+					return roachpb.NewError(err)
+				}
+				desc = nil
+			case roachpb.RangeFeedRetryError_REASON_RANGE_SPLIT,
+				roachpb.RangeFeedRetryError_REASON_RANGE_MERGED:
+				if err := token.Evict(ctx); err != nil {
+					return roachpb.NewError(err)
+				}
+				return ds.divideAndSendRangeFeedToRanges(ctx, g, rs, ts, eventCh)
+			default:
+				log.Fatalf(ctx, "unexpected RangeFeedRetryError reason %v", t.Reason)
+			}
+		default:
+			return pErr
+		}
+	}
+	return roachpb.NewError(ctx.Err())
+}
+
+// singleRangeFeed establishes a RangeFeed to the range specified by desc,
+// trying its replicas in order of proximity. It returns the last resolved
+// timestamp of the span along with the error which ended the RangeFeed, if
+// any.
+func (ds *DistSender) singleRangeFeed(
+	ctx context.Context,
+	span roachpb.Span,
+	ts hlc.Timestamp,
+	desc *roachpb.RangeDescriptor,
+	eventCh chan<- *roachpb.RangeFeedEvent,
+) (hlc.Timestamp, *roachpb.Error) {
+	args := roachpb.RangeFeedRequest{
+		Span: span,
+		Header: roachpb.Header{
+			Timestamp: ts,
+			RangeID:   desc.RangeID,
+		},
+	}
+
+	// Any replica can serve a RangeFeed, so try the closest ones first.
+	replicas := NewReplicaSlice(ds.gossip, desc)
+	replicas.OptimizeReplicaOrder(ds.getNodeDescriptor())
+
+	for _, replica := range replicas {
+		args.Replica = replica.ReplicaDescriptor
+		conn, err := ds.rpcContext.GRPCDial(replica.NodeDesc.Address.String())
+		if err != nil {
+			log.VEventf(ctx, 2, "unable to dial n%d: %s", replica.NodeID, err)
+			continue
+		}
+		stream, err := roachpb.NewInternalClient(conn).RangeFeed(ctx, &args)
+		if err != nil {
+			log.VEventf(ctx, 2, "RPC error: %s", err)
+			continue
+		}
+		for {
+			event, err := stream.Recv()
+			if err == io.EOF {
+				return args.Timestamp, nil
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return args.Timestamp, roachpb.NewError(ctx.Err())
+				}
+				log.VEventf(ctx, 2, "RPC error: %s", err)
+				break
+			}
+			switch t := event.GetValue().(type) {
+			case *roachpb.RangeFeedCheckpoint:
+				if t.Span.Contains(args.Span) {
+					args.Timestamp.Forward(t.ResolvedTS)
+				}
+			case *roachpb.RangeFeedError:
+				log.VEventf(ctx, 2, "RangeFeedError: %s", t.Error.GoError())
+				return args.Timestamp, &t.Error
+			}
+			select {
+			case eventCh <- event:
+			case <-ctx.Done():
+				return args.Timestamp, roachpb.NewError(ctx.Err())
+			}
+		}
+	}
+	return args.Timestamp, roachpb.NewError(roachpb.NewSendError("failed to send RPC"))
+}
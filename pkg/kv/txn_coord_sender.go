@@ -56,6 +56,18 @@ var maxIntents = settings.RegisterIntSetting(
 	100000,
 )
 
+// pipelinedWritesEnabled controls whether transactional writes are
+// pipelined: instead of waiting for each batch of writes to replicate,
+// the coordinator proves that they succeeded before relying on them
+// (or at the latest when committing), and commits in parallel with the
+// final writes of the transaction.
+var pipelinedWritesEnabled = settings.RegisterBoolSetting(
+	"kv.transaction.write_pipelining_enabled",
+	"if enabled, transactional writes are pipelined through Raft consensus "+
+		"and transactions commit in parallel with their last writes",
+	true,
+)
+
 // txnMetadata holds information about an ongoing transaction, as
 // seen from the perspective of this coordinator. It records all
 // keys (and key ranges) mutated as part of the transaction for
@@ -69,6 +81,12 @@ type txnMetadata struct {
 	// to update the write intent when the transaction is committed.
 	keys []roachpb.Span
 
+	// inFlightWrites stores the point writes which were sent with
+	// asynchronous consensus and which haven't been proven to have
+	// succeeded yet. Each of them needs to be verified through a
+	// QueryIntent request before the transaction relies on it.
+	inFlightWrites []roachpb.SequencedWrite
+
 	// lastUpdateNanos is the latest wall time in nanos the client sent
 	// transaction operations to this coordinator. Accessed and updated
 	// atomically.
@@ -120,10 +138,11 @@ type TxnMetrics struct {
 	Restarts *metric.Histogram
 
 	// Counts of restart types.
-	RestartsWriteTooOld    *metric.Counter
-	RestartsDeleteRange    *metric.Counter
-	RestartsSerializable   *metric.Counter
-	RestartsPossibleReplay *metric.Counter
+	RestartsWriteTooOld       *metric.Counter
+	RestartsDeleteRange       *metric.Counter
+	RestartsSerializable      *metric.Counter
+	RestartsPossibleReplay    *metric.Counter
+	RestartsAsyncWriteFailure *metric.Counter
 }
 
 var (
@@ -157,22 +176,26 @@ var (
 	metaRestartsPossibleReplay = metric.Metadata{
 		Name: "txn.restarts.possiblereplay",
 		Help: "Number of restarts due to possible replays of command batches at the storage layer"}
+	metaRestartsAsyncWriteFailure = metric.Metadata{
+		Name: "txn.restarts.asyncwritefailure",
+		Help: "Number of restarts due to pipelined writes which failed to replicate"}
 )
 
 // MakeTxnMetrics returns a TxnMetrics struct that contains metrics whose
 // windowed portions retain data for approximately histogramWindow.
 func MakeTxnMetrics(histogramWindow time.Duration) TxnMetrics {
 	return TxnMetrics{
-		Aborts:                 metric.NewCounterWithRates(metaAbortsRates),
-		Commits:                metric.NewCounterWithRates(metaCommitsRates),
-		Commits1PC:             metric.NewCounterWithRates(metaCommits1PCRates),
-		Abandons:               metric.NewCounterWithRates(metaAbandonsRates),
-		Durations:              metric.NewLatency(metaDurationsHistograms, histogramWindow),
-		Restarts:               metric.NewHistogram(metaRestartsHistogram, histogramWindow, 100, 3),
-		RestartsWriteTooOld:    metric.NewCounter(metaRestartsWriteTooOld),
-		RestartsDeleteRange:    metric.NewCounter(metaRestartsDeleteRange),
-		RestartsSerializable:   metric.NewCounter(metaRestartsSerializable),
-		RestartsPossibleReplay: metric.NewCounter(metaRestartsPossibleReplay),
+		Aborts:                    metric.NewCounterWithRates(metaAbortsRates),
+		Commits:                   metric.NewCounterWithRates(metaCommitsRates),
+		Commits1PC:                metric.NewCounterWithRates(metaCommits1PCRates),
+		Abandons:                  metric.NewCounterWithRates(metaAbandonsRates),
+		Durations:                 metric.NewLatency(metaDurationsHistograms, histogramWindow),
+		Restarts:                  metric.NewHistogram(metaRestartsHistogram, histogramWindow, 100, 3),
+		RestartsWriteTooOld:       metric.NewCounter(metaRestartsWriteTooOld),
+		RestartsDeleteRange:       metric.NewCounter(metaRestartsDeleteRange),
+		RestartsSerializable:      metric.NewCounter(metaRestartsSerializable),
+		RestartsPossibleReplay:    metric.NewCounter(metaRestartsPossibleReplay),
+		RestartsAsyncWriteFailure: metric.NewCounter(metaRestartsAsyncWriteFailure),
 	}
 }
 
@@ -441,12 +464,28 @@ func (tc *TxnCoordSender) Send(
 	var br *roachpb.BatchResponse
 	{
 		var pErr *roachpb.Error
-		br, pErr = tc.wrapped.Send(ctx, ba)
+		sendBa, pb := ba, pipelinedBatch{}
+		if ba.Txn != nil && pipelinedWritesEnabled.Get(&tc.st.SV) {
+			tc.txnMu.Lock()
+			sendBa, pb = tc.maybePipelineLocked(ba)
+			tc.txnMu.Unlock()
+		}
+		br, pErr = tc.wrapped.Send(ctx, sendBa)
+		if len(pb.proven) > 0 {
+			br, pErr = stripQueryIntents(br, pErr, len(pb.proven))
+		}
 
 		if _, ok := pErr.GetDetail().(*roachpb.OpRequiresTxnError); ok {
 			br, pErr = tc.resendWithTxn(ctx, ba)
 		}
 
+		if ba.Txn != nil {
+			tc.updateInFlightWrites(ba.Txn.ID, pb, pErr)
+		}
+		if pErr == nil && br.Txn != nil && br.Txn.Status == roachpb.STAGING {
+			br, pErr = tc.finishParallelCommit(ctx, ba, br)
+		}
+
 		if pErr = tc.updateState(ctx, startNS, ba, br, pErr); pErr != nil {
 			log.Eventf(ctx, "error: %s", pErr)
 			return nil, pErr
@@ -492,6 +531,248 @@ func (tc *TxnCoordSender) Send(
 	return br, nil
 }
 
+// pipelinedBatch describes how maybePipelineLocked prepared a batch.
+type pipelinedBatch struct {
+	// proven holds the in-flight writes verified by the QueryIntent requests
+	// which were prepended to the batch.
+	proven []roachpb.SequencedWrite
+	// pipelined holds the batch's own writes, which are in flight once the
+	// batch returns because it was sent with asynchronous consensus.
+	pipelined []roachpb.SequencedWrite
+}
+
+// maybePipelineLocked prepares a transactional batch for write pipelining.
+// QueryIntent requests are prepended for those of the transaction's
+// in-flight writes which the batch depends on: the ones it overlaps or,
+// when the batch commits the transaction, all of them. A batch consisting
+// only of point writes is sent with asynchronous consensus. A committing
+// batch whose writes can all be proven afterwards stages the transaction
+// record in parallel with them, which lets the transaction commit in a
+// single round of consensus. The supplied batch is not mutated.
+func (tc *TxnCoordSender) maybePipelineLocked(
+	ba roachpb.BatchRequest,
+) (roachpb.BatchRequest, pipelinedBatch) {
+	txnMeta, ok := tc.txnMu.txns[ba.Txn.ID]
+	if !ok {
+		// The transaction hasn't written yet, so the batch carries its
+		// BeginTransaction and nothing can be in flight.
+		return ba, pipelinedBatch{}
+	}
+
+	etIdx := -1
+	canStage := true
+	var writes []roachpb.SequencedWrite
+	for i, union := range ba.Requests {
+		switch args := union.GetInner().(type) {
+		case *roachpb.BeginTransactionRequest:
+			canStage = false
+		case *roachpb.EndTransactionRequest:
+			if !args.Commit {
+				// Rolling back doesn't depend on any of the writes.
+				return ba, pipelinedBatch{}
+			}
+			etIdx = i
+			canStage = canStage && !args.Require1PC && args.InternalCommitTrigger == nil
+		default:
+			if !roachpb.IsTransactionWrite(args) {
+				continue
+			}
+			if roachpb.IsRange(args) {
+				// Ranged writes can't be proven through QueryIntent.
+				canStage = false
+				continue
+			}
+			// DistSender increments the sequence number before sending the
+			// batch, so the intent is written at this sequence or above.
+			writes = append(writes, roachpb.SequencedWrite{
+				Key:      args.Header().Key,
+				Sequence: ba.Txn.Sequence + 1,
+			})
+		}
+	}
+
+	var pb pipelinedBatch
+	for _, w := range txnMeta.inFlightWrites {
+		if etIdx != -1 || batchOverlapsKey(ba, w.Key) {
+			pb.proven = append(pb.proven, w)
+		}
+	}
+	canStage = canStage && etIdx != -1 && len(pb.proven)+len(writes) > 0
+	if len(pb.proven) == 0 && !canStage {
+		if etIdx == -1 && ba.IsPipelinable() {
+			ba.AsyncConsensus = true
+			pb.pipelined = writes
+		}
+		return ba, pb
+	}
+
+	reqs := make([]roachpb.RequestUnion, len(pb.proven), len(pb.proven)+len(ba.Requests))
+	for i, w := range pb.proven {
+		meta := ba.Txn.TxnMeta
+		meta.Sequence = w.Sequence
+		reqs[i].MustSetInner(&roachpb.QueryIntentRequest{
+			Span:           roachpb.Span{Key: w.Key},
+			Txn:            meta,
+			ErrorIfMissing: true,
+		})
+	}
+	reqs = append(reqs, ba.Requests...)
+	if canStage {
+		et := *ba.Requests[etIdx].GetInner().(*roachpb.EndTransactionRequest)
+		et.InFlightWrites = append(append([]roachpb.SequencedWrite(nil), pb.proven...), writes...)
+		reqs[len(pb.proven)+etIdx].MustSetInner(&et)
+	} else if etIdx == -1 && ba.IsPipelinable() {
+		ba.AsyncConsensus = true
+		pb.pipelined = writes
+	}
+	ba.Requests = reqs
+	return ba, pb
+}
+
+// batchOverlapsKey returns whether any request in the batch other than an
+// EndTransaction touches the given key.
+func batchOverlapsKey(ba roachpb.BatchRequest, key roachpb.Key) bool {
+	for _, union := range ba.Requests {
+		args := union.GetInner()
+		if _, ok := args.(*roachpb.EndTransactionRequest); ok {
+			continue
+		}
+		if args.Header().Overlaps(roachpb.Span{Key: key}) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripQueryIntents removes the responses to the first n requests of a
+// batch, which were QueryIntent requests added by maybePipelineLocked, and
+// adjusts the index of an error accordingly.
+func stripQueryIntents(
+	br *roachpb.BatchResponse, pErr *roachpb.Error, n int,
+) (*roachpb.BatchResponse, *roachpb.Error) {
+	if pErr != nil {
+		if pErr.Index != nil {
+			// Avoid changing existing errors because sometimes they escape
+			// into goroutines and data races can occur.
+			pErrShallow := *pErr
+			pErrShallow.Index = nil
+			if idx := pErr.Index.Index - int32(n); idx >= 0 {
+				pErrShallow.Index = &roachpb.ErrPosition{Index: idx}
+			}
+			pErr = &pErrShallow
+		}
+		return br, pErr
+	}
+	brShallow := *br
+	brShallow.Responses = br.Responses[n:]
+	return &brShallow, nil
+}
+
+// updateInFlightWrites updates the transaction's in-flight writes with the
+// outcome of a batch prepared by maybePipelineLocked.
+func (tc *TxnCoordSender) updateInFlightWrites(
+	txnID uuid.UUID, pb pipelinedBatch, pErr *roachpb.Error,
+) {
+	restart := pErr != nil && pErr.TransactionRestart != roachpb.TransactionRestart_NONE
+	if len(pb.proven) == 0 && len(pb.pipelined) == 0 && !restart {
+		return
+	}
+	tc.txnMu.Lock()
+	defer tc.txnMu.Unlock()
+	txnMeta, ok := tc.txnMu.txns[txnID]
+	if !ok {
+		return
+	}
+	if restart {
+		// The writes of the previous epoch don't matter any more.
+		txnMeta.inFlightWrites = nil
+		return
+	}
+	if pErr == nil && len(pb.proven) > 0 {
+		inFlight := txnMeta.inFlightWrites[:0:0]
+		for _, w := range txnMeta.inFlightWrites {
+			if !containsWrite(pb.proven, w) {
+				inFlight = append(inFlight, w)
+			}
+		}
+		txnMeta.inFlightWrites = inFlight
+	}
+	// Even if the batch failed, some of its writes may have been laid down
+	// and have to be proven before committing.
+	txnMeta.inFlightWrites = append(txnMeta.inFlightWrites, pb.pipelined...)
+}
+
+func containsWrite(writes []roachpb.SequencedWrite, w roachpb.SequencedWrite) bool {
+	for _, o := range writes {
+		if o.Sequence == w.Sequence && o.Key.Equal(w.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// finishParallelCommit is called when a committing batch returns with the
+// transaction record in the STAGING state, that is, after a parallel
+// commit. If none of the transaction's writes were pushed to a higher
+// timestamp than the one the record was staged at, all of them have been
+// proven and the transaction is implicitly committed; the record is made
+// explicitly COMMITTED in the background. Otherwise, the transaction is
+// committed through a second EndTransaction, subject to the usual checks.
+func (tc *TxnCoordSender) finishParallelCommit(
+	ctx context.Context, ba roachpb.BatchRequest, br *roachpb.BatchResponse,
+) (*roachpb.BatchResponse, *roachpb.Error) {
+	args, _ := ba.GetArg(roachpb.EndTransaction)
+	et := *args.(*roachpb.EndTransactionRequest)
+	et.InFlightWrites = nil
+	txn := br.Txn.Clone()
+	txn.Status = roachpb.PENDING
+	txn.InFlightWrites = nil
+
+	if !ba.Txn.Timestamp.Less(txn.Timestamp) && !txn.WriteTooOld {
+		// The deadline was already checked when the record was staged.
+		et.Deadline = nil
+		commitTxn := txn.Clone()
+		var commitBa roachpb.BatchRequest
+		commitBa.Txn = &commitTxn
+		commitBa.Add(&et)
+		// NB: use context.Background() here because the caller's context
+		// may be cancelled as soon as we return.
+		asyncCtx := tc.AnnotateCtx(context.Background())
+		if err := tc.stopper.RunAsyncTask(
+			asyncCtx, "kv.TxnCoordSender: committing staged txn", func(ctx context.Context) {
+				if _, pErr := tc.wrapped.Send(ctx, commitBa); pErr != nil {
+					// Whoever runs into the staged record recovers it.
+					log.Warningf(ctx, "explicitly committing %s failed: %s", commitTxn, pErr)
+				}
+			}); err != nil {
+			log.Warning(ctx, err)
+		}
+		txn.Status = roachpb.COMMITTED
+		brShallow := *br
+		brShallow.Txn = &txn
+		return &brShallow, nil
+	}
+
+	log.Eventf(ctx, "parallel commit of %s was pushed; committing explicitly", txn.Short())
+	var commitBa roachpb.BatchRequest
+	commitBa.Txn = &txn
+	commitBa.Add(&et)
+	commitBr, pErr := tc.wrapped.Send(ctx, commitBa)
+	if pErr != nil {
+		if pErr.Index != nil {
+			pErrShallow := *pErr
+			pErrShallow.Index = &roachpb.ErrPosition{Index: int32(len(ba.Requests) - 1)}
+			pErr = &pErrShallow
+		}
+		return nil, pErr
+	}
+	brShallow := *br
+	brShallow.Responses = append([]roachpb.ResponseUnion(nil), br.Responses...)
+	brShallow.Responses[len(brShallow.Responses)-1] = commitBr.Responses[0]
+	brShallow.Txn = commitBr.Txn
+	return &brShallow, nil
+}
+
 // maybeRejectClientLocked checks whether the (transactional) request is in a
 // state that prevents it from continuing, such as the coordinator having
 // considered the client abandoned, or a heartbeat having reported an error.
@@ -789,7 +1070,16 @@ func (tc *TxnCoordSender) heartbeat(ctx context.Context, txnID uuid.UUID) bool {
 		tc.tryAsyncAbort(txn.ID)
 		txn.Status = roachpb.ABORTED
 	} else {
-		txn.Update(br.Responses[0].GetInner().(*roachpb.HeartbeatTxnResponse).Txn)
+		hbTxn := br.Responses[0].GetInner().(*roachpb.HeartbeatTxnResponse).Txn
+		if hbTxn != nil && hbTxn.Status == roachpb.STAGING {
+			// A parallel commit is in progress. Its outcome is learned from the
+			// committing batch, so the transaction remains pending here.
+			hbTxnCopy := *hbTxn
+			hbTxnCopy.Status = roachpb.PENDING
+			hbTxnCopy.InFlightWrites = nil
+			hbTxn = &hbTxnCopy
+		}
+		txn.Update(hbTxn)
 	}
 
 	// Give the news to the txn in the txns map. This will update long-running
@@ -856,6 +1146,8 @@ func (tc *TxnCoordSender) updateState(
 					tc.metrics.RestartsSerializable.Inc(1)
 				case roachpb.RETRY_POSSIBLE_REPLAY:
 					tc.metrics.RestartsPossibleReplay.Inc(1)
+				case roachpb.RETRY_ASYNC_WRITE_FAILURE:
+					tc.metrics.RestartsAsyncWriteFailure.Inc(1)
 				}
 			}
 			newTxn = roachpb.PrepareTransactionForRetry(ctx, pErr, ba.UserPriority, tc.clock)
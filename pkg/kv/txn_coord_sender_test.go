@@ -1727,3 +1727,143 @@ func TestTooManyIntentsAtCommit(t *testing.T) {
 		t.Fatal("did not expect value to exist")
 	}
 }
+
+// TestTxnCoordSenderPipelinedWrites verifies that writes following the
+// first one of a transaction are sent with asynchronous consensus, that the
+// coordinator proves them through QueryIntent requests before depending on
+// them, and that the commit is staged in parallel with the writes still in
+// flight. If the staged commit is pushed, it is followed by an explicit
+// commit before returning to the client; otherwise, the explicit commit
+// happens asynchronously.
+func TestTxnCoordSenderPipelinedWrites(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testutils.RunTrueAndFalse(t, "pushed", func(t *testing.T, pushed bool) {
+		stopper := stop.NewStopper()
+		defer stopper.Stop(context.TODO())
+		clock := hlc.NewClock(hlc.UnixNano, time.Nanosecond)
+
+		batches := make(chan roachpb.BatchRequest, 10)
+		var senderFn client.SenderFunc = func(
+			_ context.Context, ba roachpb.BatchRequest,
+		) (*roachpb.BatchResponse, *roachpb.Error) {
+			br := ba.CreateReply()
+			txnClone := ba.Txn.Clone()
+			br.Txn = &txnClone
+			br.Txn.Writing = true
+			if _, ok := ba.GetArg(roachpb.HeartbeatTxn); ok {
+				return br, nil
+			}
+			if args, ok := ba.GetArg(roachpb.EndTransaction); ok {
+				if len(args.(*roachpb.EndTransactionRequest).InFlightWrites) > 0 {
+					br.Txn.Status = roachpb.STAGING
+					if pushed {
+						br.Txn.Timestamp = br.Txn.Timestamp.Next()
+					}
+				} else {
+					br.Txn.Status = roachpb.COMMITTED
+				}
+			}
+			batches <- ba
+			return br, nil
+		}
+		ambient := log.AmbientContext{Tracer: tracing.NewTracer()}
+		ts := NewTxnCoordSender(
+			ambient,
+			cluster.MakeTestingClusterSettings(),
+			senderFn,
+			clock,
+			false,
+			stopper,
+			MakeTxnMetrics(metric.TestSampleInterval),
+		)
+		defer teardownHeartbeats(ts)
+
+		// expectBatch receives the next batch and verifies that it consists of
+		// QueryIntent requests for the given keys followed by requests of the
+		// given methods.
+		expectBatch := func(async bool, queried []string, methods ...roachpb.Method) roachpb.BatchRequest {
+			t.Helper()
+			ba := <-batches
+			if ba.AsyncConsensus != async {
+				t.Errorf("expected AsyncConsensus=%t in %s", async, ba)
+			}
+			var expMethods []roachpb.Method
+			for range queried {
+				expMethods = append(expMethods, roachpb.QueryIntent)
+			}
+			expMethods = append(expMethods, methods...)
+			if m := ba.Methods(); !reflect.DeepEqual(m, expMethods) {
+				t.Fatalf("expected methods %s, found %s", expMethods, m)
+			}
+			for i, key := range queried {
+				qi := ba.Requests[i].GetInner().(*roachpb.QueryIntentRequest)
+				if !qi.Key.Equal(roachpb.Key(key)) || !qi.ErrorIfMissing {
+					t.Errorf("unexpected QueryIntent %+v, expected one for key %q", qi, key)
+				}
+			}
+			return ba
+		}
+
+		ctx := context.Background()
+		db := client.NewDB(ts, clock)
+		txn := client.NewTxn(db, 0 /* gatewayNodeID */)
+
+		// The first write carries the BeginTransaction and isn't pipelined.
+		if err := txn.Put(ctx, "a", "value"); err != nil {
+			t.Fatal(err)
+		}
+		expectBatch(false, nil, roachpb.BeginTransaction, roachpb.Put)
+		if err := txn.Put(ctx, "b", "value"); err != nil {
+			t.Fatal(err)
+		}
+		expectBatch(true, nil, roachpb.Put)
+		if err := txn.Put(ctx, "c", "value"); err != nil {
+			t.Fatal(err)
+		}
+		expectBatch(true, nil, roachpb.Put)
+
+		// Reading b requires proving the write to b first. The response to
+		// the QueryIntent isn't returned to the client.
+		b := txn.NewBatch()
+		b.Get("b")
+		if err := txn.Run(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+		if len(b.Results) != 1 || len(b.Results[0].Rows) != 1 {
+			t.Fatalf("unexpected results %+v", b.Results)
+		}
+		expectBatch(false, []string{"b"}, roachpb.Get)
+
+		// The commit proves the remaining in-flight write and stages the
+		// transaction record along with its own write.
+		b = txn.NewBatch()
+		b.Put("d", "value")
+		if err := txn.CommitInBatch(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+		ba := expectBatch(false, []string{"c"}, roachpb.Put, roachpb.EndTransaction)
+		et := ba.Requests[2].GetInner().(*roachpb.EndTransactionRequest)
+		var inFlight []string
+		for _, w := range et.InFlightWrites {
+			inFlight = append(inFlight, string(w.Key))
+		}
+		if exp := []string{"c", "d"}; !reflect.DeepEqual(inFlight, exp) {
+			t.Errorf("expected in-flight writes %s, found %s", exp, inFlight)
+		}
+
+		// Either way, the transaction is committed explicitly. If the staged
+		// commit was pushed, this happened before returning to the client.
+		if pushed && len(batches) == 0 {
+			t.Fatal("expected explicit commit before returning to the client")
+		}
+		ba = expectBatch(false, nil, roachpb.EndTransaction)
+		et = ba.Requests[0].GetInner().(*roachpb.EndTransactionRequest)
+		if !et.Commit || len(et.InFlightWrites) != 0 || len(et.IntentSpans) != 4 {
+			t.Errorf("unexpected explicit commit %+v", et)
+		}
+		if status := txn.Proto().Status; status != roachpb.COMMITTED {
+			t.Errorf("expected COMMITTED transaction, found %s", status)
+		}
+	})
+}
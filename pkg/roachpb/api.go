@@ -82,6 +82,7 @@ const (
 	skipLeaseCheck
 	consultsTSCache // mutating commands which write data at a timestamp
 	updatesTSCache  // commands which read data at a timestamp
+	isPrefix        // requests which should be grouped with the next request in a batch
 )
 
 // IsReadOnly returns true iff the request is read-only.
@@ -401,6 +402,15 @@ func (ru *ResponseUnion) MustSetInner(reply Response) {
 	}
 }
 
+// MustSetValue sets the event contained in the union. It panics if the event
+// is not recognized by the union type.
+func (e *RangeFeedEvent) MustSetValue(value interface{}) {
+	e.Reset()
+	if !e.SetValue(value) {
+		panic(fmt.Sprintf("%T excludes %T", e, value))
+	}
+}
+
 // Method implements the Request interface.
 func (*GetRequest) Method() Method { return Get }
 
@@ -509,6 +519,18 @@ func (*AdminScatterRequest) Method() Method { return AdminScatter }
 // Method implements the Request interface.
 func (*AddSSTableRequest) Method() Method { return AddSSTable }
 
+// Method implements the Request interface.
+func (*RangeStatsRequest) Method() Method { return RangeStats }
+
+// Method implements the Request interface.
+func (*QueryIntentRequest) Method() Method { return QueryIntent }
+
+// Method implements the Request interface.
+func (*RecoverTxnRequest) Method() Method { return RecoverTxn }
+
+// Method implements the Request interface.
+func (*SubsumeRequest) Method() Method { return Subsume }
+
 // ShallowCopy implements the Request interface.
 func (gr *GetRequest) ShallowCopy() Request {
 	shallowCopy := *gr
@@ -725,6 +747,30 @@ func (r *AddSSTableRequest) ShallowCopy() Request {
 	return &shallowCopy
 }
 
+// ShallowCopy implements the Request interface.
+func (r *RangeStatsRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *QueryIntentRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *RecoverTxnRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (r *SubsumeRequest) ShallowCopy() Request {
+	shallowCopy := *r
+	return &shallowCopy
+}
+
 // NewGet returns a Request initialized to get the value at key.
 func NewGet(key Key) Request {
 	return &GetRequest{
@@ -949,6 +995,16 @@ func (*ExportRequest) flags() int                   { return isRead | isRange |
 func (*ImportRequest) flags() int                   { return isAdmin | isAlone }
 func (*AdminScatterRequest) flags() int             { return isAdmin | isAlone | isRange }
 func (*AddSSTableRequest) flags() int               { return isWrite | isAlone | isRange }
+func (*RangeStatsRequest) flags() int               { return isRead }
+
+// QueryIntent updates the read timestamp cache so that, once it has
+// observed that an intent is missing, the write it was looking for can
+// no longer be evaluated at or below the queried timestamp. It is a
+// prefix request: it proves a write that a later request in the same
+// batch (typically an EndTransaction) depends on.
+func (*QueryIntentRequest) flags() int { return isRead | isTxn | isPrefix | updatesTSCache }
+func (*RecoverTxnRequest) flags() int  { return isWrite | isAlone }
+func (*SubsumeRequest) flags() int     { return isRead | isTxn | isAlone }
 
 // Keys returns credentials in an aws.Config.
 func (b *ExportStorage_S3) Keys() *aws.Config {
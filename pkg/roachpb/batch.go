@@ -110,6 +110,27 @@ func (ba *BatchRequest) IsTransactionWrite() bool {
 	return ba.hasFlag(isTxnWrite)
 }
 
+// IsPipelinable returns true iff the BatchRequest is a transactional batch
+// containing only point writes (possibly preceded by prefix requests),
+// which makes it eligible for asynchronous consensus.
+func (ba *BatchRequest) IsPipelinable() bool {
+	if ba.Txn == nil {
+		return false
+	}
+	hasWrite := false
+	for _, union := range ba.Requests {
+		flags := union.GetInner().flags()
+		if (flags & isPrefix) != 0 {
+			continue
+		}
+		if (flags&isTxnWrite) == 0 || (flags&isRange) != 0 {
+			return false
+		}
+		hasWrite = true
+	}
+	return hasWrite
+}
+
 // IsSingleRequest returns true iff the BatchRequest contains a single request.
 func (ba *BatchRequest) IsSingleRequest() bool {
 	return len(ba.Requests) == 1
@@ -151,6 +172,16 @@ func (ba *BatchRequest) IsSingleEndTransactionRequest() bool {
 	return false
 }
 
+// IsSingleSubsumeRequest returns true iff the batch contains a single
+// request, and that request is a SubsumeRequest.
+func (ba *BatchRequest) IsSingleSubsumeRequest() bool {
+	if ba.IsSingleRequest() {
+		_, ok := ba.Requests[0].GetInner().(*SubsumeRequest)
+		return ok
+	}
+	return false
+}
+
 // GetPrevLeaseForLeaseRequest returns the previous lease, at the time
 // of proposal, for a request lease or transfer lease request. If the
 // batch does not contain a single lease request, this method will panic.
@@ -296,7 +327,8 @@ func (ba *BatchRequest) Methods() []Method {
 // special-cased: If false, an EndTransaction request will never be split into
 // a new chunk (otherwise, it is treated according to its flags). This allows
 // sending a whole transaction in a single Batch when addressing a single
-// range.
+// range. Prefix requests (such as QueryIntent) are kept with the request
+// following them, unless that request has to be alone.
 func (ba BatchRequest) Split(canSplitET bool) [][]RequestUnion {
 	compatible := func(method Method, exFlags, newFlags int) bool {
 		// If no flags are set so far, everything goes.
@@ -320,6 +352,7 @@ func (ba BatchRequest) Split(canSplitET bool) [][]RequestUnion {
 	for len(ba.Requests) > 0 {
 		part := ba.Requests
 		var gFlags int
+		prefixStart := -1
 		for i, union := range ba.Requests {
 			args := union.GetInner()
 			flags := args.flags()
@@ -328,10 +361,29 @@ func (ba BatchRequest) Split(canSplitET bool) [][]RequestUnion {
 			if method == Noop {
 				continue
 			}
+			// Prefix requests go wherever the request following them goes.
+			if (flags & isPrefix) != 0 {
+				if prefixStart == -1 {
+					prefixStart = i
+				}
+				continue
+			}
+			// A request which must be alone doesn't take the prefix requests
+			// preceding it along; they stay with the requests before them,
+			// or make up a part of their own.
+			alone := (flags&isAlone) != 0 && (canSplitET || method != EndTransaction)
+			if alone && gFlags == 0 && prefixStart != -1 {
+				part = ba.Requests[:i]
+				break
+			}
 			if !compatible(method, gFlags, flags) {
+				if prefixStart != -1 && !alone {
+					i = prefixStart
+				}
 				part = ba.Requests[:i]
 				break
 			}
+			prefixStart = -1
 			gFlags |= flags
 		}
 		parts = append(parts, part)
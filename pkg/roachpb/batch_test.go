@@ -33,6 +33,7 @@ func TestBatchSplit(t *testing.T) {
 	et := &EndTransactionRequest{}
 	rv := &ReverseScanRequest{}
 	np := &NoopRequest{}
+	qi := &QueryIntentRequest{}
 	testCases := []struct {
 		reqs       []Request
 		sizes      []int
@@ -57,6 +58,15 @@ func TestBatchSplit(t *testing.T) {
 		{[]Request{np, spl, np}, []int{3}, true},
 		{[]Request{np, rv, np}, []int{3}, true},
 		{[]Request{np, np, et}, []int{3}, true}, // et does not split off
+		// Check that prefix requests stay with the request following them.
+		{[]Request{get, qi, qi, put}, []int{1, 3}, true},
+		{[]Request{get, qi}, []int{2}, true},
+		{[]Request{put, qi, et}, []int{3}, false},
+		{[]Request{qi, et}, []int{2}, false},
+		// ... unless the request following them must be alone.
+		{[]Request{qi, put, qi, et}, []int{3, 1}, true},
+		{[]Request{qi, et}, []int{1, 1}, true},
+		{[]Request{qi, spl, get}, []int{1, 1, 1}, true},
 	}
 
 	for i, test := range testCases {
@@ -79,6 +89,45 @@ func TestBatchSplit(t *testing.T) {
 	}
 }
 
+func TestBatchIsPipelinable(t *testing.T) {
+	get := &GetRequest{}
+	put := &PutRequest{}
+	cput := &ConditionalPutRequest{}
+	dr := &DeleteRangeRequest{}
+	bt := &BeginTransactionRequest{}
+	et := &EndTransactionRequest{}
+	qi := &QueryIntentRequest{}
+	testCases := []struct {
+		reqs []Request
+		txn  bool
+		exp  bool
+	}{
+		{[]Request{put}, true, true},
+		{[]Request{put, cput}, true, true},
+		{[]Request{qi, put}, true, true},
+		{[]Request{put}, false, false},
+		{[]Request{}, true, false},
+		{[]Request{qi}, true, false},
+		{[]Request{get, put}, true, false},
+		{[]Request{dr}, true, false},
+		{[]Request{bt, put}, true, false},
+		{[]Request{put, et}, true, false},
+	}
+
+	for i, test := range testCases {
+		ba := BatchRequest{}
+		if test.txn {
+			ba.Txn = &Transaction{}
+		}
+		for _, args := range test.reqs {
+			ba.Add(args)
+		}
+		if res := ba.IsPipelinable(); res != test.exp {
+			t.Errorf("%d: expected IsPipelinable()=%t for %s, got %t", i, test.exp, ba, res)
+		}
+	}
+}
+
 func TestBatchRequestGetArg(t *testing.T) {
 	testCases := []struct {
 		bu         []RequestUnion
@@ -655,6 +655,13 @@ const (
 	MaxTxnPriority = math.MaxInt32
 )
 
+// IsFinalized returns true iff a transaction in this status has been
+// committed or aborted. A STAGING transaction is not finalized: its
+// outcome is determined by its in-flight writes.
+func (ts TransactionStatus) IsFinalized() bool {
+	return ts == COMMITTED || ts == ABORTED
+}
+
 // MakeTransaction creates a new transaction. The transaction key is
 // composed using the specified baseKey (for locality with data
 // affected by the transaction) and a random ID to guarantee
@@ -722,6 +729,7 @@ func (t Transaction) Clone() Transaction {
 	// Note that we're not cloning the span keys under the assumption that the
 	// keys themselves are not mutable.
 	t.Intents = append([]Span(nil), t.Intents...)
+	t.InFlightWrites = append([]SequencedWrite(nil), t.InFlightWrites...)
 	return t
 }
 
@@ -898,6 +906,9 @@ func (t *Transaction) Update(o *Transaction) {
 	if len(o.Intents) > 0 {
 		t.Intents = o.Intents
 	}
+	if len(o.InFlightWrites) > 0 {
+		t.InFlightWrites = o.InFlightWrites
+	}
 }
 
 // UpgradePriority sets transaction priority to the maximum of current
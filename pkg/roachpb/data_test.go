@@ -395,6 +395,7 @@ var nonZeroTxn = Transaction{
 	WriteTooOld:        true,
 	RetryOnPush:        true,
 	Intents:            []Span{{Key: []byte("a"), EndKey: []byte("b")}},
+	InFlightWrites:     []SequencedWrite{{Key: []byte("c"), Sequence: 1}},
 }
 
 func TestTransactionUpdate(t *testing.T) {
@@ -431,6 +432,7 @@ func TestTransactionClone(t *testing.T) {
 	// listed below. If this test fails, please update the list below and/or
 	// Transaction.Clone().
 	expFields := []string{
+		"InFlightWrites.Key",
 		"Intents.EndKey",
 		"Intents.Key",
 		"TxnMeta.Key",
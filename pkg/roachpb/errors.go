@@ -618,3 +618,45 @@ func (*TxnPrevAttemptError) message(_ *Error) string {
 }
 
 var _ ErrorDetailInterface = &TxnPrevAttemptError{}
+
+// NewRangeFeedRetryError initializes a new RangeFeedRetryError.
+func NewRangeFeedRetryError(reason RangeFeedRetryError_Reason) *RangeFeedRetryError {
+	return &RangeFeedRetryError{
+		Reason: reason,
+	}
+}
+
+func (e *RangeFeedRetryError) Error() string {
+	return e.message(nil)
+}
+
+func (e *RangeFeedRetryError) message(_ *Error) string {
+	return fmt.Sprintf("retry rangefeed (%s)", e.Reason)
+}
+
+var _ ErrorDetailInterface = &RangeFeedRetryError{}
+
+// NewIndeterminateCommitError initializes a new IndeterminateCommitError.
+func NewIndeterminateCommitError(txn Transaction) *IndeterminateCommitError {
+	return &IndeterminateCommitError{StagingTxn: txn}
+}
+
+func (e *IndeterminateCommitError) Error() string {
+	return e.message(nil)
+}
+
+func (e *IndeterminateCommitError) message(_ *Error) string {
+	return fmt.Sprintf("found txn in indeterminate STAGING state %s", e.StagingTxn)
+}
+
+var _ ErrorDetailInterface = &IndeterminateCommitError{}
+
+func (e *MergeInProgressError) Error() string {
+	return e.message(nil)
+}
+
+func (e *MergeInProgressError) message(_ *Error) string {
+	return "merge in progress"
+}
+
+var _ ErrorDetailInterface = &MergeInProgressError{}
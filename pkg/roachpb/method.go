@@ -133,4 +133,16 @@ const (
 	AdminScatter
 	// AddSSTable links a file into the RocksDB log-structured merge-tree.
 	AddSSTable
+	// RangeStats returns the MVCC statistics for a range.
+	RangeStats
+	// QueryIntent checks whether the specified intent exists. It is used
+	// by transactions that pipeline their writes to prove that a write
+	// which was acknowledged before being replicated has succeeded.
+	QueryIntent
+	// RecoverTxn finalizes a transaction whose record was left in the
+	// STAGING state, once the status of all of its in-flight writes is
+	// known.
+	RecoverTxn
+	// Subsume freezes a range for merging with its left-hand neighbor.
+	Subsume
 )
@@ -894,6 +894,23 @@ func (n *Node) Batch(
 	return br, nil
 }
 
+// RangeFeed implements the roachpb.InternalServer interface.
+func (n *Node) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) error {
+	growStack()
+
+	pErr := n.stores.RangeFeed(args, stream)
+	if pErr != nil {
+		var event roachpb.RangeFeedEvent
+		event.MustSetValue(&roachpb.RangeFeedError{
+			Error: *pErr,
+		})
+		return stream.Send(&event)
+	}
+	return nil
+}
+
 // setupSpanForIncomingRPC takes a context and returns a derived context with a
 // new span in it. Depending on the input context, that span might be a root
 // span or a child span. If it is a child span, it might be a child span of a
@@ -58,6 +58,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
 	migrations "github.com/cockroachdb/cockroach/pkg/sqlmigrations"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
@@ -97,6 +98,10 @@ var (
 	}
 )
 
+// tableStatsCacheSize is the number of tables whose statistics are cached
+// on each node for use by the planner.
+const tableStatsCacheSize = 256
+
 // Server is the cockroach server node.
 type Server struct {
 	nodeIDContainer base.NodeIDContainer
@@ -392,11 +397,12 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	s.node = NewNode(storeCfg, s.recorder, s.registry, s.stopper, txnMetrics, sql.MakeEventLogger(s.leaseMgr))
 	roachpb.RegisterInternalServer(s.grpc, s.node)
 	storage.RegisterConsistencyServer(s.grpc, s.node.storesServer)
+	storage.RegisterPerReplicaServer(s.grpc, s.node.storesServer)
 	serverpb.RegisterInitServer(s.grpc, &noopInitServer{clusterID: s.ClusterID})
 
 	s.sessionRegistry = sql.MakeSessionRegistry()
 	s.jobRegistry = jobs.MakeRegistry(
-		s.clock, s.db, sqlExecutor, s.gossip, &s.nodeIDContainer, s.ClusterID, st)
+		s.clock, s.db, s.distSender, sqlExecutor, s.gossip, &s.nodeIDContainer, s.ClusterID, st)
 
 	distSQLMetrics := distsqlrun.MakeDistSQLMetrics(cfg.HistogramWindowInterval())
 	s.registry.AddMetricStruct(distSQLMetrics)
@@ -406,6 +412,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		AmbientContext: s.cfg.AmbientCtx,
 		Settings:       st,
 		DB:             s.db,
+		Executor:       sqlExecutor,
 		// DistSQL also uses a DB that bypasses the TxnCoordSender.
 		FlowDB:     client.NewDB(s.distSender, s.clock),
 		RPCContext: s.rpcContext,
@@ -470,6 +477,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		StatusServer:            s.status,
 		SessionRegistry:         s.sessionRegistry,
 		JobRegistry:             s.jobRegistry,
+		TableStatsCache:         stats.NewTableStatisticsCache(tableStatsCacheSize, s.db, sqlExecutor),
 		HistogramWindowInterval: s.cfg.HistogramWindowInterval(),
 		RangeDescriptorCache:    s.distSender.RangeDescriptorCache(),
 		LeaseHolderCache:        s.distSender.LeaseHolderCache(),
@@ -1161,6 +1169,15 @@ If problems persist, please see ` + base.DocsURL("cluster-setup-troubleshooting.
 		return err
 	}
 
+	sql.NewTemporarySchemaCleaner(
+		s.db,
+		s.leaseMgr,
+		s.clock,
+		&s.nodeIDContainer,
+		s.sessionRegistry,
+		s.nodeLiveness,
+	).Start(s.stopper, sql.DefaultTemporarySchemaCleanupInterval)
+
 	// Before serving SQL requests, we have to make sure the database is
 	// in an acceptable form for this version of the software.
 	// We have to do this after actually starting up the server to be able to
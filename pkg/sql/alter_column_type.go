@@ -0,0 +1,283 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/coltypes"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/transform"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// alterColumnType applies an ALTER COLUMN ... TYPE command to tableDesc.
+// Changes that don't affect the encoding of the column's values, like
+// widening an INT4 to an INT8 or a STRING(n) to a STRING, only update the
+// column descriptor, and descriptorChanged is returned as true. Other
+// changes add a shadow column computing the converted values, which is
+// backfilled by the schema changer and replaces the column once complete.
+func alterColumnType(
+	tableDesc *sqlbase.TableDescriptor,
+	t *tree.AlterTableAlterColumnType,
+	semaCtx *tree.SemaContext,
+	evalCtx *tree.EvalContext,
+) (descriptorChanged bool, err error) {
+	col, dropped, err := tableDesc.FindColumnByName(t.Column)
+	if err != nil {
+		return false, err
+	}
+	if dropped {
+		return false, fmt.Errorf("column %q in the middle of being dropped", t.Column)
+	}
+	if _, err := tableDesc.FindActiveColumnByID(col.ID); err != nil {
+		return false, fmt.Errorf("column %q in the middle of being added, try again later", t.Column)
+	}
+	for _, m := range tableDesc.Mutations {
+		if m.ColumnConversion != nil && m.ColumnConversion.SourceColumnID == col.ID {
+			return false, fmt.Errorf("column %q in the middle of being converted, try again later",
+				t.Column)
+		}
+	}
+
+	if i, ok := t.ToType.(*coltypes.TInt); ok && i.IsSerial() {
+		return false, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"cannot change the type of column %q to %s", t.Column, t.ToType)
+	}
+	newType, err := sqlbase.MakeColumnType(t.ToType, semaCtx)
+	if err != nil {
+		return false, err
+	}
+
+	if t.Using == nil {
+		if newType.Equal(col.Type) {
+			// Noop.
+			return false, nil
+		}
+		if isMetadataOnlyTypeChange(col.Type, newType) {
+			col.Type = newType
+			tableDesc.UpdateColumnDescriptor(col)
+			return true, nil
+		}
+	}
+
+	// The column's values need to be rewritten. Columns referenced by indexes
+	// (and thus foreign keys), CHECK constraints and views would also need
+	// those to be rewritten, which isn't supported.
+	computedIDs, err := computedColumnsReferencing(tableDesc, col)
+	if err != nil {
+		return false, err
+	}
+	for _, idx := range tableDesc.AllNonDropIndexes() {
+		referenced := idx.ContainsColumnID(col.ID)
+		for _, id := range computedIDs {
+			referenced = referenced || idx.ContainsColumnID(id)
+		}
+		if referenced {
+			return false, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+				"cannot convert column %q referenced by index %q to %s", col.Name, idx.Name, t.ToType)
+		}
+	}
+	if len(tableDesc.Checks) > 0 {
+		referenced, err := checksReferenceColumn(tableDesc, col.Name)
+		if err != nil {
+			return false, err
+		}
+		if referenced {
+			return false, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+				"cannot convert column %q referenced by a CHECK constraint to %s", col.Name, t.ToType)
+		}
+	}
+	for _, ref := range tableDesc.DependedOnBy {
+		for _, colID := range ref.ColumnIDs {
+			if colID == col.ID {
+				return false, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+					"cannot convert column %q depended on by a view to %s", col.Name, t.ToType)
+			}
+		}
+	}
+
+	expr, err := makeColumnConversionExpr(col, t, semaCtx)
+	if err != nil {
+		return false, err
+	}
+
+	shadow := sqlbase.ColumnDescriptor{
+		Name:     uniqueShadowColumnName(tableDesc, col.Name),
+		Type:     newType,
+		Nullable: col.Nullable,
+	}
+	if col.DefaultExpr != nil {
+		// The default expression carries over to the converted column.
+		def, err := parser.ParseExpr(*col.DefaultExpr)
+		if err != nil {
+			return false, err
+		}
+		def = &tree.CastExpr{Expr: def, Type: t.ToType, SyntaxMode: tree.CastShort}
+		if _, err := sqlbase.SanitizeVarFreeExpr(
+			def, newType.ToDatumType(), "DEFAULT", semaCtx, evalCtx,
+		); err != nil {
+			return false, err
+		}
+		s := tree.Serialize(def)
+		shadow.DefaultExpr = &s
+	}
+
+	tableDesc.AddColumnConversionMutation(shadow, col.ID, expr)
+	// Check that the conversion is valid before starting the schema change.
+	if _, err := sqlbase.MakeColumnConversion(
+		tableDesc, tableDesc.Mutations[len(tableDesc.Mutations)-1],
+	); err != nil {
+		return false, err
+	}
+	// Keep the converted values in the same column family as the original ones.
+	for _, family := range tableDesc.Families {
+		for _, id := range family.ColumnIDs {
+			if id == col.ID {
+				return false, tableDesc.AddColumnToFamilyMaybeCreate(
+					shadow.Name, family.Name, false /* create */, false, /* ifNotExists */
+				)
+			}
+		}
+	}
+	return false, nil
+}
+
+// isMetadataOnlyTypeChange returns whether the values of a column of type
+// oldType are valid, identically encoded values of newType.
+func isMetadataOnlyTypeChange(oldType, newType sqlbase.ColumnType) bool {
+	if oldType.SemanticType != newType.SemanticType ||
+		len(oldType.ArrayDimensions) != 0 || len(newType.ArrayDimensions) != 0 {
+		return false
+	}
+	if (oldType.Locale == nil) != (newType.Locale == nil) ||
+		(oldType.Locale != nil && *oldType.Locale != *newType.Locale) {
+		return false
+	}
+	// widens returns whether a width or precision limit of newLimit accepts
+	// all the values accepted by oldLimit, 0 meaning unlimited.
+	widens := func(oldLimit, newLimit int32) bool {
+		return newLimit == 0 || (oldLimit != 0 && newLimit >= oldLimit)
+	}
+	switch oldType.SemanticType {
+	case sqlbase.ColumnType_INT:
+		// The width of BIT columns is exact rather than a limit.
+		if oldType.VisibleType == sqlbase.ColumnType_BIT ||
+			newType.VisibleType == sqlbase.ColumnType_BIT {
+			return false
+		}
+		return widens(oldType.Width, newType.Width)
+	case sqlbase.ColumnType_FLOAT:
+		return widens(oldType.Precision, newType.Precision)
+	case sqlbase.ColumnType_DECIMAL:
+		if newType.Precision == 0 {
+			return true
+		}
+		return oldType.Width == newType.Width && widens(oldType.Precision, newType.Precision)
+	case sqlbase.ColumnType_STRING, sqlbase.ColumnType_BYTES, sqlbase.ColumnType_COLLATEDSTRING:
+		return widens(oldType.Width, newType.Width)
+	}
+	return false
+}
+
+// makeColumnConversionExpr returns the serialized expression computing the
+// new values of the column from its old values, referred to as @1. It is
+// the USING expression if one was given and a cast otherwise.
+func makeColumnConversionExpr(
+	col sqlbase.ColumnDescriptor, t *tree.AlterTableAlterColumnType, semaCtx *tree.SemaContext,
+) (string, error) {
+	if t.Using == nil {
+		return tree.Serialize(&tree.CastExpr{
+			Expr: tree.NewOrdinalReference(0), Type: t.ToType, SyntaxMode: tree.CastShort,
+		}), nil
+	}
+
+	preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+		vBase, ok := expr.(tree.VarName)
+		if !ok {
+			return nil, true, expr
+		}
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return err, false, nil
+		}
+		c, ok := v.(*tree.ColumnItem)
+		if !ok || string(c.ColumnName) != col.Name {
+			return pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+				"USING expression can only reference column %q", col.Name), false, nil
+		}
+		return nil, false, tree.NewOrdinalReference(0)
+	}
+	expr, err := tree.SimpleVisit(t.Using, preFn)
+	if err != nil {
+		return "", err
+	}
+
+	var txCtx transform.ExprTransformContext
+	if err := txCtx.AssertNoAggregationOrWindowing(
+		expr, "USING expressions", semaCtx.SearchPath,
+	); err != nil {
+		return "", err
+	}
+	return tree.Serialize(expr), nil
+}
+
+// checksReferenceColumn returns whether any of the CHECK constraints of
+// tableDesc references the named column.
+func checksReferenceColumn(tableDesc *sqlbase.TableDescriptor, name string) (bool, error) {
+	exprStrings := make([]string, len(tableDesc.Checks))
+	for i, check := range tableDesc.Checks {
+		exprStrings[i] = check.Expr
+	}
+	exprs, err := parser.ParseExprs(exprStrings)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+		if vBase, ok := expr.(tree.VarName); ok {
+			v, err := vBase.NormalizeVarName()
+			if err != nil {
+				return err, false, nil
+			}
+			if c, ok := v.(*tree.ColumnItem); ok && string(c.ColumnName) == name {
+				found = true
+			}
+			return nil, false, v
+		}
+		return nil, true, expr
+	}
+	for _, expr := range exprs {
+		if _, err := tree.SimpleVisit(expr, preFn); err != nil {
+			return false, err
+		}
+	}
+	return found, nil
+}
+
+// uniqueShadowColumnName returns the temporary name of the column holding
+// the converted values of the named column.
+func uniqueShadowColumnName(tableDesc *sqlbase.TableDescriptor, name string) string {
+	shadowName := name + "_conv"
+	for i := 1; ; i++ {
+		if _, _, err := tableDesc.FindColumnByName(tree.Name(shadowName)); err != nil {
+			return shadowName
+		}
+		shadowName = fmt.Sprintf("%s_conv%d", name, i)
+	}
+}
@@ -37,7 +37,7 @@ type alterTableNode struct {
 //   notes: postgres requires CREATE on the table.
 //          mysql requires ALTER, CREATE, INSERT on the table.
 func (p *planner) AlterTable(ctx context.Context, n *tree.AlterTable) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +138,15 @@ func (n *alterTableNode) Start(params runParams) error {
 					Unique:           true,
 					StoreColumnNames: d.Storing.ToStrings(),
 				}
-				if err := idx.FillColumns(d.Columns); err != nil {
+				columns, err := makeIndexExprColumns(
+					n.tableDesc, d.Columns, &params.p.semaCtx, func(col sqlbase.ColumnDescriptor) {
+						n.tableDesc.AddColumnMutation(col, sqlbase.DescriptorMutation_ADD)
+					},
+				)
+				if err != nil {
+					return err
+				}
+				if err := idx.FillColumns(columns); err != nil {
 					return err
 				}
 				if d.PartitionBy != nil {
@@ -204,6 +212,10 @@ func (n *alterTableNode) Start(params runParams) error {
 			if dropped {
 				continue
 			}
+			if col.IsComputed() {
+				return pgerror.NewErrorf(pgerror.CodeObjectNotInPrerequisiteStateError,
+					"column %q holds the values of an index expression, drop the index instead", col.Name)
+			}
 			// You can't drop a column depended on by a view unless CASCADE was
 			// specified.
 			for _, ref := range n.tableDesc.DependedOnBy {
@@ -238,6 +250,23 @@ func (n *alterTableNode) Start(params runParams) error {
 			if n.tableDesc.PrimaryIndex.ContainsColumnID(col.ID) {
 				return fmt.Errorf("column %q is referenced by the primary key", col.Name)
 			}
+			// Indexes on expressions referencing the column are considered
+			// to index the column itself.
+			computedIDs, err := computedColumnsReferencing(n.tableDesc, col)
+			if err != nil {
+				return err
+			}
+			isThisColumn := func(id sqlbase.ColumnID) bool {
+				if id == col.ID {
+					return true
+				}
+				for _, computedID := range computedIDs {
+					if id == computedID {
+						return true
+					}
+				}
+				return false
+			}
 			for _, idx := range n.tableDesc.AllNonDropIndexes() {
 				// We automatically drop indexes on that column that only
 				// index that column (and no other columns). If CASCADE is
@@ -262,7 +291,7 @@ func (n *alterTableNode) Start(params runParams) error {
 
 				// Analyze the index.
 				for _, id := range idx.ColumnIDs {
-					if id == col.ID {
+					if isThisColumn(id) {
 						containsThisColumn = true
 					} else {
 						containsOnlyThisColumn = false
@@ -277,7 +306,7 @@ func (n *alterTableNode) Start(params runParams) error {
 						// sufficient reason to reject the DROP.
 						continue
 					}
-					if id == col.ID {
+					if isThisColumn(id) {
 						containsThisColumn = true
 					}
 				}
@@ -285,7 +314,7 @@ func (n *alterTableNode) Start(params runParams) error {
 				// loop below is for the new encoding (where the STORING columns are
 				// always in the value part of a KV).
 				for _, id := range idx.StoreColumnIDs {
-					if id == col.ID {
+					if isThisColumn(id) {
 						containsThisColumn = true
 					}
 				}
@@ -419,6 +448,30 @@ func (n *alterTableNode) Start(params runParams) error {
 				return errors.Errorf("validating %s constraint %q unsupported", constraint.Kind, t.Constraint)
 			}
 
+		case *tree.AlterTableAlterColumnType:
+			changed, err := alterColumnType(n.tableDesc, t, &params.p.semaCtx, &params.p.evalCtx)
+			if err != nil {
+				return err
+			}
+			descriptorChanged = descriptorChanged || changed
+
+		case *tree.AlterTableSetNotNull:
+			col, dropped, err := n.tableDesc.FindColumnByName(t.Column)
+			if err != nil {
+				return err
+			}
+			if dropped {
+				return fmt.Errorf("column %q in the middle of being dropped", t.Column)
+			}
+			if _, err := n.tableDesc.FindActiveColumnByID(col.ID); err != nil {
+				return fmt.Errorf("column %q in the middle of being added, try again later", t.Column)
+			}
+			// The column is only marked as non-nullable once the schema changer
+			// has checked that the existing rows contain no NULLs.
+			if col.Nullable && !n.tableDesc.IsValidatingNotNull(col.ID) {
+				n.tableDesc.AddNotNullMutation(col.ID)
+			}
+
 		case tree.ColumnMutationCmd:
 			// Column mutations
 			col, dropped, err := n.tableDesc.FindColumnByName(t.GetColumn())
@@ -428,6 +481,10 @@ func (n *alterTableNode) Start(params runParams) error {
 			if dropped {
 				return fmt.Errorf("column %q in the middle of being dropped", t.GetColumn())
 			}
+			if _, ok := t.(*tree.AlterTableDropNotNull); ok && n.tableDesc.IsValidatingNotNull(col.ID) {
+				return fmt.Errorf("NOT NULL constraint on column %q in the middle of being added, try again later",
+					t.GetColumn())
+			}
 			if err := applyColumnMutation(
 				&col, t, &params.p.semaCtx, &params.p.evalCtx,
 			); err != nil {
@@ -0,0 +1,245 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// applyJoinNode implements a join with a LATERAL data source, i.e. a
+// data source that refers to the columns of the data sources that
+// precede it in the FROM clause. The right side of the join is planned
+// and run anew for every row of the left side, with the current values
+// of the referenced columns, in the same way as correlated subqueries.
+type applyJoinNode struct {
+	planner  *planner
+	joinType joinType
+
+	// left is the data source for the left side of the join.
+	left planDataSource
+
+	// rightExpr is the LATERAL data source, and rightColumns contains the
+	// columns it produces.
+	rightExpr    tree.TableExpr
+	rightColumns sqlbase.ResultColumns
+
+	// scope is the scope through which the right side accesses the columns
+	// of the left side, and scopes is the stack of scopes visible to the
+	// right side (the last one is scope).
+	scope  *subqueryScope
+	scopes []*subqueryScope
+
+	// pred represents the join predicate.
+	pred *joinPredicate
+
+	// columns contains the metadata for the results of this node.
+	columns sqlbase.ResultColumns
+
+	run applyJoinRun
+}
+
+// applyJoinRun contains the run-time state of applyJoinNode during
+// local execution.
+type applyJoinRun struct {
+	// rightPlan is the plan of the right side for the current left row, if
+	// any.
+	rightPlan planNode
+	// matched is set once a row of rightPlan matches the current left row.
+	matched bool
+
+	// output contains the current result row.
+	output tree.Datums
+	// emptyRight contains NULLs, to fill the result rows of a left outer
+	// join when the right side has no matching rows.
+	emptyRight tree.Datums
+}
+
+// makeLateralJoin constructs a planDataSource for a JOIN whose right
+// side is a LATERAL data source. If the right side does not refer to the
+// left side, this is a regular join.
+func (p *planner) makeLateralJoin(
+	ctx context.Context,
+	astJoinType string,
+	left planDataSource,
+	rightExpr *tree.AliasedTableExpr,
+	cond tree.JoinCond,
+) (planDataSource, error) {
+	// The right side can refer to the columns of the left side.
+	scope := &subqueryScope{sources: multiSourceInfo{left.info}}
+	outerScopes := p.outerScopes
+	p.outerScopes = append(outerScopes[:len(outerScopes):len(outerScopes)], scope)
+	scopes := p.outerScopes
+	rightSrc, err := p.getDataSource(ctx, rightExpr, nil, publicColumns)
+	p.outerScopes = outerScopes
+	if err != nil {
+		return planDataSource{}, err
+	}
+
+	if len(scope.cols) == 0 {
+		return p.makeJoin(ctx, astJoinType, left, rightSrc, cond)
+	}
+
+	// The plan of the right side refers to the left side through the
+	// scope, which is only populated during execution; it is only used to
+	// determine the columns of the right side. The right side is planned
+	// again for every row of the left side.
+	rightSrc.plan.Close(ctx)
+
+	switch astJoinType {
+	case "RIGHT JOIN", "FULL JOIN":
+		return planDataSource{}, pgerror.NewErrorf(pgerror.CodeInvalidColumnReferenceError,
+			"the combining JOIN type must be INNER or LEFT for a LATERAL reference")
+	}
+
+	return p.makeJoinWithNode(ctx, astJoinType, left, rightSrc, cond,
+		func(typ joinType, left, right planDataSource, pred *joinPredicate, info *dataSourceInfo) planNode {
+			return &applyJoinNode{
+				planner:      p,
+				joinType:     typ,
+				left:         left,
+				rightExpr:    rightExpr,
+				rightColumns: right.info.sourceColumns,
+				scope:        scope,
+				scopes:       scopes,
+				pred:         pred,
+				columns:      info.sourceColumns,
+			}
+		})
+}
+
+// Start implements the planNode interface.
+func (n *applyJoinNode) Start(params runParams) error {
+	n.run.output = make(tree.Datums, len(n.columns))
+	if n.joinType == joinTypeLeftOuter {
+		n.run.emptyRight = make(tree.Datums, len(n.rightColumns))
+		for i := range n.run.emptyRight {
+			n.run.emptyRight[i] = tree.DNull
+		}
+	}
+	return n.left.plan.Start(params)
+}
+
+// Next implements the planNode interface.
+func (n *applyJoinNode) Next(params runParams) (bool, error) {
+	for {
+		if err := params.p.cancelChecker.Check(); err != nil {
+			return false, err
+		}
+
+		if n.run.rightPlan != nil {
+			next, err := n.run.rightPlan.Next(params)
+			if err != nil {
+				return false, err
+			}
+			if next {
+				leftRow := n.left.plan.Values()
+				rightRow := n.run.rightPlan.Values()
+				match, err := n.matches(params, leftRow, rightRow)
+				if err != nil {
+					return false, err
+				}
+				if match {
+					n.run.matched = true
+					n.pred.prepareRow(n.run.output, leftRow, rightRow)
+					return true, nil
+				}
+				continue
+			}
+			n.closeRight(params.ctx)
+			if !n.run.matched && n.joinType == joinTypeLeftOuter {
+				n.pred.prepareRow(n.run.output, n.left.plan.Values(), n.run.emptyRight)
+				return true, nil
+			}
+		}
+
+		next, err := n.left.plan.Next(params)
+		if !next || err != nil {
+			return false, err
+		}
+		if err := n.startRight(params); err != nil {
+			return false, err
+		}
+		n.run.matched = false
+	}
+}
+
+// startRight plans and starts the right side of the join for the current
+// row of the left side.
+func (n *applyJoinNode) startRight(params runParams) error {
+	leftRow := n.left.plan.Values()
+	values := make(tree.Datums, len(n.scope.cols))
+	for i, colIdx := range n.scope.cols {
+		values[i] = leftRow[colIdx]
+	}
+	// The values stay visible to the right side until the next left row.
+	n.scope.values = values
+
+	ctx := params.ctx
+	p := n.planner
+	outerScopes := p.outerScopes
+	p.outerScopes = n.scopes
+	right, err := p.getDataSource(ctx, n.rightExpr, nil, publicColumns)
+	p.outerScopes = outerScopes
+	if err != nil {
+		return err
+	}
+	plan, err := p.optimizePlan(ctx, right.plan, allColumns(right.plan))
+	if err != nil {
+		right.plan.Close(ctx)
+		return err
+	}
+	if err := p.startPlan(ctx, plan); err != nil {
+		plan.Close(ctx)
+		return err
+	}
+	n.run.rightPlan = plan
+	return nil
+}
+
+// matches returns true if the given rows of the left and right sides
+// satisfy the join predicate.
+func (n *applyJoinNode) matches(params runParams, leftRow, rightRow tree.Datums) (bool, error) {
+	for i, leftCol := range n.pred.leftEqualityIndices {
+		l, r := leftRow[leftCol], rightRow[n.pred.rightEqualityIndices[i]]
+		if l == tree.DNull || r == tree.DNull {
+			return false, nil
+		}
+		eq, err := n.pred.cmpFunctions[i](&params.p.evalCtx, l, r)
+		if err != nil || eq != tree.DBoolTrue {
+			return false, err
+		}
+	}
+	return n.pred.eval(&params.p.evalCtx, n.run.output, leftRow, rightRow)
+}
+
+func (n *applyJoinNode) closeRight(ctx context.Context) {
+	if n.run.rightPlan != nil {
+		n.run.rightPlan.Close(ctx)
+		n.run.rightPlan = nil
+	}
+}
+
+// Values implements the planNode interface.
+func (n *applyJoinNode) Values() tree.Datums { return n.run.output }
+
+// Close implements the planNode interface.
+func (n *applyJoinNode) Close(ctx context.Context) {
+	n.closeRight(ctx)
+	n.left.plan.Close(ctx)
+}
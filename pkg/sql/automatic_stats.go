@@ -0,0 +1,199 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+)
+
+var automaticStatsEnabled = settings.RegisterBoolSetting(
+	"sql.stats.automatic_collection.enabled",
+	"automatically refresh table statistics after a fraction of the rows have changed",
+	false,
+)
+
+var automaticStatsFractionStaleRows = settings.RegisterNonNegativeFloatSetting(
+	"sql.stats.automatic_collection.fraction_stale_rows",
+	"target fraction of stale rows per table that will trigger a statistics refresh",
+	0.2,
+)
+
+var automaticStatsMinStaleRows = settings.RegisterIntSetting(
+	"sql.stats.automatic_collection.min_stale_rows",
+	"target minimum number of stale rows per table that will trigger a statistics refresh",
+	500,
+)
+
+// autoStatsName is the name of the statistics created by the automatic
+// refresher.
+const autoStatsName = "__auto__"
+
+// refreshChanBufferLen is the length of the buffered channel used by the
+// statsRefresher. Mutations are dropped if the refresher falls behind.
+const refreshChanBufferLen = 256
+
+// mutation contains the number of rows modified by a statement on a table.
+type mutation struct {
+	tableID      sqlbase.ID
+	rowsAffected int
+}
+
+// statsRefresher keeps track of the number of rows modified on each table and
+// runs CREATE STATISTICS on a table once the number of rows modified since the
+// last refresh exceeds sql.stats.automatic_collection.fraction_stale_rows of
+// the row count of the table (and sql.stats.automatic_collection.min_stale_rows).
+//
+// The counts are kept in memory on each node, so they are an approximation:
+// they are lost on restart, they include rows written by transactions that
+// are later aborted, and each node only sees the mutations it executes.
+type statsRefresher struct {
+	e          *Executor
+	memMetrics *MemoryMetrics
+	mutations  chan mutation
+
+	// rowsChanged is the number of rows modified on each table since the last
+	// refresh. Only accessed by the refresher worker.
+	rowsChanged map[sqlbase.ID]int64
+}
+
+func makeStatsRefresher(e *Executor, memMetrics *MemoryMetrics) statsRefresher {
+	return statsRefresher{
+		e:           e,
+		memMetrics:  memMetrics,
+		mutations:   make(chan mutation, refreshChanBufferLen),
+		rowsChanged: make(map[sqlbase.ID]int64),
+	}
+}
+
+// start starts the worker that refreshes the statistics.
+func (r *statsRefresher) start(ctx context.Context, stopper *stop.Stopper) {
+	stopper.RunWorker(ctx, func(ctx context.Context) {
+		for {
+			select {
+			case m := <-r.mutations:
+				r.rowsChanged[m.tableID] += int64(m.rowsAffected)
+				r.maybeRefreshStats(ctx, m.tableID)
+
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// notifyMutation is called after a statement modifies rowsAffected rows of a
+// table. It never blocks.
+func (r *statsRefresher) notifyMutation(tableDesc *sqlbase.TableDescriptor, rowsAffected int) {
+	if !automaticStatsEnabled.Get(&r.e.cfg.Settings.SV) {
+		return
+	}
+	if rowsAffected == 0 || sqlbase.IsReservedID(tableDesc.ID) || !tableDesc.IsTable() {
+		return
+	}
+	select {
+	case r.mutations <- mutation{tableID: tableDesc.ID, rowsAffected: rowsAffected}:
+	default:
+		// Don't block the statement if the refresher is busy.
+	}
+}
+
+// maybeRefreshStats runs CREATE STATISTICS on the given table if enough rows
+// have changed since the last refresh.
+func (r *statsRefresher) maybeRefreshStats(ctx context.Context, tableID sqlbase.ID) {
+	ie := InternalExecutor{LeaseManager: r.e.cfg.LeaseManager}
+	var rowCount int64
+	var tn tree.TableName
+	var dropped bool
+	if err := r.e.cfg.DB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		row, err := ie.QueryRowInTransaction(
+			ctx, "get-table-row-count", txn,
+			`SELECT "rowCount" FROM system.table_statistics
+			 WHERE "tableID" = $1 ORDER BY "createdAt" DESC LIMIT 1`,
+			tableID,
+		)
+		if err != nil {
+			return err
+		}
+		if row != nil {
+			rowCount = int64(tree.MustBeDInt(row[0]))
+		}
+
+		desc, err := sqlbase.GetTableDescFromID(ctx, txn, tableID)
+		if err != nil {
+			return err
+		}
+		if dropped = desc.Dropped(); dropped {
+			return nil
+		}
+		dbDesc, err := sqlbase.GetDatabaseDescFromID(ctx, txn, desc.ParentID)
+		if err != nil {
+			return err
+		}
+		tn = tree.TableName{DatabaseName: tree.Name(dbDesc.Name), TableName: tree.Name(desc.Name)}
+		return nil
+	}); err != nil {
+		log.Warningf(ctx, "failed to check statistics of table %d: %v", tableID, err)
+		return
+	}
+	if dropped {
+		delete(r.rowsChanged, tableID)
+		return
+	}
+
+	target := automaticStatsFractionStaleRows.Get(&r.e.cfg.Settings.SV) * float64(rowCount)
+	if minStale := automaticStatsMinStaleRows.Get(&r.e.cfg.Settings.SV); target < float64(minStale) {
+		target = float64(minStale)
+	}
+	if float64(r.rowsChanged[tableID]) < target {
+		return
+	}
+	delete(r.rowsChanged, tableID)
+
+	session := NewSession(ctx, SessionArgs{User: security.RootUser}, r.e, nil, r.memMetrics)
+	session.StartUnlimitedMonitor()
+	defer session.Finish(r.e)
+
+	stmt := fmt.Sprintf("CREATE STATISTICS %s FROM %s", autoStatsName, tree.AsString(&tn))
+	res, err := r.e.ExecuteStatementsBuffered(session, stmt, nil, 1)
+	if err != nil {
+		log.Warningf(ctx, "failed to refresh statistics of table %s: %v", &tn, err)
+		return
+	}
+	res.Close(ctx)
+}
+
+// mutatedTable returns the descriptor of the table modified by the given
+// plan, if the plan is an INSERT, UPDATE, UPSERT or DELETE.
+func mutatedTable(plan planNode) *sqlbase.TableDescriptor {
+	switch n := plan.(type) {
+	case *insertNode:
+		return n.tw.tableDesc()
+	case *updateNode:
+		return n.tw.tableDesc()
+	case *deleteNode:
+		return n.tw.tableDesc()
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestAutomaticStatsRefresh(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	r := sqlutils.MakeSQLRunner(db)
+	r.Exec(t, `SET CLUSTER SETTING sql.stats.automatic_collection.enabled = true`)
+	r.Exec(t, `SET CLUSTER SETTING sql.stats.automatic_collection.min_stale_rows = 10`)
+	r.Exec(t, `CREATE DATABASE d`)
+	r.Exec(t, `CREATE TABLE d.t (a INT PRIMARY KEY, b INT)`)
+
+	// Too few rows are modified to trigger a refresh.
+	r.Exec(t, `INSERT INTO d.t SELECT i, i FROM generate_series(1, 5) AS g(i)`)
+
+	// These rows push the number of modified rows over the threshold.
+	r.Exec(t, `INSERT INTO d.t SELECT i, i FROM generate_series(6, 20) AS g(i)`)
+
+	const query = `SELECT row_count FROM [SHOW STATISTICS FOR TABLE d.t]
+	               WHERE statistics_name = '__auto__'`
+	testutils.SucceedsSoon(t, func() error {
+		rows := r.QueryStr(t, query)
+		if len(rows) == 0 {
+			return errors.New("statistics not refreshed yet")
+		}
+		if rows[0][0] != "20" {
+			t.Fatalf("expected row count 20, got %s", rows[0][0])
+		}
+		return nil
+	})
+
+	// Mutations are not tracked while automatic collection is disabled.
+	r.Exec(t, `SET CLUSTER SETTING sql.stats.automatic_collection.enabled = false`)
+	r.Exec(t, `DELETE FROM d.t WHERE a > 5`)
+	r.CheckQueryResults(t, `SELECT count(*) FROM [SHOW STATISTICS FOR TABLE d.t]`, [][]string{{"1"}})
+}
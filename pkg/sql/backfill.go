@@ -15,7 +15,9 @@
 package sql
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -26,6 +28,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -111,6 +114,7 @@ func (sc *SchemaChanger) runBackfill(
 	// mutations. Collect the elements that are part of the mutation.
 	var droppedIndexDescs []sqlbase.IndexDescriptor
 	var addedIndexDescs []sqlbase.IndexDescriptor
+	var notNullColIDs []sqlbase.ColumnID
 	// Indexes within the Mutations slice for checkpointing.
 	mutationSentinel := -1
 	var droppedIndexMutationIdx int
@@ -142,11 +146,14 @@ func (sc *SchemaChanger) runBackfill(
 			switch t := m.Descriptor_.(type) {
 			case *sqlbase.DescriptorMutation_Column:
 				desc := m.GetColumn()
-				if desc.DefaultExpr != nil || !desc.Nullable {
+				if desc.DefaultExpr != nil || !desc.Nullable || desc.IsComputed() ||
+					m.ColumnConversion != nil {
 					needColumnBackfill = true
 				}
 			case *sqlbase.DescriptorMutation_Index:
 				addedIndexDescs = append(addedIndexDescs, *t.Index)
+			case *sqlbase.DescriptorMutation_NotNull:
+				notNullColIDs = append(notNullColIDs, t.NotNull.ColumnID)
 			default:
 				return errors.Errorf("unsupported mutation: %+v", m)
 			}
@@ -160,6 +167,8 @@ func (sc *SchemaChanger) runBackfill(
 				if droppedIndexMutationIdx == mutationSentinel {
 					droppedIndexMutationIdx = i
 				}
+			case *sqlbase.DescriptorMutation_NotNull:
+				// Nothing to do: the column was never marked as non-nullable.
 			default:
 				return errors.Errorf("unsupported mutation: %+v", m)
 			}
@@ -189,6 +198,63 @@ func (sc *SchemaChanger) runBackfill(
 		}
 	}
 
+	// Validate new NOT NULL constraints.
+	if len(notNullColIDs) > 0 {
+		if err := sc.validateNotNulls(ctx, lease, tableDesc, notNullColIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateNotNulls checks that the existing rows of the table don't contain
+// NULLs in the columns to which a NOT NULL constraint is being added. Rows
+// written since all the nodes moved the constraint to the
+// DELETE_AND_WRITE_ONLY state already satisfy it. A violation results in a
+// permanent error naming the primary key of an offending row.
+func (sc *SchemaChanger) validateNotNulls(
+	ctx context.Context,
+	lease *sqlbase.TableDescriptor_SchemaChangeLease,
+	tableDesc *sqlbase.TableDescriptor,
+	colIDs []sqlbase.ColumnID,
+) error {
+	pkCols := make([]sqlbase.ColumnDescriptor, len(tableDesc.PrimaryIndex.ColumnIDs))
+	pkColNames := make([]string, len(pkCols))
+	for i, id := range tableDesc.PrimaryIndex.ColumnIDs {
+		col, err := tableDesc.FindActiveColumnByID(id)
+		if err != nil {
+			return err
+		}
+		pkCols[i] = *col
+		pkColNames[i] = tree.Name(col.Name).String()
+	}
+
+	ie := InternalExecutor{LeaseManager: sc.leaseMgr}
+	for _, id := range colIDs {
+		if err := sc.ExtendLease(ctx, lease); err != nil {
+			return err
+		}
+		col, err := tableDesc.FindActiveColumnByID(id)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`SELECT %s FROM [%d AS t] WHERE %s IS NULL LIMIT 1`,
+			strings.Join(pkColNames, ", "), tableDesc.ID, tree.Name(col.Name))
+		var row tree.Datums
+		if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+			var err error
+			row, err = ie.QueryRowInTransaction(ctx, "validate-not-null", txn, query)
+			return err
+		}); err != nil {
+			return err
+		}
+		if row != nil {
+			return pgerror.NewErrorf(pgerror.CodeNotNullViolationError,
+				"validation of NOT NULL constraint failed on column %q: found NULL in row (%s)",
+				col.Name, labeledRowValues(pkCols, row))
+		}
+	}
 	return nil
 }
 
@@ -297,7 +363,7 @@ func (sc *SchemaChanger) truncateIndexes(
 					return err
 				}
 
-				rd, err := sqlbase.MakeRowDeleter(txn, tableDesc, nil, nil, false, alloc)
+				rd, err := sqlbase.MakeRowDeleter(txn, tableDesc, nil, nil, false, nil, alloc)
 				if err != nil {
 					return err
 				}
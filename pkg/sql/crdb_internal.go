@@ -280,6 +280,12 @@ CREATE TABLE crdb_internal.schema_changes (
 					mutType = "INDEX"
 					targetID = tree.NewDInt(tree.DInt(int64(d.Index.ID)))
 					targetName = tree.NewDString(d.Index.Name)
+				case *sqlbase.DescriptorMutation_NotNull:
+					mutType = "NOT NULL"
+					targetID = tree.NewDInt(tree.DInt(int64(d.NotNull.ColumnID)))
+					if col, err := table.FindColumnByID(d.NotNull.ColumnID); err == nil {
+						targetName = tree.NewDString(col.Name)
+					}
 				}
 				if err := addRow(
 					tableID,
@@ -51,6 +51,9 @@ func (p *planner) CreateDatabase(n *tree.CreateDatabase) (planNode, error) {
 	if n.Name == "" {
 		return nil, errEmptyDatabaseName
 	}
+	if err := checkTemporarySchemaPrefix(string(n.Name)); err != nil {
+		return nil, err
+	}
 
 	if tmpl := n.Template; tmpl != "" {
 		// See https://www.postgresql.org/docs/current/static/manage-ag-templatedbs.html
@@ -133,7 +136,7 @@ type createIndexNode struct {
 //   notes: postgres requires CREATE on the table.
 //          mysql requires INDEX on the table.
 func (p *planner) CreateIndex(ctx context.Context, n *tree.CreateIndex) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +169,18 @@ func (n *createIndexNode) Start(params runParams) error {
 		Unique:           n.n.Unique,
 		StoreColumnNames: n.n.Storing.ToStrings(),
 	}
-	if err := indexDesc.FillColumns(n.n.Columns); err != nil {
+	if n.n.Inverted {
+		indexDesc.Type = sqlbase.IndexDescriptor_INVERTED
+	}
+	columns, err := makeIndexExprColumns(
+		n.tableDesc, n.n.Columns, &params.p.semaCtx, func(col sqlbase.ColumnDescriptor) {
+			n.tableDesc.AddColumnMutation(col, sqlbase.DescriptorMutation_ADD)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if err := indexDesc.FillColumns(columns); err != nil {
 		return err
 	}
 	if n.n.PartitionBy != nil {
@@ -466,6 +480,13 @@ type createViewNode struct {
 //						selected columns.
 //          mysql requires CREATE VIEW plus SELECT on all the selected columns.
 func (p *planner) CreateView(ctx context.Context, n *tree.CreateView) (planNode, error) {
+	if n.AsSource.With != nil {
+		// The table names in the view query are qualified below, which
+		// would break references to CTEs.
+		return nil, pgerror.Unimplemented("view-cte",
+			"CREATE VIEW with a WITH clause is not supported")
+	}
+
 	name, err := n.Name.NormalizeWithDatabaseName(p.session.Database)
 	if err != nil {
 		return nil, err
@@ -636,27 +657,55 @@ type createTableNode struct {
 // Privileges: CREATE on database.
 //   Notes: postgres/mysql require CREATE on database.
 func (p *planner) CreateTable(ctx context.Context, n *tree.CreateTable) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
-	if err != nil {
-		return nil, err
-	}
-
-	dbDesc, err := MustGetDatabaseDesc(ctx, p.txn, p.getVirtualTabler(), tn.Database())
-	if err != nil {
-		return nil, err
+	var dbDesc *sqlbase.DatabaseDescriptor
+	var err error
+	if n.Temporary {
+		if n.Interleave != nil {
+			return nil, pgerror.NewError(pgerror.CodeInvalidTableDefinitionError,
+				"temporary tables cannot be interleaved")
+		}
+		// The temporary schema of the session is created along with its first
+		// temporary table, in which case dbDesc is nil here.
+		dbDesc, err = p.qualifyTemporaryTableName(ctx, &n.Table)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkTemporarySchemaAccess(tn); err != nil {
+			return nil, err
+		}
+		if _, ok := parseTemporarySchemaName(tn.Database()); ok {
+			return nil, pgerror.NewError(pgerror.CodeInvalidTableDefinitionError,
+				"cannot create relations in temporary schemas unless temporary")
+		}
+		dbDesc, err = MustGetDatabaseDesc(ctx, p.txn, p.getVirtualTabler(), tn.Database())
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if err := p.CheckPrivilege(dbDesc, privilege.CREATE); err != nil {
-		return nil, err
+	if dbDesc != nil {
+		if err := p.CheckPrivilege(dbDesc, privilege.CREATE); err != nil {
+			return nil, err
+		}
 	}
 
 	HoistConstraints(n)
 	for _, def := range n.Defs {
 		switch t := def.(type) {
 		case *tree.ForeignKeyConstraintTableDef:
-			if _, err := t.Table.NormalizeWithDatabaseName(p.session.Database); err != nil {
+			refTn, err := p.normalizeTableName(ctx, &t.Table)
+			if err != nil {
 				return nil, err
 			}
+			if _, ok := parseTemporarySchemaName(refTn.Database()); ok && !n.Temporary {
+				return nil, pgerror.NewError(pgerror.CodeInvalidTableDefinitionError,
+					"constraints on permanent tables may reference only permanent tables")
+			}
 		}
 	}
 
@@ -720,6 +769,14 @@ func HoistConstraints(n *tree.CreateTable) {
 }
 
 func (n *createTableNode) Start(params runParams) error {
+	if n.dbDesc == nil {
+		dbDesc, err := params.p.createTemporarySchema(params.ctx)
+		if err != nil {
+			return err
+		}
+		n.dbDesc = dbDesc
+	}
+
 	tKey := tableKey{parentID: n.dbDesc.ID, name: n.n.Table.TableName().Table()}
 	key := tKey.Key()
 	if exists, err := descExists(params.ctx, params.p.txn, key); err == nil && exists {
@@ -1008,16 +1065,6 @@ func resolveFK(
 		}
 	}
 
-	if d.Actions.Delete != tree.NoAction &&
-		d.Actions.Delete != tree.Restrict {
-		feature := fmt.Sprintf("unsupported: ON DELETE %s", d.Actions.Delete)
-		return pgerror.Unimplemented(feature, feature)
-	}
-	if d.Actions.Update != tree.NoAction &&
-		d.Actions.Update != tree.Restrict {
-		feature := fmt.Sprintf("unsupported: ON UPDATE %s", d.Actions.Update)
-		return pgerror.Unimplemented(feature, feature)
-	}
 	ref := sqlbase.ForeignKeyReference{
 		Table:           target.ID,
 		Index:           targetIdx.ID,
@@ -1574,7 +1621,14 @@ func MakeTableDesc(
 				Name:             string(d.Name),
 				StoreColumnNames: d.Storing.ToStrings(),
 			}
-			if err := idx.FillColumns(d.Columns); err != nil {
+			if d.Inverted {
+				idx.Type = sqlbase.IndexDescriptor_INVERTED
+			}
+			columns, err := makeIndexExprColumns(&desc, d.Columns, semaCtx, desc.AddColumn)
+			if err != nil {
+				return desc, err
+			}
+			if err := idx.FillColumns(columns); err != nil {
 				return desc, err
 			}
 			if d.PartitionBy != nil {
@@ -1596,7 +1650,19 @@ func MakeTableDesc(
 				Unique:           true,
 				StoreColumnNames: d.Storing.ToStrings(),
 			}
-			if err := idx.FillColumns(d.Columns); err != nil {
+			if d.PrimaryKey {
+				for _, c := range d.Columns {
+					if c.Expr != nil {
+						return desc, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+							"expressions are not allowed in primary keys: %s", c.Expr)
+					}
+				}
+			}
+			columns, err := makeIndexExprColumns(&desc, d.Columns, semaCtx, desc.AddColumn)
+			if err != nil {
+				return desc, err
+			}
+			if err := idx.FillColumns(columns); err != nil {
 				return desc, err
 			}
 			if d.PartitionBy != nil {
@@ -0,0 +1,262 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// histogramSamples is the number of sample rows collected by each sampler
+// and used to build the histograms.
+const histogramSamples = 10000
+
+// histogramBuckets is the maximum number of buckets in the histograms.
+const histogramBuckets = 200
+
+type createStatsNode struct {
+	n         *tree.CreateStats
+	tableDesc *sqlbase.TableDescriptor
+	// columns contains one set of columns for each statistic to be created.
+	columns [][]sqlbase.ColumnDescriptor
+}
+
+// CreateStatistics creates statistics on a table and stores them in
+// system.table_statistics.
+// Privileges: SELECT on table.
+func (p *planner) CreateStatistics(ctx context.Context, n *tree.CreateStats) (planNode, error) {
+	tn, err := p.normalizeTableName(ctx, &n.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	tableDesc, err := MustGetTableDesc(ctx, p.txn, p.getVirtualTabler(), tn, false /*allowAdding*/)
+	if err != nil {
+		return nil, err
+	}
+	if !tableDesc.IsTable() || tableDesc.IsVirtualTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn, "table")
+	}
+
+	if err := p.CheckPrivilege(tableDesc, privilege.SELECT); err != nil {
+		return nil, err
+	}
+
+	var columns [][]sqlbase.ColumnDescriptor
+	if len(n.ColumnNames) == 0 {
+		columns = defaultStatsColumns(tableDesc)
+	} else {
+		cols, err := tableDesc.FindActiveColumnsByNames(n.ColumnNames)
+		if err != nil {
+			return nil, err
+		}
+		columns = [][]sqlbase.ColumnDescriptor{cols}
+	}
+
+	return &createStatsNode{n: n, tableDesc: tableDesc, columns: columns}, nil
+}
+
+// defaultStatsColumns returns the sets of columns on which statistics are
+// collected when CREATE STATISTICS does not specify any columns: the first
+// column of each index, and each prefix of more than one column of each index.
+// The multi-column statistics capture the correlation between the columns of
+// composite keys.
+func defaultStatsColumns(desc *sqlbase.TableDescriptor) [][]sqlbase.ColumnDescriptor {
+	var result [][]sqlbase.ColumnDescriptor
+	// seen contains the sets of columns already added to the result; the order
+	// of the columns does not matter for distinct counts.
+	seen := make(map[string]struct{})
+	addIndex := func(idx *sqlbase.IndexDescriptor) {
+		var colSet util.FastIntSet
+		cols := make([]sqlbase.ColumnDescriptor, 0, len(idx.ColumnIDs))
+		for _, id := range idx.ColumnIDs {
+			col, err := desc.FindActiveColumnByID(id)
+			if err != nil {
+				return
+			}
+			colSet.Add(int(id))
+			cols = append(cols, *col)
+			if _, ok := seen[colSet.String()]; ok {
+				continue
+			}
+			seen[colSet.String()] = struct{}{}
+			result = append(result, cols[:len(cols):len(cols)])
+		}
+	}
+	addIndex(&desc.PrimaryIndex)
+	for i := range desc.Indexes {
+		addIndex(&desc.Indexes[i])
+	}
+	return result
+}
+
+func (n *createStatsNode) Start(params runParams) error {
+	ctx := params.ctx
+	p := params.p
+
+	job := p.ExecCfg().JobRegistry.NewJob(jobs.Record{
+		Description:   tree.AsString(n.n),
+		Username:      p.User(),
+		DescriptorIDs: sqlbase.IDs{n.tableDesc.ID},
+		Details:       n.jobDetails(),
+	})
+	if err := job.Created(ctx, jobs.WithoutCancel); err != nil {
+		return err
+	}
+	if err := job.Started(ctx); err != nil {
+		return err
+	}
+
+	err := n.runSampling(ctx, p)
+	if err == nil {
+		if cache := p.ExecCfg().TableStatsCache; cache != nil {
+			cache.InvalidateTableStats(ctx, n.tableDesc.ID)
+		}
+	}
+	if finishErr := job.FinishedWith(ctx, err); finishErr != nil && err == nil {
+		err = finishErr
+	}
+	return err
+}
+
+func (n *createStatsNode) jobDetails() jobs.CreateStatsDetails {
+	details := jobs.CreateStatsDetails{
+		Name:        string(n.n.Name),
+		TableID:     n.tableDesc.ID,
+		ColumnLists: make([]jobs.CreateStatsDetails_ColumnList, len(n.columns)),
+	}
+	for i, cols := range n.columns {
+		ids := make([]sqlbase.ColumnID, len(cols))
+		for j := range cols {
+			ids[j] = cols[j].ID
+		}
+		details.ColumnLists[i].IDs = ids
+	}
+	return details
+}
+
+// runSampling plans and runs a distributed flow that scans the table, samples
+// the rows and builds sketches on each node, and aggregates the results on
+// the gateway. The final stage writes the statistics to
+// system.table_statistics.
+func (n *createStatsNode) runSampling(ctx context.Context, p *planner) error {
+	scan := p.Scan()
+	if err := scan.initTable(p, n.tableDesc, nil /* indexHints */, publicColumns, nil /* wantedColumns */); err != nil {
+		return err
+	}
+	scan.spans = []roachpb.Span{n.tableDesc.PrimaryIndexSpan()}
+
+	// Only the columns that appear in statistics are sampled.
+	var outCols []uint32
+	var sampledColumnIDs []sqlbase.ColumnID
+	sampledColIdx := make(map[sqlbase.ColumnID]uint32)
+	sketchSpecs := make([]distsqlrun.SketchSpec, len(n.columns))
+	for i, cols := range n.columns {
+		sketchSpecs[i] = distsqlrun.SketchSpec{
+			SketchType: distsqlrun.SketchType_HLL_PLUS_PLUS_V1,
+			StatName:   string(n.n.Name),
+		}
+		// Histograms are only collected for single-column statistics.
+		if len(cols) == 1 {
+			sketchSpecs[i].GenerateHistogram = true
+			sketchSpecs[i].HistogramMaxBuckets = histogramBuckets
+		}
+		for _, c := range cols {
+			idx, ok := sampledColIdx[c.ID]
+			if !ok {
+				idx = uint32(len(outCols))
+				sampledColIdx[c.ID] = idx
+				outCols = append(outCols, uint32(scan.colIdxMap[c.ID]))
+				sampledColumnIDs = append(sampledColumnIDs, c.ID)
+			}
+			sketchSpecs[i].Columns = append(sketchSpecs[i].Columns, idx)
+		}
+	}
+
+	dsp := p.session.distSQLPlanner
+	planCtx := dsp.newPlanningCtx(ctx, &p.evalCtx, p.txn)
+	plan, err := dsp.createTableReaders(&planCtx, scan, outCols)
+	if err != nil {
+		return err
+	}
+
+	sampler := &distsqlrun.SamplerSpec{
+		Sketches:   sketchSpecs,
+		SampleSize: histogramSamples,
+	}
+	// The sampler outputs the sampled columns followed by the row rank, the
+	// sketch index, the number of rows, the number of NULLs and the sketch
+	// data.
+	outTypes := make([]sqlbase.ColumnType, 0, len(plan.ResultTypes)+5)
+	outTypes = append(outTypes, plan.ResultTypes...)
+	outTypes = append(outTypes,
+		sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT},
+		sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT},
+		sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT},
+		sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT},
+		sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_BYTES},
+	)
+	plan.AddNoGroupingStage(
+		distsqlrun.ProcessorCoreUnion{Sampler: sampler},
+		distsqlrun.PostProcessSpec{},
+		outTypes,
+		distsqlrun.Ordering{},
+	)
+
+	agg := &distsqlrun.SampleAggregatorSpec{
+		Sketches:         sketchSpecs,
+		SampleSize:       histogramSamples,
+		SampledColumnIDs: sampledColumnIDs,
+		TableID:          n.tableDesc.ID,
+	}
+	plan.AddSingleGroupStage(
+		dsp.nodeDesc.NodeID,
+		distsqlrun.ProcessorCoreUnion{SampleAggregator: agg},
+		distsqlrun.PostProcessSpec{},
+		[]sqlbase.ColumnType{},
+	)
+
+	dsp.FinalizePlan(&planCtx, &plan)
+
+	recv, err := makeDistSQLReceiver(
+		ctx,
+		nil, /* resultWriter */
+		p.ExecCfg().RangeDescriptorCache,
+		p.ExecCfg().LeaseHolderCache,
+		p.txn,
+		func(ts hlc.Timestamp) {
+			_ = p.ExecCfg().Clock.Update(ts)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if err := dsp.Run(&planCtx, p.txn, &plan, &recv, p.evalCtx); err != nil {
+		return err
+	}
+	return recv.err
+}
+
+func (*createStatsNode) Next(runParams) (bool, error) { return false, nil }
+func (*createStatsNode) Close(context.Context)        {}
+func (*createStatsNode) Values() tree.Datums          { return tree.Datums{} }
@@ -259,6 +259,26 @@ func (p *planner) getSources(
 		if err != nil {
 			return planDataSource{}, err
 		}
+		if hasLateralSource(sources[1:]) {
+			// LATERAL sources can refer to all the sources that precede
+			// them, so the sources are joined from left to right.
+			for _, src := range sources[1:] {
+				if t, ok := src.(*tree.AliasedTableExpr); ok && t.Lateral {
+					left, err = p.makeLateralJoin(ctx, "CROSS JOIN", left, t, nil)
+				} else {
+					var right planDataSource
+					right, err = p.getDataSource(ctx, src, nil, scanVisibility)
+					if err != nil {
+						return planDataSource{}, err
+					}
+					left, err = p.makeJoin(ctx, "CROSS JOIN", left, right, nil)
+				}
+				if err != nil {
+					return planDataSource{}, err
+				}
+			}
+			return left, nil
+		}
 		right, err := p.getSources(ctx, sources[1:], scanVisibility)
 		if err != nil {
 			return planDataSource{}, err
@@ -267,6 +287,17 @@ func (p *planner) getSources(
 	}
 }
 
+// hasLateralSource returns true if one of the given sources is a LATERAL
+// data source.
+func hasLateralSource(sources []tree.TableExpr) bool {
+	for _, src := range sources {
+		if t, ok := src.(*tree.AliasedTableExpr); ok && t.Lateral {
+			return true
+		}
+	}
+	return false
+}
+
 // getVirtualDataSource attempts to find a virtual table with the
 // given name.
 func (p *planner) getVirtualDataSource(
@@ -369,6 +400,15 @@ func (p *planner) getDataSource(
 ) (planDataSource, error) {
 	switch t := src.(type) {
 	case *tree.NormalizableTableName:
+		// Is this perhaps a reference to a common table expression?
+		ds, foundCTE, err := p.getCTEDataSource(t)
+		if err != nil {
+			return planDataSource{}, err
+		}
+		if foundCTE {
+			return ds, nil
+		}
+
 		// Usual case: a table.
 		tn, err := p.QualifyWithDatabase(ctx, t)
 		if err != nil {
@@ -397,6 +437,9 @@ func (p *planner) getDataSource(
 		if err != nil {
 			return left, err
 		}
+		if lateral, ok := t.Right.(*tree.AliasedTableExpr); ok && lateral.Lateral {
+			return p.makeLateralJoin(ctx, t.Join, left, lateral, t.Cond)
+		}
 		right, err := p.getDataSource(ctx, t.Right, nil, scanVisibility)
 		if err != nil {
 			return right, err
@@ -457,6 +500,8 @@ func (p *planner) QualifyWithDatabase(
 		if err := p.searchAndQualifyDatabase(ctx, tn); err != nil {
 			return nil, err
 		}
+	} else if err := p.checkTemporarySchemaAccess(tn); err != nil {
+		return nil, err
 	}
 	return tn, nil
 }
@@ -0,0 +1,599 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// This file implements the decorrelation of subqueries: correlated
+// subqueries of common forms are rewritten into joins with the data
+// source of the enclosing query, so that they do not need to be planned
+// and run once per row (see subquery.evalCorrelated). Two forms are
+// recognized:
+//
+// - conjuncts of the WHERE clause of the form EXISTS (subquery),
+//   NOT EXISTS (subquery) and expr IN (subquery) become semi and anti
+//   joins with the FROM clause of the subquery, using the correlated
+//   conjuncts of the subquery's WHERE clause as join predicate:
+//
+//     SELECT * FROM t WHERE EXISTS (SELECT * FROM u WHERE u.x = t.x AND u.y > 0)
+//   ->
+//     SELECT * FROM t SEMI JOIN (SELECT * FROM u WHERE u.y > 0) ON u.x = t.x
+//
+// - scalar subqueries that compute a single aggregate, and whose
+//   correlated conjuncts are equalities between inner and outer
+//   expressions, become left outer joins with the subquery grouped by
+//   the inner expressions:
+//
+//     SELECT t.x, (SELECT max(u.v) FROM u WHERE u.x = t.x) FROM t
+//   ->
+//     SELECT t.x, m FROM t
+//       LEFT JOIN (SELECT max(u.v) AS m, u.x FROM u GROUP BY u.x) ON u.x = t.x
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// decorrelateWhere rewrites the conjuncts of the WHERE clause that test
+// correlated subqueries with EXISTS, NOT EXISTS or IN into semi and anti
+// joins with the renderNode's data source. It returns the remaining
+// conjuncts.
+func (r *renderNode) decorrelateWhere(ctx context.Context, where tree.Expr) tree.Expr {
+	conjuncts := splitConjuncts(where, nil)
+	var remaining tree.Exprs
+	for _, c := range conjuncts {
+		if !r.decorrelateConjunct(ctx, c) {
+			remaining = append(remaining, c)
+		}
+	}
+	if len(remaining) == len(conjuncts) {
+		return where
+	}
+	return joinConjuncts(remaining)
+}
+
+// decorrelateConjunct attempts to rewrite a conjunct of the WHERE clause
+// into a semi or anti join. It returns false if the conjunct does not have
+// a supported form, in which case it must be evaluated as a filter.
+func (r *renderNode) decorrelateConjunct(ctx context.Context, conjunct tree.Expr) bool {
+	var typ joinType
+	var sq *tree.Subquery
+	// lhs is the left operand of IN, if any.
+	var lhs tree.Expr
+	switch t := tree.StripParens(conjunct).(type) {
+	case *tree.ExistsExpr:
+		typ = joinTypeSemi
+		sq, _ = t.Subquery.(*tree.Subquery)
+	case *tree.NotExpr:
+		if e, ok := tree.StripParens(t.Expr).(*tree.ExistsExpr); ok {
+			typ = joinTypeAnti
+			sq, _ = e.Subquery.(*tree.Subquery)
+		}
+	case *tree.ComparisonExpr:
+		// NOT IN cannot be rewritten into an anti join, because it is not
+		// true when the subquery returns NULLs.
+		if t.Operator == tree.In {
+			typ = joinTypeSemi
+			sq, _ = t.Right.(*tree.Subquery)
+			lhs = t.Left
+		}
+	}
+	if sq == nil {
+		return false
+	}
+	clause := decorrelatableClause(sq.Select)
+	if clause == nil {
+		return false
+	}
+
+	p := r.planner
+	searchPath := p.session.SearchPath
+	if p.txCtx.IsAggregate(clause, searchPath) {
+		return false
+	}
+	for _, target := range clause.Exprs {
+		if p.txCtx.WindowFuncInExpr(target.Expr) || containsGenerator(target.Expr, searchPath) {
+			return false
+		}
+	}
+	var lhsExprs tree.Exprs
+	if lhs != nil {
+		if t, ok := lhs.(*tree.Tuple); ok {
+			lhsExprs = t.Exprs
+		} else {
+			lhsExprs = tree.Exprs{lhs}
+		}
+		if len(lhsExprs) != len(clause.Exprs) {
+			return false
+		}
+	}
+
+	inner, err := p.getSources(ctx, clause.From.Tables, publicColumns)
+	if err != nil {
+		return false
+	}
+	res := correlationResolver{
+		inner: multiSourceInfo{inner.info},
+		outer: multiSourceInfo{r.source.info},
+	}
+	uncorrelated, on, ok := res.splitWhere(clause.Where.Expr)
+	if !ok || len(on) == 0 {
+		inner.plan.Close(ctx)
+		return false
+	}
+
+	if lhs == nil {
+		// The results of EXISTS do not depend on the targets, but the
+		// targets must still be valid.
+		for _, target := range clause.Exprs {
+			if _, ok := target.Expr.(tree.UnqualifiedStar); ok {
+				continue
+			}
+			if _, _, _, ok := res.resolve(target.Expr); !ok {
+				inner.plan.Close(ctx)
+				return false
+			}
+		}
+	} else {
+		// expr IN (subquery) is true for the rows where expr is equal to
+		// one of the results of the subquery.
+		outerRes := correlationResolver{outer: res.outer}
+		for i, e := range lhsExprs {
+			outerExpr, _, _, ok := outerRes.resolve(e)
+			if !ok {
+				inner.plan.Close(ctx)
+				return false
+			}
+			innerExpr, _, _, ok := res.resolve(clause.Exprs[i].Expr)
+			if !ok {
+				inner.plan.Close(ctx)
+				return false
+			}
+			on = append(on, &tree.ComparisonExpr{Operator: tree.EQ, Left: outerExpr, Right: innerExpr})
+		}
+	}
+
+	if len(uncorrelated) > 0 {
+		f := &filterNode{source: inner}
+		f.ivarHelper = tree.MakeIndexedVarHelper(f, len(inner.info.sourceColumns))
+		f.filter, err = p.analyzeExpr(ctx, joinConjuncts(uncorrelated), multiSourceInfo{inner.info},
+			f.ivarHelper, types.Bool, true, "WHERE")
+		if err == nil {
+			err = p.txCtx.AssertNoAggregationOrWindowing(f.filter, "WHERE", searchPath)
+		}
+		if err != nil {
+			inner.plan.Close(ctx)
+			return false
+		}
+		inner.plan = f
+	}
+
+	pred, _, err := p.makeOnPredicate(ctx, typ, r.source.info, inner.info, joinConjuncts(on))
+	if err != nil {
+		inner.plan.Close(ctx)
+		return false
+	}
+	// The results of semi and anti joins are the left rows.
+	r.source.plan = p.newJoinNode(typ, r.source, inner, pred, r.source.info)
+	return true
+}
+
+// decorrelateTargets rewrites the correlated scalar subqueries in the
+// render targets into left outer joins with the renderNode's data source,
+// where possible. The renderNode must not be aggregated.
+func (r *renderNode) decorrelateTargets(
+	ctx context.Context, targets tree.SelectExprs,
+) tree.SelectExprs {
+	var result tree.SelectExprs
+	for i, target := range targets {
+		v := scalarSubqueryVisitor{ctx: ctx, r: r}
+		expr, changed := tree.WalkExpr(&v, target.Expr)
+		if !changed {
+			continue
+		}
+		if result == nil {
+			result = append(tree.SelectExprs(nil), targets...)
+		}
+		result[i] = tree.SelectExpr{Expr: expr, As: target.As}
+		if target.As == "" {
+			// The column is named after the original expression.
+			result[i].As = tree.UnrestrictedName(target.Expr.String())
+		}
+	}
+	if result == nil {
+		return targets
+	}
+	return result
+}
+
+// scalarSubqueryVisitor decorrelates the subqueries that are used as
+// scalar values in an expression.
+type scalarSubqueryVisitor struct {
+	ctx context.Context
+	r   *renderNode
+}
+
+var _ tree.Visitor = &scalarSubqueryVisitor{}
+
+func (v *scalarSubqueryVisitor) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
+	switch t := expr.(type) {
+	case *tree.ExistsExpr, *tree.ArrayFlatten:
+		// The subquery is not used as a scalar value.
+		return false, expr
+
+	case *tree.ComparisonExpr:
+		// See getSubqueryContext: the subqueries used by these comparisons
+		// return multiple rows or multiple columns.
+		switch t.Operator {
+		case tree.In, tree.NotIn, tree.Any, tree.Some, tree.All:
+			return false, expr
+		}
+		if _, ok := t.Left.(*tree.Tuple); ok {
+			return false, expr
+		}
+
+	case *tree.Subquery:
+		if newExpr, ok := v.r.decorrelateScalarSubquery(v.ctx, t); ok {
+			return false, newExpr
+		}
+		return false, expr
+	}
+	return true, expr
+}
+
+func (*scalarSubqueryVisitor) VisitPost(expr tree.Expr) tree.Expr { return expr }
+
+// decorrelatableAggregates contains the aggregate functions that return
+// NULL when there are no input rows, which is also the value of the
+// subquery when no rows of the left outer join match. count is handled
+// separately.
+var decorrelatableAggregates = map[string]struct{}{
+	"array_agg":  {},
+	"avg":        {},
+	"bool_and":   {},
+	"bool_or":    {},
+	"concat_agg": {},
+	"max":        {},
+	"min":        {},
+	"stddev":     {},
+	"sum":        {},
+	"sum_int":    {},
+	"variance":   {},
+}
+
+// decorrelateScalarSubquery attempts to rewrite a scalar subquery into a
+// left outer join between the renderNode's data source and the subquery
+// grouped by the inner side of its correlated equalities. On success, the
+// renderNode's data source is replaced and the returned expression refers
+// to the value of the subquery. It returns false if the subquery does not
+// have a supported form.
+func (r *renderNode) decorrelateScalarSubquery(
+	ctx context.Context, sq *tree.Subquery,
+) (tree.Expr, bool) {
+	clause := decorrelatableClause(sq.Select)
+	if clause == nil || len(clause.Exprs) != 1 {
+		return nil, false
+	}
+	p := r.planner
+	target := clause.Exprs[0].Expr
+	fn, ok := tree.StripParens(target).(*tree.FuncExpr)
+	if !ok || fn.WindowDef != nil {
+		return nil, false
+	}
+	fd, err := fn.Func.Resolve(p.session.SearchPath)
+	if err != nil {
+		return nil, false
+	}
+	isCount := fd.Name == "count" || fd.Name == "count_rows"
+	if _, ok := decorrelatableAggregates[fd.Name]; !ok && !isCount {
+		return nil, false
+	}
+
+	// Determine the correlated equalities using the FROM clause of the
+	// subquery. The plan is only used for name resolution.
+	inner, err := p.getSources(ctx, clause.From.Tables, publicColumns)
+	if err != nil {
+		return nil, false
+	}
+	res := correlationResolver{
+		inner: multiSourceInfo{inner.info},
+		outer: multiSourceInfo{r.source.info},
+	}
+	uncorrelated, correlated, ok := res.splitWhere(clause.Where.Expr)
+	if ok {
+		// The aggregate cannot refer to the outer columns.
+		var outer bool
+		_, _, outer, ok = res.resolve(target)
+		ok = ok && !outer
+	}
+	inner.plan.Close(ctx)
+	if !ok || len(correlated) == 0 {
+		return nil, false
+	}
+
+	// innerKeys contain the inner side of each equality, as written in the
+	// subquery; outerKeys contain the outer side, resolved.
+	innerKeys := make(tree.GroupBy, len(correlated))
+	outerKeys := make(tree.Exprs, len(correlated))
+	for i := range correlated {
+		cmp, ok := tree.StripParens(correlated[i]).(*tree.ComparisonExpr)
+		if !ok || cmp.Operator != tree.EQ {
+			return nil, false
+		}
+		left, leftInner, leftOuter, _ := res.resolve(cmp.Left)
+		right, rightInner, rightOuter, _ := res.resolve(cmp.Right)
+		switch {
+		case leftInner && !leftOuter && rightOuter && !rightInner:
+			innerKeys[i], outerKeys[i] = cmp.Left, right
+		case rightInner && !rightOuter && leftOuter && !leftInner:
+			innerKeys[i], outerKeys[i] = cmp.Right, left
+		default:
+			return nil, false
+		}
+	}
+
+	// Plan the grouped subquery:
+	//   SELECT <aggregate>, <inner keys> FROM ... WHERE <uncorrelated> GROUP BY <inner keys>
+	grouped := &tree.SelectClause{
+		Exprs:   make(tree.SelectExprs, 1, 1+len(innerKeys)),
+		From:    clause.From,
+		GroupBy: innerKeys,
+	}
+	grouped.Exprs[0] = tree.SelectExpr{Expr: target}
+	for _, k := range innerKeys {
+		grouped.Exprs = append(grouped.Exprs, tree.SelectExpr{Expr: k})
+	}
+	if len(uncorrelated) > 0 {
+		grouped.Where = tree.NewWhere(tree.AstWhere, joinConjuncts(uncorrelated))
+	}
+	plan, err := p.SelectClause(ctx, grouped, nil /* orderBy */, nil /* limit */, nil, /* desiredTypes */
+		publicColumns)
+	if err != nil {
+		return nil, false
+	}
+	right := planDataSource{
+		info: newSourceInfoForSingleTable(anonymousTable, planColumns(plan)),
+		plan: plan,
+	}
+
+	// Join it with the data source on the keys. The value of the
+	// subquery is the first column of the right side.
+	left := r.source
+	numLeft := len(left.info.sourceColumns)
+	on := make(tree.Exprs, len(outerKeys))
+	for i := range outerKeys {
+		on[i] = &tree.ComparisonExpr{
+			Operator: tree.EQ,
+			Left:     outerKeys[i],
+			Right:    tree.NewOrdinalReference(numLeft + 1 + i),
+		}
+	}
+	pred, info, err := p.makeOnPredicate(ctx, joinTypeLeftOuter, left.info, right.info, joinConjuncts(on))
+	if err != nil {
+		plan.Close(ctx)
+		return nil, false
+	}
+	join := p.newJoinNode(joinTypeLeftOuter, left, right, pred, info)
+
+	// Render the columns of the data source and the value of the subquery,
+	// which is hidden: the new data source is only visible to the
+	// renderNode through the expression returned below.
+	src := &renderNode{
+		planner:    p,
+		source:     planDataSource{info: info, plan: join},
+		sourceInfo: multiSourceInfo{info},
+	}
+	src.ivarHelper = tree.MakeIndexedVarHelper(src, len(info.sourceColumns))
+	for i, c := range left.info.sourceColumns {
+		expr := src.ivarHelper.IndexedVar(i)
+		src.addRenderColumn(expr, symbolicExprStr(expr), c)
+	}
+	value := tree.TypedExpr(src.ivarHelper.IndexedVar(numLeft))
+	if isCount {
+		// count returns 0 when there are no input rows.
+		c := &tree.CoalesceExpr{
+			Name:  "IFNULL",
+			Exprs: tree.Exprs{value, tree.NewDInt(0)},
+		}
+		p.semaCtx.IVarHelper = &src.ivarHelper
+		value, err = c.TypeCheck(&p.semaCtx, types.Int)
+		p.semaCtx.IVarHelper = nil
+		if err != nil {
+			src.Close(ctx)
+			return nil, false
+		}
+	}
+	src.addRenderColumn(value, symbolicExprStr(value), sqlbase.ResultColumn{
+		Name:   sq.String(),
+		Typ:    value.ResolvedType(),
+		Hidden: true,
+	})
+
+	r.source = planDataSource{
+		info: &dataSourceInfo{sourceColumns: src.columns, sourceAliases: left.info.sourceAliases},
+		plan: src,
+	}
+	r.sourceInfo = multiSourceInfo{r.source.info}
+	return tree.NewOrdinalReference(numLeft), true
+}
+
+// decorrelatableClause returns the SELECT clause of a subquery if it is a
+// simple filter over its FROM clause that can be decorrelated, or nil
+// otherwise. The subquery must not have a WITH, LIMIT or locking clause,
+// and the SELECT clause must have a WHERE clause and no GROUP BY, HAVING,
+// WINDOW or AS OF clauses. An ORDER BY clause is irrelevant without LIMIT.
+func decorrelatableClause(stmt tree.SelectStatement) *tree.SelectClause {
+	for {
+		paren, ok := stmt.(*tree.ParenSelect)
+		if !ok {
+			break
+		}
+		s := paren.Select
+		if s.With != nil || s.Limit != nil || s.Locking.Strength != tree.ForNone {
+			return nil
+		}
+		stmt = s.Select
+	}
+	clause, ok := stmt.(*tree.SelectClause)
+	if !ok || clause.Where == nil || len(clause.GroupBy) > 0 || clause.Having != nil ||
+		len(clause.Window) > 0 || clause.From == nil || clause.From.AsOf.Expr != nil {
+		return nil
+	}
+	return clause
+}
+
+// correlationResolver resolves the column references in the expressions
+// of a subquery using both the data source of the subquery (the inner
+// columns) and the data source of the enclosing query (the outer columns),
+// the former taking precedence. The references are replaced by ordinal
+// references to the columns of a join between the two data sources, where
+// the outer columns come first.
+type correlationResolver struct {
+	inner, outer multiSourceInfo
+
+	foundInner, foundOuter bool
+	failed                 bool
+}
+
+var _ tree.Visitor = &correlationResolver{}
+
+// resolve resolves the column references in expr. It also returns
+// whether expr refers to inner and outer columns, and false if expr
+// contains references that cannot be resolved or expressions that are
+// not supported in a join predicate (e.g. subqueries).
+func (v *correlationResolver) resolve(expr tree.Expr) (_ tree.Expr, inner, outer, ok bool) {
+	v.foundInner, v.foundOuter, v.failed = false, false, false
+	expr, _ = tree.WalkExpr(v, expr)
+	return expr, v.foundInner, v.foundOuter, !v.failed
+}
+
+// splitWhere splits a WHERE clause into the conjuncts that only refer to
+// the inner columns, in their original form, and the resolved conjuncts
+// that refer to outer columns.
+func (v *correlationResolver) splitWhere(
+	where tree.Expr,
+) (uncorrelated, correlated tree.Exprs, ok bool) {
+	for _, c := range splitConjuncts(where, nil) {
+		resolved, _, outer, ok := v.resolve(c)
+		if !ok {
+			return nil, nil, false
+		}
+		if outer {
+			correlated = append(correlated, resolved)
+		} else {
+			uncorrelated = append(uncorrelated, c)
+		}
+	}
+	return uncorrelated, correlated, true
+}
+
+func (v *correlationResolver) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
+	if v.failed {
+		return false, expr
+	}
+	switch t := expr.(type) {
+	case tree.UnresolvedName:
+		vn, err := t.NormalizeVarName()
+		if err != nil {
+			v.failed = true
+			return false, expr
+		}
+		return v.VisitPre(vn)
+
+	case *tree.ColumnItem:
+		_, colIdx, err := v.inner.findColumn(t)
+		if err == nil {
+			v.foundInner = true
+			return false, tree.NewOrdinalReference(len(v.outer[0].sourceColumns) + colIdx)
+		}
+		if !isUnresolvedNameError(err) {
+			v.failed = true
+			return false, expr
+		}
+		_, colIdx, err = v.outer.findColumn(t)
+		if err != nil {
+			v.failed = true
+			return false, expr
+		}
+		v.foundOuter = true
+		return false, tree.NewOrdinalReference(colIdx)
+
+	case *tree.AllColumnsSelector, *tree.IndexedVar, *tree.Subquery:
+		v.failed = true
+		return false, expr
+	}
+	return true, expr
+}
+
+func (*correlationResolver) VisitPost(expr tree.Expr) tree.Expr { return expr }
+
+// containsGenerator returns true if the expression calls a set-returning
+// function.
+func containsGenerator(expr tree.Expr, searchPath tree.SearchPath) bool {
+	v := generatorCheckVisitor{searchPath: searchPath}
+	tree.WalkExprConst(&v, expr)
+	return v.found
+}
+
+type generatorCheckVisitor struct {
+	searchPath tree.SearchPath
+	found      bool
+}
+
+var _ tree.Visitor = &generatorCheckVisitor{}
+
+func (v *generatorCheckVisitor) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
+	switch t := expr.(type) {
+	case *tree.FuncExpr:
+		if fd, err := t.Func.Resolve(v.searchPath); err == nil {
+			if _, ok := builtins.Generators[fd.Name]; ok {
+				v.found = true
+				return false, expr
+			}
+		}
+	case *tree.Subquery:
+		return false, expr
+	}
+	return !v.found, expr
+}
+
+func (*generatorCheckVisitor) VisitPost(expr tree.Expr) tree.Expr { return expr }
+
+// splitConjuncts appends the conjuncts of an expression to exprs.
+func splitConjuncts(expr tree.Expr, exprs tree.Exprs) tree.Exprs {
+	switch t := expr.(type) {
+	case *tree.AndExpr:
+		return splitConjuncts(t.Right, splitConjuncts(t.Left, exprs))
+	case *tree.ParenExpr:
+		return splitConjuncts(t.Expr, exprs)
+	}
+	return append(exprs, expr)
+}
+
+// joinConjuncts combines expressions with AND; it returns nil if there
+// are no expressions.
+func joinConjuncts(exprs tree.Exprs) tree.Expr {
+	var result tree.Expr
+	for _, e := range exprs {
+		if result == nil {
+			result = e
+		} else {
+			result = &tree.AndExpr{Left: result, Right: e}
+		}
+	}
+	return result
+}
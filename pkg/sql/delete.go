@@ -59,7 +59,13 @@ func (p *planner) Delete(
 		return nil, pgerror.NewDangerousStatementErrorf("DELETE without WHERE clause")
 	}
 
-	tn, err := p.getAliasedTableName(n.Table)
+	popWith, err := p.initWith(ctx, n.With)
+	if err != nil {
+		return nil, err
+	}
+	defer popWith()
+
+	tn, err := p.getAliasedTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -80,8 +86,12 @@ func (p *planner) Delete(
 	if err := p.fillFKTableMap(ctx, fkTables); err != nil {
 		return nil, err
 	}
+	cascades, err := p.fillFKCascadeTableMap(ctx, en.tableDesc, sqlbase.CheckDeletes, fkTables)
+	if err != nil {
+		return nil, err
+	}
 	rd, err := sqlbase.MakeRowDeleter(p.txn, en.tableDesc, fkTables, requestedCols,
-		sqlbase.CheckFKs, &p.alloc)
+		sqlbase.CheckFKs, cascades, &p.alloc)
 	if err != nil {
 		return nil, err
 	}
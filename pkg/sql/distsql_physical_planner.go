@@ -161,6 +161,10 @@ func (v *distSQLExprCheckVisitor) VisitPre(expr tree.Expr) (recurse bool, newExp
 		v.err = newQueryNotSupportedError("subqueries not supported yet")
 		return false, expr
 
+	case *outerVar:
+		v.err = newQueryNotSupportedError("correlated subqueries not supported yet")
+		return false, expr
+
 	case *tree.FuncExpr:
 		if t.IsDistSQLBlacklist() {
 			v.err = newQueryNotSupportedErrorf("function %s cannot be executed with distsql", t)
@@ -287,6 +291,9 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 		return rec, nil
 
 	case *joinNode:
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			return 0, newQueryNotSupportedError("semi and anti joins not supported yet")
+		}
 		if err := dsp.checkExpr(n.pred.onCond); err != nil {
 			return 0, err
 		}
@@ -308,6 +315,10 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 		return rec, nil
 
 	case *scanNode:
+		if n.lockStrength != tree.ForNone {
+			// Row locks are acquired by the gateway's transaction.
+			return 0, newQueryNotSupportedError("locking scans not supported")
+		}
 		rec := canDistribute
 		if n.hardLimit != 0 || n.softLimit != 0 {
 			// We don't yet recommend distributing plans where limits propagate
@@ -330,6 +341,11 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 		return rec, nil
 
 	case *indexJoinNode:
+		if n.seenKeys != nil {
+			// The rows found more than once in inverted indexes are only
+			// deduplicated by the local index join.
+			return 0, newQueryNotSupportedError("index joins on inverted indexes not supported")
+		}
 		// n.table doesn't have meaningful spans, but we need to check support (e.g.
 		// for any filtering expression).
 		if _, err := dsp.checkSupportForNode(n.table); err != nil {
@@ -339,9 +355,10 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 
 	case *groupNode:
 		for _, fholder := range n.funcs {
-			if f, ok := fholder.expr.(*tree.FuncExpr); ok {
-				if strings.ToUpper(f.Func.FunctionReference.String()) == "ARRAY_AGG" {
-					return 0, newQueryNotSupportedError("ARRAY_AGG aggregation not supported yet")
+			if f, ok := fholder.expr.(*tree.FuncExpr); ok && f.GetAggregateConstructor() != nil {
+				funcStr := strings.ToUpper(f.Func.FunctionReference.String())
+				if _, ok := distsqlrun.AggregatorSpec_Func_value[funcStr]; !ok {
+					return 0, newQueryNotSupportedErrorf("%s aggregation not supported yet", funcStr)
 				}
 			}
 		}
@@ -352,6 +369,50 @@ func (dsp *DistSQLPlanner) checkSupportForNode(node planNode) (distRecommendatio
 		// Distribute aggregations if possible.
 		return rec.compose(shouldDistribute), nil
 
+	case *windowNode:
+		for i, e := range n.windowRender {
+			typ := n.values.columns[i].Typ
+			if leafType(typ).FamilyEqual(types.FamTuple) {
+				return 0, newQueryNotSupportedErrorf("unsupported render type %s", typ)
+			}
+			if err := dsp.checkExpr(e); err != nil {
+				return 0, err
+			}
+		}
+		for _, f := range n.funcs {
+			if err := dsp.checkExpr(f.frameStartOffset); err != nil {
+				return 0, err
+			}
+			if err := dsp.checkExpr(f.frameEndOffset); err != nil {
+				return 0, err
+			}
+			fn, err := windowerFuncSpec(f)
+			if err != nil {
+				return 0, newQueryNotSupportedErrorf("%s", err)
+			}
+			argTypes := make([]sqlbase.ColumnType, len(f.args))
+			for i, arg := range f.args {
+				argTypes[i], err = sqlbase.DatumTypeToColumnType(arg.(tree.TypedExpr).ResolvedType())
+				if err != nil {
+					return 0, newQueryNotSupportedErrorf("%s", err)
+				}
+			}
+			_, outputType, err := distsqlrun.GetWindowFunctionInfo(fn, argTypes...)
+			if err != nil {
+				return 0, newQueryNotSupportedErrorf("%s", err)
+			}
+			if typ := f.ResolvedType(); !outputType.ToDatumType().Equivalent(typ) {
+				return 0, newQueryNotSupportedErrorf(
+					"window function %s returning %s not supported", f.expr.Func, typ)
+			}
+		}
+		rec, err := dsp.checkSupportForNode(n.plan)
+		if err != nil {
+			return 0, err
+		}
+		// Window functions buffer all of their input; distribute them if possible.
+		return rec.compose(shouldDistribute), nil
+
 	case *limitNode:
 		if err := dsp.checkExpr(n.countExpr); err != nil {
 			return 0, err
@@ -1766,6 +1827,317 @@ func (dsp *DistSQLPlanner) addAggregators(
 	return nil
 }
 
+// windowerFuncSpec returns the specification of the function of a window
+// function application, which is either a builtin window function or an
+// aggregate function, as the enum value with the same string representation.
+func windowerFuncSpec(f *windowFuncHolder) (distsqlrun.WindowerSpec_Func, error) {
+	funcStr := strings.ToUpper(f.expr.Func.FunctionReference.String())
+	if funcIdx, ok := distsqlrun.WindowerSpec_WindowFunc_value[funcStr]; ok {
+		fn := distsqlrun.WindowerSpec_WindowFunc(funcIdx)
+		return distsqlrun.WindowerSpec_Func{WindowFunc: &fn}, nil
+	}
+	if funcIdx, ok := distsqlrun.AggregatorSpec_Func_value[funcStr]; ok {
+		fn := distsqlrun.AggregatorSpec_Func(funcIdx)
+		return distsqlrun.WindowerSpec_Func{AggregateFunc: &fn}, nil
+	}
+	return distsqlrun.WindowerSpec_Func{}, errors.Errorf("unknown window function %s", funcStr)
+}
+
+// windowerFrameSpec returns the specification of the window frame of a window
+// function application, or nil if the default frame is used. The offsets of
+// the frame bounds are evaluated during planning, since they are constant.
+func windowerFrameSpec(
+	n *windowNode, f *windowFuncHolder,
+) (*distsqlrun.WindowerSpec_Frame, error) {
+	frame := f.windowDef.Frame
+	if frame == nil {
+		return nil, nil
+	}
+	spec := &distsqlrun.WindowerSpec_Frame{Mode: distsqlrun.WindowerSpec_Frame_RANGE}
+	if frame.Mode == tree.ROWS {
+		spec.Mode = distsqlrun.WindowerSpec_Frame_ROWS
+	}
+
+	var err error
+	spec.Start, err = windowerFrameBoundSpec(n, frame.Bounds.StartBound, f.frameStartOffset, "starting")
+	if err != nil {
+		return nil, err
+	}
+	if frame.Bounds.EndBound != nil {
+		end, err := windowerFrameBoundSpec(n, frame.Bounds.EndBound, f.frameEndOffset, "ending")
+		if err != nil {
+			return nil, err
+		}
+		spec.End = &end
+	}
+	return spec, nil
+}
+
+// windowerFrameBoundSpec returns the specification of a window frame bound,
+// whose offset (if any) is value-encoded.
+func windowerFrameBoundSpec(
+	n *windowNode, bound *tree.WindowFrameBound, offsetExpr tree.TypedExpr, which string,
+) (distsqlrun.WindowerSpec_Frame_Bound, error) {
+	var spec distsqlrun.WindowerSpec_Frame_Bound
+	switch bound.BoundType {
+	case tree.UnboundedPreceding:
+		spec.BoundType = distsqlrun.WindowerSpec_Frame_UNBOUNDED_PRECEDING
+	case tree.ValuePreceding:
+		spec.BoundType = distsqlrun.WindowerSpec_Frame_OFFSET_PRECEDING
+	case tree.CurrentRow:
+		spec.BoundType = distsqlrun.WindowerSpec_Frame_CURRENT_ROW
+	case tree.ValueFollowing:
+		spec.BoundType = distsqlrun.WindowerSpec_Frame_OFFSET_FOLLOWING
+	case tree.UnboundedFollowing:
+		spec.BoundType = distsqlrun.WindowerSpec_Frame_UNBOUNDED_FOLLOWING
+	default:
+		return spec, errors.Errorf("unknown window frame bound type %d", bound.BoundType)
+	}
+
+	offset, err := n.evalFrameOffset(offsetExpr, which)
+	if err != nil || offset == nil {
+		return spec, err
+	}
+	spec.OffsetType, err = sqlbase.DatumTypeToColumnType(offset.ResolvedType())
+	if err != nil {
+		return spec, err
+	}
+	var alloc sqlbase.DatumAlloc
+	encOffset := sqlbase.DatumToEncDatum(spec.OffsetType, offset)
+	spec.Offset, err = encOffset.Encode(&spec.OffsetType, &alloc, sqlbase.DatumEncoding_VALUE, nil)
+	return spec, err
+}
+
+// addWindowers adds windower processors corresponding to a windowNode, and a
+// rendering which computes the windowNode's renders from the results of the
+// window functions.
+//
+// Consecutive window functions with the same PARTITION BY clause are computed
+// by the same stage of windowers. Each stage appends the results of its window
+// functions to the columns of its input. If the window functions are
+// partitioned and the previous stage has multiple streams, the rows are
+// distributed by hash of the partition columns to one windower per stream;
+// otherwise, a single windower is used.
+func (dsp *DistSQLPlanner) addWindowers(
+	planCtx *planningCtx, p *physicalPlan, n *windowNode,
+) error {
+	// The windowers buffer their input, so they don't need it to be ordered.
+	p.SetMergeOrdering(orderingTerminated)
+
+	// The windowers refer to the columns of the wrapped plan by index, and the
+	// arguments of each window function need to be contiguous; project the
+	// result streams so that they map 1-to-1 to the columns of the wrapped plan.
+	needProjection := len(p.ResultTypes) != len(p.planToStreamColMap)
+	cols := make([]uint32, len(p.planToStreamColMap))
+	for i, streamCol := range p.planToStreamColMap {
+		if streamCol == -1 {
+			return errors.Errorf("column %d of the wrapped plan not available", i)
+		}
+		needProjection = needProjection || streamCol != i
+		cols[i] = uint32(streamCol)
+	}
+	if needProjection {
+		p.AddProjection(cols)
+		p.planToStreamColMap = identityMap(p.planToStreamColMap, len(cols))
+	}
+
+	// resultCols maps each window function to the column of the result streams
+	// that contains its results.
+	resultCols := make([]int, len(n.funcs))
+	for start, end := 0, 0; start < len(n.funcs); start = end {
+		partitionIdxs := n.funcs[start].partitionIdxs
+		for end = start + 1; end < len(n.funcs); end++ {
+			if !equalIndexes(n.funcs[end].partitionIdxs, partitionIdxs) {
+				break
+			}
+		}
+
+		spec := distsqlrun.WindowerSpec{
+			PartitionBy: make([]uint32, len(partitionIdxs)),
+			WindowFns:   make([]distsqlrun.WindowerSpec_WindowFn, 0, end-start),
+		}
+		for i, idx := range partitionIdxs {
+			spec.PartitionBy[i] = uint32(idx)
+		}
+		outputTypes := p.ResultTypes[:len(p.ResultTypes):len(p.ResultTypes)]
+		for funcIdx, f := range n.funcs[start:end] {
+			fn, err := windowerFuncSpec(f)
+			if err != nil {
+				return err
+			}
+			frame, err := windowerFrameSpec(n, f)
+			if err != nil {
+				return err
+			}
+			var ordering distsqlrun.Ordering
+			ordering.Columns = make([]distsqlrun.Ordering_Column, len(f.columnOrdering))
+			for i, o := range f.columnOrdering {
+				ordering.Columns[i].ColIdx = uint32(o.ColIdx)
+				ordering.Columns[i].Direction = distsqlrun.Ordering_Column_ASC
+				if o.Direction == encoding.Descending {
+					ordering.Columns[i].Direction = distsqlrun.Ordering_Column_DESC
+				}
+			}
+			spec.WindowFns = append(spec.WindowFns, distsqlrun.WindowerSpec_WindowFn{
+				Func:        fn,
+				ArgIdxStart: uint32(f.argIdxStart),
+				ArgCount:    uint32(f.argCount),
+				Ordering:    ordering,
+				Frame:       frame,
+			})
+
+			_, outputType, err := distsqlrun.GetWindowFunctionInfo(
+				fn, p.ResultTypes[f.argIdxStart:f.argIdxStart+f.argCount]...,
+			)
+			if err != nil {
+				return err
+			}
+			resultCols[start+funcIdx] = len(outputTypes)
+			outputTypes = append(outputTypes, outputType)
+		}
+
+		dsp.addWindowerStage(p, spec, outputTypes)
+	}
+
+	// Build the rendering that computes the windowNode's renders; see
+	// windowNode.populateValues. The columns of the wrapped plan which are used
+	// as arguments to the window functions are skipped, the windowFuncHolders
+	// are replaced by the results of the window functions and the IndexedVars
+	// of the colContainer and aggContainer are replaced by the corresponding
+	// columns of the wrapped plan.
+	h := distsqlplan.MakeTypeIndexedVarHelper(p.ResultTypes)
+	renders := make([]tree.TypedExpr, len(n.windowRender))
+	curColIdx := 0
+	curFnIdx := 0
+	for i, render := range n.windowRender {
+		if render == nil {
+			renders[i] = h.IndexedVar(curColIdx)
+			curColIdx++
+			continue
+		}
+		for ; curFnIdx < len(n.funcs); curFnIdx++ {
+			if n.funcs[curFnIdx].argIdxStart != curColIdx {
+				break
+			}
+			curColIdx += n.funcs[curFnIdx].argCount
+		}
+		expr, err := tree.SimpleVisit(render, func(expr tree.Expr) (error, bool, tree.Expr) {
+			switch t := expr.(type) {
+			case *windowFuncHolder:
+				return nil, false, h.IndexedVar(resultCols[t.funcIdx])
+			case *tree.IndexedVar:
+				if _, ok := n.aggContainer.aggIVars[t]; ok {
+					return nil, false, h.IndexedVar(n.aggContainer.idxMap[t.Idx])
+				}
+				return nil, false, h.IndexedVar(n.colContainer.idxMap[t.Idx])
+			}
+			return nil, true, expr
+		})
+		if err != nil {
+			return err
+		}
+		renders[i] = expr.(tree.TypedExpr)
+	}
+
+	p.AddRendering(
+		renders, planCtx.evalCtx, identityMap(nil, len(p.ResultTypes)), getTypesForPlanResult(n, nil),
+	)
+	p.planToStreamColMap = identityMap(p.planToStreamColMap, len(renders))
+	return nil
+}
+
+// addWindowerStage adds a stage of windowers with the given specification,
+// either a single windower or, if the window functions are partitioned and
+// the previous stage has multiple streams, one windower per stream which
+// receives the rows of the partitions hashed to it.
+func (dsp *DistSQLPlanner) addWindowerStage(
+	p *physicalPlan, spec distsqlrun.WindowerSpec, outputTypes []sqlbase.ColumnType,
+) {
+	// Check if the previous stage is all on one node.
+	prevStageNode := p.Processors[p.ResultRouters[0]].Node
+	for i := 1; i < len(p.ResultRouters); i++ {
+		if n := p.Processors[p.ResultRouters[i]].Node; n != prevStageNode {
+			prevStageNode = 0
+			break
+		}
+	}
+
+	if len(spec.PartitionBy) == 0 || len(p.ResultRouters) == 1 {
+		// No PARTITION BY, or we have a single stream. Use a single windower.
+		// If the previous stage was all on a single node, put the windower
+		// there. Otherwise, bring the results back on this node.
+		node := dsp.nodeDesc.NodeID
+		if prevStageNode != 0 {
+			node = prevStageNode
+		}
+		p.AddSingleGroupStage(
+			node,
+			distsqlrun.ProcessorCoreUnion{Windower: &spec},
+			distsqlrun.PostProcessSpec{},
+			outputTypes,
+		)
+		return
+	}
+
+	// We distribute (by partition columns) to multiple processors.
+
+	// Set up the output routers from the previous stage.
+	for _, resultProc := range p.ResultRouters {
+		p.Processors[resultProc].Spec.Output[0] = distsqlrun.OutputRouterSpec{
+			Type:        distsqlrun.OutputRouterSpec_BY_HASH,
+			HashColumns: spec.PartitionBy,
+		}
+	}
+
+	stageID := p.NewStageID()
+
+	// We have one windower for each result router.
+	pIdxStart := distsqlplan.ProcessorIdx(len(p.Processors))
+	for _, resultProc := range p.ResultRouters {
+		proc := distsqlplan.Processor{
+			Node: p.Processors[resultProc].Node,
+			Spec: distsqlrun.ProcessorSpec{
+				Input: []distsqlrun.InputSyncSpec{{
+					// The other fields will be filled in by mergeResultStreams.
+					ColumnTypes: p.ResultTypes,
+				}},
+				Core: distsqlrun.ProcessorCoreUnion{Windower: &spec},
+				Output: []distsqlrun.OutputRouterSpec{{
+					Type: distsqlrun.OutputRouterSpec_PASS_THROUGH,
+				}},
+				StageID: stageID,
+			},
+		}
+		p.AddProcessor(proc)
+	}
+
+	// Connect the streams.
+	for bucket := 0; bucket < len(p.ResultRouters); bucket++ {
+		pIdx := pIdxStart + distsqlplan.ProcessorIdx(bucket)
+		p.MergeResultStreams(p.ResultRouters, bucket, distsqlrun.Ordering{}, pIdx, 0)
+	}
+
+	// Set the new result routers.
+	for i := 0; i < len(p.ResultRouters); i++ {
+		p.ResultRouters[i] = pIdxStart + distsqlplan.ProcessorIdx(i)
+	}
+	p.ResultTypes = outputTypes
+	p.SetMergeOrdering(orderingTerminated)
+}
+
+// equalIndexes returns whether the two slices of column indexes are equal.
+func equalIndexes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (dsp *DistSQLPlanner) createPlanForIndexJoin(
 	planCtx *planningCtx, n *indexJoinNode,
 ) (physicalPlan, error) {
@@ -2185,6 +2557,18 @@ func (dsp *DistSQLPlanner) createPlanForNode(
 
 		return plan, nil
 
+	case *windowNode:
+		plan, err := dsp.createPlanForNode(planCtx, n.plan)
+		if err != nil {
+			return physicalPlan{}, err
+		}
+
+		if err := dsp.addWindowers(planCtx, &plan, n); err != nil {
+			return physicalPlan{}, err
+		}
+
+		return plan, nil
+
 	case *sortNode:
 		plan, err := dsp.createPlanForNode(planCtx, n.plan)
 		if err != nil {
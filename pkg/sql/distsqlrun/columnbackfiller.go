@@ -36,6 +36,18 @@ type columnBackfiller struct {
 	// updateCols is a slice of all column descriptors that are being modified.
 	updateCols  []sqlbase.ColumnDescriptor
 	updateExprs []tree.TypedExpr
+	// conversions[i] is non-nil if added[i] is the column holding the values
+	// converted by ALTER COLUMN ... TYPE, which are computed from the value
+	// of the fetched column with ordinal conversionSrcIdx[i].
+	conversions      []*sqlbase.ColumnConversion
+	conversionSrcIdx []int
+	// computed computes the values of the added computed columns, if any,
+	// from the fetched columns. colIdxMap maps the IDs of the fetched columns
+	// to their ordinals and addedIdxMap those of the added columns to their
+	// indexes in updateCols.
+	computed    *sqlbase.ComputedColumns
+	colIdxMap   map[sqlbase.ColumnID]int
+	addedIdxMap map[sqlbase.ColumnID]int
 }
 
 var _ Processor = &columnBackfiller{}
@@ -72,13 +84,26 @@ func (cb *columnBackfiller) init() error {
 	desc := cb.spec.Table
 
 	// colIdxMap maps ColumnIDs to indices into desc.Columns and desc.Mutations.
-	var colIdxMap map[sqlbase.ColumnID]int
+	colIdxMap := make(map[sqlbase.ColumnID]int, len(desc.Columns))
+	for i, c := range desc.Columns {
+		colIdxMap[c.ID] = i
+	}
 
 	if len(desc.Mutations) > 0 {
 		for _, m := range desc.Mutations {
 			if ColumnMutationFilter(m) {
 				switch m.Direction {
 				case sqlbase.DescriptorMutation_ADD:
+					conversion, err := sqlbase.MakeColumnConversion(&cb.spec.Table, m)
+					if err != nil {
+						return err
+					}
+					cb.conversions = append(cb.conversions, conversion)
+					srcIdx := -1
+					if conversion != nil {
+						srcIdx = colIdxMap[conversion.Source.ID]
+					}
+					cb.conversionSrcIdx = append(cb.conversionSrcIdx, srcIdx)
 					desc := *m.GetColumn()
 					cb.added = append(cb.added, desc)
 				case sqlbase.DescriptorMutation_DROP:
@@ -94,8 +119,29 @@ func (cb *columnBackfiller) init() error {
 		return err
 	}
 
+	hasConversions := false
+	for _, c := range cb.conversions {
+		hasConversions = hasConversions || c != nil
+	}
+
+	var computedCols []sqlbase.ColumnDescriptor
+	cb.addedIdxMap = make(map[sqlbase.ColumnID]int, len(cb.added))
+	for i, col := range cb.added {
+		cb.addedIdxMap[col.ID] = i
+		if col.IsComputed() {
+			computedCols = append(computedCols, col)
+		}
+	}
+	if len(computedCols) > 0 {
+		var err error
+		if cb.computed, err = sqlbase.MakeComputedColumns(&desc, computedCols); err != nil {
+			return err
+		}
+	}
+	cb.colIdxMap = colIdxMap
+
 	cb.updateCols = append(cb.added, cb.dropped...)
-	if len(cb.dropped) > 0 || len(defaultExprs) > 0 {
+	if len(cb.dropped) > 0 || len(defaultExprs) > 0 || hasConversions || cb.computed != nil {
 		// Populate default values.
 		cb.updateExprs = make([]tree.TypedExpr, len(cb.updateCols))
 		for j := range cb.added {
@@ -114,11 +160,6 @@ func (cb *columnBackfiller) init() error {
 	var valNeededForCol util.FastIntSet
 	valNeededForCol.AddRange(0, len(desc.Columns)-1)
 
-	colIdxMap = make(map[sqlbase.ColumnID]int, len(desc.Columns))
-	for i, c := range desc.Columns {
-		colIdxMap[c.ID] = i
-	}
-
 	tableArgs := sqlbase.MultiRowFetcherTableArgs{
 		Desc:            &desc,
 		Index:           &desc.PrimaryIndex,
@@ -173,7 +214,7 @@ func (cb *columnBackfiller) runChunk(
 		requestedCols = append(requestedCols, cb.added...)
 		ru, err := sqlbase.MakeRowUpdater(
 			txn, &tableDesc, fkTables, cb.updateCols, requestedCols,
-			sqlbase.RowUpdaterOnlyColumns, &cb.alloc,
+			sqlbase.RowUpdaterOnlyColumns, nil /* cascades */, &cb.alloc,
 		)
 		if err != nil {
 			return err
@@ -216,6 +257,19 @@ func (cb *columnBackfiller) runChunk(
 			// Evaluate the new values. This must be done separately for
 			// each row so as to handle impure functions correctly.
 			for j, e := range cb.updateExprs {
+				if j < len(cb.added) && cb.conversions[j] != nil {
+					val, err := cb.conversions[j].Convert(
+						cb.flowCtx.NewEvalCtx(), datums[cb.conversionSrcIdx[j]],
+					)
+					if err != nil {
+						if sqlbase.IsPermanentSchemaChangeError(err) {
+							return err
+						}
+						return sqlbase.NewInvalidSchemaDefinitionError(err)
+					}
+					updateValues[j] = val
+					continue
+				}
 				val, err := e.Eval(cb.flowCtx.NewEvalCtx())
 				if err != nil {
 					return sqlbase.NewInvalidSchemaDefinitionError(err)
@@ -225,6 +279,14 @@ func (cb *columnBackfiller) runChunk(
 				}
 				updateValues[j] = val
 			}
+			if cb.computed != nil {
+				cb.computed.LoadRow(cb.colIdxMap, datums, false)
+				if err := cb.computed.Compute(
+					cb.flowCtx.NewEvalCtx(), cb.addedIdxMap, updateValues,
+				); err != nil {
+					return sqlbase.NewInvalidSchemaDefinitionError(err)
+				}
+			}
 			copy(oldValues, datums)
 			// Update oldValues with NULL values where values weren't found;
 			// only update when necessary.
@@ -101,6 +101,39 @@ func (a *AggregatorSpec) summary() (string, []string) {
 	return "Aggregator", details
 }
 
+func (w *WindowerSpec) summary() (string, []string) {
+	details := make([]string, 0, len(w.WindowFns)+1)
+	if len(w.PartitionBy) > 0 {
+		details = append(details, fmt.Sprintf("PARTITION BY %s", colListStr(w.PartitionBy)))
+	}
+	for _, fn := range w.WindowFns {
+		var buf bytes.Buffer
+		if fn.Func.AggregateFunc != nil {
+			buf.WriteString(fn.Func.AggregateFunc.String())
+		} else if fn.Func.WindowFunc != nil {
+			buf.WriteString(fn.Func.WindowFunc.String())
+		}
+		buf.WriteByte('(')
+		for i := uint32(0); i < fn.ArgCount; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, "@%d", fn.ArgIdxStart+i+1)
+		}
+		buf.WriteByte(')')
+		if len(fn.Ordering.Columns) > 0 {
+			fmt.Fprintf(&buf, " ORDER BY %s", fn.Ordering.diagramString())
+		}
+		if fn.Frame != nil {
+			fmt.Fprintf(&buf, " %s", fn.Frame.Mode)
+		}
+
+		details = append(details, buf.String())
+	}
+
+	return "Windower", details
+}
+
 func (tr *TableReaderSpec) summary() (string, []string) {
 	index := "primary"
 	if tr.IndexIdx > 0 {
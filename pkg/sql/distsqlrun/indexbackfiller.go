@@ -34,6 +34,9 @@ type indexBackfiller struct {
 
 	// colIdxMap maps ColumnIDs to indices into desc.Columns and desc.Mutations.
 	colIdxMap map[sqlbase.ColumnID]int
+	// computed computes the values of the computed columns indexed by the
+	// new indexes from the values of the columns they reference, if any.
+	computed *sqlbase.ComputedColumns
 
 	types   []sqlbase.ColumnType
 	rowVals tree.Datums
@@ -94,6 +97,7 @@ func (ib *indexBackfiller) init() error {
 	}
 
 	var valNeededForCol util.FastIntSet
+	var computedCols []sqlbase.ColumnDescriptor
 	mutationID := desc.Mutations[0].MutationID
 	for _, m := range desc.Mutations {
 		if m.MutationID != mutationID {
@@ -103,11 +107,23 @@ func (ib *indexBackfiller) init() error {
 			idx := m.GetIndex()
 			for i, col := range cols {
 				if idx.ContainsColumnID(col.ID) {
+					if col.IsComputed() && !valNeededForCol.Contains(i) {
+						computedCols = append(computedCols, col)
+					}
 					valNeededForCol.Add(i)
 				}
 			}
 		}
 	}
+	if len(computedCols) > 0 {
+		// The values of computed columns are computed rather than fetched, as
+		// the column may be backfilled along with the index.
+		var err error
+		if ib.computed, err = sqlbase.MakeComputedColumns(&desc, computedCols); err != nil {
+			return err
+		}
+		valNeededForCol.AddRange(0, len(desc.Columns)-1)
+	}
 
 	tableArgs := sqlbase.MultiRowFetcherTableArgs{
 		Desc:            &desc,
@@ -144,7 +160,6 @@ func (ib *indexBackfiller) runChunk(
 	for i, m := range mutations {
 		added[i] = *m.GetIndex()
 	}
-	secondaryIndexEntries := make([]sqlbase.IndexEntry, len(mutations))
 
 	buildIndexEntries := func(ctx context.Context, txn *client.Txn) ([]sqlbase.IndexEntry, error) {
 		entries := make([]sqlbase.IndexEntry, 0, chunkSize*int64(len(added)))
@@ -178,12 +193,19 @@ func (ib *indexBackfiller) runChunk(
 			if err := sqlbase.EncDatumRowToDatums(ib.types, ib.rowVals, encRow, &ib.da); err != nil {
 				return nil, err
 			}
-			if err := sqlbase.EncodeSecondaryIndexes(
-				&ib.spec.Table, added, ib.colIdxMap,
-				ib.rowVals, secondaryIndexEntries); err != nil {
+			if ib.computed != nil {
+				ib.computed.LoadRow(ib.colIdxMap, ib.rowVals, false)
+				if err := ib.computed.Compute(
+					ib.flowCtx.NewEvalCtx(), ib.colIdxMap, ib.rowVals,
+				); err != nil {
+					return nil, sqlbase.NewInvalidSchemaDefinitionError(err)
+				}
+			}
+			entries, err = sqlbase.EncodeSecondaryIndexes(
+				&ib.spec.Table, added, ib.colIdxMap, ib.rowVals, entries)
+			if err != nil {
 				return nil, err
 			}
-			entries = append(entries, secondaryIndexEntries...)
 		}
 		return entries, nil
 	}
@@ -432,6 +432,12 @@ func newProcessor(
 		}
 		return newAggregator(flowCtx, core.Aggregator, inputs[0], post, outputs[0])
 	}
+	if core.Windower != nil {
+		if err := checkNumInOut(inputs, outputs, 1, 1); err != nil {
+			return nil, err
+		}
+		return newWindower(flowCtx, core.Windower, inputs[0], post, outputs[0])
+	}
 	if core.MergeJoiner != nil {
 		if err := checkNumInOut(inputs, outputs, 2, 1); err != nil {
 			return nil, err
@@ -15,7 +15,6 @@
 package distsqlrun
 
 import (
-	"fmt"
 	"sync"
 
 	"golang.org/x/net/context"
@@ -23,6 +22,7 @@ import (
 	"github.com/axiomhq/hyperloglog"
 	"github.com/pkg/errors"
 
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/sql/stats"
@@ -39,6 +39,7 @@ type sampleAggregator struct {
 	inTypes []sqlbase.ColumnType
 	sr      stats.SampleReservoir
 
+	tableID     sqlbase.ID
 	sampledCols []sqlbase.ColumnID
 	sketches    []sketchInfo
 
@@ -73,6 +74,7 @@ func newSampleAggregator(
 		flowCtx:      flowCtx,
 		input:        input,
 		inTypes:      input.Types(),
+		tableID:      spec.TableID,
 		sampledCols:  spec.SampledColumnIDs,
 		sketches:     make([]sketchInfo, len(spec.Sketches)),
 		rankCol:      rankCol,
@@ -182,45 +184,54 @@ func (s *sampleAggregator) mainLoop(ctx context.Context) (earlyExit bool, _ erro
 			return false, errors.Wrapf(err, "merging sketch data")
 		}
 	}
-	return false, s.writeResults()
+	return false, s.writeResults(ctx)
 }
 
 // writeResults inserts the new statistics into system.table_statistics.
-// TODO(radu): for now there is no system table to write to, just dump the
-// results for inspection.
-func (s *sampleAggregator) writeResults() error {
-	for _, si := range s.sketches {
-		fmt.Printf(
-			"colIDs: %v  numRows: %d  numNulls: %d  cardinality: %d\n",
-			si.spec.Columns, si.numRows, si.numNulls,
-			si.sketch.Estimate(),
-		)
-		if si.spec.GenerateHistogram {
-			colIdx := int(si.spec.Columns[0])
-			typ := s.inTypes[colIdx]
-
-			h, err := generateHistogram(
-				&s.flowCtx.EvalCtx,
-				s.sr.Get(),
-				colIdx,
-				typ,
-				si.numRows,
-				int(si.spec.HistogramMaxBuckets),
-			)
-			if err != nil {
-				return err
-			}
-			fmt.Printf("histogram:\n")
-			for _, b := range h.Buckets {
-				ed, _, err := sqlbase.EncDatumFromBuffer(&typ, sqlbase.DatumEncoding_ASCENDING_KEY, b.UpperBound)
+func (s *sampleAggregator) writeResults(ctx context.Context) error {
+	return s.flowCtx.clientDB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		for _, si := range s.sketches {
+			var histogram *stats.HistogramData
+			if si.spec.GenerateHistogram {
+				colIdx := int(si.spec.Columns[0])
+				typ := s.inTypes[colIdx]
+
+				h, err := generateHistogram(
+					&s.flowCtx.EvalCtx,
+					s.sr.Get(),
+					colIdx,
+					typ,
+					si.numRows-si.numNulls,
+					int(si.spec.HistogramMaxBuckets),
+				)
 				if err != nil {
 					return err
 				}
-				fmt.Printf("  %s: less=%d eq=%d\n", ed.String(&typ), b.NumRange, b.NumEq)
+				histogram = &h
+			}
+
+			columnIDs := make([]sqlbase.ColumnID, len(si.spec.Columns))
+			for i, c := range si.spec.Columns {
+				columnIDs[i] = s.sampledCols[c]
+			}
+
+			if err := stats.InsertNewStat(
+				ctx,
+				s.flowCtx.executor,
+				txn,
+				s.tableID,
+				si.spec.StatName,
+				columnIDs,
+				si.numRows,
+				int64(si.sketch.Estimate()),
+				si.numNulls,
+				histogram,
+			); err != nil {
+				return err
 			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // generateHistogram returns a histogram (on a given column) from a set of
@@ -17,22 +17,31 @@ package distsqlrun
 import (
 	"testing"
 
-	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
-	"golang.org/x/net/context"
 )
 
 func TestSampleAggregator(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
+	server, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer server.Stopper().Stop(context.TODO())
+
 	evalCtx := tree.MakeTestingEvalContext()
 	defer evalCtx.Stop(context.Background())
 	flowCtx := FlowCtx{
-		Settings: cluster.MakeTestingClusterSettings(),
+		Settings: server.ClusterSettings(),
 		EvalCtx:  evalCtx,
+		clientDB: kvDB,
+		executor: server.DistSQLServer().(*ServerImpl).Executor,
 	}
 
 	inputRows := [][]int{
@@ -48,8 +57,6 @@ func TestSampleAggregator(t *testing.T) {
 		{-1, 3},
 		{1, -1},
 	}
-	//cardinalities := []int{2, 8}
-	//numNulls := []int{2, 1}
 
 	// We randomly distribute the input rows between multiple Samplers and
 	// aggregate the results.
@@ -70,12 +77,19 @@ func TestSampleAggregator(t *testing.T) {
 			SketchType:        SketchType_HLL_PLUS_PLUS_V1,
 			Columns:           []uint32{0},
 			GenerateHistogram: false,
+			StatName:          "a",
 		},
 		{
 			SketchType:          SketchType_HLL_PLUS_PLUS_V1,
 			Columns:             []uint32{1},
 			GenerateHistogram:   true,
 			HistogramMaxBuckets: 4,
+			StatName:            "b",
+		},
+		{
+			SketchType: SketchType_HLL_PLUS_PLUS_V1,
+			Columns:    []uint32{0, 1},
+			StatName:   "c",
 		},
 	}
 
@@ -117,6 +131,7 @@ func TestSampleAggregator(t *testing.T) {
 		SampleSize:       100,
 		Sketches:         sketchSpecs,
 		SampledColumnIDs: []sqlbase.ColumnID{100, 101},
+		TableID:          13,
 	}
 
 	agg, err := newSampleAggregator(&flowCtx, spec, samplerResults, &PostProcessSpec{}, finalOut)
@@ -126,4 +141,36 @@ func TestSampleAggregator(t *testing.T) {
 	agg.Run(context.Background(), nil /* wg */)
 	// Make sure there was no error.
 	finalOut.GetRowsNoMeta(t)
+
+	r := sqlutils.MakeSQLRunner(sqlDB)
+	r.CheckQueryResults(t, `
+	  SELECT "tableID", name, "columnIDs", "rowCount", "distinctCount", "nullCount"
+	  FROM system.table_statistics
+	  ORDER BY name`,
+		[][]string{
+			{"13", "a", "{100}", "11", "2", "2"},
+			{"13", "b", "{101}", "11", "8", "1"},
+			{"13", "c", "{100,101}", "11", "11", "0"},
+		},
+	)
+
+	// Verify the histogram accounts for all the non-NULL values.
+	var histData []byte
+	r.QueryRow(t,
+		`SELECT histogram FROM system.table_statistics WHERE name = 'b'`,
+	).Scan(&histData)
+	var h stats.HistogramData
+	if err := h.Unmarshal(histData); err != nil {
+		t.Fatal(err)
+	}
+	var numVals int64
+	for _, b := range h.Buckets {
+		numVals += b.NumEq + b.NumRange
+	}
+	if len(h.Buckets) == 0 || len(h.Buckets) > 4 {
+		t.Errorf("expected between 1 and 4 buckets, got %d", len(h.Buckets))
+	}
+	if expected := int64(10); numVals != expected {
+		t.Errorf("expected histogram to cover %d values, got %d (%v)", expected, numVals, h.Buckets)
+	}
 }
@@ -71,8 +71,8 @@ func newSamplerProcessor(
 		if _, ok := supportedSketchTypes[s.SketchType]; !ok {
 			return nil, errors.Errorf("unsupported sketch type %s", s.SketchType)
 		}
-		if len(s.Columns) != 1 {
-			return nil, errors.Errorf("multi-column sketches not supported yet")
+		if len(s.Columns) == 0 {
+			return nil, errors.Errorf("no columns specified for sketch")
 		}
 	}
 
@@ -110,8 +110,8 @@ func newSamplerProcessor(
 	s.numRowsCol = len(outTypes)
 	outTypes = append(outTypes, sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT})
 
-	// An INT column indicating the number of NULL values on the first column
-	// of the sketch.
+	// An INT column indicating the number of rows that have NULL values on all
+	// the columns of the sketch.
 	s.numNullsCol = len(outTypes)
 	outTypes = append(outTypes, sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT})
 
@@ -162,20 +162,27 @@ func (s *samplerProcessor) mainLoop(ctx context.Context) (earlyExit bool, _ erro
 		}
 
 		for i := range s.sketches {
-			col := s.sketches[i].spec.Columns[0]
 			s.sketches[i].numRows++
-			if row[col].IsNull() {
+			isNull := true
+			buf = buf[:0]
+			for _, col := range s.sketches[i].spec.Columns {
+				if !row[col].IsNull() {
+					isNull = false
+				}
+				// We need to use a KEY encoding because equal values should have the
+				// same encoding. The key encoding is self-delimiting, so the encodings
+				// of multiple columns can be concatenated to encode a tuple of values.
+				// TODO(radu): a fast path for simple columns (like integer)?
+				var err error
+				buf, err = row[col].Encode(&s.outTypes[col], &da, sqlbase.DatumEncoding_ASCENDING_KEY, buf)
+				if err != nil {
+					return false, err
+				}
+			}
+			if isNull {
 				s.sketches[i].numNulls++
 				continue
 			}
-			// We need to use a KEY encoding because equal values should have the same
-			// encoding.
-			// TODO(radu): a fast path for simple columns (like integer)?
-			var err error
-			buf, err = row[col].Encode(&s.outTypes[col], &da, sqlbase.DatumEncoding_ASCENDING_KEY, buf[:0])
-			if err != nil {
-				return false, err
-			}
 			s.sketches[i].sketch.Insert(buf)
 		}
 
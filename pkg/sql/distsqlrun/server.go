@@ -30,6 +30,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/util/contextutil"
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
@@ -70,7 +71,7 @@ type DistSQLVersion uint32
 //
 // ATTENTION: When updating these fields, add to version_history.txt explaining
 // what changed.
-const Version DistSQLVersion = 7
+const Version DistSQLVersion = 8
 
 // MinAcceptedVersion is the oldest version that the server is
 // compatible with; see above.
@@ -105,6 +106,9 @@ type ServerConfig struct {
 
 	// DB is a handle to the cluster.
 	DB *client.DB
+	// Executor can be used to run "internal queries". Note that Flows also have
+	// access to a client.DB that can be used for KV-level operations.
+	Executor sqlutil.InternalExecutor
 	// FlowDB is the DB that flows should use for interacting with the database.
 	// This DB has to be set such that it bypasses the local TxnCoordSender. We
 	// want only the TxnCoordSender on the gateway to be involved with requests
@@ -285,6 +289,7 @@ func (ds *ServerImpl) setupFlow(
 		rpcCtx:         ds.RPCContext,
 		txn:            txn,
 		clientDB:       ds.DB,
+		executor:       ds.Executor,
 		testingKnobs:   ds.TestingKnobs,
 		nodeID:         nodeID,
 		TempStorage:    ds.TempStorage,
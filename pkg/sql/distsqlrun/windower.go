@@ -0,0 +1,501 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// GetWindowFunctionInfo returns the window function constructor and the
+// return type for the given window function when applied on the given types.
+func GetWindowFunctionInfo(
+	fn WindowerSpec_Func, inputTypes ...sqlbase.ColumnType,
+) (
+	windowConstructor func(*tree.EvalContext) tree.WindowFunc,
+	returnType sqlbase.ColumnType,
+	err error,
+) {
+	var funcStr string
+	switch {
+	case fn.AggregateFunc != nil:
+		if *fn.AggregateFunc == AggregatorSpec_IDENT {
+			return nil, sqlbase.ColumnType{}, errors.Errorf("ident aggregate is not a window function")
+		}
+		funcStr = fn.AggregateFunc.String()
+	case fn.WindowFunc != nil:
+		funcStr = fn.WindowFunc.String()
+	default:
+		return nil, sqlbase.ColumnType{}, errors.Errorf(
+			"function is neither an aggregate nor a window function")
+	}
+
+	datumTypes := make([]types.T, len(inputTypes))
+	for i := range inputTypes {
+		datumTypes[i] = inputTypes[i].ToDatumType()
+	}
+
+	builtins := builtins.Builtins[strings.ToLower(funcStr)]
+	for _, b := range builtins {
+		types := b.Types.Types()
+		if len(types) != len(inputTypes) {
+			continue
+		}
+		match := true
+		for i, t := range types {
+			if !datumTypes[i].Equivalent(t) {
+				match = false
+				break
+			}
+		}
+		if match {
+			// Found!
+			constructWindow := func(evalCtx *tree.EvalContext) tree.WindowFunc {
+				return b.WindowFunc(datumTypes, evalCtx)
+			}
+
+			colTyp, err := sqlbase.DatumTypeToColumnType(b.FixedReturnType())
+			if err != nil {
+				return nil, sqlbase.ColumnType{}, err
+			}
+			return constructWindow, colTyp, nil
+		}
+	}
+	return nil, sqlbase.ColumnType{}, errors.Errorf(
+		"no builtin window function for %s on %v", funcStr, inputTypes,
+	)
+}
+
+// windower is the processor core type that computes window functions. It
+// buffers all the rows of its input, divides them into partitions and
+// computes the result of each window function for each row of each partition,
+// according to the ordering and frame of the function.
+//
+// windower's output schema is comprised of the input columns followed by the
+// results of the window functions.
+type windower struct {
+	processorBase
+
+	flowCtx     *FlowCtx
+	input       RowSource
+	inputTypes  []sqlbase.ColumnType
+	outputTypes []sqlbase.ColumnType
+	datumAlloc  sqlbase.DatumAlloc
+
+	partitionBy columns
+	windowFns   []windowFunc
+
+	// rows contains all the rows of the input.
+	rows memRowContainer
+	// partitionsAcc accounts for the partitions and the results of the window
+	// functions.
+	partitionsAcc mon.BoundAccount
+}
+
+// windowFunc holds the information required to compute a window function
+// over the rows of a partition.
+type windowFunc struct {
+	create      func(*tree.EvalContext) tree.WindowFunc
+	argIdxStart int
+	argCount    int
+	ordering    sqlbase.ColumnOrdering
+	frame       *tree.WindowFrameSpec
+	startOffset tree.Datum
+	endOffset   tree.Datum
+}
+
+var _ Processor = &windower{}
+
+func newWindower(
+	flowCtx *FlowCtx, spec *WindowerSpec, input RowSource, post *PostProcessSpec, output RowReceiver,
+) (*windower, error) {
+	w := &windower{
+		flowCtx:       flowCtx,
+		input:         input,
+		inputTypes:    input.Types(),
+		partitionBy:   spec.PartitionBy,
+		windowFns:     make([]windowFunc, len(spec.WindowFns)),
+		partitionsAcc: flowCtx.EvalCtx.Mon.MakeBoundAccount(),
+	}
+	for _, c := range w.partitionBy {
+		if c >= uint32(len(w.inputTypes)) {
+			return nil, errors.Errorf("partition column %d out of range", c)
+		}
+	}
+
+	w.outputTypes = make([]sqlbase.ColumnType, len(w.inputTypes), len(w.inputTypes)+len(spec.WindowFns))
+	copy(w.outputTypes, w.inputTypes)
+	for i, fnSpec := range spec.WindowFns {
+		argStart, argEnd := fnSpec.ArgIdxStart, fnSpec.ArgIdxStart+fnSpec.ArgCount
+		if argEnd > uint32(len(w.inputTypes)) {
+			return nil, errors.Errorf("arguments [%d, %d) out of range", argStart, argEnd)
+		}
+		windowConstructor, retType, err := GetWindowFunctionInfo(
+			fnSpec.Func, w.inputTypes[argStart:argEnd]...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		fn := windowFunc{
+			create:      windowConstructor,
+			argIdxStart: int(argStart),
+			argCount:    int(fnSpec.ArgCount),
+			ordering:    convertToColumnOrdering(fnSpec.Ordering),
+		}
+		for _, o := range fn.ordering {
+			if o.ColIdx >= len(w.inputTypes) {
+				return nil, errors.Errorf("ordering column %d out of range", o.ColIdx)
+			}
+		}
+		if fnSpec.Frame != nil {
+			if err := w.initFrame(&fn, fnSpec.Frame); err != nil {
+				return nil, err
+			}
+		}
+		w.windowFns[i] = fn
+		w.outputTypes = append(w.outputTypes, retType)
+	}
+
+	if err := w.init(post, w.outputTypes, flowCtx, output); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// initFrame converts the frame of a window function specification to a
+// tree.WindowFrameSpec and decodes the offsets of its bounds.
+func (w *windower) initFrame(fn *windowFunc, frame *WindowerSpec_Frame) error {
+	fn.frame = &tree.WindowFrameSpec{}
+	switch frame.Mode {
+	case WindowerSpec_Frame_RANGE:
+		fn.frame.Mode = tree.RANGE
+	case WindowerSpec_Frame_ROWS:
+		fn.frame.Mode = tree.ROWS
+	default:
+		return errors.Errorf("unknown window frame mode %s", frame.Mode)
+	}
+
+	var err error
+	fn.frame.Bounds.StartBound, fn.startOffset, err = w.convertFrameBound(frame.Start)
+	if err != nil {
+		return err
+	}
+	if frame.End != nil {
+		fn.frame.Bounds.EndBound, fn.endOffset, err = w.convertFrameBound(*frame.End)
+		if err != nil {
+			return err
+		}
+	}
+	if (fn.startOffset != nil || fn.endOffset != nil) && fn.frame.Mode == tree.RANGE &&
+		len(fn.ordering) != 1 {
+		return errors.Errorf("RANGE frame with offsets requires exactly one ordering column")
+	}
+	return nil
+}
+
+// convertFrameBound converts a window frame bound of a WindowerSpec to a
+// tree.WindowFrameBound, returning the decoded offset of the bound, if any.
+func (w *windower) convertFrameBound(
+	bound WindowerSpec_Frame_Bound,
+) (*tree.WindowFrameBound, tree.Datum, error) {
+	var boundType tree.WindowFrameBoundType
+	switch bound.BoundType {
+	case WindowerSpec_Frame_UNBOUNDED_PRECEDING:
+		boundType = tree.UnboundedPreceding
+	case WindowerSpec_Frame_OFFSET_PRECEDING:
+		boundType = tree.ValuePreceding
+	case WindowerSpec_Frame_CURRENT_ROW:
+		boundType = tree.CurrentRow
+	case WindowerSpec_Frame_OFFSET_FOLLOWING:
+		boundType = tree.ValueFollowing
+	case WindowerSpec_Frame_UNBOUNDED_FOLLOWING:
+		boundType = tree.UnboundedFollowing
+	default:
+		return nil, nil, errors.Errorf("unknown window frame bound type %s", bound.BoundType)
+	}
+	treeBound := &tree.WindowFrameBound{BoundType: boundType}
+	if !treeBound.HasOffset() {
+		return treeBound, nil, nil
+	}
+
+	if len(bound.Offset) == 0 {
+		return nil, nil, errors.Errorf("window frame bound %s without offset", bound.BoundType)
+	}
+	offset, _, err := sqlbase.EncDatumFromBuffer(
+		&bound.OffsetType, sqlbase.DatumEncoding_VALUE, bound.Offset,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := offset.EnsureDecoded(&bound.OffsetType, &w.datumAlloc); err != nil {
+		return nil, nil, err
+	}
+	return treeBound, offset.Datum, nil
+}
+
+// Run is part of the processor interface.
+func (w *windower) Run(ctx context.Context, wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+	defer w.partitionsAcc.Close(ctx)
+
+	ctx = log.WithLogTag(ctx, "Windower", nil)
+	ctx, span := processorSpan(ctx, "windower")
+	defer tracing.FinishSpan(span)
+
+	if log.V(2) {
+		log.Infof(ctx, "starting windower process")
+		defer log.Infof(ctx, "exiting windower")
+	}
+
+	w.rows.init(nil /* ordering */, w.inputTypes, &w.flowCtx.EvalCtx)
+	defer w.rows.Close(ctx)
+
+	partitions, err := w.accumulateRows(ctx)
+	if err != nil {
+		// We swallow the error here, it has already been forwarded to the output.
+		return
+	}
+
+	log.VEvent(ctx, 1, "accumulation complete")
+
+	results, err := w.computeWindowFunctions(ctx, partitions)
+	if err != nil {
+		DrainAndClose(ctx, w.out.output, err, w.input)
+		return
+	}
+
+	// Render the results, in the order in which the rows were received.
+	var consumerDone bool
+	row := make(sqlbase.EncDatumRow, len(w.outputTypes))
+	numInputCols := len(w.inputTypes)
+	for i := 0; i < w.rows.Len(); i++ {
+		copy(row, w.rows.EncRow(i))
+		for j, res := range results[i] {
+			row[numInputCols+j] = sqlbase.DatumToEncDatum(w.outputTypes[numInputCols+j], res)
+		}
+
+		consumerDone = !emitHelper(ctx, &w.out, row, ProducerMetadata{})
+		if consumerDone {
+			break
+		}
+	}
+	// If the consumer has been found to be done, emitHelper() already closed the
+	// output.
+	if !consumerDone {
+		sendTraceData(ctx, w.out.output)
+		w.out.Close()
+	}
+}
+
+// accumulateRows reads and buffers all input rows, and returns the indexes of
+// the rows of each partition, keyed by the encoding of the partition columns.
+// If an error is returned, both the input and the output have been properly
+// closed, and the error has also been forwarded to the output.
+func (w *windower) accumulateRows(ctx context.Context) (_ map[string][]int, err error) {
+	cleanupRequired := true
+	defer func() {
+		if err != nil {
+			log.Infof(ctx, "accumulate error %s", err)
+			if cleanupRequired {
+				DrainAndClose(ctx, w.out.output, err, w.input)
+			}
+		}
+	}()
+
+	partitions := make(map[string][]int)
+	var scratch []byte
+	for {
+		row, meta := w.input.Next()
+		if !meta.Empty() {
+			if meta.Err != nil {
+				return nil, meta.Err
+			}
+			if !emitHelper(ctx, &w.out, nil /* row */, meta, w.input) {
+				cleanupRequired = false
+				return nil, errors.Errorf("consumer stopped before it received rows")
+			}
+			continue
+		}
+		if row == nil {
+			return partitions, nil
+		}
+
+		encoded, err := w.encodePartition(scratch, row)
+		if err != nil {
+			return nil, err
+		}
+		idxs, ok := partitions[string(encoded)]
+		usage := int64(unsafe.Sizeof(int(0)))
+		if !ok {
+			usage += int64(len(encoded))
+		}
+		if err := w.partitionsAcc.Grow(ctx, usage); err != nil {
+			return nil, err
+		}
+		partitions[string(encoded)] = append(idxs, w.rows.Len())
+		if err := w.rows.AddRow(ctx, row); err != nil {
+			return nil, err
+		}
+		scratch = encoded[:0]
+	}
+}
+
+// encodePartition returns the encoding of the partition columns of the given
+// row, which determines the partition the row belongs to.
+func (w *windower) encodePartition(
+	appendTo []byte, row sqlbase.EncDatumRow,
+) (encoding []byte, err error) {
+	for _, colIdx := range w.partitionBy {
+		appendTo, err = row[colIdx].Encode(
+			&w.inputTypes[colIdx], &w.datumAlloc, sqlbase.DatumEncoding_ASCENDING_KEY, appendTo,
+		)
+		if err != nil {
+			return appendTo, err
+		}
+	}
+	return appendTo, nil
+}
+
+// computeWindowFunctions computes the result of each window function for each
+// buffered row. The results are indexed by row and then by window function.
+func (w *windower) computeWindowFunctions(
+	ctx context.Context, partitions map[string][]int,
+) ([][]tree.Datum, error) {
+	rowCount := w.rows.Len()
+	windowCount := len(w.windowFns)
+	evalCtx := &w.flowCtx.EvalCtx
+
+	resSz := uintptr(rowCount) * unsafe.Sizeof([]tree.Datum{})
+	resAllocSz := uintptr(rowCount*windowCount) * unsafe.Sizeof(tree.Datum(nil))
+	if err := w.partitionsAcc.Grow(ctx, int64(resSz+resAllocSz)); err != nil {
+		return nil, err
+	}
+	results := make([][]tree.Datum, rowCount)
+	resultsAlloc := make([]tree.Datum, rowCount*windowCount)
+	for i := range results {
+		results[i] = resultsAlloc[i*windowCount : (i+1)*windowCount]
+	}
+
+	var rows []tree.IndexedRow
+	for _, partition := range partitions {
+		if n := len(partition); n > cap(rows) {
+			sz := int64(uintptr(n-cap(rows)) * unsafe.Sizeof(tree.IndexedRow{}))
+			if err := w.partitionsAcc.Grow(ctx, sz); err != nil {
+				return nil, err
+			}
+			rows = make([]tree.IndexedRow, n)
+		} else {
+			rows = rows[:n]
+		}
+
+		for windowIdx := range w.windowFns {
+			fn := &w.windowFns[windowIdx]
+			for i, idx := range partition {
+				rows[i] = tree.IndexedRow{Idx: idx, Row: w.rows.At(idx)}
+			}
+
+			// Peer groups are determined by the ordering of the window function,
+			// and are used by the default frame and RANGE frames as well as by
+			// ranking functions. Without an ordering, all rows of the partition
+			// are peers.
+			sorter := &partitionSorter{evalCtx: evalCtx, rows: rows, ordering: fn.ordering}
+			if len(fn.ordering) > 0 {
+				sort.Sort(sorter)
+			}
+
+			frame := tree.WindowFrame{
+				Rows:             rows,
+				ArgIdxStart:      fn.argIdxStart,
+				ArgCount:         fn.argCount,
+				Frame:            fn.frame,
+				StartBoundOffset: fn.startOffset,
+				EndBoundOffset:   fn.endOffset,
+			}
+			if len(fn.ordering) > 0 {
+				frame.OrdColIdx = fn.ordering[0].ColIdx
+				frame.OrdDirection = tree.Ascending
+				if fn.ordering[0].Direction == encoding.Descending {
+					frame.OrdDirection = tree.Descending
+				}
+			}
+
+			builtin := fn.create(evalCtx)
+			for frame.RowIdx < len(rows) {
+				// Compute the size of the current peer group.
+				frame.FirstPeerIdx = frame.RowIdx
+				frame.PeerRowCount = 1
+				for ; frame.FirstPeerIdx+frame.PeerRowCount < len(rows); frame.PeerRowCount++ {
+					cur := frame.FirstPeerIdx + frame.PeerRowCount
+					if sorter.Compare(cur, cur-1) != 0 {
+						break
+					}
+				}
+
+				// Perform calculations on each row in the current peer group.
+				for ; frame.RowIdx < frame.FirstPeerIdx+frame.PeerRowCount; frame.RowIdx++ {
+					res, err := builtin.Compute(ctx, evalCtx, frame)
+					if err != nil {
+						builtin.Close(ctx, evalCtx)
+						return nil, err
+					}
+					// This may overestimate, because WindowFuncs may perform internal
+					// caching.
+					if err := w.partitionsAcc.Grow(ctx, int64(res.Size())); err != nil {
+						builtin.Close(ctx, evalCtx)
+						return nil, err
+					}
+					results[rows[frame.RowIdx].Idx][windowIdx] = res
+				}
+			}
+			builtin.Close(ctx, evalCtx)
+		}
+	}
+	return results, nil
+}
+
+// partitionSorter sorts the rows of a partition according to the ordering of
+// a window function.
+type partitionSorter struct {
+	evalCtx  *tree.EvalContext
+	rows     []tree.IndexedRow
+	ordering sqlbase.ColumnOrdering
+}
+
+// partitionSorter implements the sort.Interface interface.
+func (n *partitionSorter) Len() int           { return len(n.rows) }
+func (n *partitionSorter) Swap(i, j int)      { n.rows[i], n.rows[j] = n.rows[j], n.rows[i] }
+func (n *partitionSorter) Less(i, j int) bool { return n.Compare(i, j) < 0 }
+
+// Compare compares the rows at the given indexes of the partition; rows which
+// compare equal are peers.
+func (n *partitionSorter) Compare(i, j int) int {
+	return sqlbase.CompareDatums(n.ordering, n.evalCtx, n.rows[i].Row, n.rows[j].Row)
+}
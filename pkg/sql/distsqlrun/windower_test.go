@@ -0,0 +1,197 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestWindower(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	v := [15]sqlbase.EncDatum{}
+	for i := range v {
+		v[i] = sqlbase.DatumToEncDatum(intType, tree.NewDInt(tree.DInt(i)))
+	}
+
+	var alloc sqlbase.DatumAlloc
+	offset, err := v[1].Encode(&intType, &alloc, sqlbase.DatumEncoding_VALUE, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windowFn := func(fn WindowerSpec_WindowFunc) WindowerSpec_Func {
+		return WindowerSpec_Func{WindowFunc: &fn}
+	}
+	aggregateFn := func(fn AggregatorSpec_Func) WindowerSpec_Func {
+		return WindowerSpec_Func{AggregateFunc: &fn}
+	}
+
+	testCases := []struct {
+		spec        WindowerSpec
+		inputTypes  []sqlbase.ColumnType
+		input       sqlbase.EncDatumRows
+		outputTypes []sqlbase.ColumnType
+		expected    sqlbase.EncDatumRows
+	}{
+		{
+			// SELECT @1, @2, ROW_NUMBER() OVER (PARTITION BY @2 ORDER BY @1 DESC).
+			spec: WindowerSpec{
+				PartitionBy: []uint32{1},
+				WindowFns: []WindowerSpec_WindowFn{
+					{
+						Func: windowFn(WindowerSpec_ROW_NUMBER),
+						Ordering: Ordering{Columns: []Ordering_Column{
+							{ColIdx: 0, Direction: Ordering_Column_DESC},
+						}},
+					},
+				},
+			},
+			inputTypes: twoIntCols,
+			input: sqlbase.EncDatumRows{
+				{v[1], v[2]},
+				{v[3], v[4]},
+				{v[6], v[2]},
+				{v[7], v[2]},
+				{v[8], v[4]},
+			},
+			outputTypes: threeIntCols,
+			expected: sqlbase.EncDatumRows{
+				{v[1], v[2], v[3]},
+				{v[3], v[4], v[2]},
+				{v[6], v[2], v[2]},
+				{v[7], v[2], v[1]},
+				{v[8], v[4], v[1]},
+			},
+		},
+		{
+			// SELECT @1, @2, RANK() OVER (ORDER BY @2), COUNT(@1) OVER ().
+			spec: WindowerSpec{
+				WindowFns: []WindowerSpec_WindowFn{
+					{
+						Func: windowFn(WindowerSpec_RANK),
+						Ordering: Ordering{Columns: []Ordering_Column{
+							{ColIdx: 1, Direction: Ordering_Column_ASC},
+						}},
+					},
+					{
+						Func:        aggregateFn(AggregatorSpec_COUNT),
+						ArgIdxStart: 0,
+						ArgCount:    1,
+					},
+				},
+			},
+			inputTypes: twoIntCols,
+			input: sqlbase.EncDatumRows{
+				{v[1], v[2]},
+				{v[3], v[4]},
+				{v[6], v[2]},
+				{v[7], v[2]},
+				{v[8], v[4]},
+			},
+			outputTypes: []sqlbase.ColumnType{intType, intType, intType, intType},
+			expected: sqlbase.EncDatumRows{
+				{v[1], v[2], v[1], v[5]},
+				{v[3], v[4], v[4], v[5]},
+				{v[6], v[2], v[1], v[5]},
+				{v[7], v[2], v[1], v[5]},
+				{v[8], v[4], v[4], v[5]},
+			},
+		},
+		{
+			// SELECT @1, COUNT(@1) OVER (ORDER BY @1 ROWS BETWEEN 1 PRECEDING AND
+			// 1 FOLLOWING).
+			spec: WindowerSpec{
+				WindowFns: []WindowerSpec_WindowFn{
+					{
+						Func:        aggregateFn(AggregatorSpec_COUNT),
+						ArgIdxStart: 0,
+						ArgCount:    1,
+						Ordering: Ordering{Columns: []Ordering_Column{
+							{ColIdx: 0, Direction: Ordering_Column_ASC},
+						}},
+						Frame: &WindowerSpec_Frame{
+							Mode: WindowerSpec_Frame_ROWS,
+							Start: WindowerSpec_Frame_Bound{
+								BoundType:  WindowerSpec_Frame_OFFSET_PRECEDING,
+								Offset:     offset,
+								OffsetType: intType,
+							},
+							End: &WindowerSpec_Frame_Bound{
+								BoundType:  WindowerSpec_Frame_OFFSET_FOLLOWING,
+								Offset:     offset,
+								OffsetType: intType,
+							},
+						},
+					},
+				},
+			},
+			inputTypes: oneIntCol,
+			input: sqlbase.EncDatumRows{
+				{v[3]},
+				{v[1]},
+				{v[4]},
+				{v[2]},
+			},
+			outputTypes: twoIntCols,
+			expected: sqlbase.EncDatumRows{
+				{v[3], v[3]},
+				{v[1], v[2]},
+				{v[4], v[2]},
+				{v[2], v[3]},
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run("", func(t *testing.T) {
+			in := NewRowBuffer(c.inputTypes, c.input, RowBufferArgs{})
+			out := NewRowBuffer(c.outputTypes, nil /* rows */, RowBufferArgs{})
+			evalCtx := tree.MakeTestingEvalContext()
+			defer evalCtx.Stop(context.Background())
+			flowCtx := FlowCtx{
+				Settings: cluster.MakeTestingClusterSettings(),
+				EvalCtx:  evalCtx,
+			}
+
+			w, err := newWindower(&flowCtx, &c.spec, in, &PostProcessSpec{}, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w.Run(context.Background(), nil)
+
+			// The windower emits the rows in the order in which it received them.
+			var rets sqlbase.EncDatumRows
+			for {
+				row := out.NextNoMeta(t)
+				if row == nil {
+					break
+				}
+				rets = append(rets, row)
+			}
+			if expStr, retStr := c.expected.String(c.outputTypes), rets.String(c.outputTypes); expStr != retStr {
+				t.Errorf("invalid results; expected:\n   %s\ngot:\n   %s", expStr, retStr)
+			}
+		})
+	}
+}
@@ -404,6 +404,9 @@ func (p *planner) dropIndexByName(
 	if !found {
 		return fmt.Errorf("index %q in the middle of being added, try again later", idxName)
 	}
+	// The hidden columns holding the values of the index's expressions are
+	// dropped along with it, unless other indexes still use them.
+	dropUnusedComputedColumns(tableDesc)
 
 	if err := tableDesc.Validate(ctx, p.txn); err != nil {
 		return err
@@ -645,7 +648,7 @@ func (p *planner) DropTable(ctx context.Context, n *tree.DropTable) (planNode, e
 		if err != nil {
 			return nil, err
 		}
-		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+		if err := p.qualifyTableName(ctx, tn); err != nil {
 			return nil, err
 		}
 
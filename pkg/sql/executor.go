@@ -46,6 +46,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -245,6 +246,9 @@ type Executor struct {
 	// Application-level SQL statistics
 	sqlStats sqlStats
 
+	// statsRefresher refreshes table statistics after tables are modified.
+	statsRefresher statsRefresher
+
 	// Attempts to use unimplemented features.
 	unimplementedErrors struct {
 		syncutil.Mutex
@@ -278,6 +282,7 @@ type ExecutorConfig struct {
 	StatusServer    serverpb.StatusServer
 	SessionRegistry *SessionRegistry
 	JobRegistry     *jobs.Registry
+	TableStatsCache *stats.TableStatisticsCache
 
 	TestingKnobs              *ExecutorTestingKnobs
 	SchemaChangerTestingKnobs *SchemaChangerTestingKnobs
@@ -425,6 +430,9 @@ func (e *Executor) Start(
 		}
 	})
 
+	e.statsRefresher = makeStatsRefresher(e, startupMemMetrics)
+	e.statsRefresher.start(ctx, e.stopper)
+
 	ctx = log.WithLogTag(ctx, "startup", nil)
 	startupSession := NewSession(ctx, SessionArgs{}, e, nil, startupMemMetrics)
 	startupSession.StartUnlimitedMonitor()
@@ -1264,7 +1272,7 @@ func (e *Executor) execSingleStatement(
 		panic("execStmt called outside of a txn")
 	}
 
-	queryID := e.generateQueryID()
+	queryID := e.generateClusterWideID()
 
 	queryMeta := &queryMeta{
 		start: session.phaseTimes[sessionEndParse],
@@ -2004,19 +2012,27 @@ func (e *Executor) execClassic(
 			return err
 		}
 		rowResultWriter.IncrementRowsAffected(count)
+		if desc := mutatedTable(plan); desc != nil {
+			e.statsRefresher.notifyMutation(desc, count)
+		}
 
 	case tree.Rows:
+		count := 0
 		err := forEachRow(params, plan, func(values tree.Datums) error {
 			for _, val := range values {
 				if err := checkResultType(val.ResolvedType()); err != nil {
 					return err
 				}
 			}
+			count++
 			return rowResultWriter.AddRow(ctx, values)
 		})
 		if err != nil {
 			return err
 		}
+		if desc := mutatedTable(plan); desc != nil {
+			e.statsRefresher.notifyMutation(desc, count)
+		}
 	case tree.DDL:
 		if n, ok := plan.(*createTableNode); ok && n.n.As() {
 			rowResultWriter.IncrementRowsAffected(n.count)
@@ -2268,9 +2284,9 @@ func (e *Executor) updateStmtCounts(stmt Statement) {
 	}
 }
 
-// generateQueryID generates a unique ID for a query based on the node's
-// ID and its current HLC timestamp.
-func (e *Executor) generateQueryID() uint128.Uint128 {
+// generateClusterWideID generates a unique ID for a query or a session based
+// on the node's ID and its current HLC timestamp.
+func (e *Executor) generateClusterWideID() uint128.Uint128 {
 	timestamp := e.cfg.Clock.Now()
 
 	loInt := (uint64)(e.cfg.NodeID.Get())
@@ -112,22 +112,60 @@ func doExpandPlan(
 		n.source.plan, err = doExpandPlan(ctx, p, params, n.source.plan)
 
 	case *joinNode:
-		n.left.plan, err = doExpandPlan(ctx, p, noParams, n.left.plan)
+		if n.joinType == joinTypeInner && !n.reorderDone {
+			// Choose the order of the tree of inner joins rooted at this node
+			// before its inputs are expanded.
+			var newPlan planNode
+			newPlan, err = p.reorderJoins(ctx, n)
+			if err != nil {
+				return plan, err
+			}
+			if newPlan != plan {
+				return doExpandPlan(ctx, p, params, newPlan)
+			}
+		}
+
+		// If the join is expected to be performed as a merge join, ask the
+		// inputs for an ordering on the equality columns.
+		leftParams, rightParams := noParams, noParams
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			// Semi and anti joins preserve the order of the left rows.
+			leftParams.desiredOrdering = params.desiredOrdering
+		} else if len(n.mergeJoinHint) > 0 {
+			leftParams.desiredOrdering = make(sqlbase.ColumnOrdering, len(n.mergeJoinHint))
+			rightParams.desiredOrdering = make(sqlbase.ColumnOrdering, len(n.mergeJoinHint))
+			for i, c := range n.mergeJoinHint {
+				leftParams.desiredOrdering[i] = sqlbase.ColumnOrderInfo{
+					ColIdx: n.pred.leftEqualityIndices[c.ColIdx], Direction: c.Direction,
+				}
+				rightParams.desiredOrdering[i] = sqlbase.ColumnOrderInfo{
+					ColIdx: n.pred.rightEqualityIndices[c.ColIdx], Direction: c.Direction,
+				}
+			}
+		}
+
+		n.left.plan, err = doExpandPlan(ctx, p, leftParams, n.left.plan)
 		if err != nil {
 			return plan, err
 		}
-		n.right.plan, err = doExpandPlan(ctx, p, noParams, n.right.plan)
+		n.right.plan, err = doExpandPlan(ctx, p, rightParams, n.right.plan)
 		if err != nil {
 			return plan, err
 		}
 
-		n.mergeJoinOrdering = computeMergeJoinOrdering(
-			planPhysicalProps(n.left.plan),
-			planPhysicalProps(n.right.plan),
-			n.pred.leftEqualityIndices,
-			n.pred.rightEqualityIndices,
-		)
-		n.props = n.joinOrdering()
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			// The results are a subset of the left rows, in the same order.
+			leftProps := planPhysicalProps(n.left.plan)
+			n.props = leftProps.copy()
+		} else {
+			n.mergeJoinOrdering = computeMergeJoinOrdering(
+				planPhysicalProps(n.left.plan),
+				planPhysicalProps(n.right.plan),
+				n.pred.leftEqualityIndices,
+				n.pred.rightEqualityIndices,
+			)
+			n.props = n.joinOrdering()
+		}
 
 	case *ordinalityNode:
 		// There may be too many columns in the required ordering. Filter them.
@@ -170,6 +208,14 @@ func doExpandPlan(
 	case *windowNode:
 		n.plan, err = doExpandPlan(ctx, p, noParams, n.plan)
 
+	case *projectSetNode:
+		n.source.plan, err = doExpandPlan(ctx, p, noParams, n.source.plan)
+
+	case *applyJoinNode:
+		// The right side is planned and expanded for every row of the left
+		// side.
+		n.left.plan, err = doExpandPlan(ctx, p, noParams, n.left.plan)
+
 	case *sortNode:
 		if !n.ordering.IsPrefixOf(params.desiredOrdering) {
 			params.desiredOrdering = n.ordering
@@ -245,6 +291,21 @@ func doExpandPlan(
 		}
 		plan = newPlan
 
+	case *cteScanNode:
+		// The plan of the CTE is shared by all its references; optimize
+		// it only once. All its columns are needed, since the references
+		// may use different columns.
+		if !n.src.expanded {
+			n.src.plan, err = p.optimizePlan(ctx, n.src.plan, allColumns(n.src.plan))
+			if err != nil {
+				return plan, err
+			}
+			n.src.expanded = true
+		}
+
+	case *recursiveCTENode:
+		n.initial, err = doExpandPlan(ctx, p, noParams, n.initial)
+
 	case *splitNode:
 		n.rows, err = doExpandPlan(ctx, p, noParams, n.rows)
 
@@ -264,6 +325,7 @@ func doExpandPlan(
 	case *createUserNode:
 	case *createViewNode:
 	case *createSequenceNode:
+	case *createStatsNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
 	case *dropTableNode:
@@ -489,6 +551,10 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 			}
 		}
 
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			usefulLeft = usefulOrdering
+		}
+
 		n.props.trim(usefulOrdering)
 
 		n.left.plan = p.simplifyOrderings(n.left.plan, usefulLeft)
@@ -511,6 +577,12 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 	case *windowNode:
 		n.plan = p.simplifyOrderings(n.plan, nil)
 
+	case *projectSetNode:
+		n.source.plan = p.simplifyOrderings(n.source.plan, nil)
+
+	case *applyJoinNode:
+		n.left.plan = p.simplifyOrderings(n.left.plan, nil)
+
 	case *sortNode:
 		if n.needSort {
 			// We could pass no ordering below, but a partial ordering can speed up
@@ -575,6 +647,11 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 	case *delayedNode:
 		n.plan = p.simplifyOrderings(n.plan, usefulOrdering)
 
+	case *cteScanNode:
+
+	case *recursiveCTENode:
+		n.initial = p.simplifyOrderings(n.initial, nil)
+
 	case *splitNode:
 		n.rows = p.simplifyOrderings(n.rows, nil)
 
@@ -594,6 +671,7 @@ func (p *planner) simplifyOrderings(plan planNode, usefulOrdering sqlbase.Column
 	case *createUserNode:
 	case *createViewNode:
 	case *createSequenceNode:
+	case *createStatsNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
 	case *dropTableNode:
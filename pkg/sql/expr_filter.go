@@ -71,9 +71,13 @@ func (v *varConvertVisitor) VisitPre(expr tree.Expr) (recurse bool, newExpr tree
 	}
 
 	if varExpr, ok := expr.(tree.VariableExpr); ok {
-		// Ignore sub-queries and placeholders
-		switch expr.(type) {
-		case *subquery, *tree.Placeholder:
+		// Ignore sub-queries, placeholders and references to the columns
+		// of an enclosing query. The variables of the enclosing query that
+		// are used by a correlated sub-query are converted too.
+		switch t := expr.(type) {
+		case *subquery:
+			return t.outerArgs != nil, expr
+		case *tree.Placeholder, *outerVar:
 			return false, expr
 		}
 
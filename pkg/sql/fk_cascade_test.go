@@ -0,0 +1,66 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestFKCascadeMaxDepth verifies that chains of cascading foreign key
+// actions longer than sql.fk.cascade_max_depth are rejected.
+func TestFKCascadeMaxDepth(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `
+CREATE DATABASE d;
+CREATE TABLE d.t (
+  id INT PRIMARY KEY,
+  parent INT REFERENCES d.t ON DELETE CASCADE,
+  INDEX (parent)
+);
+INSERT INTO d.t VALUES (1, NULL);
+INSERT INTO d.t VALUES (2, 1);
+INSERT INTO d.t VALUES (3, 2);
+INSERT INTO d.t VALUES (4, 3);
+`)
+
+	st := s.ClusterSettings()
+	st.Manual.Store(true)
+	fkCascadeMaxDepth.Override(&st.SV, 2)
+
+	// Deleting 1 would delete 2, 3 and 4.
+	if _, err := db.Exec(`DELETE FROM d.t WHERE id = 1`); !testutils.IsError(err,
+		`cascading foreign key actions on table "t" exceed the maximum depth of 2`,
+	) {
+		t.Fatalf("expected cascade depth error, got %v", err)
+	}
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM d.t`, [][]string{{"4"}})
+
+	fkCascadeMaxDepth.Override(&st.SV, 3)
+	sqlDB.Exec(t, `DELETE FROM d.t WHERE id = 1`)
+	sqlDB.CheckQueryResults(t, `SELECT count(*) FROM d.t`, [][]string{{"0"}})
+}
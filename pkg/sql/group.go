@@ -20,7 +20,6 @@ import (
 
 	"golang.org/x/net/context"
 
-	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -397,8 +396,15 @@ func (n *groupNode) Next(params runParams) (bool, error) {
 			if f.argRenderIdx != noRenderIdx {
 				value = values[f.argRenderIdx]
 			}
+			var otherArgs tree.Datums
+			if len(f.otherArgRenderIdxs) > 0 {
+				otherArgs = make(tree.Datums, len(f.otherArgRenderIdxs))
+				for i, idx := range f.otherArgRenderIdxs {
+					otherArgs[i] = values[idx]
+				}
+			}
 
-			if err := f.add(params.ctx, n.planner.session, bucket, value); err != nil {
+			if err := f.add(params.ctx, n.planner.session, bucket, value, otherArgs...); err != nil {
 				return false, err
 			}
 		}
@@ -583,32 +589,30 @@ func (v *extractAggregatesVisitor) VisitPre(expr tree.Expr) (recurse bool, newEx
 				// COUNT_ROWS has no arguments.
 				f = v.groupNode.newAggregateFuncHolder(t, noRenderIdx, false /* not ident */, agg)
 
-			case 1:
-				argExpr := t.Exprs[0].(tree.TypedExpr)
-
-				if err := v.planner.txCtx.AssertNoAggregationOrWindowing(
-					argExpr,
-					fmt.Sprintf("the argument of %s()", t.Func),
-					v.planner.session.SearchPath,
-				); err != nil {
-					v.err = err
-					return false, expr
-				}
-
-				// Add a render for the argument.
-				col := sqlbase.ResultColumn{
-					Name: argExpr.String(),
-					Typ:  argExpr.ResolvedType(),
+			default:
+				// Add a render for each argument.
+				argRenderIdxs := make([]int, len(t.Exprs))
+				for i, e := range t.Exprs {
+					argExpr := e.(tree.TypedExpr)
+
+					if err := v.planner.txCtx.AssertNoAggregationOrWindowing(
+						argExpr,
+						fmt.Sprintf("the argument of %s()", t.Func),
+						v.planner.session.SearchPath,
+					); err != nil {
+						v.err = err
+						return false, expr
+					}
+
+					col := sqlbase.ResultColumn{
+						Name: argExpr.String(),
+						Typ:  argExpr.ResolvedType(),
+					}
+					argRenderIdxs[i] = v.preRender.addOrReuseRender(col, argExpr, true /* reuse */)
 				}
 
-				argRenderIdx := v.preRender.addOrReuseRender(col, argExpr, true /* reuse */)
-
-				f = v.groupNode.newAggregateFuncHolder(t, argRenderIdx, false /* not ident */, agg)
-
-			default:
-				// TODO: #10495
-				v.err = pgerror.UnimplementedWithIssueError(10495, "aggregate functions with multiple arguments are not supported yet")
-				return false, expr
+				f = v.groupNode.newAggregateFuncHolder(t, argRenderIdxs[0], false /* not ident */, agg)
+				f.otherArgRenderIdxs = argRenderIdxs[1:]
 			}
 
 			if t.Type == tree.DistinctFuncType {
@@ -673,7 +677,10 @@ type aggregateFuncHolder struct {
 	// The argument of the function is a single value produced by the renderNode
 	// underneath.
 	argRenderIdx int
-	hasFilter    bool
+	// The arguments after the first one, for the functions taking several, are
+	// also values produced by the renderNode underneath.
+	otherArgRenderIdxs []int
+	hasFilter          bool
 	// If there is a filter, the result is a single value produced by the
 	// renderNode underneath.
 	filterRenderIdx int
@@ -732,7 +739,7 @@ func (a *aggregateFuncHolder) close(ctx context.Context, s *Session) {
 // add accumulates one more value for a particular bucket into an aggregation
 // function.
 func (a *aggregateFuncHolder) add(
-	ctx context.Context, s *Session, bucket []byte, d tree.Datum,
+	ctx context.Context, s *Session, bucket []byte, d tree.Datum, otherArgs ...tree.Datum,
 ) error {
 	// NB: the compiler *should* optimize `myMap[string(myBytes)]`. See:
 	// https://github.com/golang/go/commit/f5f5a8b6209f84961687d993b93ea0d397f5d5bf
@@ -742,6 +749,13 @@ func (a *aggregateFuncHolder) add(
 		if err != nil {
 			return err
 		}
+		// Encode additional arguments if necessary.
+		if otherArgs != nil {
+			encoded, err = sqlbase.EncodeDatums(encoded, otherArgs)
+			if err != nil {
+				return err
+			}
+		}
 		if _, ok := a.seen[string(encoded)]; ok {
 			// skip
 			return nil
@@ -758,5 +772,5 @@ func (a *aggregateFuncHolder) add(
 		a.buckets[string(bucket)] = impl
 	}
 
-	return impl.Add(ctx, d)
+	return impl.Add(ctx, d, otherArgs...)
 }
@@ -0,0 +1,310 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/transform"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// indexExprColumnName is the base name of the hidden computed columns
+// holding the values of the expressions of expression indexes.
+const indexExprColumnName = "crdb_idx_expr"
+
+// makeIndexExprColumns returns a copy of elems in which the elements
+// indexing an expression are replaced by references to hidden computed
+// columns of tableDesc holding the value of the expression. A column with
+// the same expression is reused if there is one, otherwise addColumn is
+// called with the new column.
+func makeIndexExprColumns(
+	tableDesc *sqlbase.TableDescriptor,
+	elems tree.IndexElemList,
+	semaCtx *tree.SemaContext,
+	addColumn func(sqlbase.ColumnDescriptor),
+) (tree.IndexElemList, error) {
+	res := make(tree.IndexElemList, len(elems))
+	for i, elem := range elems {
+		res[i] = elem
+		if elem.Expr == nil {
+			continue
+		}
+		res[i].Expr = nil
+		expr, err := normalizeIndexExpr(elem.Expr, semaCtx)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := expr.(*tree.ColumnItem); ok {
+			// An expression consisting of a column is the column itself.
+			res[i].Column = c.ColumnName
+			continue
+		}
+
+		computeExpr := tree.Serialize(expr)
+		if name, ok := findComputedColumn(tableDesc, computeExpr); ok {
+			res[i].Column = tree.Name(name)
+			continue
+		}
+
+		typedExpr, err := sqlbase.TypeCheckComputedExpr(tableDesc, expr)
+		if err != nil {
+			return nil, err
+		}
+		if err := assertPureIndexExpr(typedExpr); err != nil {
+			return nil, err
+		}
+		colType, err := sqlbase.DatumTypeToColumnType(typedExpr.ResolvedType())
+		if err != nil {
+			return nil, err
+		}
+		col := sqlbase.ColumnDescriptor{
+			Name:        uniqueIndexExprColumnName(tableDesc),
+			Type:        colType,
+			Nullable:    true,
+			Hidden:      true,
+			ComputeExpr: &computeExpr,
+		}
+		addColumn(col)
+		res[i].Column = tree.Name(col.Name)
+	}
+	return res, nil
+}
+
+// normalizeIndexExpr checks that expr can be used as the expression of an
+// expression index and strips the table names from its column references.
+func normalizeIndexExpr(expr tree.Expr, semaCtx *tree.SemaContext) (tree.Expr, error) {
+	preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+		switch t := expr.(type) {
+		case tree.VarName:
+			v, err := t.NormalizeVarName()
+			if err != nil {
+				return err, false, nil
+			}
+			c, ok := v.(*tree.ColumnItem)
+			if !ok || len(c.Selector) > 0 {
+				return pgerror.NewErrorf(pgerror.CodeInvalidColumnReferenceError,
+					"invalid column reference %s in index expression", v), false, nil
+			}
+			return nil, false, &tree.ColumnItem{ColumnName: c.ColumnName}
+		case *tree.Subquery:
+			return pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+				"subqueries are not allowed in index expressions"), false, nil
+		case *tree.Placeholder:
+			return pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+				"placeholders are not allowed in index expressions"), false, nil
+		}
+		return nil, true, expr
+	}
+	expr, err := tree.SimpleVisit(expr, preFn)
+	if err != nil {
+		return nil, err
+	}
+	var txCtx transform.ExprTransformContext
+	if err := txCtx.AssertNoAggregationOrWindowing(
+		expr, "index expressions", semaCtx.SearchPath,
+	); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// assertPureIndexExpr returns an error if expr calls an impure function,
+// whose results can't be indexed.
+func assertPureIndexExpr(expr tree.TypedExpr) error {
+	var impure *tree.FuncExpr
+	preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+		if f, ok := expr.(*tree.FuncExpr); ok && f.IsImpure() {
+			impure = f
+			return nil, false, expr
+		}
+		return nil, true, expr
+	}
+	if _, err := tree.SimpleVisit(expr, preFn); err != nil {
+		return err
+	}
+	if impure != nil {
+		return pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"impure functions are not allowed in index expressions: %s", impure)
+	}
+	return nil
+}
+
+// findComputedColumn returns the name of the computed column of tableDesc
+// with the given expression, if any.
+func findComputedColumn(tableDesc *sqlbase.TableDescriptor, computeExpr string) (string, bool) {
+	for _, col := range tableDesc.Columns {
+		if col.IsComputed() && *col.ComputeExpr == computeExpr {
+			return col.Name, true
+		}
+	}
+	for _, m := range tableDesc.Mutations {
+		if col := m.GetColumn(); col != nil && col.IsComputed() &&
+			m.Direction == sqlbase.DescriptorMutation_ADD && *col.ComputeExpr == computeExpr {
+			return col.Name, true
+		}
+	}
+	return "", false
+}
+
+// uniqueIndexExprColumnName returns an unused name for a hidden computed
+// column of tableDesc.
+func uniqueIndexExprColumnName(tableDesc *sqlbase.TableDescriptor) string {
+	name := indexExprColumnName
+	for i := 1; ; i++ {
+		if _, _, err := tableDesc.FindColumnByName(tree.Name(name)); err != nil {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", indexExprColumnName, i)
+	}
+}
+
+// computedColumnsReferencing returns the IDs of the computed columns of
+// tableDesc whose expressions reference the given column.
+func computedColumnsReferencing(
+	tableDesc *sqlbase.TableDescriptor, col sqlbase.ColumnDescriptor,
+) ([]sqlbase.ColumnID, error) {
+	var computedCols []sqlbase.ColumnDescriptor
+	for _, c := range tableDesc.Columns {
+		if c.IsComputed() {
+			computedCols = append(computedCols, c)
+		}
+	}
+	if len(computedCols) == 0 {
+		return nil, nil
+	}
+	computed, err := sqlbase.MakeComputedColumns(tableDesc, computedCols)
+	if err != nil {
+		return nil, err
+	}
+	var ids []sqlbase.ColumnID
+	for _, c := range computed.Referencing([]sqlbase.ColumnDescriptor{col}) {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// isIndexExprColumn returns true if col is a hidden computed column
+// created by makeIndexExprColumns to hold the value of an index
+// expression.
+func isIndexExprColumn(col *sqlbase.ColumnDescriptor) bool {
+	return col.Hidden && col.IsComputed()
+}
+
+// dropUnusedComputedColumns queues the drop of the hidden computed columns
+// of tableDesc that were created for index expressions and aren't indexed
+// by any index anymore. Other computed columns are left alone.
+func dropUnusedComputedColumns(tableDesc *sqlbase.TableDescriptor) {
+	for i := 0; i < len(tableDesc.Columns); i++ {
+		col := tableDesc.Columns[i]
+		if !isIndexExprColumn(&col) {
+			continue
+		}
+		used := false
+		for _, idx := range tableDesc.AllNonDropIndexes() {
+			if idx.ContainsColumnID(col.ID) {
+				used = true
+				break
+			}
+		}
+		if used {
+			continue
+		}
+		tableDesc.AddColumnMutation(col, sqlbase.DescriptorMutation_DROP)
+		tableDesc.Columns = append(tableDesc.Columns[:i], tableDesc.Columns[i+1:]...)
+		i--
+	}
+}
+
+// replaceIndexExprs replaces the subexpressions of the filter of s that are
+// equivalent to the expression of a computed column of the table by
+// references to that column, so that the indexes on the column can be used
+// to constrain the scan.
+func (p *planner) replaceIndexExprs(s *scanNode) error {
+	var exprs []string
+	var colIdxs []int
+	for _, col := range s.desc.Columns {
+		if !col.IsComputed() {
+			continue
+		}
+		colIdx, ok := s.colIdxMap[col.ID]
+		if !ok {
+			continue
+		}
+		expr, err := parser.ParseExpr(*col.ComputeExpr)
+		if err != nil {
+			return err
+		}
+		bound := true
+		preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+			c, ok := expr.(*tree.ColumnItem)
+			if !ok {
+				return nil, true, expr
+			}
+			src, _, err := s.desc.FindColumnByName(c.ColumnName)
+			if err != nil {
+				return err, false, nil
+			}
+			idx, ok := s.colIdxMap[src.ID]
+			if !ok {
+				bound = false
+				return nil, false, expr
+			}
+			return nil, false, s.filterVars.IndexedVar(idx)
+		}
+		expr, err = tree.SimpleVisit(expr, preFn)
+		if err != nil {
+			return err
+		}
+		if !bound {
+			continue
+		}
+		typedExpr, err := tree.TypeCheck(expr, &p.semaCtx, col.Type.ToDatumType())
+		if err != nil {
+			return err
+		}
+		if typedExpr, err = p.evalCtx.NormalizeExpr(typedExpr); err != nil {
+			return err
+		}
+		exprs = append(exprs, tree.AsStringWithFlags(typedExpr, tree.FmtCheckEquivalence))
+		colIdxs = append(colIdxs, colIdx)
+	}
+	if len(exprs) == 0 {
+		return nil
+	}
+
+	preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+		switch expr.(type) {
+		case *tree.IndexedVar, tree.Datum:
+			return nil, false, expr
+		}
+		str := tree.AsStringWithFlags(expr, tree.FmtCheckEquivalence)
+		for i := range exprs {
+			if str == exprs[i] {
+				return nil, false, s.filterVars.IndexedVar(colIdxs[i])
+			}
+		}
+		return nil, true, expr
+	}
+	filter, err := tree.SimpleVisit(s.filter, preFn)
+	if err != nil {
+		return err
+	}
+	s.filter = filter.(tree.TypedExpr)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestDropUnusedComputedColumns(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	computed := func(id sqlbase.ColumnID, name string, hidden bool) sqlbase.ColumnDescriptor {
+		expr := "lower(b)"
+		return sqlbase.ColumnDescriptor{
+			ID: id, Name: name, Type: sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_STRING},
+			Nullable: true, Hidden: hidden, ComputeExpr: &expr,
+		}
+	}
+	desc := sqlbase.TableDescriptor{
+		Name: "t",
+		Columns: []sqlbase.ColumnDescriptor{
+			{ID: 1, Name: "a", Type: sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_INT}},
+			{ID: 2, Name: "b", Type: sqlbase.ColumnType{SemanticType: sqlbase.ColumnType_STRING}},
+			// A computed column declared by the user, not indexed.
+			computed(3, "c", false /* hidden */),
+			// Index expression columns, one of them still indexed.
+			computed(4, indexExprColumnName, true /* hidden */),
+			computed(5, indexExprColumnName+"1", true /* hidden */),
+		},
+		PrimaryIndex: sqlbase.IndexDescriptor{
+			Name: "primary", ID: 1, ColumnIDs: []sqlbase.ColumnID{1}, ColumnNames: []string{"a"},
+		},
+		Indexes: []sqlbase.IndexDescriptor{{
+			Name: "t_idx", ID: 2, ColumnIDs: []sqlbase.ColumnID{5}, ColumnNames: []string{indexExprColumnName + "1"},
+		}},
+	}
+
+	dropUnusedComputedColumns(&desc)
+
+	var names []string
+	for _, col := range desc.Columns {
+		names = append(names, col.Name)
+	}
+	if expected := []string{"a", "b", "c", indexExprColumnName + "1"}; len(names) != len(expected) {
+		t.Fatalf("expected columns %v, got %v", expected, names)
+	} else {
+		for i := range names {
+			if names[i] != expected[i] {
+				t.Fatalf("expected columns %v, got %v", expected, names)
+			}
+		}
+	}
+	if len(desc.Mutations) != 1 || desc.Mutations[0].GetColumn().ID != 4 ||
+		desc.Mutations[0].Direction != sqlbase.DescriptorMutation_DROP {
+		t.Fatalf("expected a single mutation dropping column 4, got %v", desc.Mutations)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 )
 
 const indexJoinBatchSize = 100
@@ -104,6 +105,13 @@ type indexJoinNode struct {
 	// may produce more values than this, e.g. when its filter expression
 	// uses more columns than the PK.
 	primaryKeyColumns []bool
+
+	// seenKeys holds the primary keys looked up so far when the index is an
+	// inverted index, in which the same row can be found through several
+	// paths. It is nil otherwise. The memory used by the keys is accounted
+	// for by seenKeysAcc.
+	seenKeys    map[string]struct{}
+	seenKeysAcc mon.BoundAccount
 }
 
 // makeIndexJoin build an index join node.
@@ -126,6 +134,10 @@ func (p *planner) makeIndexJoin(
 	_ = table.initDescDefaults(origScan.scanVisibility, nil)
 	table.initOrdering(0)
 	table.disableBatchLimit()
+	// Rows are locked through their primary index entries. The index scan
+	// keeps the wait policy.
+	table.lockStrength, indexScan.lockStrength = origScan.lockStrength, tree.ForNone
+	table.lockWaitPolicy = origScan.lockWaitPolicy
 
 	colIDtoRowIndex := map[sqlbase.ColumnID]int{}
 
@@ -161,6 +173,10 @@ func (p *planner) makeIndexJoin(
 		if !ok {
 			panic(fmt.Sprintf("Unknown column %d in index!", colID))
 		}
+		if indexScan.index.Type == sqlbase.IndexDescriptor_INVERTED {
+			// An inverted index doesn't provide the values of its column.
+			continue
+		}
 		valProvidedIndex[idx] = true
 		colIDtoRowIndex[colID] = idx
 	}
@@ -201,6 +217,10 @@ func (p *planner) makeIndexJoin(
 		colIDtoRowIndex:   colIDtoRowIndex,
 		primaryKeyColumns: primaryKeyColumns,
 	}
+	if indexScan.index.Type == sqlbase.IndexDescriptor_INVERTED {
+		node.seenKeys = make(map[string]struct{})
+		node.seenKeysAcc = p.session.TxnState.makeBoundAccount()
+	}
 
 	return node, indexScan
 }
@@ -257,6 +277,15 @@ func (n *indexJoinNode) Next(params runParams) (bool, error) {
 			if err != nil {
 				return false, err
 			}
+			if n.seenKeys != nil {
+				if _, ok := n.seenKeys[string(primaryIndexKey)]; ok {
+					continue
+				}
+				if err := n.seenKeysAcc.Grow(params.ctx, int64(len(primaryIndexKey))); err != nil {
+					return false, err
+				}
+				n.seenKeys[string(primaryIndexKey)] = struct{}{}
+			}
 			key := roachpb.Key(primaryIndexKey)
 			n.table.spans = append(n.table.spans, roachpb.Span{
 				Key:    key,
@@ -274,4 +303,8 @@ func (n *indexJoinNode) Next(params runParams) (bool, error) {
 func (n *indexJoinNode) Close(ctx context.Context) {
 	n.index.Close(ctx)
 	n.table.Close(ctx)
+	if n.seenKeys != nil {
+		n.seenKeys = nil
+		n.seenKeysAcc.Close(ctx)
+	}
 }
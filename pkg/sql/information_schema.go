@@ -612,6 +612,10 @@ func forEachDatabaseDesc(
 
 	sort.Sort(sortedDBDescs(dbDescs))
 	for _, db := range dbDescs {
+		if p.isOtherSessionTemporarySchema(db.Name) {
+			// Temporary schemas are private to their session.
+			continue
+		}
 		if userCanSeeDatabase(db, p.session.User) {
 			if err := fn(db); err != nil {
 				return err
@@ -796,7 +800,8 @@ func forEachTableDescWithTableLookupInternal(
 	}
 	sort.Strings(dbNames)
 	for _, dbName := range dbNames {
-		if !isDatabaseVisible(dbName, prefix, p.session.User) {
+		if !isDatabaseVisible(dbName, prefix, p.session.User) ||
+			p.isOtherSessionTemporarySchema(dbName) {
 			continue
 		}
 		db := databases[dbName]
@@ -56,6 +56,11 @@ type insertNode struct {
 	insertCols            []sqlbase.ColumnDescriptor
 	insertColIDtoRowIndex map[sqlbase.ColumnID]int
 	tw                    tableWriter
+	// conversions compute the values of the columns being converted by an
+	// ALTER COLUMN ... TYPE.
+	conversions []*sqlbase.ColumnConversion
+	// computed computes the values of the computed columns, if any.
+	computed *sqlbase.ComputedColumns
 
 	isUpsertReturning bool
 
@@ -78,7 +83,13 @@ type insertNode struct {
 func (p *planner) Insert(
 	ctx context.Context, n *tree.Insert, desiredTypes []types.T,
 ) (planNode, error) {
-	tn, err := p.getAliasedTableName(n.Table)
+	popWith, err := p.initWith(ctx, n.With)
+	if err != nil {
+		return nil, err
+	}
+	defer popWith()
+
+	tn, err := p.getAliasedTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +130,15 @@ func (p *planner) Insert(
 		return nil, err
 	}
 
+	conversions, err := sqlbase.MakeColumnConversions(en.tableDesc)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := sqlbase.MakeWritableComputedColumns(en.tableDesc)
+	if err != nil {
+		return nil, err
+	}
+
 	var insertRows tree.SelectStatement
 	if n.DefaultValues() {
 		insertRows = getDefaultValuesClause(defaultExprs, cols)
@@ -220,6 +240,10 @@ func (p *planner) Insert(
 				if err != nil {
 					return nil, err
 				}
+				if col.IsComputed() {
+					return nil, pgerror.NewErrorf(pgerror.CodeObjectNotInPrerequisiteStateError,
+						"cannot write directly to computed column %q", c.ColumnName)
+				}
 				updateCols[i] = col
 			}
 
@@ -229,11 +253,24 @@ func (p *planner) Insert(
 			if err != nil {
 				return nil, err
 			}
+			updateConversions := conversionsForUpdate(conversions, updateCols)
+			for _, c := range updateConversions {
+				updateCols = append(updateCols, c.Column)
+			}
+			var updateComputed *sqlbase.ComputedColumns
+			if computedCols := computed.Referencing(updateCols); len(computedCols) > 0 {
+				updateCols = append(updateCols, computedCols...)
+				updateComputed = computed
+			}
 
 			fkTables := sqlbase.TablesNeededForFKs(*en.tableDesc, sqlbase.CheckUpdates)
 			if err := p.fillFKTableMap(ctx, fkTables); err != nil {
 				return nil, err
 			}
+			cascades, err := p.fillFKCascadeTableMap(ctx, en.tableDesc, sqlbase.CheckUpdates, fkTables)
+			if err != nil {
+				return nil, err
+			}
 			tu := tableUpserterPool.Get().(*tableUpserter)
 			*tu = tableUpserter{
 				ri:            ri,
@@ -242,10 +279,14 @@ func (p *planner) Insert(
 				mon:           &p.session.TxnState.mon,
 				collectRows:   isUpsertReturning,
 				fkTables:      fkTables,
+				cascades:      cascades,
 				updateCols:    updateCols,
 				conflictIndex: *conflictIndex,
 				evaler:        helper,
 				isUpsertAlias: n.OnConflict.IsUpsertAlias(),
+				conversions:   updateConversions,
+				computed:      updateComputed,
+				evalCtx:       &p.evalCtx,
 			}
 			tw = tu
 		}
@@ -260,6 +301,8 @@ func (p *planner) Insert(
 		insertColIDtoRowIndex: ri.InsertColIDtoRowIndex,
 		isUpsertReturning:     isUpsertReturning,
 		tw:                    tw,
+		conversions:           conversions,
+		computed:              computed,
 	}
 
 	if err := in.checkHelper.init(ctx, p, tn, en.tableDesc); err != nil {
@@ -386,6 +429,23 @@ func (n *insertNode) internalNext(params runParams) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if len(n.conversions) > 0 || n.computed != nil {
+		// It's not cool to modify the slice returned by a node; make a copy.
+		rowVals = append(tree.Datums(nil), rowVals...)
+		if err := sqlbase.ConvertColumns(
+			&params.p.evalCtx, n.conversions, n.insertColIDtoRowIndex, rowVals,
+		); err != nil {
+			return false, err
+		}
+		if n.computed != nil {
+			n.computed.LoadRow(n.insertColIDtoRowIndex, rowVals, false)
+			if err := n.computed.Compute(
+				&params.p.evalCtx, n.insertColIDtoRowIndex, rowVals,
+			); err != nil {
+				return false, err
+			}
+		}
+	}
 
 	if err := n.checkHelper.loadRow(n.insertColIDtoRowIndex, rowVals, false); err != nil {
 		return false, err
@@ -451,9 +511,10 @@ func GenerateInsertRow(
 		}
 	}
 
-	// Check to see if NULL is being inserted into any non-nullable column.
+	// Check to see if NULL is being inserted into any non-nullable column,
+	// including columns to which a NOT NULL constraint is being added.
 	for _, col := range tableDesc.Columns {
-		if !col.Nullable {
+		if !col.Nullable || tableDesc.IsValidatingNotNull(col.ID) {
 			if i, ok := insertColIDtoRowIndex[col.ID]; !ok || rowVals[i] == tree.DNull {
 				return nil, sqlbase.NewNonNullViolationError(col.Name)
 			}
@@ -496,6 +557,10 @@ func (p *planner) processColumns(
 		if err != nil {
 			return nil, err
 		}
+		if col.IsComputed() {
+			return nil, pgerror.NewErrorf(pgerror.CodeObjectNotInPrerequisiteStateError,
+				"cannot write directly to computed column %q", c.ColumnName)
+		}
 
 		if _, ok := colIDSet[col.ID]; ok {
 			return nil, fmt.Errorf("multiple assignments to the same column %q", n)
@@ -24,6 +24,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
@@ -67,6 +68,8 @@ type Details interface{}
 var _ Details = BackupDetails{}
 var _ Details = RestoreDetails{}
 var _ Details = SchemaChangeDetails{}
+var _ Details = ChangefeedDetails{}
+var _ Details = CreateStatsDetails{}
 
 // Record stores the job fields that are not automatically managed by Job.
 type Record struct {
@@ -212,6 +215,7 @@ func isControllable(p *Payload, op string) error {
 			18139, "import jobs do not support %s", op)
 	case TypeBackup:
 	case TypeRestore:
+	case TypeChangefeed:
 	default:
 		return fmt.Errorf("%s jobs do not support %s", strings.ToLower(typ.String()), op)
 	}
@@ -445,6 +449,11 @@ func (j *Job) DB() *client.DB {
 	return j.registry.db
 }
 
+// DistSender returns the *kv.DistSender associated with this job.
+func (j *Job) DistSender() *kv.DistSender {
+	return j.registry.distSender
+}
+
 // Gossip returns the *gossip.Gossip associated with this job.
 func (j *Job) Gossip() *gossip.Gossip {
 	return j.registry.gossip
@@ -626,6 +635,10 @@ func (p *Payload) Type() Type {
 		return TypeSchemaChange
 	case *Payload_Import:
 		return TypeImport
+	case *Payload_Changefeed:
+		return TypeChangefeed
+	case *Payload_CreateStats:
+		return TypeCreateStats
 	default:
 		panic("Payload.Type called on a payload with an unknown details type")
 	}
@@ -648,6 +661,10 @@ func WrapPayloadDetails(details Details) interface {
 		return &Payload_SchemaChange{SchemaChange: &d}
 	case ImportDetails:
 		return &Payload_Import{Import: &d}
+	case ChangefeedDetails:
+		return &Payload_Changefeed{Changefeed: &d}
+	case CreateStatsDetails:
+		return &Payload_CreateStats{CreateStats: &d}
 	default:
 		panic(fmt.Sprintf("jobs.WrapPayloadDetails: unknown details type %T", d))
 	}
@@ -669,6 +686,10 @@ func (p *Payload) UnwrapDetails() (Details, error) {
 		return *d.SchemaChange, nil
 	case *Payload_Import:
 		return *d.Import, nil
+	case *Payload_Changefeed:
+		return *d.Changefeed, nil
+	case *Payload_CreateStats:
+		return *d.CreateStats, nil
 	default:
 		return nil, errors.Errorf("jobs.Payload: unsupported details type %T", d)
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -45,13 +46,14 @@ type nodeLiveness interface {
 
 // Registry creates Jobs and manages their leases and cancelation.
 type Registry struct {
-	db        *client.DB
-	ex        sqlutil.InternalExecutor
-	gossip    *gossip.Gossip
-	clock     *hlc.Clock
-	nodeID    *base.NodeIDContainer
-	clusterID func() uuid.UUID
-	settings  *cluster.Settings
+	db         *client.DB
+	distSender *kv.DistSender
+	ex         sqlutil.InternalExecutor
+	gossip     *gossip.Gossip
+	clock      *hlc.Clock
+	nodeID     *base.NodeIDContainer
+	clusterID  func() uuid.UUID
+	settings   *cluster.Settings
 
 	mu struct {
 		syncutil.Mutex
@@ -64,6 +66,7 @@ type Registry struct {
 func MakeRegistry(
 	clock *hlc.Clock,
 	db *client.DB,
+	distSender *kv.DistSender,
 	ex sqlutil.InternalExecutor,
 	gossip *gossip.Gossip,
 	nodeID *base.NodeIDContainer,
@@ -71,13 +74,14 @@ func MakeRegistry(
 	settings *cluster.Settings,
 ) *Registry {
 	r := &Registry{
-		clock:     clock,
-		db:        db,
-		ex:        ex,
-		gossip:    gossip,
-		nodeID:    nodeID,
-		clusterID: clusterID,
-		settings:  settings,
+		clock:      clock,
+		db:         db,
+		distSender: distSender,
+		ex:         ex,
+		gossip:     gossip,
+		nodeID:     nodeID,
+		clusterID:  clusterID,
+		settings:   settings,
 	}
 	r.mu.epoch = 1
 	r.mu.jobs = make(map[int64]*Job)
@@ -79,7 +79,9 @@ func TestRegistryResumeExpiredLease(t *testing.T) {
 	clock := hlc.NewClock(hlc.UnixNano, time.Nanosecond)
 	nodeID := &base.NodeIDContainer{}
 
-	registry := jobs.MakeRegistry(clock, db, ex, gossip, nodeID, jobs.FakeClusterID, s.ClusterSettings())
+	registry := jobs.MakeRegistry(
+		clock, db, s.DistSender(), ex, gossip, nodeID, jobs.FakeClusterID, s.ClusterSettings(),
+	)
 	nodeLiveness := jobs.NewFakeNodeLiveness(clock, 4)
 
 	const cancelInterval = time.Duration(math.MaxInt64)
@@ -42,7 +42,7 @@ func TestRegistryCancelation(t *testing.T) {
 	var ex sqlutil.InternalExecutor
 	var gossip *gossip.Gossip
 	clock := hlc.NewClock(hlc.UnixNano, time.Nanosecond)
-	registry := MakeRegistry(clock, db, ex, gossip, FakeNodeID, FakeClusterID, cluster.NoSettings)
+	registry := MakeRegistry(clock, db, nil /* distSender */, ex, gossip, FakeNodeID, FakeClusterID, cluster.NoSettings)
 
 	const nodeCount = 1
 	nodeLiveness := NewFakeNodeLiveness(clock, nodeCount)
@@ -129,7 +129,7 @@ func TestRegistryRegister(t *testing.T) {
 	var ex sqlutil.InternalExecutor
 	var gossip *gossip.Gossip
 	clock := hlc.NewClock(hlc.UnixNano, time.Nanosecond)
-	registry := MakeRegistry(clock, db, ex, gossip, FakeNodeID, FakeClusterID, cluster.NoSettings)
+	registry := MakeRegistry(clock, db, nil /* distSender */, ex, gossip, FakeNodeID, FakeClusterID, cluster.NoSettings)
 
 	if err := registry.register(42, &Job{}); err != nil {
 		t.Fatal(err)
@@ -34,6 +34,12 @@ const (
 	joinTypeLeftOuter
 	joinTypeRightOuter
 	joinTypeFullOuter
+	// joinTypeSemi and joinTypeAnti return the left rows that have
+	// (respectively, do not have) at least one matching right row. These
+	// joins cannot be expressed in SQL directly; they are used to run
+	// subqueries under EXISTS and IN.
+	joinTypeSemi
+	joinTypeAnti
 )
 
 // bucket here is the set of rows for a given group key (comprised of
@@ -120,8 +126,8 @@ func (b *buckets) Fetch(encoding []byte) (*bucket, bool) {
 	return bk, ok
 }
 
-// joinNode is a planNode whose rows are the result of an inner or
-// left/right outer join.
+// joinNode is a planNode whose rows are the result of an inner,
+// left/right outer, semi or anti join.
 type joinNode struct {
 	planner  *planner
 	joinType joinType
@@ -144,6 +150,16 @@ type joinNode struct {
 	// trimmed.
 	props physicalProps
 
+	// mergeJoinHint is set by reorderJoins when the cost model expects the join
+	// to be performed as a merge join. It has the same format as
+	// mergeJoinOrdering; expandPlan requests the corresponding orderings from
+	// the inputs so that index selection picks indexes that provide them.
+	mergeJoinHint sqlbase.ColumnOrdering
+
+	// reorderDone is set once the order of the tree of inner joins that
+	// contains this node has been considered by reorderJoins.
+	reorderDone bool
+
 	// columns contains the metadata for the results of this node.
 	columns sqlbase.ResultColumns
 
@@ -201,6 +217,25 @@ func (p *planner) makeJoin(
 	left planDataSource,
 	right planDataSource,
 	cond tree.JoinCond,
+) (planDataSource, error) {
+	return p.makeJoinWithNode(ctx, astJoinType, left, right, cond,
+		func(typ joinType, left, right planDataSource, pred *joinPredicate, info *dataSourceInfo) planNode {
+			return p.newJoinNode(typ, left, right, pred, info)
+		})
+}
+
+// makeJoinWithNode is like makeJoin, but the node that performs the join
+// is created by newNode. It is used by makeJoin with joinNode, and by
+// makeLateralJoin with applyJoinNode.
+func (p *planner) makeJoinWithNode(
+	ctx context.Context,
+	astJoinType string,
+	left planDataSource,
+	right planDataSource,
+	cond tree.JoinCond,
+	newNode func(
+		typ joinType, left, right planDataSource, pred *joinPredicate, info *dataSourceInfo,
+	) planNode,
 ) (planDataSource, error) {
 	var typ joinType
 	switch astJoinType {
@@ -260,32 +295,7 @@ func (p *planner) makeJoin(
 		return planDataSource{}, err
 	}
 
-	n := &joinNode{
-		planner:  p,
-		left:     left,
-		right:    right,
-		joinType: typ,
-		pred:     pred,
-		columns:  info.sourceColumns,
-	}
-
-	n.buffer = &RowBuffer{
-		RowContainer: sqlbase.NewRowContainer(
-			p.session.TxnState.makeBoundAccount(), sqlbase.ColTypeInfoFromResCols(planColumns(n)), 0,
-		),
-	}
-
-	n.bucketsMemAcc = p.session.TxnState.OpenAccount()
-	n.buckets = buckets{
-		buckets: make(map[string]*bucket),
-		rowContainer: sqlbase.NewRowContainer(
-			p.session.TxnState.makeBoundAccount(),
-			sqlbase.ColTypeInfoFromResCols(planColumns(n.right.plan)),
-			0,
-		),
-	}
-
-	joinDataSource := planDataSource{info: info, plan: n}
+	joinDataSource := planDataSource{info: info, plan: newNode(typ, left, right, pred, info)}
 
 	if mergedColumns == nil {
 		// No merged columns, we are done.
@@ -369,17 +379,17 @@ func (p *planner) makeJoin(
 		remapped[i] = -1
 	}
 	for i := range mergedColumns {
-		leftCol := n.pred.leftEqualityIndices[i]
-		rightCol := n.pred.rightEqualityIndices[i]
+		leftCol := pred.leftEqualityIndices[i]
+		rightCol := pred.rightEqualityIndices[i]
 		leftHidden.Add(leftCol)
 		rightHidden.Add(rightCol)
 		var expr tree.TypedExpr
-		if n.joinType == joinTypeInner || n.joinType == joinTypeLeftOuter {
+		if typ == joinTypeInner || typ == joinTypeLeftOuter {
 			// The merged column is the same with the corresponding column from the
 			// left side.
 			expr = r.ivarHelper.IndexedVar(leftCol)
 			remapped[leftCol] = i
-		} else if n.joinType == joinTypeRightOuter &&
+		} else if typ == joinTypeRightOuter &&
 			!sqlbase.DatumTypeHasCompositeKeyEncoding(leftInfo.sourceColumns[leftCol].Typ) {
 			// The merged column is the same with the corresponding column from the
 			// right side.
@@ -450,12 +460,12 @@ func (p *planner) makeJoin(
 
 	// Remove any anonymous aliases that refer to hidden equality columns (i.e.
 	// those that weren't equivalent to the merged column).
-	for i, col := range n.pred.leftEqualityIndices {
+	for i, col := range pred.leftEqualityIndices {
 		if target := remapped[col]; target != i {
 			anonymousAlias.columnSet.Remove(target)
 		}
 	}
-	for i, col := range n.pred.rightEqualityIndices {
+	for i, col := range pred.rightEqualityIndices {
 		if target := remapped[numLeft+col]; target != i {
 			anonymousAlias.columnSet.Remove(remapped[numLeft+col])
 		}
@@ -464,6 +474,39 @@ func (p *planner) makeJoin(
 	return planDataSource{info: rInfo, plan: r}, nil
 }
 
+// newJoinNode creates a joinNode of the given type between the left and right
+// data sources; pred and info are the join predicate and the description of
+// the result columns.
+func (p *planner) newJoinNode(
+	typ joinType, left, right planDataSource, pred *joinPredicate, info *dataSourceInfo,
+) *joinNode {
+	n := &joinNode{
+		planner:  p,
+		left:     left,
+		right:    right,
+		joinType: typ,
+		pred:     pred,
+		columns:  info.sourceColumns,
+	}
+
+	n.buffer = &RowBuffer{
+		RowContainer: sqlbase.NewRowContainer(
+			p.session.TxnState.makeBoundAccount(), sqlbase.ColTypeInfoFromResCols(planColumns(n)), 0,
+		),
+	}
+
+	n.bucketsMemAcc = p.session.TxnState.OpenAccount()
+	n.buckets = buckets{
+		buckets: make(map[string]*bucket),
+		rowContainer: sqlbase.NewRowContainer(
+			p.session.TxnState.makeBoundAccount(),
+			sqlbase.ColTypeInfoFromResCols(planColumns(n.right.plan)),
+			0,
+		),
+	}
+	return n
+}
+
 // Start implements the planNode interface.
 func (n *joinNode) Start(params runParams) error {
 	if err := n.left.plan.Start(params); err != nil {
@@ -477,8 +520,10 @@ func (n *joinNode) Start(params runParams) error {
 		return err
 	}
 
-	// Pre-allocate the space for output rows.
-	n.output = make(tree.Datums, len(n.columns))
+	// Pre-allocate the space for output rows. Semi and anti joins only
+	// output the left columns but also use this space to evaluate the join
+	// predicate.
+	n.output = make(tree.Datums, n.pred.numLeftCols+n.pred.numRightCols)
 
 	// If needed, pre-allocate left and right rows of NULL tuples for when the
 	// join predicate fails to match.
@@ -542,7 +587,8 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 		return false, nil
 	}
 
-	wantUnmatchedLeft := n.joinType == joinTypeLeftOuter || n.joinType == joinTypeFullOuter
+	wantUnmatchedLeft := n.joinType == joinTypeLeftOuter || n.joinType == joinTypeFullOuter ||
+		n.joinType == joinTypeAnti
 	wantUnmatchedRight := n.joinType == joinTypeRightOuter || n.joinType == joinTypeFullOuter
 
 	if len(n.buckets.Buckets()) == 0 {
@@ -616,6 +662,24 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 		//    |  44  |  51  |
 		//    | NULL |  52  |
 		//    | NULL |  52  |
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			// A left row that contains NULL does not match any right row.
+			matched := false
+			if !containsNull {
+				if matched, err = n.hasMatch(encoding, lrow); err != nil {
+					return false, err
+				}
+			}
+			scratch = encoding[:0]
+			if matched != (n.joinType == joinTypeSemi) {
+				continue
+			}
+			if _, err := n.buffer.AddRow(params.ctx, lrow); err != nil {
+				return false, err
+			}
+			return n.buffer.Next(), nil
+		}
+
 		if containsNull {
 			if !wantUnmatchedLeft {
 				scratch = encoding[:0]
@@ -710,6 +774,25 @@ func (n *joinNode) Next(params runParams) (res bool, err error) {
 	return n.buffer.Next(), nil
 }
 
+// hasMatch returns true if the given left row matches at least one right
+// row, given the encoding of its equality columns.
+func (n *joinNode) hasMatch(encoding []byte, lrow tree.Datums) (bool, error) {
+	b, ok := n.buckets.Fetch(encoding)
+	if !ok {
+		return false, nil
+	}
+	for _, rrow := range b.Rows() {
+		passesOnCond, err := n.pred.eval(&n.planner.evalCtx, n.output, lrow, rrow)
+		if err != nil {
+			return false, err
+		}
+		if passesOnCond {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Values implements the planNode interface.
 func (n *joinNode) Values() tree.Datums {
 	return n.buffer.Values()
@@ -717,13 +800,19 @@ func (n *joinNode) Values() tree.Datums {
 
 // Close implements the planNode interface.
 func (n *joinNode) Close(ctx context.Context) {
+	n.closeBuffers(ctx)
+	n.right.plan.Close(ctx)
+	n.left.plan.Close(ctx)
+}
+
+// closeBuffers releases the resources of the joinNode itself, without closing
+// its inputs. It is used directly when a joinNode is replaced by another one
+// over the same inputs.
+func (n *joinNode) closeBuffers(ctx context.Context) {
 	n.buffer.Close(ctx)
 	n.buffer = nil
 	n.buckets.Close(ctx)
 	n.bucketsMemAcc.Wtxn(n.planner.session).Close(ctx)
-
-	n.right.plan.Close(ctx)
-	n.left.plan.Close(ctx)
 }
 
 func (n *joinNode) joinOrdering() physicalProps {
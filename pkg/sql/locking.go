@@ -0,0 +1,106 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+)
+
+// checkLockingClause verifies that the given locking clause can be applied
+// to the given SELECT clause.
+func checkLockingClause(s *tree.SelectClause, locking tree.LockingClause) error {
+	if locking.WaitPolicy == tree.LockWaitSkip {
+		return pgerror.Unimplemented("skip-locked", "SKIP LOCKED is not supported")
+	}
+	if s.From != nil && s.From.AsOf.Expr != nil {
+		return lockingNotAllowedError(locking, "AS OF SYSTEM TIME")
+	}
+	if s.Distinct {
+		return lockingNotAllowedError(locking, "DISTINCT clause")
+	}
+	if len(s.GroupBy) > 0 {
+		return lockingNotAllowedError(locking, "GROUP BY clause")
+	}
+	if s.Having != nil {
+		return lockingNotAllowedError(locking, "HAVING clause")
+	}
+	return nil
+}
+
+func lockingNotAllowedError(locking tree.LockingClause, what string) error {
+	return pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+		"%s is not allowed with %s", locking.Strength, what)
+}
+
+// applyLocking configures the table scans that produce the rows of the given
+// plan to lock the rows they read, as requested by a SELECT ... FOR UPDATE
+// clause. Scans in sub-queries are not affected.
+//
+// All locking strengths acquire exclusive locks. Rows that are read by a scan
+// but filtered out before being returned may be locked as well.
+func applyLocking(plan planNode, locking tree.LockingClause) error {
+	switch n := plan.(type) {
+	case *scanNode:
+		n.lockStrength = locking.Strength
+		n.lockWaitPolicy = locking.WaitPolicy
+	case *renderNode:
+		return applyLocking(n.source.plan, locking)
+	case *filterNode:
+		return applyLocking(n.source.plan, locking)
+	case *sortNode:
+		return applyLocking(n.plan, locking)
+	case *limitNode:
+		return applyLocking(n.plan, locking)
+	case *ordinalityNode:
+		return applyLocking(n.source, locking)
+	case *joinNode:
+		if err := applyLocking(n.left.plan, locking); err != nil {
+			return err
+		}
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			// The right rows are not part of the results.
+			return nil
+		}
+		return applyLocking(n.right.plan, locking)
+	case *groupNode:
+		return lockingNotAllowedError(locking, "aggregate functions")
+	case *windowNode:
+		return lockingNotAllowedError(locking, "window functions")
+	case *projectSetNode:
+		return lockingNotAllowedError(locking, "set-returning functions in the target list")
+	case *applyJoinNode:
+		return lockingNotAllowedError(locking, "LATERAL data sources")
+	case *distinctNode:
+		return lockingNotAllowedError(locking, "DISTINCT clause")
+	case *unionNode:
+		return lockingNotAllowedError(locking, "UNION/INTERSECT/EXCEPT")
+	}
+	return nil
+}
+
+// convertLockingError converts the error returned when a NOWAIT locking scan
+// runs into a row locked by another transaction into the corresponding
+// pgerror.
+func convertLockingError(err error, desc string) error {
+	if _, ok := errors.Cause(err).(*roachpb.WriteIntentError); ok {
+		return pgerror.NewErrorf(pgerror.CodeLockNotAvailableError,
+			"could not obtain lock on row in relation %q", desc)
+	}
+	return err
+}
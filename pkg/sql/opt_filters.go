@@ -253,6 +253,16 @@ func (p *planner) propagateFilters(
 			return plan, extraFilter, err
 		}
 
+	case *projectSetNode:
+		if n.source.plan, err = p.triggerFilterPropagation(ctx, n.source.plan); err != nil {
+			return plan, extraFilter, err
+		}
+
+	case *applyJoinNode:
+		if n.left.plan, err = p.triggerFilterPropagation(ctx, n.left.plan); err != nil {
+			return plan, extraFilter, err
+		}
+
 	case *createTableNode:
 		if n.n.As() {
 			if n.sourcePlan, err = p.triggerFilterPropagation(ctx, n.sourcePlan); err != nil {
@@ -299,6 +309,11 @@ func (p *planner) propagateFilters(
 			}
 		}
 
+	case *recursiveCTENode:
+		if n.initial, err = p.triggerFilterPropagation(ctx, n.initial); err != nil {
+			return plan, extraFilter, err
+		}
+
 	case *splitNode:
 		if n.rows, err = p.triggerFilterPropagation(ctx, n.rows); err != nil {
 			return plan, extraFilter, err
@@ -316,11 +331,13 @@ func (p *planner) propagateFilters(
 	case *scrubNode:
 	case *controlJobNode:
 	case *copyNode:
+	case *cteScanNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
 	case *createUserNode:
 	case *createViewNode:
 	case *createSequenceNode:
+	case *createStatsNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
 	case *dropTableNode:
@@ -684,7 +701,8 @@ func (p *planner) addJoinFilter(
 ) (planNode, tree.TypedExpr, error) {
 
 	// There are four steps to the transformation below:
-	//  1. For inner joins, incorporate the extra filter into the ON condition.
+	//  1. For inner and semi joins, incorporate the extra filter into the ON
+	//     condition.
 	//  2. Extract any join equality constraints from the ON condition.
 	//  3. "Expand" the remaining ON condition with new constraints inferred based
 	//     on the equality columns (see expandOnCond).
@@ -694,8 +712,9 @@ func (p *planner) addJoinFilter(
 
 	onAndExprs := splitAndExpr(&p.evalCtx, n.pred.onCond, nil)
 
-	// Step 1: for inner joins, incorporate the filter into the ON condition.
-	if n.joinType == joinTypeInner {
+	// Step 1: for inner and semi joins, incorporate the filter into the ON
+	// condition.
+	if n.joinType == joinTypeInner || n.joinType == joinTypeSemi {
 		onAndExprs = splitAndExpr(&p.evalCtx, extraFilter, onAndExprs)
 		extraFilter = nil
 	}
@@ -719,7 +738,7 @@ func (p *planner) addJoinFilter(
 	// Step 4: propagate the filter and ON conditions as allowed by the join type.
 	var propagateLeft, propagateRight, filterRemainder tree.TypedExpr
 	switch n.joinType {
-	case joinTypeInner:
+	case joinTypeInner, joinTypeSemi:
 		// We transform:
 		//   SELECT * FROM
 		//          l JOIN r ON (onLeft AND onRight AND onCombined)
@@ -752,6 +771,23 @@ func (p *planner) addJoinFilter(
 		// onCond = onLeft AND onCombined.
 		propagateRight, onCond = splitJoinFilterRight(n, numLeft, onCond)
 
+	case joinTypeAnti:
+		// We transform:
+		//   SELECT * FROM
+		//          l ANTI JOIN r ON (onLeft AND onRight AND onCombined)
+		//   WHERE filterLeft
+		// to:
+		//   SELECT * FROM
+		//          (SELECT * FROM l WHERE filterLeft)
+		//          ANTI JOIN
+		//          (SELECT * from r WHERE onRight)
+		//          ON (onLeft AND onCombined)
+		//
+		// The filter can only refer to the left columns, which are the only
+		// results of the join.
+		propagateLeft, filterRemainder = splitJoinFilterLeft(n, numLeft, extraFilter)
+		propagateRight, onCond = splitJoinFilterRight(n, numLeft, onCond)
+
 	case joinTypeRightOuter:
 		// We transform:
 		//   SELECT * FROM
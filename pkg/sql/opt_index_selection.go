@@ -17,6 +17,7 @@ package sql
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"sort"
 
 	"github.com/pkg/errors"
@@ -26,12 +27,19 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
 
 const nonCoveringIndexPenalty = 10
 
+// invertedIndexPenalty accounts for a path through a document being less
+// selective than the values of a forward index, and for the rows matched by
+// several paths being scanned more than once.
+const invertedIndexPenalty = 2
+
 // analyzeOrderingFn is the interface through which the index selection code
 // discovers how useful is the ordering provided by a certain index. The higher
 // layer (select) desires a certain ordering on a number of columns; it calls
@@ -126,6 +134,11 @@ func (p *planner) selectIndex(
 	}
 
 	if s.filter != nil {
+		// Let the indexes on expressions match the filter.
+		if err := p.replaceIndexExprs(s); err != nil {
+			return nil, err
+		}
+
 		// Analyze the filter expression, simplifying it and splitting it up into
 		// possibly overlapping ranges.
 		exprs, equivalent := decomposeExpr(&p.evalCtx, s.filter)
@@ -163,8 +176,33 @@ func (p *planner) selectIndex(
 		// number of disjunctive expressions we should limit how many indexes we
 		// use.
 
+		// The statistics of the table, if any, are used to estimate the number
+		// of rows scanned with each index.
+		ts := p.getTableStats(ctx, s.desc)
 		for _, c := range candidates {
-			c.analyzeExprs(&s.p.evalCtx, exprs)
+			if c.index.Type == sqlbase.IndexDescriptor_INVERTED {
+				if err := c.analyzeInvertedExprs(&s.p.evalCtx, s.filter); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			c.analyzeExprs(&s.p.evalCtx, s, ts, exprs)
+		}
+	}
+
+	// Eliminate the inverted indexes that can't be used to find the rows
+	// satisfying the filter, as they have no meaningful order.
+	for i := 0; i < len(candidates); {
+		if candidates[i].index.Type == sqlbase.IndexDescriptor_INVERTED &&
+			len(candidates[i].invertedSpans) == 0 {
+			if s.specifiedIndex != nil {
+				return nil, fmt.Errorf("index \"%s\" is inverted and can't be used for this query",
+					s.specifiedIndex.Name)
+			}
+			candidates[i] = candidates[len(candidates)-1]
+			candidates = candidates[:len(candidates)-1]
+		} else {
+			i++
 		}
 	}
 
@@ -214,11 +252,17 @@ func (p *planner) selectIndex(
 	s.index = c.index
 	s.specifiedIndex = nil
 	s.isSecondaryIndex = (c.index != &s.desc.PrimaryIndex)
-	var err error
-	s.spans, err = makeSpans(&s.p.evalCtx, c.constraints, c.desc, c.index)
-	if err != nil {
-		return nil, errors.Wrapf(err, "constraints = %v, table ID = %d, index ID = %d",
-			c.constraints, s.desc.ID, s.index.ID)
+	if c.index.Type == sqlbase.IndexDescriptor_INVERTED {
+		// The index has no constraints; the filter is applied in full to the
+		// rows of the table.
+		s.spans = c.invertedSpans
+	} else {
+		var err error
+		s.spans, err = makeSpans(&s.p.evalCtx, c.constraints, c.desc, c.index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "constraints = %v, table ID = %d, index ID = %d",
+				c.constraints, s.desc.ID, s.index.ID)
+		}
 	}
 	if len(s.spans) == 0 {
 		// There are no spans to scan.
@@ -346,6 +390,9 @@ type indexInfo struct {
 	covering    bool // Does the index cover the required IndexedVars?
 	reverse     bool
 	exactPrefix int
+	// invertedSpans are the spans to scan in an inverted index, which is only
+	// usable if they're set.
+	invertedSpans roachpb.Spans
 }
 
 func (v *indexInfo) init(s *scanNode) {
@@ -364,16 +411,83 @@ func (v *indexInfo) init(s *scanNode) {
 			// indexes.
 			v.cost *= nonCoveringIndexPenalty
 		}
+		if v.index.Type == sqlbase.IndexDescriptor_INVERTED {
+			v.cost *= invertedIndexPenalty
+		}
+	}
+}
+
+// analyzeInvertedExprs sets the spans to scan in an inverted index to find
+// the rows satisfying the filter, if one of its conjuncts is a containment
+// (@>) or key existence (?) predicate on the indexed column with a constant
+// operand. The index is as restrictive as a forward index whose columns are
+// all constrained, so the cost is left unchanged.
+func (v *indexInfo) analyzeInvertedExprs(evalCtx *tree.EvalContext, filter tree.TypedExpr) error {
+	prefix := sqlbase.MakeIndexKeyPrefix(v.desc, v.index.ID)
+	for _, e := range splitAndExpr(evalCtx, filter, nil) {
+		c, ok := e.(*tree.ComparisonExpr)
+		if !ok {
+			continue
+		}
+		left, right := c.Left, c.Right
+		op := c.Operator
+		if op == tree.ContainedBy {
+			left, right, op = right, left, tree.Contains
+		}
+		if ok, colIdx := getColVarIdx(left); !ok || v.desc.Columns[colIdx].ID != v.index.ColumnIDs[0] {
+			continue
+		}
+
+		var keys [][]byte
+		switch op {
+		case tree.Contains:
+			d, ok := right.(*tree.DJSON)
+			if !ok {
+				continue
+			}
+			var err error
+			if keys, err = json.EncodeContainingInvertedIndexKeys(prefix, d.JSON); err != nil {
+				return err
+			}
+		case tree.Existence:
+			d, ok := right.(*tree.DString)
+			if !ok {
+				continue
+			}
+			keys = json.EncodeExistsInvertedIndexPrefixes(prefix, string(*d))
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		spans := make(roachpb.Spans, len(keys))
+		for i, key := range keys {
+			spans[i] = roachpb.Span{Key: key, EndKey: roachpb.Key(key).PrefixEnd()}
+		}
+		sort.Sort(spans)
+		v.invertedSpans = spans
+		return nil
 	}
+	return nil
 }
 
 // analyzeExprs examines the range map to determine the cost of using the
-// index.
-func (v *indexInfo) analyzeExprs(evalCtx *tree.EvalContext, exprs []tree.TypedExprs) {
+// index. If the statistics of the table are available (ts is not nil), the
+// cost is scaled by the estimated number of rows in the constrained part of
+// the index; otherwise it is scaled by the fraction of the index columns that
+// are not constrained.
+func (v *indexInfo) analyzeExprs(
+	evalCtx *tree.EvalContext, s *scanNode, ts *tableStats, exprs []tree.TypedExprs,
+) {
 	if err := v.makeOrConstraints(evalCtx, exprs); err != nil {
 		panic(err)
 	}
 
+	if ts != nil && len(exprs) == 1 {
+		v.cost *= math.Max(1, ts.rowCount*v.constrainedSelectivity(evalCtx, s, ts, exprs[0]))
+		return
+	}
+
 	// Count the number of elements used to limit the start and end keys. We then
 	// boost the cost by what fraction of the index keys are being used. The
 	// higher the fraction, the lower the cost.
@@ -404,6 +518,40 @@ func (v *indexInfo) analyzeExprs(evalCtx *tree.EvalContext, exprs []tree.TypedEx
 	}
 }
 
+// constrainedSelectivity estimates the fraction of the rows of the table in
+// the spans of the index: the selectivity of the conjuncts that only refer to
+// the constrained prefix of the index columns.
+func (v *indexInfo) constrainedSelectivity(
+	evalCtx *tree.EvalContext, s *scanNode, ts *tableStats, conjuncts tree.TypedExprs,
+) float64 {
+	if len(v.constraints) == 0 {
+		return 1
+	}
+	numCols := 0
+	for _, c := range v.constraints[0] {
+		numCols += c.numColumns()
+	}
+	if numCols > len(v.index.ColumnIDs) {
+		numCols = len(v.index.ColumnIDs)
+	}
+	var prefix util.FastIntSet
+	for _, id := range v.index.ColumnIDs[:numCols] {
+		prefix.Add(int(id))
+	}
+
+	sel := 1.0
+	for _, e := range conjuncts {
+		constrained := exprCheckVars(e, func(expr tree.VariableExpr) (bool, tree.Expr) {
+			iv, ok := expr.(*tree.IndexedVar)
+			return ok && iv.Idx < len(s.cols) && prefix.Contains(int(s.cols[iv.Idx].ID)), expr
+		})
+		if constrained {
+			sel *= ts.exprSelectivity(evalCtx, s, e)
+		}
+	}
+	return sel
+}
+
 // analyzeOrdering analyzes the ordering provided by the index and determines
 // if it matches the ordering requested by the query. Non-matching orderings
 // increase the cost of using the index.
@@ -797,6 +945,11 @@ func (v *indexInfo) isCoveringIndex(scan *scanNode) bool {
 		// The primary key index always covers all of the columns.
 		return true
 	}
+	if v.index.Type == sqlbase.IndexDescriptor_INVERTED {
+		// The values of the indexed column can't be read from an inverted
+		// index.
+		return false
+	}
 
 	for _, colIdx := range scan.valNeededForCol.Ordered() {
 		// This is possible during a schema change when we have
@@ -179,7 +179,7 @@ func makeConstraints(
 		index:    index,
 		covering: true,
 	}
-	c.analyzeExprs(evalCtx, exprs)
+	c.analyzeExprs(evalCtx, nil /* s */, nil /* ts */, exprs)
 	if equiv && len(exprs) == 1 {
 		expr = joinAndExprs(exprs[0])
 	}
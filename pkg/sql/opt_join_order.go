@@ -0,0 +1,662 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+var joinReorderEnabled = settings.RegisterBoolSetting(
+	"sql.optimizer.join_reorder.enabled",
+	"if set, the order of inner joins is chosen based on table statistics",
+	true,
+)
+
+// joinReorderDPLimit is the maximum number of relations for which the best
+// join order is searched exhaustively, using dynamic programming over the
+// subsets of the relations. The order of larger joins is built greedily.
+const joinReorderDPLimit = 8
+
+// joinReorderMaxRelations is the maximum number of relations in a tree of
+// joins that can be reordered.
+const joinReorderMaxRelations = 64
+
+// joinReorderMinImprovement is the minimum relative improvement of the
+// estimated cost for which the order of the joins written in the query is
+// changed. The estimates are rough, so we don't second-guess the query for
+// small differences.
+const joinReorderMinImprovement = 0.1
+
+// hashJoinBuildFactor is the cost of adding a row to the hash table of a hash
+// join, relative to the cost of looking up a row in it. The right input of a
+// joinNode is the one that is added to the hash table.
+const hashJoinBuildFactor = 2
+
+// joinGraph describes a tree of inner joins for the purpose of choosing the
+// order in which the joins are performed. The vertices are the relations
+// joined (the leaves of the tree) and the edges are the predicates that refer
+// to more than one relation. Sets of relations are represented as bitmaps.
+type joinGraph struct {
+	// card contains the estimated number of rows of each relation.
+	card []float64
+	// edges contains the predicates that refer to more than one relation.
+	edges []joinEdge
+	// mergeable contains the ordered pairs of relations that can be joined
+	// with a merge join, because both relations can be read in the order of
+	// the equality columns between them.
+	mergeable map[[2]int]bool
+}
+
+// joinEdge is a predicate that refers to more than one relation.
+type joinEdge struct {
+	rels uint64
+	// selectivity is the estimated fraction of the rows of the cross product
+	// of the relations that satisfy the predicate.
+	selectivity float64
+}
+
+// joinTree is a join order: a binary tree whose leaves are the relations.
+type joinTree struct {
+	// rel is the index of the relation for leaves, and -1 for joins.
+	rel         int
+	left, right *joinTree
+	rels        uint64
+	// card is the estimated number of rows produced by the tree.
+	card float64
+	// cost is the estimated cost of the joins in the tree.
+	cost float64
+	// merge is set if the join is performed as a merge join.
+	merge bool
+	// node is the joinNode the tree corresponds to in the original plan, if
+	// any.
+	node *joinNode
+}
+
+// cardinality estimates the number of rows of the join of a set of
+// relations. It only depends on the relations, not on the order of the joins.
+func (g *joinGraph) cardinality(rels uint64) float64 {
+	// The estimates are multiplied in log space to avoid overflows.
+	logCard := 0.0
+	for i, c := range g.card {
+		if rels&(1<<uint(i)) != 0 {
+			logCard += math.Log(math.Max(1, c))
+		}
+	}
+	for _, e := range g.edges {
+		if e.rels&^rels == 0 {
+			logCard += math.Log(e.selectivity)
+		}
+	}
+	return math.Max(1, math.Exp(math.Min(logCard, 700)))
+}
+
+// connected returns true if there is a predicate between the two sets of
+// relations.
+func (g *joinGraph) connected(left, right uint64) bool {
+	for _, e := range g.edges {
+		if e.rels&^(left|right) == 0 && e.rels&left != 0 && e.rels&right != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *joinGraph) leaf(rel int) *joinTree {
+	return &joinTree{rel: rel, rels: 1 << uint(rel), card: math.Max(1, g.card[rel])}
+}
+
+// join returns the tree that joins left and right, choosing the cheapest
+// join algorithm.
+//
+// The cost of a tree is the sum of the number of rows produced by all its
+// joins, plus the cost of each join algorithm: a hash join looks up every
+// left row in a hash table that contains the right rows; a merge join reads
+// both inputs once, in order.
+//
+// Lookup joins, which would read only the rows of an index of the right
+// relation that match the equality columns of each left row, are not
+// considered: joinNode can only execute hash and merge joins, so costing
+// them would favor orders that are not executed as estimated.
+func (g *joinGraph) join(left, right *joinTree) *joinTree {
+	t := &joinTree{rel: -1, left: left, right: right, rels: left.rels | right.rels}
+	t.card = g.cardinality(t.rels)
+	joinCost := left.card + hashJoinBuildFactor*right.card
+	if left.rel >= 0 && right.rel >= 0 && g.mergeable[[2]int{left.rel, right.rel}] {
+		if mergeCost := left.card + right.card; mergeCost < joinCost {
+			joinCost = mergeCost
+			t.merge = true
+		}
+	}
+	t.cost = left.cost + right.cost + joinCost + t.card
+	return t
+}
+
+// estimate fills in the estimates of a tree that has the given shape.
+func (g *joinGraph) estimate(shape *joinTree) *joinTree {
+	if shape.rel >= 0 {
+		return g.leaf(shape.rel)
+	}
+	t := g.join(g.estimate(shape.left), g.estimate(shape.right))
+	t.node = shape.node
+	return t
+}
+
+// bestOrder returns the join order with the lowest estimated cost.
+func (g *joinGraph) bestOrder() *joinTree {
+	if len(g.card) <= joinReorderDPLimit {
+		return g.dpOrder()
+	}
+	return g.greedyOrder()
+}
+
+// dpOrder finds the best join order by computing the best order for each
+// subset of the relations, from the best orders of its subsets. Cross
+// products are only considered for sets of relations that are not connected
+// by any predicate.
+func (g *joinGraph) dpOrder() *joinTree {
+	n := uint(len(g.card))
+	all := uint64(1)<<n - 1
+	best := make([]*joinTree, all+1)
+	for i := range g.card {
+		best[1<<uint(i)] = g.leaf(i)
+	}
+	// The subsets of a set are smaller than the set, so they are visited
+	// before it.
+	for s := uint64(1); s <= all; s++ {
+		if best[s] != nil {
+			continue
+		}
+		var bestConnected, bestCross *joinTree
+		for l := (s - 1) & s; l != 0; l = (l - 1) & s {
+			r := s &^ l
+			t := g.join(best[l], best[r])
+			if g.connected(l, r) {
+				if bestConnected == nil || t.cost < bestConnected.cost {
+					bestConnected = t
+				}
+			} else if bestCross == nil || t.cost < bestCross.cost {
+				bestCross = t
+			}
+		}
+		if bestConnected != nil {
+			best[s] = bestConnected
+		} else {
+			best[s] = bestCross
+		}
+	}
+	return best[all]
+}
+
+// greedyOrder builds a join order by repeatedly performing the cheapest join
+// between two of the trees built so far, starting from the relations.
+func (g *joinGraph) greedyOrder() *joinTree {
+	trees := make([]*joinTree, len(g.card))
+	for i := range trees {
+		trees[i] = g.leaf(i)
+	}
+	for len(trees) > 1 {
+		var best *joinTree
+		var bestConnected bool
+		bestLeft, bestRight := -1, -1
+		for i := range trees {
+			for j := range trees {
+				if i == j {
+					continue
+				}
+				connected := g.connected(trees[i].rels, trees[j].rels)
+				if bestConnected && !connected {
+					continue
+				}
+				t := g.join(trees[i], trees[j])
+				if best == nil || (connected && !bestConnected) || t.cost < best.cost {
+					best, bestConnected = t, connected
+					bestLeft, bestRight = i, j
+				}
+			}
+		}
+		trees[bestLeft] = best
+		trees = append(trees[:bestRight], trees[bestRight+1:]...)
+	}
+	return trees[0]
+}
+
+// joinLeaf is a relation joined by a tree of inner joins.
+type joinLeaf struct {
+	source planDataSource
+	// offset is the index of the first column of the relation in the results
+	// of the original tree of joins.
+	offset int
+	// scan and stats are set by makeGraph.
+	scan  *scanNode
+	stats *tableStats
+}
+
+// joinConjunct is a conjunct of the ON condition of one of the joins.
+type joinConjunct struct {
+	expr tree.TypedExpr
+	// offset is the index, in the results of the original tree of joins, of
+	// the first column of the join whose predicate contains the conjunct. The
+	// variables of expr refer to the columns of that join.
+	offset int
+	rels   uint64
+	placed bool
+}
+
+// joinEquality is an equality between two columns of different relations.
+// The columns are indexes in the results of the original tree of joins.
+type joinEquality struct {
+	cols   [2]int
+	rels   uint64
+	placed bool
+}
+
+// joinReorderer reorders a tree of inner joins.
+type joinReorderer struct {
+	p          *planner
+	leaves     []joinLeaf
+	joins      []*joinNode
+	conjuncts  []joinConjunct
+	equalities []joinEquality
+	// colRel contains the relation of each column of the results of the
+	// original tree of joins.
+	colRel []int
+	// mergeOrders contains, for each pair of relations in g.mergeable, the
+	// equalities between them in the order of the indexes that provide the
+	// ordering, along with the directions of the index columns.
+	mergeOrders map[[2]int]sqlbase.ColumnOrdering
+}
+
+// reorderJoins chooses the order of the tree of inner joins rooted at n,
+// based on the estimated number of rows of the relations and of the results
+// of the joins. The estimates are derived from the table statistics, so the
+// order written in the query is kept unless all the relations are scans of
+// tables that have statistics.
+//
+// If the order is changed, the returned plan is a renderNode on top of the
+// new tree of joins, which restores the order of the columns of n.
+func (p *planner) reorderJoins(ctx context.Context, n *joinNode) (planNode, error) {
+	if !joinReorderEnabled.Get(&p.ExecCfg().Settings.SV) {
+		return n, nil
+	}
+
+	r := &joinReorderer{p: p}
+	shape := r.flattenJoin(n, 0)
+	for _, j := range r.joins {
+		j.reorderDone = true
+	}
+	if len(r.leaves) > joinReorderMaxRelations {
+		return n, nil
+	}
+
+	g, ok := r.makeGraph(ctx)
+	if !ok {
+		return n, nil
+	}
+	orig := g.estimate(shape)
+	best := g.bestOrder()
+	if log.V(2) {
+		log.Infof(ctx, "reorderJoins: %d relations, cost %.0f, best cost %.0f",
+			len(r.leaves), orig.cost, best.cost)
+	}
+
+	if best.cost >= orig.cost*(1-joinReorderMinImprovement) {
+		// Keep the original order, but let the joins that are better off as
+		// merge joins get the right orderings from their inputs.
+		r.setMergeJoinHints(orig)
+		return n, nil
+	}
+
+	source, cols, err := r.build(best)
+	if err != nil {
+		return n, err
+	}
+	newPos := make([]int, len(cols))
+	for i, c := range cols {
+		newPos[c] = i
+	}
+	render := &renderNode{
+		planner:    p,
+		source:     source,
+		sourceInfo: multiSourceInfo{source.info},
+	}
+	render.ivarHelper = tree.MakeIndexedVarHelper(render, len(cols))
+	for i, col := range n.columns {
+		expr := render.ivarHelper.IndexedVar(newPos[i])
+		render.addRenderColumn(expr, symbolicExprStr(expr), col)
+	}
+
+	// The relations are now used by the new joins; only the resources of the
+	// original joins themselves are released.
+	for _, j := range r.joins {
+		j.closeBuffers(ctx)
+	}
+	return render, nil
+}
+
+// flattenSource adds the given data source to the relations joined, unless
+// it is itself an inner join, in which case its inputs are added. offset is
+// the index of its first column in the results of the whole tree. It returns
+// the shape of the tree of joins.
+func (r *joinReorderer) flattenSource(ds planDataSource, offset int) *joinTree {
+	if n, ok := ds.plan.(*joinNode); ok && n.joinType == joinTypeInner && !n.reorderDone {
+		return r.flattenJoin(n, offset)
+	}
+	rel := len(r.leaves)
+	r.leaves = append(r.leaves, joinLeaf{source: ds, offset: offset})
+	for range ds.info.sourceColumns {
+		r.colRel = append(r.colRel, rel)
+	}
+	return &joinTree{rel: rel}
+}
+
+// flattenJoin collects the relations, the equalities and the ON conditions
+// of the tree of inner joins rooted at n.
+func (r *joinReorderer) flattenJoin(n *joinNode, offset int) *joinTree {
+	r.joins = append(r.joins, n)
+	numLeft := len(n.left.info.sourceColumns)
+	t := &joinTree{
+		rel:   -1,
+		left:  r.flattenSource(n.left, offset),
+		right: r.flattenSource(n.right, offset+numLeft),
+		node:  n,
+	}
+	for i := range n.pred.leftEqualityIndices {
+		r.equalities = append(r.equalities, joinEquality{cols: [2]int{
+			offset + n.pred.leftEqualityIndices[i],
+			offset + numLeft + n.pred.rightEqualityIndices[i],
+		}})
+	}
+	if !isFilterTrue(n.pred.onCond) {
+		for _, e := range splitAndExpr(&r.p.evalCtx, n.pred.onCond, nil) {
+			r.conjuncts = append(r.conjuncts, joinConjunct{expr: e, offset: offset})
+		}
+	}
+	return t
+}
+
+// makeGraph estimates the number of rows of each relation and the
+// selectivity of each predicate. It returns false if the estimates can't be
+// derived from table statistics.
+func (r *joinReorderer) makeGraph(ctx context.Context) (*joinGraph, bool) {
+	g := &joinGraph{card: make([]float64, len(r.leaves))}
+	for i := range r.leaves {
+		l := &r.leaves[i]
+		s, ok := l.source.plan.(*scanNode)
+		if !ok {
+			return nil, false
+		}
+		l.scan = s
+		l.stats = r.p.getTableStats(ctx, s.desc)
+		if l.stats == nil {
+			return nil, false
+		}
+		g.card[i] = l.stats.rowCount * l.stats.filterSelectivity(&r.p.evalCtx, s, s.filter)
+	}
+
+	// The equalities between each pair of relations form a single edge: its
+	// selectivity is derived from the number of distinct values of the
+	// equality columns on each side, using multi-column statistics when there
+	// are several equality columns.
+	type relPair [2]int
+	pairs := make(map[relPair][]int)
+	var pairOrder []relPair
+	for i := range r.equalities {
+		eq := &r.equalities[i]
+		a, b := r.colRel[eq.cols[0]], r.colRel[eq.cols[1]]
+		eq.rels = 1<<uint(a) | 1<<uint(b)
+		if a > b {
+			a, b = b, a
+			eq.cols[0], eq.cols[1] = eq.cols[1], eq.cols[0]
+		}
+		key := relPair{a, b}
+		if _, ok := pairs[key]; !ok {
+			pairOrder = append(pairOrder, key)
+		}
+		pairs[key] = append(pairs[key], i)
+	}
+	for _, key := range pairOrder {
+		var colsA, colsB util.FastIntSet
+		for _, i := range pairs[key] {
+			colsA.Add(int(r.columnID(r.equalities[i].cols[0])))
+			colsB.Add(int(r.columnID(r.equalities[i].cols[1])))
+		}
+		distinctA := math.Min(r.leaves[key[0]].stats.distinctCount(colsA), math.Max(1, g.card[key[0]]))
+		distinctB := math.Min(r.leaves[key[1]].stats.distinctCount(colsB), math.Max(1, g.card[key[1]]))
+		g.edges = append(g.edges, joinEdge{
+			rels:        1<<uint(key[0]) | 1<<uint(key[1]),
+			selectivity: 1 / math.Max(distinctA, distinctB),
+		})
+		r.addMergeable(g, key[0], key[1], pairs[key])
+	}
+
+	for i := range r.conjuncts {
+		c := &r.conjuncts[i]
+		exprCheckVars(c.expr, func(v tree.VariableExpr) (bool, tree.Expr) {
+			if iv, ok := v.(*tree.IndexedVar); ok {
+				c.rels |= 1 << uint(r.colRel[c.offset+iv.Idx])
+			}
+			return true, v
+		})
+		if c.rels&(c.rels-1) != 0 {
+			// The conjunct refers to more than one relation.
+			g.edges = append(g.edges, joinEdge{rels: c.rels, selectivity: defaultSelectivity})
+		}
+	}
+	return g, true
+}
+
+// columnID returns the ID of the table column that corresponds to a column
+// of the results of the original tree of joins.
+func (r *joinReorderer) columnID(col int) sqlbase.ColumnID {
+	l := &r.leaves[r.colRel[col]]
+	return l.scan.cols[col-l.offset].ID
+}
+
+// addMergeable checks whether relations a and b can be joined with a merge
+// join on the given equalities, i.e. whether both scans have an index that
+// provides all the needed columns and starts with the equality columns, in
+// the same order and directions. If so, the pair is added to g.mergeable.
+func (r *joinReorderer) addMergeable(g *joinGraph, a, b int, equalities []int) {
+	if !planMergeJoins.Get(&r.p.ExecCfg().Settings.SV) {
+		return
+	}
+	scanA, scanB := r.leaves[a].scan, r.leaves[b].scan
+	for _, indexA := range scanIndexes(scanA) {
+		if len(indexA.ColumnIDs) < len(equalities) {
+			continue
+		}
+		// Order the equalities according to the columns of indexA.
+		order := make([]int, 0, len(equalities))
+		for _, id := range indexA.ColumnIDs[:len(equalities)] {
+			for _, i := range equalities {
+				if r.columnID(r.equalities[i].cols[0]) == id {
+					order = append(order, i)
+					break
+				}
+			}
+		}
+		if len(order) != len(equalities) {
+			continue
+		}
+	IndexB:
+		for _, indexB := range scanIndexes(scanB) {
+			if len(indexB.ColumnIDs) < len(order) {
+				continue
+			}
+			ordering := make(sqlbase.ColumnOrdering, len(order))
+			for j, i := range order {
+				if indexB.ColumnIDs[j] != r.columnID(r.equalities[i].cols[1]) ||
+					indexB.ColumnDirections[j] != indexA.ColumnDirections[j] {
+					continue IndexB
+				}
+				dir, err := indexA.ColumnDirections[j].ToEncodingDirection()
+				if err != nil {
+					continue IndexB
+				}
+				ordering[j] = sqlbase.ColumnOrderInfo{ColIdx: i, Direction: dir}
+			}
+			if r.mergeOrders == nil {
+				r.mergeOrders = make(map[[2]int]sqlbase.ColumnOrdering)
+				g.mergeable = make(map[[2]int]bool)
+			}
+			r.mergeOrders[[2]int{a, b}] = ordering
+			r.mergeOrders[[2]int{b, a}] = ordering
+			g.mergeable[[2]int{a, b}] = true
+			g.mergeable[[2]int{b, a}] = true
+			return
+		}
+	}
+}
+
+// scanIndexes returns the indexes that can provide all the columns needed
+// from a scan.
+func scanIndexes(s *scanNode) []*sqlbase.IndexDescriptor {
+	indexes := []*sqlbase.IndexDescriptor{&s.desc.PrimaryIndex}
+	for i := range s.desc.Indexes {
+		idx := &s.desc.Indexes[i]
+		info := indexInfo{desc: s.desc, index: idx}
+		if info.isCoveringIndex(s) {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
+
+// mergeJoinHint returns the hint for a merge join between the two relations
+// of the given tree, in the format of joinNode.mergeJoinHint. pos maps the
+// columns of the results of the original tree of joins to the columns of n.
+func (r *joinReorderer) mergeJoinHint(
+	t *joinTree, n *joinNode, pos func(col int) int,
+) sqlbase.ColumnOrdering {
+	ordering := r.mergeOrders[[2]int{t.left.rel, t.right.rel}]
+	numLeft := len(n.left.info.sourceColumns)
+	hint := make(sqlbase.ColumnOrdering, 0, len(ordering))
+	for _, o := range ordering {
+		eq := &r.equalities[o.ColIdx]
+		left, right := pos(eq.cols[0]), pos(eq.cols[1])
+		if left > right {
+			left, right = right, left
+		}
+		for j := range n.pred.leftEqualityIndices {
+			if n.pred.leftEqualityIndices[j] == left && numLeft+n.pred.rightEqualityIndices[j] == right {
+				hint = append(hint, sqlbase.ColumnOrderInfo{ColIdx: j, Direction: o.Direction})
+				break
+			}
+		}
+	}
+	if len(hint) != len(ordering) {
+		return nil
+	}
+	return hint
+}
+
+// setMergeJoinHints sets the merge join hints of the original joins that
+// are estimated to be performed best as merge joins.
+func (r *joinReorderer) setMergeJoinHints(t *joinTree) {
+	if t.rel >= 0 {
+		return
+	}
+	if t.merge {
+		// The original join is between two relations, so the columns of the
+		// join start at the offset of its left relation.
+		offset := r.leaves[t.left.rel].offset
+		t.node.mergeJoinHint = r.mergeJoinHint(t, t.node, func(col int) int { return col - offset })
+	}
+	r.setMergeJoinHints(t.left)
+	r.setMergeJoinHints(t.right)
+}
+
+// build constructs the joins for the given tree. It returns the data source
+// for the tree, along with the index in the results of the original tree of
+// joins of each of its columns.
+func (r *joinReorderer) build(t *joinTree) (planDataSource, []int, error) {
+	if t.rel >= 0 {
+		l := &r.leaves[t.rel]
+		cols := make([]int, len(l.source.info.sourceColumns))
+		for i := range cols {
+			cols[i] = l.offset + i
+		}
+		return l.source, cols, nil
+	}
+
+	left, leftCols, err := r.build(t.left)
+	if err != nil {
+		return planDataSource{}, nil, err
+	}
+	right, rightCols, err := r.build(t.right)
+	if err != nil {
+		return planDataSource{}, nil, err
+	}
+	cols := make([]int, 0, len(leftCols)+len(rightCols))
+	cols = append(cols, leftCols...)
+	cols = append(cols, rightCols...)
+	pos := make(map[int]int, len(cols))
+	for i, c := range cols {
+		pos[c] = i
+	}
+
+	pred, info, err := makeCrossPredicate(joinTypeInner, left.info, right.info)
+	if err != nil {
+		return planDataSource{}, nil, err
+	}
+
+	// The predicates are placed on the lowest join that has all the columns
+	// they refer to; the children were built first and took theirs.
+	for i := range r.equalities {
+		eq := &r.equalities[i]
+		if eq.placed || eq.rels&^t.rels != 0 {
+			continue
+		}
+		eq.placed = true
+		e := tree.NewTypedComparisonExpr(
+			tree.EQ,
+			pred.iVarHelper.IndexedVar(pos[eq.cols[0]]),
+			pred.iVarHelper.IndexedVar(pos[eq.cols[1]]),
+		)
+		if !pred.tryAddEqualityFilter(e, left.info, right.info) {
+			pred.onCond = mergeConj(pred.onCond, e)
+		}
+	}
+	for i := range r.conjuncts {
+		c := &r.conjuncts[i]
+		if c.placed || c.rels&^t.rels != 0 {
+			continue
+		}
+		c.placed = true
+		e := exprConvertVars(c.expr, func(v tree.VariableExpr) (bool, tree.Expr) {
+			iv, ok := v.(*tree.IndexedVar)
+			if !ok {
+				return false, v
+			}
+			return true, pred.iVarHelper.IndexedVar(pos[c.offset+iv.Idx])
+		})
+		pred.onCond = mergeConj(pred.onCond, e)
+	}
+
+	n := r.p.newJoinNode(joinTypeInner, left, right, pred, info)
+	n.reorderDone = true
+	if t.merge {
+		n.mergeJoinHint = r.mergeJoinHint(t, n, func(col int) int { return pos[col] })
+	}
+	return planDataSource{info: info, plan: n}, cols, nil
+}
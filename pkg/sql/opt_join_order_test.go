@@ -0,0 +1,128 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func formatJoinTree(t *joinTree) string {
+	if t.rel >= 0 {
+		return fmt.Sprint(t.rel)
+	}
+	return fmt.Sprintf("(%s %s)", formatJoinTree(t.left), formatJoinTree(t.right))
+}
+
+// hasCrossProduct returns true if the tree contains a join between two sets
+// of relations that are not connected by any predicate.
+func hasCrossProduct(g *joinGraph, t *joinTree) bool {
+	if t.rel >= 0 {
+		return false
+	}
+	return !g.connected(t.left.rels, t.right.rels) ||
+		hasCrossProduct(g, t.left) || hasCrossProduct(g, t.right)
+}
+
+func TestJoinOrder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testData := []struct {
+		card      []float64
+		edges     []joinEdge
+		mergeable [][2]int
+		expected  string
+	}{
+		// The small relation is joined with the medium one first; the large
+		// relation is probed with the (small) result.
+		{
+			card:     []float64{1000, 100, 10},
+			edges:    []joinEdge{{rels: 0x3, selectivity: 0.01}, {rels: 0x6, selectivity: 0.01}},
+			expected: "(0 (1 2))",
+		},
+		// The cross product between 0 and 2 is avoided, even though they are
+		// the smallest relations.
+		{
+			card:     []float64{10, 1000, 20},
+			edges:    []joinEdge{{rels: 0x3, selectivity: 0.001}, {rels: 0x6, selectivity: 0.001}},
+			expected: "(2 (1 0))",
+		},
+		// Without predicates, the cross products keep the largest relation on
+		// the left.
+		{
+			card:     []float64{10, 1000},
+			expected: "(1 0)",
+		},
+		// Merge joins make it cheaper to keep the larger relation on the right.
+		{
+			card:      []float64{1000, 900},
+			edges:     []joinEdge{{rels: 0x3, selectivity: 0.001}},
+			mergeable: [][2]int{{1, 0}},
+			expected:  "(1 0)",
+		},
+	}
+
+	for i, d := range testData {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			g := &joinGraph{card: d.card, edges: d.edges, mergeable: make(map[[2]int]bool)}
+			for _, m := range d.mergeable {
+				g.mergeable[m] = true
+			}
+			best := g.bestOrder()
+			if s := formatJoinTree(best); s != d.expected {
+				t.Errorf("expected %s, got %s", d.expected, s)
+			}
+			if len(d.mergeable) > 0 && !best.merge {
+				t.Errorf("expected a merge join")
+			}
+		})
+	}
+}
+
+func TestJoinOrderGreedy(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// A star: relation 0 is connected to all the others, which are not
+	// connected to each other.
+	const n = joinReorderDPLimit + 4
+	g := &joinGraph{card: make([]float64, n)}
+	g.card[0] = 1e6
+	for i := 1; i < n; i++ {
+		g.card[i] = float64(10 * i)
+		g.edges = append(g.edges, joinEdge{rels: 1 | 1<<uint(i), selectivity: 0.1 / float64(i)})
+	}
+
+	// A poor order: the cross product of the small relations is joined with
+	// the large one.
+	written := g.leaf(1)
+	for i := 2; i < n; i++ {
+		written = g.join(written, g.leaf(i))
+	}
+	written = g.join(written, g.leaf(0))
+
+	best := g.bestOrder()
+	if best.rels != 1<<n-1 {
+		t.Fatalf("expected all the relations to be joined, got %b", best.rels)
+	}
+	if hasCrossProduct(g, best) {
+		t.Errorf("unexpected cross product in %s", formatJoinTree(best))
+	}
+	if best.cost >= written.cost {
+		t.Errorf("expected cost lower than %f, got %f for %s",
+			written.cost, best.cost, formatJoinTree(best))
+	}
+}
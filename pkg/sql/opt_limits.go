@@ -125,10 +125,17 @@ func applyLimit(plan planNode, numRows int64, soft bool) {
 	case *windowNode:
 		setUnlimited(n.plan)
 
+	case *projectSetNode:
+		// The number of rows produced for each source row is unknown.
+		setUnlimited(n.source.plan)
+
 	case *joinNode:
 		setUnlimited(n.left.plan)
 		setUnlimited(n.right.plan)
 
+	case *applyJoinNode:
+		setUnlimited(n.left.plan)
+
 	case *ordinalityNode:
 		applyLimit(n.source, numRows, soft)
 
@@ -156,6 +163,15 @@ func applyLimit(plan planNode, numRows int64, soft bool) {
 			setUnlimited(n.plan)
 		}
 
+	case *cteScanNode:
+		// The CTE is computed in full, regardless of how many rows are
+		// needed by this particular reference.
+		if n.src != nil && n.src.plan != nil {
+			setUnlimited(n.src.plan)
+		}
+	case *recursiveCTENode:
+		setUnlimited(n.initial)
+
 	case *splitNode:
 		setUnlimited(n.rows)
 
@@ -175,6 +191,7 @@ func applyLimit(plan planNode, numRows int64, soft bool) {
 	case *createUserNode:
 	case *createViewNode:
 	case *createSequenceNode:
+	case *createStatsNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
 	case *dropTableNode:
@@ -66,11 +66,28 @@ func setNeededColumns(plan planNode, needed []bool) {
 		setNeededColumns(n.right, needed)
 
 	case *joinNode:
+		outputNeeded := needed
+		if n.joinType == joinTypeSemi || n.joinType == joinTypeAnti {
+			// The right columns are not part of the results; they are only
+			// needed if the join predicate uses them.
+			needed = make([]bool, n.pred.numLeftCols+n.pred.numRightCols)
+			copy(needed, outputNeeded)
+		}
 		// Note: getNeededColumns takes into account both the columns
 		// tested for equality and the join predicate expression.
 		leftNeeded, rightNeeded := n.pred.getNeededColumns(needed)
 		setNeededColumns(n.left.plan, leftNeeded)
 		setNeededColumns(n.right.plan, rightNeeded)
+		markOmitted(n.columns, outputNeeded)
+
+	case *applyJoinNode:
+		// The right side is planned with all its columns for every row of
+		// the left side; the left columns it refers to are needed.
+		leftNeeded, _ := n.pred.getNeededColumns(needed)
+		for _, colIdx := range n.scope.cols {
+			leftNeeded[colIdx] = true
+		}
+		setNeededColumns(n.left.plan, leftNeeded)
 		markOmitted(n.columns, needed)
 
 	case *ordinalityNode:
@@ -113,6 +130,18 @@ func setNeededColumns(plan planNode, needed []bool) {
 		}
 		setNeededColumns(n.source.plan, sourceNeeded)
 
+	case *projectSetNode:
+		// The SRFs are always evaluated, since they determine the number of
+		// result rows; the source columns they use are needed too.
+		numSourceCols := len(n.source.info.sourceColumns)
+		sourceNeeded := make([]bool, numSourceCols)
+		copy(sourceNeeded, needed)
+		for i := range sourceNeeded {
+			sourceNeeded[i] = sourceNeeded[i] || n.ivarHelper.IndexedVarUsed(i)
+		}
+		setNeededColumns(n.source.plan, sourceNeeded)
+		markOmitted(n.columns[:numSourceCols], sourceNeeded)
+
 	case *renderNode:
 		// Optimization: remove all the render expressions that are not
 		// needed. While doing so, some indexed vars may disappear
@@ -181,6 +210,10 @@ func setNeededColumns(plan planNode, needed []bool) {
 		// foreign key relations and that are not needed for RETURNING.
 		setNeededColumns(n.run.rows, allColumns(n.run.rows))
 
+	case *recursiveCTENode:
+		// The working table needs all the columns.
+		setNeededColumns(n.initial, allColumns(n.initial))
+
 	case *splitNode:
 		setNeededColumns(n.rows, allColumns(n.rows))
 
@@ -194,11 +227,13 @@ func setNeededColumns(plan planNode, needed []bool) {
 	case *controlJobNode:
 	case *scrubNode:
 	case *copyNode:
+	case *cteScanNode:
 	case *createDatabaseNode:
 	case *createIndexNode:
 	case *createUserNode:
 	case *createViewNode:
 	case *createSequenceNode:
+	case *createStatsNode:
 	case *dropDatabaseNode:
 	case *dropIndexNode:
 	case *dropTableNode:
@@ -0,0 +1,360 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// defaultSelectivity is the estimated fraction of the rows that satisfy a
+// predicate when the statistics can't tell.
+const defaultSelectivity = 1.0 / 3
+
+// defaultDistinctFraction is the estimated ratio between the number of
+// distinct values of a column and the number of rows when there is no
+// statistic on the column.
+const defaultDistinctFraction = 0.1
+
+// tableStats holds the statistics of a table used by the cost model of the
+// planner.
+type tableStats struct {
+	desc *sqlbase.TableDescriptor
+	// rowCount is the number of rows of the table when the most recent
+	// statistic was collected.
+	rowCount float64
+	// colStats contains the most recent statistic on each set of columns.
+	colStats []*stats.TableStatistic
+}
+
+// getTableStats returns the statistics of the given table, or nil if there
+// are none. Statistics only guide the choice between equivalent plans, so
+// errors while reading them are logged and otherwise ignored.
+func (p *planner) getTableStats(ctx context.Context, desc *sqlbase.TableDescriptor) *tableStats {
+	cfg := p.ExecCfg()
+	if cfg == nil || cfg.TableStatsCache == nil || desc.IsVirtualTable() {
+		return nil
+	}
+	all, err := cfg.TableStatsCache.GetTableStats(ctx, desc.ID)
+	if err != nil {
+		log.Warningf(ctx, "unable to read statistics of table %d: %v", desc.ID, err)
+		return nil
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	ts := &tableStats{desc: desc, rowCount: float64(all[0].RowCount)}
+	seen := make(map[string]struct{})
+	for _, s := range all {
+		key := statColumnSet(s).String()
+		if _, ok := seen[key]; ok {
+			// We already have a more recent statistic on these columns.
+			continue
+		}
+		seen[key] = struct{}{}
+		ts.colStats = append(ts.colStats, s)
+	}
+	return ts
+}
+
+func statColumnSet(s *stats.TableStatistic) util.FastIntSet {
+	var cols util.FastIntSet
+	for _, c := range s.ColumnIDs {
+		cols.Add(int(c))
+	}
+	return cols
+}
+
+// findStat returns the most recent statistic on exactly the given set of
+// column IDs, or nil if there is none.
+func (ts *tableStats) findStat(cols util.FastIntSet) *stats.TableStatistic {
+	for _, s := range ts.colStats {
+		if statColumnSet(s).Equals(cols) {
+			return s
+		}
+	}
+	return nil
+}
+
+// distinctCount returns the estimated number of distinct values (or tuples of
+// values) of the given set of column IDs. Multi-column statistics are used
+// when available; otherwise the columns are assumed to be independent.
+func (ts *tableStats) distinctCount(cols util.FastIntSet) float64 {
+	if s := ts.findStat(cols); s != nil {
+		return math.Max(1, float64(s.DistinctCount))
+	}
+	if cols.Len() > 1 {
+		d := 1.0
+		cols.ForEach(func(c int) {
+			d *= ts.distinctCount(util.MakeFastIntSet(c))
+		})
+		return math.Max(1, math.Min(d, ts.rowCount))
+	}
+	return math.Max(1, ts.rowCount*defaultDistinctFraction)
+}
+
+// filterSelectivity estimates the fraction of the rows of the table scanned
+// by s that satisfy the given filter, whose variables refer to the columns of
+// the scanNode. The conjuncts of the filter are assumed to be independent.
+func (ts *tableStats) filterSelectivity(
+	evalCtx *tree.EvalContext, s *scanNode, filter tree.TypedExpr,
+) float64 {
+	if isFilterTrue(filter) {
+		return 1
+	}
+	sel := 1.0
+	for _, e := range splitAndExpr(evalCtx, filter, nil) {
+		sel *= ts.exprSelectivity(evalCtx, s, e)
+	}
+	return sel
+}
+
+// exprSelectivity estimates the fraction of the rows that satisfy a
+// predicate. Comparisons between a column and a constant are estimated using
+// the histogram of the column or its number of distinct values; anything else
+// gets defaultSelectivity.
+func (ts *tableStats) exprSelectivity(
+	evalCtx *tree.EvalContext, s *scanNode, e tree.TypedExpr,
+) float64 {
+	switch t := e.(type) {
+	case *tree.DBool:
+		if *t {
+			return 1
+		}
+		return 0
+
+	case *tree.AndExpr:
+		return ts.exprSelectivity(evalCtx, s, t.TypedLeft()) *
+			ts.exprSelectivity(evalCtx, s, t.TypedRight())
+
+	case *tree.OrExpr:
+		l := ts.exprSelectivity(evalCtx, s, t.TypedLeft())
+		r := ts.exprSelectivity(evalCtx, s, t.TypedRight())
+		return l + r - l*r
+
+	case *tree.NotExpr:
+		return 1 - ts.exprSelectivity(evalCtx, s, t.TypedInnerExpr())
+
+	case *tree.ComparisonExpr:
+		iv, ok := t.Left.(*tree.IndexedVar)
+		if !ok || iv.Idx >= len(s.cols) {
+			break
+		}
+		d, ok := t.Right.(tree.Datum)
+		if !ok {
+			break
+		}
+		if sel, ok := ts.comparisonSelectivity(evalCtx, &s.cols[iv.Idx], t.Operator, d); ok {
+			return sel
+		}
+	}
+	return defaultSelectivity
+}
+
+// comparisonSelectivity estimates the fraction of the rows for which
+// `col <op> d` is true. It returns false if the comparison is not supported.
+func (ts *tableStats) comparisonSelectivity(
+	evalCtx *tree.EvalContext, col *sqlbase.ColumnDescriptor, op tree.ComparisonOperator, d tree.Datum,
+) (float64, bool) {
+	if ts.rowCount == 0 {
+		return 0, true
+	}
+	cols := util.MakeFastIntSet(int(col.ID))
+	var nullFraction float64
+	if s := ts.findStat(cols); s != nil {
+		nullFraction = float64(s.NullCount) / ts.rowCount
+	}
+
+	switch op {
+	case tree.Is, tree.IsNotDistinctFrom:
+		if d != tree.DNull {
+			return 0, false
+		}
+		return nullFraction, true
+
+	case tree.IsNot, tree.IsDistinctFrom:
+		if d != tree.DNull {
+			return 0, false
+		}
+		return 1 - nullFraction, true
+
+	case tree.In, tree.NotIn:
+		tuple, ok := d.(*tree.DTuple)
+		if !ok {
+			return 0, false
+		}
+		sel := 0.0
+		for _, v := range tuple.D {
+			eq, ok := ts.eqSelectivity(evalCtx, col, v)
+			if !ok {
+				return 0, false
+			}
+			sel += eq
+		}
+		sel = math.Min(sel, 1-nullFraction)
+		if op == tree.NotIn {
+			sel = 1 - nullFraction - sel
+		}
+		return sel, true
+	}
+
+	if d == tree.DNull {
+		// Comparisons with NULL are never true.
+		return 0, true
+	}
+
+	switch op {
+	case tree.EQ:
+		return ts.eqSelectivity(evalCtx, col, d)
+
+	case tree.NE:
+		eq, ok := ts.eqSelectivity(evalCtx, col, d)
+		return math.Max(0, 1-nullFraction-eq), ok
+
+	case tree.LT, tree.LE, tree.GT, tree.GE:
+		h, ok := ts.decodeHistogram(col, d)
+		if !ok {
+			return 0, false
+		}
+		total := h.totalRows()
+		var rows float64
+		switch op {
+		case tree.LT:
+			rows = h.rowsLess(evalCtx, d, false /* inclusive */)
+		case tree.LE:
+			rows = h.rowsLess(evalCtx, d, true /* inclusive */)
+		case tree.GT:
+			rows = total - h.rowsLess(evalCtx, d, true /* inclusive */)
+		case tree.GE:
+			rows = total - h.rowsLess(evalCtx, d, false /* inclusive */)
+		}
+		return math.Max(0, rows) / ts.rowCount, true
+	}
+	return 0, false
+}
+
+// eqSelectivity estimates the fraction of the rows for which `col = d`.
+func (ts *tableStats) eqSelectivity(
+	evalCtx *tree.EvalContext, col *sqlbase.ColumnDescriptor, d tree.Datum,
+) (float64, bool) {
+	if d == tree.DNull {
+		return 0, true
+	}
+	if h, ok := ts.decodeHistogram(col, d); ok {
+		return h.rowsEqual(evalCtx, d) / ts.rowCount, true
+	}
+	cols := util.MakeFastIntSet(int(col.ID))
+	nonNull := 1.0
+	if s := ts.findStat(cols); s != nil {
+		nonNull -= float64(s.NullCount) / ts.rowCount
+	}
+	return nonNull / ts.distinctCount(cols), true
+}
+
+// decodedHistogram is a histogram of a column with decoded upper bounds.
+type decodedHistogram struct {
+	upperBounds []tree.Datum
+	buckets     []stats.HistogramData_Bucket
+	// distinctPerBucket is the estimated number of distinct values in the
+	// range of each bucket, excluding its upper bound.
+	distinctPerBucket float64
+}
+
+// decodeHistogram returns the histogram of the given column if there is one
+// and its values can be compared with d.
+func (ts *tableStats) decodeHistogram(
+	col *sqlbase.ColumnDescriptor, d tree.Datum,
+) (*decodedHistogram, bool) {
+	s := ts.findStat(util.MakeFastIntSet(int(col.ID)))
+	if s == nil || s.Histogram == nil || len(s.Histogram.Buckets) == 0 {
+		return nil, false
+	}
+	colType := col.Type.ToDatumType()
+	if !d.ResolvedType().Equivalent(colType) {
+		return nil, false
+	}
+	h := &decodedHistogram{
+		upperBounds: make([]tree.Datum, len(s.Histogram.Buckets)),
+		buckets:     s.Histogram.Buckets,
+	}
+	var a sqlbase.DatumAlloc
+	for i, b := range s.Histogram.Buckets {
+		upper, _, err := sqlbase.DecodeTableKey(&a, colType, b.UpperBound, encoding.Ascending)
+		if err != nil {
+			return nil, false
+		}
+		h.upperBounds[i] = upper
+	}
+	numBuckets := float64(len(h.buckets))
+	h.distinctPerBucket = math.Max(1, (float64(s.DistinctCount)-numBuckets)/numBuckets)
+	return h, true
+}
+
+// totalRows returns the number of rows represented by the histogram.
+func (h *decodedHistogram) totalRows() float64 {
+	var total float64
+	for _, b := range h.buckets {
+		total += float64(b.NumRange + b.NumEq)
+	}
+	return total
+}
+
+// rowsEqual estimates the number of rows equal to d.
+func (h *decodedHistogram) rowsEqual(evalCtx *tree.EvalContext, d tree.Datum) float64 {
+	for i, upper := range h.upperBounds {
+		switch c := d.Compare(evalCtx, upper); {
+		case c == 0:
+			return float64(h.buckets[i].NumEq)
+		case c < 0:
+			return float64(h.buckets[i].NumRange) / h.distinctPerBucket
+		}
+	}
+	// The value is larger than any value in the histogram.
+	return 0
+}
+
+// rowsLess estimates the number of rows smaller than d (or equal to d, if
+// inclusive is set). Values inside the range of a bucket are assumed to be
+// in the middle of it.
+func (h *decodedHistogram) rowsLess(
+	evalCtx *tree.EvalContext, d tree.Datum, inclusive bool,
+) float64 {
+	var rows float64
+	for i, upper := range h.upperBounds {
+		b := &h.buckets[i]
+		switch c := d.Compare(evalCtx, upper); {
+		case c > 0:
+			rows += float64(b.NumRange + b.NumEq)
+		case c == 0:
+			rows += float64(b.NumRange)
+			if inclusive {
+				rows += float64(b.NumEq)
+			}
+			return rows
+		default:
+			return rows + float64(b.NumRange)/2
+		}
+	}
+	return rows
+}
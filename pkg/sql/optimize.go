@@ -73,28 +73,8 @@ type subqueryInitializer struct {
 // subqueryNode implements the planObserver interface.
 func (i *subqueryInitializer) subqueryNode(ctx context.Context, sq *subquery) error {
 	if sq.plan != nil && !sq.expanded {
-		if sq.execMode == execModeExists || sq.execMode == execModeOneRow {
-			numRows := tree.DInt(1)
-			if sq.execMode == execModeOneRow {
-				// When using a sub-query in a scalar context, we must
-				// appropriately reject sub-queries that return more than 1
-				// row.
-				numRows = 2
-			}
-
-			sq.plan = &limitNode{p: i.p, plan: sq.plan, countExpr: tree.NewDInt(numRows)}
-		}
-
-		needed := make([]bool, len(planColumns(sq.plan)))
-		if sq.execMode != execModeExists {
-			// EXISTS does not need values; the rest does.
-			for i := range needed {
-				needed[i] = true
-			}
-		}
-
 		var err error
-		sq.plan, err = i.p.optimizePlan(ctx, sq.plan, needed)
+		sq.plan, err = i.p.optimizeSubqueryPlan(ctx, sq.execMode, sq.plan)
 		if err != nil {
 			return err
 		}
@@ -103,6 +83,34 @@ func (i *subqueryInitializer) subqueryNode(ctx context.Context, sq *subquery) er
 	return nil
 }
 
+// optimizeSubqueryPlan optimizes the plan of a sub-query executed in the
+// given mode.
+func (p *planner) optimizeSubqueryPlan(
+	ctx context.Context, execMode subqueryExecMode, plan planNode,
+) (planNode, error) {
+	if execMode == execModeExists || execMode == execModeOneRow {
+		numRows := tree.DInt(1)
+		if execMode == execModeOneRow {
+			// When using a sub-query in a scalar context, we must
+			// appropriately reject sub-queries that return more than 1
+			// row.
+			numRows = 2
+		}
+
+		plan = &limitNode{p: p, plan: plan, countExpr: tree.NewDInt(numRows)}
+	}
+
+	needed := make([]bool, len(planColumns(plan)))
+	if execMode != execModeExists {
+		// EXISTS does not need values; the rest does.
+		for i := range needed {
+			needed[i] = true
+		}
+	}
+
+	return p.optimizePlan(ctx, plan, needed)
+}
+
 func (i *subqueryInitializer) enterNode(_ context.Context, _ string, _ planNode) bool {
 	return true
 }
@@ -109,6 +109,10 @@ func TestContextualHelp(t *testing.T) {
 
 		{`CREATE SEQUENCE ??`, `CREATE SEQUENCE`},
 
+		{`CREATE STATISTICS ??`, `CREATE STATISTICS`},
+		{`CREATE STATISTICS abc ON ??`, `CREATE STATISTICS`},
+		{`CREATE STATISTICS abc ON col1 FROM ??`, `CREATE STATISTICS`},
+
 		{`CREATE TABLE blah (??`, `CREATE TABLE`},
 		{`CREATE TABLE IF NOT ??`, `CREATE TABLE`},
 		{`CREATE TABLE blah (x, y) AS ??`, `CREATE TABLE`},
@@ -231,6 +235,11 @@ func TestContextualHelp(t *testing.T) {
 
 		{`SHOW JOBS ??`, `SHOW JOBS`},
 
+		{`SHOW STATISTICS ??`, `SHOW STATISTICS`},
+		{`SHOW STATISTICS FOR TABLE ??`, `SHOW STATISTICS`},
+
+		{`SHOW HISTOGRAM ??`, `SHOW HISTOGRAM`},
+
 		{`SHOW BACKUP 'foo' ??`, `SHOW BACKUP`},
 
 		{`SHOW CLUSTER SETTING all ??`, `SHOW CLUSTER SETTING`},
@@ -336,6 +345,11 @@ func TestContextualHelp(t *testing.T) {
 
 		{`IMPORT TABLE foo CREATE USING 'foo.sql' CSV DATA ('foo') ??`, `IMPORT`},
 		{`IMPORT TABLE ??`, `IMPORT`},
+
+		{`CREATE CHANGEFEED ??`, `CREATE CHANGEFEED`},
+		{`CREATE CHANGEFEED FOR foo INTO 'sink' ??`, `CREATE CHANGEFEED`},
+		{`EXPERIMENTAL CHANGEFEED ??`, `EXPERIMENTAL CHANGEFEED`},
+		{`EXPERIMENTAL CHANGEFEED FOR foo ??`, `EXPERIMENTAL CHANGEFEED`},
 	}
 
 	// The following checks that the test definition above exercises all
@@ -79,11 +79,20 @@ func TestParse(t *testing.T) {
 		{`CREATE INDEX ON a (b) INTERLEAVE IN PARENT c (d)`},
 		{`CREATE INDEX ON a (b) INTERLEAVE IN PARENT c.d (e)`},
 		{`CREATE INDEX ON a (b ASC, c DESC)`},
+		{`CREATE INDEX a ON b (lower(c))`},
+		{`CREATE INDEX a ON b ((c + d) DESC, e)`},
+		{`CREATE UNIQUE INDEX a ON b ((c->'d'))`},
+		{`CREATE TABLE a (b STRING, INDEX (lower(b)))`},
 		{`CREATE UNIQUE INDEX a ON b (c)`},
 		{`CREATE UNIQUE INDEX a ON b (c) STORING (d)`},
 		{`CREATE UNIQUE INDEX a ON b (c) INTERLEAVE IN PARENT d (e, f)`},
 		{`CREATE UNIQUE INDEX a ON b (c) INTERLEAVE IN PARENT d.e (f, g)`},
 		{`CREATE UNIQUE INDEX a ON b.c (d)`},
+		{`CREATE INVERTED INDEX a ON b (c)`},
+		{`CREATE INVERTED INDEX ON a (b)`},
+		{`CREATE INVERTED INDEX IF NOT EXISTS a ON b.c (d)`},
+		{`CREATE TABLE a (b JSONB, INVERTED INDEX (b))`},
+		{`CREATE TABLE a (b JSONB, INVERTED INDEX c (b))`},
 
 		{`CREATE TABLE a ()`},
 		{`CREATE TABLE a (b INT)`},
@@ -178,6 +187,8 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT) INTERLEAVE IN PARENT foo (c) CASCADE`},
 		{`CREATE TABLE a.b (b INT)`},
 		{`CREATE TABLE IF NOT EXISTS a (b INT)`},
+		{`CREATE TEMPORARY TABLE a (b INT)`},
+		{`CREATE TEMPORARY TABLE IF NOT EXISTS a (b INT)`},
 
 		{`CREATE TABLE a (b INT) PARTITION BY LIST (b) (PARTITION p1 VALUES IN (1, DEFAULT), PARTITION p2 VALUES IN ((1, 2), (3, 4)))`},
 		{`CREATE TABLE a (b INT) PARTITION BY RANGE (b) (PARTITION p1 VALUES < 1, PARTITION p2 VALUES < (2, MAXVALUE), PARTITION p3 VALUES < MAXVALUE)`},
@@ -204,6 +215,7 @@ func TestParse(t *testing.T) {
 
 		{`CREATE TABLE a AS SELECT * FROM b`},
 		{`CREATE TABLE IF NOT EXISTS a AS SELECT * FROM b`},
+		{`CREATE TEMPORARY TABLE a AS SELECT * FROM b`},
 		{`CREATE TABLE a AS SELECT * FROM b ORDER BY c`},
 		{`CREATE TABLE IF NOT EXISTS a AS SELECT * FROM b ORDER BY c`},
 		{`CREATE TABLE a AS SELECT * FROM b LIMIT 3`},
@@ -241,6 +253,10 @@ func TestParse(t *testing.T) {
 		{`CREATE SEQUENCE a NO CYCLE`},
 		{`CREATE SEQUENCE a INCREMENT 5 NO MAXVALUE MINVALUE 1 START 3 NO CYCLE`},
 
+		{`CREATE STATISTICS a FROM b`},
+		{`CREATE STATISTICS a ON col1 FROM t`},
+		{`CREATE STATISTICS a ON col1, col2 FROM d.t`},
+
 		{`DELETE FROM a`},
 		{`DELETE FROM a.b`},
 		{`DELETE FROM a WHERE a = b`},
@@ -254,6 +270,7 @@ func TestParse(t *testing.T) {
 		{`DELETE FROM a WHERE a = b ORDER BY c LIMIT d RETURNING e`},
 
 		{`DISCARD ALL`},
+		{`DISCARD TEMPORARY`},
 
 		{`DROP DATABASE a`},
 		{`DROP DATABASE IF EXISTS a`},
@@ -326,6 +343,10 @@ func TestParse(t *testing.T) {
 		{`SHOW TABLES FROM a; SHOW COLUMNS FROM b`},
 		{`SHOW USERS`},
 		{`SHOW JOBS`},
+
+		{`SHOW STATISTICS FOR TABLE t`},
+		{`SHOW STATISTICS FOR TABLE d.t`},
+		{`SHOW HISTOGRAM 123`},
 		{`SHOW CLUSTER QUERIES`},
 		{`SHOW LOCAL QUERIES`},
 		{`SHOW CLUSTER SESSIONS`},
@@ -590,6 +611,11 @@ func TestParse(t *testing.T) {
 		{`SELECT a FROM generate_series(1, 32) AS s (x)`},
 		{`SELECT a FROM generate_series(1, 32) WITH ORDINALITY AS s (x)`},
 		{`SELECT a FROM t1, t2`},
+		{`SELECT a FROM t1, LATERAL (SELECT b FROM t2 WHERE t2.x = t1.x)`},
+		{`SELECT a FROM t1, LATERAL (SELECT b FROM t2 WHERE t2.x = t1.x) WITH ORDINALITY AS s (x, y)`},
+		{`SELECT a FROM t1 JOIN LATERAL (SELECT b FROM t2 WHERE t2.x = t1.x) AS s ON true`},
+		{`SELECT a FROM t1 LEFT JOIN LATERAL generate_series(1, t1.x) AS s (x) ON true`},
+		{`SELECT a FROM t1, LATERAL generate_series(1, t1.x)`},
 		{`SELECT a FROM t AS t1`},
 		{`SELECT a FROM t AS t1 (c1)`},
 		{`SELECT a FROM t AS t1 (c1, c2, c3, c4)`},
@@ -680,6 +706,8 @@ func TestParse(t *testing.T) {
 		{`SELECT a FROM t WINDOW w AS (ORDER BY c)`},
 		{`SELECT a FROM t WINDOW w AS (ORDER BY c, 1 + 2)`},
 		{`SELECT a FROM t WINDOW w AS (PARTITION BY b ORDER BY c)`},
+		{`SELECT a FROM t WINDOW w AS (PARTITION BY b ORDER BY c ROWS 1 PRECEDING)`},
+		{`SELECT a FROM t WINDOW w AS (w2 ROWS UNBOUNDED PRECEDING)`},
 
 		{`SELECT avg(1) OVER w FROM t`},
 		{`SELECT avg(1) OVER () FROM t`},
@@ -688,6 +716,19 @@ func TestParse(t *testing.T) {
 		{`SELECT avg(1) OVER (ORDER BY c) FROM t`},
 		{`SELECT avg(1) OVER (PARTITION BY b ORDER BY c) FROM t`},
 		{`SELECT avg(1) OVER (w PARTITION BY b ORDER BY c) FROM t`},
+		{`SELECT avg(1) OVER (ROWS UNBOUNDED PRECEDING) FROM t`},
+		{`SELECT avg(1) OVER (ROWS 1 PRECEDING) FROM t`},
+		{`SELECT avg(1) OVER (ROWS CURRENT ROW) FROM t`},
+		{`SELECT avg(1) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) FROM t`},
+		{`SELECT avg(1) OVER (ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) FROM t`},
+		{`SELECT avg(1) OVER (ROWS BETWEEN CURRENT ROW AND 2 FOLLOWING) FROM t`},
+		{`SELECT avg(1) OVER (ROWS BETWEEN 1 FOLLOWING AND UNBOUNDED FOLLOWING) FROM t`},
+		{`SELECT avg(1) OVER (PARTITION BY b ORDER BY c ROWS BETWEEN 2 PRECEDING AND CURRENT ROW) FROM t`},
+		{`SELECT avg(1) OVER (ORDER BY c RANGE UNBOUNDED PRECEDING) FROM t`},
+		{`SELECT avg(1) OVER (ORDER BY c RANGE BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) FROM t`},
+		{`SELECT avg(1) OVER (ORDER BY c RANGE BETWEEN 10 PRECEDING AND 5 FOLLOWING) FROM t`},
+		{`SELECT avg(1) OVER (ORDER BY c RANGE BETWEEN '1h'::INTERVAL PRECEDING AND CURRENT ROW) FROM t`},
+		{`SELECT avg(1) OVER (w ROWS BETWEEN $1 PRECEDING AND $2 FOLLOWING) FROM t`},
 
 		{`SELECT a FROM t UNION SELECT 1 FROM t`},
 		{`SELECT a FROM t UNION SELECT 1 FROM t UNION SELECT 1 FROM t`},
@@ -714,6 +755,15 @@ func TestParse(t *testing.T) {
 		{`SELECT a FROM t LIMIT a`},
 		{`SELECT a FROM t OFFSET b`},
 		{`SELECT a FROM t LIMIT a OFFSET b`},
+		{`SELECT a FROM t FOR UPDATE`},
+		{`SELECT a FROM t FOR NO KEY UPDATE`},
+		{`SELECT a FROM t FOR SHARE`},
+		{`SELECT a FROM t FOR KEY SHARE`},
+		{`SELECT a FROM t FOR UPDATE NOWAIT`},
+		{`SELECT a FROM t FOR SHARE SKIP LOCKED`},
+		{`SELECT a FROM t ORDER BY a LIMIT 1 FOR UPDATE`},
+		{`WITH a AS (SELECT 1) SELECT * FROM a, t FOR UPDATE`},
+		{`SELECT a FROM (SELECT a FROM t FOR UPDATE)`},
 		{`SELECT DISTINCT * FROM t`},
 		{`SELECT DISTINCT a, b FROM t`},
 		{`SET a = 3`},
@@ -820,6 +870,12 @@ func TestParse(t *testing.T) {
 		{`ALTER TABLE a ALTER COLUMN b DROP DEFAULT`},
 		{`ALTER TABLE a ALTER COLUMN b DROP NOT NULL`},
 		{`ALTER TABLE a ALTER b DROP NOT NULL`},
+		{`ALTER TABLE a ALTER COLUMN b SET NOT NULL`},
+		{`ALTER TABLE a ALTER b SET NOT NULL`},
+		{`ALTER TABLE a ALTER COLUMN b SET DATA TYPE INT`},
+		{`ALTER TABLE a ALTER b SET DATA TYPE STRING`},
+		{`ALTER TABLE a ALTER COLUMN b SET DATA TYPE DECIMAL(10,2) USING b::DECIMAL(10,2)`},
+		{`ALTER TABLE a ALTER COLUMN b SET DATA TYPE INT USING length(b)`},
 
 		{`COPY t FROM STDIN`},
 		{`COPY t (a, b, c) FROM STDIN`},
@@ -892,8 +948,22 @@ func TestParse(t *testing.T) {
 		{`IMPORT TABLE foo CREATE USING 'nodelocal:///some/file' CSV DATA ('path/to/some/file', $1) WITH temp = 'path/to/temp'`},
 		{`IMPORT TABLE foo (id INT PRIMARY KEY, email STRING, age INT) CSV DATA ('path/to/some/file', $1) WITH temp = 'path/to/temp'`},
 		{`IMPORT TABLE foo (id INT, email STRING, age INT) CSV DATA ('path/to/some/file', $1) WITH comma = ',', "nullif" = 'n/a', temp = $2`},
+		{`CREATE CHANGEFEED FOR foo INTO 'sink'`},
+		{`CREATE CHANGEFEED FOR foo, db.bar INTO $1 WITH cursor = '1'`},
+		{`EXPERIMENTAL CHANGEFEED FOR foo`},
+		{`EXPERIMENTAL CHANGEFEED FOR foo WITH cursor = $1`},
 		{`SET ROW (1, true, NULL)`},
 
+		{`WITH a AS (SELECT 1) SELECT * FROM a`},
+		{`WITH a (x, y) AS (SELECT 1, 2), b AS (SELECT x FROM a) SELECT * FROM b ORDER BY x LIMIT 1`},
+		{`WITH RECURSIVE t (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t WHERE n < 10) SELECT * FROM t`},
+		{`SELECT * FROM (WITH a AS (SELECT 1) SELECT * FROM a)`},
+		{`WITH a AS (SELECT 1) INSERT INTO t SELECT * FROM a`},
+		{`WITH a AS (SELECT 1) UPSERT INTO t SELECT * FROM a`},
+		{`WITH a AS (SELECT 1) UPDATE t SET b = 1 WHERE c IN (SELECT * FROM a)`},
+		{`WITH a AS (SELECT 1) DELETE FROM t WHERE c IN (SELECT * FROM a)`},
+		{`WITH a AS (INSERT INTO t VALUES (1) RETURNING k) SELECT * FROM a`},
+
 		// Regression for #15926
 		{`SELECT * FROM ((t1 NATURAL JOIN t2 WITH ORDINALITY AS o1)) WITH ORDINALITY AS o2`},
 	}
@@ -921,6 +991,10 @@ func TestParse2(t *testing.T) {
 			`CREATE DATABASE a ENCODING = 'foo'`},
 		{`CREATE DATABASE a TEMPLATE = template0`,
 			`CREATE DATABASE a TEMPLATE = 'template0'`},
+		{`CREATE TEMP TABLE a (b INT)`,
+			`CREATE TEMPORARY TABLE a (b INT)`},
+		{`DISCARD TEMP`,
+			`DISCARD TEMPORARY`},
 		{`CREATE DATABASE a TEMPLATE = invalid`,
 			`CREATE DATABASE a TEMPLATE = 'invalid'`},
 		{`CREATE TABLE a (b INT, UNIQUE INDEX foo (b))`,
@@ -930,6 +1004,10 @@ func TestParse2(t *testing.T) {
 		{`CREATE TABLE a (UNIQUE INDEX (b) PARTITION BY LIST (c) (PARTITION d VALUES IN (1)))`,
 			`CREATE TABLE a (UNIQUE (b) PARTITION BY LIST (c) (PARTITION d VALUES IN (1)))`},
 		{`CREATE INDEX ON a (b) COVERING (c)`, `CREATE INDEX ON a (b) STORING (c)`},
+		{`CREATE INDEX ON a ((lower(b)))`, `CREATE INDEX ON a (lower(b))`},
+		{`ALTER TABLE a ALTER COLUMN b TYPE INT`, `ALTER TABLE a ALTER COLUMN b SET DATA TYPE INT`},
+		{`ALTER TABLE a ALTER b TYPE STRING USING b::STRING`,
+			`ALTER TABLE a ALTER b SET DATA TYPE STRING USING b::STRING`},
 
 		{`SELECT TIMESTAMP WITHOUT TIME ZONE 'foo'`, `SELECT TIMESTAMP 'foo'`},
 		{`SELECT CAST('foo' AS TIMESTAMP WITHOUT TIME ZONE)`, `SELECT CAST('foo' AS TIMESTAMP)`},
@@ -1047,6 +1125,11 @@ func TestParse2(t *testing.T) {
 			`SELECT a FROM t LIMIT 2 * a OFFSET b`},
 		{`SELECT a FROM t FETCH FIRST (2 * a) ROWS ONLY OFFSET b`,
 			`SELECT a FROM t LIMIT 2 * a OFFSET b`},
+		// The locking clause may come before LIMIT, but is always output last.
+		{`SELECT a FROM t FOR UPDATE LIMIT 1`,
+			`SELECT a FROM t LIMIT 1 FOR UPDATE`},
+		{`SELECT a FROM t ORDER BY a FOR SHARE NOWAIT OFFSET 1`,
+			`SELECT a FROM t ORDER BY a OFFSET 1 FOR SHARE NOWAIT`},
 		// Double negation. See #1800.
 		{`SELECT *,-/* comment */-5`,
 			`SELECT *, -(-5)`},
@@ -1196,6 +1279,10 @@ func TestParse2(t *testing.T) {
 
 		{`BACKUP DATABASE foo TO bar`,
 			`BACKUP DATABASE foo TO 'bar'`},
+		{`CREATE CHANGEFEED FOR TABLE foo INTO sink`,
+			`CREATE CHANGEFEED FOR foo INTO 'sink'`},
+		{`EXPERIMENTAL CHANGEFEED FOR TABLE foo`,
+			`EXPERIMENTAL CHANGEFEED FOR foo`},
 		{`BACKUP DATABASE foo TO "bar.12" INCREMENTAL FROM "baz.34"`,
 			`BACKUP DATABASE foo TO 'bar.12' INCREMENTAL FROM 'baz.34'`},
 		{`RESTORE DATABASE foo FROM bar`,
@@ -1568,6 +1655,48 @@ SELECT a FROM foo@{NO_INDEX_JOIN,NO_INDEX_JOIN}
 			`NO_INDEX_JOIN specified multiple times at or near "no_index_join"
 SELECT a FROM foo@{NO_INDEX_JOIN,FORCE_INDEX=baz,NO_INDEX_JOIN}
                                                  ^
+`,
+		},
+		{
+			`SELECT avg(1) OVER (ROWS UNBOUNDED FOLLOWING) FROM t`,
+			`frame start cannot be UNBOUNDED FOLLOWING at or near "following"
+SELECT avg(1) OVER (ROWS UNBOUNDED FOLLOWING) FROM t
+                                   ^
+`,
+		},
+		{
+			`SELECT avg(1) OVER (ROWS 1 FOLLOWING) FROM t`,
+			`frame starting from following row cannot end with current row at or near "following"
+SELECT avg(1) OVER (ROWS 1 FOLLOWING) FROM t
+                           ^
+`,
+		},
+		{
+			`SELECT avg(1) OVER (ROWS BETWEEN UNBOUNDED FOLLOWING AND UNBOUNDED FOLLOWING) FROM t`,
+			`frame start cannot be UNBOUNDED FOLLOWING at or near "following"
+SELECT avg(1) OVER (ROWS BETWEEN UNBOUNDED FOLLOWING AND UNBOUNDED FOLLOWING) FROM t
+                                                                   ^
+`,
+		},
+		{
+			`SELECT avg(1) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED PRECEDING) FROM t`,
+			`frame end cannot be UNBOUNDED PRECEDING at or near "preceding"
+SELECT avg(1) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED PRECEDING) FROM t
+                                                                   ^
+`,
+		},
+		{
+			`SELECT avg(1) OVER (ROWS BETWEEN CURRENT ROW AND 1 PRECEDING) FROM t`,
+			`frame starting from current row cannot have preceding rows at or near "preceding"
+SELECT avg(1) OVER (ROWS BETWEEN CURRENT ROW AND 1 PRECEDING) FROM t
+                                                   ^
+`,
+		},
+		{
+			`SELECT avg(1) OVER (ROWS BETWEEN 1 FOLLOWING AND CURRENT ROW) FROM t`,
+			`frame starting from following row cannot end with current row at or near "row"
+SELECT avg(1) OVER (ROWS BETWEEN 1 FOLLOWING AND CURRENT ROW) FROM t
+                                                         ^
 `,
 		},
 		{
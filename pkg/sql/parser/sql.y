@@ -172,6 +172,18 @@ func (u *sqlSymUnion) stmt() tree.Statement {
 func (u *sqlSymUnion) stmts() []tree.Statement {
     return u.val.([]tree.Statement)
 }
+func (u *sqlSymUnion) with() *tree.With {
+    if with, ok := u.val.(*tree.With); ok {
+        return with
+    }
+    return nil
+}
+func (u *sqlSymUnion) cte() *tree.CTE {
+    return u.val.(*tree.CTE)
+}
+func (u *sqlSymUnion) ctes() []*tree.CTE {
+    return u.val.([]*tree.CTE)
+}
 func (u *sqlSymUnion) slct() *tree.Select {
     return u.val.(*tree.Select)
 }
@@ -298,6 +310,15 @@ func (u *sqlSymUnion) onConflict() *tree.OnConflict {
 func (u *sqlSymUnion) orderBy() tree.OrderBy {
     return u.val.(tree.OrderBy)
 }
+func (u *sqlSymUnion) lockingClause() tree.LockingClause {
+    return u.val.(tree.LockingClause)
+}
+func (u *sqlSymUnion) lockingStrength() tree.LockingStrength {
+    return u.val.(tree.LockingStrength)
+}
+func (u *sqlSymUnion) lockingWaitPolicy() tree.LockingWaitPolicy {
+    return u.val.(tree.LockingWaitPolicy)
+}
 func (u *sqlSymUnion) order() *tree.Order {
     return u.val.(*tree.Order)
 }
@@ -392,6 +413,15 @@ func (u *sqlSymUnion) referenceActions() tree.ReferenceActions {
     return u.val.(tree.ReferenceActions)
 }
 
+func (u *sqlSymUnion) windowFrame() *tree.WindowFrameSpec {
+    return u.val.(*tree.WindowFrameSpec)
+}
+func (u *sqlSymUnion) windowFrameBounds() tree.WindowFrameBounds {
+    return u.val.(tree.WindowFrameBounds)
+}
+func (u *sqlSymUnion) windowFrameBound() *tree.WindowFrameBound {
+    return u.val.(*tree.WindowFrameBound)
+}
 func (u *sqlSymUnion) scrubOptions() tree.ScrubOptions {
     return u.val.(tree.ScrubOptions)
 }
@@ -426,7 +456,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %token <str>   BACKUP BEGIN BETWEEN BIGINT BIGSERIAL BIT
 %token <str>   BLOB BOOL BOOLEAN BOTH BY BYTEA BYTES
 
-%token <str>   CACHE CANCEL CASCADE CASE CAST CHAR
+%token <str>   CACHE CANCEL CASCADE CASE CAST CHANGEFEED CHAR
 %token <str>   CHARACTER CHARACTERISTICS CHECK
 %token <str>   CLUSTER COALESCE COLLATE COLLATION COLUMN COLUMNS COMMIT
 %token <str>   COMMITTED CONCAT CONFIGURATION CONFIGURATIONS CONFIGURE
@@ -448,12 +478,12 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 %token <str>   GRANT GRANTS GREATEST GROUP GROUPING
 
-%token <str>   HAVING HELP HIGH HOUR
+%token <str>   HAVING HELP HIGH HISTOGRAM HOUR
 
 %token <str>   IMPORT INCREMENT INCREMENTAL IF IFNULL ILIKE IN INET INTERLEAVE
 %token <str>   INDEX INDEXES INITIALLY
 %token <str>   INNER INSERT INT INT2VECTOR INT2 INT4 INT8 INT64 INTEGER
-%token <str>   INTERSECT INTERVAL INTO IS ISOLATION
+%token <str>   INTERSECT INTERVAL INTO INVERTED IS ISOLATION
 
 %token <str>   JOB JOBS JOIN JSON JSONB
 
@@ -461,12 +491,12 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 %token <str>   LATERAL LC_CTYPE LC_COLLATE
 %token <str>   LEADING LEAST LEFT LESS LEVEL LIKE LIMIT LIST LOCAL
-%token <str>   LOCALTIME LOCALTIMESTAMP LOW LSHIFT
+%token <str>   LOCALTIME LOCALTIMESTAMP LOCKED LOW LSHIFT
 
 %token <str>   MATCH MINVALUE MAXVALUE MINUTE MONTH
 
 %token <str>   NAN NAME NAMES NATURAL NEXT NO NO_INDEX_JOIN NORMAL
-%token <str>   NOT NOTHING NULL NULLIF
+%token <str>   NOT NOTHING NOWAIT NULL NULLIF
 %token <str>   NULLS NUMERIC
 
 %token <str>   OF OFF OFFSET OID ON ONLY OPTIONS OR
@@ -485,8 +515,8 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 %token <str>   SAVEPOINT SCATTER SCRUB SEARCH SECOND SELECT SEQUENCE SEQUENCES
 %token <str>   SERIAL SERIALIZABLE SESSION SESSIONS SESSION_USER SET SETTING SETTINGS
-%token <str>   SHOW SIMILAR SIMPLE SMALLINT SMALLSERIAL SNAPSHOT SOME SOME_EXISTENCE SPLIT SQL
-%token <str>   START STATUS STDIN STRICT STRING STORE STORING SUBSTRING
+%token <str>   SHARE SHOW SIMILAR SIMPLE SKIP SMALLINT SMALLSERIAL SNAPSHOT SOME SOME_EXISTENCE SPLIT SQL
+%token <str>   START STATISTICS STATUS STDIN STRICT STRING STORE STORING SUBSTRING
 %token <str>   SYMMETRIC SYSTEM
 
 %token <str>   TABLE TABLES TEMP TEMPLATE TEMPORARY TESTING_RANGES TESTING_RELOCATE TEXT THAN THEN
@@ -582,10 +612,12 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.ScrubOptions> scrub_option_list
 %type <tree.ScrubOption> scrub_option
 
+%type <tree.Statement> changefeed_stmt
 %type <tree.Statement> commit_stmt
 %type <tree.Statement> copy_from_stmt
 
 %type <tree.Statement> create_stmt
+%type <tree.Statement> create_changefeed_stmt
 %type <tree.Statement> create_ddl_stmt
 %type <tree.Statement> create_database_stmt
 %type <tree.Statement> create_index_stmt
@@ -594,6 +626,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.Statement> create_user_stmt
 %type <tree.Statement> create_view_stmt
 %type <tree.Statement> create_sequence_stmt
+%type <tree.Statement> create_stats_stmt
 %type <tree.Statement> delete_stmt
 %type <tree.Statement> discard_stmt
 
@@ -645,6 +678,8 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.Statement> show_grants_stmt
 %type <tree.Statement> show_indexes_stmt
 %type <tree.Statement> show_jobs_stmt
+%type <tree.Statement> show_histogram_stmt
+%type <tree.Statement> show_stats_stmt
 %type <tree.Statement> show_queries_stmt
 %type <tree.Statement> show_session_stmt
 %type <tree.Statement> show_sessions_stmt
@@ -672,7 +707,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.SelectStatement> select_clause select_with_parens simple_select values_clause table_clause simple_select_clause
 %type <tree.SelectStatement> set_operation
 
-%type <empty> alter_using
+%type <tree.Expr> alter_using
 %type <tree.Expr> alter_column_default
 %type <tree.Direction> opt_asc_desc
 
@@ -726,7 +761,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.OrderBy> sort_clause opt_sort_clause
 %type <[]*tree.Order> sortby_list
 %type <tree.IndexElemList> index_params
-%type <tree.NameList> name_list opt_name_list
+%type <tree.NameList> name_list opt_name_list opt_stats_columns
 %type <[]int32> opt_array_bounds
 %type <*tree.From> from_clause update_from_clause
 %type <tree.TableExprs> from_list
@@ -743,7 +778,10 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.UnresolvedName> qname_indirection
 %type <tree.NamePart> name_indirection_elem
 %type <tree.GroupBy> group_clause
-%type <*tree.Limit> select_limit
+%type <*tree.Limit> select_limit opt_select_limit
+%type <tree.LockingClause> for_locking_clause
+%type <tree.LockingStrength> for_locking_strength
+%type <tree.LockingWaitPolicy> opt_nowait_or_skip
 %type <tree.TableNameReferences> relation_expr_list
 %type <tree.ReturningClause> returning_clause
 
@@ -764,7 +802,7 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.DurationField> opt_interval interval_second
 %type <tree.Expr> overlay_placing
 
-%type <bool> opt_unique opt_column
+%type <bool> opt_unique opt_column opt_temp
 
 %type <empty> opt_set_data
 
@@ -862,9 +900,10 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 
 %type <tree.Expr>  func_application func_expr_common_subexpr
 %type <tree.Expr>  func_expr func_expr_windowless
-%type <empty> common_table_expr
-%type <empty> with_clause opt_with opt_with_clause
-%type <empty> cte_list
+%type <*tree.CTE> common_table_expr
+%type <*tree.With> with_clause opt_with_clause
+%type <[]*tree.CTE> cte_list
+%type <empty> opt_with
 
 %type <empty> within_group_clause
 %type <tree.Expr> filter_clause
@@ -872,7 +911,9 @@ func (u *sqlSymUnion) scrubOption() tree.ScrubOption {
 %type <tree.Window> window_clause window_definition_list
 %type <*tree.WindowDef> window_definition over_clause window_specification
 %type <str> opt_existing_window_name
-%type <empty> opt_frame_clause frame_extent frame_bound
+%type <*tree.WindowFrameSpec> opt_frame_clause
+%type <tree.WindowFrameBounds> frame_extent
+%type <*tree.WindowFrameBound> frame_bound
 
 %type <[]tree.ColumnID> opt_tableref_col_list tableref_col_list
 
@@ -975,6 +1016,7 @@ stmt:
 | alter_stmt      // help texts in sub-rule
 | backup_stmt     // EXTEND WITH HELP: BACKUP
 | cancel_stmt     // help texts in sub-rule
+| changefeed_stmt // EXTEND WITH HELP: EXPERIMENTAL CHANGEFEED
 | scrub_stmt
 | copy_from_stmt
 | create_stmt     // help texts in sub-rule
@@ -1287,7 +1329,10 @@ alter_table_cmd:
     $$.val = &tree.AlterTableDropNotNull{ColumnKeyword: $2.bool(), Column: tree.Name($3)}
   }
   // ALTER TABLE <name> ALTER [COLUMN] <colname> SET NOT NULL
-| ALTER opt_column name SET NOT NULL { return unimplemented(sqllex, "alter set non null") }
+| ALTER opt_column name SET NOT NULL
+  {
+    $$.val = &tree.AlterTableSetNotNull{ColumnKeyword: $2.bool(), Column: tree.Name($3)}
+  }
   // ALTER TABLE <name> DROP [COLUMN] IF EXISTS <colname> [RESTRICT|CASCADE]
 | DROP opt_column IF EXISTS name opt_drop_behavior
   {
@@ -1310,7 +1355,15 @@ alter_table_cmd:
   }
   // ALTER TABLE <name> ALTER [COLUMN] <colname> [SET DATA] TYPE <typename>
   //     [ USING <expression> ]
-| ALTER opt_column name opt_set_data TYPE typename opt_collate_clause alter_using { return unimplemented(sqllex, "alter set type") }
+| ALTER opt_column name opt_set_data TYPE typename opt_collate_clause alter_using
+  {
+    $$.val = &tree.AlterTableAlterColumnType{
+      ColumnKeyword: $2.bool(),
+      Column: tree.Name($3),
+      ToType: $6.colType(),
+      Using: $8.expr(),
+    }
+  }
   // ALTER TABLE <name> ADD CONSTRAINT ...
 | ADD table_constraint opt_validate_behavior
   {
@@ -1386,8 +1439,14 @@ opt_collate_clause:
 | /* EMPTY */ {}
 
 alter_using:
-  USING a_expr { return unimplemented(sqllex, "alter using") }
-| /* EMPTY */ {}
+  USING a_expr
+  {
+    $$.val = $2.expr()
+  }
+| /* EMPTY */
+  {
+    $$.val = nil
+  }
 
 // %Help: BACKUP - back up data to external storage
 // %Category: CCL
@@ -1446,6 +1505,49 @@ restore_stmt:
   }
 | RESTORE error // SHOW HELP: RESTORE
 
+// %Help: CREATE CHANGEFEED - stream row changes of tables into a sink
+// %Category: CCL
+// %Text:
+// CREATE CHANGEFEED FOR <targets...> INTO <sink>
+//        [ WITH <option> [= <value>] [, ...] ]
+//
+// Targets:
+//    TABLE <tablename> [, ...]
+//
+// Sink:
+//    "[scheme]://[host]/[path]?[parameters]"
+//
+// Options:
+//    CURSOR = <timestamp>
+//
+// %SeeAlso: EXPERIMENTAL CHANGEFEED, SHOW JOBS
+create_changefeed_stmt:
+  CREATE CHANGEFEED FOR targets INTO string_or_placeholder opt_with_options
+  {
+    $$.val = &tree.CreateChangefeed{Targets: $4.targetList(), SinkURI: $6.expr(), Options: $7.kvOptions()}
+  }
+| CREATE CHANGEFEED error // SHOW HELP: CREATE CHANGEFEED
+
+// %Help: EXPERIMENTAL CHANGEFEED - stream row changes of tables to the client
+// %Category: Experimental
+// %Text:
+// EXPERIMENTAL CHANGEFEED FOR <targets...>
+//        [ WITH <option> [= <value>] [, ...] ]
+//
+// Targets:
+//    TABLE <tablename> [, ...]
+//
+// Options:
+//    CURSOR = <timestamp>
+//
+// %SeeAlso: CREATE CHANGEFEED
+changefeed_stmt:
+  EXPERIMENTAL CHANGEFEED FOR targets opt_with_options
+  {
+    $$.val = &tree.CreateChangefeed{Targets: $4.targetList(), Options: $5.kvOptions()}
+  }
+| EXPERIMENTAL CHANGEFEED error // SHOW HELP: EXPERIMENTAL CHANGEFEED
+
 import_data_format:
   CSV
   {
@@ -1601,10 +1703,11 @@ cancel_query_stmt:
 // %Category: Group
 // %Text:
 // CREATE DATABASE, CREATE TABLE, CREATE INDEX, CREATE TABLE AS,
-// CREATE USER, CREATE VIEW, CREATE SEQUENCE
+// CREATE USER, CREATE VIEW, CREATE SEQUENCE, CREATE CHANGEFEED
 create_stmt:
-  create_user_stmt     // EXTEND WITH HELP: CREATE USER
-| create_ddl_stmt      // help texts in sub-rule
+  create_user_stmt       // EXTEND WITH HELP: CREATE USER
+| create_changefeed_stmt // EXTEND WITH HELP: CREATE CHANGEFEED
+| create_ddl_stmt        // help texts in sub-rule
 | CREATE error         // SHOW HELP: CREATE
 
 create_ddl_stmt:
@@ -1613,9 +1716,10 @@ create_ddl_stmt:
 | create_table_stmt    // EXTEND WITH HELP: CREATE TABLE
 | create_table_as_stmt // EXTEND WITH HELP: CREATE TABLE
 // Error case for both CREATE TABLE and CREATE TABLE ... AS in one
-| CREATE TABLE error   // SHOW HELP: CREATE TABLE
+| CREATE opt_temp TABLE error   // SHOW HELP: CREATE TABLE
 | create_view_stmt     // EXTEND WITH HELP: CREATE VIEW
 | create_sequence_stmt // EXTEND WITH HELP: CREATE SEQUENCE
+| create_stats_stmt    // EXTEND WITH HELP: CREATE STATISTICS
 
 // %Help: DELETE - delete rows from a table
 // %Category: DML
@@ -1628,6 +1732,7 @@ delete_stmt:
   opt_with_clause DELETE FROM relation_expr_opt_alias where_clause opt_sort_clause opt_limit_clause returning_clause
   {
     $$.val = &tree.Delete{
+      With: $1.with(),
       Table: $4.tblExpr(),
       Where: tree.NewWhere(tree.AstWhere, $5.expr()),
       OrderBy: $6.orderBy(),
@@ -1639,7 +1744,7 @@ delete_stmt:
 
 // %Help: DISCARD - reset the session to its initial state
 // %Category: Cfg
-// %Text: DISCARD ALL
+// %Text: DISCARD ALL, DISCARD TEMPORARY
 discard_stmt:
   DISCARD ALL
   {
@@ -1647,8 +1752,14 @@ discard_stmt:
   }
 | DISCARD PLANS { return unimplemented(sqllex, "discard plans") }
 | DISCARD SEQUENCES { return unimplemented(sqllex, "discard sequences") }
-| DISCARD TEMP { return unimplemented(sqllex, "discard temp") }
-| DISCARD TEMPORARY { return unimplemented(sqllex, "discard temporary") }
+| DISCARD TEMP
+  {
+    $$.val = &tree.Discard{Mode: tree.DiscardModeTemp}
+  }
+| DISCARD TEMPORARY
+  {
+    $$.val = &tree.Discard{Mode: tree.DiscardModeTemp}
+  }
 | DISCARD error // SHOW HELP: DISCARD
 
 // %Help: DROP
@@ -2401,11 +2512,13 @@ show_stmt:
 | show_csettings_stmt    // EXTEND WITH HELP: SHOW CLUSTER SETTING
 | show_databases_stmt    // EXTEND WITH HELP: SHOW DATABASES
 | show_grants_stmt       // EXTEND WITH HELP: SHOW GRANTS
+| show_histogram_stmt    // EXTEND WITH HELP: SHOW HISTOGRAM
 | show_indexes_stmt      // EXTEND WITH HELP: SHOW INDEXES
 | show_jobs_stmt         // EXTEND WITH HELP: SHOW JOBS
 | show_queries_stmt      // EXTEND WITH HELP: SHOW QUERIES
 | show_session_stmt      // EXTEND WITH HELP: SHOW SESSION
 | show_sessions_stmt     // EXTEND WITH HELP: SHOW SESSIONS
+| show_stats_stmt        // EXTEND WITH HELP: SHOW STATISTICS
 | show_tables_stmt       // EXTEND WITH HELP: SHOW TABLES
 | show_testing_stmt
 | show_trace_stmt        // EXTEND WITH HELP: SHOW TRACE
@@ -2571,6 +2684,28 @@ show_jobs_stmt:
   }
 | SHOW JOBS error // SHOW HELP: SHOW JOBS
 
+// %Help: SHOW STATISTICS - display table statistics
+// %Category: Misc
+// %Text: SHOW STATISTICS FOR TABLE <table_name>
+// %SeeAlso: CREATE STATISTICS, SHOW HISTOGRAM
+show_stats_stmt:
+  SHOW STATISTICS FOR TABLE var_name
+  {
+    $$.val = &tree.ShowTableStats{Table: $5.normalizableTableName()}
+  }
+| SHOW STATISTICS error // SHOW HELP: SHOW STATISTICS
+
+// %Help: SHOW HISTOGRAM - display histogram
+// %Category: Misc
+// %Text: SHOW HISTOGRAM <histogram_id>
+// %SeeAlso: SHOW STATISTICS
+show_histogram_stmt:
+  SHOW HISTOGRAM iconst64
+  {
+    $$.val = &tree.ShowHistogram{HistogramID: $3.int64()}
+  }
+| SHOW HISTOGRAM error // SHOW HELP: SHOW HISTOGRAM
+
 // %Help: SHOW TRACE - display an execution trace
 // %Category: Misc
 // %Text:
@@ -2775,8 +2910,8 @@ pause_stmt:
 // %Help: CREATE TABLE - create a new table
 // %Category: DDL
 // %Text:
-// CREATE TABLE [IF NOT EXISTS] <tablename> ( <elements...> ) [<interleave>]
-// CREATE TABLE [IF NOT EXISTS] <tablename> [( <colnames...> )] AS <source>
+// CREATE [TEMPORARY] TABLE [IF NOT EXISTS] <tablename> ( <elements...> ) [<interleave>]
+// CREATE [TEMPORARY] TABLE [IF NOT EXISTS] <tablename> [( <colnames...> )] AS <source>
 //
 // Table elements:
 //    <name> <type> [<qualifiers...>]
@@ -2804,39 +2939,55 @@ pause_stmt:
 // WEBDOCS/create-table.html
 // WEBDOCS/create-table-as.html
 create_table_stmt:
-  CREATE TABLE any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
+  CREATE opt_temp TABLE any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
   {
     $$.val = &tree.CreateTable{
-      Table: $3.normalizableTableName(),
+      Table: $4.normalizableTableName(),
       IfNotExists: false,
-      Interleave: $7.interleave(),
-      Defs: $5.tblDefs(),
+      Temporary: $2.bool(),
+      Interleave: $8.interleave(),
+      Defs: $6.tblDefs(),
       AsSource: nil,
       AsColumnNames: nil,
-      PartitionBy: $8.partitionBy(),
+      PartitionBy: $9.partitionBy(),
     }
   }
-| CREATE TABLE IF NOT EXISTS any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
+| CREATE opt_temp TABLE IF NOT EXISTS any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
   {
     $$.val = &tree.CreateTable{
-      Table: $6.normalizableTableName(),
+      Table: $7.normalizableTableName(),
       IfNotExists: true,
-      Interleave: $10.interleave(),
-      Defs: $8.tblDefs(),
+      Temporary: $2.bool(),
+      Interleave: $11.interleave(),
+      Defs: $9.tblDefs(),
       AsSource: nil,
       AsColumnNames: nil,
-      PartitionBy: $11.partitionBy(),
+      PartitionBy: $12.partitionBy(),
     }
   }
 
 create_table_as_stmt:
-  CREATE TABLE any_name opt_column_list AS select_stmt
+  CREATE opt_temp TABLE any_name opt_column_list AS select_stmt
   {
-    $$.val = &tree.CreateTable{Table: $3.normalizableTableName(), IfNotExists: false, Interleave: nil, Defs: nil, AsSource: $6.slct(), AsColumnNames: $4.nameList()}
+    $$.val = &tree.CreateTable{Table: $4.normalizableTableName(), IfNotExists: false, Temporary: $2.bool(), Interleave: nil, Defs: nil, AsSource: $7.slct(), AsColumnNames: $5.nameList()}
   }
-| CREATE TABLE IF NOT EXISTS any_name opt_column_list AS select_stmt
+| CREATE opt_temp TABLE IF NOT EXISTS any_name opt_column_list AS select_stmt
   {
-    $$.val = &tree.CreateTable{Table: $6.normalizableTableName(), IfNotExists: true, Interleave: nil, Defs: nil, AsSource: $9.slct(), AsColumnNames: $7.nameList()}
+    $$.val = &tree.CreateTable{Table: $7.normalizableTableName(), IfNotExists: true, Temporary: $2.bool(), Interleave: nil, Defs: nil, AsSource: $10.slct(), AsColumnNames: $8.nameList()}
+  }
+
+opt_temp:
+  TEMPORARY
+  {
+    $$.val = true
+  }
+| TEMP
+  {
+    $$.val = true
+  }
+| /* EMPTY */
+  {
+    $$.val = false
   }
 
 opt_table_elem_list:
@@ -3091,6 +3242,14 @@ index_def:
       },
     }
   }
+| INVERTED INDEX opt_name '(' index_params ')'
+  {
+    $$.val = &tree.IndexTableDef{
+      Name:     tree.Name($3),
+      Columns:  $5.idxElems(),
+      Inverted: true,
+    }
+  }
 
 family_def:
   FAMILY opt_name '(' name_list ')'
@@ -3267,6 +3426,35 @@ numeric_only:
     $$.val = $1.numVal()
   }
 
+// %Help: CREATE STATISTICS - create a new table statistic
+// %Category: Misc
+// %Text:
+// CREATE STATISTICS <statisticname>
+//   [ON <colname> [, ...]]
+//   FROM <tablename>
+//
+// %SeeAlso: SHOW STATISTICS
+create_stats_stmt:
+  CREATE STATISTICS name opt_stats_columns FROM qualified_name
+  {
+    $$.val = &tree.CreateStats{
+      Name: tree.Name($3),
+      ColumnNames: $4.nameList(),
+      Table: $6.normalizableTableName(),
+    }
+  }
+| CREATE STATISTICS error // SHOW HELP: CREATE STATISTICS
+
+opt_stats_columns:
+  ON name_list
+  {
+    $$.val = $2.nameList()
+  }
+| /* EMPTY */
+  {
+    $$.val = tree.NameList(nil)
+  }
+
 // %Help: CREATE SEQUENCE - create a new sequence
 // %Category: DDL
 // %Text:
@@ -3386,6 +3574,8 @@ create_view_stmt:
 // CREATE [UNIQUE] INDEX [IF NOT EXISTS] [<idxname>]
 //        ON <tablename> ( <colname> [ASC | DESC] [, ...] )
 //        [STORING ( <colnames...> )] [<interleave>]
+// CREATE INVERTED INDEX [IF NOT EXISTS] [<idxname>]
+//        ON <tablename> ( <colname> )
 //
 // Interleave clause:
 //    INTERLEAVE IN PARENT <tablename> ( <colnames...> ) [CASCADE | RESTRICT]
@@ -3418,6 +3608,25 @@ create_index_stmt:
       PartitionBy: $15.partitionBy(),
     }
   }
+| CREATE INVERTED INDEX opt_name ON qualified_name '(' index_params ')'
+  {
+    $$.val = &tree.CreateIndex{
+      Name:     tree.Name($4),
+      Table:    $6.normalizableTableName(),
+      Inverted: true,
+      Columns:  $8.idxElems(),
+    }
+  }
+| CREATE INVERTED INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')'
+  {
+    $$.val = &tree.CreateIndex{
+      Name:        tree.Name($7),
+      Table:       $9.normalizableTableName(),
+      Inverted:    true,
+      IfNotExists: true,
+      Columns:     $11.idxElems(),
+    }
+  }
 | CREATE opt_unique INDEX error // SHOW HELP: CREATE INDEX
 
 opt_unique:
@@ -3448,8 +3657,14 @@ index_elem:
   {
     $$.val = tree.IndexElem{Column: tree.Name($1), Direction: $3.dir()}
   }
-| func_expr_windowless opt_collate opt_asc_desc { return unimplemented(sqllex, "index_elem func expr") }
-| '(' a_expr ')' opt_collate opt_asc_desc { return unimplemented(sqllex, "index_elem a_expr") }
+| func_expr_windowless opt_collate opt_asc_desc
+  {
+    $$.val = tree.IndexElem{Expr: $1.expr(), Direction: $3.dir()}
+  }
+| '(' a_expr ')' opt_collate opt_asc_desc
+  {
+    $$.val = tree.IndexElem{Expr: $2.expr(), Direction: $5.dir()}
+  }
 
 opt_collate:
   COLLATE unrestricted_name { return unimplementedWithIssue(sqllex, 16619) }
@@ -3832,12 +4047,14 @@ insert_stmt:
   opt_with_clause INSERT INTO insert_target insert_rest returning_clause
   {
     $$.val = $5.stmt()
+    $$.val.(*tree.Insert).With = $1.with()
     $$.val.(*tree.Insert).Table = $4.tblExpr()
     $$.val.(*tree.Insert).Returning = $6.retClause()
   }
 | opt_with_clause INSERT INTO insert_target insert_rest on_conflict returning_clause
   {
     $$.val = $5.stmt()
+    $$.val.(*tree.Insert).With = $1.with()
     $$.val.(*tree.Insert).Table = $4.tblExpr()
     $$.val.(*tree.Insert).OnConflict = $6.onConflict()
     $$.val.(*tree.Insert).Returning = $7.retClause()
@@ -3855,6 +4072,7 @@ upsert_stmt:
   opt_with_clause UPSERT INTO insert_target insert_rest returning_clause
   {
     $$.val = $5.stmt()
+    $$.val.(*tree.Insert).With = $1.with()
     $$.val.(*tree.Insert).Table = $4.tblExpr()
     $$.val.(*tree.Insert).OnConflict = &tree.OnConflict{}
     $$.val.(*tree.Insert).Returning = $6.retClause()
@@ -3941,6 +4159,7 @@ update_stmt:
     SET set_clause_list update_from_clause where_clause opt_sort_clause opt_limit_clause returning_clause
   {
     $$.val = &tree.Update{
+      With: $1.with(),
       Table: $3.tblExpr(),
       Exprs: $5.updateExprs(),
       Where: tree.NewWhere(tree.AstWhere, $7.expr()),
@@ -4057,17 +4276,74 @@ select_no_parens:
   {
     $$.val = &tree.Select{Select: $1.selectStmt(), OrderBy: $2.orderBy(), Limit: $3.limit()}
   }
+| select_clause opt_sort_clause for_locking_clause opt_select_limit
+  {
+    $$.val = &tree.Select{Select: $1.selectStmt(), OrderBy: $2.orderBy(), Limit: $4.limit(), Locking: $3.lockingClause()}
+  }
+| select_clause opt_sort_clause select_limit for_locking_clause
+  {
+    $$.val = &tree.Select{Select: $1.selectStmt(), OrderBy: $2.orderBy(), Limit: $3.limit(), Locking: $4.lockingClause()}
+  }
 | with_clause select_clause
   {
-    $$.val = &tree.Select{Select: $2.selectStmt()}
+    $$.val = &tree.Select{With: $1.with(), Select: $2.selectStmt()}
   }
 | with_clause select_clause sort_clause
   {
-    $$.val = &tree.Select{Select: $2.selectStmt(), OrderBy: $3.orderBy()}
+    $$.val = &tree.Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy()}
   }
 | with_clause select_clause opt_sort_clause select_limit
   {
-    $$.val = &tree.Select{Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $4.limit()}
+    $$.val = &tree.Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $4.limit()}
+  }
+| with_clause select_clause opt_sort_clause for_locking_clause opt_select_limit
+  {
+    $$.val = &tree.Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $5.limit(), Locking: $4.lockingClause()}
+  }
+| with_clause select_clause opt_sort_clause select_limit for_locking_clause
+  {
+    $$.val = &tree.Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $4.limit(), Locking: $5.lockingClause()}
+  }
+
+// The locking clause requests row-level locks on the rows returned by the
+// query. Only a single locking clause is supported, applying to all the
+// tables of the query.
+for_locking_clause:
+  for_locking_strength opt_nowait_or_skip
+  {
+    $$.val = tree.LockingClause{Strength: $1.lockingStrength(), WaitPolicy: $2.lockingWaitPolicy()}
+  }
+
+for_locking_strength:
+  FOR UPDATE
+  {
+    $$.val = tree.ForUpdate
+  }
+| FOR NO KEY UPDATE
+  {
+    $$.val = tree.ForNoKeyUpdate
+  }
+| FOR SHARE
+  {
+    $$.val = tree.ForShare
+  }
+| FOR KEY SHARE
+  {
+    $$.val = tree.ForKeyShare
+  }
+
+opt_nowait_or_skip:
+  /* EMPTY */
+  {
+    $$.val = tree.LockWaitBlock
+  }
+| SKIP LOCKED
+  {
+    $$.val = tree.LockWaitSkip
+  }
+| NOWAIT
+  {
+    $$.val = tree.LockWaitError
   }
 
 select_clause:
@@ -4127,6 +4403,7 @@ simple_select:
 //        [ ORDER BY <expr> [ ASC | DESC ] [, ...] ]
 //        [ LIMIT { <expr> | ALL } ]
 //        [ OFFSET <expr> [ ROW | ROWS ] ]
+//        [ FOR { UPDATE | NO KEY UPDATE | SHARE | KEY SHARE } [ NOWAIT | SKIP LOCKED ] ]
 // %SeeAlso: WEBDOCS/select.html
 simple_select_clause:
   SELECT opt_all_clause target_list
@@ -4211,24 +4488,51 @@ table_clause:
 //
 // Recognizing WITH_LA here allows a CTE to be named TIME or ORDINALITY.
 with_clause:
-  WITH cte_list { return unimplemented(sqllex, "with cte_list") }
-| WITH_LA cte_list { return unimplemented(sqllex, "with cte_list") }
-| WITH RECURSIVE cte_list { return unimplemented(sqllex, "with cte_list") }
+  WITH cte_list
+  {
+    $$.val = &tree.With{CTEList: $2.ctes()}
+  }
+| WITH_LA cte_list
+  {
+    $$.val = &tree.With{CTEList: $2.ctes()}
+  }
+| WITH RECURSIVE cte_list
+  {
+    $$.val = &tree.With{Recursive: true, CTEList: $3.ctes()}
+  }
 
 cte_list:
-  common_table_expr { return unimplemented(sqllex, "cte_list") }
-| cte_list ',' common_table_expr { return unimplemented(sqllex, "cte_list") }
+  common_table_expr
+  {
+    $$.val = []*tree.CTE{$1.cte()}
+  }
+| cte_list ',' common_table_expr
+  {
+    $$.val = append($1.ctes(), $3.cte())
+  }
 
 common_table_expr:
-  name opt_name_list AS '(' preparable_stmt ')' { return unimplemented(sqllex, "cte") }
+  name opt_column_list AS '(' preparable_stmt ')'
+  {
+    $$.val = &tree.CTE{
+      Name: tree.AliasClause{Alias: tree.Name($1), Cols: $2.nameList()},
+      Stmt: $5.stmt(),
+    }
+  }
 
 opt_with:
   WITH {}
 | /* EMPTY */ {}
 
 opt_with_clause:
-  with_clause { return unimplemented(sqllex, "with_clause") }
-| /* EMPTY */ {}
+  with_clause
+  {
+    $$.val = $1.with()
+  }
+| /* EMPTY */
+  {
+    $$.val = nil
+  }
 
 opt_table:
   TABLE {}
@@ -4321,6 +4625,10 @@ select_limit:
 | limit_clause
 | offset_clause
 
+opt_select_limit:
+  select_limit
+| /* EMPTY */ { $$.val = (*tree.Limit)(nil) }
+
 opt_limit_clause:
   limit_clause
 | /* EMPTY */ { $$.val = (*tree.Limit)(nil) }
@@ -4586,6 +4894,14 @@ table_ref:
   {
     $$.val = &tree.AliasedTableExpr{Expr: &tree.Subquery{Select: $1.selectStmt()}, Ordinality: $2.bool(), As: $3.aliasClause() }
   }
+| LATERAL select_with_parens opt_ordinality opt_alias_clause
+  {
+    $$.val = &tree.AliasedTableExpr{Expr: &tree.Subquery{Select: $2.selectStmt()}, Ordinality: $3.bool(), Lateral: true, As: $4.aliasClause() }
+  }
+| LATERAL qualified_name '(' opt_expr_list ')' opt_ordinality opt_alias_clause
+  {
+    $$.val = &tree.AliasedTableExpr{Expr: &tree.FuncExpr{Func: $2.resolvableFunctionReference(), Exprs: $4.exprs()}, Ordinality: $6.bool(), Lateral: true, As: $7.aliasClause() }
+  }
 | joined_table
   {
     $$.val = $1.tblExpr()
@@ -5883,8 +6199,14 @@ func_expr:
 // expressions are not allowed, where needed to disambiguate the grammar
 // (e.g. in CREATE INDEX).
 func_expr_windowless:
-  func_application { return unimplemented(sqllex, "func_application") }
-| func_expr_common_subexpr { return unimplemented(sqllex, "func_expr_common_subexpr") }
+  func_application
+  {
+    $$.val = $1.expr()
+  }
+| func_expr_common_subexpr
+  {
+    $$.val = $1.expr()
+  }
 
 // Special expressions that are considered to be functions.
 func_expr_common_subexpr:
@@ -6076,6 +6398,7 @@ window_specification:
       RefName: tree.Name($2),
       Partitions: $3.exprs(),
       OrderBy: $4.orderBy(),
+      Frame: $5.windowFrame(),
     }
   }
 
@@ -6104,29 +6427,96 @@ opt_partition_clause:
     $$.val = tree.Exprs(nil)
   }
 
-// For frame clauses, we return a tree.WindowDef, but only some fields are used:
-// frameOptions, startOffset, and endOffset.
-//
 // This is only a subset of the full SQL:2008 frame_clause grammar. We don't
 // support <window frame exclusion> yet.
 opt_frame_clause:
-  RANGE frame_extent { return unimplemented(sqllex, "frame range") }
-| ROWS frame_extent { return unimplemented(sqllex, "frame rows") }
-| /* EMPTY */ {}
+  RANGE frame_extent
+  {
+    $$.val = &tree.WindowFrameSpec{
+      Mode: tree.RANGE,
+      Bounds: $2.windowFrameBounds(),
+    }
+  }
+| ROWS frame_extent
+  {
+    $$.val = &tree.WindowFrameSpec{
+      Mode: tree.ROWS,
+      Bounds: $2.windowFrameBounds(),
+    }
+  }
+| /* EMPTY */
+  {
+    $$.val = (*tree.WindowFrameSpec)(nil)
+  }
 
 frame_extent:
-  frame_bound { return unimplemented(sqllex, "frame_extent") }
-| BETWEEN frame_bound AND frame_bound { return unimplemented(sqllex, "frame_extent") }
+  frame_bound
+  {
+    startBound := $1.windowFrameBound()
+    switch {
+    case startBound.BoundType == tree.UnboundedFollowing:
+      sqllex.Error("frame start cannot be UNBOUNDED FOLLOWING")
+      return 1
+    case startBound.BoundType == tree.ValueFollowing:
+      sqllex.Error("frame starting from following row cannot end with current row")
+      return 1
+    }
+    $$.val = tree.WindowFrameBounds{StartBound: startBound}
+  }
+| BETWEEN frame_bound AND frame_bound
+  {
+    startBound := $2.windowFrameBound()
+    endBound := $4.windowFrameBound()
+    switch {
+    case startBound.BoundType == tree.UnboundedFollowing:
+      sqllex.Error("frame start cannot be UNBOUNDED FOLLOWING")
+      return 1
+    case endBound.BoundType == tree.UnboundedPreceding:
+      sqllex.Error("frame end cannot be UNBOUNDED PRECEDING")
+      return 1
+    case startBound.BoundType == tree.CurrentRow && endBound.BoundType == tree.ValuePreceding:
+      sqllex.Error("frame starting from current row cannot have preceding rows")
+      return 1
+    case startBound.BoundType == tree.ValueFollowing && endBound.BoundType == tree.ValuePreceding:
+      sqllex.Error("frame starting from following row cannot have preceding rows")
+      return 1
+    case startBound.BoundType == tree.ValueFollowing && endBound.BoundType == tree.CurrentRow:
+      sqllex.Error("frame starting from following row cannot end with current row")
+      return 1
+    }
+    $$.val = tree.WindowFrameBounds{StartBound: startBound, EndBound: endBound}
+  }
 
 // This is used for both frame start and frame end, with output set up on the
 // assumption it's frame start; the frame_extent productions must reject
 // invalid cases.
 frame_bound:
-  UNBOUNDED PRECEDING { return unimplemented(sqllex, "frame_bound") }
-| UNBOUNDED FOLLOWING { return unimplemented(sqllex, "frame_bound") }
-| CURRENT ROW { return unimplemented(sqllex, "frame_bound") }
-| a_expr PRECEDING { return unimplemented(sqllex, "frame_bound") }
-| a_expr FOLLOWING { return unimplemented(sqllex, "frame_bound") }
+  UNBOUNDED PRECEDING
+  {
+    $$.val = &tree.WindowFrameBound{BoundType: tree.UnboundedPreceding}
+  }
+| UNBOUNDED FOLLOWING
+  {
+    $$.val = &tree.WindowFrameBound{BoundType: tree.UnboundedFollowing}
+  }
+| CURRENT ROW
+  {
+    $$.val = &tree.WindowFrameBound{BoundType: tree.CurrentRow}
+  }
+| a_expr PRECEDING
+  {
+    $$.val = &tree.WindowFrameBound{
+      OffsetExpr: $1.expr(),
+      BoundType: tree.ValuePreceding,
+    }
+  }
+| a_expr FOLLOWING
+  {
+    $$.val = &tree.WindowFrameBound{
+      OffsetExpr: $1.expr(),
+      BoundType: tree.ValueFollowing,
+    }
+  }
 
 // Supporting nonterminals for expressions.
 
@@ -6802,6 +7192,7 @@ unreserved_keyword:
 | CACHE
 | CANCEL
 | CASCADE
+| CHANGEFEED
 | CLUSTER
 | COLUMNS
 | COMMIT
@@ -6837,6 +7228,7 @@ unreserved_keyword:
 | FORCE_INDEX
 | GRANTS
 | HIGH
+| HISTOGRAM
 | HOUR
 | IMPORT
 | INCREMENT
@@ -6845,6 +7237,7 @@ unreserved_keyword:
 | INSERT
 | INT2VECTOR
 | INTERLEAVE
+| INVERTED
 | ISOLATION
 | JOB
 | JOBS
@@ -6857,6 +7250,7 @@ unreserved_keyword:
 | LEVEL
 | LIST
 | LOCAL
+| LOCKED
 | LOW
 | MATCH
 | MINUTE
@@ -6868,6 +7262,7 @@ unreserved_keyword:
 | NO
 | NORMAL
 | NO_INDEX_JOIN
+| NOWAIT
 | NULLS
 | OF
 | OFF
@@ -6922,11 +7317,14 @@ unreserved_keyword:
 | SESSION
 | SESSIONS
 | SET
+| SHARE
 | SHOW
 | SIMPLE
+| SKIP
 | SNAPSHOT
 | SQL
 | START
+| STATISTICS
 | STDIN
 | STORE
 | STORING
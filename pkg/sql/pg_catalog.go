@@ -30,6 +30,7 @@ import (
 	"golang.org/x/text/collate"
 
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -944,6 +945,13 @@ func indexDefFromDescriptor(
 		if index.ColumnDirections[i] == sqlbase.IndexDescriptor_DESC {
 			elem.Direction = tree.Descending
 		}
+		if col, _, err := table.FindColumnByName(elem.Column); err == nil && col.IsComputed() {
+			expr, err := parser.ParseExpr(*col.ComputeExpr)
+			if err != nil {
+				return "", err
+			}
+			elem.Expr = expr
+		}
 		indexDef.Columns[i] = elem
 	}
 	for i, name := range index.StoreColumnNames {
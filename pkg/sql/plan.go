@@ -143,6 +143,7 @@ type planNodeFastPath interface {
 }
 
 var _ planNode = &alterTableNode{}
+var _ planNode = &applyJoinNode{}
 var _ planNode = &alterSequenceNode{}
 var _ planNode = &copyNode{}
 var _ planNode = &createDatabaseNode{}
@@ -150,6 +151,8 @@ var _ planNode = &createIndexNode{}
 var _ planNode = &createTableNode{}
 var _ planNode = &createViewNode{}
 var _ planNode = &createSequenceNode{}
+var _ planNode = &createStatsNode{}
+var _ planNode = &cteScanNode{}
 var _ planNode = &delayedNode{}
 var _ planNode = &deleteNode{}
 var _ planNode = &distinctNode{}
@@ -171,7 +174,9 @@ var _ planNode = &insertNode{}
 var _ planNode = &joinNode{}
 var _ planNode = &limitNode{}
 var _ planNode = &ordinalityNode{}
+var _ planNode = &projectSetNode{}
 var _ planNode = &testingRelocateNode{}
+var _ planNode = &recursiveCTENode{}
 var _ planNode = &renderNode{}
 var _ planNode = &scanNode{}
 var _ planNode = &scatterNode{}
@@ -362,6 +367,8 @@ func (p *planner) newPlan(
 		return p.CreateView(ctx, n)
 	case *tree.CreateSequence:
 		return p.CreateSequence(ctx, n)
+	case *tree.CreateStats:
+		return p.CreateStatistics(ctx, n)
 	case *tree.Deallocate:
 		return p.Deallocate(ctx, n)
 	case *tree.Delete:
@@ -461,6 +468,10 @@ func (p *planner) newPlan(
 		return p.ShowRanges(ctx, n)
 	case *tree.ShowFingerprints:
 		return p.ShowFingerprints(ctx, n)
+	case *tree.ShowTableStats:
+		return p.ShowTableStats(ctx, n)
+	case *tree.ShowHistogram:
+		return p.ShowHistogram(ctx, n)
 	case *tree.Split:
 		return p.Split(ctx, n)
 	case *tree.Truncate:
@@ -553,6 +564,10 @@ func (p *planner) prepare(ctx context.Context, stmt tree.Statement) (planNode, e
 		return p.ShowTransactionStatus(ctx)
 	case *tree.ShowRanges:
 		return p.ShowRanges(ctx, n)
+	case *tree.ShowTableStats:
+		return p.ShowTableStats(ctx, n)
+	case *tree.ShowHistogram:
+		return p.ShowHistogram(ctx, n)
 	case *tree.Split:
 		return p.Split(ctx, n)
 	case *tree.TestingRelocate:
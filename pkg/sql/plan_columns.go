@@ -51,6 +51,10 @@ func getPlanColumns(plan planNode, mut bool) sqlbase.ResultColumns {
 	// Nodes that define their own schema.
 	case *copyNode:
 		return n.resultColumns
+	case *applyJoinNode:
+		return n.columns
+	case *cteScanNode:
+		return n.columns
 	case *delayedNode:
 		return n.columns
 	case *groupNode:
@@ -61,6 +65,10 @@ func getPlanColumns(plan planNode, mut bool) sqlbase.ResultColumns {
 		return n.columns
 	case *ordinalityNode:
 		return n.columns
+	case *projectSetNode:
+		return n.columns
+	case *recursiveCTENode:
+		return n.columns
 	case *renderNode:
 		return n.columns
 	case *scanNode:
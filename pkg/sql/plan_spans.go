@@ -17,6 +17,7 @@ package sql
 import (
 	"fmt"
 
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -56,6 +57,16 @@ func collectSpans(params runParams, plan planNode) (reads, writes roachpb.Spans,
 	case *deleteNode:
 		return editNodeSpans(params, &n.run.editNodeRun)
 
+	case *cteScanNode:
+		if n.src.plan == nil {
+			// The working table of a recursive CTE does not read anything
+			// by itself.
+			return nil, nil, nil
+		}
+		return collectSpans(params, n.src.plan)
+	case *recursiveCTENode:
+		return recursiveCTESpans(params, n)
+
 	case *delayedNode:
 		return collectSpans(params, n.plan)
 	case *distinctNode:
@@ -76,6 +87,8 @@ func collectSpans(params runParams, plan planNode) (reads, writes roachpb.Spans,
 		return collectSpans(params, n.plan)
 	case *ordinalityNode:
 		return collectSpans(params, n.source)
+	case *projectSetNode:
+		return collectSpans(params, n.source.plan)
 	case *filterNode:
 		return collectSpans(params, n.source.plan)
 	case *renderNode:
@@ -85,6 +98,14 @@ func collectSpans(params runParams, plan planNode) (reads, writes roachpb.Spans,
 		return indexJoinSpans(params, n)
 	case *joinNode:
 		return concatSpans(params, n.left.plan, n.right.plan)
+	case *applyJoinNode:
+		// The spans read by the right side depend on the rows of the left
+		// side; assume that it can read anything.
+		reads, writes, err := collectSpans(params, n.left.plan)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(reads, roachpb.Span{Key: keys.MinKey, EndKey: keys.MaxKey}), writes, nil
 	case *unionNode:
 		return concatSpans(params, n.left, n.right)
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/coltypes"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/transform"
@@ -107,6 +108,15 @@ type planner struct {
 	// plannedExecute is true if this planner has planned an EXECUTE statement.
 	plannedExecute bool
 
+	// cteEnv contains the common table expressions (WITH clauses) that
+	// are visible at the current point of planning.
+	cteEnv cteNameEnvironment
+
+	// outerScopes contains the data sources of the queries that enclose
+	// the subquery being planned, innermost last. Correlated subqueries
+	// refer to their columns.
+	outerScopes []*subqueryScope
+
 	// Avoid allocations by embedding commonly used objects and visitors.
 	parser                parser.Parser
 	txCtx                 transform.ExprTransformContext
@@ -344,19 +354,64 @@ func (p *planner) exec(ctx context.Context, sql string, args ...interface{}) (in
 
 func (p *planner) fillFKTableMap(ctx context.Context, m sqlbase.TableLookupsByID) error {
 	for tableID := range m {
-		table, err := p.session.tables.getTableVersionByID(ctx, p.txn, tableID)
-		if err == errTableAdding {
-			m[tableID] = sqlbase.TableLookup{IsAdding: true}
-			continue
-		}
+		table, err := p.lookupFKTable(ctx, tableID)
 		if err != nil {
 			return err
 		}
-		m[tableID] = sqlbase.TableLookup{Table: table}
+		m[tableID] = table
 	}
 	return nil
 }
 
+// lookupFKTable implements sqlbase.TableLookupFunction.
+func (p *planner) lookupFKTable(ctx context.Context, tableID sqlbase.ID) (sqlbase.TableLookup, error) {
+	table, err := p.session.tables.getTableVersionByID(ctx, p.txn, tableID)
+	if err == errTableAdding {
+		return sqlbase.TableLookup{IsAdding: true}, nil
+	}
+	if err != nil {
+		return sqlbase.TableLookup{}, err
+	}
+	return sqlbase.TableLookup{Table: table}, nil
+}
+
+// defaultFKCascadeMaxDepth is the default value of fkCascadeMaxDepth.
+const defaultFKCascadeMaxDepth = 32
+
+// fkCascadeMaxDepth is the maximum length of the chains of cascading
+// referential actions triggered by the deletion or update of a row.
+var fkCascadeMaxDepth = settings.RegisterValidatedIntSetting(
+	"sql.fk.cascade_max_depth",
+	"maximum number of tables a chain of cascading foreign key actions can go through",
+	defaultFKCascadeMaxDepth,
+	func(v int64) error {
+		if v < 1 {
+			return errors.Errorf("cannot set sql.fk.cascade_max_depth to a value less than 1: %d", v)
+		}
+		return nil
+	},
+)
+
+// fillFKCascadeTableMap adds to m, filled by fillFKTableMap, the tables
+// needed by the referential actions triggered by deleting (CheckDeletes) or
+// updating (CheckUpdates) rows of the table, and returns the configuration of
+// these actions for the row writers.
+func (p *planner) fillFKCascadeTableMap(
+	ctx context.Context,
+	table *sqlbase.TableDescriptor,
+	usage sqlbase.FKCheck,
+	m sqlbase.TableLookupsByID,
+) (*sqlbase.CascadeConfig, error) {
+	if err := sqlbase.AddTablesNeededForCascades(ctx, table, usage, m, p.lookupFKTable); err != nil {
+		return nil, err
+	}
+	maxDepth := int64(defaultFKCascadeMaxDepth)
+	if cfg := p.ExecCfg(); cfg != nil && cfg.Settings != nil {
+		maxDepth = fkCascadeMaxDepth.Get(&cfg.Settings.SV)
+	}
+	return &sqlbase.CascadeConfig{EvalCtx: &p.evalCtx, MaxDepth: int(maxDepth)}, nil
+}
+
 // isDatabaseVisible returns true if the given database is visible
 // given the provided prefix.
 // An empty prefix makes all databases visible.
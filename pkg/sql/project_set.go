@@ -0,0 +1,218 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// projectSetNode evaluates the set-returning functions (SRFs) used in
+// the render expressions of a SELECT clause. For each row of its source,
+// it runs all the SRFs, whose arguments can refer to the source columns,
+// and produces the source row extended with one column per SRF.
+//
+// As in PostgreSQL, the results of the SRFs are zipped together rather
+// than combined as a cross product: the i-th output row for a given
+// source row contains the i-th result of each SRF, and NULL for the SRFs
+// that have fewer than i results. The number of output rows per source
+// row is thus the number of results of the longest SRF.
+//
+// An SRF that produces a single column is rendered as that column;
+// otherwise its results are rendered as a tuple.
+type projectSetNode struct {
+	source     planDataSource
+	ivarHelper tree.IndexedVarHelper
+
+	// funcs contains the SRF calls, in terms of the source columns.
+	funcs []tree.TypedExpr
+
+	// numColsPerGen contains the number of columns produced by each SRF.
+	numColsPerGen []int
+
+	// columns contains the source columns followed by one column per SRF.
+	columns sqlbase.ResultColumns
+
+	run projectSetRun
+}
+
+// projectSetRun contains the run-time state of projectSetNode during
+// local execution.
+type projectSetRun struct {
+	// gen zips the results of the SRFs for the current source row, if any.
+	gen tree.ValueGenerator
+	// rowBuffer contains the current result row.
+	rowBuffer tree.Datums
+}
+
+var _ tree.IndexedVarContainer = &projectSetNode{}
+
+// makeProjectSet creates a projectSetNode over the given data source,
+// without any SRF yet.
+func makeProjectSet(src planDataSource) *projectSetNode {
+	n := &projectSetNode{source: src}
+	n.ivarHelper = tree.MakeIndexedVarHelper(n, len(src.info.sourceColumns))
+	n.columns = append(sqlbase.ResultColumns(nil), src.info.sourceColumns...)
+	return n
+}
+
+// addSRF adds an SRF call to the projectSetNode and returns the
+// description of the corresponding column.
+func (n *projectSetNode) addSRF(
+	ctx context.Context, p *planner, srf *tree.FuncExpr,
+) (sqlbase.ResultColumn, error) {
+	searchPath := p.session.SearchPath
+	if err := p.txCtx.AssertNoAggregationOrWindowing(srf, "SELECT", searchPath); err != nil {
+		return sqlbase.ResultColumn{}, err
+	}
+	fd, err := srf.Func.Resolve(searchPath)
+	if err != nil {
+		return sqlbase.ResultColumn{}, err
+	}
+
+	normalized, err := p.analyzeExpr(
+		ctx, srf, multiSourceInfo{n.source.info}, n.ivarHelper, types.Any, false, "SELECT",
+	)
+	if err != nil {
+		return sqlbase.ResultColumn{}, err
+	}
+	tType, ok := normalized.ResolvedType().(types.TTable)
+	if !ok {
+		return sqlbase.ResultColumn{}, errors.Errorf("expression is not a generator: %s", srf)
+	}
+
+	col := sqlbase.ResultColumn{Name: fd.Name, Typ: types.TTuple(tType.Cols)}
+	if len(tType.Cols) == 1 {
+		col.Name = tType.Labels[0]
+		col.Typ = tType.Cols[0]
+	}
+	n.funcs = append(n.funcs, normalized)
+	n.numColsPerGen = append(n.numColsPerGen, len(tType.Cols))
+	n.columns = append(n.columns, col)
+	return col, nil
+}
+
+// IndexedVarEval implements the tree.IndexedVarContainer interface.
+func (n *projectSetNode) IndexedVarEval(idx int, ctx *tree.EvalContext) (tree.Datum, error) {
+	return n.source.plan.Values()[idx].Eval(ctx)
+}
+
+// IndexedVarResolvedType implements the tree.IndexedVarContainer interface.
+func (n *projectSetNode) IndexedVarResolvedType(idx int) types.T {
+	return n.source.info.sourceColumns[idx].Typ
+}
+
+// IndexedVarNodeFormatter implements the tree.IndexedVarContainer interface.
+func (n *projectSetNode) IndexedVarNodeFormatter(idx int) tree.NodeFormatter {
+	return n.source.info.NodeFormatter(idx)
+}
+
+// Start implements the planNode interface.
+func (n *projectSetNode) Start(params runParams) error {
+	n.run.rowBuffer = make(tree.Datums, len(n.columns))
+	return n.source.plan.Start(params)
+}
+
+// Next implements the planNode interface.
+func (n *projectSetNode) Next(params runParams) (bool, error) {
+	for {
+		if err := params.p.cancelChecker.Check(); err != nil {
+			return false, err
+		}
+
+		if n.run.gen != nil {
+			next, err := n.run.gen.Next()
+			if err != nil {
+				return false, err
+			}
+			if next {
+				n.fillSRFColumns(n.run.gen.Values())
+				return true, nil
+			}
+			n.run.gen.Close()
+			n.run.gen = nil
+		}
+
+		next, err := n.source.plan.Next(params)
+		if !next || err != nil {
+			return false, err
+		}
+		copy(n.run.rowBuffer, n.source.plan.Values())
+		if err := n.startGenerator(params); err != nil {
+			return false, err
+		}
+	}
+}
+
+// startGenerator evaluates the SRF calls for the current source row and
+// starts a generator that zips their results.
+func (n *projectSetNode) startGenerator(params runParams) error {
+	gens := make([]tree.ValueGenerator, len(n.funcs))
+	params.p.evalCtx.IVarHelper = &n.ivarHelper
+	for i, fn := range n.funcs {
+		d, err := fn.Eval(&params.p.evalCtx)
+		if err != nil {
+			params.p.evalCtx.IVarHelper = nil
+			return err
+		}
+		if d == tree.DNull {
+			// The SRF was called with NULL arguments.
+			gens[i] = builtins.EmptyGenerator(fn.ResolvedType().(types.TTable))
+		} else {
+			gens[i] = d.(*tree.DTable).ValueGenerator
+		}
+	}
+	params.p.evalCtx.IVarHelper = nil
+
+	gen := builtins.ZipGenerators(gens...)
+	if err := gen.Start(); err != nil {
+		gen.Close()
+		return err
+	}
+	n.run.gen = gen
+	return nil
+}
+
+// fillSRFColumns sets the SRF columns of the current result row from the
+// values produced by the zipped generators.
+func (n *projectSetNode) fillSRFColumns(values tree.Datums) {
+	col := len(n.source.info.sourceColumns)
+	for _, numCols := range n.numColsPerGen {
+		if numCols == 1 {
+			n.run.rowBuffer[col] = values[0]
+		} else {
+			n.run.rowBuffer[col] = tree.NewDTuple(append(tree.Datums(nil), values[:numCols]...)...)
+		}
+		values = values[numCols:]
+		col++
+	}
+}
+
+// Values implements the planNode interface.
+func (n *projectSetNode) Values() tree.Datums { return n.run.rowBuffer }
+
+// Close implements the planNode interface.
+func (n *projectSetNode) Close(ctx context.Context) {
+	if n.run.gen != nil {
+		n.run.gen.Close()
+		n.run.gen = nil
+	}
+	n.source.plan.Close(ctx)
+}
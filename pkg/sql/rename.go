@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
@@ -43,6 +44,9 @@ func (p *planner) RenameDatabase(ctx context.Context, n *tree.RenameDatabase) (p
 	if n.Name == "" || n.NewName == "" {
 		return nil, errEmptyDatabaseName
 	}
+	if err := checkTemporarySchemaPrefix(string(n.NewName)); err != nil {
+		return nil, err
+	}
 
 	if err := p.RequireSuperUser("ALTER DATABASE ... RENAME"); err != nil {
 		return nil, err
@@ -112,14 +116,28 @@ func (p *planner) RenameDatabase(ctx context.Context, n *tree.RenameDatabase) (p
 //          mysql requires ALTER, DROP on the original table, and CREATE, INSERT
 //          on the new table (and does not copy privileges over).
 func (p *planner) RenameTable(ctx context.Context, n *tree.RenameTable) (planNode, error) {
-	oldTn, err := n.Name.NormalizeWithDatabaseName(p.session.Database)
+	oldTn, err := p.normalizeTableName(ctx, &n.Name)
 	if err != nil {
 		return nil, err
 	}
-	newTn, err := n.NewName.NormalizeWithDatabaseName(p.session.Database)
+	newDatabase := p.session.Database
+	_, oldIsTemp := parseTemporarySchemaName(oldTn.Database())
+	if oldIsTemp {
+		// A temporary table keeps living in the temporary schema when it is
+		// renamed without specifying a database.
+		newDatabase = oldTn.Database()
+	}
+	newTn, err := n.NewName.NormalizeWithDatabaseName(newDatabase)
 	if err != nil {
 		return nil, err
 	}
+	if err := p.checkTemporarySchemaAccess(newTn); err != nil {
+		return nil, err
+	}
+	if _, newIsTemp := parseTemporarySchemaName(newTn.Database()); oldIsTemp != newIsTemp {
+		return nil, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+			"cannot move objects into or out of temporary schemas")
+	}
 
 	dbDesc, err := MustGetDatabaseDesc(ctx, p.txn, p.getVirtualTabler(), oldTn.Database())
 	if err != nil {
@@ -315,7 +333,7 @@ func (p *planner) RenameIndex(ctx context.Context, n *tree.RenameIndex) (planNod
 //          mysql requires ALTER, CREATE, INSERT on the table.
 func (p *planner) RenameColumn(ctx context.Context, n *tree.RenameColumn) (planNode, error) {
 	// Check if table exists.
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -402,6 +420,35 @@ func (p *planner) RenameColumn(ctx context.Context, n *tree.RenameColumn) (planN
 			tableDesc.Checks[i].Expr = after
 		}
 	}
+	// Rename the column in the expressions of computed columns.
+	renameInComputeExpr := func(col *sqlbase.ColumnDescriptor) error {
+		if !col.IsComputed() {
+			return nil
+		}
+		expr, err := parser.ParseExpr(*col.ComputeExpr)
+		if err != nil {
+			return err
+		}
+		expr, err = tree.SimpleVisit(expr, preFn)
+		if err != nil {
+			return err
+		}
+		s := tree.Serialize(expr)
+		col.ComputeExpr = &s
+		return nil
+	}
+	for i := range tableDesc.Columns {
+		if err := renameInComputeExpr(&tableDesc.Columns[i]); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range tableDesc.Mutations {
+		if c := m.GetColumn(); c != nil {
+			if err := renameInComputeExpr(c); err != nil {
+				return nil, err
+			}
+		}
+	}
 	// Rename the column in the indexes.
 	tableDesc.RenameColumnDescriptor(col, string(n.NewName))
 
@@ -15,11 +15,11 @@
 package sql
 
 import (
-	"errors"
 	"fmt"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -139,11 +139,24 @@ func (r *renderNode) IndexedVarNodeFormatter(idx int) tree.NodeFormatter {
 func (p *planner) Select(
 	ctx context.Context, n *tree.Select, desiredTypes []types.T,
 ) (planNode, error) {
+	popWith, err := p.initWith(ctx, n.With)
+	if err != nil {
+		return nil, err
+	}
+	defer popWith()
+
 	wrapped := n.Select
 	limit := n.Limit
 	orderBy := n.OrderBy
+	locking := n.Locking
 
 	for s, ok := wrapped.(*tree.ParenSelect); ok; s, ok = wrapped.(*tree.ParenSelect) {
+		popInnerWith, err := p.initWith(ctx, s.Select.With)
+		if err != nil {
+			return nil, err
+		}
+		defer popInnerWith()
+
 		wrapped = s.Select.Select
 		if s.Select.OrderBy != nil {
 			if orderBy != nil {
@@ -157,13 +170,33 @@ func (p *planner) Select(
 			}
 			limit = s.Select.Limit
 		}
+		if s.Select.Locking.Strength != tree.ForNone {
+			if locking.Strength != tree.ForNone {
+				return nil, pgerror.NewError(pgerror.CodeSyntaxError, "multiple locking clauses not allowed")
+			}
+			locking = s.Select.Locking
+		}
 	}
 
 	switch s := wrapped.(type) {
 	case *tree.SelectClause:
 		// Select can potentially optimize index selection if it's being ordered,
 		// so we allow it to do its own sorting.
-		return p.SelectClause(ctx, s, orderBy, limit, desiredTypes, publicColumns)
+		if locking.Strength == tree.ForNone {
+			return p.SelectClause(ctx, s, orderBy, limit, desiredTypes, publicColumns)
+		}
+		if err := checkLockingClause(s, locking); err != nil {
+			return nil, err
+		}
+		plan, err := p.SelectClause(ctx, s, orderBy, limit, desiredTypes, publicColumns)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyLocking(plan, locking); err != nil {
+			plan.Close(ctx)
+			return nil, err
+		}
+		return plan, nil
 
 	// TODO(dan): Union can also do optimizations when it has an ORDER BY, but
 	// currently expects the ordering to be done externally, so we let it fall
@@ -171,6 +204,13 @@ func (p *planner) Select(
 	// investigating a general mechanism for passing some context down during
 	// plan node construction.
 	default:
+		if locking.Strength != tree.ForNone {
+			if _, ok := s.(*tree.ValuesClause); ok {
+				return nil, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+					"%s cannot be applied to VALUES", locking.Strength)
+			}
+			return nil, lockingNotAllowedError(locking, "UNION/INTERSECT/EXCEPT")
+		}
 		plan, err := p.newPlan(ctx, s, desiredTypes)
 		if err != nil {
 			return nil, err
@@ -232,9 +272,17 @@ func (p *planner) SelectClause(
 		}
 	}
 
+	targets := parsed.Exprs
+	if !p.txCtx.IsAggregate(parsed, p.session.SearchPath) {
+		// Correlated scalar subqueries are joined with the data source
+		// when possible. In aggregations, the renders can only refer to
+		// the grouped columns so this is not done.
+		targets = r.decorrelateTargets(ctx, targets)
+	}
+
 	r.ivarHelper = tree.MakeIndexedVarHelper(r, len(r.sourceInfo[0].sourceColumns))
 
-	if err := r.initTargets(ctx, parsed.Exprs, desiredTypes); err != nil {
+	if err := r.initTargets(ctx, targets, desiredTypes); err != nil {
 		return nil, err
 	}
 
@@ -406,15 +454,13 @@ func (p *planner) makeTupleRender(
 	return r, nil
 }
 
-// srfExtractionVisitor replaces the innermost set-returning function in an
-// expression with an IndexedVar that points at a new index at the end of the
-// ivarHelper. The extracted SRF is retained in the srf field.
-//
-// This visitor is intentionally limited to extracting only one SRF, because we
-// don't support lateral correlated subqueries.
+// srfExtractionVisitor replaces the set-returning functions in an
+// expression with IndexedVars that point at new indexes at the end of the
+// ivarHelper. The extracted SRFs are retained in the srfs field, in the
+// order of the new indexes.
 type srfExtractionVisitor struct {
 	err        error
-	srf        *tree.FuncExpr
+	srfs       []*tree.FuncExpr
 	ivarHelper *tree.IndexedVarHelper
 	searchPath tree.SearchPath
 }
@@ -422,11 +468,34 @@ type srfExtractionVisitor struct {
 var _ tree.Visitor = &srfExtractionVisitor{}
 
 func (v *srfExtractionVisitor) VisitPre(expr tree.Expr) (recurse bool, newNode tree.Expr) {
-	_, isSubquery := expr.(*tree.Subquery)
-	return !isSubquery, expr
+	if v.err != nil {
+		return false, expr
+	}
+	switch t := expr.(type) {
+	case *tree.Subquery:
+		return false, expr
+	case *tree.FuncExpr:
+		fd, err := t.Func.Resolve(v.searchPath)
+		if err != nil {
+			v.err = err
+			return false, expr
+		}
+		if _, ok := builtins.Generators[fd.Name]; ok {
+			for _, arg := range t.Exprs {
+				if containsGenerator(arg, v.searchPath) {
+					v.err = fmt.Errorf("set-returning functions cannot be nested: %s", t)
+					return false, expr
+				}
+			}
+		}
+	}
+	return true, expr
 }
 
 func (v *srfExtractionVisitor) VisitPost(expr tree.Expr) tree.Expr {
+	if v.err != nil {
+		return expr
+	}
 	switch t := expr.(type) {
 	case *tree.FuncExpr:
 		fd, err := t.Func.Resolve(v.searchPath)
@@ -435,11 +504,7 @@ func (v *srfExtractionVisitor) VisitPost(expr tree.Expr) tree.Expr {
 			return expr
 		}
 		if _, ok := builtins.Generators[fd.Name]; ok {
-			if v.srf != nil {
-				v.err = errors.New("cannot specify two set-returning functions in the same SELECT expression")
-				return expr
-			}
-			v.srf = t
+			v.srfs = append(v.srfs, t)
 			// We'll fill in the type later once the generator function has been
 			// analyzed.
 			return v.ivarHelper.IndexedVarWithType(v.ivarHelper.AppendSlot(), types.TTable{})
@@ -448,15 +513,13 @@ func (v *srfExtractionVisitor) VisitPost(expr tree.Expr) tree.Expr {
 	return expr
 }
 
-// rewriteSRFs creates data sources for any set-returning functions in the
-// provided render expression, cross-joins these data sources with the
-// renderNode's existing data sources, and returns a new render expression with
-// the set-returning function replaced by an IndexedVar that points at the new
-// data source.
-//
-// Expressions with more than one SRF require lateral correlated subqueries,
-// which are not yet supported. For now, this function returns an error if more
-// than one SRF is present in the render expression.
+// rewriteSRFs moves the set-returning functions in the provided render
+// expression to a projectSetNode between the renderNode and its data
+// source, and returns a new render expression where each set-returning
+// function is replaced by an IndexedVar that points at the corresponding
+// column of the projectSetNode. All the set-returning functions of the
+// renderNode share the same projectSetNode, so that their results are
+// zipped together.
 func (r *renderNode) rewriteSRFs(
 	ctx context.Context, target tree.SelectExpr,
 ) (tree.SelectExpr, error) {
@@ -464,7 +527,7 @@ func (r *renderNode) rewriteSRFs(
 	v := &r.planner.srfExtractionVisitor
 	*v = srfExtractionVisitor{
 		err:        nil,
-		srf:        nil,
+		srfs:       nil,
 		ivarHelper: &r.ivarHelper,
 		searchPath: r.planner.session.SearchPath,
 	}
@@ -475,39 +538,36 @@ func (r *renderNode) rewriteSRFs(
 
 	// Return the original render expression unchanged if the srfExtractionVisitor
 	// didn't find any SRFs.
-	if v.srf == nil {
+	if len(v.srfs) == 0 {
 		return target, nil
 	}
 
-	// We rewrote exactly one SRF; cross-join it with our sources and return the
-	// new render expression.
-	src, err := r.planner.getDataSourceAsOneColumn(ctx, v.srf)
-	if err != nil {
-		return target, err
+	// The projectSetNode is created for the first SRF of the renderNode.
+	ps, ok := r.source.plan.(*projectSetNode)
+	if !ok {
+		ps = makeProjectSet(r.source)
+		info := *r.source.info
+		info.sourceColumns = ps.columns
+		r.source = planDataSource{info: &info, plan: ps}
 	}
-
-	if !isUnarySource(r.source) {
-		// The FROM clause specifies something. Replace with a cross-join.
-		src, err = r.planner.makeJoin(ctx, "CROSS JOIN", r.source, src, nil)
-		if err != nil {
+	for _, srf := range v.srfs {
+		if _, err := ps.addSRF(ctx, r.planner, srf); err != nil {
 			return target, err
 		}
 	}
-
-	r.source = src
+	r.source.info.sourceColumns = ps.columns
 	r.sourceInfo = multiSourceInfo{r.source.info}
 
 	return tree.SelectExpr{Expr: expr}, nil
 }
 
-// A unary source is the special source used with empty FROM clauses:
-// a pseudo-table with zero columns and exactly one row.
-func isUnarySource(src planDataSource) bool {
-	_, ok := src.plan.(*unaryNode)
-	return ok && len(src.info.sourceColumns) == 0
-}
-
 func (r *renderNode) initWhere(ctx context.Context, whereExpr tree.Expr) (*filterNode, error) {
+	if whereExpr != nil {
+		// Correlated subqueries under EXISTS and IN are joined with the
+		// data source when possible.
+		whereExpr = r.decorrelateWhere(ctx, whereExpr)
+	}
+
 	f := &filterNode{source: r.source}
 	f.ivarHelper = tree.MakeIndexedVarHelper(f, len(r.sourceInfo[0].sourceColumns))
 
@@ -102,6 +102,12 @@ type scanNode struct {
 	disableBatchLimits bool
 
 	scanVisibility scanVisibility
+
+	// lockStrength and lockWaitPolicy describe the row-level locks acquired
+	// on the scanned rows, as requested by a SELECT ... FOR UPDATE clause.
+	lockStrength   tree.LockingStrength
+	lockWaitPolicy tree.LockingWaitPolicy
+
 	// This struct must be allocated on the heap and its location stay
 	// stable after construction because it implements
 	// IndexedVarContainer and the IndexedVar objects in sub-expressions
@@ -139,7 +145,11 @@ func (n *scanNode) Start(runParams) error {
 		Cols:             n.cols,
 		ValNeededForCol:  n.valNeededForCol.Copy(),
 	}
-	return n.fetcher.Init(n.reverse, false /* returnRangeInfo */, &n.p.alloc, tableArgs)
+	if err := n.fetcher.Init(n.reverse, false /* returnRangeInfo */, &n.p.alloc, tableArgs); err != nil {
+		return err
+	}
+	n.fetcher.SetLocking(n.lockStrength, n.lockWaitPolicy)
+	return nil
 }
 
 func (n *scanNode) Close(context.Context) {
@@ -151,12 +161,21 @@ func (n *scanNode) Close(context.Context) {
 func (n *scanNode) initScan(ctx context.Context) error {
 	limitHint := n.limitHint()
 	if err := n.fetcher.StartScan(ctx, n.p.txn, n.spans, !n.disableBatchLimits, limitHint, n.p.session.Tracing.KVTracingEnabled()); err != nil {
-		return err
+		return n.convertErr(err)
 	}
 	n.scanInitialized = true
 	return nil
 }
 
+// convertErr converts the KV errors returned by a NOWAIT locking scan which
+// ran into locked rows.
+func (n *scanNode) convertErr(err error) error {
+	if err == nil || n.lockWaitPolicy != tree.LockWaitError {
+		return err
+	}
+	return convertLockingError(err, n.desc.Name)
+}
+
 func (n *scanNode) limitHint() int64 {
 	var limitHint int64
 	if n.hardLimit != 0 {
@@ -188,7 +207,7 @@ func (n *scanNode) Next(params runParams) (bool, error) {
 		var err error
 		n.row, _, _, err = n.fetcher.NextRowDecoded(params.ctx)
 		if err != nil || n.row == nil {
-			return false, err
+			return false, n.convertErr(err)
 		}
 		n.p.evalCtx.IVarHelper = &n.filterVars
 		passesFilter, err := sqlbase.RunFilter(n.filter, &n.p.evalCtx)
@@ -399,6 +418,11 @@ func (n *scanNode) computePhysicalProps(
 	index *sqlbase.IndexDescriptor, exactPrefix int, reverse bool,
 ) physicalProps {
 	var pp physicalProps
+	if index.Type == sqlbase.IndexDescriptor_INVERTED {
+		// The entries of an inverted index are ordered by the paths through the
+		// documents, which doesn't order the rows.
+		return pp
+	}
 
 	columnIDs, dirs := index.FullColumnIDs()
 
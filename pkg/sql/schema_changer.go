@@ -66,6 +66,16 @@ type SchemaChanger struct {
 	distSQLPlanner *DistSQLPlanner
 	jobRegistry    *jobs.Registry
 	job            *jobs.Job
+	// dropConverted is the schema change dropping the columns replaced by
+	// the columns converted by an ALTER COLUMN ... TYPE, which is queued when
+	// the latter completes.
+	dropConverted struct {
+		mutationID sqlbase.MutationID
+		job        *jobs.Job
+		// desc is the descriptor being published by done() when it queued
+		// the mutation, whose job is yet to be created.
+		desc *sqlbase.TableDescriptor
+	}
 	// Caches updated by DistSQL.
 	rangeDescriptorCache *kv.RangeDescriptorCache
 	leaseHolderCache     *kv.LeaseHolderCache
@@ -446,6 +456,19 @@ func (sc *SchemaChanger) exec(ctx context.Context, inSession bool, evalCtx tree.
 	// Run through mutation state machine and backfill.
 	err = sc.runStateMachineAndBackfill(ctx, &lease, evalCtx, false /* isRollback */)
 
+	// Drop the columns replaced by an ALTER COLUMN ... TYPE right away, so
+	// that later schema changes on the table don't have to wait for them.
+	if err == nil && sc.dropConverted.job != nil {
+		sc.mutationID, sc.job = sc.dropConverted.mutationID, sc.dropConverted.job
+		sc.dropConverted.job = nil
+		if err := sc.job.Started(ctx); err != nil {
+			if log.V(2) {
+				log.Infof(ctx, "Failed to mark job %d as started: %v", *sc.job.ID(), err)
+			}
+		}
+		err = sc.runStateMachineAndBackfill(ctx, &lease, evalCtx, false /* isRollback */)
+	}
+
 	// Purge the mutations if the application of the mutations failed due to
 	// a permanent error. All other errors are transient errors that are
 	// resolved by retrying the backfill.
@@ -582,12 +605,17 @@ func (sc *SchemaChanger) waitToUpdateLeases(ctx context.Context, tableID sqlbase
 func (sc *SchemaChanger) done(ctx context.Context, isRollback bool) (*sqlbase.Descriptor, error) {
 	return sc.leaseMgr.Publish(ctx, sc.tableID, func(desc *sqlbase.TableDescriptor) error {
 		i := 0
+		convertedColumns := false
 		for _, mutation := range desc.Mutations {
 			if mutation.MutationID != sc.mutationID {
 				// Mutations are applied in a FIFO order. Only apply the first set of
 				// mutations if they have the mutation ID we're looking for.
 				break
 			}
+			if mutation.ColumnConversion != nil &&
+				mutation.Direction == sqlbase.DescriptorMutation_ADD {
+				convertedColumns = true
+			}
 			desc.MakeMutationComplete(mutation)
 			i++
 		}
@@ -606,8 +634,19 @@ func (sc *SchemaChanger) done(ctx context.Context, isRollback bool) (*sqlbase.De
 				break
 			}
 		}
+
+		sc.dropConverted.job, sc.dropConverted.desc = nil, nil
+		if convertedColumns {
+			sc.queueDropConvertedColumns(desc)
+		}
 		return nil
 	}, func(txn *client.Txn) error {
+		if sc.dropConverted.desc != nil {
+			if err := sc.createDropConvertedJob(ctx, txn); err != nil {
+				return err
+			}
+		}
+
 		if err := sc.job.WithTxn(txn).Succeeded(ctx); err != nil {
 			log.Warningf(ctx, "schema change ignoring error while marking job %d as successful: %+v",
 				*sc.job.ID(), err)
@@ -635,6 +674,46 @@ func (sc *SchemaChanger) done(ctx context.Context, isRollback bool) (*sqlbase.De
 	})
 }
 
+// queueDropConvertedColumns finalizes the mutation dropping the columns
+// replaced by the columns of a completed ALTER COLUMN ... TYPE, which
+// MakeMutationComplete queued. Its job is created by createDropConvertedJob
+// in the transaction publishing the descriptor, so that retries of the
+// update don't leave jobs behind.
+func (sc *SchemaChanger) queueDropConvertedColumns(desc *sqlbase.TableDescriptor) {
+	sc.dropConverted.mutationID = desc.NextMutationID
+	sc.dropConverted.desc = desc
+	desc.NextMutationID++
+}
+
+// createDropConvertedJob creates the job of the mutation queued by
+// queueDropConvertedColumns and records it in the descriptor.
+func (sc *SchemaChanger) createDropConvertedJob(ctx context.Context, txn *client.Txn) error {
+	desc, mutationID := sc.dropConverted.desc, sc.dropConverted.mutationID
+	var spanList []jobs.ResumeSpanList
+	for _, mutation := range desc.Mutations {
+		if mutation.MutationID == mutationID {
+			spanList = append(spanList, jobs.ResumeSpanList{
+				ResumeSpans: []roachpb.Span{desc.PrimaryIndexSpan()},
+			})
+		}
+	}
+	record := sc.job.Record
+	record.Description = "CLEAN UP " + record.Description
+	record.Details = jobs.SchemaChangeDetails{ResumeSpanList: spanList}
+	job := sc.jobRegistry.NewJob(record)
+	if err := job.WithTxn(txn).Created(ctx, jobs.WithoutCancel); err != nil {
+		return err
+	}
+	desc.MutationJobs = append(desc.MutationJobs, sqlbase.TableDescriptor_MutationJob{
+		MutationID: mutationID, JobID: *job.ID()})
+	// Overwrite the descriptor already written by Publish.
+	if err := txn.Put(ctx, sqlbase.MakeDescMetadataKey(desc.ID), sqlbase.WrapDescriptor(desc)); err != nil {
+		return err
+	}
+	sc.dropConverted.job = job
+	return nil
+}
+
 // notFirstInLine returns true whenever the schema change has been queued
 // up for execution after another schema change.
 func (sc *SchemaChanger) notFirstInLine(ctx context.Context) (bool, error) {
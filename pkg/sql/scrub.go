@@ -628,8 +628,12 @@ func createIndexCheckOperations(
 ) (results []checkOperation, err error) {
 	if indexNames == nil {
 		// Populate results with all secondary indexes of the
-		// table.
+		// table. The entries of inverted indexes can't be compared
+		// with the rows of the table, so they're skipped.
 		for i := range tableDesc.Indexes {
+			if tableDesc.Indexes[i].Type == sqlbase.IndexDescriptor_INVERTED {
+				continue
+			}
 			results = append(results, newIndexCheckOperation(
 				tableName,
 				tableDesc,
@@ -646,6 +650,10 @@ func createIndexCheckOperations(
 	}
 	for i := range tableDesc.Indexes {
 		if _, ok := names[tableDesc.Indexes[i].Name]; ok {
+			if tableDesc.Indexes[i].Type == sqlbase.IndexDescriptor_INVERTED {
+				return nil, pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+					"inverted index %q can't be checked", tableDesc.Indexes[i].Name)
+			}
 			results = append(results, newIndexCheckOperation(
 				tableName,
 				tableDesc,
@@ -20,6 +20,7 @@ package sql
 import (
 	"strings"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 )
@@ -38,6 +39,11 @@ type nameResolutionVisitor struct {
 	iVarHelper tree.IndexedVarHelper
 	searchPath tree.SearchPath
 
+	// outerScopes contains the data sources of the enclosing queries, when
+	// resolving names inside a subquery. Names that cannot be resolved
+	// using sources are looked up in these scopes, innermost first.
+	outerScopes []*subqueryScope
+
 	// foundDependentVars is set to true during the analysis if an
 	// expression was found which can change values between rows of the
 	// same data source, for example IndexedVars and calls to the
@@ -117,8 +123,14 @@ func (v *nameResolutionVisitor) VisitPre(expr tree.Expr) (recurse bool, newNode
 	case *tree.ColumnItem:
 		srcIdx, colIdx, err := v.sources.findColumn(t)
 		if err != nil {
-			v.err = err
-			return false, expr
+			var outer tree.Expr
+			outer, err = v.resolveOuterColumn(t, err)
+			if err != nil {
+				v.err = err
+				return false, expr
+			}
+			v.foundDependentVars = true
+			return false, outer
 		}
 		ivar := v.iVarHelper.IndexedVar(v.sources[srcIdx].colOffset + colIdx)
 		v.foundDependentVars = true
@@ -198,6 +210,45 @@ func (v *nameResolutionVisitor) VisitPre(expr tree.Expr) (recurse bool, newNode
 
 func (*nameResolutionVisitor) VisitPost(expr tree.Expr) tree.Expr { return expr }
 
+// resolveOuterColumn looks up a column reference that could not be
+// resolved in the current data sources (with error err) in the scopes of
+// the enclosing queries. The original error is returned if the column
+// cannot be found there either.
+func (v *nameResolutionVisitor) resolveOuterColumn(
+	c *tree.ColumnItem, err error,
+) (tree.Expr, error) {
+	if !isUnresolvedNameError(err) {
+		return nil, err
+	}
+	for i := len(v.outerScopes) - 1; i >= 0; i-- {
+		scope := v.outerScopes[i]
+		srcIdx, colIdx, outerErr := scope.sources.findColumn(c)
+		if outerErr != nil {
+			if isUnresolvedNameError(outerErr) {
+				continue
+			}
+			return nil, outerErr
+		}
+		idx := colIdx
+		for _, src := range scope.sources[:srcIdx] {
+			idx += len(src.sourceColumns)
+		}
+		typ := scope.sources[srcIdx].sourceColumns[colIdx].Typ
+		return scope.outerColumn(idx, typ, c), nil
+	}
+	return nil, err
+}
+
+// isUnresolvedNameError returns true if err reports a column or table
+// name that is not defined by the data sources.
+func isUnresolvedNameError(err error) bool {
+	if pgErr, ok := pgerror.GetPGCause(err); ok {
+		return pgErr.Code == pgerror.CodeUndefinedColumnError ||
+			pgErr.Code == pgerror.CodeUndefinedTableError
+	}
+	return false
+}
+
 func (s *renderNode) resolveNames(expr tree.Expr) (tree.Expr, bool, bool, error) {
 	return s.planner.resolveNames(expr, s.sourceInfo, s.ivarHelper)
 }
@@ -220,6 +271,7 @@ func (p *planner) resolveNames(
 		sources:            sources,
 		iVarHelper:         ivarHelper,
 		searchPath:         p.session.SearchPath,
+		outerScopes:        p.outerScopes,
 		foundDependentVars: false,
 	}
 	colOffset := 0
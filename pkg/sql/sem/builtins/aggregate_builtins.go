@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 )
 
@@ -131,7 +132,9 @@ var Aggregates = map[string][]tree.Builtin{
 			ReturnType:    tree.FixedReturnType(types.Int),
 			AggregateFunc: newCountRowsAggregate,
 			WindowFunc: func(params []types.T, evalCtx *tree.EvalContext) tree.WindowFunc {
-				return newAggregateWindow(newCountRowsAggregate(params, evalCtx))
+				return newAggregateWindow(func() tree.AggregateFunc {
+					return newCountRowsAggregate(params, evalCtx)
+				})
 			},
 			Info: "Calculates the number of rows.",
 		},
@@ -146,6 +149,12 @@ var Aggregates = map[string][]tree.Builtin{
 			"Identifies the minimum selected value.")
 	}, types.AnyNonArray...),
 
+	"json_agg":  {jsonAggImpl},
+	"jsonb_agg": {jsonAggImpl},
+
+	"json_object_agg":  {jsonObjectAggImpl},
+	"jsonb_object_agg": {jsonObjectAggImpl},
+
 	"sum_int": {
 		makeAggBuiltin([]types.T{types.Int}, types.Int, newSmallIntSumAggregate,
 			"Calculates the sum of the selected values."),
@@ -217,6 +226,13 @@ var Aggregates = map[string][]tree.Builtin{
 	},
 }
 
+var jsonAggImpl = makeAggBuiltin([]types.T{types.Any}, types.JSON, newJSONAggregate,
+	"Aggregates the selected values into a JSON array.")
+
+var jsonObjectAggImpl = makeAggBuiltin(
+	[]types.T{types.Any, types.Any}, types.JSON, newJSONObjectAggregate,
+	"Aggregates the selected key-value pairs into a JSON object.")
+
 func makeAggBuiltin(
 	in []types.T, ret types.T, f func([]types.T, *tree.EvalContext) tree.AggregateFunc, info string,
 ) tree.Builtin {
@@ -244,7 +260,9 @@ func makeAggBuiltinWithReturnType(
 		ReturnType:    retType,
 		AggregateFunc: f,
 		WindowFunc: func(params []types.T, evalCtx *tree.EvalContext) tree.WindowFunc {
-			return newAggregateWindow(f(params, evalCtx))
+			return newAggregateWindow(func() tree.AggregateFunc {
+				return f(params, evalCtx)
+			})
 		},
 		Info: info,
 	}
@@ -271,6 +289,15 @@ var _ tree.AggregateFunc = &identAggregate{}
 var _ tree.AggregateFunc = &concatAggregate{}
 var _ tree.AggregateFunc = &bytesXorAggregate{}
 var _ tree.AggregateFunc = &intXorAggregate{}
+var _ tree.AggregateFunc = &jsonAggregate{}
+var _ tree.AggregateFunc = &jsonObjectAggregate{}
+
+var _ removableAggregate = &avgAggregate{}
+var _ removableAggregate = &countAggregate{}
+var _ removableAggregate = &countRowsAggregate{}
+var _ removableAggregate = &smallIntSumAggregate{}
+var _ removableAggregate = &intSumAggregate{}
+var _ removableAggregate = &intervalSumAggregate{}
 
 // In order to render the unaggregated (i.e. grouped) fields, during aggregation,
 // the values for those fields have to be stored for each bucket.
@@ -350,6 +377,97 @@ func (a *arrayAggregate) Close(ctx context.Context) {
 	a.acc.Close(ctx)
 }
 
+type jsonAggregate struct {
+	builder *json.ArrayBuilder
+	sawAny  bool
+	acc     mon.BoundAccount
+}
+
+func newJSONAggregate(_ []types.T, evalCtx *tree.EvalContext) tree.AggregateFunc {
+	return &jsonAggregate{
+		builder: json.NewArrayBuilder(0),
+		acc:     evalCtx.Mon.MakeBoundAccount(),
+	}
+}
+
+// Add accumulates the passed datum into the JSON array. NULLs are added as
+// JSON nulls.
+func (a *jsonAggregate) Add(ctx context.Context, datum tree.Datum, _ ...tree.Datum) error {
+	j, err := tree.AsJSON(datum)
+	if err != nil {
+		return err
+	}
+	if err := a.acc.Grow(ctx, int64(j.Size())); err != nil {
+		return err
+	}
+	a.builder.Add(j)
+	a.sawAny = true
+	return nil
+}
+
+// Result returns a JSON array of all datums passed to Add.
+func (a *jsonAggregate) Result() (tree.Datum, error) {
+	if !a.sawAny {
+		return tree.DNull, nil
+	}
+	return &tree.DJSON{JSON: a.builder.Build()}, nil
+}
+
+// Close allows the aggregate to release the memory it requested during
+// operation.
+func (a *jsonAggregate) Close(ctx context.Context) {
+	a.acc.Close(ctx)
+}
+
+type jsonObjectAggregate struct {
+	builder *json.ObjectBuilder
+	sawAny  bool
+	acc     mon.BoundAccount
+}
+
+func newJSONObjectAggregate(_ []types.T, evalCtx *tree.EvalContext) tree.AggregateFunc {
+	return &jsonObjectAggregate{
+		builder: json.NewObjectBuilder(0),
+		acc:     evalCtx.Mon.MakeBoundAccount(),
+	}
+}
+
+// Add accumulates the pair formed by the passed key and value into the JSON
+// object.
+func (a *jsonObjectAggregate) Add(
+	ctx context.Context, key tree.Datum, others ...tree.Datum,
+) error {
+	if key == tree.DNull {
+		return pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"field name must not be null")
+	}
+	val, err := tree.AsJSON(others[0])
+	if err != nil {
+		return err
+	}
+	k := asJSONObjectKey(key)
+	if err := a.acc.Grow(ctx, int64(len(k))+int64(val.Size())); err != nil {
+		return err
+	}
+	a.builder.Add(k, val)
+	a.sawAny = true
+	return nil
+}
+
+// Result returns a JSON object of all pairs passed to Add.
+func (a *jsonObjectAggregate) Result() (tree.Datum, error) {
+	if !a.sawAny {
+		return tree.DNull, nil
+	}
+	return &tree.DJSON{JSON: a.builder.Build()}, nil
+}
+
+// Close allows the aggregate to release the memory it requested during
+// operation.
+func (a *jsonObjectAggregate) Close(ctx context.Context) {
+	a.acc.Close(ctx)
+}
+
 type avgAggregate struct {
 	agg   tree.AggregateFunc
 	count int
@@ -401,6 +519,19 @@ func (a *avgAggregate) Result() (tree.Datum, error) {
 // Close is part of the tree.AggregateFunc interface.
 func (a *avgAggregate) Close(context.Context) {}
 
+// Remove is part of the removableAggregate interface. It must only be used
+// if the underlying sum is a removableAggregate.
+func (a *avgAggregate) Remove(ctx context.Context, datum tree.Datum) error {
+	if datum == tree.DNull {
+		return nil
+	}
+	if err := a.agg.(removableAggregate).Remove(ctx, datum); err != nil {
+		return err
+	}
+	a.count--
+	return nil
+}
+
 type concatAggregate struct {
 	forBytes   bool
 	sawNonNull bool
@@ -536,6 +667,15 @@ func (a *countAggregate) Result() (tree.Datum, error) {
 // Close is part of the tree.AggregateFunc interface.
 func (a *countAggregate) Close(context.Context) {}
 
+// Remove is part of the removableAggregate interface.
+func (a *countAggregate) Remove(_ context.Context, datum tree.Datum) error {
+	if datum == tree.DNull {
+		return nil
+	}
+	a.count--
+	return nil
+}
+
 type countRowsAggregate struct {
 	count int
 }
@@ -556,6 +696,12 @@ func (a *countRowsAggregate) Result() (tree.Datum, error) {
 // Close is part of the tree.AggregateFunc interface.
 func (a *countRowsAggregate) Close(context.Context) {}
 
+// Remove is part of the removableAggregate interface.
+func (a *countRowsAggregate) Remove(context.Context, tree.Datum) error {
+	a.count--
+	return nil
+}
+
 // MaxAggregate keeps track of the largest value passed to Add.
 type MaxAggregate struct {
 	max     tree.Datum
@@ -661,6 +807,15 @@ func (a *smallIntSumAggregate) Result() (tree.Datum, error) {
 // Close is part of the tree.AggregateFunc interface.
 func (a *smallIntSumAggregate) Close(context.Context) {}
 
+// Remove subtracts the value of the passed datum from the sum.
+func (a *smallIntSumAggregate) Remove(_ context.Context, datum tree.Datum) error {
+	if datum == tree.DNull {
+		return nil
+	}
+	a.sum -= int64(tree.MustBeDInt(datum))
+	return nil
+}
+
 type intSumAggregate struct {
 	// Either the `intSum` and `decSum` fields contains the
 	// result. Which one is used is determined by the `large` field
@@ -729,6 +884,31 @@ func (a *intSumAggregate) Result() (tree.Datum, error) {
 // Close is part of the tree.AggregateFunc interface.
 func (a *intSumAggregate) Close(context.Context) {}
 
+// Remove subtracts the value of the passed datum from the sum.
+func (a *intSumAggregate) Remove(_ context.Context, datum tree.Datum) error {
+	if datum == tree.DNull {
+		return nil
+	}
+
+	t := int64(tree.MustBeDInt(datum))
+	if t != 0 {
+		if !a.large {
+			// Negating math.MinInt64 overflows, so that case also needs
+			// large integers.
+			if r, ok := tree.AddWithOverflow(a.intSum, -t); ok && t != math.MinInt64 {
+				a.intSum = r
+				return nil
+			}
+			a.large = true
+			a.decSum.SetCoefficient(a.intSum)
+		}
+		a.tmpDec.SetCoefficient(t)
+		_, err := tree.ExactCtx.Sub(&a.decSum.Decimal, &a.decSum.Decimal, &a.tmpDec)
+		return err
+	}
+	return nil
+}
+
 type decimalSumAggregate struct {
 	sum        apd.Decimal
 	sawNonNull bool
@@ -827,6 +1007,15 @@ func (a *intervalSumAggregate) Result() (tree.Datum, error) {
 // Close is part of the tree.AggregateFunc interface.
 func (a *intervalSumAggregate) Close(context.Context) {}
 
+// Remove subtracts the value of the passed datum from the sum.
+func (a *intervalSumAggregate) Remove(_ context.Context, datum tree.Datum) error {
+	if datum == tree.DNull {
+		return nil
+	}
+	a.sum = a.sum.Sub(datum.(*tree.DInterval).Duration)
+	return nil
+}
+
 // Read-only constants used for square difference computations.
 var (
 	decimalOne = apd.New(1, 0)
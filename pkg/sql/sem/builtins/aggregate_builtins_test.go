@@ -39,6 +39,7 @@ func testAggregateResultDeepCopy(
 	evalCtx := tree.NewTestingEvalContext()
 	defer evalCtx.Stop(context.Background())
 	aggImpl := aggFunc([]types.T{vals[0].ResolvedType()}, evalCtx)
+	defer aggImpl.Close(context.Background())
 	runningDatums := make([]tree.Datum, len(vals))
 	runningStrings := make([]string, len(vals))
 	for i := range vals {
@@ -162,6 +163,10 @@ func TestStdDevDecimalResultDeepCopy(t *testing.T) {
 	testAggregateResultDeepCopy(t, newDecimalStdDevAggregate, makeDecimalTestDatum(10))
 }
 
+func TestJSONAggResultDeepCopy(t *testing.T) {
+	testAggregateResultDeepCopy(t, newJSONAggregate, makeIntTestDatum(10))
+}
+
 func makeIntTestDatum(count int) []tree.Datum {
 	rng, _ := randutil.NewPseudoRand()
 
@@ -45,6 +45,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/ipaddr"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
@@ -1491,14 +1492,108 @@ CockroachDB supports the following flags:
 	},
 
 	"json_remove_path": {
-	// TODO(justin): added here so #- can be desugared into it, still needs to be
-	// implemented.
+		tree.Builtin{
+			Types:      tree.ArgTypes{{"val", types.JSON}, {"path", types.TArray{Typ: types.String}}},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				path, err := jsonPathFromDArray(args[1])
+				if err != nil {
+					return nil, err
+				}
+				j, err := json.RemovePath(tree.MustBeDJSON(args[0]).JSON, path)
+				if err != nil {
+					return nil, err
+				}
+				return &tree.DJSON{JSON: j}, nil
+			},
+			Info: "Removes the value at `path` from `val`.",
+		},
 	},
 
 	"json_typeof": {jsonTypeOfImpl},
 
 	"jsonb_typeof": {jsonTypeOfImpl},
 
+	"jsonb_set": {
+		tree.Builtin{
+			Types: tree.ArgTypes{
+				{"val", types.JSON},
+				{"path", types.TArray{Typ: types.String}},
+				{"to", types.JSON},
+			},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return jsonSet(args[0], args[1], args[2], true /* createMissing */)
+			},
+			Info: "Returns `val` with the value at `path` replaced by `to`, or added if " +
+				"it doesn't exist yet.",
+		},
+		tree.Builtin{
+			Types: tree.ArgTypes{
+				{"val", types.JSON},
+				{"path", types.TArray{Typ: types.String}},
+				{"to", types.JSON},
+				{"create_missing", types.Bool},
+			},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return jsonSet(args[0], args[1], args[2], bool(*args[3].(*tree.DBool)))
+			},
+			Info: "Returns `val` with the value at `path` replaced by `to`. If the value " +
+				"doesn't exist yet, `to` is added if `create_missing` is true.",
+		},
+	},
+
+	"jsonb_insert": {
+		tree.Builtin{
+			Types: tree.ArgTypes{
+				{"val", types.JSON},
+				{"path", types.TArray{Typ: types.String}},
+				{"to", types.JSON},
+			},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return jsonInsert(args[0], args[1], args[2], false /* after */)
+			},
+			Info: "Returns `val` with `to` inserted before the array element at `path`, " +
+				"or as the new object key at `path`.",
+		},
+		tree.Builtin{
+			Types: tree.ArgTypes{
+				{"val", types.JSON},
+				{"path", types.TArray{Typ: types.String}},
+				{"to", types.JSON},
+				{"insert_after", types.Bool},
+			},
+			ReturnType: tree.FixedReturnType(types.JSON),
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return jsonInsert(args[0], args[1], args[2], bool(*args[3].(*tree.DBool)))
+			},
+			Info: "Returns `val` with `to` inserted before the array element at `path`, " +
+				"or after it if `insert_after` is true, or as the new object key at `path`.",
+		},
+	},
+
+	"jsonb_pretty": {
+		tree.Builtin{
+			Types:      tree.ArgTypes{{"val", types.JSON}},
+			ReturnType: tree.FixedReturnType(types.String),
+			Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return tree.NewDString(json.Pretty(tree.MustBeDJSON(args[0]).JSON)), nil
+			},
+			Info: "Returns `val` as an indented text string.",
+		},
+	},
+
+	"json_build_object":  {jsonBuildObjectImpl},
+	"jsonb_build_object": {jsonBuildObjectImpl},
+
+	"json_build_array":  {jsonBuildArrayImpl},
+	"jsonb_build_array": {jsonBuildArrayImpl},
+
+	"to_json":  {toJSONImpl},
+	"to_jsonb": {toJSONImpl},
+
 	"ln": {
 		floatBuiltin1(func(x float64) (tree.Datum, error) {
 			return tree.NewDFloat(tree.DFloat(math.Log(x))), nil
@@ -2300,6 +2395,111 @@ var powImpls = []tree.Builtin{
 	},
 }
 
+var jsonBuildObjectImpl = tree.Builtin{
+	Types:        tree.VariadicType{Typ: types.Any},
+	ReturnType:   tree.FixedReturnType(types.JSON),
+	NullableArgs: true,
+	Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+		if len(args)%2 != 0 {
+			return nil, pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+				"argument list must have even number of elements")
+		}
+		builder := json.NewObjectBuilder(len(args) / 2)
+		for i := 0; i < len(args); i += 2 {
+			if args[i] == tree.DNull {
+				return nil, pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+					"argument %d cannot be null", i+1)
+			}
+			val, err := tree.AsJSON(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			builder.Add(asJSONObjectKey(args[i]), val)
+		}
+		return &tree.DJSON{JSON: builder.Build()}, nil
+	},
+	Info: "Builds a JSON object out of a variadic argument list of alternating " +
+		"keys and values.",
+}
+
+var jsonBuildArrayImpl = tree.Builtin{
+	Types:        tree.VariadicType{Typ: types.Any},
+	ReturnType:   tree.FixedReturnType(types.JSON),
+	NullableArgs: true,
+	Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+		builder := json.NewArrayBuilder(len(args))
+		for _, arg := range args {
+			j, err := tree.AsJSON(arg)
+			if err != nil {
+				return nil, err
+			}
+			builder.Add(j)
+		}
+		return &tree.DJSON{JSON: builder.Build()}, nil
+	},
+	Info: "Builds a JSON array out of a variadic argument list.",
+}
+
+var toJSONImpl = tree.Builtin{
+	Types:      tree.ArgTypes{{"val", types.Any}},
+	ReturnType: tree.FixedReturnType(types.JSON),
+	Fn: func(_ *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+		j, err := tree.AsJSON(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &tree.DJSON{JSON: j}, nil
+	},
+	Info: "Returns `val` as a JSON value.",
+}
+
+// asJSONObjectKey returns the text of d used as the key of a JSON object.
+func asJSONObjectKey(d tree.Datum) string {
+	if s, ok := tree.AsDString(d); ok {
+		return string(s)
+	}
+	return tree.AsStringWithFlags(d, tree.FmtBareStrings)
+}
+
+// jsonPathFromDArray returns the path through a JSON value designated by
+// the text array d.
+func jsonPathFromDArray(d tree.Datum) ([]string, error) {
+	arr := tree.MustBeDArray(d)
+	path := make([]string, len(arr.Array))
+	for i, elem := range arr.Array {
+		if elem == tree.DNull {
+			return nil, pgerror.NewErrorf(pgerror.CodeNullValueNotAllowedError,
+				"path element at position %d is null", i+1)
+		}
+		path[i] = string(tree.MustBeDString(elem))
+	}
+	return path, nil
+}
+
+func jsonSet(val, path, to tree.Datum, createMissing bool) (tree.Datum, error) {
+	p, err := jsonPathFromDArray(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := json.DeepSet(tree.MustBeDJSON(val).JSON, p, tree.MustBeDJSON(to).JSON, createMissing)
+	if err != nil {
+		return nil, err
+	}
+	return &tree.DJSON{JSON: j}, nil
+}
+
+func jsonInsert(val, path, to tree.Datum, after bool) (tree.Datum, error) {
+	p, err := jsonPathFromDArray(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := json.DeepInsert(tree.MustBeDJSON(val).JSON, p, tree.MustBeDJSON(to).JSON, after)
+	if err != nil {
+		return nil, err
+	}
+	return &tree.DJSON{JSON: j}, nil
+}
+
 var jsonTypeOfImpl = tree.Builtin{
 	Types:      tree.ArgTypes{{"val", types.JSON}},
 	ReturnType: tree.FixedReturnType(types.String),
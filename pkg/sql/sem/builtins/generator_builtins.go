@@ -34,6 +34,10 @@ type generatorFactory func(ctx *tree.EvalContext, args tree.Datums) (tree.ValueG
 
 var _ tree.ValueGenerator = &seriesValueGenerator{}
 var _ tree.ValueGenerator = &arrayValueGenerator{}
+var _ tree.ValueGenerator = &jsonArrayGenerator{}
+var _ tree.ValueGenerator = &jsonEachGenerator{}
+var _ tree.ValueGenerator = &emptyValueGenerator{}
+var _ tree.ValueGenerator = &zipValueGenerator{}
 
 func initGeneratorBuiltins() {
 	// Add all windows to the Builtins map after a few sanity checks.
@@ -105,6 +109,10 @@ var Generators = map[string][]tree.Builtin{
 	"jsonb_array_elements":      {jsonArrayElementsImpl},
 	"json_array_elements_text":  {jsonArrayElementsTextImpl},
 	"jsonb_array_elements_text": {jsonArrayElementsTextImpl},
+	"json_each":                 {jsonEachImpl},
+	"jsonb_each":                {jsonEachImpl},
+	"json_each_text":            {jsonEachTextImpl},
+	"jsonb_each_text":           {jsonEachTextImpl},
 }
 
 func makeGeneratorBuiltin(
@@ -306,6 +314,118 @@ func EmptyDTable() *tree.DTable {
 	return &tree.DTable{ValueGenerator: &arrayValueGenerator{array: tree.NewDArray(types.Any)}}
 }
 
+// emptyValueGenerator is a value generator of a given type that produces
+// no rows. It stands for a generator function called with NULL arguments.
+type emptyValueGenerator struct {
+	typ types.TTable
+}
+
+// EmptyGenerator returns a new tree.ValueGenerator of the given type that
+// produces no rows.
+func EmptyGenerator(typ types.TTable) tree.ValueGenerator {
+	return &emptyValueGenerator{typ: typ}
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (g *emptyValueGenerator) ResolvedType() types.TTable { return g.typ }
+
+// Start implements the tree.ValueGenerator interface.
+func (*emptyValueGenerator) Start() error { return nil }
+
+// Close implements the tree.ValueGenerator interface.
+func (*emptyValueGenerator) Close() {}
+
+// Next implements the tree.ValueGenerator interface.
+func (*emptyValueGenerator) Next() (bool, error) { return false, nil }
+
+// Values implements the tree.ValueGenerator interface.
+func (*emptyValueGenerator) Values() tree.Datums { return nil }
+
+// zipValueGenerator combines the rows of several value generators, the
+// way PostgreSQL combines the results of multiple set-returning functions
+// in the same SELECT list: the i-th row contains the values of the i-th
+// row of each generator, or NULLs for the generators that have fewer than
+// i rows. It produces rows until all the generators are exhausted.
+type zipValueGenerator struct {
+	gens []tree.ValueGenerator
+	// numCols contains the number of columns of each generator.
+	numCols []int
+	// done indicates which of the generators are exhausted.
+	done   []bool
+	typ    types.TTable
+	values tree.Datums
+}
+
+// ZipGenerators returns a new tree.ValueGenerator that zips the rows of the
+// given generators. Its rows contain the columns of all the generators.
+func ZipGenerators(gens ...tree.ValueGenerator) tree.ValueGenerator {
+	g := &zipValueGenerator{
+		gens:    gens,
+		numCols: make([]int, len(gens)),
+		done:    make([]bool, len(gens)),
+	}
+	for i, gen := range gens {
+		typ := gen.ResolvedType()
+		g.numCols[i] = len(typ.Cols)
+		g.typ.Cols = append(g.typ.Cols, typ.Cols...)
+		g.typ.Labels = append(g.typ.Labels, typ.Labels...)
+	}
+	g.values = make(tree.Datums, len(g.typ.Cols))
+	return g
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (g *zipValueGenerator) ResolvedType() types.TTable { return g.typ }
+
+// Start implements the tree.ValueGenerator interface.
+func (g *zipValueGenerator) Start() error {
+	for i, gen := range g.gens {
+		if err := gen.Start(); err != nil {
+			// Only the generators that have been started must be closed.
+			g.gens = g.gens[:i]
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (g *zipValueGenerator) Close() {
+	for _, gen := range g.gens {
+		gen.Close()
+	}
+}
+
+// Next implements the tree.ValueGenerator interface.
+func (g *zipValueGenerator) Next() (bool, error) {
+	hasRow := false
+	col := 0
+	for i, gen := range g.gens {
+		numCols := g.numCols[i]
+		if !g.done[i] {
+			next, err := gen.Next()
+			if err != nil {
+				return false, err
+			}
+			if next {
+				hasRow = true
+				copy(g.values[col:col+numCols], gen.Values())
+				col += numCols
+				continue
+			}
+			g.done[i] = true
+		}
+		for j := col; j < col+numCols; j++ {
+			g.values[j] = tree.DNull
+		}
+		col += numCols
+	}
+	return hasRow, nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (g *zipValueGenerator) Values() tree.Datums { return g.values }
+
 // unaryValueGenerator supports the execution of crdb_internal.unary_table().
 type unaryValueGenerator struct {
 	done bool
@@ -440,3 +560,95 @@ func (g *jsonArrayGenerator) Values() tree.Datums {
 		},
 	}
 }
+
+var jsonEachImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonEachGeneratorType,
+	makeJSONEachAsJSONGenerator,
+	"Expands the outermost JSON or JSONB object into a set of key/value pairs.",
+)
+
+var jsonEachTextImpl = makeGeneratorBuiltin(
+	tree.ArgTypes{{"input", types.JSON}},
+	jsonEachTextGeneratorType,
+	makeJSONEachAsTextGenerator,
+	"Expands the outermost JSON or JSONB object into a set of key/value pairs. "+
+		"The returned values will be of type text.",
+)
+
+var jsonEachGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String, types.JSON},
+	Labels: []string{"key", "value"},
+}
+
+var jsonEachTextGeneratorType = types.TTable{
+	Cols:   types.TTuple{types.String, types.String},
+	Labels: []string{"key", "value"},
+}
+
+type jsonEachGenerator struct {
+	target tree.DJSON
+	iter   *json.ObjectIterator
+	asText bool
+}
+
+var errJSONCallOnNonObject = pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+	"cannot be called on a non-object")
+
+func makeJSONEachAsJSONGenerator(_ *tree.EvalContext, args tree.Datums) (tree.ValueGenerator, error) {
+	return makeJSONEachGenerator(args, false)
+}
+
+func makeJSONEachAsTextGenerator(
+	_ *tree.EvalContext, args tree.Datums,
+) (tree.ValueGenerator, error) {
+	return makeJSONEachGenerator(args, true)
+}
+
+func makeJSONEachGenerator(args tree.Datums, asText bool) (tree.ValueGenerator, error) {
+	target := tree.MustBeDJSON(args[0])
+	if target.Type() != json.ObjectJSONType {
+		return nil, errJSONCallOnNonObject
+	}
+	return &jsonEachGenerator{
+		target: target,
+		asText: asText,
+	}, nil
+}
+
+// ResolvedType implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) ResolvedType() types.TTable {
+	if g.asText {
+		return jsonEachTextGeneratorType
+	}
+	return jsonEachGeneratorType
+}
+
+// Start implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Start() error {
+	g.iter = g.target.ObjectIter()
+	return nil
+}
+
+// Close implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Close() {}
+
+// Next implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Next() (bool, error) {
+	return g.iter.Next(), nil
+}
+
+// Values implements the tree.ValueGenerator interface.
+func (g *jsonEachGenerator) Values() tree.Datums {
+	var value tree.Datum
+	if g.asText {
+		if text := g.iter.Value().AsText(); text != nil {
+			value = tree.NewDString(*text)
+		} else {
+			value = tree.DNull
+		}
+	} else {
+		value = &tree.DJSON{JSON: g.iter.Value()}
+	}
+	return tree.Datums{tree.NewDString(g.iter.Key()), value}
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package builtins
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+)
+
+func TestZipGenerators(t *testing.T) {
+	series := func(start, stop int64) tree.ValueGenerator {
+		gen, err := makeSeriesGenerator(nil, tree.Datums{
+			tree.NewDInt(tree.DInt(start)), tree.NewDInt(tree.DInt(stop)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gen
+	}
+	strings := tree.NewDArray(types.String)
+	for _, s := range []string{"a", "b", "c", "d"} {
+		if err := strings.Append(tree.NewDString(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testData := []struct {
+		gens     []tree.ValueGenerator
+		expected []string
+	}{
+		{
+			gens:     []tree.ValueGenerator{series(1, 3)},
+			expected: []string{"(1)", "(2)", "(3)"},
+		},
+		{
+			gens:     []tree.ValueGenerator{series(1, 2), series(10, 12)},
+			expected: []string{"(1, 10)", "(2, 11)", "(NULL, 12)"},
+		},
+		{
+			gens: []tree.ValueGenerator{
+				series(1, 2), EmptyGenerator(seriesValueGeneratorType), &arrayValueGenerator{array: strings},
+			},
+			expected: []string{"(1, NULL, 'a')", "(2, NULL, 'b')", "(NULL, NULL, 'c')", "(NULL, NULL, 'd')"},
+		},
+		{
+			gens:     []tree.ValueGenerator{series(3, 1), EmptyGenerator(seriesValueGeneratorType)},
+			expected: nil,
+		},
+	}
+
+	for _, d := range testData {
+		gen := ZipGenerators(d.gens...)
+		if n := len(gen.ResolvedType().Cols); n != len(d.gens) {
+			t.Errorf("expected %d columns, got %d", len(d.gens), n)
+		}
+		if err := gen.Start(); err != nil {
+			t.Fatal(err)
+		}
+		var rows []string
+		for {
+			next, err := gen.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !next {
+				break
+			}
+			rows = append(rows, tree.NewDTuple(gen.Values()...).String())
+		}
+		gen.Close()
+		if len(rows) != len(d.expected) {
+			t.Errorf("expected %v, got %v", d.expected, rows)
+			continue
+		}
+		for i := range rows {
+			if rows[i] != d.expected[i] {
+				t.Errorf("expected %v, got %v", d.expected, rows)
+				break
+			}
+		}
+	}
+}
@@ -124,6 +124,7 @@ func mergeBuiltinSlices(s ...[]tree.Builtin) []tree.Builtin {
 }
 
 var _ tree.WindowFunc = &aggregateWindowFunc{}
+var _ tree.WindowFunc = &slidingMinMaxWindow{}
 var _ tree.WindowFunc = &rowNumberWindow{}
 var _ tree.WindowFunc = &rankWindow{}
 var _ tree.WindowFunc = &denseRankWindow{}
@@ -137,50 +138,226 @@ var _ tree.WindowFunc = &nthValueWindow{}
 
 // aggregateWindowFunc aggregates over the the current row's window frame, using
 // the internal tree.AggregateFunc to perform the aggregation.
+//
+// Window frames only ever move forward through a partition, so rows are added
+// to the aggregation as they enter the frame. If the aggregate supports the
+// removal of values, rows are also removed as they leave the frame, making the
+// computation over a whole partition linear in its size. Otherwise, the
+// aggregation is restarted whenever the start of the frame moves.
 type aggregateWindowFunc struct {
-	agg     tree.AggregateFunc
-	peerRes tree.Datum
-}
-
-func newAggregateWindow(agg tree.AggregateFunc) tree.WindowFunc {
-	return &aggregateWindowFunc{agg: agg}
+	agg       tree.AggregateFunc
+	newAgg    func() tree.AggregateFunc
+	removable removableAggregate
+
+	// The aggregation currently covers rows [start, end) of the partition, of
+	// which nonNull rows have non-NULL arguments.
+	start, end int
+	nonNull    int
+	res        tree.Datum
+	emptyRes   tree.Datum
+}
+
+// removableAggregate is implemented by aggregate functions that can undo the
+// accumulation of a value passed to Add. Removing a value must leave the
+// aggregate in the same state as if the value had never been added, which
+// rules out sums of floats (because of rounding) and of decimals (because the
+// result could have a different number of trailing zeros).
+type removableAggregate interface {
+	tree.AggregateFunc
+
+	// Remove removes a value previously passed to Add from the aggregation.
+	Remove(ctx context.Context, datum tree.Datum) error
+}
+
+// newAggregateWindow returns a tree.WindowFunc which computes the aggregate
+// function constructed by newAgg over the window frame of each row.
+func newAggregateWindow(newAgg func() tree.AggregateFunc) tree.WindowFunc {
+	agg := newAgg()
+	switch t := agg.(type) {
+	case *MinAggregate:
+		return newSlidingMinMaxWindow(t.evalCtx, 1 /* sign */)
+	case *MaxAggregate:
+		return newSlidingMinMaxWindow(t.evalCtx, -1 /* sign */)
+	}
+	w := &aggregateWindowFunc{agg: agg, newAgg: newAgg}
+	if r, ok := agg.(removableAggregate); ok {
+		w.removable = r
+		if avg, ok := agg.(*avgAggregate); ok {
+			// Averages are only removable if their sum is.
+			if _, ok := avg.agg.(removableAggregate); !ok {
+				w.removable = nil
+			}
+		}
+	}
+	return w
 }
 
 func (w *aggregateWindowFunc) Compute(
 	ctx context.Context, evalCtx *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
-	if !wf.FirstInPeerGroup() {
-		return w.peerRes, nil
-	}
-
-	// Accumulate all values in the peer group at the same time, as these
-	// must return the same value.
-	for i := 0; i < wf.PeerRowCount; i++ {
-		args := wf.ArgsWithRowOffset(i)
-		var value tree.Datum
-		// COUNT_ROWS takes no arguments.
-		if len(args) > 0 {
-			value = args[0]
+	start, err := wf.FrameStartIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	end, err := wf.FrameEndIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		end = start
+	}
+	if w.res != nil && start == w.start && end == w.end {
+		// Peers of the previous row, or rows with an identical frame, share
+		// the same result.
+		return w.res, nil
+	}
+
+	if start < w.start || end < w.end || (start > w.start && w.removable == nil) || start >= w.end {
+		// Rows need to leave the aggregation, which is only possible by
+		// starting it over.
+		if w.res != nil {
+			w.agg.Close(ctx)
+			w.agg = w.newAgg()
+			if w.removable != nil {
+				w.removable = w.agg.(removableAggregate)
+			}
 		}
-		if err := w.agg.Add(ctx, value); err != nil {
+		w.start, w.end, w.nonNull = start, start, 0
+	}
+	for ; w.start < start; w.start++ {
+		value := aggregateWindowArg(wf, w.start)
+		if err := w.removable.Remove(ctx, value); err != nil {
 			return nil, err
 		}
+		if value != tree.DNull {
+			w.nonNull--
+		}
+	}
+	for ; w.end < end; w.end++ {
+		value := aggregateWindowArg(wf, w.end)
+		if err := w.agg.Add(ctx, value, aggregateWindowOtherArgs(wf, w.end)...); err != nil {
+			return nil, err
+		}
+		if value != tree.DNull {
+			w.nonNull++
+		}
 	}
 
-	// Retrieve the value for the entire peer group, save it, and return it.
-
-	peerRes, err := w.agg.Result()
+	// Retrieve the value for the frame, save it, and return it. An
+	// aggregation from which all values have been removed may still
+	// remember having seen some, so use the result of an empty aggregation
+	// instead.
+	if w.nonNull == 0 && w.removable != nil {
+		if w.emptyRes == nil {
+			empty := w.newAgg()
+			w.emptyRes, err = empty.Result()
+			empty.Close(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		w.res = w.emptyRes
+		return w.res, nil
+	}
+	w.res, err = w.agg.Result()
 	if err != nil {
 		return nil, err
 	}
-	w.peerRes = peerRes
-	return w.peerRes, nil
+	return w.res, nil
+}
+
+// aggregateWindowArg returns the argument to the aggregate function for the
+// row at index idx of the partition.
+func aggregateWindowArg(wf tree.WindowFrame, idx int) tree.Datum {
+	args := wf.ArgsWithRowOffset(idx - wf.RowIdx)
+	// COUNT_ROWS takes no arguments.
+	if len(args) > 0 {
+		return args[0]
+	}
+	return nil
+}
+
+// aggregateWindowOtherArgs returns the arguments after the first one to the
+// aggregate function for the row at idx, if it takes several.
+func aggregateWindowOtherArgs(wf tree.WindowFrame, idx int) tree.Datums {
+	args := wf.ArgsWithRowOffset(idx - wf.RowIdx)
+	if len(args) > 1 {
+		return args[1:]
+	}
+	return nil
 }
 
 func (w *aggregateWindowFunc) Close(ctx context.Context, evalCtx *tree.EvalContext) {
 	w.agg.Close(ctx)
 }
 
+// slidingMinMaxWindow computes min or max over the current row's window frame.
+// It maintains the indexes of the rows in the frame which could still become
+// the result as the frame moves forward, in a deque ordered by their position
+// in the partition. The values of these rows are monotonic, so the result is
+// always at the front of the deque, and each row enters and leaves the deque
+// at most once per partition.
+type slidingMinMaxWindow struct {
+	evalCtx *tree.EvalContext
+	// sign is 1 for min and -1 for max.
+	sign int
+
+	deque      []int
+	start, end int
+}
+
+func newSlidingMinMaxWindow(evalCtx *tree.EvalContext, sign int) tree.WindowFunc {
+	return &slidingMinMaxWindow{evalCtx: evalCtx, sign: sign}
+}
+
+func (w *slidingMinMaxWindow) Compute(
+	_ context.Context, evalCtx *tree.EvalContext, wf tree.WindowFrame,
+) (tree.Datum, error) {
+	start, err := wf.FrameStartIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	end, err := wf.FrameEndIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		end = start
+	}
+
+	if start < w.start || end < w.end || start >= w.end {
+		w.deque = w.deque[:0]
+		w.start, w.end = start, start
+	}
+	w.start = start
+	for len(w.deque) > 0 && w.deque[0] < start {
+		w.deque = w.deque[1:]
+	}
+	for ; w.end < end; w.end++ {
+		value := aggregateWindowArg(wf, w.end)
+		if value == tree.DNull {
+			continue
+		}
+		// Rows in the frame whose value is not better than that of the new row
+		// can never become the result again.
+		for n := len(w.deque); n > 0; n-- {
+			last := aggregateWindowArg(wf, w.deque[n-1])
+			if last.Compare(w.evalCtx, value)*w.sign < 0 {
+				break
+			}
+			w.deque = w.deque[:n-1]
+		}
+		w.deque = append(w.deque, w.end)
+	}
+
+	if len(w.deque) == 0 {
+		return tree.DNull, nil
+	}
+	return aggregateWindowArg(wf, w.deque[0]), nil
+}
+
+func (w *slidingMinMaxWindow) Close(context.Context, *tree.EvalContext) {}
+
 // rowNumberWindow computes the number of the current row within its partition,
 // counting from 1.
 type rowNumberWindow struct{}
@@ -283,7 +460,7 @@ func (w *cumulativeDistWindow) Compute(
 ) (tree.Datum, error) {
 	if wf.FirstInPeerGroup() {
 		// (number of rows preceding or peer with current row) / (total rows)
-		w.peerRes = tree.NewDFloat(tree.DFloat(wf.FrameSize()) / tree.DFloat(wf.RowCount()))
+		w.peerRes = tree.NewDFloat(tree.DFloat(wf.DefaultFrameSize()) / tree.DFloat(wf.RowCount()))
 	}
 	return w.peerRes, nil
 }
@@ -413,9 +590,21 @@ func newFirstValueWindow([]types.T, *tree.EvalContext) tree.WindowFunc {
 }
 
 func (firstValueWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	_ context.Context, evalCtx *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
-	return wf.Rows[0].Row[wf.ArgIdxStart], nil
+	start, err := wf.FrameStartIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	end, err := wf.FrameEndIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if start >= end {
+		// The frame is empty.
+		return tree.DNull, nil
+	}
+	return wf.Rows[start].Row[wf.ArgIdxStart], nil
 }
 
 func (firstValueWindow) Close(context.Context, *tree.EvalContext) {}
@@ -428,9 +617,21 @@ func newLastValueWindow([]types.T, *tree.EvalContext) tree.WindowFunc {
 }
 
 func (lastValueWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	_ context.Context, evalCtx *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
-	return wf.Rows[wf.FrameSize()-1].Row[wf.ArgIdxStart], nil
+	start, err := wf.FrameStartIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	end, err := wf.FrameEndIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if start >= end {
+		// The frame is empty.
+		return tree.DNull, nil
+	}
+	return wf.Rows[end-1].Row[wf.ArgIdxStart], nil
 }
 
 func (lastValueWindow) Close(context.Context, *tree.EvalContext) {}
@@ -447,7 +648,7 @@ var errInvalidArgumentForNthValue = pgerror.NewErrorf(
 	pgerror.CodeInvalidParameterValueError, "argument of nth_value() must be greater than zero")
 
 func (nthValueWindow) Compute(
-	_ context.Context, _ *tree.EvalContext, wf tree.WindowFrame,
+	_ context.Context, evalCtx *tree.EvalContext, wf tree.WindowFrame,
 ) (tree.Datum, error) {
 	arg := wf.Args()[1]
 	if arg == tree.DNull {
@@ -461,10 +662,18 @@ func (nthValueWindow) Compute(
 
 	// per spec: Only consider the rows within the "window frame", which by default contains
 	// the rows from the start of the partition through the last peer of the current row.
-	if nth > wf.FrameSize() {
+	start, err := wf.FrameStartIdx(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	frameSize, err := wf.FrameSize(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if nth > frameSize {
 		return tree.DNull, nil
 	}
-	return wf.Rows[nth-1].Row[wf.ArgIdxStart], nil
+	return wf.Rows[start+nth-1].Row[wf.ArgIdxStart], nil
 }
 
 func (nthValueWindow) Close(context.Context, *tree.EvalContext) {}
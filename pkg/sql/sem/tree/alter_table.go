@@ -17,6 +17,8 @@ package tree
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/coltypes"
 )
 
 // AlterTable represents an ALTER TABLE statement.
@@ -60,18 +62,22 @@ type AlterTableCmd interface {
 
 func (*AlterTableAddColumn) alterTableCmd()          {}
 func (*AlterTableAddConstraint) alterTableCmd()      {}
+func (*AlterTableAlterColumnType) alterTableCmd()    {}
 func (*AlterTableDropColumn) alterTableCmd()         {}
 func (*AlterTableDropConstraint) alterTableCmd()     {}
 func (*AlterTableDropNotNull) alterTableCmd()        {}
 func (*AlterTableSetDefault) alterTableCmd()         {}
+func (*AlterTableSetNotNull) alterTableCmd()         {}
 func (*AlterTableValidateConstraint) alterTableCmd() {}
 
 var _ AlterTableCmd = &AlterTableAddColumn{}
 var _ AlterTableCmd = &AlterTableAddConstraint{}
+var _ AlterTableCmd = &AlterTableAlterColumnType{}
 var _ AlterTableCmd = &AlterTableDropColumn{}
 var _ AlterTableCmd = &AlterTableDropConstraint{}
 var _ AlterTableCmd = &AlterTableDropNotNull{}
 var _ AlterTableCmd = &AlterTableSetDefault{}
+var _ AlterTableCmd = &AlterTableSetNotNull{}
 var _ AlterTableCmd = &AlterTableValidateConstraint{}
 
 // ColumnMutationCmd is the subset of AlterTableCmds that modify an
@@ -178,6 +184,36 @@ func (node *AlterTableValidateConstraint) Format(buf *bytes.Buffer, f FmtFlags)
 	FormatNode(buf, f, node.Constraint)
 }
 
+// AlterTableAlterColumnType represents an ALTER COLUMN ... TYPE command.
+type AlterTableAlterColumnType struct {
+	ColumnKeyword bool
+	Column        Name
+	ToType        coltypes.T
+	// Using is the optional USING expression computing the new values of
+	// the column from the old ones.
+	Using Expr
+}
+
+// GetColumn implements the ColumnMutationCmd interface.
+func (node *AlterTableAlterColumnType) GetColumn() Name {
+	return node.Column
+}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterTableAlterColumnType) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER ")
+	if node.ColumnKeyword {
+		buf.WriteString("COLUMN ")
+	}
+	FormatNode(buf, f, node.Column)
+	buf.WriteString(" SET DATA TYPE ")
+	node.ToType.Format(buf, f.encodeFlags)
+	if node.Using != nil {
+		buf.WriteString(" USING ")
+		FormatNode(buf, f, node.Using)
+	}
+}
+
 // AlterTableSetDefault represents an ALTER COLUMN SET DEFAULT
 // or DROP DEFAULT command.
 type AlterTableSetDefault struct {
@@ -227,3 +263,25 @@ func (node *AlterTableDropNotNull) Format(buf *bytes.Buffer, f FmtFlags) {
 	FormatNode(buf, f, node.Column)
 	buf.WriteString(" DROP NOT NULL")
 }
+
+// AlterTableSetNotNull represents an ALTER COLUMN SET NOT NULL
+// command.
+type AlterTableSetNotNull struct {
+	ColumnKeyword bool
+	Column        Name
+}
+
+// GetColumn implements the ColumnMutationCmd interface.
+func (node *AlterTableSetNotNull) GetColumn() Name {
+	return node.Column
+}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterTableSetNotNull) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER ")
+	if node.ColumnKeyword {
+		buf.WriteString("COLUMN ")
+	}
+	FormatNode(buf, f, node.Column)
+	buf.WriteString(" SET NOT NULL")
+}
@@ -71,15 +71,27 @@ func (node *CreateDatabase) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
-// IndexElem represents a column with a direction in a CREATE INDEX statement.
+// IndexElem represents a column or an expression with a direction in a
+// CREATE INDEX statement.
 type IndexElem struct {
-	Column    Name
+	Column Name
+	// Expr is set instead of Column for an element indexing an expression.
+	Expr      Expr
 	Direction Direction
 }
 
 // Format implements the NodeFormatter interface.
 func (node IndexElem) Format(buf *bytes.Buffer, f FmtFlags) {
-	FormatNode(buf, f, node.Column)
+	switch node.Expr.(type) {
+	case nil:
+		FormatNode(buf, f, node.Column)
+	case *FuncExpr:
+		FormatNode(buf, f, node.Expr)
+	default:
+		buf.WriteByte('(')
+		FormatNode(buf, f, node.Expr)
+		buf.WriteByte(')')
+	}
 	if node.Direction != DefaultDirection {
 		buf.WriteByte(' ')
 		buf.WriteString(node.Direction.String())
@@ -105,6 +117,7 @@ type CreateIndex struct {
 	Name        Name
 	Table       NormalizableTableName
 	Unique      bool
+	Inverted    bool
 	IfNotExists bool
 	Columns     IndexElemList
 	// Extra columns to be stored together with the indexed ones as an optimization
@@ -120,6 +133,9 @@ func (node *CreateIndex) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Unique {
 		buf.WriteString("UNIQUE ")
 	}
+	if node.Inverted {
+		buf.WriteString("INVERTED ")
+	}
 	buf.WriteString("INDEX ")
 	if node.IfNotExists {
 		buf.WriteString("IF NOT EXISTS ")
@@ -474,6 +490,7 @@ type IndexTableDef struct {
 	Columns     IndexElemList
 	Storing     NameList
 	Interleave  *InterleaveDef
+	Inverted    bool
 	PartitionBy *PartitionBy
 }
 
@@ -484,6 +501,9 @@ func (node *IndexTableDef) SetName(name Name) {
 
 // Format implements the NodeFormatter interface.
 func (node *IndexTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Inverted {
+		buf.WriteString("INVERTED ")
+	}
 	buf.WriteString("INDEX ")
 	if node.Name != "" {
 		FormatNode(buf, f, node.Name)
@@ -794,6 +814,7 @@ func (node RangePartition) Format(buf *bytes.Buffer, f FmtFlags) {
 // CreateTable represents a CREATE TABLE statement.
 type CreateTable struct {
 	IfNotExists   bool
+	Temporary     bool
 	Table         NormalizableTableName
 	Interleave    *InterleaveDef
 	PartitionBy   *PartitionBy
@@ -810,7 +831,11 @@ func (node *CreateTable) As() bool {
 
 // Format implements the NodeFormatter interface.
 func (node *CreateTable) Format(buf *bytes.Buffer, f FmtFlags) {
-	buf.WriteString("CREATE TABLE ")
+	buf.WriteString("CREATE ")
+	if node.Temporary {
+		buf.WriteString("TEMPORARY ")
+	}
+	buf.WriteString("TABLE ")
 	if node.IfNotExists {
 		buf.WriteString("IF NOT EXISTS ")
 	}
@@ -913,6 +938,27 @@ const (
 	SeqOptCycle     = "CYCLE"
 )
 
+// CreateStats represents a CREATE STATISTICS statement.
+type CreateStats struct {
+	Name Name
+	// ColumnNames is empty if statistics are to be collected on a default
+	// set of columns.
+	ColumnNames NameList
+	Table       NormalizableTableName
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateStats) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE STATISTICS ")
+	FormatNode(buf, f, node.Name)
+	if len(node.ColumnNames) > 0 {
+		buf.WriteString(" ON ")
+		FormatNode(buf, f, node.ColumnNames)
+	}
+	buf.WriteString(" FROM ")
+	FormatNode(buf, f, &node.Table)
+}
+
 // CreateUser represents a CREATE USER statement.
 type CreateUser struct {
 	Name        Expr
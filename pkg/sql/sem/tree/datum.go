@@ -2088,6 +2088,58 @@ func AsDJSON(e Expr) (*DJSON, bool) {
 	return nil, false
 }
 
+// AsJSON converts a datum into a JSON value, as by the to_json function.
+// Booleans, numbers, strings, arrays and JSON values map to the same JSON
+// values, tuples to objects with the keys f1, f2, ..., and other datums to
+// JSON strings holding their text representation.
+func AsJSON(d Datum) (json.JSON, error) {
+	switch t := d.(type) {
+	case dNull:
+		return json.NullJSONValue, nil
+	case *DBool:
+		return json.FromBool(bool(*t)), nil
+	case *DInt:
+		return json.FromInt64(int64(*t)), nil
+	case *DFloat:
+		if f := float64(*t); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return json.FromFloat64(f)
+		}
+	case *DDecimal:
+		if t.Form == apd.Finite {
+			return json.FromDecimal(t.Decimal), nil
+		}
+	case *DString:
+		return json.FromString(string(*t)), nil
+	case *DCollatedString:
+		return json.FromString(t.Contents), nil
+	case *DJSON:
+		return t.JSON, nil
+	case *DArray:
+		builder := json.NewArrayBuilder(t.Len())
+		for _, e := range t.Array {
+			j, err := AsJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			builder.Add(j)
+		}
+		return builder.Build(), nil
+	case *DTuple:
+		builder := json.NewObjectBuilder(len(t.D))
+		for i, e := range t.D {
+			j, err := AsJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			builder.Add(fmt.Sprintf("f%d", i+1), j)
+		}
+		return builder.Build(), nil
+	case *DOidWrapper:
+		return AsJSON(t.Wrapped)
+	}
+	return json.FromString(AsStringWithFlags(d, FmtBareStrings)), nil
+}
+
 // MustBeDJSON attempts to retrieve a DJSON from an Expr, panicking if the
 // assertion fails.
 func MustBeDJSON(e Expr) DJSON {
@@ -775,6 +775,24 @@ var BinOps = map[BinaryOperator]binOpOverload{
 				return &DJSON{j}, nil
 			},
 		},
+		BinOp{
+			LeftType:   types.JSON,
+			RightType:  types.TArray{Typ: types.String},
+			ReturnType: types.JSON,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				j := left.(*DJSON).JSON
+				for _, k := range MustBeDArray(right).Array {
+					if k == DNull {
+						continue
+					}
+					var err error
+					if j, err = j.RemoveKey(string(MustBeDString(k))); err != nil {
+						return nil, err
+					}
+				}
+				return &DJSON{j}, nil
+			},
+		},
 	},
 
 	Mult: {
@@ -1107,6 +1125,14 @@ var BinOps = map[BinaryOperator]binOpOverload{
 				return NewDBytes(*left.(*DBytes) + *right.(*DBytes)), nil
 			},
 		},
+		BinOp{
+			LeftType:   types.JSON,
+			RightType:  types.JSON,
+			ReturnType: types.JSON,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				return &DJSON{json.Concat(left.(*DJSON).JSON, right.(*DJSON).JSON)}, nil
+			},
+		},
 	},
 
 	// TODO(pmattis): Check that the shift is valid.
@@ -41,16 +41,20 @@ func (*ValuesClause) selectStatement() {}
 
 // Select represents a SelectStatement with an ORDER and/or LIMIT.
 type Select struct {
+	With    *With
 	Select  SelectStatement
 	OrderBy OrderBy
 	Limit   *Limit
+	Locking LockingClause
 }
 
 // Format implements the NodeFormatter interface.
 func (node *Select) Format(buf *bytes.Buffer, f FmtFlags) {
+	FormatNode(buf, f, node.With)
 	FormatNode(buf, f, node.Select)
 	FormatNode(buf, f, node.OrderBy)
 	FormatNode(buf, f, node.Limit)
+	FormatNode(buf, f, node.Locking)
 }
 
 // ParenSelect represents a parenthesized SELECT/UNION/VALUES statement.
@@ -65,6 +69,41 @@ func (node *ParenSelect) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteByte(')')
 }
 
+// With represents a WITH clause, which defines one or more common table
+// expressions (CTEs) that can be referenced by name in the statement that
+// follows it.
+type With struct {
+	Recursive bool
+	CTEList   []*CTE
+}
+
+// CTE represents a common table expression inside of a WITH clause.
+type CTE struct {
+	Name AliasClause
+	Stmt Statement
+}
+
+// Format implements the NodeFormatter interface.
+func (node *With) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node == nil {
+		return
+	}
+	buf.WriteString("WITH ")
+	if node.Recursive {
+		buf.WriteString("RECURSIVE ")
+	}
+	for i, cte := range node.CTEList {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, cte.Name)
+		buf.WriteString(" AS (")
+		FormatNode(buf, f, cte.Stmt)
+		buf.WriteByte(')')
+	}
+	buf.WriteByte(' ')
+}
+
 // SelectClause represents a SELECT statement.
 type SelectClause struct {
 	Distinct    bool
@@ -274,11 +313,17 @@ type AliasedTableExpr struct {
 	Expr       TableExpr
 	Hints      *IndexHints
 	Ordinality bool
-	As         AliasClause
+	// Lateral is set for LATERAL data sources, which can refer to the
+	// columns of the data sources that precede them in the FROM clause.
+	Lateral bool
+	As      AliasClause
 }
 
 // Format implements the NodeFormatter interface.
 func (node *AliasedTableExpr) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Lateral {
+		buf.WriteString("LATERAL ")
+	}
 	FormatNode(buf, f, node.Expr)
 	if node.Hints != nil {
 		FormatNode(buf, f, node.Hints)
@@ -528,6 +573,75 @@ func (node *Limit) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
+// LockingStrength represents the strength of the row-level locks
+// requested by a locking clause.
+type LockingStrength byte
+
+// The values of LockingStrength, ordered from weakest to strongest.
+const (
+	ForNone LockingStrength = iota
+	ForKeyShare
+	ForShare
+	ForNoKeyUpdate
+	ForUpdate
+)
+
+var lockingStrengthName = [...]string{
+	ForNone:        "",
+	ForKeyShare:    "FOR KEY SHARE",
+	ForShare:       "FOR SHARE",
+	ForNoKeyUpdate: "FOR NO KEY UPDATE",
+	ForUpdate:      "FOR UPDATE",
+}
+
+func (s LockingStrength) String() string {
+	return lockingStrengthName[s]
+}
+
+// LockingWaitPolicy represents what a locking clause does when a row
+// it tries to lock is already locked by another transaction.
+type LockingWaitPolicy byte
+
+// The values of LockingWaitPolicy.
+const (
+	// LockWaitBlock waits for the conflicting lock to be released.
+	LockWaitBlock LockingWaitPolicy = iota
+	// LockWaitSkip skips rows which can't be locked (SKIP LOCKED).
+	LockWaitSkip
+	// LockWaitError returns an error for rows which can't be locked
+	// (NOWAIT).
+	LockWaitError
+)
+
+var lockingWaitPolicyName = [...]string{
+	LockWaitBlock: "",
+	LockWaitSkip:  "SKIP LOCKED",
+	LockWaitError: "NOWAIT",
+}
+
+func (p LockingWaitPolicy) String() string {
+	return lockingWaitPolicyName[p]
+}
+
+// LockingClause represents a locking clause, like FOR UPDATE.
+type LockingClause struct {
+	Strength   LockingStrength
+	WaitPolicy LockingWaitPolicy
+}
+
+// Format implements the NodeFormatter interface.
+func (node LockingClause) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Strength == ForNone {
+		return
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(node.Strength.String())
+	if node.WaitPolicy != LockWaitBlock {
+		buf.WriteByte(' ')
+		buf.WriteString(node.WaitPolicy.String())
+	}
+}
+
 // Window represents a WINDOW clause.
 type Window []*WindowDef
 
@@ -549,6 +663,7 @@ type WindowDef struct {
 	RefName    Name
 	Partitions Exprs
 	OrderBy    OrderBy
+	Frame      *WindowFrameSpec
 }
 
 // Format implements the NodeFormatter interface.
@@ -577,9 +692,105 @@ func (node *WindowDef) Format(buf *bytes.Buffer, f FmtFlags) {
 			buf.WriteString(tmpBuf.String()[1:])
 		}
 		needSpaceSeparator = true
-		_ = needSpaceSeparator // avoid compiler warning until TODO below is addressed.
 	}
-	// TODO(nvanbenschoten): Support Window Frames.
-	// if node.Frame != nil {}
+	if node.Frame != nil {
+		if needSpaceSeparator {
+			buf.WriteRune(' ')
+		}
+		FormatNode(buf, f, node.Frame)
+	}
 	buf.WriteRune(')')
 }
+
+// WindowFrameMode indicates which mode of framing is used.
+type WindowFrameMode int
+
+const (
+	// RANGE is the mode of specifying frame in terms of logical range (e.g. 100 units cheaper).
+	RANGE WindowFrameMode = iota
+	// ROWS is the mode of specifying frame in terms of physical offsets (e.g. 1 row before etc).
+	ROWS
+)
+
+var windowFrameModeName = [...]string{
+	RANGE: "RANGE",
+	ROWS:  "ROWS",
+}
+
+func (m WindowFrameMode) String() string {
+	return windowFrameModeName[m]
+}
+
+// WindowFrameBoundType indicates which type of boundary is used.
+type WindowFrameBoundType int
+
+const (
+	// UnboundedPreceding represents UNBOUNDED PRECEDING type of boundary.
+	UnboundedPreceding WindowFrameBoundType = iota
+	// ValuePreceding represents 'value' PRECEDING type of boundary.
+	ValuePreceding
+	// CurrentRow represents CURRENT ROW type of boundary.
+	CurrentRow
+	// ValueFollowing represents 'value' FOLLOWING type of boundary.
+	ValueFollowing
+	// UnboundedFollowing represents UNBOUNDED FOLLOWING type of boundary.
+	UnboundedFollowing
+)
+
+// WindowFrameBound specifies the offset and the type of boundary.
+type WindowFrameBound struct {
+	BoundType  WindowFrameBoundType
+	OffsetExpr Expr
+}
+
+// HasOffset returns whether node contains an offset.
+func (node *WindowFrameBound) HasOffset() bool {
+	return node.BoundType == ValuePreceding || node.BoundType == ValueFollowing
+}
+
+// Format implements the NodeFormatter interface.
+func (node *WindowFrameBound) Format(buf *bytes.Buffer, f FmtFlags) {
+	switch node.BoundType {
+	case UnboundedPreceding:
+		buf.WriteString("UNBOUNDED PRECEDING")
+	case ValuePreceding:
+		FormatNode(buf, f, node.OffsetExpr)
+		buf.WriteString(" PRECEDING")
+	case CurrentRow:
+		buf.WriteString("CURRENT ROW")
+	case ValueFollowing:
+		FormatNode(buf, f, node.OffsetExpr)
+		buf.WriteString(" FOLLOWING")
+	case UnboundedFollowing:
+		buf.WriteString("UNBOUNDED FOLLOWING")
+	default:
+		panic(fmt.Sprintf("unhandled case: %d", node.BoundType))
+	}
+}
+
+// WindowFrameBounds specifies boundaries of the window frame. EndBound is nil when only the frame start was specified, in which case
+// the frame ends at the current row.
+type WindowFrameBounds struct {
+	StartBound *WindowFrameBound
+	EndBound   *WindowFrameBound
+}
+
+// WindowFrameSpec represents the frame clause of a window definition.
+type WindowFrameSpec struct {
+	Mode   WindowFrameMode
+	Bounds WindowFrameBounds
+}
+
+// Format implements the NodeFormatter interface.
+func (node *WindowFrameSpec) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString(node.Mode.String())
+	buf.WriteRune(' ')
+	if node.Bounds.EndBound != nil {
+		buf.WriteString("BETWEEN ")
+		FormatNode(buf, f, node.Bounds.StartBound)
+		buf.WriteString(" AND ")
+		FormatNode(buf, f, node.Bounds.EndBound)
+	} else {
+		FormatNode(buf, f, node.Bounds.StartBound)
+	}
+}
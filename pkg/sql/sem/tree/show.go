@@ -211,6 +211,27 @@ func (node *ShowCreateTable) Format(buf *bytes.Buffer, f FmtFlags) {
 	FormatNode(buf, f, &node.Table)
 }
 
+// ShowTableStats represents a SHOW STATISTICS FOR TABLE statement.
+type ShowTableStats struct {
+	Table NormalizableTableName
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowTableStats) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW STATISTICS FOR TABLE ")
+	FormatNode(buf, f, &node.Table)
+}
+
+// ShowHistogram represents a SHOW HISTOGRAM statement.
+type ShowHistogram struct {
+	HistogramID int64
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowHistogram) Format(buf *bytes.Buffer, f FmtFlags) {
+	fmt.Fprintf(buf, "SHOW HISTOGRAM %d", node.HistogramID)
+}
+
 // ShowCreateView represents a SHOW CREATE VIEW statement.
 type ShowCreateView struct {
 	View NormalizableTableName
@@ -525,6 +525,32 @@ func (expr *FuncExpr) TypeCheck(ctx *SemaContext, desired types.T) (TypedExpr, e
 			}
 			expr.WindowDef.OrderBy[i].Expr = typedOrderBy
 		}
+		if frame := expr.WindowDef.Frame; frame != nil {
+			// Offsets of ROWS frames are row counts. Offsets of RANGE frames are
+			// added to or subtracted from the value of the ORDER BY column, so
+			// they are typed accordingly; the planner reports a proper error if
+			// there is not exactly one such column.
+			desired := types.Int
+			if frame.Mode == RANGE {
+				desired = types.Any
+				if len(expr.WindowDef.OrderBy) == 1 {
+					ordType := expr.WindowDef.OrderBy[0].Expr.(TypedExpr).ResolvedType()
+					if offsetType := RangeOffsetType(ordType); offsetType != nil {
+						desired = offsetType
+					}
+				}
+			}
+			for _, bound := range []*WindowFrameBound{frame.Bounds.StartBound, frame.Bounds.EndBound} {
+				if bound == nil || !bound.HasOffset() {
+					continue
+				}
+				typedOffset, err := bound.OffsetExpr.TypeCheck(ctx, desired)
+				if err != nil {
+					return nil, err
+				}
+				bound.OffsetExpr = typedOffset
+			}
+		}
 	}
 
 	if expr.Filter != nil {
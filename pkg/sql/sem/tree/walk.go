@@ -211,6 +211,18 @@ func (expr *FuncExpr) CopyNode() *FuncExpr {
 			}
 			windowDef.OrderBy = newOrderBy
 		}
+		if windowDef.Frame != nil {
+			frameCopy := *windowDef.Frame
+			if b := frameCopy.Bounds.StartBound; b != nil {
+				startBoundCopy := *b
+				frameCopy.Bounds.StartBound = &startBoundCopy
+			}
+			if b := frameCopy.Bounds.EndBound; b != nil {
+				endBoundCopy := *b
+				frameCopy.Bounds.EndBound = &endBoundCopy
+			}
+			windowDef.Frame = &frameCopy
+		}
 	}
 	return &exprCopy
 }
@@ -249,6 +261,26 @@ func (expr *FuncExpr) Walk(v Visitor) Expr {
 				ret.WindowDef.OrderBy[i].Expr = e
 			}
 		}
+		if frame := expr.WindowDef.Frame; frame != nil {
+			if b := frame.Bounds.StartBound; b != nil && b.HasOffset() {
+				e, changed := WalkExpr(v, b.OffsetExpr)
+				if changed {
+					if ret == expr {
+						ret = expr.CopyNode()
+					}
+					ret.WindowDef.Frame.Bounds.StartBound.OffsetExpr = e
+				}
+			}
+			if b := frame.Bounds.EndBound; b != nil && b.HasOffset() {
+				e, changed := WalkExpr(v, b.OffsetExpr)
+				if changed {
+					if ret == expr {
+						ret = expr.CopyNode()
+					}
+					ret.WindowDef.Frame.Bounds.EndBound.OffsetExpr = e
+				}
+			}
+		}
 	}
 	if expr.Filter != nil {
 		e, changed := WalkExpr(v, expr.Filter)
@@ -681,6 +713,37 @@ func (stmt *Backup) WalkStmt(v Visitor) Statement {
 	return ret
 }
 
+// CopyNode makes a copy of this Statement without recursing in any child Statements.
+func (stmt *CreateChangefeed) CopyNode() *CreateChangefeed {
+	stmtCopy := *stmt
+	stmtCopy.Options = append(KVOptions(nil), stmt.Options...)
+	return &stmtCopy
+}
+
+// WalkStmt is part of the WalkableStmt interface.
+func (stmt *CreateChangefeed) WalkStmt(v Visitor) Statement {
+	ret := stmt
+	if stmt.SinkURI != nil {
+		e, changed := WalkExpr(v, stmt.SinkURI)
+		if changed {
+			if ret == stmt {
+				ret = stmt.CopyNode()
+			}
+			ret.SinkURI = e
+		}
+	}
+	{
+		opts, changed := walkKVOptions(v, stmt.Options)
+		if changed {
+			if ret == stmt {
+				ret = stmt.CopyNode()
+			}
+			ret.Options = opts
+		}
+	}
+	return ret
+}
+
 // CopyNode makes a copy of this Statement without recursing in any child Statements.
 func (stmt *Delete) CopyNode() *Delete {
 	stmtCopy := *stmt
@@ -694,10 +757,16 @@ func (stmt *Delete) CopyNode() *Delete {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Delete) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	if stmt.Where != nil {
 		e, changed := WalkExpr(v, stmt.Where.Expr)
 		if changed {
-			ret = stmt.CopyNode()
+			if ret == stmt {
+				ret = stmt.CopyNode()
+			}
 			ret.Where.Expr = e
 		}
 	}
@@ -737,10 +806,16 @@ func (stmt *Insert) CopyNode() *Insert {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Insert) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	if stmt.Rows != nil {
 		rows, changed := WalkStmt(v, stmt.Rows)
 		if changed {
-			ret = stmt.CopyNode()
+			if ret == stmt {
+				ret = stmt.CopyNode()
+			}
 			ret.Rows = rows.(*Select)
 		}
 	}
@@ -871,6 +946,28 @@ func walkOrderBy(v Visitor, order OrderBy) (OrderBy, bool) {
 	return order, copied
 }
 
+// walkWith walks the statements of the common table expressions in a WITH
+// clause. The WITH clause is copied if any of its statements changed.
+func walkWith(v Visitor, with *With) (*With, bool) {
+	if with == nil {
+		return nil, false
+	}
+	ret := with
+	for i, cte := range with.CTEList {
+		stmt, changed := WalkStmt(v, cte.Stmt)
+		if changed {
+			if ret == with {
+				ret = &With{
+					Recursive: with.Recursive,
+					CTEList:   append([]*CTE(nil), with.CTEList...),
+				}
+			}
+			ret.CTEList[i] = &CTE{Name: cte.Name, Stmt: stmt}
+		}
+	}
+	return ret, ret != with
+}
+
 // CopyNode makes a copy of this Statement without recursing in any child Statements.
 func (stmt *Select) CopyNode() *Select {
 	stmtCopy := *stmt
@@ -884,9 +981,15 @@ func (stmt *Select) CopyNode() *Select {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Select) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	sel, changed := WalkStmt(v, stmt.Select)
 	if changed {
-		ret = stmt.CopyNode()
+		if ret == stmt {
+			ret = stmt.CopyNode()
+		}
 		ret.Select = sel.(SelectStatement)
 	}
 	order, changed := walkOrderBy(v, stmt.OrderBy)
@@ -1075,6 +1178,10 @@ func (stmt *Update) CopyNode() *Update {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Update) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	for i, expr := range stmt.Exprs {
 		e, changed := WalkExpr(v, expr.Expr)
 		if changed {
@@ -1121,6 +1228,7 @@ func (stmt *ValuesClause) WalkStmt(v Visitor) Statement {
 }
 
 var _ WalkableStmt = &Backup{}
+var _ WalkableStmt = &CreateChangefeed{}
 var _ WalkableStmt = &Delete{}
 var _ WalkableStmt = &Explain{}
 var _ WalkableStmt = &Insert{}
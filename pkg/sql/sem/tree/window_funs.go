@@ -14,7 +14,15 @@
 
 package tree
 
-import "golang.org/x/net/context"
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+)
 
 // IndexedRow is a row with a corresponding index.
 type IndexedRow struct {
@@ -29,6 +37,14 @@ type WindowFrame struct {
 	ArgIdxStart int // the index which arguments to the window function begin
 	ArgCount    int // the number of window function arguments
 
+	// Frame is the frame clause of the window definition. If nil, the default
+	// frame (RANGE UNBOUNDED PRECEDING) is used.
+	Frame            *WindowFrameSpec
+	StartBoundOffset Datum     // the evaluated offset of the frame start, if any
+	EndBoundOffset   Datum     // the evaluated offset of the frame end, if any
+	OrdColIdx        int       // the index of the ORDER BY column, for RANGE offsets
+	OrdDirection     Direction // the direction of the ORDER BY column, for RANGE offsets
+
 	// changes for each row (each call to WindowFunc.Add)
 	RowIdx int // the current row index
 
@@ -47,13 +63,181 @@ func (wf WindowFrame) RowCount() int {
 	return len(wf.Rows)
 }
 
-// FrameSize returns the size of this frame.
-// TODO(nvanbenschoten): This definition only holds while we don't support
-// frame specification (RANGE or ROWS) in the OVER clause.
-func (wf WindowFrame) FrameSize() int {
+// DefaultFrameSize returns the size of the default window frame, which
+// contains all rows from the start of the partition through the last peer
+// of the current row.
+func (wf WindowFrame) DefaultFrameSize() int {
 	return wf.FirstPeerIdx + wf.PeerRowCount
 }
 
+// FrameStartIdx returns the index of the first row in the window frame of the
+// current row.
+func (wf WindowFrame) FrameStartIdx(evalCtx *EvalContext) (int, error) {
+	if wf.Frame == nil {
+		return 0, nil
+	}
+	bound := wf.Frame.Bounds.StartBound
+	switch bound.BoundType {
+	case UnboundedPreceding:
+		return 0, nil
+	case CurrentRow:
+		if wf.Frame.Mode == RANGE {
+			return wf.FirstPeerIdx, nil
+		}
+		return wf.RowIdx, nil
+	case ValuePreceding, ValueFollowing:
+		if wf.Frame.Mode == RANGE {
+			return wf.rangeBoundIdx(evalCtx, bound.BoundType, wf.StartBoundOffset, false /* end */)
+		}
+		return wf.clampIdx(wf.rowsBoundIdx(bound.BoundType, wf.StartBoundOffset)), nil
+	default:
+		panic(fmt.Sprintf("unexpected WindowFrameBoundType for frame start: %d", bound.BoundType))
+	}
+}
+
+// FrameEndIdx returns the index of the first row after the window frame of
+// the current row. The frame is empty if this is not greater than the index
+// returned by FrameStartIdx.
+func (wf WindowFrame) FrameEndIdx(evalCtx *EvalContext) (int, error) {
+	if wf.Frame == nil {
+		return wf.DefaultFrameSize(), nil
+	}
+	bound := wf.Frame.Bounds.EndBound
+	if bound == nil {
+		// Only the frame start was specified, so the frame ends at the
+		// current row.
+		bound = &WindowFrameBound{BoundType: CurrentRow}
+	}
+	switch bound.BoundType {
+	case UnboundedFollowing:
+		return len(wf.Rows), nil
+	case CurrentRow:
+		if wf.Frame.Mode == RANGE {
+			return wf.DefaultFrameSize(), nil
+		}
+		return wf.RowIdx + 1, nil
+	case ValuePreceding, ValueFollowing:
+		if wf.Frame.Mode == RANGE {
+			return wf.rangeBoundIdx(evalCtx, bound.BoundType, wf.EndBoundOffset, true /* end */)
+		}
+		return wf.clampIdx(wf.rowsBoundIdx(bound.BoundType, wf.EndBoundOffset) + 1), nil
+	default:
+		panic(fmt.Sprintf("unexpected WindowFrameBoundType for frame end: %d", bound.BoundType))
+	}
+}
+
+// FrameSize returns the number of rows in the window frame of the current row.
+func (wf WindowFrame) FrameSize(evalCtx *EvalContext) (int, error) {
+	start, err := wf.FrameStartIdx(evalCtx)
+	if err != nil {
+		return 0, err
+	}
+	end, err := wf.FrameEndIdx(evalCtx)
+	if err != nil {
+		return 0, err
+	}
+	if end <= start {
+		return 0, nil
+	}
+	return end - start, nil
+}
+
+// rowsBoundIdx returns the index of the row that is offset rows before or
+// after the current row in ROWS mode. The index may lie outside of the
+// partition.
+func (wf WindowFrame) rowsBoundIdx(boundType WindowFrameBoundType, offset Datum) int {
+	// The offset may be arbitrarily large, so clamp it before doing any
+	// arithmetic on it to avoid overflow.
+	off := int64(MustBeDInt(offset))
+	if max := int64(len(wf.Rows)); off > max {
+		off = max
+	}
+	if boundType == ValuePreceding {
+		return wf.RowIdx - int(off)
+	}
+	return wf.RowIdx + int(off)
+}
+
+// clampIdx clamps idx to [0, len(wf.Rows)].
+func (wf WindowFrame) clampIdx(idx int) int {
+	if idx < 0 {
+		return 0
+	}
+	if idx > len(wf.Rows) {
+		return len(wf.Rows)
+	}
+	return idx
+}
+
+// rangeBoundIdx returns the index of the first row in RANGE mode whose ORDER
+// BY value is not before (or, for the frame end, is after) the value of the
+// current row shifted by offset. Rows are sorted on the ORDER BY column, so
+// the index is found with a binary search.
+func (wf WindowFrame) rangeBoundIdx(
+	evalCtx *EvalContext, boundType WindowFrameBoundType, offset Datum, end bool,
+) (int, error) {
+	cur := wf.Rows[wf.RowIdx].Row[wf.OrdColIdx]
+	if cur == DNull {
+		// NULL values are only peers with each other, so the frame of a NULL
+		// row is its peer group.
+		if end {
+			return wf.DefaultFrameSize(), nil
+		}
+		return wf.FirstPeerIdx, nil
+	}
+
+	// PRECEDING moves towards smaller values for ascending orderings and
+	// towards larger values for descending orderings; FOLLOWING does the
+	// opposite.
+	op := Plus
+	if (boundType == ValuePreceding) == (wf.OrdDirection != Descending) {
+		op = Minus
+	}
+	fn, ok := BinOps[op].lookupImpl(cur.ResolvedType(), offset.ResolvedType())
+	if !ok {
+		return 0, pgerror.NewErrorf(pgerror.CodeInternalError,
+			"no %s operator for RANGE offset: <%s> %s <%s>",
+			op, cur.ResolvedType(), op, offset.ResolvedType())
+	}
+	target, err := fn.fn(evalCtx, cur, offset)
+	if err != nil {
+		if pgErr, ok := pgerror.GetPGCause(err); ok &&
+			pgErr.Code == pgerror.CodeNumericValueOutOfRangeError {
+			// The shifted value is beyond the range of the type, and thus
+			// beyond the values of all the rows.
+			if boundType == ValuePreceding {
+				return 0, nil
+			}
+			return len(wf.Rows), nil
+		}
+		return 0, err
+	}
+
+	return sort.Search(len(wf.Rows), func(i int) bool {
+		c := wf.Rows[i].Row[wf.OrdColIdx].Compare(evalCtx, target)
+		if wf.OrdDirection == Descending {
+			c = -c
+		}
+		if end {
+			return c > 0
+		}
+		return c >= 0
+	}), nil
+}
+
+// RangeOffsetType returns the type that the offsets of a RANGE frame must
+// have when the window is ordered on a column of type t, or nil if RANGE
+// offsets are not supported for t.
+func RangeOffsetType(t types.T) types.T {
+	switch t {
+	case types.Int, types.Float, types.Decimal, types.Interval:
+		return t
+	case types.Timestamp, types.TimestampTZ:
+		return types.Interval
+	}
+	return nil
+}
+
 // FirstInPeerGroup returns if the current row is the first in its peer group.
 func (wf WindowFrame) FirstInPeerGroup() bool {
 	return wf.RowIdx == wf.FirstPeerIdx
@@ -223,6 +223,13 @@ type Session struct {
 	// ClientAddr is the client's IP address and port.
 	ClientAddr string
 
+	// id uniquely identifies the session across the cluster. It is used to
+	// name the session's temporary schema.
+	id uint128.Uint128
+	// hasTemporarySchema is set once a temporary table has been created by
+	// the session. See temporary_schema.go.
+	hasTemporarySchema bool
+
 	//
 	// State structures for the logical SQL session.
 	//
@@ -405,6 +412,19 @@ func (r *SessionRegistry) deregister(s *Session) {
 	r.Unlock()
 }
 
+// hasSession returns whether the registry holds the session with the given
+// ID.
+func (r *SessionRegistry) hasSession(id uint128.Uint128) bool {
+	r.Lock()
+	defer r.Unlock()
+	for s := range r.store {
+		if s.id == id {
+			return true
+		}
+	}
+	return false
+}
+
 // CancelQuery looks up the associated query in the session registry and cancels it.
 func (r *SessionRegistry) CancelQuery(queryIDStr string, username string) (bool, error) {
 	queryID, err := uint128.FromString(queryIDStr)
@@ -457,6 +477,7 @@ func NewSession(
 	distSQLMode := DistSQLExecMode(DistSQLClusterExecMode.Get(&e.cfg.Settings.SV))
 
 	s := &Session{
+		id:               e.generateClusterWideID(),
 		Database:         args.Database,
 		DistSQLMode:      distSQLMode,
 		SearchPath:       sqlbase.DefaultSearchPath,
@@ -550,6 +571,15 @@ func (s *Session) Finish(e *Executor) {
 	// addressed, there might be leases accumulated by preparing statements.
 	s.tables.releaseTables(s.context)
 
+	if s.hasTemporarySchema {
+		if err := dropTemporarySchema(
+			s.context, e.cfg.DB, InternalExecutor{LeaseManager: e.cfg.LeaseManager},
+			temporarySchemaName(s.id),
+		); err != nil {
+			log.Warningf(s.context, "error dropping temporary schema: %v", err)
+		}
+	}
+
 	s.ClearStatementsAndPortals(s.context)
 	s.sessionMon.Stop(s.context)
 	s.mon.Stop(s.context)
@@ -303,3 +303,48 @@ CREATE TABLE t.test (k INT PRIMARY KEY, v TEXT);
 			"the EndTransaction with the expected key")
 	}
 }
+
+// Test that the temporary tables of a session are dropped when the session
+// is closed.
+func TestSessionFinishDropsTemporarySchema(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := tests.CreateTestServerParams()
+	s, mainDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop(context.TODO())
+
+	countTemporarySchemas := func() int {
+		var count int
+		if err := mainDB.QueryRow(
+			`SELECT count(*) FROM system.namespace WHERE "parentID" = 0 AND name LIKE 'pg_temp_%'`,
+		).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	pgURL, cleanupDB := sqlutils.PGUrl(
+		t, s.ServingAddr(), "TestSessionFinishDropsTemporarySchema", url.User(security.RootUser))
+	defer cleanupDB()
+	conn, err := pq.Open(pgURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.(driver.Execer).Exec(
+		"CREATE TEMP TABLE t (k INT PRIMARY KEY); INSERT INTO t VALUES (1)", nil,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if count := countTemporarySchemas(); count != 1 {
+		t.Fatalf("expected 1 temporary schema, found %d", count)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if count := countTemporarySchemas(); count != 0 {
+			return fmt.Errorf("expected no temporary schema, found %d", count)
+		}
+		return nil
+	})
+}
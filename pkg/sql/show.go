@@ -212,7 +212,7 @@ func (p *planner) ShowColumns(ctx context.Context, n *tree.ShowColumns) (planNod
 func (p *planner) showTableDetails(
 	ctx context.Context, showType string, t tree.NormalizableTableName, query string,
 ) (planNode, error) {
-	tn, err := t.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &t)
 	if err != nil {
 		return nil, err
 	}
@@ -533,7 +533,7 @@ func (p *planner) ShowIndex(ctx context.Context, n *tree.ShowIndex) (planNode, e
 //   Notes: postgres does not have a SHOW CONSTRAINTS statement.
 //          mysql requires some privilege for any column.
 func (p *planner) ShowConstraints(ctx context.Context, n *tree.ShowConstraints) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
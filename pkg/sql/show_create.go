@@ -102,15 +102,15 @@ func (p *planner) showCreateTable(
 				quoteNames(fkIdx.ColumnNames...),
 			)
 			if fk.OnDelete != sqlbase.ForeignKeyReference_NO_ACTION {
-				fmt.Fprintf(&buf, " ON DELETE %s", fk.OnDelete.String())
+				fmt.Fprintf(&buf, " ON DELETE %s", sqlbase.ForeignKeyReferenceActionType[fk.OnDelete])
 			}
 			if fk.OnUpdate != sqlbase.ForeignKeyReference_NO_ACTION {
-				fmt.Fprintf(&buf, " ON UPDATE %s", fk.OnUpdate.String())
+				fmt.Fprintf(&buf, " ON UPDATE %s", sqlbase.ForeignKeyReferenceActionType[fk.OnUpdate])
 			}
 		}
 		if idx.ID != desc.PrimaryIndex.ID {
 			// Showing the primary index is handled above.
-			fmt.Fprintf(&buf, ",\n\t%s", idx.SQLString(""))
+			fmt.Fprintf(&buf, ",\n\t%s", desc.IndexSQLString(&idx, ""))
 			// Showing the INTERLEAVE and PARTITION BY for the primary index are
 			// handled last.
 			if err := p.showCreateInterleave(ctx, &idx, &buf, dbPrefix); err != nil {
@@ -127,7 +127,9 @@ func (p *planner) showCreateTable(
 	for _, fam := range desc.Families {
 		activeColumnNames := make([]string, 0, len(fam.ColumnNames))
 		for i, colID := range fam.ColumnIDs {
-			if _, err := desc.FindActiveColumnByID(colID); err == nil {
+			// The hidden columns of index expressions are recreated along
+			// with the indexes.
+			if col, err := desc.FindActiveColumnByID(colID); err == nil && !col.IsComputed() {
 				activeColumnNames = append(activeColumnNames, fam.ColumnNames[i])
 			}
 		}
@@ -0,0 +1,176 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/stats"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+)
+
+var showTableStatsColumns = sqlbase.ResultColumns{
+	{Name: "statistics_name", Typ: types.String},
+	{Name: "column_names", Typ: types.TArray{Typ: types.String}},
+	{Name: "created", Typ: types.Timestamp},
+	{Name: "row_count", Typ: types.Int},
+	{Name: "distinct_count", Typ: types.Int},
+	{Name: "null_count", Typ: types.Int},
+	{Name: "histogram_id", Typ: types.Int},
+}
+
+// ShowTableStats returns the statistics collected for a table.
+// Privileges: Any privilege on table.
+func (p *planner) ShowTableStats(ctx context.Context, n *tree.ShowTableStats) (planNode, error) {
+	tn, err := p.normalizeTableName(ctx, &n.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := MustGetTableDesc(ctx, p.txn, p.getVirtualTabler(), tn, false /*allowAdding*/)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.anyPrivilege(desc); err != nil {
+		return nil, err
+	}
+
+	return &delayedNode{
+		name:    "SHOW STATISTICS FOR TABLE " + tn.String(),
+		columns: showTableStatsColumns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+			rows, err := ie.QueryRowsInTransaction(
+				ctx, "read-table-stats", p.txn,
+				`SELECT "statisticID", name, "columnIDs", "createdAt", "rowCount",
+				        "distinctCount", "nullCount", histogram IS NOT NULL
+				 FROM system.table_statistics
+				 WHERE "tableID" = $1
+				 ORDER BY "createdAt", "statisticID"`,
+				desc.ID,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			v := p.newContainerValuesNode(showTableStatsColumns, len(rows))
+			for _, r := range rows {
+				columnNames := tree.NewDArray(types.String)
+				for _, d := range tree.MustBeDArray(r[2]).Array {
+					id := sqlbase.ColumnID(tree.MustBeDInt(d))
+					name := fmt.Sprintf("[%d]", id)
+					if col, err := desc.FindColumnByID(id); err == nil {
+						name = col.Name
+					}
+					if err := columnNames.Append(tree.NewDString(name)); err != nil {
+						v.Close(ctx)
+						return nil, err
+					}
+				}
+				histogramID := tree.DNull
+				if r[7] == tree.DBoolTrue {
+					histogramID = r[0]
+				}
+				newRow := tree.Datums{r[1], columnNames, r[3], r[4], r[5], r[6], histogramID}
+				if _, err := v.rows.AddRow(ctx, newRow); err != nil {
+					v.Close(ctx)
+					return nil, err
+				}
+			}
+			return v, nil
+		},
+	}, nil
+}
+
+var showHistogramColumns = sqlbase.ResultColumns{
+	{Name: "upper_bound", Typ: types.String},
+	{Name: "range_rows", Typ: types.Int},
+	{Name: "equal_rows", Typ: types.Int},
+}
+
+// ShowHistogram returns the buckets of a histogram collected by
+// CREATE STATISTICS. The histogram is identified by the ID of the statistic
+// it belongs to, as returned by SHOW STATISTICS.
+// Privileges: Any privilege on the table the histogram belongs to.
+func (p *planner) ShowHistogram(ctx context.Context, n *tree.ShowHistogram) (planNode, error) {
+	return &delayedNode{
+		name:    fmt.Sprintf("SHOW HISTOGRAM %d", n.HistogramID),
+		columns: showHistogramColumns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+			row, err := ie.QueryRowInTransaction(
+				ctx, "read-histogram", p.txn,
+				`SELECT "tableID", "columnIDs", histogram
+				 FROM system.table_statistics
+				 WHERE "statisticID" = $1`,
+				n.HistogramID,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if row == nil || row[2] == tree.DNull {
+				return nil, errors.Errorf("histogram %d not found", n.HistogramID)
+			}
+
+			desc, err := sqlbase.GetTableDescFromID(ctx, p.txn, sqlbase.ID(tree.MustBeDInt(row[0])))
+			if err != nil {
+				return nil, err
+			}
+			if err := p.anyPrivilege(desc); err != nil {
+				return nil, err
+			}
+			columnIDs := tree.MustBeDArray(row[1]).Array
+			if len(columnIDs) == 0 {
+				return nil, errors.Errorf("histogram %d has no columns", n.HistogramID)
+			}
+			col, err := desc.FindColumnByID(sqlbase.ColumnID(tree.MustBeDInt(columnIDs[0])))
+			if err != nil {
+				return nil, err
+			}
+			colType := col.Type.ToDatumType()
+
+			var histogram stats.HistogramData
+			if err := histogram.Unmarshal([]byte(*row[2].(*tree.DBytes))); err != nil {
+				return nil, err
+			}
+
+			v := p.newContainerValuesNode(showHistogramColumns, len(histogram.Buckets))
+			var a sqlbase.DatumAlloc
+			for _, b := range histogram.Buckets {
+				upper, _, err := sqlbase.DecodeTableKey(&a, colType, b.UpperBound, encoding.Ascending)
+				if err != nil {
+					v.Close(ctx)
+					return nil, err
+				}
+				newRow := tree.Datums{
+					tree.NewDString(tree.AsStringWithFlags(upper, tree.FmtBareStrings)),
+					tree.NewDInt(tree.DInt(b.NumRange)),
+					tree.NewDInt(tree.DInt(b.NumEq)),
+				}
+				if _, err := v.rows.AddRow(ctx, newRow); err != nil {
+					v.Close(ctx)
+					return nil, err
+				}
+			}
+			return v, nil
+		},
+	}, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestShowHistogram(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	r := sqlutils.MakeSQLRunner(db)
+	r.Exec(t, `CREATE DATABASE d`)
+	r.Exec(t, `CREATE TABLE d.t (a INT PRIMARY KEY, b STRING)`)
+	r.Exec(t, `INSERT INTO d.t SELECT i, CASE WHEN i % 10 = 0 THEN NULL ELSE 'v' || (i % 3)::STRING END
+	           FROM generate_series(1, 100) AS g(i)`)
+	r.Exec(t, `CREATE STATISTICS s ON b FROM d.t`)
+
+	var histogramID int64
+	r.QueryRow(t, `SELECT histogram_id FROM [SHOW STATISTICS FOR TABLE d.t]`).Scan(&histogramID)
+
+	// All the rows are sampled, so the histogram is exact. NULL values are not
+	// part of the histogram.
+	r.CheckQueryResults(t, fmt.Sprintf(`SHOW HISTOGRAM %d`, histogramID), [][]string{
+		{"v0", "0", "30"},
+		{"v1", "0", "30"},
+		{"v2", "0", "30"},
+	})
+}
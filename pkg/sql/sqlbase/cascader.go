@@ -0,0 +1,743 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlbase
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/transform"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util"
+)
+
+// The referential actions of the foreign keys (ON DELETE and ON UPDATE
+// CASCADE, SET NULL and SET DEFAULT) are performed by a cascader attached to
+// the RowDeleter or RowUpdater of the referenced table. When a row is deleted
+// or its referenced columns updated, the cascader looks up the referencing
+// rows and deletes or updates them with row writers of their own, whose
+// cascaders in turn act on the rows referencing them, and so on. The
+// referencing rows are modified in batches of their own, run before the
+// modification of the row which triggered them.
+//
+// A chain of cascading actions stops when it finds no more referencing rows,
+// when it reaches a row already deleted by the chain, or fails if it gets
+// longer than CascadeConfig.MaxDepth or would update a row whose own
+// modification led to the update, which only happens when the references
+// form a cycle. A row referencing several rows of the chain, which happens
+// when the references form a diamond, is updated once per referenced row.
+//
+// The rows updated by referential actions get new values for the columns
+// converted from the updated columns by an ALTER COLUMN ... TYPE and for the
+// computed columns depending on them, including the hidden columns of index
+// expressions. Note that the CHECK constraints of the referencing tables are
+// not evaluated for these rows.
+
+// CascadeConfig configures the referential actions performed by the row
+// writers.
+type CascadeConfig struct {
+	// EvalCtx is used to compare the values of the referenced columns and to
+	// evaluate the default values used by ON DELETE/UPDATE SET DEFAULT.
+	EvalCtx *tree.EvalContext
+	// MaxDepth is the maximum length of a chain of cascading actions.
+	MaxDepth int
+}
+
+// TableLookupFunction looks up the descriptor of a table by ID, as the
+// higher level calling code does to fill in TableLookupsByID maps.
+type TableLookupFunction func(ctx context.Context, tableID ID) (TableLookup, error)
+
+// AddTablesNeededForCascades adds to tables, which must already hold the
+// tables returned by TablesNeededForFKs for the same table and usage, the
+// tables needed by the referential actions triggered by deleting
+// (CheckDeletes) or updating (CheckUpdates) rows of table: the referencing
+// tables whose rows these actions modify, transitively, and the tables needed
+// for their own FK checks. Missing tables are looked up with lookup.
+func AddTablesNeededForCascades(
+	ctx context.Context,
+	table *TableDescriptor,
+	usage FKCheck,
+	tables TableLookupsByID,
+	lookup TableLookupFunction,
+) error {
+	if usage == CheckInserts || len(tables) == 0 {
+		return nil
+	}
+	type cascade struct {
+		table *TableDescriptor
+		usage FKCheck
+	}
+	type cascadeKey struct {
+		id    ID
+		usage FKCheck
+	}
+	queue := []cascade{{table: table, usage: usage}}
+	seen := map[cascadeKey]struct{}{{id: table.ID, usage: usage}: {}}
+
+	lookupTable := func(id ID) (TableLookup, error) {
+		if found, ok := tables[id]; ok && (found.Table != nil || found.IsAdding) {
+			return found, nil
+		}
+		found, err := lookup(ctx, id)
+		if err != nil {
+			return TableLookup{}, err
+		}
+		tables[id] = found
+		return found, nil
+	}
+
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, idx := range c.table.AllNonDropIndexes() {
+			for _, ref := range idx.ReferencedBy {
+				found, err := lookupTable(ref.Table)
+				if err != nil {
+					return err
+				}
+				if found.Table == nil {
+					// Tables being added are empty.
+					continue
+				}
+				action, err := referencingAction(found.Table, ref.Index, c.usage)
+				if err != nil {
+					return err
+				}
+				cascadeUsage, ok := cascadedModification(action, c.usage)
+				if !ok {
+					continue
+				}
+				key := cascadeKey{id: found.Table.ID, usage: cascadeUsage}
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				for id := range TablesNeededForFKs(*found.Table, cascadeUsage) {
+					if _, err := lookupTable(id); err != nil {
+						return err
+					}
+				}
+				queue = append(queue, cascade{table: found.Table, usage: cascadeUsage})
+			}
+		}
+	}
+	return nil
+}
+
+// referencingAction returns the action of the foreign key of the given index
+// of the referencing table when the referenced row is deleted (CheckDeletes)
+// or its referenced columns are updated (CheckUpdates).
+func referencingAction(
+	referencingTable *TableDescriptor, indexID IndexID, usage FKCheck,
+) (ForeignKeyReference_Action, error) {
+	idx, err := referencingTable.FindIndexByID(indexID)
+	if err != nil {
+		return ForeignKeyReference_NO_ACTION, err
+	}
+	if usage == CheckDeletes {
+		return idx.ForeignKey.OnDelete, nil
+	}
+	return idx.ForeignKey.OnUpdate, nil
+}
+
+// cascadedModification returns the kind of modification the given action
+// makes to the referencing rows when the referenced rows are deleted
+// (CheckDeletes) or updated (CheckUpdates), and false if the action is a mere
+// check.
+func cascadedModification(action ForeignKeyReference_Action, usage FKCheck) (FKCheck, bool) {
+	switch action {
+	case ForeignKeyReference_CASCADE:
+		return usage, true
+	case ForeignKeyReference_SET_NULL, ForeignKeyReference_SET_DEFAULT:
+		return CheckUpdates, true
+	default:
+		return usage, false
+	}
+}
+
+// cascadeState is shared by the cascaders of the row writers performing a
+// chain of cascading actions.
+type cascadeState struct {
+	// deleted holds the primary keys of the rows deleted by the chain,
+	// including the row which started it if it is deleted.
+	deleted map[string]struct{}
+	// path holds the primary keys of the rows whose modification led to the
+	// current action, starting with the row which started the chain.
+	path map[string]struct{}
+}
+
+// cascader performs the referential actions of the foreign keys referencing
+// a table, on behalf of the RowDeleter or RowUpdater of that table.
+type cascader struct {
+	txn    *client.Txn
+	tables TableLookupsByID
+	config *CascadeConfig
+	alloc  *DatumAlloc
+
+	// table is the referenced table, and colIDtoRowIndex maps its column IDs
+	// to the indexes of the values passed to deleteRow and updateRow.
+	table           *TableDescriptor
+	colIDtoRowIndex map[ColumnID]int
+	primaryPrefix   []byte
+
+	// usage is CheckDeletes for the cascader of a RowDeleter, and
+	// CheckUpdates for that of a RowUpdater.
+	usage FKCheck
+	// actions are the foreign keys referencing the table with a referential
+	// action other than NO ACTION or RESTRICT.
+	actions []*fkAction
+
+	// depth is the position of the table in the chain of cascading actions,
+	// 0 for the table modified by the statement.
+	depth int
+	state *cascadeState
+}
+
+// fkAction performs the referential action of a foreign key.
+type fkAction struct {
+	action ForeignKeyReference_Action
+	// fk describes the referencing index and maps its columns to the
+	// columns of the referenced rows.
+	fk baseFKHelper
+
+	// cols are the columns of the referencing table, including those being
+	// added or dropped, fetched by rowFetcher from the primary index of the
+	// referencing table. indexFetcher fetches the primary keys of the
+	// referencing rows from the referencing index if it is a secondary index.
+	cols            []ColumnDescriptor
+	colIDtoRowIndex map[ColumnID]int
+	primaryPrefix   []byte
+	rowFetcher      MultiRowFetcher
+	indexFetcher    *MultiRowFetcher
+
+	// The row writers modifying the referencing rows, created on demand.
+	rd *RowDeleter
+	ru *RowUpdater
+	// updateCols are the columns updated by ru: the referencing columns,
+	// followed by the columns being converted from them and the computed
+	// columns depending on either, whose values are computed by conversions
+	// and computed. updateColIDtoRowIndex maps their IDs to their indexes.
+	updateCols            []ColumnDescriptor
+	updateColIDtoRowIndex map[ColumnID]int
+	conversions           []*ColumnConversion
+	computed              *ComputedColumns
+	// defaultExprs are the default values of the referencing columns, for
+	// SET DEFAULT.
+	defaultExprs []tree.TypedExpr
+}
+
+// makeCascader returns a cascader performing the referential actions
+// triggered by deleting (CheckDeletes) or updating (CheckUpdates) rows of
+// the table. It takes over the foreign keys with such actions from fks,
+// those of an fkDeleteHelper which is then left with the foreign keys to
+// merely check. The returned cascader is nil if there are no actions to
+// perform.
+func makeCascader(
+	txn *client.Txn,
+	table *TableDescriptor,
+	tables TableLookupsByID,
+	fks map[IndexID][]baseFKHelper,
+	colIDtoRowIndex map[ColumnID]int,
+	usage FKCheck,
+	config *CascadeConfig,
+	alloc *DatumAlloc,
+) (*cascader, error) {
+	if config == nil {
+		// Without a configuration, referential actions are checked like
+		// RESTRICT.
+		return nil, nil
+	}
+	var c *cascader
+	for idxID, idxFKs := range fks {
+		checks := idxFKs[:0]
+		for _, fk := range idxFKs {
+			action, err := referencingAction(fk.searchTable, fk.searchIdx.ID, usage)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := cascadedModification(action, usage); !ok {
+				checks = append(checks, fk)
+				continue
+			}
+			a, err := makeFKAction(fk, action, alloc)
+			if err != nil {
+				return nil, err
+			}
+			if c == nil {
+				c = &cascader{
+					txn:             txn,
+					tables:          tables,
+					config:          config,
+					alloc:           alloc,
+					table:           table,
+					colIDtoRowIndex: colIDtoRowIndex,
+					primaryPrefix:   MakeIndexKeyPrefix(table, table.PrimaryIndex.ID),
+					usage:           usage,
+					state:           &cascadeState{},
+				}
+			}
+			c.actions = append(c.actions, a)
+		}
+		if len(checks) == 0 {
+			delete(fks, idxID)
+		} else {
+			fks[idxID] = checks
+		}
+	}
+	return c, nil
+}
+
+func makeFKAction(
+	fk baseFKHelper, action ForeignKeyReference_Action, alloc *DatumAlloc,
+) (*fkAction, error) {
+	table := fk.searchTable
+	a := &fkAction{
+		action:        action,
+		fk:            fk,
+		cols:          table.Columns,
+		primaryPrefix: MakeIndexKeyPrefix(table, table.PrimaryIndex.ID),
+	}
+	if len(table.Mutations) > 0 {
+		a.cols = make([]ColumnDescriptor, 0, len(table.Columns)+len(table.Mutations))
+		a.cols = append(a.cols, table.Columns...)
+		for _, m := range table.Mutations {
+			if col := m.GetColumn(); col != nil {
+				a.cols = append(a.cols, *col)
+			}
+		}
+	}
+	a.colIDtoRowIndex = ColIDtoRowIndexFromCols(a.cols)
+
+	var allCols util.FastIntSet
+	allCols.AddRange(0, len(a.cols)-1)
+	if err := a.rowFetcher.Init(false /* reverse */, false /* returnRangeInfo */, alloc,
+		MultiRowFetcherTableArgs{
+			Desc:            table,
+			Index:           &table.PrimaryIndex,
+			ColIdxMap:       a.colIDtoRowIndex,
+			Cols:            a.cols,
+			ValNeededForCol: allCols,
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	if fk.searchIdx.ID != table.PrimaryIndex.ID {
+		var primaryCols util.FastIntSet
+		for _, colID := range table.PrimaryIndex.ColumnIDs {
+			primaryCols.Add(a.colIDtoRowIndex[colID])
+		}
+		a.indexFetcher = &MultiRowFetcher{}
+		if err := a.indexFetcher.Init(false /* reverse */, false /* returnRangeInfo */, alloc,
+			MultiRowFetcherTableArgs{
+				Desc:             table,
+				Index:            fk.searchIdx,
+				ColIdxMap:        a.colIDtoRowIndex,
+				IsSecondaryIndex: true,
+				Cols:             a.cols,
+				ValNeededForCol:  primaryCols,
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// referencingCols returns the columns of the foreign key in the referencing
+// table.
+func (a *fkAction) referencingCols() []ColumnDescriptor {
+	cols := make([]ColumnDescriptor, a.fk.prefixLen)
+	for i, colID := range a.fk.searchIdx.ColumnIDs[:a.fk.prefixLen] {
+		cols[i] = a.cols[a.colIDtoRowIndex[colID]]
+	}
+	return cols
+}
+
+// fetchReferencingRows returns the rows referencing the given values of the
+// referenced columns.
+func (a *fkAction) fetchReferencingRows(
+	ctx context.Context, txn *client.Txn, values tree.Datums, traceKV bool,
+) ([]tree.Datums, error) {
+	span, err := a.fk.spanForValues(values)
+	if err != nil {
+		return nil, err
+	}
+	spans := roachpb.Spans{span}
+	if a.indexFetcher != nil {
+		if err := a.indexFetcher.StartScan(
+			ctx, txn, spans, false /* limitBatches */, 0 /* limitHint */, traceKV,
+		); err != nil {
+			return nil, err
+		}
+		spans = spans[:0]
+		for {
+			row, _, _, err := a.indexFetcher.NextRowDecoded(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if row == nil {
+				break
+			}
+			key, _, err := EncodeIndexKey(
+				a.fk.searchTable, &a.fk.searchTable.PrimaryIndex, a.colIDtoRowIndex, row, a.primaryPrefix)
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, roachpb.Span{Key: key, EndKey: roachpb.Key(key).PrefixEnd()})
+		}
+		if len(spans) == 0 {
+			return nil, nil
+		}
+	}
+
+	if err := a.rowFetcher.StartScan(
+		ctx, txn, spans, false /* limitBatches */, 0 /* limitHint */, traceKV,
+	); err != nil {
+		return nil, err
+	}
+	var rows []tree.Datums
+	for {
+		row, _, _, err := a.rowFetcher.NextRowDecoded(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return rows, nil
+		}
+		rows = append(rows, append(tree.Datums(nil), row...))
+	}
+}
+
+// deleteRow performs the referential actions triggered by the deletion of
+// the row with the given values.
+func (c *cascader) deleteRow(ctx context.Context, values tree.Datums, traceKV bool) error {
+	if err := c.startChain(values, true /* deleted */); err != nil {
+		return err
+	}
+	for _, a := range c.actions {
+		if err := c.cascade(ctx, a, values, nil /* newValues */, traceKV); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateRow performs the referential actions triggered by the update of the
+// row with the given old values to the given new values.
+func (c *cascader) updateRow(
+	ctx context.Context, oldValues, newValues tree.Datums, traceKV bool,
+) error {
+	started := false
+	for _, a := range c.actions {
+		changed := false
+		for _, colID := range a.fk.searchIdx.ColumnIDs[:a.fk.prefixLen] {
+			i := a.fk.ids[colID]
+			if oldValues[i].Compare(c.config.EvalCtx, newValues[i]) != 0 {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			continue
+		}
+		if !started {
+			if err := c.startChain(oldValues, false /* deleted */); err != nil {
+				return err
+			}
+			started = true
+		}
+		if err := c.cascade(ctx, a, oldValues, newValues, traceKV); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startChain records the row modified by the statement when a chain of
+// cascading actions starts from it.
+func (c *cascader) startChain(values tree.Datums, deleted bool) error {
+	if c.depth > 0 {
+		return nil
+	}
+	key, err := c.primaryKey(values)
+	if err != nil {
+		return err
+	}
+	c.state.deleted = map[string]struct{}{}
+	if deleted {
+		c.state.deleted[key] = struct{}{}
+	}
+	c.state.path = map[string]struct{}{key: {}}
+	return nil
+}
+
+func (c *cascader) primaryKey(values tree.Datums) (string, error) {
+	key, _, err := EncodeIndexKey(
+		c.table, &c.table.PrimaryIndex, c.colIDtoRowIndex, values, c.primaryPrefix)
+	return string(key), err
+}
+
+// cascade performs the referential action a on the rows referencing the row
+// with the given old values, which is being deleted if newValues is nil and
+// updated to newValues otherwise.
+func (c *cascader) cascade(
+	ctx context.Context, a *fkAction, oldValues, newValues tree.Datums, traceKV bool,
+) error {
+	for _, colID := range a.fk.searchIdx.ColumnIDs[:a.fk.prefixLen] {
+		if oldValues[a.fk.ids[colID]] == tree.DNull {
+			// Rows with NULL values reference no rows.
+			return nil
+		}
+	}
+	rows, err := a.fetchReferencingRows(ctx, c.txn, oldValues, traceKV)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	if c.depth+1 > c.config.MaxDepth {
+		return pgerror.NewErrorf(pgerror.CodeTriggeredActionExceptionError,
+			"cascading foreign key actions on table %q exceed the maximum depth of %d",
+			a.fk.searchTable.Name, c.config.MaxDepth)
+	}
+
+	deleteRows := a.action == ForeignKeyReference_CASCADE && newValues == nil
+	var updateValues tree.Datums
+	if !deleteRows {
+		if updateValues, err = c.updateValues(a, newValues); err != nil {
+			return err
+		}
+	}
+	if a.action == ForeignKeyReference_SET_DEFAULT {
+		// The FK checks of the referencing rows would still find the
+		// referenced values, which are only removed afterwards.
+		stillReferenced := true
+		for i, colID := range a.fk.searchIdx.ColumnIDs[:a.fk.prefixLen] {
+			if updateValues[i].Compare(c.config.EvalCtx, oldValues[a.fk.ids[colID]]) != 0 {
+				stillReferenced = false
+				break
+			}
+		}
+		if stillReferenced {
+			return pgerror.NewErrorf(pgerror.CodeForeignKeyViolationError,
+				"foreign key violation: value %s not found in %s@%s %s",
+				updateValues, c.table.Name, a.fk.writeIdx.Name, a.fk.writeIdx.ColumnNames[:a.fk.prefixLen])
+		}
+	}
+
+	b := c.txn.NewBatch()
+	for _, row := range rows {
+		key, _, err := EncodeIndexKey(
+			a.fk.searchTable, &a.fk.searchTable.PrimaryIndex, a.colIDtoRowIndex, row, a.primaryPrefix)
+		if err != nil {
+			return err
+		}
+		if _, ok := c.state.deleted[string(key)]; ok {
+			// The row is already being deleted.
+			continue
+		}
+
+		if deleteRows {
+			c.state.deleted[string(key)] = struct{}{}
+			rd, err := c.deleter(a)
+			if err != nil {
+				return err
+			}
+			if err := rd.DeleteRow(ctx, b, row, traceKV); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := c.state.path[string(key)]; ok {
+			return pgerror.NewErrorf(pgerror.CodeTriggeredActionExceptionError,
+				"cascading foreign key actions on table %q form a cycle", a.fk.searchTable.Name)
+		}
+		ru, err := c.updater(a)
+		if err != nil {
+			return err
+		}
+		rowValues, err := c.rowUpdateValues(a, row, updateValues)
+		if err != nil {
+			return err
+		}
+		// The actions triggered by the update of the row are performed by
+		// UpdateRow, with the row on the path.
+		c.state.path[string(key)] = struct{}{}
+		_, err = ru.UpdateRow(ctx, b, row, rowValues, traceKV)
+		delete(c.state.path, string(key))
+		if err != nil {
+			return err
+		}
+	}
+	if err := c.txn.Run(ctx, b); err != nil {
+		return ConvertBatchError(ctx, a.fk.searchTable, b)
+	}
+	return nil
+}
+
+// updateValues returns the new values of the referencing columns.
+func (c *cascader) updateValues(a *fkAction, newValues tree.Datums) (tree.Datums, error) {
+	cols := a.referencingCols()
+	values := make(tree.Datums, len(cols))
+	switch a.action {
+	case ForeignKeyReference_CASCADE:
+		for i, colID := range a.fk.searchIdx.ColumnIDs[:a.fk.prefixLen] {
+			values[i] = newValues[a.fk.ids[colID]]
+		}
+	case ForeignKeyReference_SET_NULL:
+		for i := range values {
+			values[i] = tree.DNull
+		}
+	case ForeignKeyReference_SET_DEFAULT:
+		if a.defaultExprs == nil {
+			defaultExprs, err := MakeDefaultExprs(cols, &transform.ExprTransformContext{}, c.config.EvalCtx)
+			if err != nil {
+				return nil, err
+			}
+			if defaultExprs == nil {
+				defaultExprs = make([]tree.TypedExpr, len(cols))
+				for i := range defaultExprs {
+					defaultExprs[i] = tree.DNull
+				}
+			}
+			a.defaultExprs = defaultExprs
+		}
+		for i, expr := range a.defaultExprs {
+			d, err := expr.Eval(c.config.EvalCtx)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = d
+		}
+	}
+	for i, col := range cols {
+		if values[i] == tree.DNull && (!col.Nullable || a.fk.searchTable.IsValidatingNotNull(col.ID)) {
+			return nil, NewNonNullViolationError(col.Name)
+		}
+	}
+	return values, nil
+}
+
+// rowUpdateValues returns the new values of the columns updated in the given
+// referencing row, given the new values of the referencing columns.
+func (c *cascader) rowUpdateValues(
+	a *fkAction, row tree.Datums, values tree.Datums,
+) (tree.Datums, error) {
+	if len(a.updateCols) == len(values) {
+		return values, nil
+	}
+	rowValues := make(tree.Datums, len(a.updateCols))
+	copy(rowValues, values)
+	if err := ConvertColumns(
+		c.config.EvalCtx, a.conversions, a.updateColIDtoRowIndex, rowValues,
+	); err != nil {
+		return nil, err
+	}
+	if a.computed != nil {
+		a.computed.LoadRow(a.colIDtoRowIndex, row, false)
+		a.computed.LoadRow(a.updateColIDtoRowIndex, rowValues, true)
+		if err := a.computed.Compute(c.config.EvalCtx, a.updateColIDtoRowIndex, rowValues); err != nil {
+			return nil, err
+		}
+	}
+	for i, col := range a.updateCols[len(values):] {
+		d := rowValues[len(values)+i]
+		if err := CheckValueWidth(col.Type, d, col.Name); err != nil {
+			return nil, err
+		}
+		if d == tree.DNull && (!col.Nullable || a.fk.searchTable.IsValidatingNotNull(col.ID)) {
+			return nil, NewNonNullViolationError(col.Name)
+		}
+	}
+	return rowValues, nil
+}
+
+// initUpdateCols sets the columns updated in the rows referencing the table
+// through the foreign key of a, along with the conversions and computed
+// columns computing the values of those which are not referencing columns.
+func (a *fkAction) initUpdateCols() error {
+	table := a.fk.searchTable
+	a.updateCols = a.referencingCols()
+	conversions, err := MakeColumnConversions(table)
+	if err != nil {
+		return err
+	}
+	for _, conv := range conversions {
+		for _, col := range a.updateCols {
+			if col.ID == conv.Source.ID {
+				a.conversions = append(a.conversions, conv)
+				a.updateCols = append(a.updateCols, conv.Column)
+				break
+			}
+		}
+	}
+	computed, err := MakeWritableComputedColumns(table)
+	if err != nil {
+		return err
+	}
+	if computedCols := computed.Referencing(a.updateCols); len(computedCols) > 0 {
+		a.updateCols = append(a.updateCols, computedCols...)
+		a.computed = computed
+	}
+	a.updateColIDtoRowIndex = ColIDtoRowIndexFromCols(a.updateCols)
+	return nil
+}
+
+// deleter returns the RowDeleter deleting the rows referencing the table
+// through the foreign key of a.
+func (c *cascader) deleter(a *fkAction) (*RowDeleter, error) {
+	if a.rd == nil {
+		rd, err := makeRowDeleter(c.txn, a.fk.searchTable, c.tables, a.cols, CheckFKs,
+			c.config, c.alloc, c)
+		if err != nil {
+			return nil, err
+		}
+		a.rd = &rd
+	}
+	return a.rd, nil
+}
+
+// updater returns the RowUpdater updating the columns of the foreign key of
+// a, and the columns whose values depend on them, in the rows referencing
+// the table.
+func (c *cascader) updater(a *fkAction) (*RowUpdater, error) {
+	if a.ru == nil {
+		if err := a.initUpdateCols(); err != nil {
+			return nil, err
+		}
+		ru, err := makeRowUpdater(c.txn, a.fk.searchTable, c.tables, a.updateCols, a.cols,
+			RowUpdaterDefault, c.config, c.alloc, c)
+		if err != nil {
+			return nil, err
+		}
+		if a.action == ForeignKeyReference_CASCADE {
+			// The new values reference the new values of the row which
+			// triggered the action, which are written after the referencing
+			// rows are updated.
+			delete(ru.Fks.outbound.fks, a.fk.searchIdx.ID)
+		}
+		a.ru = &ru
+	}
+	return a.ru, nil
+}
+
+// nest makes the cascader part of the chain of cascading actions of parent.
+func (c *cascader) nest(parent *cascader) {
+	if c != nil && parent != nil {
+		c.depth = parent.depth + 1
+		c.state = parent.state
+	}
+}
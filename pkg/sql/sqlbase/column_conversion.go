@@ -0,0 +1,150 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlbase
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+)
+
+// ColumnConversion computes the values of a column added by ALTER COLUMN
+// ... TYPE from the values of the column it replaces.
+type ColumnConversion struct {
+	// Source is the column being converted.
+	Source ColumnDescriptor
+	// Column is the column receiving the converted values.
+	Column ColumnDescriptor
+
+	expr       tree.TypedExpr
+	ivarHelper tree.IndexedVarHelper
+	curSource  tree.Datum
+}
+
+var _ tree.IndexedVarContainer = &ColumnConversion{}
+
+// IndexedVarEval is part of the tree.IndexedVarContainer interface.
+func (c *ColumnConversion) IndexedVarEval(idx int, ctx *tree.EvalContext) (tree.Datum, error) {
+	return c.curSource, nil
+}
+
+// IndexedVarResolvedType is part of the tree.IndexedVarContainer interface.
+func (c *ColumnConversion) IndexedVarResolvedType(idx int) types.T {
+	return c.Source.Type.ToDatumType()
+}
+
+// IndexedVarNodeFormatter is part of the tree.IndexedVarContainer interface.
+func (c *ColumnConversion) IndexedVarNodeFormatter(idx int) tree.NodeFormatter {
+	n := tree.Name(c.Source.Name)
+	return &n
+}
+
+// MakeColumnConversion returns the ColumnConversion for a column mutation
+// created by ALTER COLUMN ... TYPE, or nil if the mutation is not one.
+func MakeColumnConversion(desc *TableDescriptor, m DescriptorMutation) (*ColumnConversion, error) {
+	col := m.GetColumn()
+	if col == nil || m.ColumnConversion == nil {
+		return nil, nil
+	}
+	src, err := desc.FindColumnByID(m.ColumnConversion.SourceColumnID)
+	if err != nil {
+		return nil, err
+	}
+	c := &ColumnConversion{Source: *src, Column: *col}
+	c.ivarHelper = tree.MakeIndexedVarHelper(c, 1)
+
+	expr, err := parser.ParseExpr(m.ColumnConversion.Expr)
+	if err != nil {
+		return nil, err
+	}
+	// Bind the reference to the source column (@1) to the conversion.
+	expr, _ = tree.WalkExpr(&c.ivarHelper, expr)
+	c.expr, err = tree.TypeCheck(
+		expr, &tree.SemaContext{IVarHelper: &c.ivarHelper}, col.Type.ToDatumType(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MakeColumnConversions returns the ColumnConversions of the column
+// mutations of desc that are in the DELETE_AND_WRITE_ONLY state, which
+// must be maintained by INSERT and UPDATE.
+func MakeColumnConversions(desc *TableDescriptor) ([]*ColumnConversion, error) {
+	var conversions []*ColumnConversion
+	for _, m := range desc.Mutations {
+		if m.State != DescriptorMutation_DELETE_AND_WRITE_ONLY ||
+			m.Direction != DescriptorMutation_ADD {
+			continue
+		}
+		c, err := MakeColumnConversion(desc, m)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			conversions = append(conversions, c)
+		}
+	}
+	return conversions, nil
+}
+
+// Convert returns the converted value of the given value of the source
+// column, checking it against the width and nullability of the new column.
+func (c *ColumnConversion) Convert(evalCtx *tree.EvalContext, src tree.Datum) (tree.Datum, error) {
+	c.curSource = src
+	prevHelper := evalCtx.IVarHelper
+	evalCtx.IVarHelper = &c.ivarHelper
+	d, err := c.expr.Eval(evalCtx)
+	evalCtx.IVarHelper = prevHelper
+	if err != nil {
+		return nil, err
+	}
+	if d == tree.DNull && !c.Column.Nullable {
+		return nil, NewNonNullViolationError(c.Source.Name)
+	}
+	if err := CheckValueWidth(c.Column.Type, d, c.Source.Name); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ConvertColumns sets the values of the columns being converted in row from
+// the values of their source columns, given the mapping from column IDs to
+// indexes in row. Conversions whose column is absent from row are skipped,
+// and absent source columns are considered NULL.
+func ConvertColumns(
+	evalCtx *tree.EvalContext,
+	conversions []*ColumnConversion,
+	colIDtoRowIndex map[ColumnID]int,
+	row tree.Datums,
+) error {
+	for _, c := range conversions {
+		dst, ok := colIDtoRowIndex[c.Column.ID]
+		if !ok {
+			continue
+		}
+		src := tree.Datum(tree.DNull)
+		if i, ok := colIDtoRowIndex[c.Source.ID]; ok {
+			src = row[i]
+		}
+		d, err := c.Convert(evalCtx, src)
+		if err != nil {
+			return err
+		}
+		row[dst] = d
+	}
+	return nil
+}
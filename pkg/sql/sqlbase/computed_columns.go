@@ -0,0 +1,232 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlbase
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/util"
+)
+
+// IsComputed returns whether the values of the column are computed from the
+// values of other columns.
+func (desc *ColumnDescriptor) IsComputed() bool {
+	return desc.ComputeExpr != nil
+}
+
+// ComputedColumns evaluates the expressions of computed columns. The values
+// of the columns referenced by the expressions are loaded with LoadRow, after
+// which Compute sets the values of the computed columns.
+type ComputedColumns struct {
+	// Columns are the computed columns.
+	Columns []ColumnDescriptor
+
+	exprs []tree.TypedExpr
+	// deps are the ordinals in sourceCols of the columns referenced by the
+	// expression of each computed column.
+	deps []util.FastIntSet
+
+	sourceCols []ColumnDescriptor
+	ivarHelper tree.IndexedVarHelper
+	curRow     tree.Datums
+}
+
+var _ tree.IndexedVarContainer = &ComputedColumns{}
+
+// IndexedVarEval is part of the tree.IndexedVarContainer interface.
+func (c *ComputedColumns) IndexedVarEval(idx int, ctx *tree.EvalContext) (tree.Datum, error) {
+	return c.curRow[idx].Eval(ctx)
+}
+
+// IndexedVarResolvedType is part of the tree.IndexedVarContainer interface.
+func (c *ComputedColumns) IndexedVarResolvedType(idx int) types.T {
+	return c.sourceCols[idx].Type.ToDatumType()
+}
+
+// IndexedVarNodeFormatter is part of the tree.IndexedVarContainer interface.
+func (c *ComputedColumns) IndexedVarNodeFormatter(idx int) tree.NodeFormatter {
+	n := tree.Name(c.sourceCols[idx].Name)
+	return &n
+}
+
+// MakeComputedColumns returns the ComputedColumns evaluating the given
+// computed columns of desc.
+func MakeComputedColumns(desc *TableDescriptor, cols []ColumnDescriptor) (*ComputedColumns, error) {
+	c := newComputedColumns(desc, cols)
+	for i, col := range cols {
+		expr, err := parser.ParseExpr(*col.ComputeExpr)
+		if err != nil {
+			return nil, err
+		}
+		expr, err = c.bindColumnNames(expr, &c.deps[i])
+		if err != nil {
+			return nil, err
+		}
+		c.exprs[i], err = tree.TypeCheck(
+			expr, &tree.SemaContext{IVarHelper: &c.ivarHelper}, col.Type.ToDatumType(),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func newComputedColumns(desc *TableDescriptor, cols []ColumnDescriptor) *ComputedColumns {
+	c := &ComputedColumns{
+		Columns:    cols,
+		exprs:      make([]tree.TypedExpr, len(cols)),
+		deps:       make([]util.FastIntSet, len(cols)),
+		sourceCols: append([]ColumnDescriptor(nil), desc.Columns...),
+	}
+	for _, m := range desc.Mutations {
+		if col := m.GetColumn(); col != nil {
+			c.sourceCols = append(c.sourceCols, *col)
+		}
+	}
+	c.ivarHelper = tree.MakeIndexedVarHelper(c, len(c.sourceCols))
+	c.curRow = make(tree.Datums, len(c.sourceCols))
+	return c
+}
+
+// TypeCheckComputedExpr type checks expr as the expression of a computed
+// column of desc, resolving the column names it contains.
+func TypeCheckComputedExpr(desc *TableDescriptor, expr tree.Expr) (tree.TypedExpr, error) {
+	c := newComputedColumns(desc, nil /* cols */)
+	var deps util.FastIntSet
+	expr, err := c.bindColumnNames(expr, &deps)
+	if err != nil {
+		return nil, err
+	}
+	return tree.TypeCheck(expr, &tree.SemaContext{IVarHelper: &c.ivarHelper}, types.Any)
+}
+
+// bindColumnNames replaces the column names in expr with IndexedVars bound
+// to c, adding the ordinals of the referenced columns to deps.
+func (c *ComputedColumns) bindColumnNames(
+	expr tree.Expr, deps *util.FastIntSet,
+) (tree.Expr, error) {
+	preFn := func(expr tree.Expr) (err error, recurse bool, newExpr tree.Expr) {
+		vBase, ok := expr.(tree.VarName)
+		if !ok {
+			return nil, true, expr
+		}
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return err, false, nil
+		}
+		colItem, ok := v.(*tree.ColumnItem)
+		if !ok {
+			return errors.Errorf("invalid column reference %s in computed expression", v), false, nil
+		}
+		for i := range c.sourceCols {
+			if c.sourceCols[i].Name == string(colItem.ColumnName) {
+				if c.sourceCols[i].IsComputed() {
+					return errors.Errorf("computed expression cannot reference computed column %q",
+						colItem.ColumnName), false, nil
+				}
+				deps.Add(i)
+				return nil, false, c.ivarHelper.IndexedVar(i)
+			}
+		}
+		return errors.Errorf("column %q does not exist", colItem.ColumnName), false, nil
+	}
+	return tree.SimpleVisit(expr, preFn)
+}
+
+// MakeWritableComputedColumns returns the ComputedColumns evaluating the
+// computed columns of desc whose values must be maintained by INSERT,
+// UPDATE and UPSERT, or nil if there are none.
+func MakeWritableComputedColumns(desc *TableDescriptor) (*ComputedColumns, error) {
+	var cols []ColumnDescriptor
+	for _, col := range desc.Columns {
+		if col.IsComputed() {
+			cols = append(cols, col)
+		}
+	}
+	for _, m := range desc.Mutations {
+		if col := m.GetColumn(); col != nil && col.IsComputed() &&
+			m.State == DescriptorMutation_DELETE_AND_WRITE_ONLY &&
+			m.Direction == DescriptorMutation_ADD {
+			cols = append(cols, *col)
+		}
+	}
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	return MakeComputedColumns(desc, cols)
+}
+
+// Referencing returns the computed columns whose expressions reference any
+// of the given columns, and whose values thus change when those are updated.
+func (c *ComputedColumns) Referencing(cols []ColumnDescriptor) []ColumnDescriptor {
+	if c == nil {
+		return nil
+	}
+	var ords util.FastIntSet
+	for _, col := range cols {
+		for j := range c.sourceCols {
+			if c.sourceCols[j].ID == col.ID {
+				ords.Add(j)
+			}
+		}
+	}
+	var res []ColumnDescriptor
+	for i, computed := range c.Columns {
+		if c.deps[i].Intersects(ords) {
+			res = append(res, computed)
+		}
+	}
+	return res
+}
+
+// LoadRow sets the values of the columns referenced by the expressions from
+// row, given the mapping from column IDs to indexes in row. Any value not
+// present in row is set to NULL, unless merge is true, in which case it is
+// left unchanged (allowing updating a subset of a row's values).
+func (c *ComputedColumns) LoadRow(colIDtoRowIndex map[ColumnID]int, row tree.Datums, merge bool) {
+	for i := range c.sourceCols {
+		if ri, ok := colIDtoRowIndex[c.sourceCols[i].ID]; ok {
+			c.curRow[i] = row[ri]
+		} else if !merge {
+			c.curRow[i] = tree.DNull
+		}
+	}
+}
+
+// Compute sets the values of the computed columns present in row, given the
+// mapping from column IDs to indexes in row, from the loaded row.
+func (c *ComputedColumns) Compute(
+	evalCtx *tree.EvalContext, colIDtoRowIndex map[ColumnID]int, row tree.Datums,
+) error {
+	prevHelper := evalCtx.IVarHelper
+	evalCtx.IVarHelper = &c.ivarHelper
+	defer func() { evalCtx.IVarHelper = prevHelper }()
+	for i, col := range c.Columns {
+		ri, ok := colIDtoRowIndex[col.ID]
+		if !ok {
+			continue
+		}
+		d, err := c.exprs[i].Eval(evalCtx)
+		if err != nil {
+			return err
+		}
+		row[ri] = d
+	}
+	return nil
+}
@@ -73,7 +73,8 @@ func MakeDefaultExprs(
 	return defaultExprs, nil
 }
 
-// ProcessDefaultColumns adds columns with DEFAULT to cols if not present
+// ProcessDefaultColumns adds columns with DEFAULT, computed columns and
+// columns being converted by ALTER COLUMN ... TYPE to cols if not present
 // and returns the defaultExprs for cols.
 func ProcessDefaultColumns(
 	cols []ColumnDescriptor,
@@ -86,9 +87,9 @@ func ProcessDefaultColumns(
 		colIDSet[col.ID] = struct{}{}
 	}
 
-	// Add the column if it has a DEFAULT expression.
+	// Add the column if it has a DEFAULT expression or is computed.
 	addIfDefault := func(col ColumnDescriptor) {
-		if col.DefaultExpr != nil {
+		if col.DefaultExpr != nil || col.IsComputed() {
 			if _, ok := colIDSet[col.ID]; !ok {
 				colIDSet[col.ID] = struct{}{}
 				cols = append(cols, col)
@@ -96,15 +97,23 @@ func ProcessDefaultColumns(
 		}
 	}
 
-	// Add any column that has a DEFAULT expression.
+	// Add any column that has a DEFAULT expression or is computed.
 	for _, col := range tableDesc.Columns {
 		addIfDefault(col)
 	}
 	// Also add any column in a mutation that is DELETE_AND_WRITE_ONLY and has
-	// a DEFAULT expression.
+	// a DEFAULT expression, is computed, or whose values are converted from
+	// those of another column by ALTER COLUMN ... TYPE.
 	for _, m := range tableDesc.Mutations {
 		if col := m.GetColumn(); col != nil &&
 			m.State == DescriptorMutation_DELETE_AND_WRITE_ONLY {
+			if m.ColumnConversion != nil && m.Direction == DescriptorMutation_ADD {
+				if _, ok := colIDSet[col.ID]; !ok {
+					colIDSet[col.ID] = struct{}{}
+					cols = append(cols, *col)
+				}
+				continue
+			}
 			addIfDefault(*col)
 		}
 	}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
 
@@ -91,6 +92,13 @@ type txnKVFetcher struct {
 	// returnRangeInfo, if set, causes the kvFetcher to populate rangeInfos.
 	// See also rowFetcher.returnRangeInfo.
 	returnRangeInfo bool
+	// lockStrength, if not ForNone, causes the kvFetcher to lock every
+	// key/value it returns by laying down an intent with the unchanged value.
+	// All locking strengths currently acquire exclusive locks.
+	lockStrength tree.LockingStrength
+	// lockWaitPolicy determines what happens when the fetcher runs into a row
+	// locked by another transaction.
+	lockWaitPolicy tree.LockingWaitPolicy
 
 	fetchEnd  bool
 	batchIdx  int
@@ -209,6 +217,7 @@ func (f *txnKVFetcher) fetch(ctx context.Context) error {
 	var ba roachpb.BatchRequest
 	ba.Header.MaxSpanRequestKeys = f.getBatchSize()
 	ba.Header.ReturnRangeInfo = f.returnRangeInfo
+	ba.Header.NoWait = f.lockWaitPolicy == tree.LockWaitError
 	ba.Requests = make([]roachpb.RequestUnion, len(f.spans))
 	if f.reverse {
 		scans := make([]roachpb.ReverseScanRequest, len(f.spans))
@@ -276,12 +285,51 @@ func (f *txnKVFetcher) fetch(ctx context.Context) error {
 
 	f.batchIdx++
 
+	if f.lockStrength != tree.ForNone {
+		if err := f.lockResponses(ctx); err != nil {
+			return err
+		}
+	}
+
 	// TODO(radu): We should fetch the next chunk in the background instead of waiting for the next
 	// call to fetch(). We can use a pool of workers to issue the KV ops which will also limit the
 	// total number of fetches that happen in parallel (and thus the amount of resources we use).
 	return nil
 }
 
+// lockResponses locks the key/values in the current responses by rewriting
+// each of them with its unchanged value. The resulting intents conflict with
+// the reads and writes of other transactions until the locking transaction
+// finishes.
+func (f *txnKVFetcher) lockResponses(ctx context.Context) error {
+	var ba roachpb.BatchRequest
+	ba.Header.NoWait = f.lockWaitPolicy == tree.LockWaitError
+	for _, resp := range f.responses {
+		var rows []roachpb.KeyValue
+		switch t := resp.GetInner().(type) {
+		case *roachpb.ScanResponse:
+			rows = t.Rows
+		case *roachpb.ReverseScanResponse:
+			rows = t.Rows
+		}
+		for _, kv := range rows {
+			put := &roachpb.PutRequest{
+				Span:  roachpb.Span{Key: kv.Key},
+				Value: roachpb.Value{RawBytes: kv.Value.RawBytes},
+			}
+			ba.Add(put)
+		}
+	}
+	if len(ba.Requests) == 0 {
+		return nil
+	}
+	log.VEventf(ctx, 2, "Lock %d keys", len(ba.Requests))
+	if _, err := f.txn.Send(ctx, ba); err != nil {
+		return err.GoError()
+	}
+	return nil
+}
+
 // nextKV returns the next key/value (initiating fetches as necessary). When
 // there are no more keys, returns false and an empty key/value.
 func (f *txnKVFetcher) nextKV(ctx context.Context) (bool, roachpb.KeyValue, error) {
@@ -312,3 +360,24 @@ func (f *txnKVFetcher) nextKV(ctx context.Context) (bool, roachpb.KeyValue, erro
 		}
 	}
 }
+
+// SpanKVFetcher is a kvFetcher that returns a set slice of kvs, for decoding
+// rows from key/values obtained by other means than a scan.
+type SpanKVFetcher struct {
+	KVs []roachpb.KeyValue
+}
+
+// nextKV implements the kvFetcher interface.
+func (f *SpanKVFetcher) nextKV(ctx context.Context) (bool, roachpb.KeyValue, error) {
+	if len(f.KVs) == 0 {
+		return false, roachpb.KeyValue{}, nil
+	}
+	var kv roachpb.KeyValue
+	kv, f.KVs = f.KVs[0], f.KVs[1:]
+	return true, kv, nil
+}
+
+// getRangesInfo implements the kvFetcher interface.
+func (f *SpanKVFetcher) getRangesInfo() []roachpb.RangeInfo {
+	panic("getRangesInfo() called on SpanKVFetcher")
+}
@@ -147,6 +147,11 @@ type MultiRowFetcher struct {
 	// when beginning a new scan.
 	traceKV bool
 
+	// lockStrength and lockWaitPolicy describe the row-level locks acquired
+	// on the rows returned by the scan. See SetLocking.
+	lockStrength   tree.LockingStrength
+	lockWaitPolicy tree.LockingWaitPolicy
+
 	// -- Fields updated during a scan --
 
 	kvFetcher      kvFetcher
@@ -266,6 +271,11 @@ func (mrf *MultiRowFetcher) Init(
 				if table.neededCols.Contains(int(table.cols[i].ID)) && !table.index.ContainsColumnID(table.cols[i].ID) {
 					return fmt.Errorf("requested column %s not in index", table.cols[i].Name)
 				}
+				if table.neededCols.Contains(int(table.cols[i].ID)) &&
+					table.index.Type == IndexDescriptor_INVERTED && table.index.ColumnIDs[0] == table.cols[i].ID {
+					return fmt.Errorf("requested column %s can't be read from inverted index %s",
+						table.cols[i].Name, table.index.Name)
+				}
 			}
 		}
 
@@ -309,6 +319,16 @@ func (mrf *MultiRowFetcher) Init(
 	return nil
 }
 
+// SetLocking configures the MultiRowFetcher to lock the rows it returns in
+// the scan's transaction, as requested by a SELECT ... FOR UPDATE clause.
+// Must be called before StartScan.
+func (mrf *MultiRowFetcher) SetLocking(
+	strength tree.LockingStrength, waitPolicy tree.LockingWaitPolicy,
+) {
+	mrf.lockStrength = strength
+	mrf.lockWaitPolicy = waitPolicy
+}
+
 // StartScan initializes and starts the key-value scan. Can be used multiple
 // times.
 func (mrf *MultiRowFetcher) StartScan(
@@ -343,6 +363,8 @@ func (mrf *MultiRowFetcher) StartScan(
 	if err != nil {
 		return err
 	}
+	f.lockStrength = mrf.lockStrength
+	f.lockWaitPolicy = mrf.lockWaitPolicy
 	return mrf.StartScanFrom(ctx, &f)
 }
 
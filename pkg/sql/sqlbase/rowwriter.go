@@ -55,12 +55,7 @@ type rowHelper struct {
 func (rh *rowHelper) encodeIndexes(
 	colIDtoRowIndex map[ColumnID]int, values []tree.Datum,
 ) (primaryIndexKey []byte, secondaryIndexEntries []IndexEntry, err error) {
-	if rh.primaryIndexKeyPrefix == nil {
-		rh.primaryIndexKeyPrefix = MakeIndexKeyPrefix(rh.TableDesc,
-			rh.TableDesc.PrimaryIndex.ID)
-	}
-	primaryIndexKey, _, err = EncodeIndexKey(
-		rh.TableDesc, &rh.TableDesc.PrimaryIndex, colIDtoRowIndex, values, rh.primaryIndexKeyPrefix)
+	primaryIndexKey, err = rh.encodePrimaryIndex(colIDtoRowIndex, values)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -71,17 +66,27 @@ func (rh *rowHelper) encodeIndexes(
 	return primaryIndexKey, secondaryIndexEntries, nil
 }
 
+// encodePrimaryIndex encodes the primary index key.
+func (rh *rowHelper) encodePrimaryIndex(
+	colIDtoRowIndex map[ColumnID]int, values []tree.Datum,
+) (primaryIndexKey []byte, err error) {
+	if rh.primaryIndexKeyPrefix == nil {
+		rh.primaryIndexKeyPrefix = MakeIndexKeyPrefix(rh.TableDesc,
+			rh.TableDesc.PrimaryIndex.ID)
+	}
+	primaryIndexKey, _, err = EncodeIndexKey(
+		rh.TableDesc, &rh.TableDesc.PrimaryIndex, colIDtoRowIndex, values, rh.primaryIndexKeyPrefix)
+	return primaryIndexKey, err
+}
+
 // encodeSecondaryIndexes encodes the secondary index keys. The
 // secondaryIndexEntries are only valid until the next call to encodeIndexes or
 // encodeSecondaryIndexes.
 func (rh *rowHelper) encodeSecondaryIndexes(
 	colIDtoRowIndex map[ColumnID]int, values []tree.Datum,
 ) (secondaryIndexEntries []IndexEntry, err error) {
-	if len(rh.indexEntries) != len(rh.Indexes) {
-		rh.indexEntries = make([]IndexEntry, len(rh.Indexes))
-	}
-	err = EncodeSecondaryIndexes(
-		rh.TableDesc, rh.Indexes, colIDtoRowIndex, values, rh.indexEntries)
+	rh.indexEntries, err = EncodeSecondaryIndexes(
+		rh.TableDesc, rh.Indexes, colIDtoRowIndex, values, rh.indexEntries[:0])
 	if err != nil {
 		return nil, err
 	}
@@ -368,16 +373,16 @@ type RowUpdater struct {
 	rd RowDeleter
 	ri RowInserter
 
-	Fks fkUpdateHelper
+	Fks      fkUpdateHelper
+	cascader *cascader
 
 	// For allocation avoidance.
-	marshalled      []roachpb.Value
-	newValues       []tree.Datum
-	key             roachpb.Key
-	indexEntriesBuf []IndexEntry
-	valueBuf        []byte
-	scratch         []byte
-	value           roachpb.Value
+	marshalled []roachpb.Value
+	newValues  []tree.Datum
+	key        roachpb.Key
+	valueBuf   []byte
+	scratch    []byte
+	value      roachpb.Value
 }
 
 type rowUpdaterType int
@@ -399,6 +404,10 @@ const (
 // The returned RowUpdater contains a FetchCols field that defines the
 // expectation of which values are passed as oldValues to UpdateRow. Any column
 // passed in requestedCols will be included in FetchCols.
+//
+// The referential actions of the foreign keys referencing the updated columns
+// are performed as configured by cascades, or checked like RESTRICT if it is
+// nil.
 func MakeRowUpdater(
 	txn *client.Txn,
 	tableDesc *TableDescriptor,
@@ -406,7 +415,26 @@ func MakeRowUpdater(
 	updateCols []ColumnDescriptor,
 	requestedCols []ColumnDescriptor,
 	updateType rowUpdaterType,
+	cascades *CascadeConfig,
+	alloc *DatumAlloc,
+) (RowUpdater, error) {
+	return makeRowUpdater(
+		txn, tableDesc, fkTables, updateCols, requestedCols, updateType, cascades, alloc, nil, /* parent */
+	)
+}
+
+// makeRowUpdater is MakeRowUpdater for the row writers of the tables
+// referencing another, whose cascades are part of those of parent.
+func makeRowUpdater(
+	txn *client.Txn,
+	tableDesc *TableDescriptor,
+	fkTables TableLookupsByID,
+	updateCols []ColumnDescriptor,
+	requestedCols []ColumnDescriptor,
+	updateType rowUpdaterType,
+	cascades *CascadeConfig,
 	alloc *DatumAlloc,
+	parent *cascader,
 ) (RowUpdater, error) {
 	updateColIDtoRowIndex := ColIDtoRowIndexFromCols(updateCols)
 
@@ -493,7 +521,7 @@ func MakeRowUpdater(
 		// them, so request them all.
 		var err error
 		if ru.rd, err = MakeRowDeleter(txn, tableDesc, fkTables,
-			tableCols, SkipFKs, alloc); err != nil {
+			tableCols, SkipFKs, nil /* cascades */, alloc); err != nil {
 			return RowUpdater{}, err
 		}
 		ru.FetchCols = ru.rd.FetchCols
@@ -550,6 +578,11 @@ func MakeRowUpdater(
 		ru.FetchColIDtoRowIndex, alloc); err != nil {
 		return RowUpdater{}, err
 	}
+	if ru.cascader, err = makeCascader(txn, tableDesc, fkTables, ru.Fks.inbound.fks,
+		ru.FetchColIDtoRowIndex, CheckUpdates, cascades, alloc); err != nil {
+		return RowUpdater{}, err
+	}
+	ru.cascader.nest(parent)
 	return ru, nil
 }
 
@@ -574,17 +607,11 @@ func (ru *RowUpdater) UpdateRow(
 		return nil, errors.Errorf("got %d values but expected %d", len(updateValues), len(ru.UpdateCols))
 	}
 
-	primaryIndexKey, secondaryIndexEntries, err := ru.Helper.encodeIndexes(ru.FetchColIDtoRowIndex, oldValues)
+	primaryIndexKey, err := ru.Helper.encodePrimaryIndex(ru.FetchColIDtoRowIndex, oldValues)
 	if err != nil {
 		return nil, err
 	}
 
-	// The secondary index entries returned by rowHelper.encodeIndexes are only
-	// valid until the next call to encodeIndexes. We need to copy them so that
-	// we can compare against the new secondary index entries.
-	secondaryIndexEntries = append(ru.indexEntriesBuf[:0], secondaryIndexEntries...)
-	ru.indexEntriesBuf = secondaryIndexEntries
-
 	// Check that the new value types match the column types. This needs to
 	// happen before index encoding because certain datum types (i.e. tuple)
 	// cannot be used as index values.
@@ -600,22 +627,20 @@ func (ru *RowUpdater) UpdateRow(
 		ru.newValues[ru.FetchColIDtoRowIndex[updateCol.ID]] = updateValues[i]
 	}
 
+	// Secondary indexes are encoded one at a time, as inverted indexes have a
+	// variable number of entries per row.
+	encodeSecondaryIndex := func(i int, values []tree.Datum) (IndexEntry, error) {
+		return EncodeSecondaryIndex(
+			ru.Helper.TableDesc, &ru.Helper.Indexes[i], ru.FetchColIDtoRowIndex, values)
+	}
+
 	rowPrimaryKeyChanged := false
-	var newSecondaryIndexEntries []IndexEntry
 	if ru.primaryKeyColChange {
-		var newPrimaryIndexKey []byte
-		newPrimaryIndexKey, newSecondaryIndexEntries, err =
-			ru.Helper.encodeIndexes(ru.FetchColIDtoRowIndex, ru.newValues)
+		newPrimaryIndexKey, err := ru.Helper.encodePrimaryIndex(ru.FetchColIDtoRowIndex, ru.newValues)
 		if err != nil {
 			return nil, err
 		}
 		rowPrimaryKeyChanged = !bytes.Equal(primaryIndexKey, newPrimaryIndexKey)
-	} else {
-		newSecondaryIndexEntries, err =
-			ru.Helper.encodeSecondaryIndexes(ru.FetchColIDtoRowIndex, ru.newValues)
-		if err != nil {
-			return nil, err
-		}
 	}
 
 	if rowPrimaryKeyChanged {
@@ -624,13 +649,30 @@ func (ru *RowUpdater) UpdateRow(
 		); err != nil {
 			return nil, err
 		}
-		for i := range newSecondaryIndexEntries {
-			if !bytes.Equal(newSecondaryIndexEntries[i].Key, secondaryIndexEntries[i].Key) {
+		for i := range ru.Helper.Indexes {
+			if ru.Helper.Indexes[i].Type == IndexDescriptor_INVERTED {
+				// Inverted indexes can't be referenced by foreign keys.
+				continue
+			}
+			secondaryIndexEntry, err := encodeSecondaryIndex(i, oldValues)
+			if err != nil {
+				return nil, err
+			}
+			newSecondaryIndexEntry, err := encodeSecondaryIndex(i, ru.newValues)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(newSecondaryIndexEntry.Key, secondaryIndexEntry.Key) {
 				if err := ru.Fks.checkIdx(ctx, ru.Helper.Indexes[i].ID, oldValues, ru.newValues); err != nil {
 					return nil, err
 				}
 			}
 		}
+		if ru.cascader != nil {
+			if err := ru.cascader.updateRow(ctx, oldValues, ru.newValues, traceKV); err != nil {
+				return nil, err
+			}
+		}
 		if err := ru.Fks.checker.runCheck(ctx, oldValues, ru.newValues); err != nil {
 			return nil, err
 		}
@@ -744,8 +786,21 @@ func (ru *RowUpdater) UpdateRow(
 	}
 
 	// Update secondary indexes.
-	for i, newSecondaryIndexEntry := range newSecondaryIndexEntries {
-		secondaryIndexEntry := secondaryIndexEntries[i]
+	for i := range ru.Helper.Indexes {
+		if ru.Helper.Indexes[i].Type == IndexDescriptor_INVERTED {
+			if err := ru.updateInvertedIndex(ctx, b, i, oldValues, traceKV); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		secondaryIndexEntry, err := encodeSecondaryIndex(i, oldValues)
+		if err != nil {
+			return nil, err
+		}
+		newSecondaryIndexEntry, err := encodeSecondaryIndex(i, ru.newValues)
+		if err != nil {
+			return nil, err
+		}
 		var expValue interface{}
 		if !bytes.Equal(newSecondaryIndexEntry.Key, secondaryIndexEntry.Key) {
 			if err := ru.Fks.checkIdx(ctx, ru.Helper.Indexes[i].ID, oldValues, ru.newValues); err != nil {
@@ -769,6 +824,11 @@ func (ru *RowUpdater) UpdateRow(
 			b.CPut(newSecondaryIndexEntry.Key, &newSecondaryIndexEntry.Value, expValue)
 		}
 	}
+	if ru.cascader != nil {
+		if err := ru.cascader.updateRow(ctx, oldValues, ru.newValues, traceKV); err != nil {
+			return nil, err
+		}
+	}
 	if err := ru.Fks.checker.runCheck(ctx, oldValues, ru.newValues); err != nil {
 		return nil, err
 	}
@@ -776,6 +836,60 @@ func (ru *RowUpdater) UpdateRow(
 	return ru.newValues, nil
 }
 
+// updateInvertedIndex adds to the batch the kv operations necessary to update
+// the entries of the i-th index of ru.Helper, an inverted index, from those of
+// oldValues to those of ru.newValues. Only the entries of the paths added to
+// or removed from the document are written.
+func (ru *RowUpdater) updateInvertedIndex(
+	ctx context.Context, b *client.Batch, i int, oldValues []tree.Datum, traceKV bool,
+) error {
+	index := &ru.Helper.Indexes[i]
+	oldEntries, err := EncodeInvertedIndexEntries(
+		ru.Helper.TableDesc, index, ru.FetchColIDtoRowIndex, oldValues)
+	if err != nil {
+		return err
+	}
+	newEntries, err := EncodeInvertedIndexEntries(
+		ru.Helper.TableDesc, index, ru.FetchColIDtoRowIndex, ru.newValues)
+	if err != nil {
+		return err
+	}
+	_, deleteOnly := ru.deleteOnlyIndex[i]
+
+	// Both lists of entries are sorted by key, so they can be merged.
+	for len(oldEntries) > 0 || len(newEntries) > 0 {
+		var cmp int
+		switch {
+		case len(newEntries) == 0:
+			cmp = -1
+		case len(oldEntries) == 0:
+			cmp = 1
+		default:
+			cmp = oldEntries[0].Key.Compare(newEntries[0].Key)
+		}
+		switch {
+		case cmp < 0:
+			if traceKV {
+				log.VEventf(ctx, 2, "Del %s", oldEntries[0].Key)
+			}
+			b.Del(oldEntries[0].Key)
+			oldEntries = oldEntries[1:]
+		case cmp > 0:
+			// Do not update Indexes in the DELETE_ONLY state.
+			if !deleteOnly {
+				if traceKV {
+					log.VEventf(ctx, 2, "CPut %s -> %v", newEntries[0].Key, newEntries[0].Value.PrettyPrint())
+				}
+				b.CPut(newEntries[0].Key, &newEntries[0].Value, nil)
+			}
+			newEntries = newEntries[1:]
+		default:
+			oldEntries, newEntries = oldEntries[1:], newEntries[1:]
+		}
+	}
+	return nil
+}
+
 // IsColumnOnlyUpdate returns true if this RowUpdater is only updating column
 // data (in contrast to updating the primary key or other indexes).
 func (ru *RowUpdater) IsColumnOnlyUpdate() bool {
@@ -794,6 +908,7 @@ type RowDeleter struct {
 	FetchCols            []ColumnDescriptor
 	FetchColIDtoRowIndex map[ColumnID]int
 	Fks                  fkDeleteHelper
+	cascader             *cascader
 	// For allocation avoidance.
 	startKey roachpb.Key
 	endKey   roachpb.Key
@@ -804,13 +919,35 @@ type RowDeleter struct {
 // The returned RowDeleter contains a FetchCols field that defines the
 // expectation of which values are passed as values to DeleteRow. Any column
 // passed in requestedCols will be included in FetchCols.
+//
+// If checkFKs is set, the referential actions of the foreign keys referencing
+// the table are performed as configured by cascades, or checked like RESTRICT
+// if it is nil.
 func MakeRowDeleter(
 	txn *client.Txn,
 	tableDesc *TableDescriptor,
 	fkTables TableLookupsByID,
 	requestedCols []ColumnDescriptor,
 	checkFKs bool,
+	cascades *CascadeConfig,
+	alloc *DatumAlloc,
+) (RowDeleter, error) {
+	return makeRowDeleter(
+		txn, tableDesc, fkTables, requestedCols, checkFKs, cascades, alloc, nil, /* parent */
+	)
+}
+
+// makeRowDeleter is MakeRowDeleter for the row writers of the tables
+// referencing another, whose cascades are part of those of parent.
+func makeRowDeleter(
+	txn *client.Txn,
+	tableDesc *TableDescriptor,
+	fkTables TableLookupsByID,
+	requestedCols []ColumnDescriptor,
+	checkFKs bool,
+	cascades *CascadeConfig,
 	alloc *DatumAlloc,
+	parent *cascader,
 ) (RowDeleter, error) {
 	indexes := tableDesc.Indexes
 	for _, m := range tableDesc.Mutations {
@@ -863,6 +1000,11 @@ func MakeRowDeleter(
 			fetchColIDtoRowIndex, alloc); err != nil {
 			return RowDeleter{}, err
 		}
+		if rd.cascader, err = makeCascader(txn, tableDesc, fkTables, rd.Fks.fks,
+			fetchColIDtoRowIndex, CheckDeletes, cascades, alloc); err != nil {
+			return RowDeleter{}, err
+		}
+		rd.cascader.nest(parent)
 	}
 
 	return rd, nil
@@ -873,6 +1015,13 @@ func MakeRowDeleter(
 func (rd *RowDeleter) DeleteRow(
 	ctx context.Context, b *client.Batch, values []tree.Datum, traceKV bool,
 ) error {
+	// The referencing rows are modified before the remaining references are
+	// checked, so that the checks don't find rows deleted by the cascades.
+	if rd.cascader != nil {
+		if err := rd.cascader.deleteRow(ctx, values, traceKV); err != nil {
+			return err
+		}
+	}
 	if err := rd.Fks.checkAll(ctx, values); err != nil {
 		return err
 	}
@@ -909,15 +1058,17 @@ func (rd *RowDeleter) DeleteIndexRow(
 	if err := rd.Fks.checkAll(ctx, values); err != nil {
 		return err
 	}
-	secondaryIndexEntry, err := EncodeSecondaryIndex(
-		rd.Helper.TableDesc, idx, rd.FetchColIDtoRowIndex, values)
+	secondaryIndexEntries, err := EncodeSecondaryIndexes(
+		rd.Helper.TableDesc, []IndexDescriptor{*idx}, rd.FetchColIDtoRowIndex, values, nil)
 	if err != nil {
 		return err
 	}
-	if traceKV {
-		log.VEventf(ctx, 2, "Del %s", secondaryIndexEntry.Key)
+	for _, secondaryIndexEntry := range secondaryIndexEntries {
+		if traceKV {
+			log.VEventf(ctx, 2, "Del %s", secondaryIndexEntry.Key)
+		}
+		b.Del(secondaryIndexEntry.Key)
 	}
-	b.Del(secondaryIndexEntry.Key)
 	return nil
 }
 
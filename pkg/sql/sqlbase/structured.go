@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -287,10 +288,35 @@ func (desc *IndexDescriptor) ColNamesString() string {
 }
 
 var isUnique = map[bool]string{true: "UNIQUE "}
+var isInverted = map[bool]string{true: "INVERTED "}
 
 // SQLString returns the SQL string describing this index. If non-empty,
 // "ON tableName" is included in the output in the correct place.
 func (desc *IndexDescriptor) SQLString(tableName string) string {
+	return desc.sqlString(tableName, desc.ColNamesString())
+}
+
+// IndexSQLString is like idx.SQLString, except that the computed columns of
+// desc indexed by idx are described by their expressions.
+func (desc *TableDescriptor) IndexSQLString(idx *IndexDescriptor, tableName string) string {
+	var buf bytes.Buffer
+	for i, name := range idx.ColumnNames {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		elem := tree.IndexElem{Column: tree.Name(name)}
+		if col, _, err := desc.FindColumnByName(elem.Column); err == nil && col.IsComputed() {
+			if expr, err := parser.ParseExpr(*col.ComputeExpr); err == nil {
+				elem.Expr = expr
+			}
+		}
+		tree.FormatNode(&buf, tree.FmtSimple, elem)
+		fmt.Fprintf(&buf, " %s", idx.ColumnDirections[i])
+	}
+	return idx.sqlString(tableName, buf.String())
+}
+
+func (desc *IndexDescriptor) sqlString(tableName string, colNames string) string {
 	var storing string
 	if len(desc.StoreColumnNames) > 0 {
 		colNames := make(tree.NameList, len(desc.StoreColumnNames))
@@ -303,11 +329,12 @@ func (desc *IndexDescriptor) SQLString(tableName string) string {
 	if tableName != "" {
 		onTable = fmt.Sprintf("ON %s ", tableName)
 	}
-	return fmt.Sprintf("%sINDEX %s%s (%s)%s",
+	return fmt.Sprintf("%s%sINDEX %s%s (%s)%s",
 		isUnique[desc.Unique],
+		isInverted[desc.Type == IndexDescriptor_INVERTED],
 		onTable,
 		tree.AsString(tree.Name(desc.Name)),
-		desc.ColNamesString(),
+		colNames,
 		storing,
 	)
 }
@@ -1089,6 +1116,14 @@ func (desc *TableDescriptor) ValidateTable() error {
 				idx := desc.Index
 				return errors.Errorf("mutation in state %s, direction %s, index %s, id %v", m.State, m.Direction, idx.Name, idx.ID)
 			}
+		case *DescriptorMutation_NotNull:
+			colID := desc.NotNull.ColumnID
+			if unSetEnums {
+				return errors.Errorf("mutation in state %s, direction %s, not null constraint on column id %v", m.State, m.Direction, colID)
+			}
+			if _, ok := columnIDs[colID]; !ok {
+				return errors.Errorf("not null constraint mutation on unknown column id %v", colID)
+			}
 		default:
 			return errors.Errorf("mutation in state %s, direction %s, and no column/index descriptor", m.State, m.Direction)
 		}
@@ -1236,6 +1271,24 @@ func (desc *TableDescriptor) validateTableIndexes(
 			return fmt.Errorf("index %q must contain at least 1 column", index.Name)
 		}
 
+		if index.Type == IndexDescriptor_INVERTED {
+			if index.ID == desc.PrimaryIndex.ID {
+				return fmt.Errorf("primary index %q can't be inverted", index.Name)
+			}
+			if len(index.ColumnIDs) != 1 {
+				return fmt.Errorf("inverted index %q must contain exactly 1 column", index.Name)
+			}
+			if index.Unique {
+				return fmt.Errorf("inverted index %q can't be unique", index.Name)
+			}
+			if len(index.StoreColumnIDs) > 0 {
+				return fmt.Errorf("inverted index %q can't store columns", index.Name)
+			}
+			if index.ColumnDirections[0] != IndexDescriptor_ASC {
+				return fmt.Errorf("inverted index %q can't be descending", index.Name)
+			}
+		}
+
 		for i, name := range index.ColumnNames {
 			colID, ok := columnNames[name]
 			if !ok {
@@ -1659,10 +1712,26 @@ func notIndexableError(cols []ColumnDescriptor) error {
 	return errors.New(result)
 }
 
+// indexableColumns returns the columns of tableDesc, including those being
+// added along with an index.
+func indexableColumns(tableDesc *TableDescriptor) []ColumnDescriptor {
+	cols := tableDesc.Columns
+	if len(tableDesc.Mutations) > 0 {
+		cols = append([]ColumnDescriptor(nil), cols...)
+		for _, m := range tableDesc.Mutations {
+			if col := m.GetColumn(); col != nil && m.Direction == DescriptorMutation_ADD {
+				cols = append(cols, *col)
+			}
+		}
+	}
+	return cols
+}
+
 func checkColumnsValidForIndex(tableDesc *TableDescriptor, indexColNames []string) error {
 	invalidColumns := make([]ColumnDescriptor, 0, len(indexColNames))
+	cols := indexableColumns(tableDesc)
 	for _, indexCol := range indexColNames {
-		for _, col := range tableDesc.Columns {
+		for _, col := range cols {
 			if col.Name == indexCol {
 				if !columnTypeIsIndexable(col.Type) {
 					invalidColumns = append(invalidColumns, col)
@@ -1676,6 +1745,21 @@ func checkColumnsValidForIndex(tableDesc *TableDescriptor, indexColNames []strin
 	return nil
 }
 
+func checkColumnsValidForInvertedIndex(tableDesc *TableDescriptor, indexColNames []string) error {
+	if len(indexColNames) != 1 {
+		return pgerror.NewError(pgerror.CodeFeatureNotSupportedError,
+			"inverted indexes can't be on multiple columns")
+	}
+	for _, col := range indexableColumns(tableDesc) {
+		if col.Name == indexColNames[0] && col.Type.SemanticType != ColumnType_JSON {
+			return pgerror.NewErrorf(pgerror.CodeDatatypeMismatchError,
+				"column %s is of type %s and thus can't be indexed by an inverted index",
+				col.Name, col.Type.SemanticType)
+		}
+	}
+	return nil
+}
+
 // AddColumn adds a column to the table.
 func (desc *TableDescriptor) AddColumn(col ColumnDescriptor) {
 	desc.Columns = append(desc.Columns, col)
@@ -1688,7 +1772,14 @@ func (desc *TableDescriptor) AddFamily(fam ColumnFamilyDescriptor) {
 
 // AddIndex adds an index to the table.
 func (desc *TableDescriptor) AddIndex(idx IndexDescriptor, primary bool) error {
-	if err := checkColumnsValidForIndex(desc, idx.ColumnNames); err != nil {
+	if idx.Type == IndexDescriptor_INVERTED {
+		if primary {
+			return errors.New("primary keys can't be inverted indexes")
+		}
+		if err := checkColumnsValidForInvertedIndex(desc, idx.ColumnNames); err != nil {
+			return err
+		}
+	} else if err := checkColumnsValidForIndex(desc, idx.ColumnNames); err != nil {
 		return err
 	}
 	if primary {
@@ -2008,12 +2099,25 @@ func (desc *TableDescriptor) MakeMutationComplete(m DescriptorMutation) {
 	case DescriptorMutation_ADD:
 		switch t := m.Descriptor_.(type) {
 		case *DescriptorMutation_Column:
-			desc.AddColumn(*t.Column)
+			if m.ColumnConversion != nil {
+				desc.swapConvertedColumn(*t.Column, m.ColumnConversion.SourceColumnID)
+			} else {
+				desc.AddColumn(*t.Column)
+			}
 
 		case *DescriptorMutation_Index:
 			if err := desc.AddIndex(*t.Index, false); err != nil {
 				panic(err)
 			}
+
+		case *DescriptorMutation_NotNull:
+			// The existing rows have been validated, the column can now be
+			// marked as non-nullable.
+			for i := range desc.Columns {
+				if desc.Columns[i].ID == t.NotNull.ColumnID {
+					desc.Columns[i].Nullable = false
+				}
+			}
 		}
 
 	case DescriptorMutation_DROP:
@@ -2026,6 +2130,34 @@ func (desc *TableDescriptor) MakeMutationComplete(m DescriptorMutation) {
 	}
 }
 
+// swapConvertedColumn replaces the source column of a completed ALTER
+// COLUMN ... TYPE with the column holding the converted values, which takes
+// over its name and position. The source column, renamed to the temporary
+// name of the converted column, is queued to be dropped by a mutation that
+// the caller must finalize.
+func (desc *TableDescriptor) swapConvertedColumn(col ColumnDescriptor, sourceID ColumnID) {
+	for i := range desc.Columns {
+		if desc.Columns[i].ID != sourceID {
+			continue
+		}
+		source := desc.Columns[i]
+		col.Name, source.Name = source.Name, col.Name
+		desc.Columns[i] = col
+		for j := range desc.Families {
+			for k, id := range desc.Families[j].ColumnIDs {
+				switch id {
+				case col.ID:
+					desc.Families[j].ColumnNames[k] = col.Name
+				case source.ID:
+					desc.Families[j].ColumnNames[k] = source.Name
+				}
+			}
+		}
+		desc.AddColumnMutation(source, DescriptorMutation_DROP)
+		return
+	}
+}
+
 // AddColumnMutation adds a column mutation to desc.Mutations.
 func (desc *TableDescriptor) AddColumnMutation(
 	c ColumnDescriptor, direction DescriptorMutation_Direction,
@@ -2034,11 +2166,59 @@ func (desc *TableDescriptor) AddColumnMutation(
 	desc.addMutation(m)
 }
 
+// AddColumnConversionMutation adds a mutation to desc.Mutations adding a
+// column whose values are computed from those of the column with ID sourceID
+// by expr, in which the source column is referred to as @1. Once the
+// mutation completes, the new column replaces the source column.
+func (desc *TableDescriptor) AddColumnConversionMutation(
+	c ColumnDescriptor, sourceID ColumnID, expr string,
+) {
+	m := DescriptorMutation{
+		Descriptor_: &DescriptorMutation_Column{Column: &c},
+		Direction:   DescriptorMutation_ADD,
+		ColumnConversion: &DescriptorMutation_ColumnConversion{
+			SourceColumnID: sourceID,
+			Expr:           expr,
+		},
+	}
+	desc.addMutation(m)
+}
+
+// AddNotNullMutation adds a mutation to desc.Mutations adding a NOT NULL
+// constraint to the column with the given ID.
+func (desc *TableDescriptor) AddNotNullMutation(colID ColumnID) {
+	m := DescriptorMutation{
+		Descriptor_: &DescriptorMutation_NotNull{
+			NotNull: &DescriptorMutation_NotNullConstraint{ColumnID: colID},
+		},
+		Direction: DescriptorMutation_ADD,
+	}
+	desc.addMutation(m)
+}
+
+// IsValidatingNotNull returns whether a NOT NULL constraint is being added
+// to the column with the given ID. Writes must already enforce such a
+// constraint, since rows written after the validation of the existing rows
+// started are not validated.
+func (desc *TableDescriptor) IsValidatingNotNull(colID ColumnID) bool {
+	for _, m := range desc.Mutations {
+		if n := m.GetNotNull(); n != nil && n.ColumnID == colID &&
+			m.Direction == DescriptorMutation_ADD {
+			return true
+		}
+	}
+	return false
+}
+
 // AddIndexMutation adds an index mutation to desc.Mutations.
 func (desc *TableDescriptor) AddIndexMutation(
 	idx IndexDescriptor, direction DescriptorMutation_Direction,
 ) error {
-	if err := checkColumnsValidForIndex(desc, idx.ColumnNames); err != nil {
+	if idx.Type == IndexDescriptor_INVERTED {
+		if err := checkColumnsValidForInvertedIndex(desc, idx.ColumnNames); err != nil {
+			return err
+		}
+	} else if err := checkColumnsValidForIndex(desc, idx.ColumnNames); err != nil {
 		return err
 	}
 	m := DescriptorMutation{Descriptor_: &DescriptorMutation_Index{Index: &idx}, Direction: direction}
@@ -2508,3 +2688,13 @@ var ForeignKeyReferenceActionValue = [...]ForeignKeyReference_Action{
 	tree.SetNull:    ForeignKeyReference_SET_NULL,
 	tree.Cascade:    ForeignKeyReference_CASCADE,
 }
+
+// ForeignKeyReferenceActionType allows the conversion between a
+// ForeignKeyReference_Action and a tree.ReferenceAction.
+var ForeignKeyReferenceActionType = [...]tree.ReferenceAction{
+	ForeignKeyReference_NO_ACTION:   tree.NoAction,
+	ForeignKeyReference_RESTRICT:    tree.Restrict,
+	ForeignKeyReference_SET_DEFAULT: tree.SetDefault,
+	ForeignKeyReference_SET_NULL:    tree.SetNull,
+	ForeignKeyReference_CASCADE:     tree.Cascade,
+}
@@ -87,6 +87,15 @@ func SanitizeVarFreeExpr(
 	return typedExpr, nil
 }
 
+// MakeColumnType returns the ColumnType of a column declared with type typ.
+func MakeColumnType(typ coltypes.T, semaCtx *tree.SemaContext) (ColumnType, error) {
+	base, err := DatumTypeToColumnType(coltypes.CastTargetToDatumType(typ))
+	if err != nil {
+		return ColumnType{}, err
+	}
+	return populateTypeAttrs(base, typ, semaCtx)
+}
+
 func populateTypeAttrs(
 	base ColumnType, typ coltypes.T, semaCtx *tree.SemaContext,
 ) (ColumnType, error) {
@@ -827,7 +836,8 @@ func DecodeIndexKey(
 
 // DecodeKeyVals decodes the values that are part of the key. The decoded
 // values are stored in the vals. If this slice is nil, the direction
-// used will default to encoding.Ascending.
+// used will default to encoding.Ascending. The values of inverted index
+// columns can't be decoded and are left unset.
 func DecodeKeyVals(
 	types []ColumnType, vals []EncDatum, directions []encoding.Direction, key []byte,
 ) ([]byte, error) {
@@ -836,6 +846,17 @@ func DecodeKeyVals(
 			len(directions), len(vals))
 	}
 	for j := range vals {
+		if types[j].SemanticType == ColumnType_JSON {
+			// JSON columns are only part of the keys of inverted indexes, which
+			// hold a path through the document rather than the document itself.
+			// The path is skipped, leaving the value unset.
+			l, err := json.PeekInvertedIndexKeyLength(key)
+			if err != nil {
+				return nil, err
+			}
+			vals[j], key = EncDatum{}, key[l:]
+			continue
+		}
 		enc := DatumEncoding_ASCENDING_KEY
 		if directions != nil && (directions[j] == encoding.Descending) {
 			enc = DatumEncoding_DESCENDING_KEY
@@ -1532,6 +1553,10 @@ func EncodeSecondaryIndex(
 	colMap map[ColumnID]int,
 	values []tree.Datum,
 ) (IndexEntry, error) {
+	if secondaryIndex.Type == IndexDescriptor_INVERTED {
+		return IndexEntry{}, errors.Errorf(
+			"inverted index %q has a variable number of entries per row", secondaryIndex.Name)
+	}
 	secondaryIndexKeyPrefix := MakeIndexKeyPrefix(tableDesc, secondaryIndex.ID)
 	secondaryIndexKey, containsNull, err := EncodeIndexKey(
 		tableDesc, secondaryIndex, colMap, values, secondaryIndexKeyPrefix)
@@ -1604,25 +1629,88 @@ func EncodeSecondaryIndex(
 	return entry, nil
 }
 
-// EncodeSecondaryIndexes encodes key/values for the secondary indexes. colMap
-// maps ColumnIDs to indices in `values`. secondaryIndexEntries is the return
-// value (passed as a parameter so the caller can reuse between rows) and is
-// expected to be the same length as indexes.
+// EncodeInvertedIndexEntries encodes key/values for an inverted index: one
+// entry per path through the JSON document held in the indexed column, in
+// key order. colMap maps ColumnIDs to indices in `values`.
+func EncodeInvertedIndexEntries(
+	tableDesc *TableDescriptor,
+	invertedIndex *IndexDescriptor,
+	colMap map[ColumnID]int,
+	values []tree.Datum,
+) ([]IndexEntry, error) {
+	val := findColumnValue(invertedIndex.ColumnIDs[0], colMap, values)
+	if val == tree.DNull {
+		// NULL documents aren't indexed.
+		return nil, nil
+	}
+	doc, ok := val.(*tree.DJSON)
+	if !ok {
+		return nil, errors.Errorf("value type %s can't be stored in inverted index %q",
+			val.ResolvedType(), invertedIndex.Name)
+	}
+
+	// Add the extra columns to make the keys unique - they are encoded
+	// ascendingly which is done by passing nil for the encoding directions.
+	extraKey, _, err := EncodeColumns(invertedIndex.ExtraColumnIDs, nil,
+		colMap, values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := doc.JSON.EncodeInvertedIndexKeys(MakeIndexKeyPrefix(tableDesc, invertedIndex.ID))
+	entries := make([]IndexEntry, 0, len(paths))
+	for _, path := range paths {
+		var entry IndexEntry
+		// Index keys are considered "sentinel" keys in that they do not have a
+		// column ID suffix.
+		entry.Key = keys.MakeFamilyKey(append(path, extraKey...), 0)
+		// The zero value for an index-key is a 0-length bytes value.
+		entry.Value.SetBytes([]byte{})
+		entries = append(entries, entry)
+	}
+
+	// Documents repeating a value, like [1, 1], have duplicate paths, which
+	// must be written only once.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key.Compare(entries[j].Key) < 0
+	})
+	unique := entries[:0]
+	for i := range entries {
+		if i == 0 || !entries[i].Key.Equal(entries[i-1].Key) {
+			unique = append(unique, entries[i])
+		}
+	}
+	return unique, nil
+}
+
+// EncodeSecondaryIndexes encodes key/values for the secondary indexes,
+// appending them to secondaryIndexEntries (passed as a parameter so the
+// caller can reuse it between rows). colMap maps ColumnIDs to indices in
+// `values`. Forward indexes have exactly one entry per row, while inverted
+// indexes have any number of them.
 func EncodeSecondaryIndexes(
 	tableDesc *TableDescriptor,
 	indexes []IndexDescriptor,
 	colMap map[ColumnID]int,
 	values []tree.Datum,
 	secondaryIndexEntries []IndexEntry,
-) error {
+) ([]IndexEntry, error) {
 	for i := range indexes {
-		var err error
-		secondaryIndexEntries[i], err = EncodeSecondaryIndex(tableDesc, &indexes[i], colMap, values)
+		if indexes[i].Type == IndexDescriptor_INVERTED {
+			entries, err := EncodeInvertedIndexEntries(tableDesc, &indexes[i], colMap, values)
+			if err != nil {
+				return nil, err
+			}
+			secondaryIndexEntries = append(secondaryIndexEntries, entries...)
+			continue
+		}
+		entry, err := EncodeSecondaryIndex(tableDesc, &indexes[i], colMap, values)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		secondaryIndexEntries = append(secondaryIndexEntries, entry)
 	}
-	return nil
+	return secondaryIndexEntries, nil
 }
 
 // CheckColumnType verifies that a given value is compatible
@@ -0,0 +1,81 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+)
+
+// InsertNewStat inserts a new statistic in the system.table_statistics table.
+// The statistic ID is generated with unique_rowid(). A histogram is only
+// stored if h is not nil.
+func InsertNewStat(
+	ctx context.Context,
+	executor sqlutil.InternalExecutor,
+	txn *client.Txn,
+	tableID sqlbase.ID,
+	name string,
+	columnIDs []sqlbase.ColumnID,
+	rowCount, distinctCount, nullCount int64,
+	h *HistogramData,
+) error {
+	// We must pass a nil interface{} if we want to insert a NULL.
+	var nameVal, histogramVal interface{}
+	if name != "" {
+		nameVal = name
+	}
+	if h != nil {
+		var err error
+		histogramVal, err = h.Marshal()
+		if err != nil {
+			return err
+		}
+	}
+
+	columnIDsVal := tree.NewDArray(types.Int)
+	for _, c := range columnIDs {
+		if err := columnIDsVal.Append(tree.NewDInt(tree.DInt(int(c)))); err != nil {
+			return err
+		}
+	}
+
+	_, err := executor.ExecuteStatementInTransaction(
+		ctx, "insert-statistic", txn,
+		`INSERT INTO system.table_statistics (
+					"tableID",
+					"statisticID",
+					"name",
+					"columnIDs",
+					"rowCount",
+					"distinctCount",
+					"nullCount",
+					histogram
+				) VALUES ($1, unique_rowid(), $2, $3, $4, $5, $6, $7)`,
+		tableID,
+		nameVal,
+		columnIDsVal,
+		rowCount,
+		distinctCount,
+		nullCount,
+		histogramVal,
+	)
+	return err
+}
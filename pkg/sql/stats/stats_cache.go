@@ -0,0 +1,190 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/cache"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// TableStatistic is a statistic on a set of columns of a table, as stored in
+// system.table_statistics.
+type TableStatistic struct {
+	// StatisticID is the ID of the statistic; it also identifies its histogram.
+	StatisticID uint64
+	// Name is the name of the statistic; it is empty if the statistic was
+	// created without a name.
+	Name string
+	// ColumnIDs are the columns on which the statistic was collected.
+	ColumnIDs []sqlbase.ColumnID
+	// CreatedAt is the time at which the statistic was created.
+	CreatedAt time.Time
+	// RowCount is the number of rows in the table.
+	RowCount uint64
+	// DistinctCount is the estimated number of distinct values (or tuples of
+	// values) of the columns.
+	DistinctCount uint64
+	// NullCount is the number of rows where all the columns are NULL.
+	NullCount uint64
+	// Histogram is the histogram of the values of the column; it is nil if no
+	// histogram was collected, which is always the case for multi-column
+	// statistics.
+	Histogram *HistogramData
+}
+
+// tableStatsExpiration is the time after which the cached statistics of a
+// table are read again from system.table_statistics. CREATE STATISTICS
+// invalidates the statistics cached on the gateway node; the other nodes pick
+// up the new statistics once their cache entries expire.
+const tableStatsExpiration = time.Minute
+
+// cacheEntry is the value stored in the cache for each table.
+type cacheEntry struct {
+	stats     []*TableStatistic
+	fetchedAt time.Time
+}
+
+// A TableStatisticsCache is a cache of the statistics of each table, keyed
+// by table ID. It is used by the planner to estimate the cost of plans.
+type TableStatisticsCache struct {
+	// NB: This can't be a RWMutex for lookup because UnorderedCache.Get
+	// manipulates an internal LRU list.
+	mu struct {
+		syncutil.Mutex
+		cache *cache.UnorderedCache
+	}
+	clientDB    *client.DB
+	sqlExecutor sqlutil.InternalExecutor
+}
+
+// NewTableStatisticsCache creates a new TableStatisticsCache that holds the
+// statistics of up to cacheSize tables.
+func NewTableStatisticsCache(
+	cacheSize int, db *client.DB, sqlExecutor sqlutil.InternalExecutor,
+) *TableStatisticsCache {
+	tableStatsCache := &TableStatisticsCache{
+		clientDB:    db,
+		sqlExecutor: sqlExecutor,
+	}
+	tableStatsCache.mu.cache = cache.NewUnorderedCache(cache.Config{
+		Policy:      cache.CacheLRU,
+		ShouldEvict: func(s int, key, value interface{}) bool { return s > cacheSize },
+	})
+	return tableStatsCache
+}
+
+// GetTableStats returns the statistics of the given table, most recent
+// first. The result is empty if no statistics were collected on the table.
+// The returned statistics must not be modified.
+func (sc *TableStatisticsCache) GetTableStats(
+	ctx context.Context, tableID sqlbase.ID,
+) ([]*TableStatistic, error) {
+	if sqlbase.IsReservedID(tableID) {
+		// Don't try to get statistics for system tables (most importantly,
+		// for table_statistics itself).
+		return nil, nil
+	}
+
+	sc.mu.Lock()
+	if v, ok := sc.mu.cache.Get(tableID); ok {
+		e := v.(*cacheEntry)
+		if timeutil.Since(e.fetchedAt) < tableStatsExpiration {
+			sc.mu.Unlock()
+			return e.stats, nil
+		}
+	}
+	sc.mu.Unlock()
+
+	// The statistics are read outside of the lock; concurrent lookups of the
+	// same table may read them more than once, which is harmless. The
+	// statistics are shared by all the sessions, so the lookup is not recorded
+	// in the trace of the statement that happens to trigger it.
+	ctx = opentracing.ContextWithSpan(ctx, nil)
+	stats, err := sc.readTableStats(ctx, tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.mu.cache.Add(tableID, &cacheEntry{stats: stats, fetchedAt: timeutil.Now()})
+	return stats, nil
+}
+
+// InvalidateTableStats removes the statistics of the given table from the
+// cache, so that the next lookup reads them from system.table_statistics.
+func (sc *TableStatisticsCache) InvalidateTableStats(ctx context.Context, tableID sqlbase.ID) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.mu.cache.Del(tableID)
+}
+
+// readTableStats reads the statistics of a table from
+// system.table_statistics, most recent first.
+func (sc *TableStatisticsCache) readTableStats(
+	ctx context.Context, tableID sqlbase.ID,
+) ([]*TableStatistic, error) {
+	var rows []tree.Datums
+	if err := sc.clientDB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		rows, err = sc.sqlExecutor.QueryRowsInTransaction(
+			ctx, "get-table-statistics", txn,
+			`SELECT "statisticID", name, "columnIDs", "createdAt", "rowCount",
+			        "distinctCount", "nullCount", histogram
+			 FROM system.table_statistics
+			 WHERE "tableID" = $1
+			 ORDER BY "createdAt" DESC, "statisticID" DESC`,
+			tableID,
+		)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	stats := make([]*TableStatistic, len(rows))
+	for i, r := range rows {
+		s := &TableStatistic{
+			StatisticID:   uint64(tree.MustBeDInt(r[0])),
+			CreatedAt:     r[3].(*tree.DTimestamp).Time,
+			RowCount:      uint64(tree.MustBeDInt(r[4])),
+			DistinctCount: uint64(tree.MustBeDInt(r[5])),
+			NullCount:     uint64(tree.MustBeDInt(r[6])),
+		}
+		if r[1] != tree.DNull {
+			s.Name = string(tree.MustBeDString(r[1]))
+		}
+		for _, d := range tree.MustBeDArray(r[2]).Array {
+			s.ColumnIDs = append(s.ColumnIDs, sqlbase.ColumnID(tree.MustBeDInt(d)))
+		}
+		if r[7] != tree.DNull {
+			s.Histogram = &HistogramData{}
+			if err := s.Histogram.Unmarshal([]byte(*r[7].(*tree.DBytes))); err != nil {
+				return nil, err
+			}
+		}
+		stats[i] = s
+	}
+	return stats, nil
+}
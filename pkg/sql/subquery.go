@@ -41,6 +41,96 @@ type subquery struct {
 	started  bool
 	plan     planNode
 	result   tree.Datum
+
+	// The following fields are set for correlated subqueries, i.e.
+	// subqueries that refer to columns of the enclosing query. Such
+	// subqueries cannot be evaluated once upfront; instead they are
+	// planned and run anew for every row of the enclosing query, with the
+	// current values of the outer columns.
+	//
+	// scope is the scope through which the subquery accesses the outer
+	// columns, and scopes is the stack of scopes visible to the subquery
+	// (the last one is scope). outerArgs contains one expression for each
+	// outer column in scope.cols, in terms of the enclosing query.
+	scope     *subqueryScope
+	scopes    []*subqueryScope
+	outerArgs []tree.TypedExpr
+}
+
+// subqueryScope describes the data sources of a query that contains
+// subqueries. Column references in a subquery that cannot be resolved
+// using the subquery's own data sources are resolved using the scopes
+// of the enclosing queries, innermost first.
+type subqueryScope struct {
+	sources multiSourceInfo
+
+	// cols contains the indexes of the columns (in the flattened sources)
+	// that are referenced by the subquery; vars contains the corresponding
+	// outerVars.
+	cols []int
+	vars []*outerVar
+
+	// values contains the current values of the columns in cols. It is
+	// only populated while a correlated subquery is evaluated.
+	values tree.Datums
+}
+
+// outerColumn returns an expression that refers to the given outer
+// column from inside the subquery. While the subquery is planned for
+// evaluation, the current value of the column is returned directly so
+// that the plan can take advantage of it (e.g. to restrict index spans).
+func (s *subqueryScope) outerColumn(colIdx int, typ types.T, name *tree.ColumnItem) tree.Expr {
+	for i, c := range s.cols {
+		if c == colIdx {
+			return s.value(i)
+		}
+	}
+	s.cols = append(s.cols, colIdx)
+	s.vars = append(s.vars, &outerVar{scope: s, idx: len(s.vars), typ: typ, name: name})
+	return s.value(len(s.cols) - 1)
+}
+
+func (s *subqueryScope) value(i int) tree.Expr {
+	if i < len(s.values) && s.values[i] != tree.DNull {
+		return s.values[i]
+	}
+	return s.vars[i]
+}
+
+// outerVar is a reference to a column of an enclosing query from inside a
+// correlated subquery. Its value is constant during each evaluation of
+// the subquery.
+type outerVar struct {
+	scope *subqueryScope
+	idx   int
+	typ   types.T
+	name  *tree.ColumnItem
+}
+
+var _ tree.TypedExpr = &outerVar{}
+var _ tree.VariableExpr = &outerVar{}
+
+func (v *outerVar) Format(buf *bytes.Buffer, f tree.FmtFlags) {
+	tree.FormatNode(buf, f, v.name)
+}
+
+func (v *outerVar) String() string { return tree.AsString(v) }
+
+func (v *outerVar) Walk(_ tree.Visitor) tree.Expr { return v }
+
+func (v *outerVar) Variable() {}
+
+func (v *outerVar) TypeCheck(_ *tree.SemaContext, _ types.T) (tree.TypedExpr, error) {
+	return v, nil
+}
+
+func (v *outerVar) ResolvedType() types.T { return v.typ }
+
+func (v *outerVar) Eval(_ *tree.EvalContext) (tree.Datum, error) {
+	if v.scope.values == nil {
+		panic(fmt.Sprintf("outer column %s was not evaluated", v))
+	}
+	return v.scope.values[v.idx], nil
 }
 
 type subqueryExecMode int
@@ -82,7 +172,26 @@ func (s *subquery) Format(buf *bytes.Buffer, f tree.FmtFlags) {
 func (s *subquery) String() string { return tree.AsString(s) }
 
 func (s *subquery) Walk(v tree.Visitor) tree.Expr {
-	return s
+	// The outer columns referenced by a correlated subquery are
+	// expressions of the enclosing query, and must be visible to the
+	// visitors that rebind or convert its variables.
+	var args []tree.TypedExpr
+	for i, e := range s.outerArgs {
+		newExpr, changed := tree.WalkExpr(v, e)
+		if !changed {
+			continue
+		}
+		if args == nil {
+			args = append([]tree.TypedExpr(nil), s.outerArgs...)
+		}
+		args[i] = newExpr.(tree.TypedExpr)
+	}
+	if args == nil {
+		return s
+	}
+	sCopy := *s
+	sCopy.outerArgs = args
+	return &sCopy
 }
 
 func (s *subquery) Variable() {}
@@ -101,16 +210,69 @@ func (s *subquery) TypeCheck(_ *tree.SemaContext, desired types.T) (tree.TypedEx
 
 func (s *subquery) ResolvedType() types.T { return s.typ }
 
-func (s *subquery) Eval(_ *tree.EvalContext) (tree.Datum, error) {
+func (s *subquery) Eval(ctx *tree.EvalContext) (tree.Datum, error) {
+	if s.outerArgs != nil {
+		return s.evalCorrelated(ctx)
+	}
 	if s.result == nil {
 		panic("subquery was not pre-evaluated properly")
 	}
 	return s.result, nil
 }
 
+// evalCorrelated plans and runs a correlated subquery for the current row
+// of the enclosing query.
+func (s *subquery) evalCorrelated(evalCtx *tree.EvalContext) (tree.Datum, error) {
+	values := make(tree.Datums, len(s.outerArgs))
+	for i, arg := range s.outerArgs {
+		d, err := arg.Eval(evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = d
+	}
+
+	// Running the subquery's plan changes the IndexedVarHelper of the
+	// evaluation context; restore it for the rest of the enclosing
+	// expression.
+	defer func(h *tree.IndexedVarHelper) { evalCtx.IVarHelper = h }(evalCtx.IVarHelper)
+
+	// The outer columns are visible to the plan through the scope for the
+	// duration of the evaluation.
+	defer func(v tree.Datums) { s.scope.values = v }(s.scope.values)
+	s.scope.values = values
+
+	ctx := evalCtx.Ctx()
+	p := s.planner
+	outerScopes := p.outerScopes
+	p.outerScopes = s.scopes
+	plan, err := p.newPlan(ctx, s.subquery.Select, nil)
+	p.outerScopes = outerScopes
+	if err != nil {
+		return nil, err
+	}
+	if plan, err = p.optimizeSubqueryPlan(ctx, s.execMode, plan); err != nil {
+		plan.Close(ctx)
+		return nil, err
+	}
+	if err := p.startPlan(ctx, plan); err != nil {
+		plan.Close(ctx)
+		return nil, err
+	}
+	return s.evalPlan(ctx, plan)
+}
+
 func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 	// After evaluation, there is no plan remaining.
-	defer func() { s.plan.Close(ctx); s.plan = nil }()
+	plan := s.plan
+	s.plan = nil
+	return s.evalPlan(ctx, plan)
+}
+
+// evalPlan computes the value of the subquery from the results of the
+// given plan, which has been started. The plan is closed afterwards.
+func (s *subquery) evalPlan(ctx context.Context, plan planNode) (result tree.Datum, err error) {
+	defer plan.Close(ctx)
 
 	params := runParams{
 		ctx: ctx,
@@ -120,7 +282,7 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 	case execModeExists:
 		// For EXISTS expressions, all we want to know is if there is at least one
 		// result.
-		next, err := s.plan.Next(params)
+		next, err := plan.Next(params)
 		if err != nil {
 			return result, err
 		}
@@ -133,9 +295,9 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 
 	case execModeAllRows, execModeAllRowsNormalized:
 		var rows tree.DTuple
-		next, err := s.plan.Next(params)
-		for ; next; next, err = s.plan.Next(params) {
-			values := s.plan.Values()
+		next, err := plan.Next(params)
+		for ; next; next, err = plan.Next(params) {
+			values := plan.Values()
 			switch len(values) {
 			case 1:
 				// This seems hokey, but if we don't do this then the subquery expands
@@ -155,7 +317,7 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 			return result, err
 		}
 
-		if ok, dir := s.subqueryTupleOrdering(); ok {
+		if ok, dir := subqueryTupleOrdering(plan); ok {
 			if dir == encoding.Descending {
 				rows.D.Reverse()
 			}
@@ -168,12 +330,12 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 
 	case execModeOneRow:
 		result = tree.DNull
-		hasRow, err := s.plan.Next(params)
+		hasRow, err := plan.Next(params)
 		if err != nil {
 			return result, err
 		}
 		if hasRow {
-			values := s.plan.Values()
+			values := plan.Values()
 			switch len(values) {
 			case 1:
 				result = values[0]
@@ -182,7 +344,7 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 				copy(valuesCopy.D, values)
 				result = valuesCopy
 			}
-			another, err := s.plan.Next(params)
+			another, err := plan.Next(params)
 			if err != nil {
 				return result, err
 			}
@@ -197,7 +359,7 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 	return result, nil
 }
 
-// subqueryTupleOrdering returns whether the rows of the subquery are ordered
+// subqueryTupleOrdering returns whether the rows of the subquery plan are ordered
 // such that the resulting subquery tuple can be considered fully sorted.
 // For this to happen, the columns in the subquery must be sorted in the same
 // direction and with the same order of precedence that the tuple will have. The
@@ -209,10 +371,10 @@ func (s *subquery) doEval(ctx context.Context) (result tree.Datum, err error) {
 //   SELECT 1 IN (SELECT 1 ORDER BY 1)
 // because even if they are included in an ORDER BY clause, they will not be part
 // of the plan.Ordering().
-func (s *subquery) subqueryTupleOrdering() (bool, encoding.Direction) {
+func subqueryTupleOrdering(plan planNode) (bool, encoding.Direction) {
 	// Columns must be sorted in the order that they appear in the render
 	// and which they will later appear in the resulting tuple.
-	desired := make(sqlbase.ColumnOrdering, len(planColumns(s.plan)))
+	desired := make(sqlbase.ColumnOrdering, len(planColumns(plan)))
 	for i := range desired {
 		desired[i] = sqlbase.ColumnOrderInfo{
 			ColIdx:    i,
@@ -221,7 +383,7 @@ func (s *subquery) subqueryTupleOrdering() (bool, encoding.Direction) {
 	}
 
 	// Check Ascending direction.
-	order := planPhysicalProps(s.plan)
+	order := planPhysicalProps(plan)
 	match := order.computeMatch(desired)
 	if match == len(desired) {
 		return true, encoding.Ascending
@@ -246,6 +408,15 @@ func (v *subqueryPlanVisitor) subqueryNode(ctx context.Context, sq *subquery) er
 	if !sq.expanded {
 		panic("subquery was not expanded properly")
 	}
+	if !sq.started && sq.outerArgs != nil {
+		// Correlated subqueries are planned and run for every row of the
+		// enclosing query. The initial plan was only needed to determine
+		// the type of the results.
+		sq.plan.Close(ctx)
+		sq.plan = nil
+		sq.started = true
+		return nil
+	}
 	if !sq.started {
 		if err := v.p.startPlan(ctx, sq.plan); err != nil {
 			return err
@@ -288,6 +459,9 @@ type subquerySpanCollector struct {
 }
 
 func (v *subquerySpanCollector) subqueryNode(ctx context.Context, sq *subquery) error {
+	if sq.plan == nil {
+		return nil
+	}
 	reads, writes, err := collectSpans(v.params, sq.plan)
 	if err != nil {
 		return err
@@ -318,6 +492,11 @@ type subqueryVisitor struct {
 	pathBuf [4]tree.Expr
 	err     error
 
+	// sources and ivarHelper describe the data sources of the expression
+	// being analyzed, if any. The subqueries can refer to these columns.
+	sources    multiSourceInfo
+	ivarHelper tree.IndexedVarHelper
+
 	// TODO(andrei): plumb the context through the tree.Visitor.
 	ctx context.Context
 }
@@ -351,17 +530,36 @@ func (v *subqueryVisitor) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.E
 
 	v.hasSubqueries = true
 
+	// The subquery can refer to the columns of the surrounding
+	// expression's data sources.
+	var scope *subqueryScope
+	outerScopes := v.planner.outerScopes
+	if v.sources != nil {
+		scope = &subqueryScope{sources: v.sources}
+		v.planner.outerScopes = append(outerScopes[:len(outerScopes):len(outerScopes)], scope)
+	}
+	scopes := v.planner.outerScopes
+
 	// Calling newPlan() might recursively invoke expandSubqueries, so we need to preserve
 	// the state of the visitor across the call to newPlan().
 	visitorCopy := v.planner.subqueryVisitor
 	plan, err := v.planner.newPlan(v.ctx, sq.Select, nil)
 	v.planner.subqueryVisitor = visitorCopy
+	v.planner.outerScopes = outerScopes
 	if err != nil {
 		v.err = err
 		return false, expr
 	}
 
 	result := &subquery{planner: v.planner, subquery: sq, plan: plan}
+	if scope != nil && len(scope.cols) > 0 {
+		result.scope = scope
+		result.scopes = scopes
+		result.outerArgs = make([]tree.TypedExpr, len(scope.cols))
+		for i, colIdx := range scope.cols {
+			result.outerArgs[i] = v.ivarHelper.IndexedVar(colIdx)
+		}
+	}
 
 	if exists != nil {
 		result.execMode = execModeExists
@@ -409,10 +607,24 @@ func (v *subqueryVisitor) VisitPost(expr tree.Expr) tree.Expr {
 	return expr
 }
 
+// replaceSubqueries replaces the subqueries in expr by subquery nodes.
+// The parameters sources and ivarHelper, if sources is non-nil, describe
+// the data sources of the expression; correlated subqueries refer to
+// these columns.
 func (p *planner) replaceSubqueries(
-	ctx context.Context, expr tree.Expr, columns int,
+	ctx context.Context,
+	expr tree.Expr,
+	columns int,
+	sources multiSourceInfo,
+	ivarHelper tree.IndexedVarHelper,
 ) (tree.Expr, error) {
-	p.subqueryVisitor = subqueryVisitor{planner: p, columns: columns, ctx: ctx}
+	p.subqueryVisitor = subqueryVisitor{
+		planner:    p,
+		columns:    columns,
+		sources:    sources,
+		ivarHelper: ivarHelper,
+		ctx:        ctx,
+	}
 	p.subqueryVisitor.path = p.subqueryVisitor.pathBuf[:0]
 	expr, _ = tree.WalkExpr(&p.subqueryVisitor, expr)
 	return expr, p.subqueryVisitor.err
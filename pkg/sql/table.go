@@ -575,7 +575,9 @@ func getTableNames(
 	return tableNames, nil
 }
 
-func (p *planner) getAliasedTableName(n tree.TableExpr) (*tree.TableName, error) {
+func (p *planner) getAliasedTableName(
+	ctx context.Context, n tree.TableExpr,
+) (*tree.TableName, error) {
 	if ate, ok := n.(*tree.AliasedTableExpr); ok {
 		n = ate.Expr
 	}
@@ -583,7 +585,7 @@ func (p *planner) getAliasedTableName(n tree.TableExpr) (*tree.TableName, error)
 	if !ok {
 		return nil, errors.Errorf("TODO(pmattis): unsupported FROM: %s", n)
 	}
-	return table.NormalizeWithDatabaseName(p.session.Database)
+	return p.normalizeTableName(ctx, table)
 }
 
 // createSchemaChangeJob finalizes the current mutations in the table
@@ -696,12 +698,16 @@ func expandTableGlob(
 }
 
 // searchAndQualifyDatabase augments the table name with the database
-// where it was found. It searches first in the session current
-// database, if that's defined, otherwise the search path.  The
-// provided TableName is modified in-place in case of success, and
-// left unchanged otherwise.
+// where it was found. It searches first in the session's temporary
+// schema, if any, then in the session current database, if that's
+// defined, otherwise the search path.  The provided TableName is
+// modified in-place in case of success, and left unchanged otherwise.
 // The table name must not be qualified already.
 func (p *planner) searchAndQualifyDatabase(ctx context.Context, tn *tree.TableName) error {
+	if found, err := p.searchTemporarySchema(ctx, tn); err != nil || found {
+		return err
+	}
+
 	t := *tn
 
 	descFunc := p.session.tables.getTableVersion
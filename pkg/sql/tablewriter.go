@@ -241,10 +241,19 @@ type tableUpserter struct {
 	// These are set for ON CONFLICT DO UPDATE, but not for DO NOTHING
 	updateCols []sqlbase.ColumnDescriptor
 	evaler     tableUpsertEvaler
+	// conversions compute the values of the columns at the end of updateCols,
+	// which are being converted by an ALTER COLUMN ... TYPE from columns
+	// being updated.
+	conversions []*sqlbase.ColumnConversion
+	// computed computes the values of the computed columns at the end of
+	// updateCols, whose expressions reference columns being updated.
+	computed *sqlbase.ComputedColumns
+	evalCtx  *tree.EvalContext
 
 	// Set by init.
 	txn                   *client.Txn
 	fkTables              sqlbase.TableLookupsByID // for fk checks in update case
+	cascades              *sqlbase.CascadeConfig   // for fk actions in update case
 	ru                    sqlbase.RowUpdater
 	updateColIDtoRowIndex map[sqlbase.ColumnID]int
 	fetchCols             []sqlbase.ColumnDescriptor
@@ -316,7 +325,7 @@ func (tu *tableUpserter) init(txn *client.Txn) error {
 		var err error
 		tu.ru, err = sqlbase.MakeRowUpdater(
 			txn, tableDesc, tu.fkTables, tu.updateCols, requestedCols,
-			sqlbase.RowUpdaterDefault, tu.alloc,
+			sqlbase.RowUpdaterDefault, tu.cascades, tu.alloc,
 		)
 		if err != nil {
 			return err
@@ -462,6 +471,31 @@ func (tu *tableUpserter) flush(
 				if err != nil {
 					return nil, err
 				}
+				if len(tu.conversions) > 0 || tu.computed != nil {
+					vals := make(tree.Datums, len(tu.ru.UpdateCols))
+					copy(vals, updateValues)
+					if err := sqlbase.ConvertColumns(
+						tu.evalCtx, tu.conversions, tu.updateColIDtoRowIndex, vals,
+					); err != nil {
+						return nil, err
+					}
+					if tu.computed != nil {
+						tu.computed.LoadRow(tu.fetchColIDtoRowIndex, existingValues, false)
+						tu.computed.LoadRow(tu.updateColIDtoRowIndex, vals, true)
+						if err := tu.computed.Compute(tu.evalCtx, tu.updateColIDtoRowIndex, vals); err != nil {
+							return nil, err
+						}
+					}
+					updateValues = vals
+				}
+				// As in UPDATE, NULLs can't be written to non-nullable columns,
+				// including columns to which a NOT NULL constraint is being added.
+				for i, col := range tu.ru.UpdateCols {
+					if updateValues[i] == tree.DNull &&
+						(!col.Nullable || tableDesc.IsValidatingNotNull(col.ID)) {
+						return nil, sqlbase.NewNonNullViolationError(col.Name)
+					}
+				}
 				updatedRow, err := tu.ru.UpdateRow(ctx, b, existingValues, updateValues, traceKV)
 				if err != nil {
 					return nil, err
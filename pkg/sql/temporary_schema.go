@@ -0,0 +1,334 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/uint128"
+)
+
+// Temporary tables live in a schema private to the session which created
+// them. Since a cockroach database is the equivalent of a postgres schema,
+// that schema is a database named after the ID of the session. It is
+// created along with the first temporary table of the session, searched
+// before the current database when resolving unqualified table names, and
+// dropped when the session is closed. Temporary schemas left behind by
+// sessions which were not closed cleanly, for instance because their node
+// died, are dropped by a TemporarySchemaCleaner.
+
+// temporarySchemaPrefix is the prefix of the names of the temporary schemas.
+const temporarySchemaPrefix = "pg_temp_"
+
+// temporarySchemaName returns the name of the temporary schema of the
+// session with the given ID.
+func temporarySchemaName(sessionID uint128.Uint128) string {
+	return fmt.Sprintf("%s%d_%d", temporarySchemaPrefix, sessionID.Hi, sessionID.Lo)
+}
+
+// parseTemporarySchemaName returns the ID of the session owning the
+// temporary schema with the given name. ok is false if the name is not the
+// name of a temporary schema.
+func parseTemporarySchemaName(name string) (sessionID uint128.Uint128, ok bool) {
+	if !strings.HasPrefix(name, temporarySchemaPrefix) {
+		return sessionID, false
+	}
+	parts := strings.Split(strings.TrimPrefix(name, temporarySchemaPrefix), "_")
+	if len(parts) != 2 {
+		return sessionID, false
+	}
+	hi, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return sessionID, false
+	}
+	lo, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return sessionID, false
+	}
+	return uint128.FromInts(hi, lo), true
+}
+
+// checkTemporarySchemaPrefix rejects the names of databases which could be
+// mistaken for temporary schemas: these are dropped along with the session
+// they seem to belong to.
+func checkTemporarySchemaPrefix(name string) error {
+	if strings.HasPrefix(name, temporarySchemaPrefix) {
+		return pgerror.NewErrorf(pgerror.CodeReservedNameError,
+			"database names starting with %q are reserved for temporary schemas",
+			temporarySchemaPrefix)
+	}
+	return nil
+}
+
+// isOtherSessionTemporarySchema returns true if the database with the given
+// name is the temporary schema of another session.
+func (p *planner) isOtherSessionTemporarySchema(name string) bool {
+	_, ok := parseTemporarySchemaName(name)
+	return ok && name != temporarySchemaName(p.session.id)
+}
+
+// sessionNodeID returns the ID of the node on which the session with the
+// given ID was opened. See generateClusterWideID().
+func sessionNodeID(sessionID uint128.Uint128) roachpb.NodeID {
+	return roachpb.NodeID(uint32(sessionID.Lo))
+}
+
+// qualifyTemporaryTableName qualifies the name of a temporary table being
+// created with the session's temporary schema. The descriptor of the schema
+// is returned if it already exists.
+func (p *planner) qualifyTemporaryTableName(
+	ctx context.Context, nt *tree.NormalizableTableName,
+) (*sqlbase.DatabaseDescriptor, error) {
+	tn, err := nt.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	schemaName := temporarySchemaName(p.session.id)
+	if !tn.DBNameOriginallyOmitted && tn.Database() != schemaName {
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidTableDefinitionError,
+			"cannot create temporary relation in non-temporary schema")
+	}
+	tn.DatabaseName = tree.Name(schemaName)
+	return getDatabaseDesc(ctx, p.txn, p.getVirtualTabler(), schemaName)
+}
+
+// createTemporarySchema creates the session's temporary schema. The session
+// user is granted all privileges on it, and thus on the temporary tables
+// created in it.
+func (p *planner) createTemporarySchema(
+	ctx context.Context,
+) (*sqlbase.DatabaseDescriptor, error) {
+	desc := sqlbase.DatabaseDescriptor{
+		Name:       temporarySchemaName(p.session.id),
+		Privileges: sqlbase.NewDefaultPrivilegeDescriptor(),
+	}
+	desc.Privileges.Grant(p.session.User, privilege.List{privilege.ALL})
+	if _, err := p.createDatabase(ctx, &desc, false /* ifNotExists */); err != nil {
+		return nil, err
+	}
+	p.session.tables.addUncommittedDatabase(desc.Name, desc.ID, false /* dropped */)
+	p.session.hasTemporarySchema = true
+	return &desc, nil
+}
+
+// searchTemporarySchema qualifies the table name with the session's
+// temporary schema if the schema holds a table or view of that name. The
+// provided TableName is modified in-place in case of success, and left
+// unchanged otherwise.
+func (p *planner) searchTemporarySchema(ctx context.Context, tn *tree.TableName) (bool, error) {
+	if !p.session.hasTemporarySchema {
+		return false, nil
+	}
+	t := *tn
+	t.DatabaseName = tree.Name(temporarySchemaName(p.session.id))
+	desc, err := getTableOrViewDesc(ctx, p.txn, p.getVirtualTabler(), &t)
+	if err != nil {
+		if sqlbase.IsUndefinedDatabaseError(err) {
+			// The transaction which created the schema was rolled back.
+			return false, nil
+		}
+		return false, err
+	}
+	if desc == nil || desc.Dropped() {
+		return false, nil
+	}
+	*tn = t
+	return true, nil
+}
+
+// checkTemporarySchemaAccess rejects the names of tables in the temporary
+// schemas of other sessions: these are invisible outside of their session.
+func (p *planner) checkTemporarySchemaAccess(tn *tree.TableName) error {
+	if p.isOtherSessionTemporarySchema(tn.Database()) {
+		return sqlbase.NewUndefinedRelationError(tn)
+	}
+	return nil
+}
+
+// qualifyTableName qualifies a table name which doesn't specify a
+// database with the session's temporary schema if it holds a table of that
+// name, and with the session's current database otherwise.
+func (p *planner) qualifyTableName(ctx context.Context, tn *tree.TableName) error {
+	if !tn.DBNameOriginallyOmitted {
+		return p.checkTemporarySchemaAccess(tn)
+	}
+	if found, err := p.searchTemporarySchema(ctx, tn); err != nil || found {
+		return err
+	}
+	return tn.QualifyWithDatabase(p.session.Database)
+}
+
+// normalizeTableName combines Normalize and qualifyTableName.
+func (p *planner) normalizeTableName(
+	ctx context.Context, nt *tree.NormalizableTableName,
+) (*tree.TableName, error) {
+	tn, err := nt.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.qualifyTableName(ctx, tn); err != nil {
+		return nil, err
+	}
+	return tn, nil
+}
+
+// discardTemporarySchema drops the session's temporary schema, if any.
+func (p *planner) discardTemporarySchema(ctx context.Context) (planNode, error) {
+	if !p.session.hasTemporarySchema {
+		return &zeroNode{}, nil
+	}
+	return p.DropDatabase(ctx, &tree.DropDatabase{
+		Name:         tree.Name(temporarySchemaName(p.session.id)),
+		IfExists:     true,
+		DropBehavior: tree.DropCascade,
+	})
+}
+
+// dropTemporarySchema drops a temporary schema along with the tables it
+// holds.
+func dropTemporarySchema(
+	ctx context.Context, db *client.DB, ie InternalExecutor, schemaName string,
+) error {
+	stmt := fmt.Sprintf("DROP DATABASE IF EXISTS %s CASCADE", tree.Name(schemaName).String())
+	return db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		_, err := ie.ExecuteStatementInTransaction(ctx, "drop-temp-schema", txn, stmt)
+		return err
+	})
+}
+
+// DefaultTemporarySchemaCleanupInterval is the interval at which the
+// TemporarySchemaCleaner looks for temporary schemas to drop.
+//
+// DefaultTemporarySchemaCleanupInterval is mutable for testing. NB: Updates to
+// this value after TemporarySchemaCleaner.Start has been called will not have
+// any effect.
+var DefaultTemporarySchemaCleanupInterval = 30 * time.Minute
+
+// temporarySchemaLiveness is the subset of storage.NodeLiveness's interface
+// needed by TemporarySchemaCleaner.
+type temporarySchemaLiveness interface {
+	GetLivenesses() []storage.Liveness
+}
+
+// TemporarySchemaCleaner drops the temporary schemas of the sessions which
+// no longer exist but weren't closed cleanly: the sessions opened on this
+// node which it doesn't know about anymore, which were opened before a
+// restart, and the sessions opened on nodes which are no longer live.
+type TemporarySchemaCleaner struct {
+	db       *client.DB
+	ie       InternalExecutor
+	clock    *hlc.Clock
+	nodeID   *base.NodeIDContainer
+	registry *SessionRegistry
+	liveness temporarySchemaLiveness
+}
+
+// NewTemporarySchemaCleaner creates a new TemporarySchemaCleaner.
+func NewTemporarySchemaCleaner(
+	db *client.DB,
+	leaseMgr *LeaseManager,
+	clock *hlc.Clock,
+	nodeID *base.NodeIDContainer,
+	registry *SessionRegistry,
+	liveness temporarySchemaLiveness,
+) *TemporarySchemaCleaner {
+	return &TemporarySchemaCleaner{
+		db:       db,
+		ie:       InternalExecutor{LeaseManager: leaseMgr},
+		clock:    clock,
+		nodeID:   nodeID,
+		registry: registry,
+		liveness: liveness,
+	}
+}
+
+// Start periodically drops the temporary schemas of the sessions which no
+// longer exist.
+func (c *TemporarySchemaCleaner) Start(stopper *stop.Stopper, interval time.Duration) {
+	stopper.RunWorker(context.Background(), func(ctx context.Context) {
+		for {
+			select {
+			case <-time.After(interval):
+				if err := c.cleanup(ctx); err != nil {
+					log.Warningf(ctx, "error while dropping temporary schemas: %+v", err)
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// cleanup drops the temporary schemas of the sessions which no longer exist.
+func (c *TemporarySchemaCleaner) cleanup(ctx context.Context) error {
+	var rows []tree.Datums
+	if err := c.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		rows, err = c.ie.QueryRowsInTransaction(
+			ctx, "list-temp-schemas", txn,
+			// The names are filtered by parseTemporarySchemaName below: LIKE
+			// would take the underscores of the prefix for wildcards.
+			`SELECT name FROM system.namespace WHERE "parentID" = 0`,
+		)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	now := c.clock.Now()
+	isLive := make(map[roachpb.NodeID]bool)
+	for _, l := range c.liveness.GetLivenesses() {
+		isLive[l.NodeID] = l.IsLive(now, c.clock.MaxOffset())
+	}
+	selfID := c.nodeID.Get()
+
+	for _, row := range rows {
+		schemaName := string(tree.MustBeDString(row[0]))
+		sessionID, ok := parseTemporarySchemaName(schemaName)
+		if !ok {
+			continue
+		}
+		nodeID := sessionNodeID(sessionID)
+		if nodeID == selfID {
+			if c.registry.hasSession(sessionID) {
+				continue
+			}
+		} else if live, ok := isLive[nodeID]; !ok || live {
+			// The node is live or unknown; its sessions are its own business.
+			continue
+		}
+		log.Infof(ctx, "dropping temporary schema %s of closed session", schemaName)
+		if err := dropTemporarySchema(ctx, c.db, c.ie, schemaName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
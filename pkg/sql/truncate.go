@@ -47,7 +47,7 @@ func (p *planner) Truncate(ctx context.Context, n *tree.Truncate) (planNode, err
 		if err != nil {
 			return nil, err
 		}
-		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+		if err := p.qualifyTableName(ctx, tn); err != nil {
 			return nil, err
 		}
 
@@ -347,7 +347,7 @@ func truncateTableInChunks(
 			log.VEventf(ctx, 2, "table %s truncate at row: %d, span: %s", tableDesc.Name, row, resume)
 		}
 		if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
-			rd, err := sqlbase.MakeRowDeleter(txn, tableDesc, nil, nil, false, alloc)
+			rd, err := sqlbase.MakeRowDeleter(txn, tableDesc, nil, nil, false, nil, alloc)
 			if err != nil {
 				return err
 			}
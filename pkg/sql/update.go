@@ -121,6 +121,13 @@ type updateNode struct {
 	tw            tableUpdater
 	checkHelper   checkHelper
 	sourceSlots   []sourceSlot
+	// conversions compute the values of the columns at the end of
+	// updateCols, which are being converted by an ALTER COLUMN ... TYPE
+	// from columns being updated.
+	conversions []*sqlbase.ColumnConversion
+	// computed computes the values of the computed columns in updateCols,
+	// whose expressions reference columns being updated, if any.
+	computed *sqlbase.ComputedColumns
 
 	run struct {
 		// The following fields are populated during Start().
@@ -215,7 +222,13 @@ func (p *planner) Update(
 
 	tracing.AnnotateTrace()
 
-	tn, err := p.getAliasedTableName(n.Table)
+	popWith, err := p.initWith(ctx, n.With)
+	if err != nil {
+		return nil, err
+	}
+	defer popWith()
+
+	tn, err := p.getAliasedTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -228,7 +241,8 @@ func (p *planner) Update(
 	setExprs := make([]*tree.UpdateExpr, len(n.Exprs))
 	for i, expr := range n.Exprs {
 		// Replace the sub-query nodes.
-		newExpr, err := p.replaceSubqueries(ctx, expr.Expr, len(expr.Names))
+		newExpr, err := p.replaceSubqueries(ctx, expr.Expr, len(expr.Names),
+			nil /* sources */, tree.IndexedVarHelper{})
 		if err != nil {
 			return nil, err
 		}
@@ -245,6 +259,23 @@ func (p *planner) Update(
 	if err != nil {
 		return nil, err
 	}
+	conversions, err := sqlbase.MakeColumnConversions(en.tableDesc)
+	if err != nil {
+		return nil, err
+	}
+	conversions = conversionsForUpdate(conversions, updateCols)
+	for _, c := range conversions {
+		updateCols = append(updateCols, c.Column)
+	}
+	computed, err := sqlbase.MakeWritableComputedColumns(en.tableDesc)
+	if err != nil {
+		return nil, err
+	}
+	if computedCols := computed.Referencing(updateCols); len(computedCols) > 0 {
+		updateCols = append(updateCols, computedCols...)
+	} else {
+		computed = nil
+	}
 
 	defaultExprs, err := sqlbase.MakeDefaultExprs(updateCols, &p.txCtx, &p.evalCtx)
 	if err != nil {
@@ -252,9 +283,10 @@ func (p *planner) Update(
 	}
 
 	var requestedCols []sqlbase.ColumnDescriptor
-	if _, retExprs := n.Returning.(*tree.ReturningExprs); retExprs || len(en.tableDesc.Checks) > 0 {
+	if _, retExprs := n.Returning.(*tree.ReturningExprs); retExprs ||
+		len(en.tableDesc.Checks) > 0 || computed != nil {
 		// TODO(dan): This could be made tighter, just the rows needed for RETURNING
-		// exprs.
+		// exprs, CHECK constraints and computed columns.
 		requestedCols = en.tableDesc.Columns
 	}
 
@@ -262,8 +294,12 @@ func (p *planner) Update(
 	if err := p.fillFKTableMap(ctx, fkTables); err != nil {
 		return nil, err
 	}
+	cascades, err := p.fillFKCascadeTableMap(ctx, en.tableDesc, sqlbase.CheckUpdates, fkTables)
+	if err != nil {
+		return nil, err
+	}
 	ru, err := sqlbase.MakeRowUpdater(p.txn, en.tableDesc, fkTables, updateCols,
-		requestedCols, sqlbase.RowUpdaterDefault, &p.alloc)
+		requestedCols, sqlbase.RowUpdaterDefault, cascades, &p.alloc)
 	if err != nil {
 		return nil, err
 	}
@@ -386,6 +422,8 @@ func (p *planner) Update(
 		updateColsIdx: updateColsIdx,
 		tw:            tw,
 		sourceSlots:   sourceSlots,
+		conversions:   conversions,
+		computed:      computed,
 	}
 	if err := un.checkHelper.init(ctx, p, tn, en.tableDesc); err != nil {
 		return nil, err
@@ -441,6 +479,18 @@ func (u *updateNode) Next(params runParams) (bool, error) {
 			valueIdx++
 		}
 	}
+	if err := sqlbase.ConvertColumns(
+		&params.p.evalCtx, u.conversions, u.updateColsIdx, updateValues,
+	); err != nil {
+		return false, err
+	}
+	if u.computed != nil {
+		u.computed.LoadRow(u.tw.ru.FetchColIDtoRowIndex, oldValues, false)
+		u.computed.LoadRow(u.updateColsIdx, updateValues, true)
+		if err := u.computed.Compute(&params.p.evalCtx, u.updateColsIdx, updateValues); err != nil {
+			return false, err
+		}
+	}
 
 	if err := u.checkHelper.loadRow(u.tw.ru.FetchColIDtoRowIndex, oldValues, false); err != nil {
 		return false, err
@@ -461,7 +511,7 @@ func (u *updateNode) Next(params runParams) (bool, error) {
 
 	for i, col := range u.tw.ru.UpdateCols {
 		val := updateValues[i]
-		if !col.Nullable && val == tree.DNull {
+		if val == tree.DNull && (!col.Nullable || u.tableDesc.IsValidatingNotNull(col.ID)) {
 			return false, sqlbase.NewNonNullViolationError(col.Name)
 		}
 	}
@@ -483,6 +533,24 @@ func (u *updateNode) Next(params runParams) (bool, error) {
 	return true, nil
 }
 
+// conversionsForUpdate returns the conversions of the columns being
+// converted by an ALTER COLUMN ... TYPE from any of updateCols, whose values
+// need to be recomputed by the update.
+func conversionsForUpdate(
+	conversions []*sqlbase.ColumnConversion, updateCols []sqlbase.ColumnDescriptor,
+) []*sqlbase.ColumnConversion {
+	var res []*sqlbase.ColumnConversion
+	for _, c := range conversions {
+		for _, col := range updateCols {
+			if col.ID == c.Source.ID {
+				res = append(res, c)
+				break
+			}
+		}
+	}
+	return res
+}
+
 // namesForExprs expands names in the tuples and subqueries in exprs.
 func (p *planner) namesForExprs(exprs tree.UpdateExprs) (tree.UnresolvedNames, error) {
 	var names tree.UnresolvedNames
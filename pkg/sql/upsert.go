@@ -219,6 +219,10 @@ func upsertExprsAndIndex(
 		}
 		updateExprs := make(tree.UpdateExprs, 0, len(insertCols))
 		for _, c := range insertCols {
+			if c.IsComputed() {
+				// Computed columns are recomputed by the update.
+				continue
+			}
 			if _, ok := indexColSet[c.ID]; !ok {
 				names := tree.UnresolvedNames{
 					tree.UnresolvedName{tree.Name(c.Name)},
@@ -150,6 +150,13 @@ func (v *planVisitor) visit(plan planNode) {
 			if n.hardLimit > 0 && isFilterTrue(n.filter) {
 				v.observer.attr(name, "limit", fmt.Sprintf("%d", n.hardLimit))
 			}
+			if n.lockStrength != tree.ForNone {
+				locking := n.lockStrength.String()
+				if n.lockWaitPolicy != tree.LockWaitBlock {
+					locking += " " + n.lockWaitPolicy.String()
+				}
+				v.observer.attr(name, "locking", locking)
+			}
 		}
 		subplans := v.expr(name, "filter", -1, n.filter, nil)
 		v.subqueries(name, subplans)
@@ -186,6 +193,10 @@ func (v *planVisitor) visit(plan planNode) {
 				jType = "right outer"
 			case joinTypeFullOuter:
 				jType = "full outer"
+			case joinTypeSemi:
+				jType = "semi"
+			case joinTypeAnti:
+				jType = "anti"
 			}
 			v.observer.attr(name, "type", jType)
 
@@ -216,6 +227,28 @@ func (v *planVisitor) visit(plan planNode) {
 		v.visit(n.left.plan)
 		v.visit(n.right.plan)
 
+	case *applyJoinNode:
+		if v.observer.attr != nil {
+			jType := "inner"
+			if n.joinType == joinTypeLeftOuter {
+				jType = "left outer"
+			}
+			v.observer.attr(name, "type", jType)
+			if len(n.pred.leftColNames) > 0 {
+				var buf bytes.Buffer
+				buf.WriteByte('(')
+				tree.FormatNode(&buf, tree.FmtSimple, n.pred.leftColNames)
+				buf.WriteString(") = (")
+				tree.FormatNode(&buf, tree.FmtSimple, n.pred.rightColNames)
+				buf.WriteByte(')')
+				v.observer.attr(name, "equality", buf.String())
+			}
+			v.observer.attr(name, "lateral", tree.AsStringWithFlags(n.rightExpr, tree.FmtSimple))
+		}
+		subplans := v.expr(name, "pred", -1, n.pred.onCond, nil)
+		v.subqueries(name, subplans)
+		v.visit(n.left.plan)
+
 	case *limitNode:
 		subplans := v.expr(name, "count", -1, n.countExpr, nil)
 		subplans = v.expr(name, "offset", -1, n.offsetExpr, subplans)
@@ -393,12 +426,33 @@ func (v *planVisitor) visit(plan planNode) {
 			v.visit(n.plan)
 		}
 
+	case *cteScanNode:
+		if n.src != nil {
+			if v.observer.attr != nil {
+				v.observer.attr(name, "source", n.src.name.Alias.String())
+			}
+			if n.src.plan != nil {
+				v.visit(n.src.plan)
+			}
+		}
+
+	case *recursiveCTENode:
+		v.visit(n.initial)
+
 	case *explainDistSQLNode:
 		v.visit(n.plan)
 
 	case *ordinalityNode:
 		v.visit(n.source)
 
+	case *projectSetNode:
+		var subplans []planNode
+		for i, fn := range n.funcs {
+			subplans = v.expr(name, "func", i, fn, subplans)
+		}
+		v.subqueries(name, subplans)
+		v.visit(n.source.plan)
+
 	case *traceNode:
 		v.visit(n.plan)
 
@@ -521,6 +575,7 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&alterTableNode{}):           "alter table",
 	reflect.TypeOf(&alterSequenceNode{}):        "alter sequence",
 	reflect.TypeOf(&alterUserSetPasswordNode{}): "alter user",
+	reflect.TypeOf(&applyJoinNode{}):            "apply join",
 	reflect.TypeOf(&cancelQueryNode{}):          "cancel query",
 	reflect.TypeOf(&controlJobNode{}):           "control job",
 	reflect.TypeOf(&copyNode{}):                 "copy",
@@ -530,6 +585,8 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&createUserNode{}):           "create user",
 	reflect.TypeOf(&createViewNode{}):           "create view",
 	reflect.TypeOf(&createSequenceNode{}):       "create sequence",
+	reflect.TypeOf(&createStatsNode{}):          "create statistics",
+	reflect.TypeOf(&cteScanNode{}):              "cte scan",
 	reflect.TypeOf(&delayedNode{}):              "virtual table",
 	reflect.TypeOf(&deleteNode{}):               "delete",
 	reflect.TypeOf(&distinctNode{}):             "distinct",
@@ -551,6 +608,8 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&joinNode{}):                 "join",
 	reflect.TypeOf(&limitNode{}):                "limit",
 	reflect.TypeOf(&ordinalityNode{}):           "ordinality",
+	reflect.TypeOf(&projectSetNode{}):           "project set",
+	reflect.TypeOf(&recursiveCTENode{}):         "recursive cte",
 	reflect.TypeOf(&testingRelocateNode{}):      "testingRelocate",
 	reflect.TypeOf(&renderNode{}):               "render",
 	reflect.TypeOf(&scanNode{}):                 "scan",
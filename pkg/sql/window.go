@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/transform"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -208,11 +209,86 @@ func (n *windowNode) constructWindowDefinitions(
 			}
 		}
 
+		// Validate frame clause.
+		if frame := windowDef.Frame; frame != nil {
+			if err := windowFn.analyzeFrameOffsets(ctx, s, frame); err != nil {
+				return err
+			}
+		}
+
 		windowFn.windowDef = windowDef
 	}
 	return nil
 }
 
+// analyzeFrameOffsets type checks the offsets of the bounds of the provided
+// window frame. The offsets of a ROWS frame are row counts. The offsets of a
+// RANGE frame are added to or subtracted from the value of the ORDER BY column,
+// of which there must be exactly one.
+func (w *windowFuncHolder) analyzeFrameOffsets(
+	ctx context.Context, s *renderNode, frame *tree.WindowFrameSpec,
+) error {
+	bounds := []struct {
+		bound *tree.WindowFrameBound
+		dst   *tree.TypedExpr
+	}{
+		{frame.Bounds.StartBound, &w.frameStartOffset},
+		{frame.Bounds.EndBound, &w.frameEndOffset},
+	}
+	for _, b := range bounds {
+		if b.bound == nil || !b.bound.HasOffset() {
+			continue
+		}
+
+		desired := types.Int
+		if frame.Mode == tree.RANGE {
+			if len(w.columnOrdering) != 1 {
+				return pgerror.NewError(pgerror.CodeWindowingError,
+					"RANGE with offset PRECEDING/FOLLOWING requires exactly one ORDER BY column")
+			}
+			ordType := s.columns[w.columnOrdering[0].ColIdx].Typ
+			if desired = tree.RangeOffsetType(ordType); desired == nil {
+				return pgerror.NewErrorf(pgerror.CodeFeatureNotSupportedError,
+					"RANGE with offset PRECEDING/FOLLOWING is not supported for column type %s", ordType)
+			}
+		}
+
+		typingContext := frame.Mode.String()
+		if containsColumnReference(b.bound.OffsetExpr) {
+			return pgerror.NewErrorf(pgerror.CodeInvalidColumnReferenceError,
+				"argument of %s must not contain variables", typingContext)
+		}
+		if err := s.planner.txCtx.AssertNoAggregationOrWindowing(
+			b.bound.OffsetExpr, typingContext, s.planner.session.SearchPath,
+		); err != nil {
+			return err
+		}
+		typedOffset, err := s.planner.analyzeExpr(
+			ctx, b.bound.OffsetExpr, nil, tree.IndexedVarHelper{}, desired, true, typingContext,
+		)
+		if err != nil {
+			return err
+		}
+		*b.dst = typedOffset
+	}
+	return nil
+}
+
+// containsColumnReference returns whether the provided expression refers to
+// any column.
+func containsColumnReference(expr tree.Expr) bool {
+	found := false
+	_, _ = tree.SimpleVisit(expr, func(expr tree.Expr) (error, bool, tree.Expr) {
+		switch expr.(type) {
+		case *tree.IndexedVar, tree.UnresolvedName, *tree.ColumnItem:
+			found = true
+			return nil, false, expr
+		}
+		return nil, !found, expr
+	})
+	return found
+}
+
 // constructWindowDef constructs a WindowDef using the provided WindowDef value and the
 // set of named window specifications on the current SELECT clause. If the provided
 // WindowDef does not reference a named window spec, then it will simply be returned without
@@ -246,6 +322,11 @@ func constructWindowDef(
 		return *referencedSpec, nil
 	}
 
+	// referencedSpec.Frame is never used.
+	if referencedSpec.Frame != nil {
+		return def, errors.Errorf("cannot copy window %q because it has a frame clause", refName)
+	}
+
 	// referencedSpec.Partitions is always used.
 	if len(def.Partitions) > 0 {
 		return def, errors.Errorf("cannot override PARTITION BY clause of window %q", refName)
@@ -335,6 +416,7 @@ func (n *windowNode) replaceIndexVarsAndAggFuncs(s *renderNode) {
 	n.aggContainer = windowNodeAggContainer{
 		windowNodeIvarContainer: makeWindowNodeIvarContainer(n),
 		aggFuncs:                make(map[int]*tree.FuncExpr),
+		aggIVars:                make(map[*tree.IndexedVar]struct{}),
 	}
 	// The number of aggregation functions that need to be replaced with IndexedVars
 	// is unknown, so we collect them here and bind them to an IndexedVarHelper later.
@@ -380,6 +462,7 @@ func (n *windowNode) replaceIndexVarsAndAggFuncs(s *renderNode) {
 					aggIVars[colIdx] = aggIVar
 					n.aggContainer.idxMap[idx] = colIdx
 					n.aggContainer.aggFuncs[idx] = t
+					n.aggContainer.aggIVars[aggIVar] = struct{}{}
 					return nil, false, aggIVar
 				}
 				return nil, true, expr
@@ -611,8 +694,9 @@ func (n *windowNode) computeWindows(ctx context.Context) error {
 		// See Cao et al. [http://vldb.org/pvldb/vol5/p1244_yucao_vldb2012.pdf]
 		for rowI := 0; rowI < rowCount; rowI++ {
 			row := n.wrappedRenderVals.At(rowI)
-			sourceVals := row[:n.sourceCols]
-			entry := tree.IndexedRow{Idx: rowI, Row: sourceVals}
+			// The entire row is kept so that RANGE frames can access the values
+			// of the ORDER BY column.
+			entry := tree.IndexedRow{Idx: rowI, Row: row}
 			if len(windowFn.partitionIdxs) == 0 {
 				// If no partition indexes are included for the window function, all
 				// rows are added to the same partition.
@@ -638,29 +722,36 @@ func (n *windowNode) computeWindows(ctx context.Context) error {
 			}
 		}
 
+		// Evaluate the offsets of the window frame bounds, which are constant
+		// for all rows.
+		startOffset, err := n.evalFrameOffset(windowFn.frameStartOffset, "starting")
+		if err != nil {
+			return err
+		}
+		endOffset, err := n.evalFrameOffset(windowFn.frameEndOffset, "ending")
+		if err != nil {
+			return err
+		}
+
 		// For each partition, perform necessary sorting based on the window function's
 		// ORDER BY attribute. After this, perform the window function computation for
 		// each tuple and save the result in n.windowValues.
 		//
 		// TODO(nvanbenschoten)
 		// - Investigate inter- and intra-partition parallelism
-		// - Investigate more efficient aggregation techniques
-		//   * Removable Cumulative
-		//   * Segment Tree
+		// - Investigate segment trees for aggregates that cannot remove values
 		// See Leis et al. [http://www.vldb.org/pvldb/vol8/p1058-leis.pdf]
 		for _, partition := range partitions {
-			// TODO(nvanbenschoten): Handle framing here. Right now we only handle the default
-			// framing option of RANGE UNBOUNDED PRECEDING. With ORDER BY, this sets the frame
-			// to be all rows from the partition start up through the current row's last ORDER BY
-			// peer. Without ORDER BY, all rows of the partition are included in the window frame,
-			// since all rows become peers of the current row. Once we add better framing support,
-			// we should flesh this logic out more.
+			// Without a frame clause, the default framing option of RANGE UNBOUNDED
+			// PRECEDING is used. With ORDER BY, this sets the frame to be all rows
+			// from the partition start up through the current row's last ORDER BY
+			// peer. Without ORDER BY, all rows of the partition are included in the
+			// window frame, since all rows become peers of the current row.
 			builtin := windowFn.expr.GetWindowConstructor()(&n.planner.evalCtx)
 			defer builtin.Close(ctx, &n.planner.evalCtx)
 
-			// Since we only support two types of window frames (see TODO above), we only
-			// need two possible types of peerGroupChecker's to help determine peer groups
-			// for given tuples.
+			// Peer groups are determined by the ORDER BY clause, and are used by
+			// the default frame and RANGE frames as well as by ranking functions.
 			var peerGrouper peerGroupChecker
 			if windowFn.columnOrdering != nil {
 				// If an ORDER BY clause is provided, order the partition and use the
@@ -686,10 +777,21 @@ func (n *windowNode) computeWindows(ctx context.Context) error {
 
 			// Iterate over peer groups within partition using a window frame.
 			frame := tree.WindowFrame{
-				Rows:        partition,
-				ArgIdxStart: windowFn.argIdxStart,
-				ArgCount:    windowFn.argCount,
-				RowIdx:      0,
+				Rows:             partition,
+				ArgIdxStart:      windowFn.argIdxStart,
+				ArgCount:         windowFn.argCount,
+				Frame:            windowFn.windowDef.Frame,
+				StartBoundOffset: startOffset,
+				EndBoundOffset:   endOffset,
+				RowIdx:           0,
+			}
+			if len(windowFn.columnOrdering) > 0 {
+				ordering := windowFn.columnOrdering[0]
+				frame.OrdColIdx = ordering.ColIdx
+				frame.OrdDirection = tree.Ascending
+				if ordering.Direction == encoding.Descending {
+					frame.OrdDirection = tree.Descending
+				}
 			}
 			for frame.RowIdx < len(partition) {
 				// Compute the size of the current peer group.
@@ -725,6 +827,46 @@ func (n *windowNode) computeWindows(ctx context.Context) error {
 	return nil
 }
 
+// evalFrameOffset evaluates the offset of an "<offset> PRECEDING" or
+// "<offset> FOLLOWING" window frame bound. The offset cannot refer to the
+// columns of the window's source, so it is evaluated once against the
+// planner's evaluation context and applies to every row. It returns nil if
+// the bound has no offset (e.g. UNBOUNDED PRECEDING or CURRENT ROW).
+//
+// A NULL offset is rejected with a CodeNullValueNotAllowedError error and a
+// negative offset with a CodeInvalidParameterValueError error; which is
+// either "starting" or "ending" and names the bound in these errors.
+func (n *windowNode) evalFrameOffset(offset tree.TypedExpr, which string) (tree.Datum, error) {
+	if offset == nil {
+		return nil, nil
+	}
+	evalCtx := &n.planner.evalCtx
+	d, err := offset.Eval(evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if d == tree.DNull {
+		return nil, pgerror.NewErrorf(pgerror.CodeNullValueNotAllowedError,
+			"frame %s offset must not be null", which)
+	}
+	var negative bool
+	switch t := d.(type) {
+	case *tree.DInt:
+		negative = *t < 0
+	case *tree.DFloat:
+		negative = *t < 0
+	case *tree.DDecimal:
+		negative = t.Sign() < 0
+	case *tree.DInterval:
+		negative = t.Compare(evalCtx, &tree.DInterval{}) < 0
+	}
+	if negative {
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+			"frame %s offset must not be negative", which)
+	}
+	return d, nil
+}
+
 // populateValues populates n.values with final datum values after computing
 // window result values in n.windowValues.
 func (n *windowNode) populateValues(ctx context.Context) error {
@@ -908,6 +1050,10 @@ type windowFuncHolder struct {
 	windowDef      tree.WindowDef
 	partitionIdxs  []int
 	columnOrdering sqlbase.ColumnOrdering
+
+	// The type-checked offsets of the window frame bounds, if any.
+	frameStartOffset tree.TypedExpr
+	frameEndOffset   tree.TypedExpr
 }
 
 func (*windowFuncHolder) Variable() {}
@@ -995,6 +1141,10 @@ type windowNodeAggContainer struct {
 
 	// aggFuncs maps the index of IndexedVars to their corresponding aggregate function.
 	aggFuncs map[int]*tree.FuncExpr
+
+	// aggIVars contains the IndexedVars bound to this container, which allows
+	// telling them apart from the IndexedVars bound to the colContainer.
+	aggIVars map[*tree.IndexedVar]struct{}
 }
 
 // IndexedVarResolvedType implements the tree.IndexedVarContainer interface.
@@ -0,0 +1,582 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+)
+
+// This file implements common table expressions (CTEs), i.e. the
+// WITH clause.
+//
+// Each CTE is planned exactly once, when its WITH clause is
+// encountered, into a cteSource. Every reference to the CTE name in
+// the statement that follows becomes a cteScanNode that shares this
+// cteSource. The first cteScanNode to start runs the CTE's plan to
+// completion and buffers its results in a memory-accounted row
+// container; all the cteScanNodes then replay the buffered rows. This
+// way a CTE referenced multiple times is only computed once, as
+// mandated by the SQL standard.
+//
+// A CTE in a WITH RECURSIVE clause that refers to itself is planned
+// as a recursiveCTENode, which in turn becomes the plan of the shared
+// cteSource. See the comments on recursiveCTENode below for details.
+
+// cteNameScope maps the names of the CTEs defined by a single WITH
+// clause to their sources.
+type cteNameScope map[tree.Name]*cteSource
+
+// cteNameEnvironment is the stack of CTE name scopes visible at the
+// current point of planning. The innermost scope is at the end.
+type cteNameEnvironment []cteNameScope
+
+// cteSource holds the state shared by all the references to a single
+// common table expression.
+type cteSource struct {
+	// name is the alias clause that introduced the CTE.
+	name tree.AliasClause
+
+	// plan computes the rows of the CTE. It is nil for the working
+	// table of a recursive CTE, whose rows are populated directly by
+	// the recursiveCTENode.
+	plan planNode
+
+	// columns are the result columns of the CTE, after the column
+	// aliases in name have been applied.
+	columns sqlbase.ResultColumns
+
+	// expanded is set once plan has been optimized.
+	expanded bool
+
+	// rows buffers the results of plan. It is populated upon the start
+	// of the first cteScanNode that refers to this source.
+	rows *sqlbase.RowContainer
+
+	// refs counts the references to this source that have not been
+	// closed yet. The source is released when the last one is closed.
+	refs int
+}
+
+// materialize runs the plan of the CTE, if it has not run already,
+// and buffers its results.
+func (s *cteSource) materialize(params runParams) error {
+	if s.rows != nil {
+		return nil
+	}
+	if err := s.plan.Start(params); err != nil {
+		return err
+	}
+	s.rows = sqlbase.NewRowContainer(
+		params.p.session.TxnState.makeBoundAccount(),
+		sqlbase.ColTypeInfoFromResCols(s.columns),
+		0,
+	)
+	for {
+		if err := params.p.cancelChecker.Check(); err != nil {
+			return err
+		}
+		next, err := s.plan.Next(params)
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+		if _, err := s.rows.AddRow(params.ctx, s.plan.Values()); err != nil {
+			return err
+		}
+	}
+}
+
+// release drops one reference to the source, and closes the source
+// once there are no references left.
+func (s *cteSource) release(ctx context.Context) {
+	s.refs--
+	if s.refs == 0 {
+		s.close(ctx)
+	}
+}
+
+// close releases the resources held by the source.
+func (s *cteSource) close(ctx context.Context) {
+	if s.plan != nil {
+		s.plan.Close(ctx)
+		s.plan = nil
+	}
+	if s.rows != nil {
+		s.rows.Close(ctx)
+		s.rows = nil
+	}
+}
+
+// newScan creates a new reference to the source.
+func (s *cteSource) newScan() *cteScanNode {
+	s.refs++
+	return &cteScanNode{
+		src: s,
+		// Each reference can be renamed independently, so it needs its
+		// own copy of the columns.
+		columns: append(sqlbase.ResultColumns(nil), s.columns...),
+	}
+}
+
+// cteScanNode is a planNode that produces the rows of a common table
+// expression, for one reference to the CTE.
+type cteScanNode struct {
+	src     *cteSource
+	columns sqlbase.ResultColumns
+	nextRow int
+}
+
+// Start implements the planNode interface.
+func (n *cteScanNode) Start(params runParams) error {
+	return n.src.materialize(params)
+}
+
+// Next implements the planNode interface.
+func (n *cteScanNode) Next(runParams) (bool, error) {
+	if n.nextRow >= n.src.rows.Len() {
+		return false, nil
+	}
+	n.nextRow++
+	return true, nil
+}
+
+// Values implements the planNode interface.
+func (n *cteScanNode) Values() tree.Datums {
+	return n.src.rows.At(n.nextRow - 1)
+}
+
+// Close implements the planNode interface.
+func (n *cteScanNode) Close(ctx context.Context) {
+	if n.src != nil {
+		n.src.release(ctx)
+		n.src = nil
+	}
+}
+
+// initWith plans the CTEs defined by the given WITH clause and makes
+// them visible by name to the statement that follows the clause. The
+// returned function must be called once this statement has been
+// planned, to restore the previous CTE name environment.
+func (p *planner) initWith(ctx context.Context, with *tree.With) (func(), error) {
+	if with == nil {
+		return func() {}, nil
+	}
+
+	scope := make(cteNameScope, len(with.CTEList))
+	popWith := func() {
+		p.cteEnv = p.cteEnv[:len(p.cteEnv)-1]
+		// Close the sources that ended up not being referenced.
+		for _, src := range scope {
+			if src.refs == 0 {
+				src.close(ctx)
+			}
+		}
+	}
+	p.cteEnv = append(p.cteEnv, scope)
+
+	for _, cte := range with.CTEList {
+		if _, ok := scope[cte.Name.Alias]; ok {
+			popWith()
+			return nil, pgerror.NewErrorf(pgerror.CodeDuplicateAliasError,
+				"WITH query name %q specified more than once", tree.ErrString(&cte.Name.Alias))
+		}
+		src, err := p.makeCTESource(ctx, cte, with.Recursive)
+		if err != nil {
+			popWith()
+			return nil, err
+		}
+		// A CTE is visible to the CTEs that follow it in the same WITH
+		// clause.
+		scope[cte.Name.Alias] = src
+	}
+	return popWith, nil
+}
+
+// makeCTESource plans a single common table expression.
+func (p *planner) makeCTESource(
+	ctx context.Context, cte *tree.CTE, recursive bool,
+) (*cteSource, error) {
+	sel, ok := cte.Stmt.(*tree.Select)
+	if !ok {
+		return nil, pgerror.Unimplemented("cte-dml",
+			"data-modifying statements in WITH are not supported")
+	}
+
+	var plan planNode
+	if recursive {
+		var err error
+		plan, err = p.makeRecursiveCTE(ctx, cte.Name, sel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if plan == nil {
+		var err error
+		plan, err = p.newPlan(ctx, sel, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	columns, err := cteColumns(cte.Name, planColumns(plan))
+	if err != nil {
+		plan.Close(ctx)
+		return nil, err
+	}
+	return &cteSource{name: cte.Name, plan: plan, columns: columns}, nil
+}
+
+// cteColumns applies the column aliases of a CTE to the result
+// columns of its query.
+func cteColumns(
+	name tree.AliasClause, cols sqlbase.ResultColumns,
+) (sqlbase.ResultColumns, error) {
+	src := planDataSource{
+		info: newSourceInfoForSingleTable(tree.TableName{TableName: name.Alias}, cols),
+	}
+	src, err := renameSource(src, name, false /* includeHidden */)
+	if err != nil {
+		return nil, err
+	}
+	return src.info.sourceColumns, nil
+}
+
+// getCTEDataSource checks whether the given table name refers to a
+// common table expression currently in scope. If so, it returns a
+// data source for a new reference to the CTE.
+func (p *planner) getCTEDataSource(
+	t *tree.NormalizableTableName,
+) (planDataSource, bool, error) {
+	if len(p.cteEnv) == 0 {
+		return planDataSource{}, false, nil
+	}
+	tn, err := t.Normalize()
+	if err != nil {
+		return planDataSource{}, false, err
+	}
+	if tn.DatabaseName != "" {
+		// CTE names are never qualified.
+		return planDataSource{}, false, nil
+	}
+	for i := len(p.cteEnv) - 1; i >= 0; i-- {
+		if src, ok := p.cteEnv[i][tn.TableName]; ok {
+			plan := src.newScan()
+			return planDataSource{
+				info: newSourceInfoForSingleTable(tree.TableName{TableName: tn.TableName}, plan.columns),
+				plan: plan,
+			}, true, nil
+		}
+	}
+	return planDataSource{}, false, nil
+}
+
+// recursiveCTENode computes the rows of a WITH RECURSIVE common table
+// expression of the form:
+//
+//   WITH RECURSIVE name AS (<initial term> UNION [ALL] <recursive term>)
+//
+// The initial term is run first; its results form the first working
+// table. Then the recursive term, where the CTE name refers to the
+// working table, is run repeatedly; the rows it produces in one
+// iteration form the working table of the next iteration. The process
+// stops when an iteration produces no rows. The result of the CTE is
+// the concatenation of all the working tables.
+//
+// For UNION (as opposed to UNION ALL), rows that have been produced
+// already are discarded, which guarantees termination when the set of
+// reachable rows is finite.
+//
+// Since planNodes cannot be restarted, the recursive term is planned
+// anew for every iteration.
+type recursiveCTENode struct {
+	p *planner
+
+	columns sqlbase.ResultColumns
+
+	// initial is the plan for the initial term.
+	initial planNode
+	// recursive is the syntax of the recursive term.
+	recursive *tree.Select
+	// all is true for UNION ALL.
+	all bool
+
+	// cteEnv is the CTE name environment in which the recursive term
+	// is planned. Its innermost scope binds the CTE name to working.
+	// The recursiveCTENode holds a reference to every other source in
+	// cteEnv, since they must outlive the plans of all the iterations.
+	cteEnv cteNameEnvironment
+
+	// working is the working table, i.e. the rows produced by the
+	// previous iteration. The recursiveCTENode holds a reference to it,
+	// so that it is not released when the plan of an iteration is
+	// closed.
+	working *cteSource
+	// next accumulates the rows produced by the current iteration.
+	next *sqlbase.RowContainer
+
+	// seen contains the encoding of every row produced so far, for
+	// UNION. Its memory usage is accounted for in seenAcc.
+	seen    map[string]struct{}
+	seenAcc mon.BoundAccount
+	scratch []byte
+
+	// nextRow is the index of the next row of the working table to
+	// return.
+	nextRow int
+	// done is set once an iteration produces no rows.
+	done bool
+}
+
+// makeRecursiveCTE plans a CTE of a WITH RECURSIVE clause. It returns
+// a nil plan if the CTE does not actually refer to itself, in which
+// case the CTE can be planned as a regular one.
+func (p *planner) makeRecursiveCTE(
+	ctx context.Context, name tree.AliasClause, sel *tree.Select,
+) (planNode, error) {
+	union, ok := sel.Select.(*tree.UnionClause)
+	if !ok || union.Type != tree.UnionOp ||
+		sel.With != nil || sel.OrderBy != nil || sel.Limit != nil {
+		return nil, nil
+	}
+
+	initial, err := p.newPlan(ctx, union.Left, nil)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := cteColumns(name, planColumns(initial))
+	if err != nil {
+		initial.Close(ctx)
+		return nil, err
+	}
+
+	n := &recursiveCTENode{
+		p:         p,
+		columns:   columns,
+		initial:   initial,
+		recursive: union.Right,
+		all:       union.All,
+		working:   &cteSource{name: name, columns: columns, expanded: true, refs: 1},
+	}
+	// Take a copy of the current environment, so that the recursive
+	// term resolves names in the same way at every iteration regardless
+	// of the CTEs defined afterwards.
+	n.cteEnv = make(cteNameEnvironment, 0, len(p.cteEnv)+1)
+	for _, scope := range p.cteEnv {
+		scopeCopy := make(cteNameScope, len(scope))
+		for cteName, src := range scope {
+			src.refs++
+			scopeCopy[cteName] = src
+		}
+		n.cteEnv = append(n.cteEnv, scopeCopy)
+	}
+	n.cteEnv = append(n.cteEnv, cteNameScope{name.Alias: n.working})
+
+	// Plan the recursive term once, to check whether it refers to the
+	// CTE and whether its results are compatible with those of the
+	// initial term.
+	rec, err := n.planRecursiveTerm(ctx)
+	if err != nil {
+		n.Close(ctx)
+		return nil, err
+	}
+	defer rec.Close(ctx)
+
+	switch n.working.refs {
+	case 1:
+		// The recursive term does not refer to the CTE.
+		n.Close(ctx)
+		return nil, nil
+	case 2:
+	default:
+		n.Close(ctx)
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidRecursionError,
+			"recursive reference to query %q must not appear more than once",
+			tree.ErrString(&name.Alias))
+	}
+
+	initialColumns := planColumns(initial)
+	recColumns := planColumns(rec)
+	if len(initialColumns) != len(recColumns) {
+		n.Close(ctx)
+		return nil, pgerror.NewErrorf(pgerror.CodeSyntaxError,
+			"each UNION query must have the same number of columns: %d vs %d",
+			len(initialColumns), len(recColumns))
+	}
+	for i := range initialColumns {
+		l, r := initialColumns[i].Typ, recColumns[i].Typ
+		if !(l.Equivalent(r) || r == types.Null) {
+			n.Close(ctx)
+			return nil, pgerror.NewErrorf(pgerror.CodeDatatypeMismatchError,
+				"recursive query %q column %d has type %s in non-recursive term but type %s overall",
+				tree.ErrString(&name.Alias), i+1, l, r)
+		}
+	}
+	return n, nil
+}
+
+// planRecursiveTerm creates a new plan for the recursive term.
+func (n *recursiveCTENode) planRecursiveTerm(ctx context.Context) (planNode, error) {
+	defer func(prev cteNameEnvironment) { n.p.cteEnv = prev }(n.p.cteEnv)
+	n.p.cteEnv = n.cteEnv
+	return n.p.newPlan(ctx, n.recursive, nil)
+}
+
+// Start implements the planNode interface.
+func (n *recursiveCTENode) Start(params runParams) error {
+	ti := sqlbase.ColTypeInfoFromResCols(n.columns)
+	n.working.rows = sqlbase.NewRowContainer(params.p.session.TxnState.makeBoundAccount(), ti, 0)
+	n.next = sqlbase.NewRowContainer(params.p.session.TxnState.makeBoundAccount(), ti, 0)
+	if !n.all {
+		n.seen = make(map[string]struct{})
+		n.seenAcc = params.p.session.TxnState.makeBoundAccount()
+	}
+
+	// The results of the initial term form the first working table.
+	if err := n.initial.Start(params); err != nil {
+		return err
+	}
+	if err := n.drain(params, n.initial, n.working.rows); err != nil {
+		return err
+	}
+	n.done = n.working.rows.Len() == 0
+	return nil
+}
+
+// Next implements the planNode interface.
+func (n *recursiveCTENode) Next(params runParams) (bool, error) {
+	for n.nextRow >= n.working.rows.Len() {
+		if n.done {
+			return false, nil
+		}
+		if err := n.iterate(params); err != nil {
+			return false, err
+		}
+	}
+	n.nextRow++
+	return true, nil
+}
+
+// Values implements the planNode interface.
+func (n *recursiveCTENode) Values() tree.Datums {
+	return n.working.rows.At(n.nextRow - 1)
+}
+
+// iterate runs the recursive term against the current working table,
+// then makes its results the new working table.
+func (n *recursiveCTENode) iterate(params runParams) error {
+	plan, err := n.planRecursiveTerm(params.ctx)
+	if err != nil {
+		return err
+	}
+	plan, err = params.p.optimizePlan(params.ctx, plan, allColumns(plan))
+	defer plan.Close(params.ctx)
+	if err != nil {
+		return err
+	}
+	if err := params.p.startPlan(params.ctx, plan); err != nil {
+		return err
+	}
+	if err := n.drain(params, plan, n.next); err != nil {
+		return err
+	}
+
+	n.working.rows, n.next = n.next, n.working.rows
+	n.next.Clear(params.ctx)
+	n.nextRow = 0
+	n.done = n.working.rows.Len() == 0
+	return nil
+}
+
+// drain adds the rows produced by the given plan to the given row
+// container, skipping the rows already produced in the case of UNION.
+func (n *recursiveCTENode) drain(
+	params runParams, plan planNode, dst *sqlbase.RowContainer,
+) error {
+	for {
+		if err := params.p.cancelChecker.Check(); err != nil {
+			return err
+		}
+		next, err := plan.Next(params)
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+		row := plan.Values()
+		if !n.all {
+			n.scratch, err = sqlbase.EncodeDatums(n.scratch[:0], row)
+			if err != nil {
+				return err
+			}
+			if _, ok := n.seen[string(n.scratch)]; ok {
+				continue
+			}
+			if err := n.seenAcc.Grow(params.ctx, int64(len(n.scratch))); err != nil {
+				return err
+			}
+			n.seen[string(n.scratch)] = struct{}{}
+		}
+		if _, err := dst.AddRow(params.ctx, row); err != nil {
+			return err
+		}
+	}
+}
+
+// Close implements the planNode interface.
+func (n *recursiveCTENode) Close(ctx context.Context) {
+	n.initial.Close(ctx)
+	if n.next != nil {
+		n.next.Close(ctx)
+		n.next = nil
+	}
+	if n.seen != nil {
+		n.seen = nil
+		n.seenAcc.Close(ctx)
+	}
+	n.working.release(ctx)
+	for _, scope := range n.cteEnv[:len(n.cteEnv)-1] {
+		for _, src := range scope {
+			src.release(ctx)
+		}
+	}
+}
+
+// recursiveCTESpans collects the spans of a recursiveCTENode. The
+// spans of the recursive term are determined from a fresh plan for
+// it.
+func recursiveCTESpans(
+	params runParams, n *recursiveCTENode,
+) (reads, writes roachpb.Spans, err error) {
+	plan, err := n.planRecursiveTerm(params.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan, err = params.p.optimizePlan(params.ctx, plan, allColumns(plan))
+	defer plan.Close(params.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return concatSpans(params, n.initial, plan)
+}
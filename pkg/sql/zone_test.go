@@ -174,6 +174,26 @@ func TestValidSetShowZones(t *testing.T) {
 	sqlutils.VerifyAllZoneConfigs(t, sqlDB, defaultRow, systemRow, jobsRow)
 	sqlutils.VerifyZoneConfigForTarget(t, sqlDB, "TABLE system.jobs", jobsRow)
 
+	// Ensure lease preferences can be configured.
+	zoneLeasePreferences := config.DefaultZoneConfig()
+	zoneLeasePreferences.LeasePreferences = []config.LeasePreference{
+		{Constraints: []config.Constraint{
+			{Type: config.Constraint_REQUIRED, Key: "region", Value: "us-east1"},
+		}},
+		{Constraints: []config.Constraint{
+			{Type: config.Constraint_PROHIBITED, Value: "ssd"},
+		}},
+	}
+	dbLeasePreferencesRow := sqlutils.ZoneRow{
+		ID:           keys.MaxReservedDescID + 1,
+		CLISpecifier: "d",
+		Config:       zoneLeasePreferences,
+	}
+	sqlutils.SetZoneConfig(t, sqlDB, "DATABASE d", "lease_preferences: [[+region=us-east1], [-ssd]]")
+	sqlutils.VerifyZoneConfigForTarget(t, sqlDB, "DATABASE d", dbLeasePreferencesRow)
+	sqlutils.VerifyZoneConfigForTarget(t, sqlDB, "TABLE d.t", dbLeasePreferencesRow)
+	sqlutils.DeleteZoneConfig(t, sqlDB, "DATABASE d")
+
 	// Ensure zone configs are read transactionally instead of from the cached
 	// system config.
 	txn, err := db.Begin()
@@ -207,6 +227,22 @@ func TestInvalidSetShowZones(t *testing.T) {
 			"ALTER RANGE default EXPERIMENTAL CONFIGURE ZONE '&!@*@&'",
 			"could not parse zone config",
 		},
+		{
+			"ALTER RANGE default EXPERIMENTAL CONFIGURE ZONE 'lease_preferences: [[region=us-east1]]'",
+			"lease preference constraints must either be required",
+		},
+		{
+			"ALTER RANGE default EXPERIMENTAL CONFIGURE ZONE 'lease_preferences: [[]]'",
+			"every lease preference must include at least one constraint",
+		},
+		{
+			"ALTER RANGE default EXPERIMENTAL CONFIGURE ZONE 'constraints: {+region=us-east1: 4}'",
+			"constraints apply to 4 replicas, but num_replicas is only 3",
+		},
+		{
+			"ALTER RANGE default EXPERIMENTAL CONFIGURE ZONE 'constraints: {\"+ssd,-ssd\": 1}'",
+			"constraints \\[\\+ssd, -ssd\\] contradict each other",
+		},
 		{
 			"ALTER TABLE system.namespace EXPERIMENTAL CONFIGURE ZONE ''",
 			"cannot set zone configs for system config tables",
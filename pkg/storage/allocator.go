@@ -587,9 +587,11 @@ func (a *Allocator) scorerOptions(disableStatsBasedRebalancing bool) scorerOptio
 // TransferLeaseTarget returns a suitable replica to transfer the range lease
 // to from the provided list. It excludes the current lease holder replica
 // unless asked to do otherwise by the checkTransferLeaseSource parameter.
+// Replicas satisfying the lease preferences of the zone are chosen over the
+// others.
 func (a *Allocator) TransferLeaseTarget(
 	ctx context.Context,
-	constraints config.Constraints,
+	zone config.ZoneConfig,
 	existing []roachpb.ReplicaDescriptor,
 	leaseStoreID roachpb.StoreID,
 	rangeID roachpb.RangeID,
@@ -599,7 +601,7 @@ func (a *Allocator) TransferLeaseTarget(
 	alwaysAllowDecisionWithoutStats bool,
 ) roachpb.ReplicaDescriptor {
 	sl, _, _ := a.storePool.getStoreList(rangeID, storeFilterNone)
-	sl = sl.filter(constraints)
+	sl = sl.filter(zone.Constraints)
 
 	// Filter stores that are on nodes containing existing replicas, but leave
 	// the stores containing the existing replicas in place. This excludes stores
@@ -624,6 +626,23 @@ func (a *Allocator) TransferLeaseTarget(
 	}
 	sl = makeStoreList(filteredDescs)
 
+	// Leases must be transferred to replicas satisfying the lease preferences,
+	// if any of them does.
+	preferred := a.preferredLeaseholders(zone, existing)
+	if len(preferred) == 1 {
+		if preferred[0].StoreID == leaseStoreID {
+			return roachpb.ReplicaDescriptor{}
+		}
+		return preferred[0]
+	} else if len(preferred) > 1 {
+		existing = preferred
+		// If the current lease holder doesn't satisfy the preferences, the lease
+		// must be transferred regardless of the balance of leases.
+		if !storeHasReplica(leaseStoreID, preferred) {
+			checkTransferLeaseSource = false
+		}
+	}
+
 	source, ok := a.storePool.getStoreDescriptor(leaseStoreID)
 	if !ok {
 		return roachpb.ReplicaDescriptor{}
@@ -679,12 +698,13 @@ func (a *Allocator) TransferLeaseTarget(
 	return candidates[a.randGen.Intn(len(candidates))]
 }
 
-// ShouldTransferLease returns true if the specified store is overfull in terms
-// of leases with respect to the other stores matching the specified
-// attributes.
+// ShouldTransferLease returns true if the specified store doesn't satisfy the
+// lease preferences of the zone while another replica does, or if it is
+// overfull in terms of leases with respect to the other stores matching the
+// specified attributes.
 func (a *Allocator) ShouldTransferLease(
 	ctx context.Context,
-	constraints config.Constraints,
+	zone config.ZoneConfig,
 	existing []roachpb.ReplicaDescriptor,
 	leaseStoreID roachpb.StoreID,
 	rangeID roachpb.RangeID,
@@ -694,8 +714,24 @@ func (a *Allocator) ShouldTransferLease(
 	if !ok {
 		return false
 	}
+
+	// Determine whether the lease must be transferred to satisfy the lease
+	// preferences. If it doesn't, only the preferred replicas are considered
+	// when balancing leases.
+	preferred := a.preferredLeaseholders(zone, existing)
+	if len(preferred) > 0 {
+		if !storeHasReplica(leaseStoreID, preferred) {
+			log.VEventf(ctx, 3, "ShouldTransferLease (lease-holder=%d): lease preferences not satisfied", leaseStoreID)
+			return true
+		}
+		if len(preferred) == 1 {
+			return false
+		}
+		existing = preferred
+	}
+
 	sl, _, _ := a.storePool.getStoreList(rangeID, storeFilterNone)
-	sl = sl.filter(constraints)
+	sl = sl.filter(zone.Constraints)
 	log.VEventf(ctx, 3, "ShouldTransferLease (lease-holder=%d):\n%s", leaseStoreID, sl)
 
 	transferDec, _ := a.shouldTransferLeaseUsingStats(ctx, sl, source, existing, stats)
@@ -919,6 +955,43 @@ func (a Allocator) shouldTransferLeaseWithoutStats(
 	return false
 }
 
+// preferredLeaseholders returns the existing replicas which satisfy the first
+// lease preference of the zone that any of them satisfies. It returns nil if
+// the zone has no lease preferences, or if none of them is satisfied.
+func (a Allocator) preferredLeaseholders(
+	zone config.ZoneConfig, existing []roachpb.ReplicaDescriptor,
+) []roachpb.ReplicaDescriptor {
+	// The preferences are ordered by priority, so there's no need to look at
+	// the later ones once replicas matching a preference have been found.
+	for _, preference := range zone.LeasePreferences {
+		constraints := config.Constraints{Constraints: preference.Constraints}
+		var preferred []roachpb.ReplicaDescriptor
+		for _, repl := range existing {
+			storeDesc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+			if !ok {
+				continue
+			}
+			if ok, _ := constraintCheck(storeDesc, constraints); ok {
+				preferred = append(preferred, repl)
+			}
+		}
+		if len(preferred) > 0 {
+			return preferred
+		}
+	}
+	return nil
+}
+
+// storeHasReplica returns whether one of the replicas is on the store.
+func storeHasReplica(storeID roachpb.StoreID, replicas []roachpb.ReplicaDescriptor) bool {
+	for _, repl := range replicas {
+		if repl.StoreID == storeID {
+			return true
+		}
+	}
+	return false
+}
+
 // computeQuorum computes the quorum value for the given number of nodes.
 func computeQuorum(nodes int) int {
 	return (nodes / 2) + 1
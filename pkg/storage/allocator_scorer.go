@@ -102,6 +102,7 @@ func (bd balanceDimensions) String() string {
 type candidate struct {
 	store           roachpb.StoreDescriptor
 	valid           bool
+	necessary       bool
 	constraintScore float64
 	convergesScore  int
 	balanceScore    balanceDimensions
@@ -110,9 +111,9 @@ type candidate struct {
 }
 
 func (c candidate) String() string {
-	return fmt.Sprintf("s%d, valid:%t, constraint:%.2f, converges:%d, balance:%s, rangeCount:%d, "+
-		"logicalBytes:%s, writesPerSecond:%.2f, details:(%s)",
-		c.store.StoreID, c.valid, c.constraintScore, c.convergesScore, c.balanceScore, c.rangeCount,
+	return fmt.Sprintf("s%d, valid:%t, necessary:%t, constraint:%.2f, converges:%d, balance:%s, "+
+		"rangeCount:%d, logicalBytes:%s, writesPerSecond:%.2f, details:(%s)",
+		c.store.StoreID, c.valid, c.necessary, c.constraintScore, c.convergesScore, c.balanceScore, c.rangeCount,
 		humanizeutil.IBytes(c.store.Capacity.LogicalBytes), c.store.Capacity.WritesPerSecond, c.details)
 }
 
@@ -124,6 +125,9 @@ func (c candidate) less(o candidate) bool {
 	if !c.valid {
 		return true
 	}
+	if c.necessary != o.necessary {
+		return o.necessary
+	}
 	if c.constraintScore != o.constraintScore {
 		return c.constraintScore < o.constraintScore
 	}
@@ -172,7 +176,8 @@ func (c byScoreAndID) Less(i, j int) bool {
 		c[i].convergesScore == c[j].convergesScore &&
 		c[i].balanceScore.totalScore() == c[j].balanceScore.totalScore() &&
 		c[i].rangeCount == c[j].rangeCount &&
-		c[i].valid == c[j].valid {
+		c[i].valid == c[j].valid &&
+		c[i].necessary == c[j].necessary {
 		return c[i].store.StoreID < c[j].store.StoreID
 	}
 	return c[i].less(c[j])
@@ -198,7 +203,8 @@ func (cl candidateList) best() candidateList {
 		return cl
 	}
 	for i := 1; i < len(cl); i++ {
-		if cl[i].constraintScore < cl[0].constraintScore ||
+		if cl[i].necessary != cl[0].necessary ||
+			cl[i].constraintScore < cl[0].constraintScore ||
 			(cl[i].constraintScore == cl[len(cl)-1].constraintScore &&
 				cl[i].convergesScore < cl[len(cl)-1].convergesScore) {
 			return cl[:i]
@@ -223,7 +229,8 @@ func (cl candidateList) worst() candidateList {
 	}
 	// Find the worst constraint values.
 	for i := len(cl) - 2; i >= 0; i-- {
-		if cl[i].constraintScore > cl[len(cl)-1].constraintScore ||
+		if cl[i].necessary != cl[len(cl)-1].necessary ||
+			cl[i].constraintScore > cl[len(cl)-1].constraintScore ||
 			(cl[i].constraintScore == cl[len(cl)-1].constraintScore &&
 				cl[i].convergesScore > cl[len(cl)-1].convergesScore) {
 			return cl[i+1:]
@@ -309,6 +316,7 @@ func allocateCandidates(
 	existingNodeLocalities map[roachpb.NodeID]roachpb.Locality,
 	options scorerOptions,
 ) candidateList {
+	replicaCounts := replicaConstraintCounts(existingStores(sl, existing), constraints)
 	var candidates candidateList
 	for _, s := range sl.stores {
 		if !preexistingReplicaCheck(s.Node.NodeID, existing) {
@@ -326,6 +334,7 @@ func allocateCandidates(
 		candidates = append(candidates, candidate{
 			store:           s,
 			valid:           true,
+			necessary:       allocateReplicaConstraintsCheck(s, constraints, replicaCounts),
 			constraintScore: diversityScore + float64(preferredMatched),
 			balanceScore:    balanceScore,
 			rangeCount:      int(s.Capacity.RangeCount),
@@ -350,6 +359,7 @@ func removeCandidates(
 	existingNodeLocalities map[roachpb.NodeID]roachpb.Locality,
 	options scorerOptions,
 ) candidateList {
+	replicaCounts := replicaConstraintCounts(sl.stores, constraints)
 	var candidates candidateList
 	for _, s := range sl.stores {
 		constraintsOk, preferredMatched := constraintCheck(s, constraints)
@@ -383,6 +393,7 @@ func removeCandidates(
 		candidates = append(candidates, candidate{
 			store:           s,
 			valid:           true,
+			necessary:       removeReplicaConstraintsCheck(s, constraints, replicaCounts),
 			constraintScore: diversityScore + float64(preferredMatched),
 			convergesScore:  convergesScore,
 			balanceScore:    balanceScore,
@@ -423,23 +434,52 @@ func rebalanceCandidates(
 	var constraintsOkStoreDescriptors []roachpb.StoreDescriptor
 
 	type constraintInfo struct {
-		ok      bool
-		matched int
+		ok        bool
+		necessary bool
+		matched   int
 	}
 	storeInfos := make(map[roachpb.StoreID]constraintInfo)
+	replicaCounts := replicaConstraintCounts(existingStores(sl, existing), constraints)
 	var rebalanceConstraintsCheck bool
+	// replicaConstraintsSatisfiable is set if a store without a replica would
+	// satisfy per-replica constraints which the existing replicas don't.
+	var replicaConstraintsSatisfiable bool
 	for _, s := range sl.stores {
 		constraintsOk, preferredMatched := constraintCheck(s, constraints)
-		storeInfos[s.StoreID] = constraintInfo{ok: constraintsOk, matched: preferredMatched}
 		_, exists := existingStoreIDs[s.StoreID]
+		var necessary bool
+		if exists {
+			necessary = removeReplicaConstraintsCheck(s, constraints, replicaCounts)
+		} else {
+			necessary = allocateReplicaConstraintsCheck(s, constraints, replicaCounts)
+		}
+		storeInfos[s.StoreID] = constraintInfo{
+			ok: constraintsOk, necessary: necessary, matched: preferredMatched,
+		}
 		if constraintsOk {
 			constraintsOkStoreDescriptors = append(constraintsOkStoreDescriptors, s)
+			if !exists && necessary && maxCapacityCheck(s) {
+				replicaConstraintsSatisfiable = true
+			}
 		} else if exists {
 			rebalanceConstraintsCheck = true
 			log.VEventf(ctx, 2, "must rebalance from s%d due to constraint check", s.StoreID)
 		}
 	}
 
+	// If some of the per-replica constraints are not satisfied by enough
+	// replicas but could be, the replicas not needed to satisfy the others must
+	// move.
+	var replicaConstraintsCheck bool
+	if replicaConstraintsSatisfiable {
+		for _, s := range sl.stores {
+			if _, ok := existingStoreIDs[s.StoreID]; ok && !storeInfos[s.StoreID].necessary {
+				rebalanceConstraintsCheck, replicaConstraintsCheck = true, true
+				log.VEventf(ctx, 2, "must rebalance from s%d due to per-replica constraints", s.StoreID)
+			}
+		}
+	}
+
 	constraintsOkStoreList := makeStoreList(constraintsOkStoreDescriptors)
 	var shouldRebalanceCheck bool
 	if !rebalanceConstraintsCheck {
@@ -474,6 +514,14 @@ func rebalanceCandidates(
 				})
 				continue
 			}
+			if replicaConstraintsCheck && !storeInfo.necessary {
+				existingCandidates = append(existingCandidates, candidate{
+					store:   s,
+					valid:   false,
+					details: "per-replica constraint check fail",
+				})
+				continue
+			}
 			if !maxCapacityOK {
 				existingCandidates = append(existingCandidates, candidate{
 					store:   s,
@@ -495,6 +543,7 @@ func rebalanceCandidates(
 			existingCandidates = append(existingCandidates, candidate{
 				store:           s,
 				valid:           true,
+				necessary:       storeInfo.necessary,
 				constraintScore: diversityScore + float64(storeInfo.matched),
 				convergesScore:  convergesScore,
 				balanceScore:    balanceScore,
@@ -523,6 +572,7 @@ func rebalanceCandidates(
 			candidates = append(candidates, candidate{
 				store:           s,
 				valid:           true,
+				necessary:       storeInfo.necessary,
 				constraintScore: diversityScore + float64(storeInfo.matched),
 				convergesScore:  convergesScore,
 				balanceScore:    balanceScore,
@@ -697,6 +747,78 @@ func constraintCheck(store roachpb.StoreDescriptor, constraints config.Constrain
 	return true, positive
 }
 
+// replicaConstraintsCheck returns true iff the store satisfies all of the
+// (required or prohibited) per-replica constraints rc.
+func replicaConstraintsCheck(store roachpb.StoreDescriptor, rc config.ReplicaConstraints) bool {
+	ok, _ := constraintCheck(store, config.Constraints{Constraints: rc.Constraints})
+	return ok
+}
+
+// existingStores returns the descriptors of the stores in sl holding the
+// existing replicas.
+func existingStores(
+	sl StoreList, existing []roachpb.ReplicaDescriptor,
+) []roachpb.StoreDescriptor {
+	var stores []roachpb.StoreDescriptor
+	for _, s := range sl.stores {
+		for _, r := range existing {
+			if r.StoreID == s.StoreID {
+				stores = append(stores, s)
+				break
+			}
+		}
+	}
+	return stores
+}
+
+// replicaConstraintCounts returns, for each of the per-replica constraints,
+// the number of the given stores satisfying it.
+func replicaConstraintCounts(
+	stores []roachpb.StoreDescriptor, constraints config.Constraints,
+) []int {
+	if len(constraints.PerReplica) == 0 {
+		return nil
+	}
+	counts := make([]int, len(constraints.PerReplica))
+	for i, rc := range constraints.PerReplica {
+		for _, s := range stores {
+			if replicaConstraintsCheck(s, rc) {
+				counts[i]++
+			}
+		}
+	}
+	return counts
+}
+
+// allocateReplicaConstraintsCheck returns whether a new replica on the store
+// would satisfy per-replica constraints not yet satisfied by enough of the
+// existing replicas, given the counts returned by replicaConstraintCounts.
+func allocateReplicaConstraintsCheck(
+	store roachpb.StoreDescriptor, constraints config.Constraints, counts []int,
+) bool {
+	for i, rc := range constraints.PerReplica {
+		if counts[i] < int(rc.NumReplicas) && replicaConstraintsCheck(store, rc) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeReplicaConstraintsCheck returns whether the existing replica on the
+// store is needed to satisfy per-replica constraints, that is whether removing
+// it would leave them satisfied by too few replicas, given the counts returned
+// by replicaConstraintCounts.
+func removeReplicaConstraintsCheck(
+	store roachpb.StoreDescriptor, constraints config.Constraints, counts []int,
+) bool {
+	for i, rc := range constraints.PerReplica {
+		if counts[i] <= int(rc.NumReplicas) && replicaConstraintsCheck(store, rc) {
+			return true
+		}
+	}
+	return false
+}
+
 // diversityScore returns a score between 1 and 0 where higher scores are stores
 // with the fewest locality tiers in common with already existing replicas.
 func diversityScore(
@@ -1162,7 +1162,7 @@ func TestAllocatorTransferLeaseTarget(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 			target := a.TransferLeaseTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				c.existing,
 				c.leaseholder,
 				0,
@@ -1216,7 +1216,7 @@ func TestAllocatorTransferLeaseTargetMultiStore(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 			target := a.TransferLeaseTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				existing,
 				c.leaseholder,
 				0,
@@ -1279,7 +1279,7 @@ func TestAllocatorShouldTransferLease(t *testing.T) {
 		t.Run("", func(t *testing.T) {
 			result := a.ShouldTransferLease(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				c.existing,
 				c.leaseholder,
 				0,
@@ -1292,6 +1292,98 @@ func TestAllocatorShouldTransferLease(t *testing.T) {
 	}
 }
 
+func TestAllocatorLeasePreferences(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator( /* deterministic */ true)
+	defer stopper.Stop(context.Background())
+
+	// 4 stores where the lease count for each store is equal to 10x the store
+	// ID, and where stores 2 and 3 match the first lease preference and store
+	// 4 matches the second.
+	attrs := [][]string{nil, {"pref1"}, {"pref1"}, {"pref2"}}
+	var stores []*roachpb.StoreDescriptor
+	for i := 1; i <= 4; i++ {
+		stores = append(stores, &roachpb.StoreDescriptor{
+			StoreID:  roachpb.StoreID(i),
+			Attrs:    roachpb.Attributes{Attrs: attrs[i-1]},
+			Node:     roachpb.NodeDescriptor{NodeID: roachpb.NodeID(i)},
+			Capacity: roachpb.StoreCapacity{LeaseCount: int32(10 * i)},
+		})
+	}
+	sg := gossiputil.NewStoreGossiper(g)
+	sg.GossipStores(stores, t)
+
+	zone := config.ZoneConfig{
+		LeasePreferences: []config.LeasePreference{
+			{Constraints: []config.Constraint{{Value: "pref1", Type: config.Constraint_REQUIRED}}},
+			{Constraints: []config.Constraint{{Value: "pref2", Type: config.Constraint_REQUIRED}}},
+		},
+	}
+
+	replicas := func(storeIDs ...roachpb.StoreID) []roachpb.ReplicaDescriptor {
+		var r []roachpb.ReplicaDescriptor
+		for _, storeID := range storeIDs {
+			r = append(r, roachpb.ReplicaDescriptor{
+				NodeID:  roachpb.NodeID(storeID),
+				StoreID: storeID,
+			})
+		}
+		return r
+	}
+
+	testCases := []struct {
+		leaseholder    roachpb.StoreID
+		existing       []roachpb.ReplicaDescriptor
+		expectTransfer bool
+		expectTarget   roachpb.StoreID
+	}{
+		// The lease moves to the least loaded store matching the first
+		// preference.
+		{leaseholder: 1, existing: replicas(1, 2, 3, 4), expectTransfer: true, expectTarget: 2},
+		{leaseholder: 4, existing: replicas(1, 2, 3, 4), expectTransfer: true, expectTarget: 2},
+		// The lease stays on a store matching the first preference.
+		{leaseholder: 2, existing: replicas(1, 2, 3, 4), expectTransfer: false, expectTarget: 0},
+		// The lease moves to the only store matching the first preference.
+		{leaseholder: 4, existing: replicas(1, 3, 4), expectTransfer: true, expectTarget: 3},
+		{leaseholder: 3, existing: replicas(1, 3, 4), expectTransfer: false, expectTarget: 0},
+		// The second preference applies when no replica matches the first.
+		{leaseholder: 1, existing: replicas(1, 4), expectTransfer: true, expectTarget: 4},
+		{leaseholder: 4, existing: replicas(1, 4), expectTransfer: false, expectTarget: 0},
+		// Without replicas matching any preference, leases are balanced as
+		// usual.
+		{leaseholder: 1, existing: replicas(1), expectTransfer: false, expectTarget: 0},
+	}
+	for _, c := range testCases {
+		t.Run("", func(t *testing.T) {
+			result := a.ShouldTransferLease(
+				context.Background(),
+				zone,
+				c.existing,
+				c.leaseholder,
+				0,
+				nil, /* replicaStats */
+			)
+			if c.expectTransfer != result {
+				t.Errorf("expected ShouldTransferLease %v, but found %v", c.expectTransfer, result)
+			}
+			target := a.TransferLeaseTarget(
+				context.Background(),
+				zone,
+				c.existing,
+				c.leaseholder,
+				0,
+				nil,   /* replicaStats */
+				true,  /* checkTransferLeaseSource */
+				true,  /* checkCandidateFullness */
+				false, /* alwaysAllowDecisionWithoutStats */
+			)
+			if c.expectTarget != target.StoreID {
+				t.Errorf("expected TransferLeaseTarget s%d, but found s%d", c.expectTarget, target.StoreID)
+			}
+		})
+	}
+}
+
 // Test out the load-based lease transfer algorithm against a variety of
 // request distributions and inter-node latencies.
 func TestAllocatorTransferLeaseTargetLoadBased(t *testing.T) {
@@ -1445,7 +1537,7 @@ func TestAllocatorTransferLeaseTargetLoadBased(t *testing.T) {
 			})
 			target := a.TransferLeaseTarget(
 				context.Background(),
-				config.Constraints{},
+				config.ZoneConfig{},
 				existing,
 				c.leaseholder,
 				0,
@@ -1661,6 +1753,103 @@ func TestAllocatorRemoveTarget(t *testing.T) {
 	}
 }
 
+// TestAllocatorPerReplicaConstraints verifies that replicas are added,
+// removed and rebalanced so that the per-replica constraints are satisfied.
+func TestAllocatorPerReplicaConstraints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Stores 1-3 are in us-east and stores 4-6 in us-west.
+	var stores []*roachpb.StoreDescriptor
+	for i := 1; i <= 6; i++ {
+		region := "us-east"
+		if i > 3 {
+			region = "us-west"
+		}
+		stores = append(stores, &roachpb.StoreDescriptor{
+			StoreID: roachpb.StoreID(i),
+			Node: roachpb.NodeDescriptor{
+				NodeID: roachpb.NodeID(i),
+				Locality: roachpb.Locality{
+					Tiers: []roachpb.Tier{{Key: "region", Value: region}},
+				},
+			},
+			Capacity: roachpb.StoreCapacity{Capacity: 200, Available: 100, RangeCount: 10},
+		})
+	}
+
+	ctx := context.Background()
+	stopper, g, _, a, _ := createTestAllocator( /* deterministic */ true)
+	defer stopper.Stop(ctx)
+	gossiputil.NewStoreGossiper(g).GossipStores(stores, t)
+
+	// Two replicas in us-east and one in us-west.
+	constraints := config.Constraints{
+		PerReplica: []config.ReplicaConstraints{
+			{NumReplicas: 2, Constraints: []config.Constraint{
+				{Type: config.Constraint_REQUIRED, Key: "region", Value: "us-east"},
+			}},
+			{NumReplicas: 1, Constraints: []config.Constraint{
+				{Type: config.Constraint_REQUIRED, Key: "region", Value: "us-west"},
+			}},
+		},
+	}
+
+	replicas := func(storeIDs ...roachpb.StoreID) []roachpb.ReplicaDescriptor {
+		var r []roachpb.ReplicaDescriptor
+		for i, storeID := range storeIDs {
+			r = append(r, roachpb.ReplicaDescriptor{
+				NodeID:    roachpb.NodeID(storeID),
+				StoreID:   storeID,
+				ReplicaID: roachpb.ReplicaID(i + 1),
+			})
+		}
+		return r
+	}
+	isEast := func(storeID roachpb.StoreID) bool { return storeID <= 3 }
+
+	// A replica in us-east and one in us-west: the next one goes to us-east.
+	existing := replicas(1, 4)
+	for i := 0; i < 10; i++ {
+		target, _, err := a.AllocateTarget(
+			ctx, constraints, existing, testRangeInfo(existing, firstRange), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEast(target.StoreID) {
+			t.Fatalf("expected allocation to a store in us-east, got s%d", target.StoreID)
+		}
+	}
+
+	// Two replicas in each region: one in us-west is removed.
+	existing = replicas(1, 2, 4, 5)
+	for i := 0; i < 10; i++ {
+		removed, _, err := a.RemoveTarget(
+			ctx, constraints, existing, testRangeInfo(existing, firstRange), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isEast(removed.StoreID) {
+			t.Fatalf("expected removal from a store in us-west, got s%d", removed.StoreID)
+		}
+	}
+
+	// All the replicas in us-east: one is moved to us-west.
+	existing = replicas(1, 2, 3)
+	target, _ := a.RebalanceTarget(
+		ctx, constraints, nil, testRangeInfo(existing, firstRange), storeFilterThrottled, false)
+	if target == nil || isEast(target.StoreID) {
+		t.Fatalf("expected rebalancing to a store in us-west, got %v", target)
+	}
+
+	// The constraints are satisfied and the stores balanced: nothing moves.
+	existing = replicas(1, 2, 4)
+	if target, _ := a.RebalanceTarget(
+		ctx, constraints, nil, testRangeInfo(existing, firstRange), storeFilterThrottled, false,
+	); target != nil {
+		t.Fatalf("expected no rebalancing, got s%d", target.StoreID)
+	}
+}
+
 func TestAllocatorComputeAction(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
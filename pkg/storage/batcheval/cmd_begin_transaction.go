@@ -59,12 +59,16 @@ func BeginTransaction(
 			// txn.
 			return result.Result{}, roachpb.NewTransactionAbortedError()
 
-		case roachpb.PENDING:
+		case roachpb.PENDING, roachpb.STAGING:
 			if h.Txn.Epoch > tmpTxn.Epoch {
 				// On a transaction retry there will be an extant txn record
 				// but this run should have an upgraded epoch. The extant txn
 				// record may have been pushed or otherwise updated, so update
-				// this command's txn and rewrite the record.
+				// this command's txn and rewrite the record. A STAGING record
+				// is superseded by the new epoch, along with its in-flight
+				// writes.
+				tmpTxn.Status = roachpb.PENDING
+				tmpTxn.InFlightWrites = nil
 				reply.Txn.Update(&tmpTxn)
 			} else {
 				// Our txn record already exists. This is either a client error, sending
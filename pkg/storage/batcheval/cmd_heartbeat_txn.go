@@ -52,7 +52,7 @@ func HeartbeatTxn(
 		return result.Result{}, errors.Errorf("heartbeat for transaction %s failed; record not present", h.Txn)
 	}
 
-	if txn.Status == roachpb.PENDING {
+	if !txn.Status.IsFinalized() {
 		txn.LastHeartbeat.Forward(args.Now)
 		if err := engine.MVCCPutProto(ctx, batch, cArgs.Stats, key, hlc.Timestamp{}, nil, &txn); err != nil {
 			return result.Result{}, err
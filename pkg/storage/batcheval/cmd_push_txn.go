@@ -45,6 +45,10 @@ import (
 // Txn already committed/aborted: If pushee txn is committed or
 // aborted return success.
 //
+// Txn staging: If pushee txn is STAGING and the push would otherwise
+// succeed, return IndeterminateCommitError; the pusher must recover
+// the transaction (see RecoverTxn) before it can make progress.
+//
 // Txn Timeout: If pushee txn entry isn't present or its LastHeartbeat
 // timestamp isn't set, use its as LastHeartbeat. If current time -
 // LastHeartbeat > 2 * DefaultHeartbeatInterval, then the pushee txn
@@ -142,10 +146,16 @@ func PushTxn(
 	reply.PusheeTxn = existTxn.Clone()
 
 	// If already committed or aborted, return success.
-	if reply.PusheeTxn.Status != roachpb.PENDING {
+	if reply.PusheeTxn.Status.IsFinalized() {
 		// Trivial noop.
 		return result.Result{}, nil
 	}
+	// A STAGING record from an earlier epoch is stale: the pushee has since
+	// restarted, so it is treated as PENDING.
+	if reply.PusheeTxn.Status == roachpb.STAGING && reply.PusheeTxn.Epoch < args.PusheeTxn.Epoch {
+		reply.PusheeTxn.Status = roachpb.PENDING
+		reply.PusheeTxn.InFlightWrites = nil
+	}
 
 	// If we're trying to move the timestamp forward, and it's already
 	// far enough forward, return success.
@@ -206,6 +216,13 @@ func PushTxn(
 		return result.Result{}, err
 	}
 
+	// A STAGING transaction can be neither aborted nor pushed: it may
+	// already be implicitly committed. The pusher has to recover its
+	// outcome by looking for its in-flight writes instead.
+	if reply.PusheeTxn.Status == roachpb.STAGING {
+		return result.Result{}, roachpb.NewIndeterminateCommitError(reply.PusheeTxn)
+	}
+
 	// Upgrade priority of pushed transaction to one less than pusher's.
 	reply.PusheeTxn.UpgradePriority(args.PusherTxn.Priority - 1)
 
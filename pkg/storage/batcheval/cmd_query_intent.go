@@ -0,0 +1,66 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package batcheval
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// QueryIntent checks whether the specified transaction has written an
+// intent on the request's key. The intent matches if it belongs to the
+// same epoch of the transaction, carries a sequence number at least as
+// large as the queried one, and was written at or below the queried
+// transaction's timestamp.
+//
+// The request updates the timestamp cache (see its flags), so an intent
+// that is found missing can't be written later at or below the queried
+// timestamp. This is what allows both a transaction's coordinator and a
+// concurrent recovery to rely on a negative answer.
+func QueryIntent(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (result.Result, error) {
+	args := cArgs.Args.(*roachpb.QueryIntentRequest)
+	reply := resp.(*roachpb.QueryIntentResponse)
+
+	// Read at the maximum timestamp so that an intent is returned no matter
+	// its timestamp; its timestamp is checked below.
+	_, intents, err := engine.MVCCGet(
+		ctx, batch, args.Key, hlc.MaxTimestamp, false /* consistent */, nil, /* txn */
+	)
+	if err != nil {
+		return result.Result{}, err
+	}
+
+	for _, intent := range intents {
+		meta := intent.Txn
+		if meta.ID == args.Txn.ID &&
+			meta.Epoch == args.Txn.Epoch &&
+			meta.Sequence >= args.Txn.Sequence &&
+			!args.Txn.Timestamp.Less(meta.Timestamp) {
+			reply.FoundIntent = true
+			break
+		}
+	}
+
+	if !reply.FoundIntent && args.ErrorIfMissing {
+		return result.Result{}, roachpb.NewTransactionRetryError(roachpb.RETRY_ASYNC_WRITE_FAILURE)
+	}
+	return result.Result{}, nil
+}
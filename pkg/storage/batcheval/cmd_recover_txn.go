@@ -0,0 +1,83 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package batcheval
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// RecoverTxn finalizes a transaction that was found in the STAGING
+// state, after its in-flight writes have been queried. If all of them
+// were found, the transaction is implicitly committed and its record is
+// moved to COMMITTED; otherwise, the QueryIntent requests that failed
+// to find a write have prevented it from ever succeeding, and the
+// record is moved to ABORTED.
+//
+// If the record has changed since it was observed in the STAGING state
+// (because the coordinator finalized it, or restarted at a new epoch),
+// it is returned without modification.
+func RecoverTxn(
+	ctx context.Context, batch engine.ReadWriter, cArgs CommandArgs, resp roachpb.Response,
+) (result.Result, error) {
+	args := cArgs.Args.(*roachpb.RecoverTxnRequest)
+	reply := resp.(*roachpb.RecoverTxnResponse)
+
+	if cArgs.Header.Txn != nil {
+		return result.Result{}, ErrTransactionUnsupported
+	}
+	if !bytes.Equal(args.Key, args.Txn.Key) {
+		return result.Result{}, errors.Errorf("request key %s does not match txn key %s", args.Key, args.Txn.Key)
+	}
+	key := keys.TransactionKey(args.Txn.Key, args.Txn.ID)
+
+	ok, err := engine.MVCCGetProto(ctx, batch, key, hlc.Timestamp{},
+		true /* consistent */, nil /* txn */, &reply.RecoveredTxn)
+	if err != nil {
+		return result.Result{}, err
+	} else if !ok {
+		return result.Result{}, errors.Errorf("transaction record for %s not found", args.Txn.Short())
+	}
+
+	if reply.RecoveredTxn.Status != roachpb.STAGING ||
+		reply.RecoveredTxn.Epoch != args.Txn.Epoch ||
+		reply.RecoveredTxn.Timestamp != args.Txn.Timestamp {
+		// The record was modified after it was observed. Nothing to do.
+		return result.Result{}, nil
+	}
+
+	if args.ImplicitlyCommitted {
+		reply.RecoveredTxn.Status = roachpb.COMMITTED
+	} else {
+		reply.RecoveredTxn.Status = roachpb.ABORTED
+	}
+	reply.RecoveredTxn.InFlightWrites = nil
+	if err := engine.MVCCPutProto(
+		ctx, batch, cArgs.Stats, key, hlc.Timestamp{}, nil, &reply.RecoveredTxn,
+	); err != nil {
+		return result.Result{}, err
+	}
+	result := result.Result{}
+	result.Local.UpdatedTxn = &reply.RecoveredTxn
+	return result, nil
+}
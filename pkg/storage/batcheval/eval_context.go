@@ -60,6 +60,8 @@ type EvalContext interface {
 	GetMVCCStats() enginepb.MVCCStats
 	GetGCThreshold() hlc.Timestamp
 	GetTxnSpanGCThreshold() hlc.Timestamp
+	GetLeaseAppliedIndex() uint64
+	GetSplitQPS() float64
 	GetLastReplicaGCTimestamp(context.Context) (hlc.Timestamp, error)
 	GetLease() (roachpb.Lease, *roachpb.Lease)
 }
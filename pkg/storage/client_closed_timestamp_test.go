@@ -0,0 +1,85 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestFollowerReads verifies that the replicas which don't hold the lease of
+// a range serve reads at timestamps closed by the lease holder, and redirect
+// the more recent reads to the lease holder.
+func TestFollowerReads(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	sv := &mtc.storeConfig.Settings.SV
+	storagebase.FollowerReadsEnabled.Override(sv, true)
+	storagebase.ClosedTimestampTargetDuration.Override(sv, time.Millisecond)
+
+	mtc.replicateRange(1, 1, 2)
+
+	key, value := roachpb.Key("a"), []byte("value")
+	if _, pErr := client.SendWrapped(context.Background(), rg1(mtc.stores[0]), putArgs(key, value)); pErr != nil {
+		t.Fatal(pErr)
+	}
+	readTS := mtc.clocks[0].Now()
+
+	// Let the lease holder close a timestamp above the read.
+	mtc.manualClock.Increment((10 * time.Millisecond).Nanoseconds())
+
+	testutils.SucceedsSoon(t, func() error {
+		reply, pErr := client.SendWrappedWith(context.Background(), mtc.stores[1], roachpb.Header{
+			RangeID:   1,
+			Timestamp: readTS,
+		}, getArgs(key))
+		if pErr != nil {
+			return pErr.GoError()
+		}
+		val := reply.(*roachpb.GetResponse).Value
+		if val == nil {
+			return errors.Errorf("key %s not found", key)
+		}
+		if b, err := val.GetBytes(); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(b, value) {
+			t.Fatalf("expected %q, got %q", value, b)
+		}
+		return nil
+	})
+
+	// A read at the current time isn't closed yet.
+	_, pErr := client.SendWrappedWith(context.Background(), mtc.stores[1], roachpb.Header{
+		RangeID:   1,
+		Timestamp: mtc.clocks[1].Now(),
+	}, getArgs(key))
+	if _, ok := pErr.GetDetail().(*roachpb.NotLeaseHolderError); !ok {
+		t.Fatalf("expected NotLeaseHolderError, got %v", pErr)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
@@ -367,6 +368,254 @@ func TestStoreRangeMergeNonCollocated(t *testing.T) {
 	}
 }
 
+// expectBlocked returns a channel receiving the result of the write, after
+// checking that it blocks.
+func expectBlocked(t *testing.T, write func() error) chan error {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- write()
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("expected the write to the frozen range to block, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return errCh
+}
+
+// TestStoreRangeMergeSubsumeFreezesRange verifies that a SubsumeRequest
+// reports the lease applied index covering the writes to the range, and
+// blocks the requests of other transactions until the merge transaction
+// aborts.
+func TestStoreRangeMergeSubsumeFreezesRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	storeCfg := storage.TestStoreConfig(nil)
+	storeCfg.TestingKnobs.DisableSplitQueue = true
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, storeCfg)
+
+	_, rhsDesc, pErr := createSplitRanges(store)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	ctx := context.Background()
+	if err := store.DB().Put(ctx, "c", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	rhsRepl := store.LookupReplica(rhsDesc.StartKey, nil)
+
+	// Mimic a merge transaction, which deletes the descriptor of the right
+	// hand side before freezing it.
+	txn := client.NewTxn(store.DB(), 0 /* gatewayNodeID */)
+	if err := txn.Put(ctx, "a", "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Del(ctx, keys.RangeDescriptorKey(rhsDesc.StartKey)); err != nil {
+		t.Fatal(err)
+	}
+	var ba roachpb.BatchRequest
+	ba.RangeID = rhsDesc.RangeID
+	ba.Txn = txn.Proto()
+	ba.Timestamp = ba.Txn.Timestamp
+	ba.Add(&roachpb.SubsumeRequest{Span: roachpb.Span{Key: rhsDesc.StartKey.AsRawKey()}})
+	br, pErr := store.Send(ctx, ba)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	freeze := br.Responses[0].GetInner().(*roachpb.SubsumeResponse)
+	if a, e := freeze.LeaseAppliedIndex, rhsRepl.GetLeaseAppliedIndex(); a != e {
+		t.Fatalf("expected lease applied index %d, got %d", e, a)
+	}
+	if lease, _ := rhsRepl.GetLease(); !lease.Equivalent(freeze.Lease) {
+		t.Fatalf("expected lease %s, got %s", lease, freeze.Lease)
+	}
+
+	putErr := expectBlocked(t, func() error {
+		return store.DB().Put(ctx, "c", "v2")
+	})
+	if a, e := rhsRepl.GetLeaseAppliedIndex(), freeze.LeaseAppliedIndex; a != e {
+		t.Fatalf("expected lease applied index to remain %d, got %d", e, a)
+	}
+
+	if err := txn.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-putErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStoreRangeMergeFreezeFollowsLease verifies that a replica acquiring the
+// lease of a range whose descriptor is being deleted by a merge transaction
+// doesn't serve requests until the transaction aborts.
+func TestStoreRangeMergeFreezeFollowsLease(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 2)
+
+	_, rhsDesc, pErr := createSplitRanges(mtc.stores[0])
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	mtc.replicateRange(rhsDesc.RangeID, 1)
+
+	ctx := context.Background()
+	txn := client.NewTxn(mtc.dbs[0], 0 /* gatewayNodeID */)
+	if err := txn.Put(ctx, "a", "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Del(ctx, keys.RangeDescriptorKey(rhsDesc.StartKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new lease is applied after the deletion intent, which the new lease
+	// holder sees.
+	mtc.transferLease(ctx, rhsDesc.RangeID, 0, 1)
+	putErr := expectBlocked(t, func() error {
+		return mtc.dbs[0].Put(ctx, "c", "v")
+	})
+
+	if err := txn.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-putErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createSplitTableRanges splits off a range at the start of a user table and
+// splits it again within the table, returning the descriptors of the two
+// ranges of the table.
+func createSplitTableRanges(
+	db *client.DB, store *storage.Store,
+) (*roachpb.RangeDescriptor, *roachpb.RangeDescriptor, error) {
+	tableKey := keys.MakeTablePrefix(keys.MaxReservedDescID + 1)
+	midKey := append(append([]byte(nil), tableKey...), 'b')
+	for _, key := range []roachpb.Key{tableKey, midKey} {
+		if err := db.AdminSplit(context.TODO(), key, key); err != nil {
+			return nil, nil, err
+		}
+	}
+	lhsDesc := store.LookupReplica(roachpb.RKey(tableKey), nil).Desc()
+	rhsDesc := store.LookupReplica(roachpb.RKey(midKey), nil).Desc()
+	if lhsDesc.RangeID == rhsDesc.RangeID {
+		return nil, nil, errors.Errorf("ranges were not split: %s", lhsDesc)
+	}
+	return lhsDesc, rhsDesc, nil
+}
+
+// TestStoreRangeMergeQueue verifies that the merge queue merges small
+// adjacent ranges, but only once it has been enabled.
+func TestStoreRangeMergeQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	storeCfg := storage.TestStoreConfig(nil)
+	storeCfg.TestingKnobs.DisableSplitQueue = true
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, storeCfg)
+
+	lhsDesc, rhsDesc, err := createSplitTableRanges(store.DB(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The merge queue is disabled by default.
+	store.ForceMergeScanAndProcess()
+	if repl := store.LookupReplica(rhsDesc.StartKey, nil); repl.RangeID != rhsDesc.RangeID {
+		t.Fatalf("expected %s to be left alone, got %s", rhsDesc, repl)
+	}
+
+	storage.MergeQueueEnabled.Override(&store.ClusterSettings().SV, true)
+	store.ForceMergeScanAndProcess()
+	repl := store.LookupReplica(rhsDesc.StartKey, nil)
+	if repl.RangeID != lhsDesc.RangeID {
+		t.Fatalf("expected %s to be merged into %s, got %s", rhsDesc, lhsDesc, repl)
+	}
+	if !repl.Desc().EndKey.Equal(rhsDesc.EndKey) {
+		t.Fatalf("expected merged range to end at %s, got %s", rhsDesc.EndKey, repl.Desc())
+	}
+
+	// The range at the start of the keyspace is followed by a mandatory split
+	// point, and must not be merged into the table's range.
+	if first := store.LookupReplica(roachpb.RKeyMin, nil); first.RangeID == repl.RangeID {
+		t.Fatalf("expected the first range not to be merged, got %s", first)
+	}
+}
+
+// TestStoreRangeMergeQueueSkipsHotRanges verifies that the merge queue does
+// not merge ranges whose combined load would get them split by load again.
+func TestStoreRangeMergeQueueSkipsHotRanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	manual := hlc.NewManualClock(123)
+	storeCfg := storage.TestStoreConfig(hlc.NewClock(manual.UnixNano, time.Nanosecond))
+	storeCfg.TestingKnobs.DisableSplitQueue = true
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store := createTestStoreWithConfig(t, stopper, storeCfg)
+
+	lhsDesc, rhsDesc, err := createSplitTableRanges(store.DB(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.MergeQueueEnabled.Override(&store.ClusterSettings().SV, true)
+	storage.SplitByLoadQPSThreshold.Override(&store.ClusterSettings().SV, 10)
+
+	// Send 20 requests to the right hand side within a second.
+	for i := 0; i < 20; i++ {
+		if _, err := store.DB().Get(context.TODO(), rhsDesc.StartKey.AsRawKey()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manual.Increment(time.Second.Nanoseconds())
+
+	store.ForceMergeScanAndProcess()
+	if repl := store.LookupReplica(rhsDesc.StartKey, nil); repl.RangeID != rhsDesc.RangeID {
+		t.Fatalf("expected %s to be left alone, got %s", rhsDesc, repl)
+	}
+
+	// Once the threshold is raised above the load of the ranges, they are
+	// merged.
+	storage.SplitByLoadQPSThreshold.Override(&store.ClusterSettings().SV, 1000)
+	store.ForceMergeScanAndProcess()
+	if repl := store.LookupReplica(rhsDesc.StartKey, nil); repl.RangeID != lhsDesc.RangeID {
+		t.Fatalf("expected %s to be merged into %s, got %s", rhsDesc, lhsDesc, repl)
+	}
+}
+
+// TestStoreRangeMergeQueueCollocate verifies that the merge queue collocates
+// the replicas of adjacent ranges before merging them.
+func TestStoreRangeMergeQueueCollocate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := &multiTestContext{}
+	defer mtc.Stop()
+	mtc.Start(t, 3)
+
+	store := mtc.stores[0]
+	lhsDesc, rhsDesc, err := createSplitTableRanges(mtc.dbs[0], store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replicate the left hand side only.
+	mtc.replicateRange(lhsDesc.RangeID, 1, 2)
+
+	storage.MergeQueueEnabled.Override(&store.ClusterSettings().SV, true)
+	store.ForceMergeScanAndProcess()
+
+	testutils.SucceedsSoon(t, func() error {
+		for _, s := range mtc.stores {
+			repl := s.LookupReplica(rhsDesc.StartKey, nil)
+			if repl == nil || repl.RangeID != lhsDesc.RangeID {
+				return errors.Errorf("s%d: %s not merged into %s yet: %v", s.StoreID(), rhsDesc, lhsDesc, repl)
+			}
+		}
+		return nil
+	})
+}
+
 // TestStoreRangeMergeStats starts by splitting a range, then writing random data
 // to both sides of the split. It then merges the ranges and verifies the merged
 // range has stats consistent with recomputations.
@@ -913,6 +913,52 @@ func TestStoreRangeSplitWithMaxBytesUpdate(t *testing.T) {
 	})
 }
 
+// TestStoreRangeSplitByLoad verifies that a range receiving more requests per
+// second than the load threshold is split in the middle of its load.
+func TestStoreRangeSplitByLoad(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store, manual := createTestStore(t, stopper)
+
+	st := store.ClusterSettings()
+	st.Manual.Store(true)
+	storage.SplitByLoadQPSThreshold.Override(&st.SV, 50)
+
+	descID := uint32(keys.MaxReservedDescID + 1)
+	tableKey := keys.MakeTablePrefix(descID)
+	if err := store.DB().AdminSplit(context.TODO(), tableKey, tableKey); err != nil {
+		t.Fatal(err)
+	}
+	repl := store.LookupReplica(tableKey, nil)
+
+	rowKey := func(i int) roachpb.Key {
+		key := encoding.EncodeUvarintAscending(keys.MakeTablePrefix(descID), 1)
+		key = encoding.EncodeVarintAscending(key, int64(i))
+		return keys.MakeFamilyKey(key, 0)
+	}
+
+	// Send 100 requests per second on uniformly distributed rows for longer
+	// than the split decision needs to sample them.
+	const rows = 1000
+	for i := 0; i < 2000; i++ {
+		manual.Increment((10 * time.Millisecond).Nanoseconds())
+		if _, err := store.DB().Get(context.TODO(), rowKey(rand.Intn(rows))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testutils.SucceedsSoon(t, func() error {
+		store.ForceSplitScanAndProcess()
+		lhs := store.LookupReplica(roachpb.RKey(rowKey(0)), nil)
+		rhs := store.LookupReplica(roachpb.RKey(rowKey(rows-1)), nil)
+		if lhs.RangeID != repl.RangeID || rhs.RangeID == repl.RangeID {
+			return errors.Errorf("range %s has not yet been split by load", repl)
+		}
+		return nil
+	})
+}
+
 // TestStoreRangeSystemSplits verifies that splits are based on the contents of
 // the SystemConfig span.
 func TestStoreRangeSystemSplits(t *testing.T) {
@@ -768,6 +768,7 @@ func (m *multiTestContext) addStore(idx int) {
 	sender.AddStore(store)
 	storesServer := storage.MakeServer(&roachpb.NodeDescriptor{NodeID: nodeID}, sender)
 	storage.RegisterConsistencyServer(grpcServer, storesServer)
+	storage.RegisterPerReplicaServer(grpcServer, storesServer)
 
 	ln, err := netutil.ListenAndServeGRPC(m.transportStopper, grpcServer, util.TestAddr)
 	if err != nil {
@@ -14,6 +14,8 @@
 
 package enginepb
 
+import "fmt"
+
 // ToStats converts the receiver to an MVCCStats.
 func (ms *MVCCNetworkStats) ToStats() MVCCStats {
 	return MVCCStats(*ms)
@@ -23,3 +25,12 @@ func (ms *MVCCNetworkStats) ToStats() MVCCStats {
 func (ms *MVCCStats) ToNetworkStats() MVCCNetworkStats {
 	return MVCCNetworkStats(*ms)
 }
+
+// MustSetValue sets the operation contained in the union. It panics if the
+// operation is not recognized by the union type.
+func (op *MVCCLogicalOp) MustSetValue(value interface{}) {
+	op.Reset()
+	if !op.SetValue(value) {
+		panic(fmt.Sprintf("%T excludes %T", op, value))
+	}
+}
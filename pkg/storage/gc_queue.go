@@ -391,7 +391,7 @@ func processLocalKeyRange(
 
 		// The transaction record should be considered for removal.
 		switch txn.Status {
-		case roachpb.PENDING:
+		case roachpb.PENDING, roachpb.STAGING:
 			// Marked as running, so we need to push it to abort it but won't
 			// try to GC it in this cycle (for convenience).
 			// TODO(tschottdorf): refactor so that we can GC PENDING entries
@@ -611,6 +611,14 @@ func (gcq *gcQueue) processImpl(
 
 	gcKeys, info, err := RunGC(ctx, desc, snap, now, zone.GC,
 		func(now hlc.Timestamp, txn *roachpb.Transaction, typ roachpb.PushTxnType) {
+			// A STAGING transaction can't be pushed before its outcome is
+			// recovered.
+			if txn.Status == roachpb.STAGING {
+				if err := repl.store.intentResolver.recoverTxn(ctx, txn); err != nil {
+					log.Warningf(ctx, "recovery of txn %s failed: %s", txn, err)
+					return
+				}
+			}
 			pushTxn(ctx, gcq.store.DB(), now, txn, typ)
 		},
 		func(intents []roachpb.Intent, opts ResolveOptions) error {
@@ -875,7 +883,7 @@ func RunGC(
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, gcTaskLimit)
 	for _, txn := range txnMap {
-		if txn.Status != roachpb.PENDING {
+		if txn.Status.IsFinalized() {
 			continue
 		}
 		wg.Add(1)
@@ -904,7 +912,7 @@ func RunGC(
 	log.Eventf(ctx, "resolving up to %d intents", len(txnMap))
 	var intents []roachpb.Intent
 	for txnID, txn := range txnMap {
-		if txn.Status != roachpb.PENDING {
+		if txn.Status.IsFinalized() {
 			for _, intent := range intentSpanMap[txnID] {
 				intents = append(intents, roachpb.Intent{Span: intent, Status: txn.Status, Txn: txn.TxnMeta})
 			}
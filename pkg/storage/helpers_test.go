@@ -99,6 +99,12 @@ func (s *Store) ForceSplitScanAndProcess() {
 	forceScanAndProcess(s, s.splitQueue.baseQueue)
 }
 
+// ForceMergeScanAndProcess iterates over all ranges and enqueues any that
+// may need to be merged.
+func (s *Store) ForceMergeScanAndProcess() {
+	forceScanAndProcess(s, s.mergeQueue.baseQueue)
+}
+
 // ForceRaftLogScanAndProcess iterates over all ranges and enqueues any that
 // need their raft logs truncated and then process each of them.
 func (s *Store) ForceRaftLogScanAndProcess() {
@@ -167,6 +173,11 @@ func (s *Store) SetSplitQueueActive(active bool) {
 	s.setSplitQueueActive(active)
 }
 
+// SetMergeQueueActive enables or disables the merge queue.
+func (s *Store) SetMergeQueueActive(active bool) {
+	s.setMergeQueueActive(active)
+}
+
 // SetRaftSnapshotQueueActive enables or disables the raft snapshot queue.
 func (s *Store) SetRaftSnapshotQueueActive(active bool) {
 	s.setRaftSnapshotQueueActive(active)
@@ -231,11 +231,26 @@ func (ir *intentResolver) maybePushTransactions(
 			PushType: pushType,
 		})
 	}
-	b := &client.Batch{}
-	b.AddRawRequest(pushReqs...)
+	var b *client.Batch
 	var pErr *roachpb.Error
-	if err := ir.store.db.Run(ctx, b); err != nil {
-		pErr = b.MustPErr()
+	for {
+		b = &client.Batch{}
+		b.AddRawRequest(pushReqs...)
+		if err := ir.store.db.Run(ctx, b); err != nil {
+			pErr = b.MustPErr()
+		}
+		// A transaction in the STAGING state can't be pushed; its outcome
+		// has to be recovered first, after which the push is retried and
+		// finds it finalized (or restarted).
+		if tErr, ok := pErr.GetDetail().(*roachpb.IndeterminateCommitError); ok {
+			if err := ir.recoverTxn(ctx, &tErr.StagingTxn); err != nil {
+				pErr = roachpb.NewError(err)
+				break
+			}
+			pErr = nil
+			continue
+		}
+		break
 	}
 	ir.mu.Lock()
 	cleanupPushIntentsLocked()
@@ -263,11 +278,64 @@ func (ir *intentResolver) maybePushTransactions(
 		}
 		intent.Txn = pushee.TxnMeta
 		intent.Status = pushee.Status
+		if intent.Status == roachpb.STAGING {
+			// A STAGING transaction is only returned by a push that didn't
+			// need to change it, as its timestamp was already high enough.
+			// Its intents are still pending.
+			intent.Status = roachpb.PENDING
+		}
 		resolveIntents = append(resolveIntents, intent)
 	}
 	return resolveIntents, nil
 }
 
+// recoverTxn determines the outcome of a transaction which was found in
+// the STAGING state, and records it in the transaction record. Each of
+// the transaction's in-flight writes is queried at the staging
+// timestamp. Querying an intent prevents it from being written at or
+// below that timestamp afterwards, so if any of them is missing, the
+// transaction can never become implicitly committed and is aborted.
+// Otherwise, it is implicitly committed and is marked as such.
+func (ir *intentResolver) recoverTxn(ctx context.Context, txn *roachpb.Transaction) error {
+	log.VEventf(ctx, 1, "recovering STAGING txn %s", txn.Short())
+
+	implicitlyCommitted := true
+	if len(txn.InFlightWrites) > 0 {
+		b := &client.Batch{}
+		b.Header.Timestamp = txn.Timestamp
+		for _, w := range txn.InFlightWrites {
+			meta := txn.TxnMeta
+			meta.Sequence = w.Sequence
+			b.AddRawRequest(&roachpb.QueryIntentRequest{
+				Span: roachpb.Span{Key: w.Key},
+				Txn:  meta,
+			})
+		}
+		if err := ir.store.db.Run(ctx, b); err != nil {
+			return err
+		}
+		for _, resp := range b.RawResponse().Responses {
+			if !resp.GetInner().(*roachpb.QueryIntentResponse).FoundIntent {
+				implicitlyCommitted = false
+				break
+			}
+		}
+	}
+
+	b := &client.Batch{}
+	b.AddRawRequest(&roachpb.RecoverTxnRequest{
+		Span:                roachpb.Span{Key: txn.Key},
+		Txn:                 *txn,
+		ImplicitlyCommitted: implicitlyCommitted,
+	})
+	if err := ir.store.db.Run(ctx, b); err != nil {
+		return err
+	}
+	recovered := b.RawResponse().Responses[0].GetInner().(*roachpb.RecoverTxnResponse).RecoveredTxn
+	log.VEventf(ctx, 1, "recovered txn %s as %s", recovered.Short(), recovered.Status)
+	return nil
+}
+
 // processIntentsAsync asynchronously processes intents which were
 // encountered during another command but did not interfere with the
 // execution of that command. This occurs in two cases: inconsistent
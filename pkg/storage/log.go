@@ -40,6 +40,9 @@ const (
 	ReasonStoreDecommissioning RangeLogEventReason = "store decommissioning"
 	ReasonRebalance            RangeLogEventReason = "rebalance"
 	ReasonAdminRequest         RangeLogEventReason = "admin request"
+	ReasonZoneConfigBoundary   RangeLogEventReason = "zone config boundary"
+	ReasonRangeTooLarge        RangeLogEventReason = "range too large"
+	ReasonRangeLoad            RangeLogEventReason = "range load above threshold"
 )
 
 func (s *Store) insertRangeLogEvent(
@@ -107,10 +110,12 @@ VALUES(
 // logSplit logs a range split event into the event table. The affected range is
 // the range which previously existed and is being split in half; the "other"
 // range is the new range which is being created.
-// TODO(mrtracy): There are several different reasons that a replica split
-// could occur, and that information should be logged.
 func (s *Store) logSplit(
-	ctx context.Context, txn *client.Txn, updatedDesc, newDesc roachpb.RangeDescriptor,
+	ctx context.Context,
+	txn *client.Txn,
+	updatedDesc, newDesc roachpb.RangeDescriptor,
+	reason RangeLogEventReason,
+	details string,
 ) error {
 	if !s.cfg.LogRangeEvents {
 		return nil
@@ -124,6 +129,8 @@ func (s *Store) logSplit(
 		Info: &RangeLogEvent_Info{
 			UpdatedDesc: &updatedDesc,
 			NewDesc:     &newDesc,
+			Reason:      reason,
+			Details:     details,
 		},
 	})
 }
@@ -0,0 +1,259 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/gossip"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+const (
+	// mergeQueueTimerDuration is the duration between merges of queued ranges.
+	mergeQueueTimerDuration = 0 // zero duration to process merges greedily.
+)
+
+// MergeQueueEnabled wraps "kv.range_merge.queue_enabled".
+var MergeQueueEnabled = settings.RegisterBoolSetting(
+	"kv.range_merge.queue_enabled",
+	"whether the automatic merge queue is enabled",
+	false,
+)
+
+// mergeQueue manages a queue of ranges slated to be merged with their right
+// hand neighbor because their combined size is below the minimum size of
+// their zone.
+//
+// The queue processes the left hand side of a merge on the store holding its
+// lease. Before merging, it collocates the replicas of the right hand side
+// with those of the left hand side and moves the lease of the right hand side
+// to the store, as required by AdminMerge.
+type mergeQueue struct {
+	*baseQueue
+	db *client.DB
+}
+
+// newMergeQueue returns a new instance of mergeQueue.
+func newMergeQueue(store *Store, db *client.DB, gossip *gossip.Gossip) *mergeQueue {
+	mq := &mergeQueue{
+		db: db,
+	}
+	mq.baseQueue = newBaseQueue(
+		"merge", mq, store, gossip,
+		queueConfig{
+			maxSize:              defaultQueueMaxSize,
+			needsLease:           true,
+			needsSystemConfig:    true,
+			acceptsUnsplitRanges: false,
+			successes:            store.metrics.MergeQueueSuccesses,
+			failures:             store.metrics.MergeQueueFailures,
+			pending:              store.metrics.MergeQueuePending,
+			processingNanos:      store.metrics.MergeQueueProcessingNanos,
+		},
+	)
+	return mq
+}
+
+func (mq *mergeQueue) enabled() bool {
+	return MergeQueueEnabled.Get(&mq.store.ClusterSettings().SV)
+}
+
+// shouldQueue determines whether a range should be queued for merging. This
+// is true if the range is smaller than the minimum size of its zone, no zone
+// config or table boundary separates it from its right hand neighbor, and it
+// isn't hot enough to be split by load. The smaller the range, the higher
+// its priority.
+func (mq *mergeQueue) shouldQueue(
+	ctx context.Context, now hlc.Timestamp, repl *Replica, sysCfg config.SystemConfig,
+) (shouldQ bool, priority float64) {
+	if !mq.enabled() {
+		return false, 0
+	}
+	desc := repl.Desc()
+	if desc.EndKey.Equal(roachpb.RKeyMax) {
+		// The last range has no right hand neighbor.
+		return false, 0
+	}
+	if sysCfg.NeedsSplit(desc.StartKey, desc.EndKey.Next()) {
+		// The end key of the range is a mandatory split point.
+		return false, 0
+	}
+	zone, err := sysCfg.GetZoneConfigForKey(desc.StartKey)
+	if err != nil {
+		log.Error(ctx, err)
+		return false, 0
+	}
+	if zone.RangeMinBytes <= 0 {
+		return false, 0
+	}
+	if repl.GetSplitQPS() >= repl.splitByLoadQPSThreshold() {
+		// Merging the range would undo a split by load.
+		return false, 0
+	}
+	if ratio := float64(repl.GetMVCCStats().Total()) / float64(zone.RangeMinBytes); ratio < 1 {
+		priority = 1 - ratio
+		shouldQ = true
+	}
+	return
+}
+
+// process merges the range with its right hand neighbor if their combined
+// size is below the minimum size of their zone, and their combined load is
+// below the threshold for load based splitting.
+func (mq *mergeQueue) process(ctx context.Context, lhsRepl *Replica, sysCfg config.SystemConfig) error {
+	if !mq.enabled() {
+		log.VEventf(ctx, 2, "skipping merge: queue has been disabled")
+		return nil
+	}
+
+	lhsDesc := lhsRepl.Desc()
+	if lhsDesc.EndKey.Equal(roachpb.RKeyMax) {
+		return nil
+	}
+	lhsStats := lhsRepl.GetMVCCStats()
+	lhsQPS := lhsRepl.GetSplitQPS()
+
+	// Fetch the descriptor, stats and load of the right hand side from its
+	// lease holder, which may be on another store.
+	rhsDesc, rhsStats, rhsQPS, err := mq.rangeStats(ctx, lhsDesc.EndKey.AsRawKey())
+	if err != nil {
+		return err
+	}
+	if !rhsDesc.StartKey.Equal(lhsDesc.EndKey) {
+		// The left hand side has changed since we looked up its descriptor.
+		log.VEventf(ctx, 2, "skipping merge: ranges are no longer adjacent")
+		return nil
+	}
+
+	if sysCfg.NeedsSplit(lhsDesc.StartKey, rhsDesc.EndKey) {
+		log.VEventf(ctx, 2, "skipping merge: ranges are separated by a split point")
+		return nil
+	}
+	lhsZone, err := sysCfg.GetZoneConfigForKey(lhsDesc.StartKey)
+	if err != nil {
+		return err
+	}
+	rhsZone, err := sysCfg.GetZoneConfigForKey(rhsDesc.StartKey)
+	if err != nil {
+		return err
+	}
+	if !lhsZone.Equal(rhsZone) {
+		log.VEventf(ctx, 2, "skipping merge: ranges belong to different zones")
+		return nil
+	}
+	if mergedSize := lhsStats.Total() + rhsStats.Total(); mergedSize >= lhsZone.RangeMinBytes {
+		log.VEventf(ctx, 2, "skipping merge: merged range would be %d bytes, min is %d bytes",
+			mergedSize, lhsZone.RangeMinBytes)
+		return nil
+	}
+	// Merging ranges whose combined load would get them split by load again
+	// would only cause them to be merged and split back and forth.
+	if mergedQPS, threshold := lhsQPS+rhsQPS, lhsRepl.splitByLoadQPSThreshold(); mergedQPS >= threshold {
+		log.VEventf(ctx, 2, "skipping merge: merged range would receive %.2f qps, split threshold is %.2f qps",
+			mergedQPS, threshold)
+		return nil
+	}
+
+	if err := mq.collocate(ctx, lhsDesc, &rhsDesc); err != nil {
+		return errors.Wrapf(err, "unable to collocate %s with %s", rhsDesc, lhsRepl)
+	}
+
+	log.VEventf(ctx, 2, "merging %s into %s", rhsDesc, lhsRepl)
+	if _, pErr := lhsRepl.AdminMerge(ctx, roachpb.AdminMergeRequest{
+		Span: roachpb.Span{Key: lhsDesc.StartKey.AsRawKey()},
+	}); pErr != nil {
+		return pErr.GoError()
+	}
+	return nil
+}
+
+// rangeStats returns the descriptor, the MVCC statistics and the requests per
+// second of the range containing the key.
+func (mq *mergeQueue) rangeStats(
+	ctx context.Context, key roachpb.Key,
+) (roachpb.RangeDescriptor, enginepb.MVCCStats, float64, error) {
+	var b client.Batch
+	b.AddRawRequest(&roachpb.RangeStatsRequest{
+		Span: roachpb.Span{Key: key},
+	})
+	if err := mq.db.Run(ctx, &b); err != nil {
+		return roachpb.RangeDescriptor{}, enginepb.MVCCStats{}, 0, err
+	}
+	resp := b.RawResponse().Responses[0].GetInner().(*roachpb.RangeStatsResponse)
+	return resp.RangeDesc, resp.MVCCStats, resp.QueriesPerSecond, nil
+}
+
+// collocate moves the replicas and the lease of the right hand side of a
+// merge onto the stores of the replicas of the left hand side and the store
+// holding its lease, respectively. Replicas are added before the lease is
+// transferred, and removed afterwards, so that the range never loses the
+// store holding its lease.
+func (mq *mergeQueue) collocate(
+	ctx context.Context, lhsDesc, rhsDesc *roachpb.RangeDescriptor,
+) error {
+	var toAdd, toRemove []roachpb.ReplicationTarget
+	for _, rep := range lhsDesc.Replicas {
+		if _, ok := rhsDesc.GetReplicaDescriptor(rep.StoreID); !ok {
+			toAdd = append(toAdd, roachpb.ReplicationTarget{NodeID: rep.NodeID, StoreID: rep.StoreID})
+		}
+	}
+	for _, rep := range rhsDesc.Replicas {
+		if _, ok := lhsDesc.GetReplicaDescriptor(rep.StoreID); !ok {
+			toRemove = append(toRemove, roachpb.ReplicationTarget{NodeID: rep.NodeID, StoreID: rep.StoreID})
+		}
+	}
+
+	rhsKey := rhsDesc.StartKey.AsRawKey()
+	if len(toAdd) > 0 {
+		log.VEventf(ctx, 2, "adding replicas of %s on %v", rhsDesc, toAdd)
+		if err := mq.db.AdminChangeReplicas(ctx, rhsKey, roachpb.ADD_REPLICA, toAdd); err != nil {
+			return err
+		}
+	}
+	if rhsRepl := mq.store.LookupReplica(rhsDesc.StartKey, nil); rhsRepl == nil ||
+		!rhsRepl.OwnsValidLease(mq.store.Clock().Now()) {
+		log.VEventf(ctx, 2, "transferring lease of %s to s%d", rhsDesc, mq.store.StoreID())
+		if err := mq.db.AdminTransferLease(ctx, rhsKey, mq.store.StoreID()); err != nil {
+			return err
+		}
+	}
+	if len(toRemove) > 0 {
+		log.VEventf(ctx, 2, "removing replicas of %s on %v", rhsDesc, toRemove)
+		if err := mq.db.AdminChangeReplicas(ctx, rhsKey, roachpb.REMOVE_REPLICA, toRemove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timer returns interval between processing successive queued merges.
+func (*mergeQueue) timer(_ time.Duration) time.Duration {
+	return mergeQueueTimerDuration
+}
+
+// purgatoryChan returns nil.
+func (*mergeQueue) purgatoryChan() <-chan struct{} {
+	return nil
+}
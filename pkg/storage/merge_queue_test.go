@@ -0,0 +1,122 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+)
+
+// TestMergeQueueShouldQueue verifies that shouldQueue queues the ranges
+// smaller than the minimum size of their zone which aren't followed by a
+// mandatory split point, unless they are hot enough to be split by load.
+func TestMergeQueueShouldQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	config.TestingSetZoneConfig(2000, config.ZoneConfig{RangeMinBytes: 1 << 20, RangeMaxBytes: 32 << 20})
+	config.TestingSetZoneConfig(2002, config.ZoneConfig{RangeMinBytes: 1 << 20, RangeMaxBytes: 32 << 20})
+
+	tableKey := func(id uint32, suffix string) roachpb.RKey {
+		return roachpb.RKey(append(keys.MakeTablePrefix(id), suffix...))
+	}
+
+	testCases := []struct {
+		start, end roachpb.RKey
+		bytes      int64
+		qps        int
+		shouldQ    bool
+		priority   float64
+	}{
+		// Last range.
+		{tableKey(2000, ""), roachpb.RKeyMax, 0, 0, false, 0},
+		// Followed by a table boundary.
+		{tableKey(2000, ""), tableKey(2001, ""), 0, 0, false, 0},
+		// Followed by a static split point.
+		{roachpb.RKeyMin, roachpb.RKey(keys.Meta2Prefix), 0, 0, false, 0},
+		// Empty.
+		{tableKey(2000, ""), tableKey(2000, "b"), 0, 0, true, 1},
+		// Half the min bytes.
+		{tableKey(2000, ""), tableKey(2000, "b"), 1 << 19, 0, true, 0.5},
+		// Min bytes.
+		{tableKey(2000, ""), tableKey(2000, "b"), 1 << 20, 0, false, 0},
+		// Below the load based splitting threshold.
+		{tableKey(2000, ""), tableKey(2000, "b"), 0, 100, true, 1},
+		// At the load based splitting threshold.
+		{tableKey(2000, ""), tableKey(2000, "b"), 0, 200, false, 0},
+	}
+
+	mergeQ := newMergeQueue(tc.store, nil, tc.gossip)
+
+	cfg, ok := tc.gossip.GetSystemConfig()
+	if !ok {
+		t.Fatal("config not set")
+	}
+
+	SplitByLoadQPSThreshold.Override(&tc.store.cfg.Settings.SV, 200)
+	for _, enabled := range []bool{false, true} {
+		MergeQueueEnabled.Override(&tc.store.cfg.Settings.SV, enabled)
+		for i, test := range testCases {
+			// Create a replica for testing that is not hooked up to the store.
+			copy := *tc.repl.Desc()
+			copy.StartKey = test.start
+			copy.EndKey = test.end
+			repl, err := NewReplica(&copy, tc.store, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			repl.mu.Lock()
+			repl.mu.state.Stats = &enginepb.MVCCStats{KeyBytes: test.bytes}
+			repl.mu.Unlock()
+
+			// Record the requests over the last second.
+			now := tc.store.Clock().PhysicalTime()
+			repl.loadBasedSplitter.Record(now.Add(-time.Second), test.qps, nil)
+			repl.loadBasedSplitter.Record(now, 0, nil)
+
+			expShouldQ, expPriority := test.shouldQ, test.priority
+			if !enabled {
+				expShouldQ, expPriority = false, 0
+			}
+			shouldQ, priority := mergeQ.shouldQueue(context.TODO(), hlc.Timestamp{}, repl, cfg)
+			if shouldQ != expShouldQ {
+				t.Errorf("%d (enabled=%t): should queue expected %t; got %t", i, enabled, expShouldQ, shouldQ)
+			}
+			if math.Abs(priority-expPriority) > 0.00001 {
+				t.Errorf("%d (enabled=%t): priority expected %f; got %f", i, enabled, expPriority, priority)
+			}
+		}
+	}
+}
+
+////
+// NOTE: tests which actually verify processing of the merge queue are
+// in client_merge_test.go, which is in a different test package in
+// order to allow for distributed transactions with a proper client.
@@ -161,6 +161,9 @@ var (
 		Help: "Count of system KV pairs"}
 
 	// Metrics used by the rebalancing logic that aren't already captured elsewhere.
+	metaAverageQueriesPerSecond = metric.Metadata{
+		Name: "rebalancing.queriespersecond",
+		Help: "Number of kv-level requests received per second by the store, averaged over a large time period as used in rebalancing decisions"}
 	metaAverageWritesPerSecond = metric.Metadata{
 		Name: "rebalancing.writespersecond",
 		Help: "Number of keys written (i.e. applied by raft) per second to the store, averaged over a large time period as used in rebalancing decisions"}
@@ -317,6 +320,18 @@ var (
 	metaGCQueueProcessingNanos = metric.Metadata{
 		Name: "queue.gc.processingnanos",
 		Help: "Nanoseconds spent processing replicas in the GC queue"}
+	metaMergeQueueSuccesses = metric.Metadata{
+		Name: "queue.merge.process.success",
+		Help: "Number of replicas successfully processed by the merge queue"}
+	metaMergeQueueFailures = metric.Metadata{
+		Name: "queue.merge.process.failure",
+		Help: "Number of replicas which failed processing in the merge queue"}
+	metaMergeQueuePending = metric.Metadata{
+		Name: "queue.merge.pending",
+		Help: "Number of pending replicas in the merge queue"}
+	metaMergeQueueProcessingNanos = metric.Metadata{
+		Name: "queue.merge.processingnanos",
+		Help: "Nanoseconds spent processing replicas in the merge queue"}
 	metaRaftLogQueueSuccesses = metric.Metadata{
 		Name: "queue.raftlog.process.success",
 		Help: "Number of replicas successfully processed by the Raft log queue"}
@@ -524,7 +539,8 @@ type StoreMetrics struct {
 	SysCount        *metric.Gauge
 
 	// Rebalancing metrics.
-	AverageWritesPerSecond *metric.GaugeFloat64
+	AverageQueriesPerSecond *metric.GaugeFloat64
+	AverageWritesPerSecond  *metric.GaugeFloat64
 
 	// RocksDB metrics.
 	RdbBlockCacheHits           *metric.Gauge
@@ -596,6 +612,10 @@ type StoreMetrics struct {
 	GCQueueFailures                           *metric.Counter
 	GCQueuePending                            *metric.Gauge
 	GCQueueProcessingNanos                    *metric.Counter
+	MergeQueueSuccesses                       *metric.Counter
+	MergeQueueFailures                        *metric.Counter
+	MergeQueuePending                         *metric.Gauge
+	MergeQueueProcessingNanos                 *metric.Counter
 	RaftLogQueueSuccesses                     *metric.Counter
 	RaftLogQueueFailures                      *metric.Counter
 	RaftLogQueuePending                       *metric.Gauge
@@ -715,7 +735,8 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		SysCount:        metric.NewGauge(metaSysCount),
 
 		// Rebalancing metrics.
-		AverageWritesPerSecond: metric.NewGaugeFloat64(metaAverageWritesPerSecond),
+		AverageQueriesPerSecond: metric.NewGaugeFloat64(metaAverageQueriesPerSecond),
+		AverageWritesPerSecond:  metric.NewGaugeFloat64(metaAverageWritesPerSecond),
 
 		// RocksDB metrics.
 		RdbBlockCacheHits:           metric.NewGauge(metaRdbBlockCacheHits),
@@ -780,6 +801,10 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		GCQueueFailures:                           metric.NewCounter(metaGCQueueFailures),
 		GCQueuePending:                            metric.NewGauge(metaGCQueuePending),
 		GCQueueProcessingNanos:                    metric.NewCounter(metaGCQueueProcessingNanos),
+		MergeQueueSuccesses:                       metric.NewCounter(metaMergeQueueSuccesses),
+		MergeQueueFailures:                        metric.NewCounter(metaMergeQueueFailures),
+		MergeQueuePending:                         metric.NewGauge(metaMergeQueuePending),
+		MergeQueueProcessingNanos:                 metric.NewCounter(metaMergeQueueProcessingNanos),
 		RaftLogQueueSuccesses:                     metric.NewCounter(metaRaftLogQueueSuccesses),
 		RaftLogQueueFailures:                      metric.NewCounter(metaRaftLogQueueFailures),
 		RaftLogQueuePending:                       metric.NewGauge(metaRaftLogQueuePending),
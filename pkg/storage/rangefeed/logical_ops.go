@@ -0,0 +1,251 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rangefeed
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+// batchKeyOps accumulates the physical operations of a batch on the MVCC
+// versions of a single key.
+type batchKeyOps struct {
+	// The last metadata written for the key, if any.
+	meta *enginepb.MVCCMetadata
+	// Whether the metadata of the key was cleared after it was last written.
+	metaCleared bool
+	// The versions of the key written and cleared by the batch.
+	puts   []engine.MVCCKeyValue
+	clears []hlc.Timestamp
+}
+
+// LogicalOpsFromBatch translates the physical operations of a write batch
+// into logical MVCC operations. The reader must reflect the state of the
+// engine the batch is about to be applied to, which is used to tell what
+// happened to the intents whose metadata the batch clears. Only the
+// operations on non-local keys are translated.
+func LogicalOpsFromBatch(reader engine.Reader, repr []byte) ([]enginepb.MVCCLogicalOp, error) {
+	r, err := engine.NewRocksDBBatchReader(repr)
+	if err != nil {
+		return nil, err
+	}
+	var order []string
+	byKey := make(map[string]*batchKeyOps)
+	for r.Next() {
+		if r.BatchType() == engine.BatchTypeMerge {
+			// Merges are only used for non-MVCC data, like time series.
+			continue
+		}
+		key, err := r.MVCCKey()
+		if err != nil {
+			return nil, err
+		}
+		if keys.IsLocal(key.Key) {
+			continue
+		}
+		k, ok := byKey[string(key.Key)]
+		if !ok {
+			k = &batchKeyOps{}
+			byKey[string(key.Key)] = k
+			order = append(order, string(key.Key))
+		}
+		switch {
+		case !key.IsValue() && r.BatchType() == engine.BatchTypeValue:
+			k.meta = &enginepb.MVCCMetadata{}
+			if err := protoutil.Unmarshal(r.Value(), k.meta); err != nil {
+				return nil, err
+			}
+			k.metaCleared = false
+		case !key.IsValue():
+			k.meta = nil
+			k.metaCleared = true
+		case r.BatchType() == engine.BatchTypeValue:
+			k.puts = append(k.puts, engine.MVCCKeyValue{
+				Key:   engine.MVCCKey{Key: append(roachpb.Key(nil), key.Key...), Timestamp: key.Timestamp},
+				Value: append([]byte(nil), r.Value()...),
+			})
+		default:
+			k.clears = append(k.clears, key.Timestamp)
+		}
+	}
+	if err := r.Error(); err != nil {
+		return nil, err
+	}
+
+	var ops []enginepb.MVCCLogicalOp
+	for _, key := range order {
+		k := byKey[key]
+		keyOps, err := k.logicalOps(reader, roachpb.Key(key))
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, keyOps...)
+	}
+	return ops, nil
+}
+
+func (k *batchKeyOps) logicalOps(
+	reader engine.Reader, key roachpb.Key,
+) ([]enginepb.MVCCLogicalOp, error) {
+	var prevMeta *enginepb.MVCCMetadata
+	if k.meta != nil || k.metaCleared {
+		var meta enginepb.MVCCMetadata
+		ok, _, _, err := reader.GetProto(engine.MakeMVCCMetadataKey(key), &meta)
+		if err != nil {
+			return nil, err
+		}
+		if ok && meta.Txn != nil {
+			prevMeta = &meta
+		}
+	}
+
+	var ops []enginepb.MVCCLogicalOp
+	add := func(op interface{}) {
+		var logicalOp enginepb.MVCCLogicalOp
+		logicalOp.MustSetValue(op)
+		ops = append(ops, logicalOp)
+	}
+	switch {
+	case k.meta != nil && k.meta.Txn != nil:
+		// An intent was written. Its provisional value isn't committed.
+		ts := hlc.Timestamp(k.meta.Timestamp)
+		if prevMeta != nil && prevMeta.Txn.ID == k.meta.Txn.ID {
+			add(&enginepb.MVCCUpdateIntentOp{TxnID: k.meta.Txn.ID, Timestamp: ts})
+		} else {
+			add(&enginepb.MVCCWriteIntentOp{TxnID: k.meta.Txn.ID, Timestamp: ts})
+		}
+	case k.meta != nil:
+		// An inline value was written, which isn't versioned.
+	case k.metaCleared && prevMeta != nil:
+		// An intent was resolved. It was committed if its provisional value
+		// was kept, possibly at a new timestamp, or aborted otherwise.
+		txnID := prevMeta.Txn.ID
+		if n := len(k.puts); n > 0 {
+			put := k.puts[n-1]
+			add(commitIntentOp(txnID, put.Key.Key, put.Key.Timestamp, put.Value))
+			break
+		}
+		ts := hlc.Timestamp(prevMeta.Timestamp)
+		for _, clearTS := range k.clears {
+			if clearTS == ts {
+				add(&enginepb.MVCCAbortIntentOp{TxnID: txnID})
+				return ops, nil
+			}
+		}
+		value, err := reader.Get(engine.MVCCKey{Key: key, Timestamp: ts})
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, errors.Errorf("provisional value of committed intent on %s at %s not found", key, ts)
+		}
+		add(commitIntentOp(txnID, key, ts, value))
+	default:
+		// Values were written outside of a transaction.
+		for _, put := range k.puts {
+			add(&enginepb.MVCCWriteValueOp{
+				Key:       put.Key.Key,
+				Timestamp: put.Key.Timestamp,
+				Value:     put.Value,
+			})
+		}
+	}
+	return ops, nil
+}
+
+func commitIntentOp(
+	txnID uuid.UUID, key roachpb.Key, ts hlc.Timestamp, value []byte,
+) *enginepb.MVCCCommitIntentOp {
+	return &enginepb.MVCCCommitIntentOp{
+		TxnID:     txnID,
+		Key:       key,
+		Timestamp: ts,
+		Value:     value,
+	}
+}
+
+// scanIntents calls the function with the transaction and the timestamp of
+// each intent in the span.
+func scanIntents(
+	reader engine.Reader, span roachpb.Span, f func(uuid.UUID, hlc.Timestamp),
+) error {
+	return reader.Iterate(
+		engine.MakeMVCCMetadataKey(span.Key), engine.MakeMVCCMetadataKey(span.EndKey),
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			if kv.Key.IsValue() {
+				return false, nil
+			}
+			var meta enginepb.MVCCMetadata
+			if err := protoutil.Unmarshal(kv.Value, &meta); err != nil {
+				return false, err
+			}
+			if meta.Txn != nil {
+				f(meta.Txn.ID, hlc.Timestamp(meta.Timestamp))
+			}
+			return false, nil
+		})
+}
+
+// catchUpScan sends the values committed in the span above the start
+// timestamp, according to the reader.
+func catchUpScan(
+	reader engine.Reader,
+	span roachpb.Span,
+	startTS hlc.Timestamp,
+	send func(*roachpb.RangeFeedEvent) error,
+) error {
+	// The provisional value of an intent directly follows its metadata, and
+	// isn't committed.
+	var intentKey roachpb.Key
+	var intentTS hlc.Timestamp
+	return reader.Iterate(
+		engine.MakeMVCCMetadataKey(span.Key), engine.MakeMVCCMetadataKey(span.EndKey),
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			if !kv.Key.IsValue() {
+				var meta enginepb.MVCCMetadata
+				if err := protoutil.Unmarshal(kv.Value, &meta); err != nil {
+					return false, err
+				}
+				intentKey, intentTS = nil, hlc.Timestamp{}
+				if meta.Txn != nil {
+					intentKey = append(intentKey, kv.Key.Key...)
+					intentTS = hlc.Timestamp(meta.Timestamp)
+				}
+				return false, nil
+			}
+			if !startTS.Less(kv.Key.Timestamp) {
+				return false, nil
+			}
+			if kv.Key.Timestamp == intentTS && kv.Key.Key.Equal(intentKey) {
+				return false, nil
+			}
+			var event roachpb.RangeFeedEvent
+			event.MustSetValue(&roachpb.RangeFeedValue{
+				Key: append(roachpb.Key(nil), kv.Key.Key...),
+				Value: roachpb.Value{
+					RawBytes:  append([]byte(nil), kv.Value...),
+					Timestamp: kv.Key.Timestamp,
+				},
+			})
+			return false, send(&event)
+		})
+}
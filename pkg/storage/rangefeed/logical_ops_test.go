@@ -0,0 +1,168 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rangefeed
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+func TestLogicalOpsFromBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	eng := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer eng.Close()
+
+	ts := func(wallTime int64) hlc.Timestamp { return hlc.Timestamp{WallTime: wallTime} }
+	keyA, keyB := roachpb.Key("a"), roachpb.Key("b")
+	value := roachpb.MakeValueFromString("val")
+
+	// applyBatch runs the function on a new batch, and returns the logical
+	// operations of the batch before committing it.
+	applyBatch := func(f func(engine.ReadWriter) error) []enginepb.MVCCLogicalOp {
+		t.Helper()
+		batch := eng.NewBatch()
+		defer batch.Close()
+		if err := f(batch); err != nil {
+			t.Fatal(err)
+		}
+		ops, err := LogicalOpsFromBatch(eng, batch.Repr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Commit(false /* sync */); err != nil {
+			t.Fatal(err)
+		}
+		return ops
+	}
+	expect := func(ops []enginepb.MVCCLogicalOp, exp ...interface{}) {
+		t.Helper()
+		if len(ops) != len(exp) {
+			t.Fatalf("expected %d ops, got %+v", len(exp), ops)
+		}
+		for i := range ops {
+			if op := ops[i].GetValue(); !reflect.DeepEqual(op, exp[i]) {
+				t.Fatalf("%d: expected %+v, got %+v", i, exp[i], op)
+			}
+		}
+	}
+
+	// A non-transactional write.
+	ops := applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCPut(ctx, rw, nil, keyA, ts(1), value, nil)
+	})
+	expect(ops, &enginepb.MVCCWriteValueOp{Key: keyA, Timestamp: ts(1), Value: value.RawBytes})
+
+	// A transactional write, rewritten at a higher timestamp and then committed
+	// at the same timestamp.
+	txn := roachpb.MakeTransaction("test", keyB, 0, enginepb.SERIALIZABLE, ts(2), 0)
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCPut(ctx, rw, nil, keyB, txn.Timestamp, value, &txn)
+	})
+	expect(ops, &enginepb.MVCCWriteIntentOp{TxnID: txn.ID, Timestamp: ts(2)})
+
+	txn.Timestamp = ts(3)
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCPut(ctx, rw, nil, keyB, txn.Timestamp, value, &txn)
+	})
+	expect(ops, &enginepb.MVCCUpdateIntentOp{TxnID: txn.ID, Timestamp: ts(3)})
+
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCResolveWriteIntent(ctx, rw, nil, roachpb.Intent{
+			Span: roachpb.Span{Key: keyB}, Txn: txn.TxnMeta, Status: roachpb.COMMITTED,
+		})
+	})
+	expect(ops, &enginepb.MVCCCommitIntentOp{
+		TxnID: txn.ID, Key: keyB, Timestamp: ts(3), Value: value.RawBytes,
+	})
+
+	// A transactional write committed at a higher timestamp.
+	txn = roachpb.MakeTransaction("test", keyA, 0, enginepb.SERIALIZABLE, ts(4), 0)
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCPut(ctx, rw, nil, keyA, txn.Timestamp, value, &txn)
+	})
+	expect(ops, &enginepb.MVCCWriteIntentOp{TxnID: txn.ID, Timestamp: ts(4)})
+
+	txn.Timestamp = ts(5)
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCResolveWriteIntent(ctx, rw, nil, roachpb.Intent{
+			Span: roachpb.Span{Key: keyA}, Txn: txn.TxnMeta, Status: roachpb.COMMITTED,
+		})
+	})
+	expect(ops, &enginepb.MVCCCommitIntentOp{
+		TxnID: txn.ID, Key: keyA, Timestamp: ts(5), Value: value.RawBytes,
+	})
+
+	// An aborted transactional write.
+	txn = roachpb.MakeTransaction("test", keyA, 0, enginepb.SERIALIZABLE, ts(6), 0)
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCPut(ctx, rw, nil, keyA, txn.Timestamp, value, &txn)
+	})
+	expect(ops, &enginepb.MVCCWriteIntentOp{TxnID: txn.ID, Timestamp: ts(6)})
+
+	ops = applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCResolveWriteIntent(ctx, rw, nil, roachpb.Intent{
+			Span: roachpb.Span{Key: keyA}, Txn: txn.TxnMeta, Status: roachpb.ABORTED,
+		})
+	})
+	expect(ops, &enginepb.MVCCAbortIntentOp{TxnID: txn.ID})
+
+	// Only the committed values are scanned by a catch-up scan.
+	txn = roachpb.MakeTransaction("test", keyB, 0, enginepb.SERIALIZABLE, ts(7), 0)
+	applyBatch(func(rw engine.ReadWriter) error {
+		return engine.MVCCPut(ctx, rw, nil, keyB, txn.Timestamp, value, &txn)
+	})
+	var scanned []string
+	if err := catchUpScan(
+		eng, roachpb.Span{Key: keyA, EndKey: roachpb.Key("c")}, ts(1),
+		func(event *roachpb.RangeFeedEvent) error {
+			v := event.GetValue().(*roachpb.RangeFeedValue)
+			scanned = append(scanned, string(v.Key)+"@"+v.Value.Timestamp.String())
+			return nil
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"a@" + ts(5).String(), "b@" + ts(3).String()}; !reflect.DeepEqual(scanned, exp) {
+		t.Fatalf("expected catch-up scan %v, got %v", exp, scanned)
+	}
+
+	// The remaining intent is found by an intent scan.
+	var intents int
+	if err := scanIntents(
+		eng, roachpb.Span{Key: keyA, EndKey: roachpb.Key("c")},
+		func(_ uuid.UUID, intentTS hlc.Timestamp) {
+			if intentTS != ts(7) {
+				t.Fatalf("expected intent at %s, got %s", ts(7), intentTS)
+			}
+			intents++
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if intents != 1 {
+		t.Fatalf("expected 1 intent, got %d", intents)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rangefeed implements the server side of rangefeeds, which stream
+// the values committed in a span of a range, along with checkpoints promising
+// that no more values will be committed in the span below a resolved
+// timestamp.
+package rangefeed
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// Stream is a object capable of transmitting RangeFeedEvents.
+type Stream interface {
+	// Context returns the context for this stream.
+	Context() context.Context
+	// Send blocks until it sends m, the stream is done, or the stream breaks.
+	// Send must be safe to call on the same stream in different goroutines.
+	Send(*roachpb.RangeFeedEvent) error
+}
+
+// A Processor manages the rangefeed registrations of a replica. It is fed the
+// logical operations of the commands applied by the replica and its closed
+// timestamps, from which it publishes the committed values and the resolved
+// timestamps of the range to the registrations.
+//
+// A Processor is not safe for concurrent use: the replica only calls it while
+// holding its raftMu, so that the operations of the applied commands are
+// consumed in order. Events are buffered for each registration and sent on
+// its stream by the goroutine running the registration, so that slow streams
+// don't block the application of commands.
+type Processor struct {
+	span roachpb.RSpan
+	regs map[*Registration]struct{}
+	rts  resolvedTimestamp
+}
+
+// NewProcessor creates a new Processor for the given span of a range. It must
+// be initialized with the intents in the span using Init before use.
+func NewProcessor(span roachpb.RSpan) *Processor {
+	return &Processor{
+		span: span,
+		regs: make(map[*Registration]struct{}),
+		rts:  makeResolvedTimestamp(),
+	}
+}
+
+// Init scans the span of the processor for the intents the reader contains,
+// which hold back the resolved timestamp until they are resolved. The reader
+// must reflect all the commands applied so far, and none after.
+func (p *Processor) Init(reader engine.Reader) error {
+	return scanIntents(reader, p.span.AsRawSpanWithNoLocals(), p.rts.addIntent)
+}
+
+// Len returns the number of registrations of the processor.
+func (p *Processor) Len() int {
+	return len(p.regs)
+}
+
+// Register registers a stream for the values committed in the span above the
+// start timestamp. The values committed before the registration are scanned
+// from the catch-up snapshot, which must reflect all the commands applied so
+// far, and none after. The processor takes ownership of the snapshot.
+//
+// The caller must run the returned Registration, which sends the events to
+// the stream, and unregister it once it returns.
+func (p *Processor) Register(
+	span roachpb.Span, startTS hlc.Timestamp, catchUpSnap engine.Reader, stream Stream,
+) *Registration {
+	r := newRegistration(span, startTS, catchUpSnap, stream)
+	p.regs[r] = struct{}{}
+	p.publishCheckpoint(r)
+	return r
+}
+
+// Unregister removes the registration from the processor, if present.
+func (p *Processor) Unregister(r *Registration) {
+	delete(p.regs, r)
+}
+
+// Disconnect disconnects all the registrations of the processor with the
+// error.
+func (p *Processor) Disconnect(pErr *roachpb.Error) {
+	for r := range p.regs {
+		r.disconnect(pErr)
+		delete(p.regs, r)
+	}
+}
+
+// ConsumeLogicalOps publishes the values committed by the logical operations
+// of an applied command, and the resulting resolved timestamp.
+func (p *Processor) ConsumeLogicalOps(ops ...enginepb.MVCCLogicalOp) {
+	var advanced bool
+	for _, op := range ops {
+		switch t := op.GetValue().(type) {
+		case *enginepb.MVCCWriteValueOp:
+			p.publishValue(t.Key, t.Timestamp, t.Value)
+		case *enginepb.MVCCCommitIntentOp:
+			p.publishValue(t.Key, t.Timestamp, t.Value)
+		}
+		if p.rts.ConsumeLogicalOp(op) {
+			advanced = true
+		}
+	}
+	if advanced {
+		p.publishCheckpoints()
+	}
+}
+
+// ForwardClosedTS forwards the closed timestamp of the range, and publishes
+// the resulting resolved timestamp.
+func (p *Processor) ForwardClosedTS(closedTS hlc.Timestamp) {
+	if p.rts.ForwardClosedTS(closedTS) {
+		p.publishCheckpoints()
+	}
+}
+
+func (p *Processor) publishValue(key roachpb.Key, ts hlc.Timestamp, value []byte) {
+	var event roachpb.RangeFeedEvent
+	event.MustSetValue(&roachpb.RangeFeedValue{
+		Key: key,
+		Value: roachpb.Value{
+			RawBytes:  value,
+			Timestamp: ts,
+		},
+	})
+	for r := range p.regs {
+		if !r.span.Contains(roachpb.Span{Key: key}) || !r.startTS.Less(ts) {
+			continue
+		}
+		p.publish(r, &event)
+	}
+}
+
+func (p *Processor) publishCheckpoints() {
+	for r := range p.regs {
+		p.publishCheckpoint(r)
+	}
+}
+
+func (p *Processor) publishCheckpoint(r *Registration) {
+	resolvedTS := p.rts.Get()
+	if resolvedTS == (hlc.Timestamp{}) {
+		return
+	}
+	var event roachpb.RangeFeedEvent
+	event.MustSetValue(&roachpb.RangeFeedCheckpoint{
+		Span:       r.span,
+		ResolvedTS: resolvedTS,
+	})
+	p.publish(r, &event)
+}
+
+// publish buffers the event for the registration, or disconnects it if its
+// buffer is full.
+func (p *Processor) publish(r *Registration, event *roachpb.RangeFeedEvent) {
+	if !r.publish(event) {
+		r.disconnect(roachpb.NewError(
+			roachpb.NewRangeFeedRetryError(roachpb.RangeFeedRetryError_REASON_SLOW_CONSUMER),
+		))
+		delete(p.regs, r)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rangefeed
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+type testStream struct {
+	ctx    context.Context
+	events chan *roachpb.RangeFeedEvent
+}
+
+func newTestStream() *testStream {
+	return &testStream{
+		ctx:    context.Background(),
+		events: make(chan *roachpb.RangeFeedEvent, 16),
+	}
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testStream) Send(e *roachpb.RangeFeedEvent) error {
+	s.events <- e
+	return nil
+}
+
+func writeValueOp(key string, ts hlc.Timestamp) enginepb.MVCCLogicalOp {
+	var op enginepb.MVCCLogicalOp
+	op.MustSetValue(&enginepb.MVCCWriteValueOp{Key: []byte(key), Timestamp: ts, Value: []byte("val")})
+	return op
+}
+
+func TestProcessor(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	eng := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer eng.Close()
+
+	ts := func(wallTime int64) hlc.Timestamp { return hlc.Timestamp{WallTime: wallTime} }
+	span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("m")}
+	p := NewProcessor(roachpb.RSpan{Key: roachpb.RKey("a"), EndKey: roachpb.RKey("z")})
+	if err := p.Init(eng); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := newTestStream()
+	r := p.Register(span, ts(5), eng.NewSnapshot(), stream)
+	errC := make(chan *roachpb.Error, 1)
+	go func() { errC <- r.Run(stream.Context()) }()
+
+	expectValue := func(key string, valTS hlc.Timestamp) {
+		t.Helper()
+		e := <-stream.events
+		v, ok := e.GetValue().(*roachpb.RangeFeedValue)
+		if !ok || string(v.Key) != key || v.Value.Timestamp != valTS {
+			t.Fatalf("expected value %s@%s, got %+v", key, valTS, e)
+		}
+	}
+	expectCheckpoint := func(resolvedTS hlc.Timestamp) {
+		t.Helper()
+		e := <-stream.events
+		c, ok := e.GetValue().(*roachpb.RangeFeedCheckpoint)
+		if !ok || !reflect.DeepEqual(c.Span, span) || c.ResolvedTS != resolvedTS {
+			t.Fatalf("expected checkpoint of %s at %s, got %+v", span, resolvedTS, e)
+		}
+	}
+
+	// Values outside of the span, or at or below the start timestamp of the
+	// registration, aren't published.
+	p.ConsumeLogicalOps(
+		writeValueOp("b", ts(4)),
+		writeValueOp("n", ts(6)),
+		writeValueOp("c", ts(6)),
+	)
+	expectValue("c", ts(6))
+
+	// Intents hold back the published resolved timestamp.
+	txnID := uuid.MakeV4()
+	p.ConsumeLogicalOps(writeIntentOp(txnID, ts(8)))
+	p.ForwardClosedTS(ts(10))
+	expectCheckpoint(ts(8).Prev())
+	p.ConsumeLogicalOps(commitIntentLogicalOp(txnID, ts(8)))
+	expectValue("a", ts(8))
+	expectCheckpoint(ts(10))
+
+	// Disconnecting the processor stops the registration with the error.
+	p.Disconnect(roachpb.NewError(
+		roachpb.NewRangeFeedRetryError(roachpb.RangeFeedRetryError_REASON_RANGE_SPLIT),
+	))
+	pErr := <-errC
+	if _, ok := pErr.GetDetail().(*roachpb.RangeFeedRetryError); !ok {
+		t.Fatalf("expected RangeFeedRetryError, got %v", pErr)
+	}
+	if p.Len() != 0 {
+		t.Fatalf("expected no registrations, found %d", p.Len())
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rangefeed
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// defaultEventBufferSize is the number of events buffered for each
+// registration. A registration whose stream falls further behind is
+// disconnected.
+const defaultEventBufferSize = 4096
+
+// A Registration is a stream registered with a Processor for the values
+// committed in a span above a start timestamp.
+type Registration struct {
+	span        roachpb.Span
+	startTS     hlc.Timestamp
+	catchUpSnap engine.Reader
+	stream      Stream
+
+	// The events published by the processor but not yet sent on the stream.
+	buf chan *roachpb.RangeFeedEvent
+	// The error with which the processor disconnected the registration, if
+	// any. Buffered so that the processor never blocks on it.
+	errC chan *roachpb.Error
+}
+
+func newRegistration(
+	span roachpb.Span, startTS hlc.Timestamp, catchUpSnap engine.Reader, stream Stream,
+) *Registration {
+	return &Registration{
+		span:        span,
+		startTS:     startTS,
+		catchUpSnap: catchUpSnap,
+		stream:      stream,
+		buf:         make(chan *roachpb.RangeFeedEvent, defaultEventBufferSize),
+		errC:        make(chan *roachpb.Error, 1),
+	}
+}
+
+// publish buffers the event, and returns false if the buffer is full.
+func (r *Registration) publish(event *roachpb.RangeFeedEvent) bool {
+	select {
+	case r.buf <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// disconnect stops the registration with the error. It must only be called
+// once.
+func (r *Registration) disconnect(pErr *roachpb.Error) {
+	r.errC <- pErr
+}
+
+// Run sends the values committed before the registration, scanned from its
+// catch-up snapshot, and then the events published by the processor, on the
+// stream of the registration. It returns when the stream's context is done,
+// when the stream breaks or when the processor disconnects the registration.
+func (r *Registration) Run(ctx context.Context) *roachpb.Error {
+	err := catchUpScan(r.catchUpSnap, r.span, r.startTS, r.stream.Send)
+	r.catchUpSnap.Close()
+	r.catchUpSnap = nil
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+
+	for {
+		select {
+		case event := <-r.buf:
+			if err := r.stream.Send(event); err != nil {
+				return roachpb.NewError(err)
+			}
+		case pErr := <-r.errC:
+			return pErr
+		case <-ctx.Done():
+			return roachpb.NewError(ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rangefeed
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+// A resolvedTimestamp is the timestamp below which no more values will be
+// committed in a range. It is the minimum of the range's closed timestamp,
+// below which no new values will be written, and of the timestamps of the
+// unresolved intents in the range, which may still commit.
+//
+// Since a transaction commits at or above the timestamps of all of its
+// intents, each transaction with intents in the range holds the resolved
+// timestamp below the largest timestamp of its intents in the range.
+type resolvedTimestamp struct {
+	closedTS   hlc.Timestamp
+	resolvedTS hlc.Timestamp
+	txns       map[uuid.UUID]*unresolvedTxn
+}
+
+// unresolvedTxn tracks the intents of a transaction in the range.
+type unresolvedTxn struct {
+	// The number of intents of the transaction in the range.
+	refCount int
+	// The largest timestamp of the intents of the transaction in the range.
+	timestamp hlc.Timestamp
+}
+
+func makeResolvedTimestamp() resolvedTimestamp {
+	return resolvedTimestamp{
+		txns: make(map[uuid.UUID]*unresolvedTxn),
+	}
+}
+
+// Get returns the current resolved timestamp.
+func (rts *resolvedTimestamp) Get() hlc.Timestamp {
+	return rts.resolvedTS
+}
+
+// ForwardClosedTS forwards the closed timestamp of the range. It returns
+// whether the resolved timestamp moved forward.
+func (rts *resolvedTimestamp) ForwardClosedTS(closedTS hlc.Timestamp) bool {
+	if !rts.closedTS.Forward(closedTS) {
+		return false
+	}
+	return rts.recompute()
+}
+
+// ConsumeLogicalOp updates the set of unresolved intents with the logical
+// operation. It returns whether the resolved timestamp moved forward.
+func (rts *resolvedTimestamp) ConsumeLogicalOp(op enginepb.MVCCLogicalOp) bool {
+	switch t := op.GetValue().(type) {
+	case *enginepb.MVCCWriteValueOp:
+		// Values written outside of transactions are above the closed
+		// timestamp, so they don't affect the resolved timestamp.
+		return false
+	case *enginepb.MVCCWriteIntentOp:
+		rts.addIntent(t.TxnID, t.Timestamp)
+		return false
+	case *enginepb.MVCCUpdateIntentOp:
+		if txn, ok := rts.txns[t.TxnID]; ok {
+			txn.timestamp.Forward(t.Timestamp)
+		}
+		return false
+	case *enginepb.MVCCCommitIntentOp:
+		return rts.removeIntent(t.TxnID)
+	case *enginepb.MVCCAbortIntentOp:
+		return rts.removeIntent(t.TxnID)
+	default:
+		panic("unknown logical op")
+	}
+}
+
+func (rts *resolvedTimestamp) addIntent(txnID uuid.UUID, ts hlc.Timestamp) {
+	txn, ok := rts.txns[txnID]
+	if !ok {
+		txn = &unresolvedTxn{}
+		rts.txns[txnID] = txn
+	}
+	txn.refCount++
+	txn.timestamp.Forward(ts)
+}
+
+func (rts *resolvedTimestamp) removeIntent(txnID uuid.UUID) bool {
+	txn, ok := rts.txns[txnID]
+	if !ok {
+		// The intent was written before the resolved timestamp started being
+		// tracked and wasn't found when scanning for intents, which means it
+		// was already resolved.
+		return false
+	}
+	if txn.refCount--; txn.refCount > 0 {
+		return false
+	}
+	delete(rts.txns, txnID)
+	return rts.recompute()
+}
+
+// recompute computes the resolved timestamp from the closed timestamp and
+// the unresolved intents, and returns whether it moved forward. The resolved
+// timestamp never regresses.
+func (rts *resolvedTimestamp) recompute() bool {
+	resolvedTS := rts.closedTS
+	for _, txn := range rts.txns {
+		if !resolvedTS.Less(txn.timestamp) {
+			resolvedTS = txn.timestamp.Prev()
+		}
+	}
+	return rts.resolvedTS.Forward(resolvedTS)
+}
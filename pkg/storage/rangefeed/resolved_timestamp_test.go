@@ -0,0 +1,86 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rangefeed
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+)
+
+func writeIntentOp(txnID uuid.UUID, ts hlc.Timestamp) enginepb.MVCCLogicalOp {
+	var op enginepb.MVCCLogicalOp
+	op.MustSetValue(&enginepb.MVCCWriteIntentOp{TxnID: txnID, Timestamp: ts})
+	return op
+}
+
+func updateIntentOp(txnID uuid.UUID, ts hlc.Timestamp) enginepb.MVCCLogicalOp {
+	var op enginepb.MVCCLogicalOp
+	op.MustSetValue(&enginepb.MVCCUpdateIntentOp{TxnID: txnID, Timestamp: ts})
+	return op
+}
+
+func commitIntentLogicalOp(txnID uuid.UUID, ts hlc.Timestamp) enginepb.MVCCLogicalOp {
+	var op enginepb.MVCCLogicalOp
+	op.MustSetValue(&enginepb.MVCCCommitIntentOp{TxnID: txnID, Key: []byte("a"), Timestamp: ts})
+	return op
+}
+
+func abortIntentOp(txnID uuid.UUID) enginepb.MVCCLogicalOp {
+	var op enginepb.MVCCLogicalOp
+	op.MustSetValue(&enginepb.MVCCAbortIntentOp{TxnID: txnID})
+	return op
+}
+
+func TestResolvedTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ts := func(wallTime int64) hlc.Timestamp { return hlc.Timestamp{WallTime: wallTime} }
+	txn1, txn2 := uuid.MakeV4(), uuid.MakeV4()
+	rts := makeResolvedTimestamp()
+
+	expect := func(advanced, expAdvanced bool, expTS hlc.Timestamp) {
+		t.Helper()
+		if advanced != expAdvanced {
+			t.Fatalf("expected advanced=%t, got %t", expAdvanced, advanced)
+		}
+		if rts.Get() != expTS {
+			t.Fatalf("expected resolved timestamp %s, got %s", expTS, rts.Get())
+		}
+	}
+
+	// Without intents, the resolved timestamp follows the closed timestamp.
+	expect(rts.ForwardClosedTS(ts(10)), true, ts(10))
+	expect(rts.ForwardClosedTS(ts(5)), false, ts(10))
+
+	// Intents hold the resolved timestamp below the largest timestamp of the
+	// intents of their transaction.
+	expect(rts.ConsumeLogicalOp(writeIntentOp(txn1, ts(12))), false, ts(10))
+	expect(rts.ConsumeLogicalOp(writeIntentOp(txn1, ts(11))), false, ts(10))
+	expect(rts.ConsumeLogicalOp(writeIntentOp(txn2, ts(15))), false, ts(10))
+	expect(rts.ForwardClosedTS(ts(20)), true, ts(12).Prev())
+	expect(rts.ConsumeLogicalOp(updateIntentOp(txn1, ts(13))), false, ts(12).Prev())
+
+	// Resolving one of the two intents of txn1 doesn't release it.
+	expect(rts.ConsumeLogicalOp(commitIntentLogicalOp(txn1, ts(13))), false, ts(12).Prev())
+	expect(rts.ConsumeLogicalOp(commitIntentLogicalOp(txn1, ts(13))), true, ts(15).Prev())
+	expect(rts.ConsumeLogicalOp(abortIntentOp(txn2)), true, ts(20))
+
+	// Resolving intents which aren't tracked is a no-op.
+	expect(rts.ConsumeLogicalOp(abortIntentOp(txn2)), false, ts(20))
+}
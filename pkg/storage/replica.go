@@ -47,7 +47,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/storage/rangefeed"
 	"github.com/cockroachdb/cockroach/pkg/storage/spanset"
+	"github.com/cockroachdb/cockroach/pkg/storage/split"
 	"github.com/cockroachdb/cockroach/pkg/storage/stateloader"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/storage/tscache"
@@ -233,6 +235,9 @@ const (
 	destroyReasonRemovalPending
 	// The replica has been GCed.
 	destroyReasonRemoved
+	// The replica has been subsumed by a merge, which the subsuming replica
+	// on the store hasn't applied yet.
+	destroyReasonMergePending
 )
 
 type destroyStatus struct {
@@ -277,6 +282,9 @@ type Replica struct {
 	// writeStats tracks the number of keys written by applied raft commands
 	// in order to aid in replica rebalancing decisions.
 	writeStats *replicaStats
+	// loadBasedSplitter keeps track of the load of the replica in order to
+	// decide whether and where to split it.
+	loadBasedSplitter split.Decider
 
 	// creatingReplica is set when a replica is created as uninitialized
 	// via a raft message.
@@ -305,6 +313,9 @@ type Replica struct {
 		stateLoader stateloader.StateLoader
 		// on-disk storage for sideloaded SSTables. nil when there's no ReplicaID.
 		sideloaded sideloadStorage
+		// The rangefeed processor of the replica, which publishes the events
+		// of the rangefeeds registered on it. nil when there are none.
+		rangefeed *rangefeed.Processor
 	}
 
 	// Contains the lease history when enabled.
@@ -337,6 +348,16 @@ type Replica struct {
 		state storagebase.ReplicaState
 		// Counter used for assigning lease indexes for proposals.
 		lastAssignedLeaseIndex uint64
+		// The highest closed timestamp of the commands applied by the replica,
+		// at or below which it can serve follower reads. See
+		// replica_closed_timestamp.go.
+		closedTimestamp hlc.Timestamp
+		// The highest closed timestamp attached to the commands proposed by the
+		// replica, and the number of writes above it, by timestamp, which have
+		// been evaluated or are being evaluated but haven't applied yet. Both
+		// are only used while the replica holds the lease.
+		proposedClosedTimestamp hlc.Timestamp
+		trackedWrites           map[hlc.Timestamp]int
 		// Last index/term persisted to the raft log (not necessarily
 		// committed). Note that lastTerm may be 0 (and thus invalid) even when
 		// lastIndex is known, in which case the term will have to be retrieved
@@ -392,6 +413,14 @@ type Replica struct {
 		// contained RaftCommand, which we treat as immutable.
 		proposals         map[storagebase.CmdIDKey]*ProposalData
 		internalRaftGroup *raft.RawNode
+		// mergeComplete is non-nil while the replica is the right hand side of
+		// a merge in progress, i.e. while its range descriptor carries the
+		// deletion intent of the merge transaction, whose ID is mergeTxnID. It
+		// is closed once that transaction is known to have committed or
+		// aborted. Until then, all requests but those of the merge transaction
+		// wait for it to be closed. See maybeWatchForMerge.
+		mergeComplete chan struct{}
+		mergeTxnID    uuid.UUID
 		// The ID of the replica within the Raft group. May be 0 if the replica has
 		// been created from a preemptive snapshot (i.e. before being added to the
 		// Raft group). The replica ID will be non-zero whenever the replica is
@@ -634,6 +663,7 @@ func newReplica(rangeID roachpb.RangeID, store *Store) *Replica {
 	// Pass nil for the localityOracle because we intentionally don't track the
 	// origin locality of write load.
 	r.writeStats = newReplicaStats(store.Clock(), nil)
+	split.Init(&r.loadBasedSplitter, rand.Intn, r.splitByLoadQPSThreshold)
 
 	// Init rangeStr with the range ID.
 	r.rangeStr.store(0, &roachpb.RangeDescriptor{RangeID: rangeID})
@@ -1811,6 +1841,15 @@ func (r *Replica) Send(
 	if r.leaseholderStats != nil && ba.Header.GatewayNodeID != 0 {
 		r.leaseholderStats.record(ba.Header.GatewayNodeID)
 	}
+	if r.loadBasedSplitter.Record(r.store.Clock().PhysicalTime(), len(ba.Requests), func() roachpb.Span {
+		rSpan, err := keys.Range(ba)
+		if err != nil {
+			return roachpb.Span{}
+		}
+		return rSpan.AsRawSpanWithNoLocals()
+	}) && r.store.splitQueue != nil {
+		r.store.splitQueue.MaybeAdd(r, r.store.Clock().Now())
+	}
 
 	// Add the range log tag.
 	ctx = r.AnnotateCtx(ctx)
@@ -1832,24 +1871,37 @@ func (r *Replica) Send(
 		return nil, roachpb.NewError(err)
 	}
 
-	// Differentiate between admin, read-only and write.
 	var pErr *roachpb.Error
-	if useRaft {
-		log.Event(ctx, "read-write path")
-		br, pErr = r.executeWriteBatch(ctx, ba)
-	} else if isReadOnly {
-		log.Event(ctx, "read-only path")
-		br, pErr = r.executeReadOnlyBatch(ctx, ba)
-	} else if ba.IsAdmin() {
-		log.Event(ctx, "admin path")
-		br, pErr = r.executeAdminBatch(ctx, ba)
-	} else if len(ba.Requests) == 0 {
-		// empty batch; shouldn't happen (we could handle it, but it hints
-		// at someone doing weird things, and once we drop the key range
-		// from the header it won't be clear how to route those requests).
-		log.Fatalf(ctx, "empty batch")
-	} else {
-		log.Fatalf(ctx, "don't know how to handle command %s", ba)
+	for {
+		if err := r.maybeWaitForMerge(ctx, ba); err != nil {
+			return nil, roachpb.NewError(err)
+		}
+
+		// Differentiate between admin, read-only and write.
+		if useRaft {
+			log.Event(ctx, "read-write path")
+			br, pErr = r.executeWriteBatch(ctx, ba)
+		} else if isReadOnly {
+			log.Event(ctx, "read-only path")
+			br, pErr = r.executeReadOnlyBatch(ctx, ba)
+		} else if ba.IsAdmin() {
+			log.Event(ctx, "admin path")
+			br, pErr = r.executeAdminBatch(ctx, ba)
+		} else if len(ba.Requests) == 0 {
+			// empty batch; shouldn't happen (we could handle it, but it hints
+			// at someone doing weird things, and once we drop the key range
+			// from the header it won't be clear how to route those requests).
+			log.Fatalf(ctx, "empty batch")
+		} else {
+			log.Fatalf(ctx, "don't know how to handle command %s", ba)
+		}
+		if _, ok := pErr.GetDetail().(*roachpb.MergeInProgressError); ok {
+			// The range was frozen for a merge while the batch was waiting in
+			// the command queue. Retry it once the merge completes.
+			log.Event(ctx, "retrying after merge")
+			continue
+		}
+		break
 	}
 	if pErr != nil {
 		if _, ok := pErr.GetDetail().(*roachpb.RaftGroupDeletedError); ok {
@@ -2527,10 +2579,16 @@ func (r *Replica) executeAdminBatch(
 func (r *Replica) executeReadOnlyBatch(
 	ctx context.Context, ba roachpb.BatchRequest,
 ) (br *roachpb.BatchResponse, pErr *roachpb.Error) {
-	// If the read is consistent, the read requires the range lease.
+	// If the read is consistent, the read requires the range lease, unless
+	// its timestamp is closed, in which case it is served as a follower read.
+	var followerReadErr *roachpb.Error
 	if ba.ReadConsistency != roachpb.INCONSISTENT {
 		if _, pErr = r.redirectOnOrAcquireLease(ctx); pErr != nil {
-			return nil, pErr
+			// A range is only frozen for a merge under its lease.
+			if ba.IsSingleSubsumeRequest() || !r.canServeFollowerRead(ctx, ba, pErr) {
+				return nil, pErr
+			}
+			followerReadErr, pErr = pErr, nil
 		}
 	}
 
@@ -2546,6 +2604,15 @@ func (r *Replica) executeReadOnlyBatch(
 	if err != nil {
 		return nil, roachpb.NewError(err)
 	}
+	if ba.IsSingleSubsumeRequest() {
+		// The range is frozen once the writes evaluated before the
+		// SubsumeRequest, whose results may not have been waited for, have
+		// applied.
+		if err := r.waitForPendingProposals(ctx); err != nil {
+			endCmds.done(nil, roachpb.NewError(err), proposalNoRetry)
+			return nil, roachpb.NewError(err)
+		}
+	}
 
 	log.Event(ctx, "waiting for read lock")
 	r.readOnlyCmdMu.RLock()
@@ -2563,6 +2630,10 @@ func (r *Replica) executeReadOnlyBatch(
 		return nil, roachpb.NewError(err)
 	}
 
+	if pErr := r.checkMergeFreeze(&ba); pErr != nil {
+		return nil, pErr
+	}
+
 	rSpan, err := keys.Range(ba)
 	if err != nil {
 		return nil, roachpb.NewError(err)
@@ -2580,6 +2651,18 @@ func (r *Replica) executeReadOnlyBatch(
 	readOnly := r.store.Engine().NewReadOnly()
 	defer readOnly.Close()
 	br, result, pErr = evaluateBatch(ctx, storagebase.CmdIDKey(""), readOnly, rec, nil, ba)
+	if pErr == nil && ba.IsSingleSubsumeRequest() {
+		if err := r.freezeForMerge(ctx, ba.Txn.ID); err != nil {
+			br, pErr = nil, roachpb.NewError(err)
+		}
+	}
+	if followerReadErr != nil && pErr != nil {
+		if _, ok := pErr.GetDetail().(*roachpb.WriteIntentError); ok {
+			// Intents are resolved by the lease holder, so redirect the read
+			// there instead of resolving them from here.
+			return nil, followerReadErr
+		}
+	}
 
 	if intents := result.Local.DetachIntents(pErr != nil); len(intents) > 0 {
 		log.Eventf(ctx, "submitting %d intents to asynchronous processing", len(intents))
@@ -2733,13 +2816,29 @@ func (r *Replica) tryExecuteWriteBatch(
 		lease = status.Lease
 	}
 
+	// The range may have been frozen for a merge while the batch was waiting
+	// in the command queue, or when this replica acquired the lease.
+	if mErr := r.checkMergeFreeze(&ba); mErr != nil {
+		return nil, mErr, proposalNoRetry
+	}
+
 	// Examine the read and write timestamp caches for preceding
 	// commands which require this command to move its timestamp
 	// forward. Or, in the case of a transactional write, the txn
 	// timestamp and possible write-too-old bool.
-	if bumped, pErr := r.applyTimestampCache(ctx, &ba); pErr != nil {
+	bumped, pErr := r.applyTimestampCache(ctx, &ba)
+	if pErr != nil {
 		return nil, pErr, proposalNoRetry
-	} else if bumped {
+	}
+	if !ba.IsLeaseRequest() {
+		// Move the write above the timestamps closed by the lease holder,
+		// and prevent new ones from being closed at or above it until it
+		// has applied.
+		bumpedAboveClosed, untrack := r.trackWriteAboveClosedTimestamp(&ba)
+		defer untrack()
+		bumped = bumped || bumpedAboveClosed
+	}
+	if bumped {
 		// If we bump the transaction's timestamp, we must absolutely
 		// tell the client in a response transaction (for otherwise it
 		// doesn't know about the incremented timestamp). Response
@@ -2865,6 +2964,14 @@ func (r *Replica) requestToProposal(
 		ReplicatedEvalResult: result.Replicated,
 		WriteBatch:           result.WriteBatch,
 	}
+	if result.WriteBatch != nil {
+		logicalOpLog, err := r.logicalOpLog(result.WriteBatch.Data)
+		if err != nil {
+			return proposal, roachpb.NewError(
+				errors.Wrap(err, "unable to translate WriteBatch into logical operations"))
+		}
+		proposal.command.LogicalOpLog = logicalOpLog
+	}
 
 	if r.store.TestingKnobs().EvalKnobs.TestingEvalFilter != nil {
 		// For backwards compatibility, tests that use TestingEvalFilter
@@ -2950,6 +3057,7 @@ func (r *Replica) insertProposalLocked(
 	}
 	if !proposal.Request.IsLeaseRequest() {
 		r.mu.lastAssignedLeaseIndex++
+		proposal.command.ClosedTimestamp = r.closeTimestampLocked()
 	}
 	proposal.command.MaxLeaseIndex = r.mu.lastAssignedLeaseIndex
 	proposal.command.ProposerReplica = proposerReplica
@@ -3147,6 +3255,22 @@ func (r *Replica) propose(
 		r.mu.Unlock()
 		return ok
 	}
+
+	// If the batch asked for asynchronous consensus and can use it, don't
+	// wait for the proposal to apply: acknowledge it right away with the
+	// result of its evaluation. The command is still applied as usual, and
+	// the client is responsible for proving that it succeeded before relying
+	// on it (see QueryIntent). Its context is detached from the client's,
+	// which is going away before the command applies.
+	if ba.AsyncConsensus && ba.IsPipelinable() &&
+		proposal.Local.Err == nil && proposal.Local.Reply != nil {
+		proposal.ctx = r.AnnotateCtx(context.TODO())
+		reply := protoutil.Clone(proposal.Local.Reply).(*roachpb.BatchResponse)
+		ch := make(chan proposalResult, 1)
+		ch <- proposalResult{Reply: reply}
+		close(ch)
+		return ch, func() bool { return false }, undoQuotaAcquisition, nil
+	}
 	return proposal.doneCh, tryAbandon, undoQuotaAcquisition, nil
 }
 
@@ -4646,6 +4770,29 @@ func (r *Replica) processRaftCommand(
 			}
 		}
 
+		if forcedErr == nil {
+			// The command applied, along with all the writes below its closed
+			// timestamp, so reads at or below it can now be served by any
+			// replica.
+			r.mu.Lock()
+			r.mu.closedTimestamp.Forward(raftCmd.ClosedTimestamp)
+			r.mu.Unlock()
+
+			// Publish the values the command committed, and the closed
+			// timestamp, to the rangefeeds registered on the replica. Splits
+			// and merges change the span of the range, so they disconnect them.
+			if writeBatch != nil {
+				r.handleLogicalOpLogRaftMuLocked(raftCmd.LogicalOpLog)
+			}
+			r.handleClosedTimestampRaftMuLocked(raftCmd.ClosedTimestamp)
+			if raftCmd.ReplicatedEvalResult.Split != nil {
+				r.disconnectRangefeedWithReasonRaftMuLocked(roachpb.RangeFeedRetryError_REASON_RANGE_SPLIT)
+			}
+			if raftCmd.ReplicatedEvalResult.Merge != nil {
+				r.disconnectRangefeedWithReasonRaftMuLocked(roachpb.RangeFeedRetryError_REASON_RANGE_MERGED)
+			}
+		}
+
 		if filter := r.store.cfg.TestingKnobs.TestingPostApplyFilter; pErr == nil && filter != nil {
 			pErr = filter(storagebase.ApplyFilterArgs{
 				CmdID:                idKey,
@@ -5022,6 +5169,7 @@ func (r *Replica) evaluateTxnWriteBatch(
 	ctx context.Context, idKey storagebase.CmdIDKey, ba roachpb.BatchRequest, spans *spanset.SpanSet,
 ) (engine.Batch, enginepb.MVCCStats, *roachpb.BatchResponse, result.Result, *roachpb.Error) {
 	ms := enginepb.MVCCStats{}
+	ba = maybeStripInFlightWrites(ba)
 	// If not transactional or there are indications that the batch's txn will
 	// require restart or retry, execute as normal.
 	if isOnePhaseCommit(ba, r.store.TestingKnobs()) {
@@ -5125,6 +5273,45 @@ func isOnePhaseCommit(ba roachpb.BatchRequest, knobs *StoreTestingKnobs) bool {
 	return !knobs.DisableOptional1PC || etArg.Require1PC
 }
 
+// maybeStripInFlightWrites removes the in-flight writes from the
+// EndTransaction request of a batch staging a parallel commit if each of
+// them is either written or queried by another request in the same
+// batch. The batch is evaluated atomically, so if it succeeds, none of
+// the writes are in flight any more and the transaction can be committed
+// outright instead of being moved to the STAGING state.
+func maybeStripInFlightWrites(ba roachpb.BatchRequest) roachpb.BatchRequest {
+	l := len(ba.Requests) - 1
+	if l < 0 {
+		return ba
+	}
+	etArg, ok := ba.Requests[l].GetInner().(*roachpb.EndTransactionRequest)
+	if !ok || len(etArg.InFlightWrites) == 0 {
+		return ba
+	}
+	inBatch := make(map[string]struct{}, l)
+	for _, union := range ba.Requests[:l] {
+		args := union.GetInner()
+		switch args.(type) {
+		case *roachpb.QueryIntentRequest:
+		default:
+			if !roachpb.IsTransactionWrite(args) || roachpb.IsRange(args) {
+				continue
+			}
+		}
+		inBatch[string(args.Header().Key)] = struct{}{}
+	}
+	for _, w := range etArg.InFlightWrites {
+		if _, ok := inBatch[string(w.Key)]; !ok {
+			return ba
+		}
+	}
+	etCopy := *etArg
+	etCopy.InFlightWrites = nil
+	ba.Requests = append([]roachpb.RequestUnion(nil), ba.Requests...)
+	ba.Requests[l].MustSetInner(&etCopy)
+	return ba
+}
+
 // optimizePuts searches for contiguous runs of Put & CPut commands in
 // the supplied request union. Any run which exceeds a minimum length
 // threshold employs a full order iterator to determine whether the
@@ -5883,6 +6070,12 @@ func (r *Replica) QueriesPerSecond() float64 {
 	return qps
 }
 
+// GetSplitQPS returns the range's requests per second as last computed by
+// its load based splitter.
+func (r *Replica) GetSplitQPS() float64 {
+	return r.loadBasedSplitter.LastQPS(r.store.Clock().PhysicalTime())
+}
+
 // WritesPerSecond returns the range's average keys written per second.
 func (r *Replica) WritesPerSecond() float64 {
 	wps, _ := r.writeStats.avgQPS()
@@ -0,0 +1,232 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/batcheval/result"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// Closed timestamps allow the replicas which don't hold the lease of a range
+// to serve consistent reads of sufficiently old data (follower reads).
+//
+// The lease holder attaches a closed timestamp to each command it proposes,
+// promising that no command proposed after it writes at or below that
+// timestamp. It closes timestamps trailing the current time by
+// kv.closed_timestamp.target_duration, but always below the timestamps of
+// the writes being evaluated or proposed, and pushes the writes which come
+// later above the timestamps it closed, as the timestamp cache does for
+// reads. Once a command applies, its closed timestamp is known to every
+// replica which applied it, along with all the writes at or below it, so the
+// replica can serve reads at or below it.
+//
+// A range receiving no writes proposes no commands, so its lease holder
+// periodically proposes empty commands for the sole purpose of closing new
+// timestamps.
+//
+// A new lease holder never writes at or below the timestamps closed by its
+// predecessors since its lease, and thus its timestamp cache, starts after
+// them.
+
+// writeTimestamp returns the timestamp at which the batch writes.
+func writeTimestamp(ba roachpb.BatchRequest) hlc.Timestamp {
+	if ba.Txn != nil {
+		return ba.Txn.Timestamp
+	}
+	return ba.Timestamp
+}
+
+// trackWriteAboveClosedTimestamp forwards the timestamp of the write batch
+// above the timestamps closed so far, and tracks it so that no timestamp at or
+// above it is closed until the returned function is called, once the write
+// has been proposed and applied. It returns whether the timestamp was bumped.
+func (r *Replica) trackWriteAboveClosedTimestamp(ba *roachpb.BatchRequest) (bool, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	closed := r.mu.proposedClosedTimestamp
+	closed.Forward(r.mu.closedTimestamp)
+	var bumped bool
+	if ba.Txn != nil {
+		if !closed.Less(ba.Txn.Timestamp) {
+			txn := ba.Txn.Clone()
+			bumped = txn.Timestamp.Forward(closed.Next())
+			ba.Txn = &txn
+		}
+	} else {
+		bumped = ba.Timestamp.Forward(closed.Next())
+	}
+
+	ts := writeTimestamp(*ba)
+	if r.mu.trackedWrites == nil {
+		r.mu.trackedWrites = make(map[hlc.Timestamp]int)
+	}
+	r.mu.trackedWrites[ts]++
+	return bumped, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.mu.trackedWrites[ts]--; r.mu.trackedWrites[ts] == 0 {
+			delete(r.mu.trackedWrites, ts)
+		}
+	}
+}
+
+// closeTimestampLocked returns the closed timestamp to attach to a command
+// proposed by the lease holder. It trails the current time by the target
+// duration, but stays below the timestamps of the tracked writes and of the
+// writes proposed but not yet applied.
+func (r *Replica) closeTimestampLocked() hlc.Timestamp {
+	closed := r.mu.proposedClosedTimestamp
+	closed.Forward(r.mu.closedTimestamp)
+	sv := &r.store.cfg.Settings.SV
+	targetDuration := storagebase.ClosedTimestampTargetDuration.Get(sv)
+	if !closedTimestampsEnabled(sv) || targetDuration == 0 {
+		return closed
+	}
+	target := r.store.Clock().Now().Add(-targetDuration.Nanoseconds(), 0)
+	// Close timestamps in increments of a tenth of the target duration so as
+	// not to go through the writes in flight for every proposal.
+	if !closed.Add(targetDuration.Nanoseconds()/10, 0).Less(target) {
+		return closed
+	}
+	for ts := range r.mu.trackedWrites {
+		if !target.Less(ts) {
+			target = ts.Prev()
+		}
+	}
+	for _, p := range r.mu.proposals {
+		if p.Request.IsLeaseRequest() || len(p.Request.Requests) == 0 {
+			// Lease requests and the empty commands proposed by
+			// maybeCloseTimestamp don't write.
+			continue
+		}
+		if ts := writeTimestamp(*p.Request); !target.Less(ts) {
+			target = ts.Prev()
+		}
+	}
+	closed.Forward(target)
+	r.mu.proposedClosedTimestamp = closed
+	return closed
+}
+
+// maybeCloseTimestamp proposes an empty command to close a new timestamp if
+// the replica holds the lease but hasn't recently closed one, which is the
+// case when the range receives no writes.
+func (r *Replica) maybeCloseTimestamp(ctx context.Context) {
+	targetDuration := storagebase.ClosedTimestampTargetDuration.Get(&r.store.cfg.Settings.SV)
+	if targetDuration == 0 {
+		return
+	}
+	now := r.store.Clock().Now()
+	desc := r.Desc()
+
+	r.raftMu.Lock()
+	defer r.raftMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.mu.destroyStatus.IsAlive() || !r.ownsValidLeaseRLocked(now) {
+		return
+	}
+	closed := r.mu.proposedClosedTimestamp
+	closed.Forward(r.mu.closedTimestamp)
+	if now.Add(-targetDuration.Nanoseconds()*3/2, 0).Less(closed) {
+		return
+	}
+	repDesc, err := r.getReplicaDescriptorRLocked()
+	if err != nil {
+		return
+	}
+	proposal := &ProposalData{
+		ctx:     r.AnnotateCtx(context.TODO()),
+		idKey:   makeIDKey(),
+		doneCh:  make(chan proposalResult, 1),
+		Request: &roachpb.BatchRequest{},
+		Local:   &result.LocalResult{Reply: &roachpb.BatchResponse{}},
+		command: storagebase.RaftCommand{
+			ReplicatedEvalResult: storagebase.ReplicatedEvalResult{
+				Timestamp: now,
+				StartKey:  desc.StartKey,
+				EndKey:    desc.EndKey,
+			},
+		},
+	}
+	r.insertProposalLocked(proposal, repDesc, *r.mu.state.Lease)
+	if err := r.submitProposalLocked(proposal); err != nil {
+		delete(r.mu.proposals, proposal.idKey)
+		log.VEventf(ctx, 2, "unable to propose closed timestamp: %s", err)
+	}
+}
+
+// canServeFollowerRead returns whether the replica, which returned the given
+// error when asked for the lease, can nevertheless serve the read-only batch
+// because its timestamp is closed.
+func (r *Replica) canServeFollowerRead(
+	ctx context.Context, ba roachpb.BatchRequest, pErr *roachpb.Error,
+) bool {
+	if _, ok := pErr.GetDetail().(*roachpb.NotLeaseHolderError); !ok {
+		return false
+	}
+	if !storagebase.FollowerReadsEnabled.Get(&r.store.cfg.Settings.SV) ||
+		!storagebase.IsFollowerReadBatch(ba) {
+		return false
+	}
+	ts := storagebase.FollowerReadTimestamp(ba)
+	r.mu.RLock()
+	closed := r.mu.closedTimestamp
+	r.mu.RUnlock()
+	if closed.Less(ts) {
+		log.Eventf(ctx, "can't serve follower read at %s above closed timestamp %s", ts, closed)
+		return false
+	}
+	log.Event(ctx, "serving via follower read")
+	return true
+}
+
+// startClosedTimestampLoop periodically has the replicas of the store which
+// hold their lease close a new timestamp if they haven't recently, as long as
+// follower reads or rangefeeds are enabled.
+func (s *Store) startClosedTimestampLoop(ctx context.Context) {
+	s.stopper.RunWorker(ctx, func(ctx context.Context) {
+		timer := timeutil.NewTimer()
+		defer timer.Stop()
+		for {
+			interval := storagebase.ClosedTimestampTargetDuration.Get(&s.cfg.Settings.SV) / 2
+			if interval == 0 {
+				interval = time.Minute
+			}
+			timer.Reset(interval)
+			select {
+			case <-timer.C:
+				timer.Read = true
+				if !closedTimestampsEnabled(&s.cfg.Settings.SV) {
+					continue
+				}
+				newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
+					r.maybeCloseTimestamp(ctx)
+					return true
+				})
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
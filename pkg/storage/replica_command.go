@@ -90,6 +90,8 @@ var commands = map[roachpb.Method]Command{
 	roachpb.GC:                 {DeclareKeys: declareKeysGC, Eval: batcheval.GC},
 	roachpb.PushTxn:            {DeclareKeys: declareKeysPushTransaction, Eval: batcheval.PushTxn},
 	roachpb.QueryTxn:           {DeclareKeys: batcheval.DefaultDeclareKeys, Eval: batcheval.QueryTxn},
+	roachpb.QueryIntent:        {DeclareKeys: batcheval.DefaultDeclareKeys, Eval: batcheval.QueryIntent},
+	roachpb.RecoverTxn:         {DeclareKeys: declareKeysRecoverTransaction, Eval: batcheval.RecoverTxn},
 	roachpb.ResolveIntent:      {DeclareKeys: declareKeysResolveIntent, Eval: batcheval.ResolveIntent},
 	roachpb.ResolveIntentRange: {DeclareKeys: declareKeysResolveIntentRange, Eval: batcheval.ResolveIntentRange},
 	roachpb.Merge:              {DeclareKeys: batcheval.DefaultDeclareKeys, Eval: batcheval.Merge},
@@ -97,6 +99,8 @@ var commands = map[roachpb.Method]Command{
 	roachpb.RequestLease:       {DeclareKeys: declareKeysRequestLease, Eval: batcheval.RequestLease},
 	roachpb.TransferLease:      {DeclareKeys: declareKeysRequestLease, Eval: batcheval.TransferLease},
 	roachpb.LeaseInfo:          {DeclareKeys: declareKeysLeaseInfo, Eval: batcheval.LeaseInfo},
+	roachpb.RangeStats:         {DeclareKeys: declareKeysRangeStats, Eval: batcheval.RangeStats},
+	roachpb.Subsume:            {DeclareKeys: declareKeysSubsume, Eval: batcheval.Subsume},
 	roachpb.ComputeChecksum:    {DeclareKeys: batcheval.DefaultDeclareKeys, Eval: batcheval.ComputeChecksum},
 	roachpb.WriteBatch:         writeBatchCmd,
 	roachpb.Export:             exportCmd,
@@ -388,6 +392,22 @@ func evalEndTransaction(
 			args.IntentSpans, reply.Txn), args, true, /* alwaysReturn */
 		), roachpb.NewTransactionAbortedError()
 
+	case roachpb.STAGING:
+		if h.Txn.Epoch > reply.Txn.Epoch {
+			// The transaction restarted after staging its commit, so the
+			// STAGING record is stale and can't ever be implicitly committed.
+			reply.Txn.Status = roachpb.PENDING
+			reply.Txn.InFlightWrites = nil
+		} else if h.Txn.Epoch == reply.Txn.Epoch && !args.Commit {
+			// The transaction may be implicitly committed, in which case
+			// aborting it would lose its writes. It has to be recovered
+			// instead, which happens when its intents are encountered.
+			return result.Result{}, roachpb.NewTransactionStatusError(
+				"cannot abort a STAGING transaction",
+			)
+		}
+		fallthrough
+
 	case roachpb.PENDING:
 		if h.Txn.Epoch < reply.Txn.Epoch {
 			// TODO(tschottdorf): this leaves the Txn record (and more
@@ -454,7 +474,29 @@ func evalEndTransaction(
 				"transaction deadline exceeded")
 		}
 
+		// If some of the transaction's writes are still in flight, stage
+		// the commit instead. The transaction is committed once all of
+		// them have succeeded, which its coordinator (or, failing that,
+		// anyone who encounters its intents) records by moving it out of
+		// the STAGING state. Until then, its intents can't be resolved.
+		if len(args.InFlightWrites) > 0 {
+			if args.InternalCommitTrigger != nil {
+				return result.Result{}, roachpb.NewTransactionStatusError(
+					"cannot stage the commit of a transaction with a commit trigger")
+			}
+			reply.Txn.Status = roachpb.STAGING
+			reply.Txn.InFlightWrites = args.InFlightWrites
+			reply.Txn.Intents = args.IntentSpans
+			if err := engine.MVCCPutProto(ctx, batch, ms, key, hlc.Timestamp{}, nil /* txn */, reply.Txn); err != nil {
+				return result.Result{}, err
+			}
+			var pd result.Result
+			pd.Local.UpdatedTxn = reply.Txn
+			return pd, nil
+		}
+
 		reply.Txn.Status = roachpb.COMMITTED
+		reply.Txn.InFlightWrites = nil
 	} else {
 		reply.Txn.Status = roachpb.ABORTED
 	}
@@ -817,6 +859,13 @@ func declareKeysPushTransaction(
 	spans.Add(spanset.SpanReadWrite, roachpb.Span{Key: keys.AbortSpanKey(header.RangeID, pr.PusheeTxn.ID)})
 }
 
+func declareKeysRecoverTransaction(
+	_ roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *spanset.SpanSet,
+) {
+	rr := req.(*roachpb.RecoverTxnRequest)
+	spans.Add(spanset.SpanReadWrite, roachpb.Span{Key: keys.TransactionKey(rr.Txn.Key, rr.Txn.ID)})
+}
+
 func declareKeysResolveIntentCombined(
 	desc roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *spanset.SpanSet,
 ) {
@@ -1267,7 +1316,7 @@ func (r *Replica) AdminSplit(
 		return roachpb.AdminSplitResponse{}, roachpb.NewErrorf("cannot split range with no key provided")
 	}
 	for retryable := retry.StartWithCtx(ctx, base.DefaultRetryOptions()); retryable.Next(); {
-		reply, _, pErr := r.adminSplitWithDescriptor(ctx, args, r.Desc())
+		reply, _, pErr := r.adminSplitWithDescriptor(ctx, args, r.Desc(), ReasonAdminRequest, "")
 		// On seeing a ConditionFailedError or an AmbiguousResultError, retry the
 		// command with the updated descriptor.
 		switch pErr.GetDetail().(type) {
@@ -1302,7 +1351,8 @@ func maybeDescriptorChangedError(desc *roachpb.RangeDescriptor, err error) (stri
 // descriptors, and updates the range addressing metadata. The handover of
 // responsibility for the reassigned key range is carried out seamlessly
 // through a split trigger carried out as part of the commit of that
-// transaction.
+// transaction. The reason and details of the split are recorded in the range
+// event log.
 //
 // The supplied RangeDescriptor is used as a form of optimistic lock. An
 // operation which might split a range should obtain a copy of the range's
@@ -1314,7 +1364,11 @@ func maybeDescriptorChangedError(desc *roachpb.RangeDescriptor, err error) (stri
 //
 // See the comment on splitTrigger for details on the complexities.
 func (r *Replica) adminSplitWithDescriptor(
-	ctx context.Context, args roachpb.AdminSplitRequest, desc *roachpb.RangeDescriptor,
+	ctx context.Context,
+	args roachpb.AdminSplitRequest,
+	desc *roachpb.RangeDescriptor,
+	reason RangeLogEventReason,
+	details string,
 ) (_ roachpb.AdminSplitResponse, validSplitKey bool, _ *roachpb.Error) {
 	var reply roachpb.AdminSplitResponse
 
@@ -1432,7 +1486,7 @@ func (r *Replica) adminSplitWithDescriptor(
 		// instead of a transaction; there's no reason this logging
 		// shouldn't be done in parallel via the batch with the updated
 		// range addressing.
-		if err := r.store.logSplit(ctx, txn, leftDesc, *rightDesc); err != nil {
+		if err := r.store.logSplit(ctx, txn, leftDesc, *rightDesc, reason, details); err != nil {
 			return err
 		}
 
@@ -1876,7 +1930,21 @@ func splitTrigger(
 // reassigned key range is carried out seamlessly through a merge
 // trigger carried out as part of the commit of that transaction.  A
 // merge requires that the two ranges are collocated on the same set
-// of replicas.
+// of replicas, and that this store holds the lease of both.
+//
+// The subsumed range is frozen by a SubsumeRequest, evaluated under its lease
+// once the merge transaction has deleted its range descriptor: it then
+// serves no requests but those of the merge transaction until the
+// transaction is known to have committed or aborted, whatever the outcome
+// of AdminMerge. Any replica of the subsumed range acquiring its lease while
+// the deletion intent is in place is frozen the same way. The transaction
+// commits only once all of the replicas of the subsumed range have applied
+// the commands up to the freeze, and the lease under which it was frozen is
+// still in effect. This ensures the subsumed range can't be written to after
+// its data has been handed to the subsuming range, that no replica of the
+// subsuming range misses any of these writes, and that all the reads the
+// subsumed range served are reflected in the timestamp cache of this store,
+// which the subsuming range keeps using.
 //
 // The supplied RangeDescriptor is used as a form of optimistic lock. See the
 // comment of "AdminSplit" for more information on this pattern.
@@ -1900,16 +1968,22 @@ func (r *Replica) AdminMerge(
 	// descriptor end key. We look up the descriptor here only to get
 	// the new end key and then repeat the lookup inside the
 	// transaction.
-	{
-		rightRng := r.store.LookupReplica(origLeftDesc.EndKey, nil)
-		if rightRng == nil {
-			return reply, roachpb.NewErrorf("ranges not collocated")
-		}
+	rightRng := r.store.LookupReplica(origLeftDesc.EndKey, nil)
+	if rightRng == nil {
+		return reply, roachpb.NewErrorf("ranges not collocated")
+	}
+	updatedLeftDesc.EndKey = rightRng.Desc().EndKey
 
-		updatedLeftDesc.EndKey = rightRng.Desc().EndKey
-		log.Infof(ctx, "initiating a merge of %s into this range", rightRng)
+	// The right hand side's lease must be held by this store, which holds the
+	// lease of the left hand side. Note that we can't return a
+	// NotLeaseHolderError, which would redirect the request for the left hand
+	// side.
+	if _, pErr := rightRng.redirectOnOrAcquireLease(ctx); pErr != nil {
+		return reply, roachpb.NewErrorf("leases not collocated: %s", pErr)
 	}
 
+	log.Infof(ctx, "initiating a merge of %s into this range", rightRng)
+
 	if err := r.store.DB().Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		log.Event(ctx, "merge closure begins")
 		txn.SetDebugName(mergeTxnName)
@@ -1957,6 +2031,30 @@ func (r *Replica) AdminMerge(
 		if err := mergeRangeAddressing(b, origLeftDesc, &updatedLeftDesc); err != nil {
 			return err
 		}
+		if err := txn.Run(ctx, b); err != nil {
+			return err
+		}
+
+		// All the writes of the transaction to the right hand side have now
+		// been proposed. Freeze it, and wait until its replicas have applied
+		// all of its commands before handing its data to the left hand side.
+		log.Event(ctx, "freezing RHS")
+		freeze, err := rightRng.subsume(ctx, txn)
+		if err != nil {
+			return err
+		}
+		log.Event(ctx, "waiting for RHS replicas to catch up")
+		if err := waitForApplication(
+			ctx, r.store.cfg.Transport, rightDesc, freeze.LeaseAppliedIndex,
+		); err != nil {
+			return err
+		}
+		if lease, _ := rightRng.GetLease(); !lease.Equivalent(freeze.Lease) ||
+			!rightRng.OwnsValidLease(r.store.Clock().Now()) {
+			return errors.Errorf("%s: lease changed during merge", rightRng)
+		}
+
+		b = txn.NewBatch()
 		// End the transaction manually instead of letting RunTransaction
 		// loop do it, in order to provide a merge trigger.
 		b.AddRawRequest(&roachpb.EndTransactionRequest{
@@ -1977,6 +2075,278 @@ func (r *Replica) AdminMerge(
 	return reply, nil
 }
 
+// subsume freezes the replica, which is the right hand side of the merge
+// performed by txn, by evaluating a SubsumeRequest under its lease. The
+// response carries the lease applied index which the replicas of the range
+// must reach before the merge commits, and the lease under which the range
+// was frozen. The range stays frozen until the merge transaction commits or
+// aborts, even if the merge fails or its outcome is ambiguous; see
+// maybeWatchForMerge.
+func (r *Replica) subsume(ctx context.Context, txn *client.Txn) (*roachpb.SubsumeResponse, error) {
+	var ba roachpb.BatchRequest
+	ba.RangeID = r.RangeID
+	ba.Txn = txn.Proto()
+	ba.Timestamp = ba.Txn.Timestamp
+	ba.Add(&roachpb.SubsumeRequest{
+		Span: roachpb.Span{Key: r.Desc().StartKey.AsRawKey()},
+	})
+	br, pErr := r.Send(ctx, ba)
+	if pErr != nil {
+		return nil, pErr.GoError()
+	}
+	return br.Responses[0].GetInner().(*roachpb.SubsumeResponse), nil
+}
+
+// freezeForMerge is called once the SubsumeRequest of the merge transaction
+// whose ID is txnID is evaluated, with all the keys of the range declared. It
+// freezes the replica, which is the right hand side of the merge, until the
+// merge transaction commits or aborts.
+func (r *Replica) freezeForMerge(ctx context.Context, txnID uuid.UUID) error {
+	if err := r.maybeWatchForMerge(ctx); err != nil {
+		return err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.mu.mergeComplete == nil || r.mu.mergeTxnID != txnID {
+		return errors.Errorf("%s: range descriptor not deleted by merge transaction %s", r, txnID.Short())
+	}
+	return nil
+}
+
+// mergeIntent returns the intent on the range descriptor of the replica if
+// it deletes the descriptor, which only the transaction merging the range
+// into its left hand neighbor does.
+func (r *Replica) mergeIntent(ctx context.Context) (*roachpb.Intent, error) {
+	descKey := keys.RangeDescriptorKey(r.Desc().StartKey)
+	_, intents, err := engine.MVCCGet(
+		ctx, r.store.Engine(), descKey, hlc.MaxTimestamp, false /* consistent */, nil, /* txn */
+	)
+	if err != nil || len(intents) == 0 {
+		return nil, err
+	}
+	intent := intents[0]
+	val, _, err := engine.MVCCGetAsTxn(ctx, r.store.Engine(), descKey, intent.Txn.Timestamp, intent.Txn)
+	if err != nil || val != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// maybeWatchForMerge freezes the replica if its range descriptor carries the
+// deletion intent of a merge transaction, until that transaction is known to
+// have committed or aborted. It is called by the SubsumeRequest of the merge,
+// and whenever the replica acquires the lease: the freeze follows the
+// replicated state of the range, so that no replica serves requests which
+// the merge could lose, whatever happens to the lease under which the
+// SubsumeRequest was evaluated or to the merge transaction's coordinator.
+func (r *Replica) maybeWatchForMerge(ctx context.Context) error {
+	intent, err := r.mergeIntent(ctx)
+	if err != nil || intent == nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mu.mergeComplete != nil {
+		// The merge is already being watched.
+		return nil
+	}
+	mergeComplete := make(chan struct{})
+	r.mu.mergeComplete = mergeComplete
+	r.mu.mergeTxnID = intent.Txn.ID
+
+	taskCtx := r.AnnotateCtx(context.Background())
+	if err := r.store.stopper.RunAsyncTask(taskCtx, "storage.Replica: watching for merge", func(ctx context.Context) {
+		r.watchForMerge(ctx, intent.Txn, mergeComplete)
+	}); err != nil {
+		// The server is shutting down. The replica stays frozen; the blocked
+		// requests return once the stopper quiesces.
+		log.Infof(ctx, "not watching for merge of %s: %s", r, err)
+	}
+	return nil
+}
+
+// watchForMerge waits for the merge transaction txn to commit or abort, by
+// pushing it with the minimum priority, which can't abort it while its
+// coordinator is alive. It then unblocks the requests blocked by the
+// mergeComplete channel. If the merge committed, the replica is marked as
+// destroyed first, so that these requests are redirected to the subsuming
+// range.
+func (r *Replica) watchForMerge(
+	ctx context.Context, txn enginepb.TxnMeta, mergeComplete chan struct{},
+) {
+	var committed, resolved bool
+	for re := retry.StartWithCtx(ctx, base.DefaultRetryOptions()); re.Next(); {
+		b := &client.Batch{}
+		b.AddRawRequest(&roachpb.PushTxnRequest{
+			Span: roachpb.Span{Key: txn.Key},
+			PusherTxn: roachpb.Transaction{
+				TxnMeta: enginepb.TxnMeta{Priority: roachpb.MinTxnPriority},
+			},
+			PusheeTxn: txn,
+			Now:       r.store.Clock().Now(),
+			PushType:  roachpb.PUSH_ABORT,
+		})
+		if err := r.store.DB().Run(ctx, b); err != nil {
+			log.Warningf(ctx, "error while pushing merge transaction %s: %s", txn.ID.Short(), err)
+			continue
+		}
+		status := b.RawResponse().Responses[0].GetInner().(*roachpb.PushTxnResponse).PusheeTxn.Status
+		if status == roachpb.PENDING {
+			continue
+		}
+		committed = status == roachpb.COMMITTED
+		if !committed {
+			// The transaction record of a committed merge may have been
+			// garbage collected, in which case the push reports the merge as
+			// aborted. The addressing records tell which range now ends at the
+			// end key of the replica.
+			var err error
+			if committed, err = r.mergeCommitted(ctx); err != nil {
+				log.Warningf(ctx, "error while looking up the outcome of merge transaction %s: %s",
+					txn.ID.Short(), err)
+				continue
+			}
+		}
+		resolved = true
+		break
+	}
+	if !resolved {
+		// The server is shutting down; the replica stays frozen.
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if committed && r.mu.destroyStatus.IsAlive() {
+		// The replica is destroyed once the subsuming replica on this store
+		// applies the merge; until then it must not serve requests either.
+		r.mu.destroyStatus.Set(roachpb.NewRangeNotFoundError(r.RangeID), destroyReasonMergePending)
+	}
+	r.mu.mergeComplete = nil
+	r.mu.mergeTxnID = uuid.UUID{}
+	close(mergeComplete)
+}
+
+// mergeCommitted returns whether the range of the replica has been merged
+// into its left hand neighbor, according to the meta2 record of its end key.
+func (r *Replica) mergeCommitted(ctx context.Context) (bool, error) {
+	desc := r.Desc()
+	var metaDesc roachpb.RangeDescriptor
+	if err := r.store.DB().GetProto(ctx, keys.RangeMetaKey(desc.EndKey).AsRawKey(), &metaDesc); err != nil {
+		return false, err
+	}
+	return metaDesc.RangeID != desc.RangeID, nil
+}
+
+// blockedByMergeRLocked returns the channel closed once the merge in
+// progress of which the replica is the right hand side completes, if the
+// batch must wait for it.
+//
+// Batches of the merge transaction are not blocked, nor are batches which
+// only push transactions and resolve intents, as the merge transaction may
+// itself have to wait for them, for instance to clean up the intents left
+// by one of its aborted attempts.
+func (r *Replica) blockedByMergeRLocked(ba *roachpb.BatchRequest) chan struct{} {
+	mergeComplete := r.mu.mergeComplete
+	if mergeComplete == nil || (ba.Txn != nil && ba.Txn.ID == r.mu.mergeTxnID) ||
+		ba.IsSingleSubsumeRequest() {
+		return nil
+	}
+	for _, union := range ba.Requests {
+		switch union.GetInner().(type) {
+		case *roachpb.PushTxnRequest, *roachpb.QueryTxnRequest,
+			*roachpb.ResolveIntentRequest, *roachpb.ResolveIntentRangeRequest:
+		default:
+			return mergeComplete
+		}
+	}
+	return nil
+}
+
+// checkMergeFreeze returns a MergeInProgressError if the batch, which has
+// been added to the command queue, must wait for the merge in progress of
+// which the replica is the right hand side. The range may have been frozen
+// while the batch was waiting in the command queue, behind the
+// SubsumeRequest; Send retries it once the merge completes.
+func (r *Replica) checkMergeFreeze(ba *roachpb.BatchRequest) *roachpb.Error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.blockedByMergeRLocked(ba) != nil {
+		return roachpb.NewError(&roachpb.MergeInProgressError{})
+	}
+	return nil
+}
+
+// maybeWaitForMerge blocks the batch until the merge in progress of which
+// the replica is the right hand side, if any, completes. It returns a
+// RangeNotFoundError if the merge succeeded.
+func (r *Replica) maybeWaitForMerge(ctx context.Context, ba roachpb.BatchRequest) error {
+	r.mu.RLock()
+	mergeComplete := r.blockedByMergeRLocked(&ba)
+	r.mu.RUnlock()
+	if mergeComplete == nil {
+		return nil
+	}
+	log.Event(ctx, "waiting for merge to complete")
+	select {
+	case <-mergeComplete:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.store.stopper.ShouldQuiesce():
+		return &roachpb.NodeUnavailableError{}
+	}
+	if _, err := r.IsDestroyed(); err != nil {
+		return roachpb.NewRangeNotFoundError(r.RangeID)
+	}
+	return nil
+}
+
+// waitForPendingProposals waits until the commands proposed by the replica
+// have all been applied or abandoned. It is called by a SubsumeRequest
+// holding all the keys of the range in the command queue, which no further
+// commands can be proposed for, so that the lease applied index it returns
+// covers the writes which were evaluated before it.
+func (r *Replica) waitForPendingProposals(ctx context.Context) error {
+	for re := retry.StartWithCtx(ctx, base.DefaultRetryOptions()); re.Next(); {
+		r.mu.RLock()
+		pending := len(r.mu.proposals)
+		r.mu.RUnlock()
+		if pending == 0 {
+			return nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Errorf("%s: proposals still pending", r)
+}
+
+// waitForApplication waits until each replica of the range has applied the
+// command with the given lease applied index.
+func waitForApplication(
+	ctx context.Context, transport *RaftTransport, desc roachpb.RangeDescriptor, leaseIndex uint64,
+) error {
+	for _, replica := range desc.Replicas {
+		addr, err := transport.resolver(replica.NodeID)
+		if err != nil {
+			return errors.Wrapf(err, "could not resolve node ID %d", replica.NodeID)
+		}
+		conn, err := transport.rpcContext.GRPCDial(addr.String())
+		if err != nil {
+			return errors.Wrapf(err, "could not dial node ID %d address %s", replica.NodeID, addr)
+		}
+		if _, err := NewPerReplicaClient(conn).WaitForApplication(ctx, &WaitForApplicationRequest{
+			StoreRequestHeader: StoreRequestHeader{NodeID: replica.NodeID, StoreID: replica.StoreID},
+			RangeID:            desc.RangeID,
+			LeaseIndex:         leaseIndex,
+		}); err != nil {
+			return errors.Wrapf(err, "replica %s did not catch up", replica)
+		}
+	}
+	return nil
+}
+
 // mergeTrigger is called on a successful commit of an AdminMerge
 // transaction. It recomputes stats for the receiving range.
 //
@@ -2522,6 +2892,23 @@ func declareKeysLeaseInfo(
 	spans.Add(spanset.SpanReadOnly, roachpb.Span{Key: keys.RangeLeaseKey(header.RangeID)})
 }
 
+func declareKeysRangeStats(
+	desc roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *spanset.SpanSet,
+) {
+	spans.Add(spanset.SpanReadOnly, roachpb.Span{Key: keys.RangeDescriptorKey(desc.StartKey)})
+	spans.Add(spanset.SpanReadOnly, roachpb.Span{Key: keys.RangeStatsKey(header.RangeID)})
+}
+
+// declareKeysSubsume declares all the keys of the range, so that no other
+// command is in flight when the range is frozen.
+func declareKeysSubsume(
+	desc roachpb.RangeDescriptor, header roachpb.Header, req roachpb.Request, spans *spanset.SpanSet,
+) {
+	for _, r := range makeReplicatedKeyRanges(&desc) {
+		spans.Add(spanset.SpanReadWrite, roachpb.Span{Key: r.start.Key, EndKey: r.end.Key})
+	}
+}
+
 // TestingRelocateRange relocates a given range to a given set of stores. The first
 // store in the slice becomes the new leaseholder.
 //
@@ -151,6 +151,20 @@ func (rec SpanSetReplicaEvalContext) GetTxnSpanGCThreshold() hlc.Timestamp {
 	return rec.i.GetTxnSpanGCThreshold()
 }
 
+// GetLeaseAppliedIndex returns the lease index of the last applied command.
+func (rec SpanSetReplicaEvalContext) GetLeaseAppliedIndex() uint64 {
+	rec.ss.AssertAllowed(spanset.SpanReadOnly,
+		roachpb.Span{Key: keys.LeaseAppliedIndexKey(rec.GetRangeID())},
+	)
+	return rec.i.GetLeaseAppliedIndex()
+}
+
+// GetSplitQPS returns the Replica's queries/s as measured for load based
+// splitting.
+func (rec SpanSetReplicaEvalContext) GetSplitQPS() float64 {
+	return rec.i.GetSplitQPS()
+}
+
 // String implements Stringer.
 func (rec SpanSetReplicaEvalContext) String() string {
 	return rec.i.String()
@@ -226,6 +226,15 @@ func (r *Replica) leasePostApply(ctx context.Context, newLease roachpb.Lease) {
 		}
 	}
 
+	if iAmTheLeaseHolder && !prevLease.Equivalent(newLease) {
+		// If the range is the right hand side of a merge in progress, it was
+		// frozen under a previous lease, possibly on another store, and must
+		// stay frozen under this one until the merge commits or aborts.
+		if err := r.maybeWatchForMerge(ctx); err != nil {
+			log.Fatalf(ctx, "failed to check for a merge in progress: %s", err)
+		}
+	}
+
 	// We're setting the new lease after we've updated the timestamp cache in
 	// order to avoid race conditions where a replica starts serving requests
 	// for a lease without first having taken into account requests served
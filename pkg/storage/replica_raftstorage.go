@@ -715,6 +715,10 @@ func (r *Replica) applySnapshot(
 		return nil
 	}
 
+	// The snapshot replaces the data of the replica without going through
+	// the commands the rangefeeds consume.
+	r.disconnectRangefeedWithReasonRaftMuLocked(roachpb.RangeFeedRetryError_REASON_RAFT_SNAPSHOT)
+
 	var stats struct {
 		clear   time.Time
 		batch   time.Time
@@ -0,0 +1,178 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/storage/rangefeed"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// RangefeedEnabled is a cluster setting that enables rangefeed requests.
+var RangefeedEnabled = settings.RegisterBoolSetting(
+	"kv.rangefeed.enabled",
+	"if set, rangefeed registration is enabled",
+	false,
+)
+
+// closedTimestampsEnabled returns whether the lease holders close timestamps,
+// which both follower reads and rangefeeds rely on.
+func closedTimestampsEnabled(sv *settings.Values) bool {
+	return storagebase.FollowerReadsEnabled.Get(sv) || RangefeedEnabled.Get(sv)
+}
+
+// logicalOpLog translates the write batch of a command being proposed into
+// the logical operations consumed by the rangefeeds registered on the
+// replicas applying it. It returns nil while rangefeeds are disabled.
+func (r *Replica) logicalOpLog(repr []byte) (*storagebase.LogicalOpLog, error) {
+	if !RangefeedEnabled.Get(&r.store.cfg.Settings.SV) {
+		return nil, nil
+	}
+	// The command queue guarantees that no command writing to the keys of the
+	// batch applies before it, so the engine reflects the state the batch
+	// will be applied to.
+	ops, err := rangefeed.LogicalOpsFromBatch(r.store.Engine(), repr)
+	if err != nil {
+		return nil, err
+	}
+	return &storagebase.LogicalOpLog{Ops: ops}, nil
+}
+
+// RangeFeed registers a rangefeed over the specified span. It sends updates
+// to the provided stream and returns with an optional error when the
+// rangefeed is complete.
+func (r *Replica) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream rangefeed.Stream,
+) *roachpb.Error {
+	ctx := r.AnnotateCtx(stream.Context())
+
+	if !RangefeedEnabled.Get(&r.store.cfg.Settings.SV) {
+		return roachpb.NewErrorf("rangefeeds require the kv.rangefeed.enabled setting")
+	}
+	rSpan, err := keys.SpanAddr(args.Span)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	if err := r.requestCanProceed(rSpan, args.Timestamp); err != nil {
+		return roachpb.NewError(err)
+	}
+
+	r.raftMu.Lock()
+	if _, err := r.IsDestroyed(); err != nil {
+		r.raftMu.Unlock()
+		return roachpb.NewError(err)
+	}
+	p, err := r.maybeInitRangefeedRaftMuLocked()
+	if err != nil {
+		r.raftMu.Unlock()
+		return roachpb.NewError(err)
+	}
+	reg := p.Register(args.Span, args.Timestamp, r.store.Engine().NewSnapshot(), stream)
+	r.raftMu.Unlock()
+
+	log.VEventf(ctx, 2, "registered rangefeed over %s at %s", args.Span, args.Timestamp)
+	pErr := reg.Run(ctx)
+
+	r.raftMu.Lock()
+	if p := r.raftMu.rangefeed; p != nil {
+		p.Unregister(reg)
+		if p.Len() == 0 {
+			r.raftMu.rangefeed = nil
+		}
+	}
+	r.raftMu.Unlock()
+	return pErr
+}
+
+// maybeInitRangefeedRaftMuLocked returns the rangefeed processor of the
+// replica, creating it if it doesn't exist yet.
+func (r *Replica) maybeInitRangefeedRaftMuLocked() (*rangefeed.Processor, error) {
+	if r.raftMu.rangefeed != nil {
+		return r.raftMu.rangefeed, nil
+	}
+	r.mu.RLock()
+	desc := r.mu.state.Desc
+	closedTS := r.mu.closedTimestamp
+	r.mu.RUnlock()
+
+	p := rangefeed.NewProcessor(roachpb.RSpan{Key: desc.StartKey, EndKey: desc.EndKey})
+	if err := p.Init(r.store.Engine()); err != nil {
+		return nil, errors.Wrap(err, "unable to scan intents")
+	}
+	p.ForwardClosedTS(closedTS)
+	r.raftMu.rangefeed = p
+	return p, nil
+}
+
+// handleLogicalOpLogRaftMuLocked passes the logical operations of an applied
+// command to the rangefeeds of the replica. A command which carries no logical
+// operations, because it was evaluated while rangefeeds were disabled,
+// disconnects them since their events would be incomplete.
+func (r *Replica) handleLogicalOpLogRaftMuLocked(ops *storagebase.LogicalOpLog) {
+	p := r.raftMu.rangefeed
+	if p == nil {
+		return
+	}
+	if ops == nil {
+		r.disconnectRangefeedWithReasonRaftMuLocked(
+			roachpb.RangeFeedRetryError_REASON_LOGICAL_OPS_MISSING,
+		)
+		return
+	}
+	p.ConsumeLogicalOps(ops.Ops...)
+}
+
+// handleClosedTimestampRaftMuLocked passes the closed timestamp of an applied
+// command to the rangefeeds of the replica.
+func (r *Replica) handleClosedTimestampRaftMuLocked(closedTS hlc.Timestamp) {
+	if p := r.raftMu.rangefeed; p != nil {
+		p.ForwardClosedTS(closedTS)
+	}
+}
+
+// disconnectRangefeedWithReasonRaftMuLocked disconnects the rangefeeds of the
+// replica, which can no longer serve them, with a retryable error.
+func (r *Replica) disconnectRangefeedWithReasonRaftMuLocked(
+	reason roachpb.RangeFeedRetryError_Reason,
+) {
+	p := r.raftMu.rangefeed
+	if p == nil {
+		return
+	}
+	p.Disconnect(roachpb.NewError(roachpb.NewRangeFeedRetryError(reason)))
+	r.raftMu.rangefeed = nil
+}
+
+// RangeFeed registers a rangefeed over the specified span on the replica of
+// the range addressed by the request.
+func (s *Store) RangeFeed(args *roachpb.RangeFeedRequest, stream rangefeed.Stream) *roachpb.Error {
+	if err := verifyKeys(args.Span.Key, args.Span.EndKey, true); err != nil {
+		return roachpb.NewError(err)
+	}
+	repl, err := s.GetReplica(args.RangeID)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	if !repl.IsInitialized() {
+		return roachpb.NewError(roachpb.NewRangeNotFoundError(args.RangeID))
+	}
+	return repl.RangeFeed(args, stream)
+}
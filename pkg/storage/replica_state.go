@@ -181,6 +181,13 @@ func (r *Replica) GetGCThreshold() hlc.Timestamp {
 	return *r.mu.state.GCThreshold
 }
 
+// GetLeaseAppliedIndex returns the lease index of the last applied command.
+func (r *Replica) GetLeaseAppliedIndex() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mu.state.LeaseAppliedIndex
+}
+
 // GetTxnSpanGCThreshold returns the time of the replica's last transaction span
 // GC.
 func (r *Replica) GetTxnSpanGCThreshold() hlc.Timestamp {
@@ -5163,6 +5163,148 @@ func TestPushTxnAlreadyCommittedOrAborted(t *testing.T) {
 	}
 }
 
+// TestReplicaQueryIntent verifies that QueryIntent finds an intent only if it
+// was written by the same epoch of the queried transaction, at or above the
+// queried sequence number and at or below the queried timestamp.
+func TestReplicaQueryIntent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	key := roachpb.Key("a")
+	txn := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.Clock())
+	txn.Sequence = 1
+	_, btH := beginTxnArgs(key, txn)
+	put := putArgs(key, []byte("value"))
+	if _, pErr := maybeWrapWithBeginTransaction(context.Background(), tc.Sender(), btH, &put); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	testCases := []struct {
+		name  string
+		mod   func(*enginepb.TxnMeta)
+		found bool
+	}{
+		{"same txn", func(*enginepb.TxnMeta) {}, true},
+		{"lower sequence", func(m *enginepb.TxnMeta) { m.Sequence-- }, true},
+		{"higher timestamp", func(m *enginepb.TxnMeta) { m.Timestamp = m.Timestamp.Next() }, true},
+		{"higher sequence", func(m *enginepb.TxnMeta) { m.Sequence++ }, false},
+		{"higher epoch", func(m *enginepb.TxnMeta) { m.Epoch++ }, false},
+		{"lower timestamp", func(m *enginepb.TxnMeta) { m.Timestamp = m.Timestamp.Prev() }, false},
+		{"other txn", func(m *enginepb.TxnMeta) { m.ID = uuid.MakeV4() }, false},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			testutils.RunTrueAndFalse(t, "errorIfMissing", func(t *testing.T, errorIfMissing bool) {
+				meta := txn.TxnMeta
+				test.mod(&meta)
+				qi := roachpb.QueryIntentRequest{
+					Span:           roachpb.Span{Key: key},
+					Txn:            meta,
+					ErrorIfMissing: errorIfMissing,
+				}
+				resp, pErr := tc.SendWrapped(&qi)
+				if !test.found && errorIfMissing {
+					tErr, ok := pErr.GetDetail().(*roachpb.TransactionRetryError)
+					if !ok || tErr.Reason != roachpb.RETRY_ASYNC_WRITE_FAILURE {
+						t.Fatalf("expected async write failure, found %v", pErr)
+					}
+					return
+				}
+				if pErr != nil {
+					t.Fatal(pErr)
+				}
+				if found := resp.(*roachpb.QueryIntentResponse).FoundIntent; found != test.found {
+					t.Errorf("expected FoundIntent=%t, found %t", test.found, found)
+				}
+			})
+		})
+	}
+}
+
+// TestEndTransactionParallelCommit verifies that committing a transaction
+// with in-flight writes stages its record, that pushers run into the STAGING
+// record and are asked to recover it, and that recovery finalizes it.
+func TestEndTransactionParallelCommit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer setTxnAutoGC(false)()
+	tc := testContext{}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.Start(t, stopper)
+
+	for i, implicitlyCommitted := range []bool{true, false} {
+		key := roachpb.Key(fmt.Sprintf("key-%d", i))
+		pusher := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.Clock())
+		pushee := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.Clock())
+		pusher.Priority = roachpb.MaxTxnPriority
+		pushee.Priority = roachpb.MinTxnPriority
+
+		_, btH := beginTxnArgs(key, pushee)
+		put := putArgs(key, key)
+		if _, pErr := maybeWrapWithBeginTransaction(context.Background(), tc.Sender(), btH, &put); pErr != nil {
+			t.Fatal(pErr)
+		}
+		etArgs, h := endTxnArgs(pushee, true /* commit */)
+		etArgs.IntentSpans = []roachpb.Span{{Key: key}}
+		etArgs.InFlightWrites = []roachpb.SequencedWrite{{Key: key, Sequence: pushee.Sequence}}
+		pushee.Sequence++
+		if _, pErr := tc.SendWrappedWith(h, &etArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+
+		// The intent isn't resolved while the record is STAGING.
+		meta := pushee.TxnMeta
+		meta.Sequence = etArgs.InFlightWrites[0].Sequence
+		if _, pErr := tc.SendWrapped(&roachpb.QueryIntentRequest{
+			Span:           roachpb.Span{Key: key},
+			Txn:            meta,
+			ErrorIfMissing: true,
+		}); pErr != nil {
+			t.Fatal(pErr)
+		}
+
+		// A pusher can't abort the staged transaction, but has to recover it.
+		pushArgs := pushTxnArgs(pusher, pushee, roachpb.PUSH_ABORT)
+		_, pErr := tc.SendWrapped(&pushArgs)
+		iErr, ok := pErr.GetDetail().(*roachpb.IndeterminateCommitError)
+		if !ok {
+			t.Fatalf("expected IndeterminateCommitError, found %v", pErr)
+		}
+		if status := iErr.StagingTxn.Status; status != roachpb.STAGING {
+			t.Fatalf("expected STAGING transaction, found %s", status)
+		}
+
+		recoverArgs := roachpb.RecoverTxnRequest{
+			Span:                roachpb.Span{Key: key},
+			Txn:                 iErr.StagingTxn,
+			ImplicitlyCommitted: implicitlyCommitted,
+		}
+		resp, pErr := tc.SendWrapped(&recoverArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		expStatus := roachpb.ABORTED
+		if implicitlyCommitted {
+			expStatus = roachpb.COMMITTED
+		}
+		if status := resp.(*roachpb.RecoverTxnResponse).RecoveredTxn.Status; status != expStatus {
+			t.Fatalf("expected %s transaction, found %s", expStatus, status)
+		}
+
+		// The push now finds the finalized transaction.
+		resp, pErr = tc.SendWrapped(&pushArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		if status := resp.(*roachpb.PushTxnResponse).PusheeTxn.Status; status != expStatus {
+			t.Errorf("expected push to return %s transaction, found %s", expStatus, status)
+		}
+	}
+}
+
 // TestPushTxnUpgradeExistingTxn verifies that pushing
 // a transaction record with a new epoch upgrades the pushee's
 // epoch and timestamp if greater. In all test cases, the
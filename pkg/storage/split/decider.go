@@ -0,0 +1,129 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package split implements the decision of splitting ranges based on the
+// load they receive.
+package split
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// A Decider collects measurements about the load of a range and decides
+// whether and where it should be split.
+//
+// Once a second, the Decider computes the number of requests per second
+// received by the range. When it exceeds the threshold, the Decider starts
+// sampling the keys of the requests with a Finder, which suggests a split key
+// once it has sampled them for long enough. The sampling stops whenever the
+// number of requests per second falls back below the threshold.
+type Decider struct {
+	intn         func(n int) int // for reservoir sampling
+	qpsThreshold func() float64  // requests per second above which to split
+
+	mu struct {
+		syncutil.Mutex
+
+		lastQPSRollover time.Time // most recent time the QPS was computed
+		count           int64     // number of requests since lastQPSRollover
+		lastQPS         float64   // last computed QPS
+
+		splitFinder *Finder // non-nil while sampling requests
+	}
+}
+
+// Init initializes a Decider, which is assumed to be zero. The intn function
+// returns a random integer in [0,n), and the qpsThreshold function returns
+// the number of requests per second above which to split the range.
+func Init(d *Decider, intn func(n int) int, qpsThreshold func() float64) {
+	d.intn = intn
+	d.qpsThreshold = qpsThreshold
+}
+
+// Record notifies the Decider that n requests are being carried out on the
+// span returned by the supplied function. The function is only called when
+// the Decider samples the keys of the requests.
+//
+// Record returns true when the Decider has a split key to suggest, which can
+// then be retrieved with MaybeSplitKey. It does so at most once a second.
+func (d *Decider) Record(now time.Time, n int, span func() roachpb.Span) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.recordLocked(now, n, span)
+}
+
+func (d *Decider) recordLocked(now time.Time, n int, span func() roachpb.Span) bool {
+	d.mu.count += int64(n)
+
+	if d.mu.lastQPSRollover.IsZero() {
+		d.mu.lastQPSRollover = now
+	}
+	var rolledOver bool
+	if elapsed := now.Sub(d.mu.lastQPSRollover); elapsed >= time.Second {
+		rolledOver = true
+		d.mu.lastQPS = float64(d.mu.count) / elapsed.Seconds()
+		d.mu.count = 0
+		d.mu.lastQPSRollover = now
+		if d.mu.lastQPS >= d.qpsThreshold() {
+			if d.mu.splitFinder == nil {
+				d.mu.splitFinder = NewFinder(now)
+			}
+		} else {
+			d.mu.splitFinder = nil
+		}
+	}
+
+	if d.mu.splitFinder == nil {
+		return false
+	}
+	if n > 0 {
+		d.mu.splitFinder.Record(span(), d.intn)
+	}
+	return rolledOver && d.mu.splitFinder.Ready(now) && d.mu.splitFinder.Key() != nil
+}
+
+// LastQPS returns the most recently computed number of requests per second
+// received by the range.
+func (d *Decider) LastQPS(now time.Time) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recordLocked(now, 0, nil)
+	return d.mu.lastQPS
+}
+
+// MaybeSplitKey returns the key at which the range should be split to
+// balance its load, or nil if it shouldn't be split.
+func (d *Decider) MaybeSplitKey(now time.Time) roachpb.Key {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recordLocked(now, 0, nil)
+	if d.mu.splitFinder == nil || !d.mu.splitFinder.Ready(now) {
+		return nil
+	}
+	return d.mu.splitFinder.Key()
+}
+
+// Reset forgets the measurements of the Decider, for instance after the range
+// has been split.
+func (d *Decider) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.lastQPSRollover = time.Time{}
+	d.mu.count = 0
+	d.mu.lastQPS = 0
+	d.mu.splitFinder = nil
+}
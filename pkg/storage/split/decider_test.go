@@ -0,0 +1,95 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package split
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestDecider verifies that the Decider samples requests only while the
+// range receives more requests per second than the threshold, and suggests
+// a split key after sampling them for long enough.
+func TestDecider(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	intn := rand.New(rand.NewSource(1)).Intn
+
+	var d Decider
+	Init(&d, intn, func() float64 { return 100 })
+
+	start := time.Unix(1000, 0)
+	ms := func(i int) time.Time {
+		return start.Add(time.Duration(i) * time.Millisecond)
+	}
+	// record runs qps requests per second on random keys for the given
+	// number of seconds, starting at the given number of milliseconds, and
+	// reports whether the Decider suggested a split.
+	record := func(startMS, seconds, qps int) (suggested bool) {
+		for i := 0; i < seconds*qps; i++ {
+			now := ms(startMS + i*1000/qps)
+			if d.Record(now, 1, func() roachpb.Span {
+				return roachpb.Span{Key: keyN(intn(10000))}
+			}) {
+				suggested = true
+			}
+		}
+		return suggested
+	}
+
+	// Below the threshold.
+	if record(0, 20, 50) {
+		t.Fatal("unexpected split suggestion below the threshold")
+	}
+	if d.mu.splitFinder != nil {
+		t.Fatal("unexpected sampling below the threshold")
+	}
+	if qps := d.LastQPS(ms(20000)); qps < 40 || qps > 60 {
+		t.Fatalf("expected about 50 qps, got %f", qps)
+	}
+
+	// Above the threshold, but not for long enough.
+	if record(20000, 5, 200) {
+		t.Fatal("unexpected split suggestion after a short sampling duration")
+	}
+	if d.mu.splitFinder == nil {
+		t.Fatal("expected sampling above the threshold")
+	}
+	if key := d.MaybeSplitKey(ms(25000)); key != nil {
+		t.Fatalf("unexpected split key %s", key)
+	}
+
+	// Above the threshold for long enough.
+	if !record(25000, 10, 200) {
+		t.Fatal("expected a split suggestion")
+	}
+	if key := d.MaybeSplitKey(ms(35000)); key == nil {
+		t.Fatal("expected a split key")
+	}
+
+	// Falling back below the threshold stops the sampling.
+	record(35000, 2, 10)
+	if key := d.MaybeSplitKey(ms(37000)); key != nil {
+		t.Fatalf("unexpected split key %s below the threshold", key)
+	}
+
+	d.Reset()
+	if qps := d.LastQPS(ms(37000)); qps != 0 {
+		t.Fatalf("expected reset qps, got %f", qps)
+	}
+}
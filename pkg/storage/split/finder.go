@@ -0,0 +1,142 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package split
+
+import (
+	"bytes"
+	"math"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+)
+
+const (
+	// RecordDurationThreshold is the minimum duration over which a Finder
+	// samples the requests to a range before suggesting a split key.
+	RecordDurationThreshold = 10 * time.Second
+	// splitKeySampleSize is the number of keys sampled by a Finder.
+	splitKeySampleSize = 20
+	// splitKeyMinCounter is the minimum number of requests a sampled key must
+	// have been compared to before it can be chosen as a split key.
+	splitKeyMinCounter = 100
+	// splitKeyThreshold is the maximum imbalance between the requests to the
+	// left and to the right of a split key, relative to their sum.
+	splitKeyThreshold = 0.25
+	// splitKeyContainedThreshold is the maximum fraction of the requests a
+	// split key can fall in the middle of.
+	splitKeyContainedThreshold = 0.5
+)
+
+// sample is a key sampled by a Finder, along with the number of requests
+// which fell entirely to its left and to its right, and of requests which
+// span it.
+type sample struct {
+	key                    roachpb.Key
+	left, right, contained int
+}
+
+// Finder finds a split key balancing the load of a range between the two
+// ranges resulting from the split. It keeps a reservoir sample of the start
+// keys of the requests to the range, and for each of them counts the
+// requests which would end up on either side of a split at that key.
+//
+// A split key is only suggested if the requests would be roughly balanced
+// between both sides of the split. In particular, no split key is suggested
+// for a range receiving sequential writes: the requests to the right of any
+// sampled key outnumber the requests to its left, and splitting would only
+// move the hotspot to the right hand side.
+type Finder struct {
+	startTime time.Time
+	samples   [splitKeySampleSize]sample
+	count     int
+}
+
+// NewFinder initiates a Finder with the given time.
+func NewFinder(startTime time.Time) *Finder {
+	return &Finder{
+		startTime: startTime,
+	}
+}
+
+// Ready checks if the Finder has been sampling requests for long enough to
+// suggest a split key.
+func (f *Finder) Ready(nowTime time.Time) bool {
+	return nowTime.Sub(f.startTime) > RecordDurationThreshold
+}
+
+// Record informs the Finder of a request to the given span. The intn function
+// returns a random integer in [0,n) and is used for reservoir sampling.
+func (f *Finder) Record(span roachpb.Span, intn func(int) int) {
+	if f == nil {
+		return
+	}
+
+	var idx int
+	count := f.count
+	f.count++
+	if count < splitKeySampleSize {
+		idx = count
+	} else if idx = intn(count); idx >= splitKeySampleSize {
+		// The request isn't sampled. Count it against the sampled keys.
+		for i := range f.samples {
+			s := &f.samples[i]
+			if bytes.Compare(span.Key, s.key) < 0 {
+				if len(span.EndKey) > 0 && bytes.Compare(span.EndKey, s.key) > 0 {
+					s.contained++
+				} else {
+					s.left++
+				}
+			} else {
+				s.right++
+			}
+		}
+		return
+	}
+
+	// Note that we always sample the start key of the span. We could take
+	// the middle of the span, but that seems unnecessarily complex in
+	// practice.
+	f.samples[idx] = sample{key: span.Key}
+}
+
+// Key returns the sampled key which best balances the requests between the
+// two sides of a split, or nil if no sampled key balances them well enough.
+func (f *Finder) Key() roachpb.Key {
+	if f == nil {
+		return nil
+	}
+
+	var bestIdx = -1
+	var bestScore = math.Inf(1)
+	for i, s := range f.samples {
+		if s.left+s.right+s.contained < splitKeyMinCounter {
+			continue
+		}
+		balanceScore := math.Abs(float64(s.left-s.right)) / float64(s.left+s.right+s.contained)
+		containedScore := float64(s.contained) / float64(s.left+s.right+s.contained)
+		if balanceScore >= splitKeyThreshold || containedScore >= splitKeyContainedThreshold {
+			continue
+		}
+		if score := balanceScore + containedScore; score < bestScore {
+			bestIdx = i
+			bestScore = score
+		}
+	}
+
+	if bestIdx == -1 {
+		return nil
+	}
+	return f.samples[bestIdx].key
+}
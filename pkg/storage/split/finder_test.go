@@ -0,0 +1,108 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package split
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func keyN(i int) roachpb.Key {
+	return roachpb.Key(fmt.Sprintf("%05d", i))
+}
+
+// TestFinderKey verifies that Key picks the sampled key which best balances
+// the requests.
+func TestFinderKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		samples []sample
+		expKey  roachpb.Key
+	}{
+		// No samples.
+		{nil, nil},
+		// Too few requests.
+		{[]sample{{key: keyN(1), left: 20, right: 20}}, nil},
+		// Balanced.
+		{[]sample{{key: keyN(1), left: 60, right: 60}}, keyN(1)},
+		// Unbalanced.
+		{[]sample{{key: keyN(1), left: 10, right: 110}}, nil},
+		// Mostly in the middle of requests.
+		{[]sample{{key: keyN(1), left: 30, right: 30, contained: 100}}, nil},
+		// The most balanced key wins.
+		{[]sample{
+			{key: keyN(1), left: 50, right: 60},
+			{key: keyN(2), left: 55, right: 55},
+			{key: keyN(3), left: 60, right: 50},
+		}, keyN(2)},
+		// Spanning requests count against a key.
+		{[]sample{
+			{key: keyN(1), left: 55, right: 55, contained: 20},
+			{key: keyN(2), left: 52, right: 58},
+		}, keyN(2)},
+	}
+
+	for i, tc := range testCases {
+		f := NewFinder(time.Time{})
+		copy(f.samples[:], tc.samples)
+		if key := f.Key(); !bytes.Equal(key, tc.expKey) {
+			t.Errorf("%d: expected key %s, got %s", i, tc.expKey, key)
+		}
+	}
+}
+
+// TestFinderRecord verifies that the Finder suggests a key in the middle of
+// uniformly distributed requests, and no key for sequential requests.
+func TestFinderRecord(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	const n = 10000
+	intn := rand.New(rand.NewSource(1)).Intn
+
+	uniform := NewFinder(time.Time{})
+	sequential := NewFinder(time.Time{})
+	for i := 0; i < n; i++ {
+		uniform.Record(roachpb.Span{Key: keyN(intn(n))}, intn)
+		sequential.Record(roachpb.Span{Key: keyN(i)}, intn)
+	}
+
+	key := uniform.Key()
+	if bytes.Compare(key, keyN(n/4)) < 0 || bytes.Compare(key, keyN(3*n/4)) > 0 {
+		t.Errorf("expected a key between %s and %s, got %s", keyN(n/4), keyN(3*n/4), key)
+	}
+	if key := sequential.Key(); key != nil {
+		t.Errorf("expected no key for sequential requests, got %s", key)
+	}
+}
+
+// TestFinderReady verifies that a Finder is ready once it has been sampling
+// requests for long enough.
+func TestFinderReady(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	start := time.Unix(100, 0)
+	f := NewFinder(start)
+	if f.Ready(start.Add(RecordDurationThreshold / 2)) {
+		t.Error("expected finder not to be ready")
+	}
+	if !f.Ready(start.Add(RecordDurationThreshold + time.Second)) {
+		t.Error("expected finder to be ready")
+	}
+}
@@ -15,6 +15,8 @@
 package storage
 
 import (
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/pkg/errors"
@@ -23,8 +25,11 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
 
@@ -33,8 +38,33 @@ const (
 	splitQueueTimerDuration = 0 // zero duration to process splits greedily.
 )
 
-// splitQueue manages a queue of ranges slated to be split due to size
-// or along intersecting zone config boundaries.
+// SplitByLoadEnabled wraps "kv.range_split.by_load_enabled".
+var SplitByLoadEnabled = settings.RegisterBoolSetting(
+	"kv.range_split.by_load_enabled",
+	"allow automatic splits of ranges based on where load is concentrated",
+	true,
+)
+
+// SplitByLoadQPSThreshold wraps "kv.range_split.load_qps_threshold".
+var SplitByLoadQPSThreshold = settings.RegisterIntSetting(
+	"kv.range_split.load_qps_threshold",
+	"the QPS over which the range becomes a candidate for load based splitting",
+	250,
+)
+
+// splitByLoadQPSThreshold returns the number of requests per second above
+// which the replica is split based on its load. It returns +Inf when load
+// based splitting is disabled.
+func (r *Replica) splitByLoadQPSThreshold() float64 {
+	st := r.store.ClusterSettings()
+	if !SplitByLoadEnabled.Get(&st.SV) {
+		return math.Inf(1)
+	}
+	return float64(SplitByLoadQPSThreshold.Get(&st.SV))
+}
+
+// splitQueue manages a queue of ranges slated to be split due to size,
+// along intersecting zone config boundaries, or to spread their load.
 type splitQueue struct {
 	*baseQueue
 	db *client.DB
@@ -63,7 +93,8 @@ func newSplitQueue(store *Store, db *client.DB, gossip *gossip.Gossip) *splitQue
 
 // shouldQueue determines whether a range should be queued for
 // splitting. This is true if the range is intersected by a zone config
-// prefix or if the range's size in bytes exceeds the limit for the zone.
+// prefix, if the range's size in bytes exceeds the limit for the zone, or
+// if the range's load warrants a split.
 func (sq *splitQueue) shouldQueue(
 	ctx context.Context, now hlc.Timestamp, repl *Replica, sysCfg config.SystemConfig,
 ) (shouldQ bool, priority float64) {
@@ -80,6 +111,12 @@ func (sq *splitQueue) shouldQueue(
 		priority += ratio
 		shouldQ = true
 	}
+
+	// Add a small bump if the range's load warrants a split.
+	if repl.loadBasedSplitter.MaybeSplitKey(repl.store.Clock().PhysicalTime()) != nil {
+		priority += 1
+		shouldQ = true
+	}
 	return
 }
 
@@ -97,6 +134,8 @@ func (sq *splitQueue) process(ctx context.Context, r *Replica, sysCfg config.Sys
 				SplitKey: splitKey.AsRawKey(),
 			},
 			desc,
+			ReasonZoneConfigBoundary,
+			"",
 		); pErr != nil {
 			return errors.Wrapf(pErr.GoError(), "unable to split %s at key %q", r, splitKey)
 		}
@@ -113,6 +152,9 @@ func (sq *splitQueue) process(ctx context.Context, r *Replica, sysCfg config.Sys
 			ctx,
 			roachpb.AdminSplitRequest{},
 			desc,
+			ReasonRangeTooLarge,
+			fmt.Sprintf("%s above threshold size %s",
+				humanizeutil.IBytes(size), humanizeutil.IBytes(maxBytes)),
 		); pErr != nil {
 			return pErr.GoError()
 		} else if !validSplitKey {
@@ -129,7 +171,36 @@ func (sq *splitQueue) process(ctx context.Context, r *Replica, sysCfg config.Sys
 				return err
 			}
 			r.SetMaxBytes(zone.RangeMaxBytes)
+			return nil
+		}
+	}
+
+	// Finally, handle the case of splitting due to load.
+	now := r.store.Clock().PhysicalTime()
+	if splitByLoadKey := r.loadBasedSplitter.MaybeSplitKey(now); splitByLoadKey != nil {
+		// Keep the split key from falling within a row.
+		splitKey, err := keys.EnsureSafeSplitKey(splitByLoadKey)
+		if err != nil || len(splitKey) == 0 {
+			log.VEventf(ctx, 2, "unable to split %s by load at key %q: %v", r, splitByLoadKey, err)
+			return nil
+		}
+		qps := r.loadBasedSplitter.LastQPS(now)
+		if _, _, pErr := r.adminSplitWithDescriptor(
+			ctx,
+			roachpb.AdminSplitRequest{
+				Span: roachpb.Span{
+					Key: splitKey,
+				},
+				SplitKey: splitKey,
+			},
+			desc,
+			ReasonRangeLoad,
+			fmt.Sprintf("%.2f qps above threshold of %.2f qps", qps, r.splitByLoadQPSThreshold()),
+		); pErr != nil {
+			return errors.Wrapf(pErr.GoError(), "unable to split %s at key %q", r, splitKey)
 		}
+		// The load of the range has changed, start over.
+		r.loadBasedSplitter.Reset()
 	}
 	return nil
 }
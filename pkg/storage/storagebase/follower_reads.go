@@ -0,0 +1,72 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storagebase
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// FollowerReadsEnabled controls whether the lease holders publish closed
+// timestamps and whether the replicas serve reads at or below them.
+var FollowerReadsEnabled = settings.RegisterBoolSetting(
+	"kv.closed_timestamp.follower_reads_enabled",
+	"allow all the replicas of a range to serve consistent historical reads at closed timestamps",
+	false,
+)
+
+// ClosedTimestampTargetDuration is how far behind the current time the lease
+// holders try to close timestamps. Writes below it are pushed above it.
+var ClosedTimestampTargetDuration = settings.RegisterNonNegativeDurationSetting(
+	"kv.closed_timestamp.target_duration",
+	"if nonzero, attempt to provide closed timestamp notifications for timestamps trailing cluster time by approximately this duration",
+	30*time.Second,
+)
+
+// IsFollowerReadBatch returns whether the batch could be served by a replica
+// which doesn't hold the lease, if its timestamp is closed: it must be made of
+// consistent non-locking reads, outside of a transaction which has written.
+func IsFollowerReadBatch(ba roachpb.BatchRequest) bool {
+	if ba.ReadConsistency != roachpb.CONSISTENT || len(ba.Requests) == 0 {
+		return false
+	}
+	if ba.Txn != nil && ba.Txn.Writing {
+		return false
+	}
+	for _, union := range ba.Requests {
+		switch union.GetInner().(type) {
+		case *roachpb.GetRequest, *roachpb.ScanRequest, *roachpb.ReverseScanRequest:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FollowerReadTimestamp returns the timestamp up to which a follower read of
+// the batch could observe values, including those in its uncertainty
+// interval. The batch can be served by a replica whose closed timestamp is at
+// or above it.
+func FollowerReadTimestamp(ba roachpb.BatchRequest) hlc.Timestamp {
+	ts := ba.Timestamp
+	if ba.Txn != nil {
+		ts.Forward(ba.Txn.Timestamp)
+		ts.Forward(ba.Txn.MaxTimestamp)
+	}
+	return ts
+}
@@ -356,7 +356,9 @@ type Store struct {
 	rangeIDAlloc       *idAllocator                // Range ID allocator
 	gcQueue            *gcQueue                    // Garbage collection queue
 	splitQueue         *splitQueue                 // Range splitting queue
+	mergeQueue         *mergeQueue                 // Range merging queue
 	replicateQueue     *replicateQueue             // Replication queue
+	storeRebalancer    *StoreRebalancer            // Load-based rebalancer
 	replicaGCQueue     *replicaGCQueue             // Replica GC queue
 	raftLogQueue       *raftLogQueue               // Raft log truncation queue
 	raftSnapshotQueue  *raftSnapshotQueue          // Raft repair queue
@@ -723,6 +725,10 @@ type StoreTestingKnobs struct {
 	DisableReplicaRebalancing bool
 	// DisableSplitQueue disables the split queue.
 	DisableSplitQueue bool
+	// DisableMergeQueue disables the merge queue.
+	DisableMergeQueue bool
+	// DisableStoreRebalancer disables the store rebalancer.
+	DisableStoreRebalancer bool
 	// DisableTimeSeriesMaintenanceQueue disables the time series maintenance
 	// queue.
 	DisableTimeSeriesMaintenanceQueue bool
@@ -875,13 +881,15 @@ func NewStore(cfg StoreConfig, eng engine.Engine, nodeDesc *roachpb.NodeDescript
 		)
 		s.gcQueue = newGCQueue(s, s.cfg.Gossip)
 		s.splitQueue = newSplitQueue(s, s.db, s.cfg.Gossip)
+		s.mergeQueue = newMergeQueue(s, s.db, s.cfg.Gossip)
 		s.replicateQueue = newReplicateQueue(s, s.cfg.Gossip, s.allocator, s.cfg.Clock)
+		s.storeRebalancer = NewStoreRebalancer(s.cfg.AmbientCtx, cfg.Settings, s.replicateQueue)
 		s.replicaGCQueue = newReplicaGCQueue(s, s.db, s.cfg.Gossip)
 		s.raftLogQueue = newRaftLogQueue(s, s.db, s.cfg.Gossip)
 		s.raftSnapshotQueue = newRaftSnapshotQueue(s, s.cfg.Gossip, s.cfg.Clock)
 		s.consistencyQueue = newConsistencyQueue(s, s.cfg.Gossip)
 		s.scanner.AddQueues(
-			s.gcQueue, s.splitQueue, s.replicateQueue, s.replicaGCQueue,
+			s.gcQueue, s.splitQueue, s.mergeQueue, s.replicateQueue, s.replicaGCQueue,
 			s.raftLogQueue, s.raftSnapshotQueue, s.consistencyQueue)
 
 		if s.cfg.TimeSeriesDataStore != nil {
@@ -901,6 +909,9 @@ func NewStore(cfg StoreConfig, eng engine.Engine, nodeDesc *roachpb.NodeDescript
 	if cfg.TestingKnobs.DisableSplitQueue {
 		s.setSplitQueueActive(false)
 	}
+	if cfg.TestingKnobs.DisableMergeQueue {
+		s.setMergeQueueActive(false)
+	}
 	if cfg.TestingKnobs.DisableTimeSeriesMaintenanceQueue {
 		s.setTimeSeriesMaintenanceQueueActive(false)
 	}
@@ -1199,6 +1210,16 @@ func (s *Store) Start(ctx context.Context, stopper *stop.Stopper) error {
 			}
 		})
 
+		// Start the store rebalancer, which moves load off the store when it
+		// receives more queries per second than the rest of the cluster.
+		if !s.cfg.TestingKnobs.DisableStoreRebalancer {
+			s.storeRebalancer.Start(ctx, s.stopper)
+		}
+
+		// Start closing timestamps on the ranges which receive no writes, so
+		// that their followers can serve reads.
+		s.startClosedTimestampLoop(ctx)
+
 		// Run metrics computation up front to populate initial statistics.
 		if err = s.ComputeMetrics(ctx, -1); err != nil {
 			log.Infof(ctx, "%s: failed initial metrics computation: %s", s, err)
@@ -2017,30 +2038,40 @@ func (s *Store) MergeRange(
 	return subsumingRng.setDesc(&copy)
 }
 
-// If the subsuming replica has the range lease, we update its timestamp cache
-// with the entries from the subsumed. Otherwise, then the timestamp cache
-// doesn't matter (in fact it should be empty, to save memory).
+// maybeMergeTimestampCaches ensures that, if the subsuming replica holds the
+// range lease, the timestamp cache of the store reflects the reads served by
+// the subsumed range. AdminMerge collocates the leases of both ranges, in
+// which case the timestamp cache shared by the replicas of the store already
+// does. Otherwise, the low water mark of the keys of the subsumed range is
+// conservatively forwarded past any timestamp at which its lease holder may
+// have served reads.
 func (s *Store) maybeMergeTimestampCaches(
 	ctx context.Context, subsumingRep *Replica, subsumedRep *Replica,
 ) error {
-	subsumingRep.mu.Lock()
-	defer subsumingRep.mu.Unlock()
-	subsumingLease := subsumingRep.mu.state.Lease
+	now := s.Clock().Now()
+	if !subsumingRep.OwnsValidLease(now) {
+		return nil
+	}
 
-	subsumedRep.mu.Lock()
-	defer subsumedRep.mu.Unlock()
+	subsumedRep.mu.RLock()
 	subsumedLease := *subsumedRep.mu.state.Lease
-
-	// Merge support is currently incomplete and incorrect. In particular, the
-	// lease holders must be colocated and the subsumed range appropriately
-	// quiesced. See also #2433.
-	now := s.Clock().Now()
-	if subsumedRep.isLeaseValidRLocked(subsumedLease, now) &&
-		subsumingLease.Replica.StoreID != subsumedLease.Replica.StoreID {
-		log.Fatalf(ctx, "cannot merge ranges with non-colocated leases. "+
-			"Subsuming lease: %s. Subsumed lease: %s.", subsumingLease, subsumedLease)
+	subsumedDesc := subsumedRep.mu.state.Desc
+	subsumedRep.mu.RUnlock()
+	if subsumedLease.OwnedBy(s.StoreID()) {
+		return nil
 	}
 
+	log.Warningf(ctx, "merging ranges with non-collocated leases; subsumed lease: %s", subsumedLease)
+	lowWater := now.Add(s.Clock().MaxOffset().Nanoseconds(), 0)
+	if !s.tsCacheMu.cache.ThreadSafe() {
+		s.tsCacheMu.Lock()
+	}
+	for _, keyRange := range makeReplicatedKeyRanges(subsumedDesc) {
+		s.tsCacheMu.cache.SetLowWater(keyRange.start.Key, keyRange.end.Key, lowWater)
+	}
+	if !s.tsCacheMu.cache.ThreadSafe() {
+		s.tsCacheMu.Unlock()
+	}
 	return nil
 }
 
@@ -2207,6 +2238,7 @@ func (s *Store) removeReplicaImpl(
 	rep.mu.destroyStatus.Set(roachpb.NewRangeNotFoundError(rep.RangeID), destroyReasonRemoved)
 	rep.mu.Unlock()
 	rep.readOnlyCmdMu.Unlock()
+	rep.disconnectRangefeedWithReasonRaftMuLocked(roachpb.RangeFeedRetryError_REASON_REPLICA_REMOVED)
 
 	if destroyData {
 		if err := rep.destroyDataRaftMuLocked(ctx, consistentDesc); err != nil {
@@ -2293,12 +2325,18 @@ func (s *Store) Capacity() (roachpb.StoreCapacity, error) {
 	now := s.cfg.Clock.Now()
 	var leaseCount int32
 	var logicalBytes int64
+	var totalQueriesPerSecond float64
 	var totalWritesPerSecond float64
 	bytesPerReplica := make([]float64, 0, capacity.RangeCount)
 	writesPerReplica := make([]float64, 0, capacity.RangeCount)
 	newStoreReplicaVisitor(s).Visit(func(r *Replica) bool {
 		if r.OwnsValidLease(now) {
 			leaseCount++
+			if r.leaseholderStats != nil {
+				if qps, dur := r.leaseholderStats.avgQPS(); dur >= MinStatsDuration {
+					totalQueriesPerSecond += qps
+				}
+			}
 		}
 		mvccStats := r.GetMVCCStats()
 		logicalBytes += mvccStats.Total()
@@ -2314,6 +2352,7 @@ func (s *Store) Capacity() (roachpb.StoreCapacity, error) {
 	})
 	capacity.LeaseCount = leaseCount
 	capacity.LogicalBytes = logicalBytes
+	capacity.QueriesPerSecond = totalQueriesPerSecond
 	capacity.WritesPerSecond = totalWritesPerSecond
 	capacity.BytesPerReplica = roachpb.PercentilesFromData(bytesPerReplica)
 	capacity.WritesPerReplica = roachpb.PercentilesFromData(writesPerReplica)
@@ -2599,7 +2638,12 @@ func (s *Store) Send(
 			// this is the code path with the requesting client waiting.
 			if pErr.Index != nil {
 				var pushType roachpb.PushTxnType
-				if ba.IsWrite() {
+				if ba.NoWait {
+					// The client does not want to wait on the conflicting
+					// transactions. Only push them out of the way if they have
+					// already expired.
+					pushType = roachpb.PUSH_TOUCH
+				} else if ba.IsWrite() {
 					pushType = roachpb.PUSH_ABORT
 				} else {
 					pushType = roachpb.PUSH_TIMESTAMP
@@ -2625,7 +2669,13 @@ func (s *Store) Send(
 					clonedTxn := h.Txn.Clone()
 					h.Txn = &clonedTxn
 				}
+				wiPErr := pErr
 				if pErr = s.intentResolver.processWriteIntentError(ctx, pErr, args, h, pushType); pErr != nil {
+					if _, ok := pErr.GetDetail().(*roachpb.TransactionPushError); ok && ba.NoWait {
+						// A conflicting transaction is still live; return the
+						// original conflict to the client instead of waiting.
+						return nil, wiPErr
+					}
 					// Do not propagate ambiguous results; assume success and retry original op.
 					if _, ok := pErr.GetDetail().(*roachpb.AmbiguousResultError); !ok {
 						// Preserve the error index.
@@ -4093,6 +4143,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 		leaseEpochCount               int64
 		raftLeaderNotLeaseHolderCount int64
 		quiescentCount                int64
+		averageQueriesPerSecond       float64
 		averageWritesPerSecond        float64
 
 		rangeCount                int64
@@ -4125,6 +4176,11 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 			case roachpb.LeaseEpoch:
 				leaseEpochCount++
 			}
+			if rep.leaseholderStats != nil {
+				if qps, dur := rep.leaseholderStats.avgQPS(); dur >= MinStatsDuration {
+					averageQueriesPerSecond += qps
+				}
+			}
 		}
 		if metrics.Quiescent {
 			quiescentCount++
@@ -4152,6 +4208,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 	s.metrics.LeaseExpirationCount.Update(leaseExpirationCount)
 	s.metrics.LeaseEpochCount.Update(leaseEpochCount)
 	s.metrics.QuiescentCount.Update(quiescentCount)
+	s.metrics.AverageQueriesPerSecond.Update(averageQueriesPerSecond)
 	s.metrics.AverageWritesPerSecond.Update(averageWritesPerSecond)
 	s.recordNewWritesPerSecond(averageWritesPerSecond)
 
@@ -4349,6 +4406,9 @@ func (s *Store) setReplicateQueueActive(active bool) {
 func (s *Store) setSplitQueueActive(active bool) {
 	s.splitQueue.SetDisabled(!active)
 }
+func (s *Store) setMergeQueueActive(active bool) {
+	s.mergeQueue.SetDisabled(!active)
+}
 func (s *Store) setTimeSeriesMaintenanceQueueActive(active bool) {
 	s.tsMaintenanceQueue.SetDisabled(!active)
 }
@@ -0,0 +1,532 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+const (
+	// storeRebalancerTimerDuration is the duration between checks of whether
+	// the store is overloaded compared to the rest of the cluster.
+	storeRebalancerTimerDuration = time.Minute
+
+	// minQPSThresholdDifference is the minimum difference in QPS between a
+	// store and the mean of the cluster for the store to be considered
+	// overloaded, which keeps lightly loaded clusters from rebalancing
+	// constantly.
+	minQPSThresholdDifference = 100
+)
+
+var (
+	metaStoreRebalancerLeaseTransferCount = metric.Metadata{
+		Name: "rebalancing.lease.transfers",
+		Help: "Number of lease transfers motivated by store-level load imbalances",
+	}
+	metaStoreRebalancerRangeRebalanceCount = metric.Metadata{
+		Name: "rebalancing.range.rebalances",
+		Help: "Number of range rebalance operations motivated by store-level load imbalances",
+	}
+)
+
+// StoreRebalancerMetrics is the set of metrics for the store-level rebalancer.
+type StoreRebalancerMetrics struct {
+	LeaseTransferCount  *metric.Counter
+	RangeRebalanceCount *metric.Counter
+}
+
+func makeStoreRebalancerMetrics() StoreRebalancerMetrics {
+	return StoreRebalancerMetrics{
+		LeaseTransferCount:  metric.NewCounter(metaStoreRebalancerLeaseTransferCount),
+		RangeRebalanceCount: metric.NewCounter(metaStoreRebalancerRangeRebalanceCount),
+	}
+}
+
+// LBRebalancingMode controls whether the store rebalancer moves leases and
+// replicas away from stores receiving more load than the rest of the cluster.
+type LBRebalancingMode int64
+
+const (
+	// LBRebalancingOff means that the store rebalancer doesn't do anything.
+	LBRebalancingOff LBRebalancingMode = iota
+	// LBRebalancingLeasesOnly means that the store rebalancer only transfers
+	// leases.
+	LBRebalancingLeasesOnly
+	// LBRebalancingLeasesAndReplicas means that the store rebalancer transfers
+	// leases first, and then moves replicas if that wasn't enough.
+	LBRebalancingLeasesAndReplicas
+)
+
+// LoadBasedRebalancingMode wraps "kv.allocator.load_based_rebalancing".
+var LoadBasedRebalancingMode = settings.RegisterEnumSetting(
+	"kv.allocator.load_based_rebalancing",
+	"whether to rebalance based on the distribution of QPS across stores",
+	"leases and replicas",
+	map[int64]string{
+		int64(LBRebalancingOff):               "off",
+		int64(LBRebalancingLeasesOnly):        "leases",
+		int64(LBRebalancingLeasesAndReplicas): "leases and replicas",
+	},
+)
+
+// qpsRebalanceThreshold is the fraction above or below the mean QPS of the
+// cluster at which a store is considered overloaded or underloaded.
+var qpsRebalanceThreshold = settings.RegisterNonNegativeFloatSetting(
+	"kv.allocator.qps_rebalance_threshold",
+	"minimum fraction away from the mean a store's QPS can be before it is considered overfull or underfull",
+	0.25,
+)
+
+// StoreRebalancer periodically compares the number of queries per second
+// received by the leaseholders of its store with those of the other stores
+// in the cluster, as gossiped through the StorePool. When the store is
+// overloaded, it moves the leases of its hottest ranges to replicas on less
+// loaded stores, and then, if that wasn't enough and the mode permits it,
+// moves the replicas of its hottest ranges (along with their leases) to less
+// loaded stores, until the QPS of the store is back within a band around the
+// mean of the cluster.
+//
+// The StoreRebalancer complements the replicate queue, which balances the
+// number of ranges and leases per store but lacks a store-wide view of load.
+type StoreRebalancer struct {
+	log.AmbientContext
+	metrics StoreRebalancerMetrics
+	st      *cluster.Settings
+	rq      *replicateQueue
+}
+
+// NewStoreRebalancer creates a StoreRebalancer to work in tandem with the
+// provided replicateQueue.
+func NewStoreRebalancer(
+	ambientCtx log.AmbientContext, st *cluster.Settings, rq *replicateQueue,
+) *StoreRebalancer {
+	ambientCtx.AddLogTag("store-rebalancer", nil)
+	sr := &StoreRebalancer{
+		AmbientContext: ambientCtx,
+		metrics:        makeStoreRebalancerMetrics(),
+		st:             st,
+		rq:             rq,
+	}
+	rq.store.metrics.registry.AddMetricStruct(&sr.metrics)
+	return sr
+}
+
+// Start runs an infinite loop in a goroutine which regularly checks whether
+// the store is overloaded compared to the rest of the cluster, and if so
+// rebalances its load.
+func (sr *StoreRebalancer) Start(ctx context.Context, stopper *stop.Stopper) {
+	ctx = sr.AnnotateCtx(ctx)
+
+	stopper.RunWorker(ctx, func(ctx context.Context) {
+		var timer timeutil.Timer
+		defer timer.Stop()
+		timer.Reset(jitteredInterval(storeRebalancerTimerDuration))
+		for {
+			select {
+			case <-stopper.ShouldQuiesce():
+				return
+			case <-timer.C:
+				timer.Read = true
+				timer.Reset(jitteredInterval(storeRebalancerTimerDuration))
+			}
+
+			mode := LBRebalancingMode(LoadBasedRebalancingMode.Get(&sr.st.SV))
+			if mode == LBRebalancingOff {
+				continue
+			}
+			storeList, _, _ := sr.rq.allocator.storePool.getStoreList(roachpb.RangeID(0), storeFilterNone)
+			sr.rebalanceStore(ctx, mode, storeList)
+		}
+	})
+}
+
+// jitteredInterval returns a randomly jittered (+/-25%) duration from
+// interval, so that the stores of a cluster don't all rebalance at the same
+// time.
+func jitteredInterval(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * (0.75 + 0.5*rand.Float64()))
+}
+
+// replicaWithStats pairs a replica with the number of queries per second it
+// receives as the leaseholder of its range.
+type replicaWithStats struct {
+	repl *Replica
+	qps  float64
+}
+
+// qpsThresholds returns the QPS below and above which a store is considered
+// underloaded and overloaded, respectively.
+func qpsThresholds(st *cluster.Settings, mean float64) (min, max float64) {
+	fraction := qpsRebalanceThreshold.Get(&st.SV)
+	min = math.Min(mean*(1-fraction), mean-minQPSThresholdDifference)
+	max = math.Max(mean*(1+fraction), mean+minQPSThresholdDifference)
+	return min, max
+}
+
+func (sr *StoreRebalancer) rebalanceStore(
+	ctx context.Context, mode LBRebalancingMode, storeList StoreList,
+) {
+	store := sr.rq.store
+	qpsMinThreshold, qpsMaxThreshold := qpsThresholds(sr.st, storeList.candidateQueriesPerSecond.mean)
+
+	storeMap := make(map[roachpb.StoreID]*roachpb.StoreDescriptor, len(storeList.stores))
+	for i := range storeList.stores {
+		storeMap[storeList.stores[i].StoreID] = &storeList.stores[i]
+	}
+	localDesc, ok := storeMap[store.StoreID()]
+	if !ok {
+		log.Warningf(ctx, "StorePool missing descriptor for local store")
+		return
+	}
+	if localDesc.Capacity.QueriesPerSecond <= qpsMaxThreshold {
+		log.VEventf(ctx, 1, "local QPS %.2f is below max threshold %.2f (mean=%.2f); no rebalancing needed",
+			localDesc.Capacity.QueriesPerSecond, qpsMaxThreshold, storeList.candidateQueriesPerSecond.mean)
+		return
+	}
+	log.Infof(ctx,
+		"considering load-based lease transfers for s%d with %.2f qps (mean=%.2f, upperThreshold=%.2f)",
+		localDesc.StoreID, localDesc.Capacity.QueriesPerSecond,
+		storeList.candidateQueriesPerSecond.mean, qpsMaxThreshold)
+
+	sysCfg, ok := store.cfg.Gossip.GetSystemConfig()
+	if !ok {
+		log.VEventf(ctx, 1, "no system config available, unable to rebalance load")
+		return
+	}
+
+	hottestRanges := sr.hottestRanges()
+	var replicasToMaybeRebalance []replicaWithStats
+	for len(hottestRanges) > 0 && localDesc.Capacity.QueriesPerSecond > qpsMaxThreshold {
+		replWithStats := hottestRanges[0]
+		hottestRanges = hottestRanges[1:]
+		if !shouldMoveLoad(replWithStats.qps, localDesc, qpsMinThreshold) {
+			continue
+		}
+
+		desc := replWithStats.repl.Desc()
+		zone, err := sysCfg.GetZoneConfigForKey(desc.StartKey)
+		if err != nil {
+			log.Error(ctx, err)
+			return
+		}
+		candidates := filterBehindReplicas(replWithStats.repl.RaftStatus(), desc.Replicas, 0 /* brandNewReplicaID */)
+		// Leases are only transferred to replicas satisfying the lease
+		// preferences, lest the replicate queue move them back.
+		if preferred := sr.rq.allocator.preferredLeaseholders(zone, candidates); len(preferred) > 0 {
+			candidates = preferred
+		}
+		target, ok := chooseLeaseTarget(
+			store.StoreID(), candidates, replWithStats.qps, storeMap, qpsMaxThreshold)
+		if !ok {
+			replicasToMaybeRebalance = append(replicasToMaybeRebalance, replWithStats)
+			continue
+		}
+
+		log.VEventf(ctx, 1, "transferring lease for r%d (qps=%.2f) to s%d (qps=%.2f)",
+			desc.RangeID, replWithStats.qps, target.StoreID, storeMap[target.StoreID].Capacity.QueriesPerSecond)
+		if err := replWithStats.repl.AdminTransferLease(ctx, target.StoreID); err != nil {
+			log.Errorf(ctx, "unable to transfer lease to s%d: %s", target.StoreID, err)
+			continue
+		}
+		sr.metrics.LeaseTransferCount.Inc(1)
+		sr.rq.lastLeaseTransfer.Store(timeutil.Now())
+		localDesc.Capacity.LeaseCount--
+		localDesc.Capacity.QueriesPerSecond -= replWithStats.qps
+		storeMap[target.StoreID].Capacity.LeaseCount++
+		storeMap[target.StoreID].Capacity.QueriesPerSecond += replWithStats.qps
+	}
+
+	if localDesc.Capacity.QueriesPerSecond <= qpsMaxThreshold {
+		log.Infof(ctx, "load-based lease transfers successfully brought s%d down to %.2f qps (mean=%.2f, upperThreshold=%.2f)",
+			localDesc.StoreID, localDesc.Capacity.QueriesPerSecond,
+			storeList.candidateQueriesPerSecond.mean, qpsMaxThreshold)
+		return
+	}
+	if mode != LBRebalancingLeasesAndReplicas {
+		log.Infof(ctx, "ran out of leases worth transferring and qps (%.2f) is still above desired threshold (%.2f)",
+			localDesc.Capacity.QueriesPerSecond, qpsMaxThreshold)
+		return
+	}
+	log.Infof(ctx,
+		"ran out of leases worth transferring and qps (%.2f) is still above desired threshold (%.2f); considering load-based replica rebalances",
+		localDesc.Capacity.QueriesPerSecond, qpsMaxThreshold)
+
+	// Replicas are only moved to stores which aren't throttled.
+	targetList, _, _ := sr.rq.allocator.storePool.getStoreList(roachpb.RangeID(0), storeFilterThrottled)
+
+	replicasToMaybeRebalance = append(replicasToMaybeRebalance, hottestRanges...)
+	for len(replicasToMaybeRebalance) > 0 && localDesc.Capacity.QueriesPerSecond > qpsMaxThreshold {
+		replWithStats := replicasToMaybeRebalance[0]
+		replicasToMaybeRebalance = replicasToMaybeRebalance[1:]
+		if !shouldMoveLoad(replWithStats.qps, localDesc, qpsMinThreshold) {
+			continue
+		}
+
+		desc := replWithStats.repl.Desc()
+		zone, err := sysCfg.GetZoneConfigForKey(desc.StartKey)
+		if err != nil {
+			log.Error(ctx, err)
+			return
+		}
+		target, ok := chooseReplicaTarget(
+			store.StoreID(),
+			desc,
+			zone.Constraints,
+			zone.LeasePreferences,
+			sr.rq.allocator.storePool.getLocalities(desc.Replicas),
+			replWithStats.qps,
+			targetList.stores,
+			storeMap,
+			qpsMaxThreshold,
+		)
+		if !ok {
+			log.VEventf(ctx, 3, "no suitable target to move r%d (qps=%.2f) to", desc.RangeID, replWithStats.qps)
+			continue
+		}
+
+		log.VEventf(ctx, 1, "moving r%d (qps=%.2f) from s%d to s%d (qps=%.2f)",
+			desc.RangeID, replWithStats.qps, localDesc.StoreID, target.StoreID,
+			storeMap[target.StoreID].Capacity.QueriesPerSecond)
+		details := fmt.Sprintf("s%d qps %.2f above threshold %.2f",
+			localDesc.StoreID, localDesc.Capacity.QueriesPerSecond, qpsMaxThreshold)
+		if err := sr.moveReplica(ctx, replWithStats.repl, desc, target, details); err != nil {
+			log.Errorf(ctx, "unable to move r%d to s%d: %s", desc.RangeID, target.StoreID, err)
+			continue
+		}
+		sr.metrics.RangeRebalanceCount.Inc(1)
+		localDesc.Capacity.RangeCount--
+		localDesc.Capacity.LeaseCount--
+		localDesc.Capacity.QueriesPerSecond -= replWithStats.qps
+		storeMap[target.StoreID].Capacity.RangeCount++
+		storeMap[target.StoreID].Capacity.LeaseCount++
+		storeMap[target.StoreID].Capacity.QueriesPerSecond += replWithStats.qps
+	}
+
+	log.Infof(ctx,
+		"load-based replica rebalances brought s%d to %.2f qps (mean=%.2f, upperThreshold=%.2f)",
+		localDesc.StoreID, localDesc.Capacity.QueriesPerSecond,
+		storeList.candidateQueriesPerSecond.mean, qpsMaxThreshold)
+}
+
+// hottestRanges returns the replicas of the store holding a valid lease,
+// sorted by decreasing number of queries per second.
+func (sr *StoreRebalancer) hottestRanges() []replicaWithStats {
+	store := sr.rq.store
+	now := store.Clock().Now()
+	var replicas []replicaWithStats
+	newStoreReplicaVisitor(store).Visit(func(r *Replica) bool {
+		if !r.OwnsValidLease(now) || r.leaseholderStats == nil {
+			return true
+		}
+		if qps, dur := r.leaseholderStats.avgQPS(); dur >= MinStatsDuration {
+			replicas = append(replicas, replicaWithStats{repl: r, qps: qps})
+		}
+		return true
+	})
+	sort.Slice(replicas, func(i, j int) bool {
+		return replicas[i].qps > replicas[j].qps
+	})
+	return replicas
+}
+
+// shouldMoveLoad returns whether moving the given amount of QPS away from the
+// store is worthwhile. Moving it must not take the store below the min
+// threshold, lest its load bounce back and forth between stores.
+func shouldMoveLoad(qps float64, localDesc *roachpb.StoreDescriptor, qpsMinThreshold float64) bool {
+	return qps > 0 && localDesc.Capacity.QueriesPerSecond-qps >= qpsMinThreshold
+}
+
+// chooseLeaseTarget returns the replica, among the candidates, to which the
+// lease of a range receiving the given QPS should be transferred. It picks
+// the least loaded store which would stay below the max threshold once it
+// holds the lease.
+func chooseLeaseTarget(
+	localStoreID roachpb.StoreID,
+	candidates []roachpb.ReplicaDescriptor,
+	qps float64,
+	storeMap map[roachpb.StoreID]*roachpb.StoreDescriptor,
+	qpsMaxThreshold float64,
+) (roachpb.ReplicaDescriptor, bool) {
+	var target roachpb.ReplicaDescriptor
+	var targetQPS float64
+	found := false
+	for _, candidate := range candidates {
+		if candidate.StoreID == localStoreID {
+			continue
+		}
+		storeDesc, ok := storeMap[candidate.StoreID]
+		if !ok {
+			continue
+		}
+		newQPS := storeDesc.Capacity.QueriesPerSecond + qps
+		if newQPS > qpsMaxThreshold {
+			continue
+		}
+		if !found || newQPS < targetQPS {
+			target, targetQPS, found = candidate, newQPS, true
+		}
+	}
+	return target, found
+}
+
+// chooseReplicaTarget returns the store to which the local replica of the
+// range, which receives the given QPS, should be moved. It picks the least
+// loaded store which satisfies the constraints and lease preferences of the
+// range, doesn't reduce its diversity, has room for the replica, and would
+// stay below the max threshold once it holds the lease.
+func chooseReplicaTarget(
+	localStoreID roachpb.StoreID,
+	desc *roachpb.RangeDescriptor,
+	constraints config.Constraints,
+	leasePreferences []config.LeasePreference,
+	localities map[roachpb.NodeID]roachpb.Locality,
+	qps float64,
+	stores []roachpb.StoreDescriptor,
+	storeMap map[roachpb.StoreID]*roachpb.StoreDescriptor,
+	qpsMaxThreshold float64,
+) (roachpb.StoreDescriptor, bool) {
+	localRepl, ok := desc.GetReplicaDescriptor(localStoreID)
+	if !ok {
+		return roachpb.StoreDescriptor{}, false
+	}
+	// The localities of the other replicas, against which the diversity of
+	// the candidates is measured.
+	otherLocalities := make(map[roachpb.NodeID]roachpb.Locality, len(localities))
+	for nodeID, locality := range localities {
+		if nodeID != localRepl.NodeID {
+			otherLocalities[nodeID] = locality
+		}
+	}
+	localDiversity := diversityRemovalScore(localRepl.NodeID, localities)
+	// The target must satisfy the per-replica constraints satisfied by the
+	// local store, lest the move leave them unsatisfied.
+	var localReplicaConstraints []config.ReplicaConstraints
+	if localStore, ok := storeMap[localStoreID]; ok {
+		for _, rc := range constraints.PerReplica {
+			if replicaConstraintsCheck(*localStore, rc) {
+				localReplicaConstraints = append(localReplicaConstraints, rc)
+			}
+		}
+	}
+
+	var target roachpb.StoreDescriptor
+	var targetQPS float64
+	found := false
+	for _, store := range stores {
+		if !preexistingReplicaCheck(store.Node.NodeID, desc.Replicas) {
+			continue
+		}
+		if ok, _ := constraintCheck(store, constraints); !ok || !maxCapacityCheck(store) {
+			continue
+		}
+		if !satisfiesLeasePreferences(store, leasePreferences) {
+			continue
+		}
+		satisfiesReplicaConstraints := true
+		for _, rc := range localReplicaConstraints {
+			if !replicaConstraintsCheck(store, rc) {
+				satisfiesReplicaConstraints = false
+				break
+			}
+		}
+		if !satisfiesReplicaConstraints {
+			continue
+		}
+		if diversityScore(store, otherLocalities) < localDiversity {
+			continue
+		}
+		// Use the most up to date QPS of the store, which accounts for the
+		// load moved so far.
+		storeQPS := store.Capacity.QueriesPerSecond
+		if storeDesc, ok := storeMap[store.StoreID]; ok {
+			storeQPS = storeDesc.Capacity.QueriesPerSecond
+		}
+		newQPS := storeQPS + qps
+		if newQPS > qpsMaxThreshold {
+			continue
+		}
+		if !found || newQPS < targetQPS {
+			target, targetQPS, found = store, newQPS, true
+		}
+	}
+	return target, found
+}
+
+// satisfiesLeasePreferences returns whether the store satisfies any of the
+// lease preferences, or true if there are none.
+func satisfiesLeasePreferences(
+	store roachpb.StoreDescriptor, leasePreferences []config.LeasePreference,
+) bool {
+	if len(leasePreferences) == 0 {
+		return true
+	}
+	for _, preference := range leasePreferences {
+		if ok, _ := constraintCheck(store, config.Constraints{Constraints: preference.Constraints}); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// moveReplica moves the local replica of the range, along with its lease, to
+// the target store. The replica on the target store is added first, then
+// receives the lease, and finally removes the local replica.
+func (sr *StoreRebalancer) moveReplica(
+	ctx context.Context,
+	repl *Replica,
+	desc *roachpb.RangeDescriptor,
+	target roachpb.StoreDescriptor,
+	details string,
+) error {
+	store := sr.rq.store
+	addTarget := roachpb.ReplicationTarget{NodeID: target.Node.NodeID, StoreID: target.StoreID}
+	if err := sr.rq.addReplica(
+		ctx, repl, addTarget, desc, SnapshotRequest_REBALANCE, ReasonRebalance, details, false, /* dryRun */
+	); err != nil {
+		return err
+	}
+	if err := repl.AdminTransferLease(ctx, target.StoreID); err != nil {
+		return errors.Wrapf(err, "unable to transfer lease to s%d", target.StoreID)
+	}
+	sr.rq.lastLeaseTransfer.Store(timeutil.Now())
+
+	// The local replica no longer holds the lease, so its removal is carried
+	// out by the new leaseholder.
+	removeTarget := roachpb.ReplicationTarget{NodeID: store.Ident.NodeID, StoreID: store.StoreID()}
+	if err := store.DB().AdminChangeReplicas(
+		ctx, desc.StartKey.AsRawKey(), roachpb.REMOVE_REPLICA, []roachpb.ReplicationTarget{removeTarget},
+	); err != nil {
+		return errors.Wrapf(err, "unable to remove replica from s%d", store.StoreID())
+	}
+	sr.rq.allocator.storePool.updateLocalStoreAfterRebalance(
+		store.StoreID(), rangeInfoForRepl(repl, desc), roachpb.REMOVE_REPLICA)
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func storeWithQPS(storeID roachpb.StoreID, qps float64) roachpb.StoreDescriptor {
+	return roachpb.StoreDescriptor{
+		StoreID: storeID,
+		Node: roachpb.NodeDescriptor{
+			NodeID: roachpb.NodeID(storeID),
+			Locality: roachpb.Locality{
+				Tiers: []roachpb.Tier{{Key: "node", Value: roachpb.NodeID(storeID).String()}},
+			},
+		},
+		Capacity: roachpb.StoreCapacity{
+			Capacity:         100,
+			Available:        100,
+			QueriesPerSecond: qps,
+		},
+	}
+}
+
+func makeStoreMap(stores []roachpb.StoreDescriptor) map[roachpb.StoreID]*roachpb.StoreDescriptor {
+	storeMap := make(map[roachpb.StoreID]*roachpb.StoreDescriptor, len(stores))
+	for i := range stores {
+		storeMap[stores[i].StoreID] = &stores[i]
+	}
+	return storeMap
+}
+
+func TestQPSThresholds(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	st := cluster.MakeTestingClusterSettings()
+
+	testCases := []struct {
+		mean, expMin, expMax float64
+	}{
+		// The thresholds are at least minQPSThresholdDifference away from the
+		// mean.
+		{mean: 0, expMin: -100, expMax: 100},
+		{mean: 200, expMin: 100, expMax: 300},
+		// Otherwise, they are a fraction away from the mean.
+		{mean: 1000, expMin: 750, expMax: 1250},
+	}
+	for i, tc := range testCases {
+		if min, max := qpsThresholds(st, tc.mean); min != tc.expMin || max != tc.expMax {
+			t.Errorf("%d: expected thresholds [%.2f,%.2f], got [%.2f,%.2f]",
+				i, tc.expMin, tc.expMax, min, max)
+		}
+	}
+}
+
+func TestChooseLeaseTarget(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stores := []roachpb.StoreDescriptor{
+		storeWithQPS(1, 2000),
+		storeWithQPS(2, 900),
+		storeWithQPS(3, 700),
+		storeWithQPS(4, 100),
+	}
+	storeMap := makeStoreMap(stores)
+	const maxQPS = 1250
+
+	testCases := []struct {
+		storeIDs []roachpb.StoreID
+		qps      float64
+		expected roachpb.StoreID // 0 if no target is expected
+	}{
+		// The least loaded store with a replica is chosen.
+		{[]roachpb.StoreID{1, 2, 3}, 100, 3},
+		{[]roachpb.StoreID{1, 2, 3, 4}, 100, 4},
+		// Stores which would end up overloaded are skipped.
+		{[]roachpb.StoreID{1, 2, 3}, 500, 3},
+		{[]roachpb.StoreID{1, 2, 3}, 600, 0},
+		// Stores without a descriptor are skipped.
+		{[]roachpb.StoreID{1, 5}, 100, 0},
+		// The local store is never chosen.
+		{[]roachpb.StoreID{1}, 100, 0},
+	}
+	for i, tc := range testCases {
+		var candidates []roachpb.ReplicaDescriptor
+		for _, storeID := range tc.storeIDs {
+			candidates = append(candidates, roachpb.ReplicaDescriptor{
+				NodeID:    roachpb.NodeID(storeID),
+				StoreID:   storeID,
+				ReplicaID: roachpb.ReplicaID(storeID),
+			})
+		}
+		target, ok := chooseLeaseTarget(1, candidates, tc.qps, storeMap, maxQPS)
+		if ok != (tc.expected != 0) || target.StoreID != tc.expected {
+			t.Errorf("%d: expected target s%d, got s%d (found=%t)", i, tc.expected, target.StoreID, ok)
+		}
+	}
+}
+
+func TestChooseReplicaTarget(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stores := []roachpb.StoreDescriptor{
+		storeWithQPS(1, 2000),
+		storeWithQPS(2, 900),
+		storeWithQPS(3, 700),
+		storeWithQPS(4, 400),
+		storeWithQPS(5, 100),
+	}
+	stores[4].Attrs = roachpb.Attributes{Attrs: []string{"ssd"}}
+	storeMap := makeStoreMap(stores)
+	const maxQPS = 1250
+
+	desc := &roachpb.RangeDescriptor{
+		RangeID: 1,
+		Replicas: []roachpb.ReplicaDescriptor{
+			{NodeID: 1, StoreID: 1, ReplicaID: 1},
+			{NodeID: 2, StoreID: 2, ReplicaID: 2},
+			{NodeID: 3, StoreID: 3, ReplicaID: 3},
+		},
+	}
+	localities := make(map[roachpb.NodeID]roachpb.Locality)
+	for _, rep := range desc.Replicas {
+		localities[rep.NodeID] = stores[rep.StoreID-1].Node.Locality
+	}
+
+	ssdPreference := []config.LeasePreference{{Constraints: []config.Constraint{
+		{Value: "ssd", Type: config.Constraint_REQUIRED},
+	}}}
+	hddPreference := []config.LeasePreference{{Constraints: []config.Constraint{
+		{Value: "ssd", Type: config.Constraint_PROHIBITED},
+	}}}
+
+	testCases := []struct {
+		constraints      config.Constraints
+		leasePreferences []config.LeasePreference
+		qps              float64
+		expected         roachpb.StoreID // 0 if no target is expected
+	}{
+		// The least loaded store without a replica is chosen.
+		{config.Constraints{}, nil, 100, 5},
+		// Stores which would end up overloaded are skipped.
+		{config.Constraints{}, nil, 1200, 0},
+		// Stores which don't satisfy the constraints are skipped.
+		{config.Constraints{Constraints: []config.Constraint{
+			{Value: "ssd", Type: config.Constraint_PROHIBITED},
+		}}, nil, 100, 4},
+		// Stores which don't satisfy the lease preferences are skipped.
+		{config.Constraints{}, ssdPreference, 100, 5},
+		{config.Constraints{}, hddPreference, 100, 4},
+	}
+	for i, tc := range testCases {
+		target, ok := chooseReplicaTarget(
+			1, desc, tc.constraints, tc.leasePreferences, localities, tc.qps, stores, storeMap, maxQPS)
+		if ok != (tc.expected != 0) || target.StoreID != tc.expected {
+			t.Errorf("%d: expected target s%d, got s%d (found=%t)", i, tc.expected, target.StoreID, ok)
+		}
+	}
+
+	// Load moved to a store during the same pass is taken into account.
+	storeMap[5].Capacity.QueriesPerSecond = 1200
+	target, ok := chooseReplicaTarget(1, desc, config.Constraints{}, nil, localities, 100, stores, storeMap, maxQPS)
+	if !ok || target.StoreID != 4 {
+		t.Errorf("expected target s4, got s%d (found=%t)", target.StoreID, ok)
+	}
+}
+
+func TestShouldMoveLoad(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	localDesc := storeWithQPS(1, 1000)
+	testCases := []struct {
+		qps, minQPS float64
+		expected    bool
+	}{
+		{0, 500, false},
+		{100, 500, true},
+		{500, 500, true},
+		{600, 500, false},
+	}
+	for i, tc := range testCases {
+		if moved := shouldMoveLoad(tc.qps, &localDesc, tc.minQPS); moved != tc.expected {
+			t.Errorf("%d: expected %t, got %t", i, tc.expected, moved)
+		}
+	}
+}
@@ -1611,6 +1611,52 @@ func setTxnAutoGC(to bool) func() {
 // TestStoreReadInconsistent verifies that gets and scans with read
 // consistency set to INCONSISTENT either push or simply ignore extant
 // intents (if they cannot be pushed), depending on the intent priority.
+// TestStoreNoWaitOnIntent verifies that a request with the NoWait flag set
+// returns a WriteIntentError instead of waiting on or pushing a live
+// conflicting transaction.
+func TestStoreNoWaitOnIntent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	store, _ := createTestStore(t, stopper)
+
+	key := roachpb.Key("a")
+	pushee := newTransaction("pushee", key, 1, enginepb.SERIALIZABLE, store.cfg.Clock)
+	pusher := newTransaction("pusher", key, 1, enginepb.SERIALIZABLE, store.cfg.Clock)
+	pushee.Priority = roachpb.MinTxnPriority
+	pusher.Priority = roachpb.MaxTxnPriority
+
+	// Lay down an intent from the pushee.
+	args := putArgs(key, []byte("value1"))
+	if _, pErr := maybeWrapWithBeginTransaction(context.Background(), store.testSender(), roachpb.Header{Txn: pushee}, &args); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// The pusher would win a regular push, but with NoWait it only tries to
+	// clean up expired transactions.
+	gArgs := getArgs(key)
+	_, pErr := client.SendWrappedWith(context.Background(), store.testSender(), roachpb.Header{
+		Txn:    pusher,
+		NoWait: true,
+	}, &gArgs)
+	if _, ok := pErr.GetDetail().(*roachpb.WriteIntentError); !ok {
+		t.Fatalf("expected WriteIntentError; got %v", pErr)
+	}
+
+	// The pushee's transaction is untouched.
+	txnKey := keys.TransactionKey(pushee.Key, pushee.ID)
+	var txn roachpb.Transaction
+	if ok, err := engine.MVCCGetProto(context.Background(), store.Engine(), txnKey, hlc.Timestamp{}, true, nil, &txn); !ok || err != nil {
+		t.Fatalf("not found or err: %v", err)
+	}
+	if txn.Status != roachpb.PENDING {
+		t.Errorf("expected pushee to be pending; got %s", txn.Status)
+	}
+	if txn.Timestamp != pushee.Timestamp {
+		t.Errorf("expected pushee timestamp %s; got %s", pushee.Timestamp, txn.Timestamp)
+	}
+}
+
 func TestStoreReadInconsistent(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	// The test relies on being able to commit a Txn without specifying the
@@ -219,6 +219,25 @@ func (ls *Stores) Send(
 	return br, pErr
 }
 
+// RangeFeed registers a rangefeed over the specified span. It sends updates
+// to the provided stream and returns with an optional error when the
+// rangefeed is complete.
+func (ls *Stores) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) *roachpb.Error {
+	if args.RangeID == 0 {
+		log.Fatal(stream.Context(), "rangefeed request missing range ID")
+	} else if args.Replica.StoreID == 0 {
+		log.Fatal(stream.Context(), "rangefeed request missing store ID")
+	}
+
+	store, err := ls.GetStore(args.Replica.StoreID)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	return store.RangeFeed(args, stream)
+}
+
 // LookupReplica looks up replica by key [range]. Lookups are done
 // by consulting each store in turn via Store.LookupReplica(key).
 // Returns RangeID and replica on success; RangeKeyMismatch error
@@ -20,17 +20,20 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
 )
 
-// Server implements ConsistencyServer.
+// Server implements ConsistencyServer and PerReplicaServer.
 type Server struct {
 	descriptor *roachpb.NodeDescriptor
 	stores     *Stores
 }
 
 var _ ConsistencyServer = Server{}
+var _ PerReplicaServer = Server{}
 
 // MakeServer returns a new instance of Server.
 func MakeServer(descriptor *roachpb.NodeDescriptor, stores *Stores) Server {
@@ -74,3 +77,30 @@ func (is Server) CollectChecksum(
 		})
 	return resp, err
 }
+
+// WaitForApplication implements PerReplicaServer.
+//
+// It waits until the replica of the range on the store has applied the
+// command with the requested lease applied index. The replica may not exist
+// yet, for instance if it is waiting for its initial snapshot.
+func (is Server) WaitForApplication(
+	ctx context.Context, req *WaitForApplicationRequest,
+) (*WaitForApplicationResponse, error) {
+	resp := &WaitForApplicationResponse{}
+	err := is.execStoreCommand(req.StoreRequestHeader, func(s *Store) error {
+		for r := retry.StartWithCtx(ctx, base.DefaultRetryOptions()); r.Next(); {
+			repl, err := s.GetReplica(req.RangeID)
+			if err != nil {
+				if _, ok := err.(*roachpb.RangeNotFoundError); ok {
+					continue
+				}
+				return err
+			}
+			if repl.GetLeaseAppliedIndex() >= req.LeaseIndex {
+				return nil
+			}
+		}
+		return ctx.Err()
+	})
+	return resp, err
+}
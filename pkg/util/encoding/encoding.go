@@ -48,6 +48,12 @@ const (
 	floatPos     = floatZero + 1
 	floatNaNDesc = floatPos + 1 // NaN encoded descendingly
 
+	// jsonEmptyArray and jsonEmptyObject end the paths through JSON
+	// documents, in the keys of inverted indexes, that lead to an empty
+	// array or object rather than to a scalar value.
+	jsonEmptyArray  = floatNaNDesc + 1
+	jsonEmptyObject = jsonEmptyArray + 1
+
 	// The gap between floatNaNDesc and bytesMarker was left for
 	// compatibility reasons.
 	bytesMarker          byte = 0x12
@@ -688,6 +694,16 @@ func EncodeArrayAscending(b []byte) []byte {
 	return append(b, byte(Array))
 }
 
+// EncodeJSONEmptyArray encodes an empty JSON array for use with JSON inverted indexes.
+func EncodeJSONEmptyArray(b []byte) []byte {
+	return append(b, jsonEmptyArray)
+}
+
+// EncodeJSONEmptyObject encodes an empty JSON object for use with JSON inverted indexes.
+func EncodeJSONEmptyObject(b []byte) []byte {
+	return append(b, jsonEmptyObject)
+}
+
 // EncodeTrueAscending encodes the boolean value true for use with JSON inverted indexes.
 func EncodeTrueAscending(b []byte) []byte {
 	return append(b, byte(True))
@@ -953,6 +969,12 @@ func PeekType(b []byte) Type {
 			return Float
 		case m >= decimalNaN && m <= decimalNaNDesc:
 			return Decimal
+		case m == byte(True):
+			return True
+		case m == byte(False):
+			return False
+		case m == byte(Array):
+			return Array
 		}
 	}
 	return Unknown
@@ -1058,6 +1080,16 @@ func prettyPrintValueImpl(b []byte, sep string) (string, bool) {
 // after decoding.
 func prettyPrintFirstValue(b []byte) ([]byte, string, error) {
 	var err error
+	if len(b) > 0 {
+		// Empty JSON arrays and objects are found in the paths through JSON
+		// documents of inverted index keys.
+		switch b[0] {
+		case jsonEmptyArray:
+			return b[1:], "[]", nil
+		case jsonEmptyObject:
+			return b[1:], "{}", nil
+		}
+	}
 	switch PeekType(b) {
 	case Null:
 		b, _ = DecodeIfNull(b)
@@ -1114,6 +1146,14 @@ func prettyPrintFirstValue(b []byte) ([]byte, string, error) {
 			return b, "", err
 		}
 		return b, d.String(), nil
+	case True:
+		return b[1:], "true", nil
+	case False:
+		return b[1:], "false", nil
+	case Array:
+		// Array markers are found in the paths through JSON documents of
+		// inverted index keys.
+		return b[1:], "Arr", nil
 	default:
 		// This shouldn't ever happen, but if it does, return an empty slice.
 		return nil, strconv.Quote(string(b)), nil
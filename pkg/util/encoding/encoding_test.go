@@ -887,6 +887,9 @@ func TestPeekType(t *testing.T) {
 		{EncodeTimeDescending(nil, timeutil.Now()), Time},
 		{encodedDurationAscending, Duration},
 		{encodedDurationDescending, Duration},
+		{EncodeTrueAscending(nil), True},
+		{EncodeFalseAscending(nil), False},
+		{EncodeArrayAscending(nil), Array},
 	}
 	for i, c := range testCases {
 		typ := PeekType(c.enc)
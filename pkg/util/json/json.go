@@ -89,6 +89,10 @@ type JSON interface {
 	// Exists implements the `?` operator.
 	Exists(string) bool
 
+	// ObjectIter returns an iterator over the key-value pairs of the JSON
+	// document if it is an object, and nil otherwise.
+	ObjectIter() *ObjectIterator
+
 	// isScalar returns whether the JSON document is null, true, false, a string,
 	// or a number.
 	isScalar() bool
@@ -414,6 +418,9 @@ func (j jsonNumber) EncodeInvertedIndexKeys(b []byte) [][]byte {
 	return [][]byte{encoding.EncodeDecimalAscending(b, &dec)}
 }
 func (j jsonArray) EncodeInvertedIndexKeys(b []byte) [][]byte {
+	if len(j) == 0 {
+		return [][]byte{encoding.EncodeJSONEmptyArray(b)}
+	}
 	var outKeys [][]byte
 
 	for i := range j {
@@ -427,6 +434,9 @@ func (j jsonArray) EncodeInvertedIndexKeys(b []byte) [][]byte {
 }
 
 func (j jsonObject) EncodeInvertedIndexKeys(b []byte) [][]byte {
+	if len(j) == 0 {
+		return [][]byte{encoding.EncodeJSONEmptyObject(b)}
+	}
 	var outKeys [][]byte
 	for i := range j {
 		for _, childBytes := range j[i].v.EncodeInvertedIndexKeys(nil) {
@@ -442,6 +452,102 @@ func (j jsonObject) EncodeInvertedIndexKeys(b []byte) [][]byte {
 	return outKeys
 }
 
+// EncodeExistsInvertedIndexPrefixes returns the prefixes, appended to b, of
+// the inverted index keys of the documents for which Exists(s) is true: the
+// keys of the paths through the value of a top-level key s of an object, and
+// the key of a top-level element s of an array. Since empty arrays and
+// objects have keys too, every key of an object has at least one path.
+func EncodeExistsInvertedIndexPrefixes(b []byte, s string) [][]byte {
+	objectPrefix := encoding.EncodeNotNullAscending(append([]byte(nil), b...))
+	arrayPrefix := encoding.EncodeArrayAscending(append([]byte(nil), b...))
+	return [][]byte{
+		encoding.EncodeStringAscending(objectPrefix, s),
+		encoding.EncodeStringAscending(arrayPrefix, s),
+	}
+}
+
+var (
+	invertedArrayMarker       = encoding.EncodeArrayAscending(nil)[0]
+	invertedObjectKeyMarker   = encoding.EncodeNotNullAscending(nil)[0]
+	invertedTrueMarker        = encoding.EncodeTrueAscending(nil)[0]
+	invertedFalseMarker       = encoding.EncodeFalseAscending(nil)[0]
+	invertedEmptyArrayMarker  = encoding.EncodeJSONEmptyArray(nil)[0]
+	invertedEmptyObjectMarker = encoding.EncodeJSONEmptyObject(nil)[0]
+)
+
+// PeekInvertedIndexKeyLength returns the length of the path through a JSON
+// document encoded by EncodeInvertedIndexKeys at the start of b.
+func PeekInvertedIndexKeyLength(b []byte) (int, error) {
+	valueStart, err := peekInvertedIndexKeyValueStart(b)
+	if err != nil {
+		return 0, err
+	}
+	switch b[valueStart] {
+	case invertedTrueMarker, invertedFalseMarker, invertedEmptyArrayMarker, invertedEmptyObjectMarker:
+		return valueStart + 1, nil
+	}
+	l, err := encoding.PeekLength(b[valueStart:])
+	if err != nil {
+		return 0, err
+	}
+	return valueStart + l, nil
+}
+
+// peekInvertedIndexKeyValueStart returns the offset of the scalar value ending
+// the path through a JSON document encoded by EncodeInvertedIndexKeys at the
+// start of b.
+func peekInvertedIndexKeyValueStart(b []byte) (int, error) {
+	n := 0
+	for {
+		if n >= len(b) {
+			return 0, pgerror.NewError(pgerror.CodeInternalError, "error decoding inverted index key")
+		}
+		switch b[n] {
+		case invertedArrayMarker:
+			n++
+		case invertedObjectKeyMarker:
+			n++
+			l, err := encoding.PeekLength(b[n:])
+			if err != nil {
+				return 0, err
+			}
+			n += l
+		default:
+			return n, nil
+		}
+	}
+}
+
+// EncodeContainingInvertedIndexKeys returns inverted index keys, appended to
+// b, such that the keys of any document containing j include at least one of
+// them. Since an array contains its scalar elements, these are the keys of a
+// path through j to a scalar value, with and without an array around the
+// value it ends with. It returns nil if no path through j ends with a scalar
+// value, e.g. if j is an empty array or object, which is contained in any
+// array or object respectively.
+func EncodeContainingInvertedIndexKeys(b []byte, j JSON) ([][]byte, error) {
+	var path []byte
+	var valueStart int
+	for _, p := range j.EncodeInvertedIndexKeys(nil) {
+		var err error
+		if valueStart, err = peekInvertedIndexKeyValueStart(p); err != nil {
+			return nil, err
+		}
+		if p[valueStart] != invertedEmptyArrayMarker && p[valueStart] != invertedEmptyObjectMarker {
+			path = p
+			break
+		}
+	}
+	if path == nil {
+		return nil, nil
+	}
+	inArray := append([]byte(nil), b...)
+	inArray = append(inArray, path[:valueStart]...)
+	inArray = encoding.EncodeArrayAscending(inArray)
+	inArray = append(inArray, path[valueStart:]...)
+	return [][]byte{append(append([]byte(nil), b...), path...), inArray}, nil
+}
+
 // MakeJSON returns a JSON value given a Go-style representation of JSON.
 // * JSON null is Go `nil`,
 // * JSON true is Go `true`,
@@ -604,7 +710,13 @@ var errCannotDeleteFromScalar = pgerror.NewError(pgerror.CodeInvalidParameterVal
 var errCannotDeleteFromObject = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot delete from object using integer index")
 
 func (j jsonArray) RemoveKey(key string) (JSON, error) {
-	return j, nil
+	newVal := make(jsonArray, 0, len(j))
+	for i := range j {
+		if elem, ok := j[i].(jsonString); !ok || string(elem) != key {
+			newVal = append(newVal, j[i])
+		}
+	}
+	return newVal, nil
 }
 
 func (j jsonObject) RemoveKey(key string) (JSON, error) {
@@ -701,6 +813,40 @@ func (j jsonObject) Exists(s string) bool {
 	return j.FetchValKey(s) != nil
 }
 
+// ObjectIterator iterates over the key-value pairs of a JSON object in the
+// order of their keys.
+type ObjectIterator struct {
+	src jsonObject
+	idx int
+}
+
+// Next advances the iterator, returning false once all the pairs have been
+// visited.
+func (it *ObjectIterator) Next() bool {
+	it.idx++
+	return it.idx < len(it.src)
+}
+
+// Key returns the key of the current pair.
+func (it *ObjectIterator) Key() string {
+	return string(it.src[it.idx].k)
+}
+
+// Value returns the value of the current pair.
+func (it *ObjectIterator) Value() JSON {
+	return it.src[it.idx].v
+}
+
+func (jsonNull) ObjectIter() *ObjectIterator   { return nil }
+func (jsonTrue) ObjectIter() *ObjectIterator   { return nil }
+func (jsonFalse) ObjectIter() *ObjectIterator  { return nil }
+func (jsonNumber) ObjectIter() *ObjectIterator { return nil }
+func (jsonString) ObjectIter() *ObjectIterator { return nil }
+func (jsonArray) ObjectIter() *ObjectIterator  { return nil }
+func (j jsonObject) ObjectIter() *ObjectIterator {
+	return &ObjectIterator{src: j, idx: -1}
+}
+
 func (jsonNull) isScalar() bool   { return true }
 func (jsonFalse) isScalar() bool  { return true }
 func (jsonTrue) isScalar() bool   { return true }
@@ -708,3 +854,364 @@ func (jsonNumber) isScalar() bool { return true }
 func (jsonString) isScalar() bool { return true }
 func (jsonArray) isScalar() bool  { return false }
 func (jsonObject) isScalar() bool { return false }
+
+// FromString returns the JSON string s.
+func FromString(s string) JSON {
+	return jsonString(s)
+}
+
+// FromBool returns the JSON boolean b.
+func FromBool(b bool) JSON {
+	if b {
+		return TrueJSONValue
+	}
+	return FalseJSONValue
+}
+
+// FromDecimal returns the JSON number d.
+func FromDecimal(d apd.Decimal) JSON {
+	return jsonNumber(d)
+}
+
+// FromInt64 returns the JSON number i.
+func FromInt64(i int64) JSON {
+	dec := apd.Decimal{}
+	dec.SetCoefficient(i)
+	return jsonNumber(dec)
+}
+
+// FromFloat64 returns the JSON number f, failing if f is infinite or NaN,
+// which JSON numbers can't represent.
+func FromFloat64(f float64) (JSON, error) {
+	dec := apd.Decimal{}
+	if _, err := dec.SetFloat64(f); err != nil {
+		return nil, err
+	}
+	if dec.Form != apd.Finite {
+		return nil, pgerror.NewErrorf(pgerror.CodeInvalidParameterValueError,
+			"%g can't be represented as a JSON number", f)
+	}
+	return jsonNumber(dec), nil
+}
+
+// ArrayBuilder builds a JSON array from a sequence of values.
+type ArrayBuilder struct {
+	elems jsonArray
+}
+
+// NewArrayBuilder returns an ArrayBuilder, preallocating room for
+// numAddsHint values.
+func NewArrayBuilder(numAddsHint int) *ArrayBuilder {
+	return &ArrayBuilder{elems: make(jsonArray, 0, numAddsHint)}
+}
+
+// Add appends j to the array.
+func (b *ArrayBuilder) Add(j JSON) {
+	b.elems = append(b.elems, j)
+}
+
+// Build returns the array of the values added so far. Values added
+// afterwards aren't part of the returned array.
+func (b *ArrayBuilder) Build() JSON {
+	return b.elems[:len(b.elems):len(b.elems)]
+}
+
+// ObjectBuilder builds a JSON object from a sequence of key-value pairs.
+type ObjectBuilder struct {
+	pairs []jsonKeyValuePair
+}
+
+// NewObjectBuilder returns an ObjectBuilder, preallocating room for
+// numAddsHint pairs.
+func NewObjectBuilder(numAddsHint int) *ObjectBuilder {
+	return &ObjectBuilder{pairs: make([]jsonKeyValuePair, 0, numAddsHint)}
+}
+
+// Add adds the pair k: v to the object. If k was added before, the value
+// added last is kept.
+func (b *ObjectBuilder) Add(k string, v JSON) {
+	b.pairs = append(b.pairs, jsonKeyValuePair{k: jsonString(k), v: v})
+}
+
+// Build returns the object of the pairs added so far. Pairs added afterwards
+// aren't part of the returned object.
+func (b *ObjectBuilder) Build() JSON {
+	pairs := append([]jsonKeyValuePair(nil), b.pairs...)
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].k < pairs[j].k })
+	result := pairs[:0]
+	for i := range pairs {
+		if i+1 < len(pairs) && pairs[i+1].k == pairs[i].k {
+			continue
+		}
+		result = append(result, pairs[i])
+	}
+	return jsonObject(result)
+}
+
+// Concat implements the `||` operator. The pairs of two objects are merged,
+// those of b taking precedence. Otherwise the elements of two arrays are
+// concatenated, any operand which isn't an array being treated as a single
+// element array.
+func Concat(a, b JSON) JSON {
+	if ao, ok := a.(jsonObject); ok {
+		if bo, ok := b.(jsonObject); ok {
+			builder := NewObjectBuilder(len(ao) + len(bo))
+			for _, objs := range []jsonObject{ao, bo} {
+				for i := range objs {
+					builder.Add(string(objs[i].k), objs[i].v)
+				}
+			}
+			return builder.Build()
+		}
+	}
+	builder := NewArrayBuilder(0)
+	for _, j := range []JSON{a, b} {
+		if arr, ok := j.(jsonArray); ok {
+			for i := range arr {
+				builder.Add(arr[i])
+			}
+		} else {
+			builder.Add(j)
+		}
+	}
+	return builder.Build()
+}
+
+var errCannotSetPathInScalar = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot set path in scalar")
+var errCannotDeletePathInScalar = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot delete path in scalar")
+var errCannotReplaceExistingKey = pgerror.NewError(pgerror.CodeInvalidParameterValueError, "cannot replace existing key")
+
+// pathLeafFn computes the new value of the container j of the last element
+// of a path, given that element.
+type pathLeafFn func(j JSON, key string, pos int) (JSON, error)
+
+// modifyPath returns a copy of j in which the container of the last element
+// of path, starting at path[pos], is replaced by the result of leaf. j is
+// returned unchanged if a container along the path doesn't exist.
+func modifyPath(j JSON, path []string, pos int, leaf pathLeafFn) (JSON, error) {
+	if pos == len(path)-1 {
+		return leaf(j, path[pos], pos)
+	}
+	switch t := j.(type) {
+	case jsonObject:
+		child := t.FetchValKey(path[pos])
+		if child == nil {
+			return j, nil
+		}
+		newChild, err := modifyPath(child, path, pos+1, leaf)
+		if err != nil {
+			return nil, err
+		}
+		return t.setKey(path[pos], newChild), nil
+	case jsonArray:
+		idx, err := pathArrayIndex(path, pos, len(t))
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || idx >= len(t) {
+			return j, nil
+		}
+		newChild, err := modifyPath(t[idx], path, pos+1, leaf)
+		if err != nil {
+			return nil, err
+		}
+		result := append(jsonArray(nil), t...)
+		result[idx] = newChild
+		return result, nil
+	}
+	return j, nil
+}
+
+// pathArrayIndex returns the index into an array of length n designated by
+// path[pos], counting from the end of the array if negative.
+func pathArrayIndex(path []string, pos int, n int) (int, error) {
+	idx, err := strconv.Atoi(path[pos])
+	if err != nil {
+		return 0, pgerror.NewErrorf(pgerror.CodeInvalidTextRepresentationError,
+			"path element at position %d is not an integer: %q", pos+1, path[pos])
+	}
+	if idx < 0 {
+		idx += n
+	}
+	return idx, nil
+}
+
+// setKey returns a copy of j in which the value of key is v.
+func (j jsonObject) setKey(key string, v JSON) jsonObject {
+	i := sort.Search(len(j), func(i int) bool { return string(j[i].k) >= key })
+	if i < len(j) && string(j[i].k) == key {
+		result := append(jsonObject(nil), j...)
+		result[i].v = v
+		return result
+	}
+	result := make(jsonObject, 0, len(j)+1)
+	result = append(result, j[:i]...)
+	result = append(result, jsonKeyValuePair{k: jsonString(key), v: v})
+	return append(result, j[i:]...)
+}
+
+// insertIndex returns a copy of j in which v is inserted at idx, which is
+// clamped to the bounds of j.
+func (j jsonArray) insertIndex(idx int, v JSON) jsonArray {
+	if idx < 0 {
+		idx = 0
+	}
+	if idx > len(j) {
+		idx = len(j)
+	}
+	result := make(jsonArray, 0, len(j)+1)
+	result = append(result, j[:idx]...)
+	result = append(result, v)
+	return append(result, j[idx:]...)
+}
+
+// DeepSet implements the jsonb_set function, replacing the value at path in
+// j by to. If the last element of path doesn't exist in its container and
+// createMissing is true, to is added to the container: as a new key of an
+// object, or at the start or end of an array depending on the sign of the
+// out of bounds index.
+func DeepSet(j JSON, path []string, to JSON, createMissing bool) (JSON, error) {
+	if j.isScalar() {
+		return nil, errCannotSetPathInScalar
+	}
+	if len(path) == 0 {
+		return j, nil
+	}
+	return modifyPath(j, path, 0, func(j JSON, key string, pos int) (JSON, error) {
+		switch t := j.(type) {
+		case jsonObject:
+			if !createMissing && t.FetchValKey(key) == nil {
+				return j, nil
+			}
+			return t.setKey(key, to), nil
+		case jsonArray:
+			idx, err := pathArrayIndex(path, pos, len(t))
+			if err != nil {
+				return nil, err
+			}
+			if idx >= 0 && idx < len(t) {
+				result := append(jsonArray(nil), t...)
+				result[idx] = to
+				return result, nil
+			}
+			if !createMissing {
+				return j, nil
+			}
+			return t.insertIndex(idx, to), nil
+		}
+		return j, nil
+	})
+}
+
+// DeepInsert implements the jsonb_insert function, inserting to at path in
+// j. In an array, to is inserted before the element designated by path, or
+// after it if after is true. In an object, to is added as a new key, which
+// must not exist yet.
+func DeepInsert(j JSON, path []string, to JSON, after bool) (JSON, error) {
+	if j.isScalar() {
+		return nil, errCannotSetPathInScalar
+	}
+	if len(path) == 0 {
+		return j, nil
+	}
+	return modifyPath(j, path, 0, func(j JSON, key string, pos int) (JSON, error) {
+		switch t := j.(type) {
+		case jsonObject:
+			if t.FetchValKey(key) != nil {
+				return nil, errCannotReplaceExistingKey
+			}
+			return t.setKey(key, to), nil
+		case jsonArray:
+			idx, err := pathArrayIndex(path, pos, len(t))
+			if err != nil {
+				return nil, err
+			}
+			if after && idx >= 0 {
+				idx++
+			}
+			return t.insertIndex(idx, to), nil
+		}
+		return j, nil
+	})
+}
+
+// RemovePath implements the `#-` operator, removing the value at path in j.
+func RemovePath(j JSON, path []string) (JSON, error) {
+	if j.isScalar() {
+		return nil, errCannotDeletePathInScalar
+	}
+	if len(path) == 0 {
+		return j, nil
+	}
+	return modifyPath(j, path, 0, func(j JSON, key string, pos int) (JSON, error) {
+		switch t := j.(type) {
+		case jsonObject:
+			return t.RemoveKey(key)
+		case jsonArray:
+			idx, err := pathArrayIndex(path, pos, len(t))
+			if err != nil {
+				return nil, err
+			}
+			if idx < 0 {
+				return j, nil
+			}
+			return t.RemoveIndex(idx)
+		}
+		return j, nil
+	})
+}
+
+// Pretty returns the JSON document formatted over several lines, each
+// nested value being indented by four spaces, as by Postgres' jsonb_pretty.
+func Pretty(j JSON) string {
+	var buf bytes.Buffer
+	prettyFormat(&buf, j, 0)
+	return buf.String()
+}
+
+const prettyIndent = "    "
+
+func prettyFormat(buf *bytes.Buffer, j JSON, depth int) {
+	newLine := func(depth int) {
+		buf.WriteByte('\n')
+		for i := 0; i < depth; i++ {
+			buf.WriteString(prettyIndent)
+		}
+	}
+	switch t := j.(type) {
+	case jsonArray:
+		if len(t) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteByte('[')
+		for i := range t {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			newLine(depth + 1)
+			prettyFormat(buf, t[i], depth+1)
+		}
+		newLine(depth)
+		buf.WriteByte(']')
+	case jsonObject:
+		if len(t) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteByte('{')
+		for i := range t {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			newLine(depth + 1)
+			encodeJSONString(buf, string(t[i].k))
+			buf.WriteString(": ")
+			prettyFormat(buf, t[i].v, depth+1)
+		}
+		newLine(depth)
+		buf.WriteByte('}')
+	default:
+		j.Format(buf)
+	}
+}
@@ -475,13 +475,18 @@ func TestJSONRemoveKey(t *testing.T) {
 			{key: `bar`, expected: json(`{"foo": 1}`)},
 			{key: `baz`, expected: json(`{"foo": 1, "bar": "baz"}`)},
 		},
-		// Deleting a string key from an array never has any effect.
+		// Deleting a string key from an array removes the matching strings.
 		`["a", "b", "c"]`: {
 			{key: ``, expected: json(`["a", "b", "c"]`)},
 			{key: `foo`, expected: json(`["a", "b", "c"]`)},
 			{key: `0`, expected: json(`["a", "b", "c"]`)},
 			{key: `1`, expected: json(`["a", "b", "c"]`)},
 			{key: `-1`, expected: json(`["a", "b", "c"]`)},
+			{key: `b`, expected: json(`["a", "c"]`)},
+		},
+		`["a", 1, "a", {"a": 2}]`: {
+			{key: `a`, expected: json(`[1, {"a": 2}]`)},
+			{key: `1`, expected: json(`["a", 1, "a", {"a": 2}]`)},
 		},
 		`5`:     {{key: `a`, errMsg: "cannot delete from scalar"}},
 		`"b"`:   {{key: `a`, errMsg: "cannot delete from scalar"}},
@@ -578,6 +583,153 @@ func TestJSONRemoveIndex(t *testing.T) {
 	}
 }
 
+func TestObjectBuilder(t *testing.T) {
+	json := jsonTestShorthand
+	b := NewObjectBuilder(0)
+	b.Add("b", FromInt64(1))
+	b.Add("a", FromString("x"))
+	b.Add("c", NullJSONValue)
+	b.Add("b", FromBool(true))
+	expected := json(`{"a": "x", "b": true, "c": null}`)
+	if result := b.Build(); result.Compare(expected) != 0 {
+		t.Fatalf("expected %s, got %s", expected, result)
+	}
+
+	it := expected.ObjectIter()
+	var keys []string
+	for it.Next() {
+		keys = append(keys, it.Key()+"="+it.Value().String())
+	}
+	if s := strings.Join(keys, ","); s != `a="x",b=true,c=null` {
+		t.Fatalf("unexpected pairs %s", s)
+	}
+	if it := json(`[1]`).ObjectIter(); it != nil {
+		t.Fatal("expected no iterator for an array")
+	}
+}
+
+func TestJSONConcat(t *testing.T) {
+	json := jsonTestShorthand
+	cases := []struct {
+		a, b     string
+		expected string
+	}{
+		{`{"a": 1, "b": 2}`, `{"b": 3, "c": 4}`, `{"a": 1, "b": 3, "c": 4}`},
+		{`{}`, `{}`, `{}`},
+		{`[1, 2]`, `[3]`, `[1, 2, 3]`},
+		{`[1, 2]`, `3`, `[1, 2, 3]`},
+		{`"a"`, `[1]`, `["a", 1]`},
+		{`{"a": 1}`, `[1]`, `[{"a": 1}, 1]`},
+		{`{"a": 1}`, `null`, `[{"a": 1}, null]`},
+		{`1`, `2`, `[1, 2]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.a+"||"+tc.b, func(t *testing.T) {
+			result := Concat(json(tc.a), json(tc.b))
+			if result.Compare(json(tc.expected)) != 0 {
+				t.Fatalf("expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestJSONModifyPath(t *testing.T) {
+	json := jsonTestShorthand
+	type modifyFn func(j JSON, path []string) (JSON, error)
+	set := func(j JSON, path []string) (JSON, error) {
+		return DeepSet(j, path, json(`"x"`), true /* createMissing */)
+	}
+	setExisting := func(j JSON, path []string) (JSON, error) {
+		return DeepSet(j, path, json(`"x"`), false /* createMissing */)
+	}
+	insertBefore := func(j JSON, path []string) (JSON, error) {
+		return DeepInsert(j, path, json(`"x"`), false /* after */)
+	}
+	insertAfter := func(j JSON, path []string) (JSON, error) {
+		return DeepInsert(j, path, json(`"x"`), true /* after */)
+	}
+	cases := []struct {
+		name     string
+		fn       modifyFn
+		j        string
+		path     []string
+		expected string
+		errMsg   string
+	}{
+		{`set`, set, `{"a": 1}`, []string{`a`}, `{"a": "x"}`, ``},
+		{`set`, set, `{"a": 1}`, []string{`b`}, `{"a": 1, "b": "x"}`, ``},
+		{`set`, set, `{"a": 1}`, []string{`b`, `c`}, `{"a": 1}`, ``},
+		{`set`, set, `{"a": 1}`, []string{`a`, `c`}, `{"a": 1}`, ``},
+		{`set`, set, `{"a": [1, {"b": 2}]}`, []string{`a`, `1`, `b`}, `{"a": [1, {"b": "x"}]}`, ``},
+		{`set`, set, `{"a": [1, {"b": 2}]}`, []string{`a`, `-1`, `c`}, `{"a": [1, {"b": 2, "c": "x"}]}`, ``},
+		{`set`, set, `[1, 2]`, []string{`0`}, `["x", 2]`, ``},
+		{`set`, set, `[1, 2]`, []string{`-1`}, `[1, "x"]`, ``},
+		{`set`, set, `[1, 2]`, []string{`5`}, `[1, 2, "x"]`, ``},
+		{`set`, set, `[1, 2]`, []string{`-5`}, `["x", 1, 2]`, ``},
+		{`set`, set, `[1, 2]`, []string{`a`}, ``, `path element at position 1 is not an integer: "a"`},
+		{`set`, set, `{"a": 1}`, []string{}, `{"a": 1}`, ``},
+		{`set`, set, `1`, []string{`a`}, ``, `cannot set path in scalar`},
+		{`setExisting`, setExisting, `{"a": 1}`, []string{`b`}, `{"a": 1}`, ``},
+		{`setExisting`, setExisting, `[1, 2]`, []string{`2`}, `[1, 2]`, ``},
+		{`setExisting`, setExisting, `{"a": 1}`, []string{`a`}, `{"a": "x"}`, ``},
+		{`insertBefore`, insertBefore, `[1, 2]`, []string{`1`}, `[1, "x", 2]`, ``},
+		{`insertBefore`, insertBefore, `[1, 2]`, []string{`-1`}, `[1, "x", 2]`, ``},
+		{`insertBefore`, insertBefore, `[1, 2]`, []string{`5`}, `[1, 2, "x"]`, ``},
+		{`insertBefore`, insertBefore, `[1, 2]`, []string{`-5`}, `["x", 1, 2]`, ``},
+		{`insertBefore`, insertBefore, `{"a": [1]}`, []string{`a`, `0`}, `{"a": ["x", 1]}`, ``},
+		{`insertBefore`, insertBefore, `{"a": 1}`, []string{`b`}, `{"a": 1, "b": "x"}`, ``},
+		{`insertBefore`, insertBefore, `{"a": 1}`, []string{`a`}, ``, `cannot replace existing key`},
+		{`insertAfter`, insertAfter, `[1, 2]`, []string{`0`}, `[1, "x", 2]`, ``},
+		{`insertAfter`, insertAfter, `[1, 2]`, []string{`-1`}, `[1, 2, "x"]`, ``},
+		{`remove`, RemovePath, `{"a": {"b": 1, "c": 2}}`, []string{`a`, `b`}, `{"a": {"c": 2}}`, ``},
+		{`remove`, RemovePath, `{"a": [1, 2]}`, []string{`a`, `-1`}, `{"a": [1]}`, ``},
+		{`remove`, RemovePath, `{"a": [1, 2]}`, []string{`a`, `5`}, `{"a": [1, 2]}`, ``},
+		{`remove`, RemovePath, `{"a": [1, 2]}`, []string{`b`, `0`}, `{"a": [1, 2]}`, ``},
+		{`remove`, RemovePath, `[1, 2]`, []string{`b`}, ``, `path element at position 1 is not an integer: "b"`},
+		{`remove`, RemovePath, `"a"`, []string{`a`}, ``, `cannot delete path in scalar`},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s(%s,%v)", tc.name, tc.j, tc.path), func(t *testing.T) {
+			result, err := tc.fn(json(tc.j), tc.path)
+			if tc.errMsg != "" {
+				if err == nil {
+					t.Fatal("expected error")
+				} else if !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf(`expected error message "%s" to contain "%s"`, err.Error(), tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Compare(json(tc.expected)) != 0 {
+				t.Fatalf("expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestJSONPretty(t *testing.T) {
+	cases := []struct {
+		j        string
+		expected string
+	}{
+		{`1`, `1`},
+		{`[]`, `[]`},
+		{`{}`, `{}`},
+		{`[1, "a"]`, "[\n    1,\n    \"a\"\n]"},
+		{`{"a": [1, {"b": null}], "c": {}}`,
+			"{\n    \"a\": [\n        1,\n        {\n            \"b\": null\n        }\n    ],\n    \"c\": {}\n}"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.j, func(t *testing.T) {
+			if result := Pretty(jsonTestShorthand(tc.j)); result != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
 func getApdEncoding(num float64) *apd.Decimal {
 	dec := &apd.Decimal{}
 	dec, _ = dec.SetFloat64(num)
@@ -640,10 +792,23 @@ func TestEncodeDecodeJSONInvertedIndex(t *testing.T) {
 			bytes.Join([][]byte{keyPrefix(bytePrefix),
 				encoding.EncodeStringAscending(nil, "e"), encoding.EncodeStringAscending(nil, "f")}, nil),
 		}},
+		{`[]`, [][]byte{encoding.EncodeJSONEmptyArray(bytePrefix)}},
+		{`{}`, [][]byte{encoding.EncodeJSONEmptyObject(bytePrefix)}},
+		{`{"a":{},"b":[]}`, [][]byte{
+			bytes.Join([][]byte{keyPrefix(bytePrefix),
+				encoding.EncodeStringAscending(nil, "a"), encoding.EncodeJSONEmptyObject(nil)}, nil),
+
+			bytes.Join([][]byte{keyPrefix(bytePrefix),
+				encoding.EncodeStringAscending(nil, "b"), encoding.EncodeJSONEmptyArray(nil)}, nil),
+		}},
 	}
 
 	for _, c := range testCases {
 		enc := jsonTestShorthand(c.value).EncodeInvertedIndexKeys(bytePrefix)
+		if len(enc) != len(c.expEnc) {
+			t.Errorf("expected %d keys for %v, got %d", len(c.expEnc), c.value, len(enc))
+			continue
+		}
 		for j, path := range enc {
 			if !bytes.Equal(path, c.expEnc[j]) {
 				t.Errorf("unexpected encoding mismatch for %v. expected [%#v], got [%#v]",
@@ -654,6 +819,125 @@ func TestEncodeDecodeJSONInvertedIndex(t *testing.T) {
 	}
 }
 
+func TestPeekInvertedIndexKeyLength(t *testing.T) {
+	rng := rand.New(rand.NewSource(timeutil.Now().Unix()))
+	prefix := []byte("prefix")
+	suffix := encoding.EncodeVarintAscending(nil, 42)
+	for i := 0; i < 1000; i++ {
+		j, err := Random(20, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range j.EncodeInvertedIndexKeys(prefix) {
+			path := key[len(prefix):]
+			l, err := PeekInvertedIndexKeyLength(append(append([]byte(nil), path...), suffix...))
+			if err != nil {
+				t.Fatalf("%s: %v", j, err)
+			}
+			if l != len(path) {
+				t.Fatalf("%s: expected length %d for %x, got %d", j, len(path), path, l)
+			}
+		}
+	}
+}
+
+func TestEncodeContainingInvertedIndexKeys(t *testing.T) {
+	rng := rand.New(rand.NewSource(timeutil.Now().Unix()))
+	prefix := []byte("prefix")
+	for i := 0; i < 1000; i++ {
+		j, err := Random(20, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other := j.(containsTester).subdocument(true /* isRoot */, rng)
+		containingKeys, err := EncodeContainingInvertedIndexKeys(prefix, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if containingKeys == nil {
+			continue
+		}
+		found := false
+		for _, key := range j.EncodeInvertedIndexKeys(prefix) {
+			for _, k := range containingKeys {
+				if bytes.Equal(key, k) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("%s contains %s but has none of its keys", j, other)
+		}
+	}
+
+	testCases := []struct {
+		value, other string
+	}{
+		{`[1, 2]`, `1`},
+		{`{"a": [1, 2]}`, `{"a": 1}`},
+		{`{"a": 1, "b": 2}`, `{"b": 2}`},
+		{`[{"a": [true]}]`, `[{"a": true}]`},
+		{`{"a": {}, "b": 1}`, `{"a": {}, "b": 1}`},
+		{`{"a": {"c": 1}, "b": [2]}`, `{"a": {}, "b": [2]}`},
+	}
+	for _, c := range testCases {
+		j, other := jsonTestShorthand(c.value), jsonTestShorthand(c.other)
+		if !Contains(j, other) {
+			t.Fatalf("expected %s to contain %s", j, other)
+		}
+		containingKeys, err := EncodeContainingInvertedIndexKeys(prefix, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, key := range j.EncodeInvertedIndexKeys(prefix) {
+			for _, k := range containingKeys {
+				if bytes.Equal(key, k) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s contains %s but has none of its keys", j, other)
+		}
+	}
+}
+
+func TestEncodeExistsInvertedIndexPrefixes(t *testing.T) {
+	prefix := []byte("prefix")
+	prefixes := EncodeExistsInvertedIndexPrefixes(prefix, "a")
+	testCases := []struct {
+		value  string
+		exists bool
+	}{
+		{`{"a": 1}`, true},
+		{`{"a": {"b": [1, 2]}}`, true},
+		{`["a", "b"]`, true},
+		{`{"b": "a"}`, false},
+		{`[["a"]]`, false},
+		{`[{"a": 1}]`, false},
+		{`"a"`, false},
+		{`{"a": {}}`, true},
+		{`{"a": []}`, true},
+		{`{}`, false},
+		{`[]`, false},
+	}
+	for _, c := range testCases {
+		j := jsonTestShorthand(c.value)
+		found := false
+		for _, key := range j.EncodeInvertedIndexKeys(prefix) {
+			for _, p := range prefixes {
+				if bytes.HasPrefix(key, p) {
+					found = true
+				}
+			}
+		}
+		if found != c.exists || j.Exists("a") != c.exists {
+			t.Errorf("%s: expected exists to be %t", c.value, c.exists)
+		}
+	}
+}
+
 func TestJSONRandomRoundTrip(t *testing.T) {
 	rng := rand.New(rand.NewSource(timeutil.Now().Unix()))
 	for i := 0; i < 1000; i++ {